@@ -1,6 +1,7 @@
 package client
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -10,6 +11,7 @@ import (
 	"github.com/Tencent/WeKnora/docreader/proto"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/resolver"
 )
 
@@ -81,6 +83,18 @@ func (c *Client) Close() error {
 	return c.conn.Close()
 }
 
+// HealthCheck queries the standard gRPC health service of the DocReader server
+func (c *Client) HealthCheck(ctx context.Context) error {
+	resp, err := healthpb.NewHealthClient(c.conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		return err
+	}
+	if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("docreader is %s", resp.GetStatus())
+	}
+	return nil
+}
+
 // SetDebug enables or disables debug logging
 func (c *Client) SetDebug(debug bool) {
 	c.debug = debug
@@ -0,0 +1,146 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/common"
+	"github.com/Tencent/WeKnora/internal/event"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+)
+
+// SetToolApproval enables human-in-the-loop approval for the given tool names
+func (e *AgentEngine) SetToolApproval(service interfaces.ToolApprovalService, toolNames []string) {
+	if service == nil || len(toolNames) == 0 {
+		return
+	}
+	e.approvalService = service
+	e.approvalTools = make(map[string]bool, len(toolNames))
+	for _, name := range toolNames {
+		e.approvalTools[name] = true
+	}
+}
+
+// requiresApproval reports whether a tool call must be approved by the user
+func (e *AgentEngine) requiresApproval(toolName string) bool {
+	return e.approvalService != nil && e.approvalTools[toolName]
+}
+
+// awaitToolApproval pauses the run until the user approves or denies a sensitive tool call.
+// It returns true if the tool may be executed; otherwise it returns the result reported to the model.
+func (e *AgentEngine) awaitToolApproval(
+	ctx context.Context,
+	toolCallID, toolName string,
+	args map[string]any,
+	iteration int,
+	sessionID, messageID string,
+) (*types.ToolResult, bool) {
+	if !e.requiresApproval(toolName) {
+		return nil, true
+	}
+
+	timeout := e.config.ToolApprovalTimeoutSeconds
+	if timeout <= 0 {
+		timeout = types.DefaultToolApprovalTimeoutSeconds
+	}
+	timeoutAction := e.config.ToolApprovalTimeoutAction
+	if timeoutAction == "" {
+		timeoutAction = types.ToolApprovalTimeoutDeny
+	}
+
+	argsJSON, _ := json.Marshal(args)
+	approval := &types.ToolApproval{
+		SessionID:     sessionID,
+		MessageID:     messageID,
+		ToolCallID:    toolCallID,
+		ToolName:      toolName,
+		Arguments:     types.JSON(argsJSON),
+		TimeoutAction: timeoutAction,
+		ExpiresAt:     time.Now().Add(time.Duration(timeout) * time.Second),
+	}
+	if err := e.approvalService.RequestApproval(ctx, approval); err != nil {
+		// Fail closed: a sensitive tool is never executed without a recorded approval
+		logger.Errorf(ctx, "[Agent] Failed to create approval request for %s: %v", toolName, err)
+		return &types.ToolResult{
+			Success: false,
+			Error:   "Tool call requires user approval, but the approval request could not be created",
+		}, false
+	}
+
+	logger.Infof(ctx, "[Agent] Tool %s requires approval, waiting up to %ds (approval=%s)",
+		toolName, timeout, approval.ID)
+	common.PipelineInfo(ctx, "Agent", "tool_approval_wait", map[string]interface{}{
+		"iteration":    iteration,
+		"tool":         toolName,
+		"tool_call_id": toolCallID,
+		"approval_id":  approval.ID,
+		"timeout_s":    timeout,
+	})
+	e.eventBus.Emit(ctx, event.Event{
+		ID:        toolCallID + "-tool-approval",
+		Type:      event.EventAgentToolApproval,
+		SessionID: sessionID,
+		Data:      toolApprovalEventData(approval, args, iteration),
+	})
+
+	decision, err := e.approvalService.WaitForDecision(ctx, approval)
+	if err != nil {
+		logger.Warnf(ctx, "[Agent] Approval wait for %s aborted: %v", toolName, err)
+		return &types.ToolResult{
+			Success: false,
+			Error:   fmt.Sprintf("Tool call was not approved: %v", err),
+		}, false
+	}
+
+	e.eventBus.Emit(ctx, event.Event{
+		ID:        toolCallID + "-tool-approval-resolved",
+		Type:      event.EventAgentToolApprovalResolved,
+		SessionID: sessionID,
+		Data:      toolApprovalEventData(decision, args, iteration),
+	})
+	common.PipelineInfo(ctx, "Agent", "tool_approval_result", map[string]interface{}{
+		"iteration":   iteration,
+		"tool":        toolName,
+		"approval_id": decision.ID,
+		"status":      decision.Status,
+	})
+
+	if decision.IsApproved() {
+		return nil, true
+	}
+
+	message := "The user denied this tool call. Do not retry it; continue without it or ask the user how to proceed."
+	if decision.Status == types.ToolApprovalStatusExpired {
+		message = "The approval request for this tool call timed out. Do not retry it; continue without it."
+	}
+	if decision.Reason != "" {
+		message += " Reason: " + decision.Reason
+	}
+	return &types.ToolResult{
+		Success: false,
+		Error:   message,
+		Data: map[string]interface{}{
+			"approval_id":     decision.ID,
+			"approval_status": decision.Status,
+		},
+	}, false
+}
+
+// toolApprovalEventData builds the event payload of an approval request
+func toolApprovalEventData(approval *types.ToolApproval, args map[string]any, iteration int) event.AgentToolApprovalData {
+	return event.AgentToolApprovalData{
+		ApprovalID:    approval.ID,
+		ToolCallID:    approval.ToolCallID,
+		ToolName:      approval.ToolName,
+		Arguments:     args,
+		Status:        approval.Status,
+		Reason:        approval.Reason,
+		TimeoutAction: approval.TimeoutAction,
+		ExpiresAt:     approval.ExpiresAt,
+		Iteration:     iteration,
+	}
+}
@@ -0,0 +1,99 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/agent/tools"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// denyingApprovalService denies every approval request
+type denyingApprovalService struct{}
+
+func (denyingApprovalService) RequestApproval(ctx context.Context, approval *types.ToolApproval) error {
+	approval.ID = "approval-1"
+	return nil
+}
+
+func (denyingApprovalService) WaitForDecision(ctx context.Context, approval *types.ToolApproval) (*types.ToolApproval, error) {
+	decided := *approval
+	decided.Status = types.ToolApprovalStatusDenied
+	return &decided, nil
+}
+
+func (denyingApprovalService) Decide(context.Context, string, string, bool, string) (*types.ToolApproval, error) {
+	return nil, errors.New("not supported")
+}
+
+func (denyingApprovalService) ListPending(context.Context, string) ([]*types.ToolApproval, error) {
+	return nil, nil
+}
+
+// approvalTestTool fails with err when set, and counts its executions
+type approvalTestTool struct {
+	name  string
+	err   error
+	calls int
+}
+
+func (t *approvalTestTool) Name() string                { return t.name }
+func (t *approvalTestTool) Description() string         { return "Test tool " + t.name }
+func (t *approvalTestTool) Parameters() json.RawMessage { return json.RawMessage(`{"type":"object"}`) }
+
+func (t *approvalTestTool) Execute(ctx context.Context, args json.RawMessage) (*types.ToolResult, error) {
+	t.calls++
+	if t.err != nil {
+		return &types.ToolResult{Success: false, Error: t.err.Error()}, t.err
+	}
+	return &types.ToolResult{Success: true, Output: "done"}, nil
+}
+
+func TestDeniedToolCallAfterFailedToolCall(t *testing.T) {
+	failing := &approvalTestTool{name: "fetch", err: errors.New("connection refused")}
+	guarded := &approvalTestTool{name: "delete_file"}
+	registry := tools.NewToolRegistry()
+	registry.RegisterTool(failing)
+	registry.RegisterTool(guarded)
+
+	model := &scriptedChat{responses: []types.StreamResponse{
+		{ToolCalls: []types.LLMToolCall{
+			{ID: "call-1", Type: "function", Function: types.FunctionCall{Name: "fetch", Arguments: `{}`}},
+			{ID: "call-2", Type: "function", Function: types.FunctionCall{Name: "delete_file", Arguments: `{}`}},
+		}},
+		{Content: "Nothing was deleted."},
+	}}
+
+	var recorded *types.AgentTrace
+	config := &types.AgentConfig{MaxIterations: 5, Temperature: 0.3}
+	engine := NewAgentEngine(config, model, registry, nil, nil, nil, nil, "session-1", "")
+	engine.SetToolApproval(denyingApprovalService{}, []string{"delete_file"})
+	engine.SetTraceSink(func(ctx context.Context, trace *types.AgentTrace) { recorded = trace })
+	if _, err := engine.Execute(context.Background(), "session-1", "message-1", "Clean up", nil); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+
+	if failing.calls != 1 || guarded.calls != 0 {
+		t.Fatalf("expected the failing tool to run once and the denied tool never, got %d and %d",
+			failing.calls, guarded.calls)
+	}
+	if recorded == nil || len(recorded.Rounds) == 0 || len(recorded.Rounds[0].ToolCalls) != 2 {
+		t.Fatalf("expected 2 recorded tool calls, got %+v", recorded)
+	}
+	calls := recorded.Rounds[0].ToolCalls
+	if calls[0].Error != "connection refused" {
+		t.Fatalf("unexpected error of the failed call: %q", calls[0].Error)
+	}
+	if calls[1].Executed || !strings.Contains(calls[1].Error, "denied") {
+		t.Fatalf("denied call reported %q, executed=%v", calls[1].Error, calls[1].Executed)
+	}
+	// The model is told about the denial, not the earlier failure
+	messages := model.requests[1]
+	last := messages[len(messages)-1]
+	if last.Role != "tool" || !strings.Contains(last.Content, "denied") {
+		t.Fatalf("unexpected tool message sent to the model: %+v", last)
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/Tencent/WeKnora/internal/common"
 	"github.com/Tencent/WeKnora/internal/event"
 	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/metrics"
 	"github.com/Tencent/WeKnora/internal/models/chat"
 	"github.com/Tencent/WeKnora/internal/types"
 	"github.com/Tencent/WeKnora/internal/types/interfaces"
@@ -29,12 +30,18 @@ type AgentEngine struct {
 	toolRegistry         *tools.ToolRegistry
 	chatModel            chat.Chat
 	eventBus             *event.EventBus
-	knowledgeBasesInfo   []*KnowledgeBaseInfo      // Detailed knowledge base information for prompt
-	selectedDocs         []*SelectedDocumentInfo   // User-selected documents (via @ mention)
-	contextManager       interfaces.ContextManager // Context manager for writing agent conversation to LLM context
-	sessionID            string                    // Session ID for context management
-	systemPromptTemplate string                    // System prompt template (optional, uses default if empty)
-	skillsManager        *skills.Manager           // Skills manager for Progressive Disclosure (optional)
+	knowledgeBasesInfo   []*KnowledgeBaseInfo           // Detailed knowledge base information for prompt
+	selectedDocs         []*SelectedDocumentInfo        // User-selected documents (via @ mention)
+	contextManager       interfaces.ContextManager      // Context manager for writing agent conversation to LLM context
+	sessionID            string                         // Session ID for context management
+	systemPromptTemplate string                         // System prompt template (optional, uses default if empty)
+	skillsManager        *skills.Manager                // Skills manager for Progressive Disclosure (optional)
+	pinnedContext        string                         // Pinned context appended to the system prompt (optional)
+	approvalService      interfaces.ToolApprovalService // Approval service for sensitive tool calls (optional)
+	approvalTools        map[string]bool                // Tool names that require user approval
+	traceSink            TraceSink                      // Receives the full trace of runs (optional)
+	trace                *traceRecorder                 // Trace of the current run, nil when tracing is disabled
+	fixedSystemPrompt    string                         // System prompt used instead of the built one (replays)
 }
 
 // listToolNames returns tool.function names for logging
@@ -112,6 +119,19 @@ func (e *AgentEngine) GetSkillsManager() *skills.Manager {
 	return e.skillsManager
 }
 
+// SetPinnedContext sets context (e.g. pinned MCP resources) appended to the system prompt
+func (e *AgentEngine) SetPinnedContext(pinnedContext string) {
+	e.pinnedContext = pinnedContext
+}
+
+// withPinnedContext appends the pinned context to a system prompt
+func (e *AgentEngine) withPinnedContext(systemPrompt string) string {
+	if e.pinnedContext == "" {
+		return systemPrompt
+	}
+	return systemPrompt + "\n\n" + e.pinnedContext
+}
+
 // Execute executes the agent with conversation history and streaming output
 // All events are emitted to EventBus and handled by subscribers (like Handler layer)
 func (e *AgentEngine) Execute(
@@ -163,6 +183,7 @@ func (e *AgentEngine) Execute(
 			e.systemPromptTemplate,
 		)
 	}
+	systemPrompt = e.withPinnedContext(systemPrompt)
 	logger.Debugf(ctx, "[Agent] SystemPrompt Length: %d characters", len(systemPrompt))
 	logger.Debugf(ctx, "[Agent] SystemPrompt (stream)\n----\n%s\n----", systemPrompt)
 
@@ -181,7 +202,9 @@ func (e *AgentEngine) Execute(
 		"tools":      toolListStr,
 	})
 
+	e.startTrace(sessionID, messageID, query, systemPrompt, tools)
 	_, err := e.executeLoop(ctx, state, query, messages, tools, sessionID, messageID)
+	e.finishTrace(ctx, state, err)
 	if err != nil {
 		logger.Errorf(ctx, "[Agent] Execution failed: %v", err)
 		e.eventBus.Emit(ctx, event.Event{
@@ -224,6 +247,9 @@ func (e *AgentEngine) executeLoop(
 	common.PipelineInfo(ctx, "Agent", "loop_start", map[string]interface{}{
 		"max_iterations": e.config.MaxIterations,
 	})
+	// Knowledge tools number the chunks they return so that the final answer can cite them as [n]
+	citations := types.NewCitationIndex()
+	ctx = context.WithValue(ctx, types.CitationIndexContextKey, citations)
 	for state.CurrentRound < e.config.MaxIterations {
 		roundStart := time.Now()
 		logger.Infof(ctx, "========== Round %d/%d Started ==========", state.CurrentRound+1, e.config.MaxIterations)
@@ -310,6 +336,8 @@ func (e *AgentEngine) executeLoop(
 				state.CurrentRound+1,
 				time.Since(roundStart).Milliseconds(),
 			)
+			e.trace.endRound()
+			metrics.ObserveAgentRound(time.Since(roundStart))
 			break
 		}
 
@@ -323,6 +351,8 @@ func (e *AgentEngine) executeLoop(
 			)
 
 			for i, tc := range response.ToolCalls {
+				// Scoped to the call so that a denied call does not report the error of an earlier one
+				var err error
 				logger.Infof(ctx, "[Agent][Round-%d][Tool-%d/%d] Tool: %s, ID: %s",
 					state.CurrentRound+1, i+1, len(response.ToolCalls), tc.Function.Name, tc.ID)
 
@@ -330,6 +360,9 @@ func (e *AgentEngine) executeLoop(
 				if err := json.Unmarshal([]byte(tc.Function.Arguments), &args); err != nil {
 					logger.Errorf(ctx, "[Agent][Round-%d][Tool-%d/%d] Failed to parse tool arguments: %v",
 						state.CurrentRound+1, i+1, len(response.ToolCalls), err)
+					e.trace.addToolCall(tc, &types.ToolCall{
+						Result: &types.ToolResult{Error: fmt.Sprintf("invalid arguments: %v", err)},
+					}, false)
 					continue
 				}
 
@@ -352,6 +385,14 @@ func (e *AgentEngine) executeLoop(
 				})
 				logger.Debugf(ctx, "[Agent] ToolCall -> %s args=%s", tc.Function.Name, tc.Function.Arguments)
 
+				// Pause for user approval if the tool is marked as sensitive
+				result, approved := e.awaitToolApproval(
+					ctx, tc.ID, tc.Function.Name, args, state.CurrentRound, sessionID, messageID,
+				)
+				if approved {
+					toolCallStartTime = time.Now()
+				}
+
 				// Execute tool
 				logger.Infof(ctx, "[Agent][Round-%d][Tool-%d/%d] Executing tool: %s...",
 					state.CurrentRound+1, i+1, len(response.ToolCalls), tc.Function.Name)
@@ -362,7 +403,11 @@ func (e *AgentEngine) executeLoop(
 					"tool_call_id": tc.ID,
 					"tool_index":   fmt.Sprintf("%d/%d", i+1, len(response.ToolCalls)),
 				})
-				result, err := e.toolRegistry.ExecuteTool(ctx, tc.Function.Name, json.RawMessage(tc.Function.Arguments))
+				if approved {
+					// Tools running nested work (e.g. sub-agents) tag their events with the tool call ID
+					toolCtx := context.WithValue(ctx, types.ToolCallIDContextKey, tc.ID)
+					result, err = e.toolRegistry.ExecuteTool(toolCtx, tc.Function.Name, json.RawMessage(tc.Function.Arguments))
+				}
 				duration := time.Since(toolCallStartTime).Milliseconds()
 				logger.Infof(ctx, "[Agent][Round-%d][Tool-%d/%d] Tool execution completed in %dms",
 					state.CurrentRound+1, i+1, len(response.ToolCalls), duration)
@@ -385,6 +430,14 @@ func (e *AgentEngine) executeLoop(
 				}
 
 				toolSuccess := toolCall.Result != nil && toolCall.Result.Success
+				toolResult := metrics.ResultSuccess
+				if !approved {
+					toolResult = metrics.ResultRejected
+				} else if !toolSuccess {
+					toolResult = metrics.ResultError
+				}
+				metrics.ObserveToolCall(e.toolRegistry.ToolKind(tc.Function.Name), tc.Function.Name, toolResult,
+					time.Since(toolCallStartTime))
 				pipelineFields := map[string]interface{}{
 					"iteration":    state.CurrentRound,
 					"round":        state.CurrentRound + 1,
@@ -437,6 +490,7 @@ func (e *AgentEngine) executeLoop(
 
 				// Store tool call (Observations are now derived from ToolCall.Result.Output)
 				step.ToolCalls = append(step.ToolCalls, toolCall)
+				e.trace.addToolCall(tc, &toolCall, approved)
 
 				// Emit tool result event (include structured data from tool result)
 				e.eventBus.Emit(ctx, event.Event{
@@ -500,6 +554,8 @@ func (e *AgentEngine) executeLoop(
 			"tool_calls":  len(step.ToolCalls),
 			"thought_len": len(step.Thought),
 		})
+		e.trace.endRound()
+		metrics.ObserveAgentRound(time.Since(roundStart))
 		// 5. Check if we should continue
 		state.CurrentRound++
 	}
@@ -523,6 +579,33 @@ func (e *AgentEngine) executeLoop(
 		state.IsComplete = true
 	}
 
+	metrics.ObserveAgentRun(len(state.RoundSteps))
+
+	state.KnowledgeRefs = citations.References()
+	var citationReport *types.CitationReport
+	if len(state.KnowledgeRefs) > 0 {
+		e.eventBus.Emit(ctx, event.Event{
+			ID:        generateEventID("references"),
+			Type:      event.EventAgentReferences,
+			SessionID: sessionID,
+			Data: event.AgentReferencesData{
+				References: state.KnowledgeRefs,
+				Iteration:  state.CurrentRound,
+			},
+		})
+		if len(e.config.OutputSchema) == 0 {
+			var verifier chat.Chat
+			if e.config.CitationModelCheck {
+				verifier = e.chatModel
+			}
+			citationReport = common.CheckCitations(ctx, state.FinalAnswer, state.KnowledgeRefs, verifier)
+		}
+	}
+
+	if len(e.config.OutputSchema) > 0 {
+		emitStructuredAnswer(ctx, e.chatModel, e.eventBus, e.config.OutputSchema, sessionID, query, state.FinalAnswer)
+	}
+
 	// Emit completion event
 	// Convert knowledge refs to interface{} slice for event data
 	knowledgeRefsInterface := make([]interface{}, 0, len(state.KnowledgeRefs))
@@ -541,6 +624,7 @@ func (e *AgentEngine) executeLoop(
 			TotalSteps:      len(state.RoundSteps),
 			TotalDurationMs: time.Since(startTime).Milliseconds(),
 			MessageID:       messageID, // Include message ID for proper message update
+			Citations:       citationReport,
 		},
 	})
 
@@ -640,24 +724,30 @@ func (e *AgentEngine) appendToolResults(
 }
 
 // streamLLMToEventBus streams LLM response through EventBus (generic method)
+// stage, iteration: identify the model call in the trace of the run
 // emitFunc: callback to emit each chunk event
 // Returns: full accumulated content, tool calls (if any), error
 func (e *AgentEngine) streamLLMToEventBus(
 	ctx context.Context,
+	stage string,
+	iteration int,
 	messages []chat.Message,
 	opts *chat.ChatOptions,
 	emitFunc func(chunk *types.StreamResponse, fullContent string),
 ) (string, []types.LLMToolCall, error) {
 	logger.Debugf(ctx, "[Agent][Stream] Starting LLM stream with %d messages", len(messages))
 
+	traceIndex := e.trace.beginModelCall(stage, iteration, messages, opts)
 	stream, err := e.chatModel.ChatStream(ctx, messages, opts)
 	if err != nil {
 		logger.Errorf(ctx, "[Agent][Stream] Failed to start LLM stream: %v", err)
+		e.trace.endModelCall(traceIndex, "", nil, nil, err)
 		return "", nil, err
 	}
 
 	fullContent := ""
 	var toolCalls []types.LLMToolCall
+	var usage *types.TokenUsage
 	chunkCount := 0
 
 	for chunk := range stream {
@@ -671,6 +761,9 @@ func (e *AgentEngine) streamLLMToEventBus(
 		if len(chunk.ToolCalls) > 0 {
 			toolCalls = chunk.ToolCalls
 		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
 
 		// Emit event through callback
 		if emitFunc != nil {
@@ -678,6 +771,7 @@ func (e *AgentEngine) streamLLMToEventBus(
 		}
 	}
 
+	e.trace.endModelCall(traceIndex, fullContent, toolCalls, usage, nil)
 	return fullContent, toolCalls, nil
 }
 
@@ -709,6 +803,8 @@ func (e *AgentEngine) streamReflectionToEventBus(
 
 	fullReflection, _, err := e.streamLLMToEventBus(
 		ctx,
+		types.AgentTraceStageReflection,
+		iteration,
 		messages,
 		&chat.ChatOptions{Temperature: 0.5},
 		func(chunk *types.StreamResponse, fullContent string) {
@@ -761,6 +857,8 @@ func (e *AgentEngine) streamThinkingToEventBus(
 
 	fullContent, toolCalls, err := e.streamLLMToEventBus(
 		ctx,
+		types.AgentTraceStageThink,
+		iteration,
 		messages,
 		opts,
 		func(chunk *types.StreamResponse, fullContent string) {
@@ -834,12 +932,15 @@ func (e *AgentEngine) streamFinalAnswerToEventBus(
 	})
 
 	// Build messages with all context
-	systemPrompt := BuildSystemPrompt(
-		e.knowledgeBasesInfo,
-		e.config.WebSearchEnabled,
-		e.selectedDocs,
-		e.systemPromptTemplate,
-	)
+	systemPrompt := e.fixedSystemPrompt
+	if systemPrompt == "" {
+		systemPrompt = e.withPinnedContext(BuildSystemPrompt(
+			e.knowledgeBasesInfo,
+			e.config.WebSearchEnabled,
+			e.selectedDocs,
+			e.systemPromptTemplate,
+		))
+	}
 
 	messages := []chat.Message{
 		{Role: "system", Content: systemPrompt},
@@ -887,6 +988,8 @@ func (e *AgentEngine) streamFinalAnswerToEventBus(
 
 	fullAnswer, _, err := e.streamLLMToEventBus(
 		ctx,
+		types.AgentTraceStageFinalAnswer,
+		state.CurrentRound,
 		messages,
 		&chat.ChatOptions{Temperature: e.config.Temperature, Thinking: e.config.Thinking},
 		func(chunk *types.StreamResponse, fullContent string) {
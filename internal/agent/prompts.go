@@ -481,7 +481,7 @@ For every retrieval attempt (Phase 1 or Phase 3), follow this exact chain:
 
 ### Final Output Standards
 *   **Definitive:** Based strictly on the "Deep Read" content.
-*   **Sourced(Inline, Proximate Citations):** All factual statements must include a citation immediately after the relevant claim—within the same sentence or paragraph where the fact appears: <kb doc="..." chunk_id="..." /> or <web url="..." title="..." /> (if from web).
+*   **Sourced(Inline, Proximate Citations):** All factual statements must include a citation immediately after the relevant claim—within the same sentence or paragraph where the fact appears: the citation number shown with the knowledge base result or chunk, e.g. [1] or [1][3], or <web url="..." title="..." /> (if from web). Only use citation numbers returned by the tools.
 	Citations may not be placed at the end of the answer. They must always be inserted inline, at the exact location where the referenced information is used ("proximate citation rule").
 *   **Structured:** Clear hierarchy and logic.
 *   **Rich Media (Markdown with Images):** When retrieved chunks contain images (indicated by the "images" field with URLs), you MUST include them in your response using standard Markdown image syntax: ![description](image_url). Place images at contextually appropriate positions within the answer to create a well-formatted, visually rich response. Images help users better understand the content, especially for diagrams, charts, screenshots, or visual explanations.
@@ -0,0 +1,175 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/Tencent/WeKnora/internal/common"
+	"github.com/Tencent/WeKnora/internal/models/chat"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// judgePrompt asks the judge model whether an answer meets the criteria of an assertion
+const judgePrompt = `You are evaluating the answer of an AI assistant in a regression test.
+Decide whether the answer meets the criteria. Judge only against the criteria, not against your own knowledge.
+Reply with a JSON object: {"passed": true or false, "reason": "one sentence explaining the decision"}.`
+
+// judgeSchema is the output schema of the judge model
+var judgeSchema = types.OutputSchema{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"passed": map[string]interface{}{"type": "boolean"},
+		"reason": map[string]interface{}{"type": "string"},
+	},
+	"required": []interface{}{"passed", "reason"},
+}
+
+// TestOutcome is what the assertions of a test case check of an agent run
+type TestOutcome struct {
+	Answer string
+	// Tools called, in call order
+	ToolCalls []string
+	// Knowledge referenced by the run, deduplicated
+	KnowledgeIDs []string
+	Rounds       int
+}
+
+// NewTestOutcome collects the outcome of a run from its final state. The knowledge referenced by
+// the run is the knowledge found by its knowledge searches and the references of the state.
+func NewTestOutcome(state *types.AgentState) *TestOutcome {
+	outcome := &TestOutcome{
+		Answer:       state.FinalAnswer,
+		ToolCalls:    make([]string, 0),
+		KnowledgeIDs: make([]string, 0),
+		Rounds:       len(state.RoundSteps),
+	}
+	addKnowledge := func(id string) {
+		if id != "" && !slices.Contains(outcome.KnowledgeIDs, id) {
+			outcome.KnowledgeIDs = append(outcome.KnowledgeIDs, id)
+		}
+	}
+	for _, step := range state.RoundSteps {
+		for _, call := range step.ToolCalls {
+			outcome.ToolCalls = append(outcome.ToolCalls, call.Name)
+			if call.Result == nil || call.Result.Data == nil || call.Result.Data["display_type"] != "search_results" {
+				continue
+			}
+			results, _ := call.Result.Data["results"].([]map[string]interface{})
+			for _, result := range results {
+				id, _ := result["knowledge_id"].(string)
+				addKnowledge(id)
+			}
+		}
+	}
+	for _, ref := range state.KnowledgeRefs {
+		addKnowledge(ref.KnowledgeID)
+	}
+	return outcome
+}
+
+// ValidateTestAssertion checks the type and value of an assertion
+func ValidateTestAssertion(assertion types.AgentTestAssertion) error {
+	if !slices.Contains(types.AgentTestAssertionTypes, assertion.Type) {
+		return fmt.Errorf("unsupported assertion type %q", assertion.Type)
+	}
+	if strings.TrimSpace(assertion.Value) == "" {
+		return fmt.Errorf("%s assertion requires a value", assertion.Type)
+	}
+	if assertion.Type == types.AgentTestAssertAnswerMatches {
+		if _, err := regexp.Compile(assertion.Value); err != nil {
+			return fmt.Errorf("invalid regular expression %q: %v", assertion.Value, err)
+		}
+	}
+	return nil
+}
+
+// EvaluateTestCase checks the assertions of a test case on the outcome of a run, followed by its
+// round limit. llm_judge assertions are judged by judge, and fail when it is nil or cannot judge.
+func EvaluateTestCase(
+	ctx context.Context,
+	judge chat.Chat,
+	testCase *types.AgentTestCase,
+	outcome *TestOutcome,
+) []types.AgentTestAssertionResult {
+	results := make([]types.AgentTestAssertionResult, 0, len(testCase.Assertions)+1)
+	for _, assertion := range testCase.Assertions {
+		result := types.AgentTestAssertionResult{Type: assertion.Type, Value: assertion.Value}
+		switch assertion.Type {
+		case types.AgentTestAssertToolCalled:
+			result.Passed = slices.Contains(outcome.ToolCalls, assertion.Value)
+			if !result.Passed {
+				result.Detail = fmt.Sprintf("tools called: %s", strings.Join(outcome.ToolCalls, ", "))
+			}
+		case types.AgentTestAssertKnowledgeCited:
+			result.Passed = slices.Contains(outcome.KnowledgeIDs, assertion.Value)
+			if !result.Passed {
+				result.Detail = fmt.Sprintf("knowledge referenced: %s", strings.Join(outcome.KnowledgeIDs, ", "))
+			}
+		case types.AgentTestAssertAnswerContains:
+			result.Passed = strings.Contains(strings.ToLower(outcome.Answer), strings.ToLower(assertion.Value))
+			if !result.Passed {
+				result.Detail = "the answer does not contain the text"
+			}
+		case types.AgentTestAssertAnswerMatches:
+			pattern, err := regexp.Compile(assertion.Value)
+			if err != nil {
+				result.Detail = err.Error()
+				break
+			}
+			result.Passed = pattern.MatchString(outcome.Answer)
+			if !result.Passed {
+				result.Detail = "the answer does not match the expression"
+			}
+		case types.AgentTestAssertLLMJudge:
+			result.Passed, result.Detail = judgeAnswer(ctx, judge, testCase.Query, outcome.Answer, assertion.Value)
+		default:
+			result.Detail = fmt.Sprintf("unsupported assertion type %q", assertion.Type)
+		}
+		results = append(results, result)
+	}
+
+	if testCase.MaxRounds > 0 {
+		result := types.AgentTestAssertionResult{
+			Type:   types.AgentTestAssertMaxRounds,
+			Value:  fmt.Sprint(testCase.MaxRounds),
+			Passed: outcome.Rounds <= testCase.MaxRounds,
+		}
+		if !result.Passed {
+			result.Detail = fmt.Sprintf("the agent took %d rounds", outcome.Rounds)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// judgeAnswer asks the judge model whether the answer to query meets the criteria,
+// and returns its decision and reasoning
+func judgeAnswer(ctx context.Context, judge chat.Chat, query, answer, criteria string) (bool, string) {
+	if judge == nil {
+		return false, "no judge model"
+	}
+	resolved, err := judgeSchema.Compile()
+	if err != nil {
+		return false, err.Error()
+	}
+	messages := []chat.Message{
+		{Role: "system", Content: judgePrompt},
+		{Role: "user", Content: fmt.Sprintf("Question:\n%s\n\nAnswer:\n%s\n\nCriteria:\n%s", query, answer, criteria)},
+	}
+	opts := &chat.ChatOptions{Temperature: 0, Format: judgeSchema.JSON()}
+	response, err := judge.Chat(ctx, messages, opts)
+	if err != nil {
+		return false, fmt.Sprintf("judge failed: %v", err)
+	}
+	value, _, err := common.RepairStructuredOutput(ctx, judge, messages, opts, resolved, response.Content)
+	if err != nil {
+		return false, fmt.Sprintf("judge failed: %v", err)
+	}
+	decision, _ := value.(map[string]interface{})
+	passed, _ := decision["passed"].(bool)
+	reason, _ := decision["reason"].(string)
+	return passed, reason
+}
@@ -0,0 +1,97 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+func TestNewTestOutcomeCollectsToolsAndKnowledge(t *testing.T) {
+	state := &types.AgentState{
+		FinalAnswer: "answer",
+		RoundSteps: []types.AgentStep{
+			{ToolCalls: []types.ToolCall{
+				{Name: "knowledge_search", Result: &types.ToolResult{Data: map[string]interface{}{
+					"display_type": "search_results",
+					"results": []map[string]interface{}{
+						{"knowledge_id": "k1"}, {"knowledge_id": "k2"}, {"knowledge_id": "k1"},
+					},
+				}}},
+			}},
+			{ToolCalls: []types.ToolCall{{Name: "thinking"}}},
+		},
+		KnowledgeRefs: []*types.SearchResult{{KnowledgeID: "k3"}},
+	}
+
+	outcome := NewTestOutcome(state)
+	if outcome.Rounds != 2 || outcome.Answer != "answer" {
+		t.Fatalf("unexpected outcome: %+v", outcome)
+	}
+	if len(outcome.ToolCalls) != 2 || outcome.ToolCalls[0] != "knowledge_search" || outcome.ToolCalls[1] != "thinking" {
+		t.Fatalf("unexpected tool calls: %v", outcome.ToolCalls)
+	}
+	if len(outcome.KnowledgeIDs) != 3 || outcome.KnowledgeIDs[2] != "k3" {
+		t.Fatalf("unexpected knowledge: %v", outcome.KnowledgeIDs)
+	}
+}
+
+func TestEvaluateTestCase(t *testing.T) {
+	testCase := &types.AgentTestCase{
+		Query: "What is WeKnora?",
+		Assertions: types.AgentTestAssertions{
+			{Type: types.AgentTestAssertToolCalled, Value: "thinking"},
+			{Type: types.AgentTestAssertKnowledgeCited, Value: "k2"},
+			{Type: types.AgentTestAssertAnswerContains, Value: "weknora"},
+			{Type: types.AgentTestAssertAnswerMatches, Value: `retrieval (framework|system)`},
+			{Type: types.AgentTestAssertLLMJudge, Value: "says it is a framework"},
+		},
+		MaxRounds: 1,
+	}
+	outcome := &TestOutcome{
+		Answer:       "WeKnora is a retrieval framework.",
+		ToolCalls:    []string{"thinking"},
+		KnowledgeIDs: []string{"k1"},
+		Rounds:       2,
+	}
+	judge := newScriptedChat(`{"passed": true, "reason": "it says so"}`)
+
+	results := EvaluateTestCase(context.Background(), judge, testCase, outcome)
+	expected := []bool{true, false, true, true, true, false}
+	if len(results) != len(expected) {
+		t.Fatalf("expected %d results, got %+v", len(expected), results)
+	}
+	for i, result := range results {
+		if result.Passed != expected[i] {
+			t.Errorf("assertion %s: expected passed=%v, got %+v", result.Type, expected[i], result)
+		}
+	}
+	if results[4].Detail != "it says so" {
+		t.Errorf("unexpected judge reason: %q", results[4].Detail)
+	}
+	if results[5].Type != types.AgentTestAssertMaxRounds || results[5].Detail != "the agent took 2 rounds" {
+		t.Errorf("unexpected round limit result: %+v", results[5])
+	}
+
+	// Without a judge model, llm_judge assertions fail
+	results = EvaluateTestCase(context.Background(), nil, testCase, outcome)
+	if results[4].Passed || results[4].Detail != "no judge model" {
+		t.Errorf("unexpected result without judge: %+v", results[4])
+	}
+}
+
+func TestValidateTestAssertion(t *testing.T) {
+	invalid := []types.AgentTestAssertion{
+		{Type: "unknown", Value: "x"},
+		{Type: types.AgentTestAssertAnswerContains, Value: " "},
+		{Type: types.AgentTestAssertAnswerMatches, Value: "("},
+	}
+	for _, assertion := range invalid {
+		if err := ValidateTestAssertion(assertion); err == nil {
+			t.Errorf("expected %+v to be invalid", assertion)
+		}
+	}
+	if err := ValidateTestAssertion(types.AgentTestAssertion{Type: types.AgentTestAssertToolCalled, Value: "thinking"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,187 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/Tencent/WeKnora/internal/agent/tools"
+	"github.com/Tencent/WeKnora/internal/models/chat"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// ErrTraceNotReplayable is returned for traces without a recorded think round to start from
+var ErrTraceNotReplayable = errors.New("trace has no model request to replay")
+
+// ReplayTrace re-executes a recorded run against chatModel, starting from the recorded first request.
+// Tool calls are answered with the recorded results instead of running the tools, so that the
+// replay only differs from the recording by the model, the system prompt and the temperature.
+// Returns the trace of the replay, which also reports the error of a failed replay.
+func ReplayTrace(
+	ctx context.Context,
+	recorded *types.AgentTrace,
+	chatModel chat.Chat,
+	req *types.AgentTraceReplayRequest,
+) (*types.AgentTrace, error) {
+	var first *types.AgentTraceRound
+	thinkRounds := 0
+	for i := range recorded.Rounds {
+		if recorded.Rounds[i].Stage != types.AgentTraceStageThink {
+			continue
+		}
+		if first == nil {
+			first = &recorded.Rounds[i]
+		}
+		thinkRounds++
+	}
+	if first == nil {
+		return nil, ErrTraceNotReplayable
+	}
+
+	systemPrompt := recorded.SystemPrompt
+	if req.SystemPrompt != nil {
+		systemPrompt = *req.SystemPrompt
+	}
+	messages := fromTraceMessages(first.Messages)
+	for i := range messages {
+		if messages[i].Role == "system" {
+			messages[i].Content = systemPrompt
+		}
+	}
+
+	config := &types.AgentConfig{
+		MaxIterations: recorded.MaxIterations,
+		Temperature:   first.Options.Temperature,
+		Thinking:      first.Options.Thinking,
+	}
+	if req.Temperature != nil {
+		config.Temperature = *req.Temperature
+	}
+	if req.MaxIterations > 0 {
+		config.MaxIterations = req.MaxIterations
+	}
+	if config.MaxIterations <= 0 {
+		config.MaxIterations = thinkRounds
+	}
+
+	registry := tools.NewToolRegistry()
+	chatTools := make([]chat.Tool, 0, len(recorded.Tools))
+	results := newRecordedToolResults(recorded)
+	for _, def := range recorded.Tools {
+		registry.RegisterTool(&recordedTool{
+			BaseTool: tools.NewBaseTool(def.Name, def.Description, def.Parameters),
+			results:  results,
+		})
+		chatTools = append(chatTools, chat.Tool{
+			Type: "function",
+			Function: chat.FunctionDef{
+				Name:        def.Name,
+				Description: def.Description,
+				Parameters:  def.Parameters,
+			},
+		})
+	}
+
+	var replayed *types.AgentTrace
+	engine := NewAgentEngine(config, chatModel, registry, nil, nil, nil, nil, recorded.SessionID, "")
+	engine.fixedSystemPrompt = systemPrompt
+	engine.SetTraceSink(func(ctx context.Context, trace *types.AgentTrace) {
+		replayed = trace
+	})
+
+	state := &types.AgentState{
+		RoundSteps:    []types.AgentStep{},
+		KnowledgeRefs: []*types.SearchResult{},
+	}
+	engine.startTrace(recorded.SessionID, recorded.MessageID, recorded.Query, systemPrompt, chatTools)
+	_, err := engine.executeLoop(ctx, state, recorded.Query, messages, chatTools, recorded.SessionID, recorded.MessageID)
+	engine.finishTrace(ctx, state, err)
+	replayed.ReplayOf = recorded.ID
+	return replayed, nil
+}
+
+// recordedToolResults hands out the recorded results of the tool calls of a run
+type recordedToolResults struct {
+	// Recorded calls per tool name, in call order
+	calls map[string][]*types.AgentTraceToolCall
+	used  map[*types.AgentTraceToolCall]bool
+}
+
+// newRecordedToolResults collects the executed tool calls of a trace
+func newRecordedToolResults(trace *types.AgentTrace) *recordedToolResults {
+	results := &recordedToolResults{
+		calls: make(map[string][]*types.AgentTraceToolCall),
+		used:  make(map[*types.AgentTraceToolCall]bool),
+	}
+	for i := range trace.Rounds {
+		round := &trace.Rounds[i]
+		for j := range round.ToolCalls {
+			call := &round.ToolCalls[j]
+			if call.Executed {
+				results.calls[call.Name] = append(results.calls[call.Name], call)
+			}
+		}
+	}
+	return results
+}
+
+// take returns the unused recorded call of a tool with the same arguments, or else the first unused
+// call of the tool, so that a replay asking slightly different questions still gets an answer
+func (r *recordedToolResults) take(name string, args json.RawMessage) *types.AgentTraceToolCall {
+	want := canonicalArguments(string(args))
+	var fallback *types.AgentTraceToolCall
+	for _, call := range r.calls[name] {
+		if r.used[call] {
+			continue
+		}
+		if canonicalArguments(call.Arguments) == want {
+			r.used[call] = true
+			return call
+		}
+		if fallback == nil {
+			fallback = call
+		}
+	}
+	if fallback != nil {
+		r.used[fallback] = true
+	}
+	return fallback
+}
+
+// recordedTool stands in for a tool of a recorded run and answers with the recorded results
+type recordedTool struct {
+	tools.BaseTool
+	results *recordedToolResults
+}
+
+// Execute implements types.Tool
+func (t *recordedTool) Execute(ctx context.Context, args json.RawMessage) (*types.ToolResult, error) {
+	call := t.results.take(t.Name(), args)
+	if call == nil {
+		return &types.ToolResult{
+			Success: false,
+			Error:   fmt.Sprintf("no recorded result left for tool %s", t.Name()),
+		}, nil
+	}
+	return &types.ToolResult{
+		Success: call.Success,
+		Output:  call.Output,
+		Data:    call.Data,
+		Error:   call.Error,
+	}, nil
+}
+
+// canonicalArguments normalizes tool call arguments so that equal arguments compare equal
+// regardless of key order and whitespace
+func canonicalArguments(arguments string) string {
+	var value interface{}
+	if err := json.Unmarshal([]byte(arguments), &value); err != nil {
+		return arguments
+	}
+	normalized, err := json.Marshal(value)
+	if err != nil {
+		return arguments
+	}
+	return string(normalized)
+}
@@ -0,0 +1,92 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/agent/tools"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// replayTestTool counts its executions
+type replayTestTool struct {
+	calls int
+}
+
+func (t *replayTestTool) Name() string                { return "lookup" }
+func (t *replayTestTool) Description() string         { return "Look up a term" }
+func (t *replayTestTool) Parameters() json.RawMessage { return json.RawMessage(`{"type":"object"}`) }
+
+func (t *replayTestTool) Execute(ctx context.Context, args json.RawMessage) (*types.ToolResult, error) {
+	t.calls++
+	return &types.ToolResult{Success: true, Output: "WeKnora is a RAG framework"}, nil
+}
+
+func scriptedRun(answer string) *scriptedChat {
+	return &scriptedChat{responses: []types.StreamResponse{
+		{
+			ToolCalls: []types.LLMToolCall{{
+				ID:       "call-1",
+				Type:     "function",
+				Function: types.FunctionCall{Name: "lookup", Arguments: `{"term": "WeKnora"}`},
+			}},
+			Usage: &types.TokenUsage{PromptTokens: 100, CompletionTokens: 10, TotalTokens: 110},
+		},
+		{
+			Content: answer,
+			Usage:   &types.TokenUsage{PromptTokens: 150, CompletionTokens: 20, TotalTokens: 170},
+		},
+	}}
+}
+
+func TestReplayTraceUsesRecordedToolResults(t *testing.T) {
+	tool := &replayTestTool{}
+	registry := tools.NewToolRegistry()
+	registry.RegisterTool(tool)
+
+	var recorded *types.AgentTrace
+	config := &types.AgentConfig{MaxIterations: 5, Temperature: 0.3}
+	engine := NewAgentEngine(config, scriptedRun("A RAG framework."), registry, nil, nil, nil, nil, "session-1", "")
+	engine.SetTraceSink(func(ctx context.Context, trace *types.AgentTrace) { recorded = trace })
+	if _, err := engine.Execute(context.Background(), "session-1", "message-1", "What is WeKnora?", nil); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+
+	if recorded == nil || len(recorded.Rounds) != 2 {
+		t.Fatalf("expected a trace with 2 rounds, got %+v", recorded)
+	}
+	first := recorded.Rounds[0]
+	if len(first.ToolCalls) != 1 || first.ToolCalls[0].Output != "WeKnora is a RAG framework" || !first.ToolCalls[0].Executed {
+		t.Fatalf("tool call not recorded: %+v", first.ToolCalls)
+	}
+	if first.Messages[0].Role != "system" || first.Messages[0].Content != recorded.SystemPrompt {
+		t.Fatalf("system prompt not recorded in the request")
+	}
+	if recorded.Usage.TotalTokens != 280 || recorded.FinalAnswer != "A RAG framework." {
+		t.Fatalf("unexpected usage %+v or answer %q", recorded.Usage, recorded.FinalAnswer)
+	}
+
+	replayModel := scriptedRun("WeKnora is a RAG framework.")
+	prompt := "You are terse."
+	replayed, err := ReplayTrace(context.Background(), recorded, replayModel, &types.AgentTraceReplayRequest{
+		SystemPrompt: &prompt,
+	})
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if tool.calls != 1 {
+		t.Fatalf("replay must not execute tools, got %d executions", tool.calls)
+	}
+	if replayModel.requests[0][0].Content != prompt || replayed.SystemPrompt != prompt {
+		t.Fatalf("system prompt override not applied")
+	}
+	// The second request carries the recorded tool result
+	last := replayModel.requests[1][len(replayModel.requests[1])-1]
+	if last.Role != "tool" || last.Content != "WeKnora is a RAG framework" {
+		t.Fatalf("recorded tool result not replayed: %+v", last)
+	}
+	if replayed.FinalAnswer != "WeKnora is a RAG framework." || replayed.ReplayOf != recorded.ID {
+		t.Fatalf("unexpected replay %+v", replayed)
+	}
+}
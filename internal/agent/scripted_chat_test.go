@@ -0,0 +1,69 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Tencent/WeKnora/internal/models/chat"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// scriptedChat is a chat model answering each call, streamed or not, with the next scripted
+// response. It records the messages of the calls and fails the calls beyond the script.
+type scriptedChat struct {
+	responses []types.StreamResponse
+	requests  [][]chat.Message
+}
+
+// newScriptedChat returns a model answering with the given contents in order
+func newScriptedChat(contents ...string) *scriptedChat {
+	model := &scriptedChat{}
+	for _, content := range contents {
+		model.responses = append(model.responses, types.StreamResponse{Content: content})
+	}
+	return model
+}
+
+// next records a call and returns its scripted response
+func (m *scriptedChat) next(messages []chat.Message) (types.StreamResponse, error) {
+	m.requests = append(m.requests, messages)
+	if len(m.responses) == 0 {
+		return types.StreamResponse{}, fmt.Errorf("unexpected call %d to the scripted model", len(m.requests))
+	}
+	response := m.responses[0]
+	m.responses = m.responses[1:]
+	return response, nil
+}
+
+func (m *scriptedChat) Chat(ctx context.Context, messages []chat.Message, opts *chat.ChatOptions) (*types.ChatResponse, error) {
+	response, err := m.next(messages)
+	if err != nil {
+		return nil, err
+	}
+	return &types.ChatResponse{Content: response.Content, ToolCalls: response.ToolCalls}, nil
+}
+
+func (m *scriptedChat) ChatStream(ctx context.Context, messages []chat.Message, opts *chat.ChatOptions) (<-chan types.StreamResponse, error) {
+	response, err := m.next(messages)
+	if err != nil {
+		return nil, err
+	}
+	stream := make(chan types.StreamResponse, 1)
+	response.Done = true
+	stream <- response
+	close(stream)
+	return stream, nil
+}
+
+func (m *scriptedChat) GetModelName() string { return "test-model" }
+
+func (m *scriptedChat) GetModelID() string { return "test-model" }
+
+// prompts returns the last message of each call
+func (m *scriptedChat) prompts() []string {
+	prompts := make([]string, 0, len(m.requests))
+	for _, messages := range m.requests {
+		prompts = append(prompts, messages[len(messages)-1].Content)
+	}
+	return prompts
+}
@@ -0,0 +1,61 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Tencent/WeKnora/internal/common"
+	"github.com/Tencent/WeKnora/internal/event"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/models/chat"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// structuredAnswerPrompt asks the model to convert the final answer into the output schema
+const structuredAnswerPrompt = `Convert the answer below into a JSON object that conforms to the given JSON Schema.
+Only use information stated in the answer, leave optional fields out when the answer does not provide them.
+Reply with the JSON object only, without any explanation or code fences.`
+
+// emitStructuredAnswer converts the final answer into the output schema, repairing invalid output
+// with the model, and emits the outcome. The answer text itself is left unchanged.
+func emitStructuredAnswer(
+	ctx context.Context,
+	chatModel chat.Chat,
+	eventBus *event.EventBus,
+	schema types.OutputSchema,
+	sessionID, query, answer string,
+) {
+	data := event.StructuredOutputData{}
+	resolved, err := schema.Compile()
+	if err == nil && chatModel == nil {
+		err = errors.New("no chat model to convert the answer")
+	}
+	if err == nil {
+		messages := []chat.Message{
+			{Role: "system", Content: structuredAnswerPrompt},
+			{Role: "user", Content: fmt.Sprintf("Question:\n%s\n\nAnswer:\n%s", query, answer)},
+		}
+		opts := &chat.ChatOptions{Temperature: 0, Format: schema.JSON()}
+		var response *types.ChatResponse
+		if response, err = chatModel.Chat(ctx, messages, opts); err == nil {
+			data.Output, data.Raw, err = common.RepairStructuredOutput(
+				ctx, chatModel, messages, opts, resolved, response.Content,
+			)
+		}
+	}
+	if err != nil {
+		logger.Warnf(ctx, "[Agent] Structured output failed: %v", err)
+		common.PipelineWarn(ctx, "Agent", "structured_output_failed", map[string]interface{}{
+			"session_id": sessionID,
+			"error":      err.Error(),
+		})
+		data.Error = err.Error()
+	}
+	eventBus.Emit(ctx, event.Event{
+		ID:        generateEventID("structured-output"),
+		Type:      event.EventStructuredOutput,
+		SessionID: sessionID,
+		Data:      data,
+	})
+}
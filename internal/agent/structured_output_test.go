@@ -0,0 +1,44 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/event"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+func TestEmitStructuredAnswerRepairsInvalidOutput(t *testing.T) {
+	schema := types.OutputSchema{
+		"type":     "object",
+		"required": []interface{}{"severity"},
+		"properties": map[string]interface{}{
+			"severity": map[string]interface{}{"type": "string", "enum": []interface{}{"low", "high"}},
+		},
+	}
+	model := newScriptedChat(
+		`{"severity": "urgent"}`,
+		"```json\n{\"severity\": \"high\"}\n```",
+	)
+
+	eventBus := event.NewEventBus()
+	var data event.StructuredOutputData
+	eventBus.On(event.EventStructuredOutput, func(ctx context.Context, evt event.Event) error {
+		data = evt.Data.(event.StructuredOutputData)
+		return nil
+	})
+
+	emitStructuredAnswer(context.Background(), model, eventBus, schema, "s1", "How bad is it?", "It is high severity.")
+
+	if data.Error != "" || data.Raw != `{"severity":"high"}` {
+		t.Fatalf("unexpected structured output: %+v", data)
+	}
+	if len(model.requests) != 2 {
+		t.Fatalf("expected one repair call, got %d calls", len(model.requests))
+	}
+	repair := model.prompts()[1]
+	if !strings.Contains(repair, "does not conform to the schema") {
+		t.Errorf("repair prompt does not explain the error: %s", repair)
+	}
+}
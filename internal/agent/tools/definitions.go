@@ -17,6 +17,12 @@ const (
 	// Skills-related tools (only available when skills are enabled)
 	ToolExecuteSkillScript = "execute_skill_script"
 	ToolReadSkill          = "read_skill"
+	// MCP-related tools (only available when MCP services are enabled)
+	ToolReadMCPResource = "read_mcp_resource"
+	// Long-term memory tools (only available when memory is enabled)
+	ToolSaveMemory   = "save_memory"
+	ToolRecallMemory = "recall_memory"
+	ToolForgetMemory = "forget_memory"
 )
 
 // AvailableTool defines a simple tool metadata used by settings APIs.
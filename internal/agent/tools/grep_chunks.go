@@ -315,14 +315,19 @@ func (t *GrepChunksTool) searchChunks(
 	}
 
 	// Apply pattern matching (case-insensitive fixed string matching, OR logic for multiple patterns)
+	// SQLite has no ILIKE, but its LIKE is already case-insensitive for ASCII
+	likeCondition := "chunks.content ILIKE ?"
+	if t.db.Dialector.Name() == "sqlite" {
+		likeCondition = "chunks.content LIKE ?"
+	}
 	if len(patterns) == 1 {
-		query = query.Where("chunks.content ILIKE ?", "%"+patterns[0]+"%")
+		query = query.Where(likeCondition, "%"+patterns[0]+"%")
 	} else {
 		// Multiple patterns: use OR logic
 		var conditions []string
 		var args []interface{}
 		for _, pattern := range patterns {
-			conditions = append(conditions, "chunks.content ILIKE ?")
+			conditions = append(conditions, likeCondition)
 			args = append(args, "%"+pattern+"%")
 		}
 		query = query.Where("("+strings.Join(conditions, " OR ")+")", args...)
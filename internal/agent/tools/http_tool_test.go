@@ -0,0 +1,219 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+func TestHTTPToolExecute(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":"not found"}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{
+				"path":   r.URL.Path,
+				"query":  r.URL.Query(),
+				"auth":   r.Header.Get("X-Api-Key"),
+				"trace":  r.Header.Get("X-Trace"),
+				"body":   string(body),
+				"method": r.Method,
+				"items":  []map[string]string{{"name": "a"}, {"name": "b"}},
+			},
+		})
+	}))
+	defer server.Close()
+
+	def := &types.HTTPTool{
+		ID:         "tool-1",
+		Name:       "Update-Order",
+		Method:     http.MethodPost,
+		URL:        server.URL + "/orders/{order_id}",
+		Parameters: types.HTTPToolSchema(`{"type":"object","properties":{},"required":["order_id"]}`),
+		ArgLocations: types.HTTPToolStrings{
+			"tags":    types.HTTPToolArgQuery,
+			"X-Trace": types.HTTPToolArgHeader,
+		},
+		AuthHeader: "X-Api-Key",
+		AuthSecret: "secret",
+	}
+	tool := NewHTTPTool(def, server.Client(), true)
+	if tool.Name() != "http_update_order" {
+		t.Fatalf("unexpected name %q", tool.Name())
+	}
+
+	run := func(args string) map[string]interface{} {
+		t.Helper()
+		result, err := tool.Execute(context.Background(), json.RawMessage(args))
+		if err != nil || !result.Success {
+			t.Fatalf("execute failed: %v %+v", err, result)
+		}
+		output := result.Output[strings.Index(result.Output, "\n")+1:]
+		var decoded map[string]interface{}
+		if err := json.Unmarshal([]byte(output), &decoded); err != nil {
+			t.Fatalf("output is not JSON: %q", output)
+		}
+		return decoded
+	}
+
+	data := run(`{"order_id":"a/b 1","tags":["x","y"],"X-Trace":"t1","status":"paid","count":2}`)["data"].(map[string]interface{})
+	if data["path"] != "/orders/a/b 1" || data["method"] != http.MethodPost {
+		t.Errorf("unexpected request line: %v %v", data["method"], data["path"])
+	}
+	if tags := data["query"].(map[string]interface{})["tags"].([]interface{}); len(tags) != 2 {
+		t.Errorf("unexpected query tags: %v", tags)
+	}
+	if data["auth"] != "secret" || data["trace"] != "t1" {
+		t.Errorf("unexpected headers: auth=%v trace=%v", data["auth"], data["trace"])
+	}
+	if data["body"] != `{"count":2,"status":"paid"}` {
+		t.Errorf("unexpected body: %v", data["body"])
+	}
+
+	result, _ := tool.Execute(context.Background(), json.RawMessage(`{}`))
+	if result.Success || !strings.Contains(result.Error, "order_id") {
+		t.Errorf("expected missing order_id, got %+v", result)
+	}
+
+	def.ResponsePath = "$.data.items[*].name"
+	result, _ = tool.Execute(context.Background(), json.RawMessage(`{"order_id":"1"}`))
+	if !result.Success || !strings.HasSuffix(result.Output, `["a","b"]`) {
+		t.Errorf("unexpected extracted output: %+v", result)
+	}
+
+	def.URL = server.URL + "/missing"
+	def.Parameters = nil
+	result, _ = tool.Execute(context.Background(), nil)
+	if result.Success || !strings.Contains(result.Error, "HTTP 404") {
+		t.Errorf("expected HTTP 404 failure, got %+v", result)
+	}
+
+	// Loopback addresses are rejected unless private URLs are allowed
+	result, _ = NewHTTPTool(def, server.Client(), false).Execute(context.Background(), nil)
+	if result.Success || !strings.Contains(result.Error, "not allowed") {
+		t.Errorf("expected SSRF rejection, got %+v", result)
+	}
+}
+
+func TestHTTPToolRedirectDropsAuthForOtherHosts(t *testing.T) {
+	received := make(map[string]http.Header)
+	record := func(w http.ResponseWriter, r *http.Request) {
+		received[r.Host+r.URL.Path] = r.Header.Clone()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}
+	other := httptest.NewServer(http.HandlerFunc(record))
+	defer other.Close()
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/same":
+			http.Redirect(w, r, "/final", http.StatusFound)
+		case "/other":
+			http.Redirect(w, r, other.URL+"/final", http.StatusFound)
+		default:
+			record(w, r)
+		}
+	}))
+	defer origin.Close()
+	originHost := strings.TrimPrefix(origin.URL, "http://")
+	otherHost := strings.TrimPrefix(other.URL, "http://")
+
+	tests := []struct {
+		name       string
+		path       string
+		authHeader string
+		header     string
+		finalHost  string
+		wantAuth   bool
+	}{
+		{name: "custom header, same host", path: "/same", authHeader: "X-Api-Key", header: "X-Api-Key", finalHost: originHost, wantAuth: true},
+		{name: "custom header, other host", path: "/other", authHeader: "X-Api-Key", header: "X-Api-Key", finalHost: otherHost},
+		{name: "bearer, same host", path: "/same", header: "Authorization", finalHost: originHost, wantAuth: true},
+		{name: "bearer, other port of the same hostname", path: "/other", header: "Authorization", finalHost: otherHost},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clear(received)
+			def := &types.HTTPTool{
+				Name:       "redirect",
+				Method:     http.MethodGet,
+				URL:        origin.URL + tt.path,
+				Headers:    types.HTTPToolStrings{"X-Trace": "t1"},
+				AuthHeader: tt.authHeader,
+				AuthSecret: "secret",
+			}
+			result, err := NewHTTPTool(def, origin.Client(), true).Execute(context.Background(), nil)
+			if err != nil || !result.Success {
+				t.Fatalf("execute failed: %v %+v", err, result)
+			}
+			header, ok := received[tt.finalHost+"/final"]
+			if !ok {
+				t.Fatalf("redirect target not called, got %v", received)
+			}
+			if got := header.Get(tt.header) == "secret"; got != tt.wantAuth {
+				t.Errorf("auth header sent to %s: %v, want %v", tt.finalHost, got, tt.wantAuth)
+			}
+			if header.Get("X-Trace") != "t1" {
+				t.Errorf("other headers should be kept, got %v", header)
+			}
+		})
+	}
+
+	// The redirect policy of the client still applies
+	client := origin.Client()
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	def := &types.HTTPTool{Name: "redirect", URL: origin.URL + "/other", AuthSecret: "secret"}
+	result, _ := NewHTTPTool(def, client, true).Execute(context.Background(), nil)
+	if result.Data["status_code"] != http.StatusFound {
+		t.Errorf("expected the redirect response, got %+v", result)
+	}
+}
+
+func TestExtractJSONPath(t *testing.T) {
+	var data interface{}
+	_ = json.Unmarshal([]byte(`{"a":{"b":[{"c":1},{"c":2}],"key with space":"v"}}`), &data)
+
+	cases := []struct {
+		path string
+		want string
+	}{
+		{"$", `{"a":{"b":[{"c":1},{"c":2}],"key with space":"v"}}`},
+		{"$.a.b[0].c", `1`},
+		{"$.a.b[-1].c", `2`},
+		{"$.a.b[*].c", `[1,2]`},
+		{"$['a']['key with space']", `"v"`},
+	}
+	for _, c := range cases {
+		value, err := ExtractJSONPath(data, c.path)
+		if err != nil {
+			t.Errorf("%s: %v", c.path, err)
+			continue
+		}
+		got, _ := json.Marshal(value)
+		if string(got) != c.want {
+			t.Errorf("%s: got %s, want %s", c.path, got, c.want)
+		}
+	}
+
+	if _, err := ExtractJSONPath(data, "$.a.missing"); err == nil {
+		t.Error("expected an error for a missing key")
+	}
+	for _, path := range []string{"a.b", "$..b", "$.a[", "$.a[?(@.c)]"} {
+		if err := ValidateJSONPath(path); err == nil {
+			t.Errorf("expected %q to be rejected", path)
+		}
+	}
+}
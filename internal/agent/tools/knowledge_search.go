@@ -1016,6 +1016,9 @@ func (t *KnowledgeSearchTool) formatOutput(
 	knowledgeTotalMap := make(map[string]int64)        // knowledge_id -> total chunks
 	knowledgeTitleMap := make(map[string]string)       // knowledge_id -> title
 
+	// Citation numbers the final answer cites the results with
+	citations := types.CitationIndexFromContext(ctx)
+
 	for i, result := range results {
 		var faqMeta *types.FAQChunkMetadata
 		if result.KnowledgeBaseType == types.KnowledgeBaseTypeFAQ {
@@ -1077,7 +1080,12 @@ func (t *KnowledgeSearchTool) formatOutput(
 		}
 
 		// relevanceLevel := GetRelevanceLevel(result.Score)
-		output += fmt.Sprintf("\nResult #%d:\n", i+1)
+		citation := citations.Add(result.SearchResult)
+		if citation > 0 {
+			output += fmt.Sprintf("\nResult #%d (cite as [%d]):\n", i+1, citation)
+		} else {
+			output += fmt.Sprintf("\nResult #%d:\n", i+1)
+		}
 		output += fmt.Sprintf(
 			"  [chunk_id: %s][chunk_index: %d]\nContent: %s\n",
 			result.ID,
@@ -1135,6 +1143,9 @@ func (t *KnowledgeSearchTool) formatOutput(
 		})
 
 		last := formattedResults[len(formattedResults)-1]
+		if citation > 0 {
+			last["citation"] = citation
+		}
 
 		// 添加图片信息到结构化数据
 		if result.ImageInfo != "" {
@@ -162,7 +162,25 @@ func (t *ListKnowledgeChunksTool) Execute(ctx context.Context, args json.RawMess
 
 	knowledgeTitle := t.lookupKnowledgeTitle(ctx, knowledgeID)
 
-	output := t.buildOutput(knowledgeID, knowledgeTitle, totalChunks, fetched, chunks)
+	// Number the chunks so that the final answer can cite them as [n]
+	citationIndex := types.CitationIndexFromContext(ctx)
+	citations := make([]int, len(chunks))
+	for idx, c := range chunks {
+		citations[idx] = citationIndex.Add(&types.SearchResult{
+			ID:             c.ID,
+			Content:        c.Content,
+			KnowledgeID:    c.KnowledgeID,
+			ChunkIndex:     c.ChunkIndex,
+			KnowledgeTitle: knowledgeTitle,
+			StartAt:        c.StartAt,
+			EndAt:          c.EndAt,
+			ChunkType:      string(c.ChunkType),
+			ParentChunkID:  c.ParentChunkID,
+			ImageInfo:      c.ImageInfo,
+		})
+	}
+
+	output := t.buildOutput(knowledgeID, knowledgeTitle, totalChunks, fetched, chunks, citations)
 
 	formattedChunks := make([]map[string]interface{}, 0, len(chunks))
 	for idx, c := range chunks {
@@ -178,6 +196,9 @@ func (t *ListKnowledgeChunksTool) Execute(ctx context.Context, args json.RawMess
 			"end_at":          c.EndAt,
 			"parent_chunk_id": c.ParentChunkID,
 		}
+		if citations[idx] > 0 {
+			chunkData["citation"] = citations[idx]
+		}
 
 		// 添加图片信息
 		if c.ImageInfo != "" {
@@ -243,6 +264,7 @@ func (t *ListKnowledgeChunksTool) buildOutput(
 	total int64,
 	fetched int,
 	chunks []*types.Chunk,
+	citations []int,
 ) string {
 	builder := &strings.Builder{}
 	builder.WriteString("=== 知识文档分块 ===\n\n")
@@ -273,6 +295,9 @@ func (t *ListKnowledgeChunksTool) buildOutput(
 	for idx, c := range chunks {
 		fmt.Fprintf(builder, "Chunk #%d (Index %d)\n", idx+1, c.ChunkIndex+1)
 		fmt.Fprintf(builder, "  chunk_id: %s\n", c.ID)
+		if citations[idx] > 0 {
+			fmt.Fprintf(builder, "  引用编号: [%d]\n", citations[idx])
+		}
 		fmt.Fprintf(builder, "  类型: %s\n", c.ChunkType)
 		fmt.Fprintf(builder, "  内容: %s\n", summarizeContent(c.Content))
 
@@ -41,6 +41,11 @@ func (t *MCPTool) Name() string {
 	return fmt.Sprintf("mcp_%s_%s", serviceID, toolName)
 }
 
+// ServiceID returns the ID of the MCP service that provides this tool
+func (t *MCPTool) ServiceID() string {
+	return t.service.ID
+}
+
 // Description returns the tool description.
 // Prefix indicates external/untrusted source to reduce indirect prompt injection impact.
 func (t *MCPTool) Description() string {
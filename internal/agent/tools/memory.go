@@ -0,0 +1,235 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"github.com/Tencent/WeKnora/internal/utils"
+)
+
+const (
+	defaultMemoryRecallLimit = 5
+	maxMemoryRecallLimit     = 20
+)
+
+var saveMemoryTool = BaseTool{
+	name: ToolSaveMemory,
+	description: `Save a durable fact or preference about the user to long-term memory, so that it is remembered in future conversations.
+
+## When to Use
+- The user explicitly asks you to remember something
+- The user shares a stable preference, role, goal or constraint (e.g. preferred language, team, project, output format)
+
+## When NOT to Use
+- One-off details that only matter for the current question
+- Secrets such as passwords, API keys or tokens
+- Facts already listed in the User Memory section of the system prompt
+
+## Notes
+- Write one self-contained fact per call, in third person (e.g. "The user prefers answers in English")
+- Saving the same content again only refreshes it`,
+	schema: utils.GenerateSchema[SaveMemoryInput](),
+}
+
+var recallMemoryTool = BaseTool{
+	name: ToolRecallMemory,
+	description: `Search the user's long-term memory for facts and preferences saved in earlier conversations.
+
+## When to Use
+- The answer depends on something the user may have told you before and it is not in the User Memory section of the system prompt
+- The user asks what you remember about them`,
+	schema: utils.GenerateSchema[RecallMemoryInput](),
+}
+
+var forgetMemoryTool = BaseTool{
+	name: ToolForgetMemory,
+	description: `Delete a memory from the user's long-term memory.
+
+## When to Use
+- The user asks you to forget something
+- A saved memory is outdated or contradicted by the user; forget it and save the corrected fact
+
+Use the memory ID shown in the User Memory section or returned by recall_memory.`,
+	schema: utils.GenerateSchema[ForgetMemoryInput](),
+}
+
+// SaveMemoryInput defines the input parameters for the save_memory tool
+type SaveMemoryInput struct {
+	Content string `json:"content" jsonschema:"The fact or preference to remember, one self-contained sentence"`
+}
+
+// RecallMemoryInput defines the input parameters for the recall_memory tool
+type RecallMemoryInput struct {
+	Query string `json:"query" jsonschema:"What to look for in the user's memory"`
+	Limit int    `json:"limit,omitempty" jsonschema:"Maximum number of memories to return, default 5, max 20"`
+}
+
+// ForgetMemoryInput defines the input parameters for the forget_memory tool
+type ForgetMemoryInput struct {
+	MemoryID string `json:"memory_id" jsonschema:"ID of the memory to delete"`
+}
+
+// memoryToolContext is shared by the memory tools of an agent
+type memoryToolContext struct {
+	memoryService    interfaces.UserMemoryService
+	agentID          string
+	sessionID        string
+	embeddingModelID string
+}
+
+// SaveMemoryTool saves facts about the user to long-term memory
+type SaveMemoryTool struct {
+	BaseTool
+	memoryToolContext
+}
+
+// RecallMemoryTool searches the long-term memory of the user
+type RecallMemoryTool struct {
+	BaseTool
+	memoryToolContext
+}
+
+// ForgetMemoryTool deletes a memory of the user
+type ForgetMemoryTool struct {
+	BaseTool
+	memoryToolContext
+}
+
+// NewMemoryTools creates the save_memory, recall_memory and forget_memory tools
+func NewMemoryTools(
+	memoryService interfaces.UserMemoryService,
+	agentID string,
+	sessionID string,
+	embeddingModelID string,
+) []types.Tool {
+	mc := memoryToolContext{
+		memoryService:    memoryService,
+		agentID:          agentID,
+		sessionID:        sessionID,
+		embeddingModelID: embeddingModelID,
+	}
+	return []types.Tool{
+		&SaveMemoryTool{BaseTool: saveMemoryTool, memoryToolContext: mc},
+		&RecallMemoryTool{BaseTool: recallMemoryTool, memoryToolContext: mc},
+		&ForgetMemoryTool{BaseTool: forgetMemoryTool, memoryToolContext: mc},
+	}
+}
+
+// Execute saves a memory
+func (t *SaveMemoryTool) Execute(ctx context.Context, args json.RawMessage) (*types.ToolResult, error) {
+	var input SaveMemoryInput
+	if err := json.Unmarshal(args, &input); err != nil {
+		return &types.ToolResult{
+			Success: false,
+			Error:   fmt.Sprintf("Failed to parse args: %v", err),
+		}, err
+	}
+
+	memory, err := t.memoryService.SaveMemory(ctx, input.Content, t.agentID, t.sessionID, t.embeddingModelID)
+	if err != nil {
+		logger.Warnf(ctx, "[Tool][SaveMemory] Failed to save memory: %v", err)
+		return &types.ToolResult{
+			Success: false,
+			Error:   fmt.Sprintf("Failed to save memory: %v", err),
+		}, nil
+	}
+
+	return &types.ToolResult{
+		Success: true,
+		Output:  fmt.Sprintf("Saved to long-term memory (id: %s): %s", memory.ID, memory.Content),
+		Data: map[string]interface{}{
+			"memory_id": memory.ID,
+			"content":   memory.Content,
+		},
+	}, nil
+}
+
+// Execute recalls memories relevant to the query
+func (t *RecallMemoryTool) Execute(ctx context.Context, args json.RawMessage) (*types.ToolResult, error) {
+	var input RecallMemoryInput
+	if err := json.Unmarshal(args, &input); err != nil {
+		return &types.ToolResult{
+			Success: false,
+			Error:   fmt.Sprintf("Failed to parse args: %v", err),
+		}, err
+	}
+	limit := input.Limit
+	if limit <= 0 {
+		limit = defaultMemoryRecallLimit
+	}
+	if limit > maxMemoryRecallLimit {
+		limit = maxMemoryRecallLimit
+	}
+
+	recalled, err := t.memoryService.RecallMemories(ctx, input.Query, t.embeddingModelID, limit)
+	if err != nil {
+		logger.Warnf(ctx, "[Tool][RecallMemory] Failed to recall memories: %v", err)
+		return &types.ToolResult{
+			Success: false,
+			Error:   fmt.Sprintf("Failed to recall memories: %v", err),
+		}, nil
+	}
+
+	if len(recalled) == 0 {
+		return &types.ToolResult{
+			Success: true,
+			Output:  "No relevant memories found.",
+			Data:    map[string]interface{}{"memories": []interface{}{}},
+		}, nil
+	}
+
+	var output strings.Builder
+	fmt.Fprintf(&output, "Found %d relevant memories:\n", len(recalled))
+	memories := make([]map[string]interface{}, 0, len(recalled))
+	for i, r := range recalled {
+		fmt.Fprintf(&output, "%d. [%s] %s (saved %s)\n",
+			i+1, r.Memory.ID, r.Memory.Content, r.Memory.UpdatedAt.Format("2006-01-02"))
+		memories = append(memories, map[string]interface{}{
+			"memory_id": r.Memory.ID,
+			"content":   r.Memory.Content,
+			"score":     r.Score,
+		})
+	}
+
+	return &types.ToolResult{
+		Success: true,
+		Output:  output.String(),
+		Data:    map[string]interface{}{"memories": memories},
+	}, nil
+}
+
+// Execute deletes a memory
+func (t *ForgetMemoryTool) Execute(ctx context.Context, args json.RawMessage) (*types.ToolResult, error) {
+	var input ForgetMemoryInput
+	if err := json.Unmarshal(args, &input); err != nil {
+		return &types.ToolResult{
+			Success: false,
+			Error:   fmt.Sprintf("Failed to parse args: %v", err),
+		}, err
+	}
+	if strings.TrimSpace(input.MemoryID) == "" {
+		return &types.ToolResult{
+			Success: false,
+			Error:   "missing required parameter: memory_id",
+		}, nil
+	}
+
+	if err := t.memoryService.DeleteMemory(ctx, input.MemoryID); err != nil {
+		logger.Warnf(ctx, "[Tool][ForgetMemory] Failed to delete memory %s: %v", input.MemoryID, err)
+		return &types.ToolResult{
+			Success: false,
+			Error:   fmt.Sprintf("Failed to forget memory: %v", err),
+		}, nil
+	}
+
+	return &types.ToolResult{
+		Success: true,
+		Output:  fmt.Sprintf("Memory %s forgotten.", input.MemoryID),
+		Data:    map[string]interface{}{"memory_id": input.MemoryID},
+	}, nil
+}
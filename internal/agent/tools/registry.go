@@ -6,6 +6,7 @@ import (
 	"fmt"
 
 	"github.com/Tencent/WeKnora/internal/common"
+	"github.com/Tencent/WeKnora/internal/metrics"
 	"github.com/Tencent/WeKnora/internal/types"
 )
 
@@ -41,6 +42,22 @@ func (r *ToolRegistry) GetTool(name string) (types.Tool, error) {
 	return tool, nil
 }
 
+// ToolKind returns the metrics label of a registered tool: mcp, http, sub_agent or builtin
+func (r *ToolRegistry) ToolKind(name string) string {
+	switch r.tools[name].(type) {
+	case nil:
+		return metrics.ToolKindUnknown
+	case *MCPTool:
+		return metrics.ToolKindMCP
+	case *HTTPTool:
+		return metrics.ToolKindHTTP
+	case *SubAgentTool:
+		return metrics.ToolKindSubAgent
+	default:
+		return metrics.ToolKindBuiltin
+	}
+}
+
 // ListTools returns all registered tool names
 func (r *ToolRegistry) ListTools() []string {
 	names := make([]string, 0, len(r.tools))
@@ -0,0 +1,147 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// subAgentMaxReferences caps the references returned from a delegated run
+const subAgentMaxReferences = 20
+
+// SubAgentRunner runs a delegated agent for a query.
+// Events of the nested run are reported under parentToolCallID, the ID of the calling tool call.
+type SubAgentRunner func(ctx context.Context, query, parentToolCallID string) (*types.AgentState, error)
+
+// SubAgentTool delegates a sub-question to another custom agent, which runs with its own
+// configuration, knowledge base scope and iteration budget
+type SubAgentTool struct {
+	BaseTool
+	agentID   string
+	agentName string
+	run       SubAgentRunner
+}
+
+// NewSubAgentTool creates a tool delegating to agent through run
+func NewSubAgentTool(agent *types.CustomAgent, run SubAgentRunner) *SubAgentTool {
+	description := fmt.Sprintf("[Sub-agent: %s] ", agent.Name)
+	if agent.Description != "" {
+		description += agent.Description + " "
+	}
+	description += "Delegate a self-contained sub-question to this agent. It works independently with its own tools " +
+		"and knowledge bases and cannot see this conversation, so include all the context it needs in the query. " +
+		"Returns its final answer and the knowledge references it used."
+
+	return &SubAgentTool{
+		BaseTool: NewBaseTool("agent_"+sanitizeName(agent.ID), description, json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "query": {
+      "type": "string",
+      "description": "The sub-question for the agent, self-contained with all the context needed to answer it"
+    }
+  },
+  "required": ["query"]
+}`)),
+		agentID:   agent.ID,
+		agentName: agent.Name,
+		run:       run,
+	}
+}
+
+// AgentID returns the ID of the agent the tool delegates to
+func (t *SubAgentTool) AgentID() string {
+	return t.agentID
+}
+
+// Execute runs the sub-agent and returns its final answer
+func (t *SubAgentTool) Execute(ctx context.Context, args json.RawMessage) (*types.ToolResult, error) {
+	var input struct {
+		Query string `json:"query"`
+	}
+	if err := json.Unmarshal(args, &input); err != nil {
+		return &types.ToolResult{Success: false, Error: fmt.Sprintf("Failed to parse args: %v", err)}, err
+	}
+	query := strings.TrimSpace(input.Query)
+	if query == "" {
+		return &types.ToolResult{Success: false, Error: "query is required"}, nil
+	}
+
+	parentToolCallID, _ := ctx.Value(types.ToolCallIDContextKey).(string)
+	logger.Infof(ctx, "[Tool][SubAgent] Delegating to agent %s (%s), parent tool call: %s",
+		t.agentName, t.agentID, parentToolCallID)
+
+	state, err := t.run(ctx, query, parentToolCallID)
+	if err != nil {
+		logger.Warnf(ctx, "[Tool][SubAgent] Agent %s failed: %v", t.agentID, err)
+		return &types.ToolResult{
+			Success: false,
+			Error:   fmt.Sprintf("Agent %s failed: %v", t.agentName, err),
+		}, nil
+	}
+
+	references := collectSubAgentReferences(state)
+	toolCalls := 0
+	for _, step := range state.RoundSteps {
+		toolCalls += len(step.ToolCalls)
+	}
+
+	var output strings.Builder
+	fmt.Fprintf(&output, "Answer from agent %q:\n%s\n", t.agentName, state.FinalAnswer)
+	if len(references) > 0 {
+		output.WriteString("\nReferences used by the agent:\n")
+		for i, ref := range references {
+			fmt.Fprintf(&output, "[%d] %v (knowledge_id: %v, chunk_id: %v)\n",
+				i+1, ref["knowledge_title"], ref["knowledge_id"], ref["chunk_id"])
+		}
+	}
+
+	return &types.ToolResult{
+		Success: true,
+		Output:  output.String(),
+		Data: map[string]interface{}{
+			"display_type": "sub_agent",
+			"agent_id":     t.agentID,
+			"agent_name":   t.agentName,
+			"answer":       state.FinalAnswer,
+			"references":   references,
+			"rounds":       len(state.RoundSteps),
+			"tool_calls":   toolCalls,
+		},
+	}, nil
+}
+
+// collectSubAgentReferences returns the knowledge search results of a delegated run, deduplicated by chunk
+func collectSubAgentReferences(state *types.AgentState) []map[string]interface{} {
+	references := make([]map[string]interface{}, 0)
+	seen := make(map[string]bool)
+	for _, step := range state.RoundSteps {
+		for _, call := range step.ToolCalls {
+			if call.Result == nil || call.Result.Data == nil || call.Result.Data["display_type"] != "search_results" {
+				continue
+			}
+			results, _ := call.Result.Data["results"].([]map[string]interface{})
+			for _, result := range results {
+				chunkID := fmt.Sprint(result["chunk_id"])
+				if seen[chunkID] {
+					continue
+				}
+				seen[chunkID] = true
+				references = append(references, map[string]interface{}{
+					"chunk_id":        result["chunk_id"],
+					"knowledge_id":    result["knowledge_id"],
+					"knowledge_title": result["knowledge_title"],
+					"content":         result["content"],
+				})
+				if len(references) >= subAgentMaxReferences {
+					return references
+				}
+			}
+		}
+	}
+	return references
+}
@@ -0,0 +1,79 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+func TestSubAgentToolExecute(t *testing.T) {
+	searchResult := func(chunkIDs ...string) *types.ToolResult {
+		results := make([]map[string]interface{}, 0, len(chunkIDs))
+		for _, id := range chunkIDs {
+			results = append(results, map[string]interface{}{
+				"chunk_id":        id,
+				"knowledge_id":    "k-" + id,
+				"knowledge_title": "Doc " + id,
+				"content":         "content " + id,
+			})
+		}
+		return &types.ToolResult{
+			Success: true,
+			Data:    map[string]interface{}{"display_type": "search_results", "results": results},
+		}
+	}
+
+	var gotQuery, gotParent string
+	agent := &types.CustomAgent{ID: "builtin-data-analyst", Name: "Data Analyst"}
+	tool := NewSubAgentTool(agent, func(ctx context.Context, query, parentToolCallID string) (*types.AgentState, error) {
+		gotQuery, gotParent = query, parentToolCallID
+		return &types.AgentState{
+			FinalAnswer: "Revenue grew 12%.",
+			RoundSteps: []types.AgentStep{
+				{ToolCalls: []types.ToolCall{{Name: ToolKnowledgeSearch, Result: searchResult("c1", "c2")}}},
+				{ToolCalls: []types.ToolCall{
+					{Name: ToolKnowledgeSearch, Result: searchResult("c2", "c3")},
+					{Name: ToolThinking, Result: &types.ToolResult{Success: true}},
+				}},
+			},
+		}, nil
+	})
+	if tool.Name() != "agent_builtin_data_analyst" {
+		t.Fatalf("unexpected name %q", tool.Name())
+	}
+
+	ctx := context.WithValue(context.Background(), types.ToolCallIDContextKey, "call-1")
+	result, err := tool.Execute(ctx, json.RawMessage(`{"query":" How did revenue change? "}`))
+	if err != nil || !result.Success {
+		t.Fatalf("execute failed: %v %+v", err, result)
+	}
+	if gotQuery != "How did revenue change?" || gotParent != "call-1" {
+		t.Errorf("unexpected run arguments: query=%q parent=%q", gotQuery, gotParent)
+	}
+	if !strings.Contains(result.Output, "Revenue grew 12%.") || !strings.Contains(result.Output, "[3] Doc c3") {
+		t.Errorf("unexpected output: %s", result.Output)
+	}
+	if refs := result.Data["references"].([]map[string]interface{}); len(refs) != 3 {
+		t.Errorf("expected 3 deduplicated references, got %d", len(refs))
+	}
+	if result.Data["tool_calls"] != 3 {
+		t.Errorf("unexpected tool call count: %v", result.Data["tool_calls"])
+	}
+
+	result, _ = tool.Execute(ctx, json.RawMessage(`{"query":""}`))
+	if result.Success {
+		t.Error("expected an empty query to fail")
+	}
+
+	failing := NewSubAgentTool(agent, func(context.Context, string, string) (*types.AgentState, error) {
+		return nil, errors.New("model unavailable")
+	})
+	result, err = failing.Execute(ctx, json.RawMessage(`{"query":"q"}`))
+	if err != nil || result.Success || !strings.Contains(result.Error, "model unavailable") {
+		t.Errorf("expected the failure to be reported to the caller, got %v %+v", err, result)
+	}
+}
@@ -0,0 +1,212 @@
+package agent
+
+import (
+	"context"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/models/chat"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// TraceSink receives the trace of a run when the run ends, whether it completed or failed
+type TraceSink func(ctx context.Context, trace *types.AgentTrace)
+
+// traceRecorder records the model calls and tool calls of a run.
+// All methods are no-ops on a nil recorder, which is used when tracing is disabled.
+type traceRecorder struct {
+	trace *types.AgentTrace
+	// Index of the current think round, which the tool calls are added to
+	current int
+}
+
+// SetTraceSink enables recording the full trace of runs, which is passed to sink when a run ends
+func (e *AgentEngine) SetTraceSink(sink TraceSink) {
+	e.traceSink = sink
+}
+
+// startTrace starts recording a run
+func (e *AgentEngine) startTrace(sessionID, messageID, query, systemPrompt string, tools []chat.Tool) {
+	if e.traceSink == nil {
+		e.trace = nil
+		return
+	}
+	traceTools := make(types.AgentTraceTools, 0, len(tools))
+	for _, tool := range tools {
+		traceTools = append(traceTools, types.AgentTraceTool{
+			Name:        tool.Function.Name,
+			Description: tool.Function.Description,
+			Parameters:  tool.Function.Parameters,
+		})
+	}
+	e.trace = &traceRecorder{
+		trace: &types.AgentTrace{
+			SessionID:     sessionID,
+			MessageID:     messageID,
+			ModelID:       e.chatModel.GetModelID(),
+			ModelName:     e.chatModel.GetModelName(),
+			Query:         query,
+			SystemPrompt:  systemPrompt,
+			Tools:         traceTools,
+			MaxIterations: e.config.MaxIterations,
+			Rounds:        types.AgentTraceRounds{},
+			StartedAt:     time.Now(),
+		},
+		current: -1,
+	}
+}
+
+// finishTrace ends the recording of a run and hands the trace to the sink
+func (e *AgentEngine) finishTrace(ctx context.Context, state *types.AgentState, runErr error) {
+	if e.trace == nil {
+		return
+	}
+	trace := e.trace.trace
+	e.trace = nil
+	trace.DurationMs = time.Since(trace.StartedAt).Milliseconds()
+	if state != nil {
+		trace.FinalAnswer = state.FinalAnswer
+	}
+	if runErr != nil {
+		trace.Error = runErr.Error()
+	}
+	for _, round := range trace.Rounds {
+		trace.Usage.Add(round.Usage)
+	}
+	e.traceSink(ctx, trace)
+}
+
+// beginModelCall records the request of a model call and returns its round index
+func (r *traceRecorder) beginModelCall(
+	stage string,
+	iteration int,
+	messages []chat.Message,
+	opts *chat.ChatOptions,
+) int {
+	if r == nil {
+		return -1
+	}
+	round := types.AgentTraceRound{
+		Iteration: iteration,
+		Stage:     stage,
+		Messages:  toTraceMessages(messages),
+		StartedAt: time.Now(),
+	}
+	if opts != nil {
+		round.Options = types.AgentTraceOptions{
+			Temperature: opts.Temperature,
+			Thinking:    opts.Thinking,
+			ToolCount:   len(opts.Tools),
+		}
+	}
+	r.trace.Rounds = append(r.trace.Rounds, round)
+	index := len(r.trace.Rounds) - 1
+	if stage == types.AgentTraceStageThink {
+		r.current = index
+	}
+	return index
+}
+
+// endModelCall records the response of a model call
+func (r *traceRecorder) endModelCall(
+	index int,
+	content string,
+	toolCalls []types.LLMToolCall,
+	usage *types.TokenUsage,
+	err error,
+) {
+	if r == nil || index < 0 {
+		return
+	}
+	round := &r.trace.Rounds[index]
+	round.Response = content
+	round.ResponseCalls = toolCalls
+	round.Usage = usage
+	if err != nil {
+		round.Error = err.Error()
+	}
+	round.ModelDurationMs = time.Since(round.StartedAt).Milliseconds()
+	round.DurationMs = round.ModelDurationMs
+}
+
+// addToolCall records a tool call of the current think round
+func (r *traceRecorder) addToolCall(llmCall types.LLMToolCall, toolCall *types.ToolCall, executed bool) {
+	if r == nil || r.current < 0 {
+		return
+	}
+	call := types.AgentTraceToolCall{
+		ID:        llmCall.ID,
+		Name:      llmCall.Function.Name,
+		Arguments: llmCall.Function.Arguments,
+		Executed:  executed,
+	}
+	if toolCall != nil {
+		call.DurationMs = toolCall.Duration
+		if toolCall.Result != nil {
+			call.Success = toolCall.Result.Success
+			call.Output = toolCall.Result.Output
+			call.Error = toolCall.Result.Error
+			call.Data = toolCall.Result.Data
+		}
+	}
+	round := &r.trace.Rounds[r.current]
+	round.ToolCalls = append(round.ToolCalls, call)
+}
+
+// endRound records the duration of the current think round, including its tool calls
+func (r *traceRecorder) endRound() {
+	if r == nil || r.current < 0 {
+		return
+	}
+	round := &r.trace.Rounds[r.current]
+	round.DurationMs = time.Since(round.StartedAt).Milliseconds()
+}
+
+// toTraceMessages converts the messages of a model request for the trace
+func toTraceMessages(messages []chat.Message) []types.AgentTraceMessage {
+	result := make([]types.AgentTraceMessage, 0, len(messages))
+	for _, msg := range messages {
+		traced := types.AgentTraceMessage{
+			Role:       msg.Role,
+			Content:    msg.Content,
+			Name:       msg.Name,
+			ToolCallID: msg.ToolCallID,
+		}
+		for _, tc := range msg.ToolCalls {
+			traced.ToolCalls = append(traced.ToolCalls, types.LLMToolCall{
+				ID:   tc.ID,
+				Type: tc.Type,
+				Function: types.FunctionCall{
+					Name:      tc.Function.Name,
+					Arguments: tc.Function.Arguments,
+				},
+			})
+		}
+		result = append(result, traced)
+	}
+	return result
+}
+
+// fromTraceMessages converts recorded messages back into a model request
+func fromTraceMessages(messages []types.AgentTraceMessage) []chat.Message {
+	result := make([]chat.Message, 0, len(messages))
+	for _, msg := range messages {
+		restored := chat.Message{
+			Role:       msg.Role,
+			Content:    msg.Content,
+			Name:       msg.Name,
+			ToolCallID: msg.ToolCallID,
+		}
+		for _, tc := range msg.ToolCalls {
+			restored.ToolCalls = append(restored.ToolCalls, chat.ToolCall{
+				ID:   tc.ID,
+				Type: tc.Type,
+				Function: chat.FunctionCall{
+					Name:      tc.Function.Name,
+					Arguments: tc.Function.Arguments,
+				},
+			})
+		}
+		result = append(result, restored)
+	}
+	return result
+}
@@ -0,0 +1,758 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/agent/tools"
+	"github.com/Tencent/WeKnora/internal/common"
+	"github.com/Tencent/WeKnora/internal/event"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/models/chat"
+	"github.com/Tencent/WeKnora/internal/models/rerank"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+)
+
+const (
+	// workflowEventOutputChars caps the node output streamed in workflow_node events
+	workflowEventOutputChars = 2000
+	// Default number of results kept by retrieve and rerank nodes
+	workflowDefaultRetrieveTopK = 10
+	workflowDefaultRerankTopK   = 5
+)
+
+// WorkflowEngine runs the workflow of a workflow-mode agent.
+// Nodes run one at a time in dependency order, each reported by workflow_node events on the
+// EventBus. It implements interfaces.AgentEngine so that workflow runs stream like agent runs.
+type WorkflowEngine struct {
+	config               *types.AgentConfig
+	chatModel            chat.Chat
+	rerankModel          rerank.Reranker
+	toolRegistry         *tools.ToolRegistry
+	eventBus             *event.EventBus
+	knowledgeBaseService interfaces.KnowledgeBaseService
+	modelService         interfaces.ModelService
+	approvalTools        map[string]bool // Tools requiring approval, which workflows cannot call
+}
+
+// NewWorkflowEngine creates an engine running config.Workflow
+func NewWorkflowEngine(
+	config *types.AgentConfig,
+	chatModel chat.Chat,
+	rerankModel rerank.Reranker,
+	toolRegistry *tools.ToolRegistry,
+	eventBus *event.EventBus,
+	knowledgeBaseService interfaces.KnowledgeBaseService,
+	modelService interfaces.ModelService,
+	approvalTools []string,
+) *WorkflowEngine {
+	approval := make(map[string]bool, len(approvalTools))
+	for _, name := range approvalTools {
+		approval[name] = true
+	}
+	return &WorkflowEngine{
+		config:               config,
+		chatModel:            chatModel,
+		rerankModel:          rerankModel,
+		toolRegistry:         toolRegistry,
+		eventBus:             eventBus,
+		knowledgeBaseService: knowledgeBaseService,
+		modelService:         modelService,
+		approvalTools:        approval,
+	}
+}
+
+// workflowNodeResult is the outcome of a workflow node
+type workflowNodeResult struct {
+	ran     bool                   // False when the node was skipped
+	output  string                 // Text output, referenced by templates as {{node_id}}
+	results []*types.SearchResult  // Search results of retrieve, rerank and merge nodes
+	branch  string                 // Branch chosen by a condition node
+	data    map[string]interface{} // Structured data streamed with the node outcome
+}
+
+// workflowRun holds the state of a workflow run
+type workflowRun struct {
+	query   string
+	nodes   map[string]*types.WorkflowNode
+	results map[string]*workflowNodeResult
+}
+
+// Execute runs the workflow for query. The conversation history is not used:
+// every run is a deterministic function of the query.
+func (e *WorkflowEngine) Execute(
+	ctx context.Context,
+	sessionID, messageID, query string,
+	llmContext []chat.Message,
+) (*types.AgentState, error) {
+	defer e.toolRegistry.Cleanup(ctx)
+	startTime := time.Now()
+
+	fail := func(err error) (*types.AgentState, error) {
+		logger.Errorf(ctx, "[Workflow] Execution failed: %v", err)
+		e.eventBus.Emit(ctx, event.Event{
+			ID:        generateEventID("error"),
+			Type:      event.EventError,
+			SessionID: sessionID,
+			Data: event.ErrorData{
+				Error:     err.Error(),
+				Stage:     "workflow_execution",
+				SessionID: sessionID,
+			},
+		})
+		return nil, err
+	}
+
+	if e.config.Workflow == nil {
+		return fail(fmt.Errorf("agent has no workflow"))
+	}
+	order, err := e.config.Workflow.TopologicalOrder()
+	if err != nil {
+		return fail(err)
+	}
+
+	logger.Infof(ctx, "[Workflow] SessionID: %s, MessageID: %s, nodes: %d, query: %s",
+		sessionID, messageID, len(order), query)
+	common.PipelineInfo(ctx, "Workflow", "execute_start", map[string]interface{}{
+		"session_id": sessionID,
+		"message_id": messageID,
+		"nodes":      len(order),
+	})
+
+	run := &workflowRun{
+		query:   query,
+		nodes:   make(map[string]*types.WorkflowNode, len(order)),
+		results: make(map[string]*workflowNodeResult, len(order)),
+	}
+	for _, node := range order {
+		run.nodes[node.ID] = node
+	}
+	state := &types.AgentState{
+		RoundSteps:    []types.AgentStep{},
+		KnowledgeRefs: []*types.SearchResult{},
+	}
+
+	lastNodeID := ""
+	for step, node := range order {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		eventID := generateEventID("workflow-" + node.ID)
+		nodeData := event.WorkflowNodeData{
+			NodeID:   node.ID,
+			NodeType: node.Type,
+			NodeName: node.DisplayName(),
+			Step:     step,
+		}
+
+		if reason := run.skipReason(node); reason != "" {
+			logger.Infof(ctx, "[Workflow] Node %s skipped: %s", node.ID, reason)
+			run.results[node.ID] = &workflowNodeResult{}
+			nodeData.Status = event.WorkflowNodeSkipped
+			nodeData.Output = reason
+			e.emitNode(ctx, sessionID, eventID, nodeData)
+			continue
+		}
+
+		nodeData.Status = event.WorkflowNodeRunning
+		e.emitNode(ctx, sessionID, eventID, nodeData)
+
+		nodeStart := time.Now()
+		nodeCtx := context.WithValue(ctx, types.ToolCallIDContextKey, eventID)
+		result, err := e.runNode(nodeCtx, run, node)
+		nodeData.Duration = time.Since(nodeStart).Milliseconds()
+		if err != nil {
+			logger.Warnf(ctx, "[Workflow] Node %s failed: %v", node.ID, err)
+			nodeData.Status = event.WorkflowNodeFailed
+			nodeData.Error = err.Error()
+			e.emitNode(ctx, sessionID, eventID, nodeData)
+			state.RoundSteps = append(state.RoundSteps, workflowStep(step, eventID, node, nil, err, nodeData.Duration))
+			if !node.ContinueOnError {
+				return fail(fmt.Errorf("workflow node %s failed: %w", node.ID, err))
+			}
+			result = &workflowNodeResult{}
+		} else {
+			nodeData.Status = event.WorkflowNodeCompleted
+			nodeData.Output = truncateWorkflowOutput(result.output)
+			nodeData.Branch = result.branch
+			nodeData.Data = result.data
+			e.emitNode(ctx, sessionID, eventID, nodeData)
+			state.RoundSteps = append(state.RoundSteps, workflowStep(step, eventID, node, result, nil, nodeData.Duration))
+		}
+		result.ran = true
+		run.results[node.ID] = result
+		lastNodeID = node.ID
+	}
+
+	// Answer: the output template, or the output of the last node that ran
+	if e.config.Workflow.Output != "" {
+		state.FinalAnswer = run.render(e.config.Workflow.Output)
+	} else if lastNodeID != "" {
+		state.FinalAnswer = run.results[lastNodeID].output
+	}
+	state.KnowledgeRefs = run.references(order)
+	state.IsComplete = true
+	state.CurrentRound = len(state.RoundSteps)
+
+	if len(state.KnowledgeRefs) > 0 {
+		e.eventBus.Emit(ctx, event.Event{
+			ID:        generateEventID("references"),
+			Type:      event.EventAgentReferences,
+			SessionID: sessionID,
+			Data: event.AgentReferencesData{
+				References: state.KnowledgeRefs,
+				Iteration:  len(order),
+			},
+		})
+	}
+	e.eventBus.Emit(ctx, event.Event{
+		ID:        generateEventID("answer"),
+		Type:      event.EventAgentFinalAnswer,
+		SessionID: sessionID,
+		Data: event.AgentFinalAnswerData{
+			Content: state.FinalAnswer,
+			Done:    true,
+		},
+	})
+
+	if len(e.config.OutputSchema) > 0 {
+		emitStructuredAnswer(ctx, e.chatModel, e.eventBus, e.config.OutputSchema, sessionID, query, state.FinalAnswer)
+	}
+
+	knowledgeRefsInterface := make([]interface{}, 0, len(state.KnowledgeRefs))
+	for _, ref := range state.KnowledgeRefs {
+		knowledgeRefsInterface = append(knowledgeRefsInterface, ref)
+	}
+	e.eventBus.Emit(ctx, event.Event{
+		ID:        generateEventID("complete"),
+		Type:      event.EventAgentComplete,
+		SessionID: sessionID,
+		Data: event.AgentCompleteData{
+			FinalAnswer:     state.FinalAnswer,
+			KnowledgeRefs:   knowledgeRefsInterface,
+			AgentSteps:      state.RoundSteps,
+			TotalSteps:      len(state.RoundSteps),
+			TotalDurationMs: time.Since(startTime).Milliseconds(),
+			MessageID:       messageID,
+		},
+	})
+
+	common.PipelineInfo(ctx, "Workflow", "execute_complete", map[string]interface{}{
+		"session_id": sessionID,
+		"steps":      len(state.RoundSteps),
+		"references": len(state.KnowledgeRefs),
+	})
+	logger.Infof(ctx, "[Workflow] Completed %d nodes in %dms", len(state.RoundSteps), time.Since(startTime).Milliseconds())
+	return state, nil
+}
+
+// emitNode emits a workflow_node event
+func (e *WorkflowEngine) emitNode(ctx context.Context, sessionID, eventID string, data event.WorkflowNodeData) {
+	e.eventBus.Emit(ctx, event.Event{
+		ID:        eventID,
+		Type:      event.EventWorkflowNode,
+		SessionID: sessionID,
+		Data:      data,
+	})
+}
+
+// runNode runs a node whose dependencies have run
+func (e *WorkflowEngine) runNode(
+	ctx context.Context,
+	run *workflowRun,
+	node *types.WorkflowNode,
+) (*workflowNodeResult, error) {
+	switch node.Type {
+	case types.WorkflowNodeRetrieve:
+		return e.runRetrieve(ctx, run, node)
+	case types.WorkflowNodeRerank:
+		return e.runRerank(ctx, run, node)
+	case types.WorkflowNodeLLM:
+		return e.runLLM(ctx, run, node)
+	case types.WorkflowNodeTool:
+		return e.runTool(ctx, run, node)
+	case types.WorkflowNodeCondition:
+		return run.runCondition(node)
+	case types.WorkflowNodeMerge:
+		return run.runMerge(node), nil
+	default:
+		return nil, fmt.Errorf("unknown node type %q", node.Type)
+	}
+}
+
+// runRetrieve searches the agent's knowledge bases, or the subset selected by the node
+func (e *WorkflowEngine) runRetrieve(
+	ctx context.Context,
+	run *workflowRun,
+	node *types.WorkflowNode,
+) (*workflowNodeResult, error) {
+	query := run.queryOf(node)
+	selected := make(map[string]bool, len(node.KnowledgeBases))
+	for _, id := range node.KnowledgeBases {
+		selected[id] = true
+	}
+	targets := make(types.SearchTargets, 0, len(e.config.SearchTargets))
+	for _, target := range e.config.SearchTargets {
+		if len(selected) == 0 || selected[target.KnowledgeBaseID] {
+			targets = append(targets, target)
+		}
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no knowledge base to search")
+	}
+	topK := node.TopK
+	if topK <= 0 {
+		topK = workflowDefaultRetrieveTopK
+	}
+
+	var results []*types.SearchResult
+	var lastErr error
+	failed := 0
+	for _, target := range targets {
+		params := types.SearchParams{
+			QueryText:        query,
+			MatchCount:       topK,
+			VectorThreshold:  node.VectorThreshold,
+			KeywordThreshold: node.KeywordThreshold,
+		}
+		if target.Type == types.SearchTargetTypeKnowledge {
+			params.KnowledgeIDs = target.KnowledgeIDs
+		}
+		kbResults, err := e.knowledgeBaseService.HybridSearch(ctx, target.KnowledgeBaseID, params)
+		if err != nil {
+			logger.Warnf(ctx, "[Workflow] Failed to search KB %s: %v", target.KnowledgeBaseID, err)
+			lastErr = err
+			failed++
+			continue
+		}
+		results = append(results, kbResults...)
+	}
+	if failed == len(targets) {
+		return nil, fmt.Errorf("search failed: %w", lastErr)
+	}
+
+	results = dedupeSearchResults(results)
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	if len(results) > topK {
+		results = results[:topK]
+	}
+	return &workflowNodeResult{
+		output:  formatWorkflowResults(results),
+		results: results,
+		data:    map[string]interface{}{"query": query, "result_count": len(results)},
+	}, nil
+}
+
+// runRerank reranks the search results of the dependencies that ran
+func (e *WorkflowEngine) runRerank(
+	ctx context.Context,
+	run *workflowRun,
+	node *types.WorkflowNode,
+) (*workflowNodeResult, error) {
+	query := run.queryOf(node)
+	var candidates []*types.SearchResult
+	for _, dep := range node.DependsOn {
+		if result := run.results[dep]; result.ran {
+			candidates = append(candidates, result.results...)
+		}
+	}
+	candidates = dedupeSearchResults(candidates)
+	if len(candidates) == 0 {
+		return &workflowNodeResult{data: map[string]interface{}{"query": query, "result_count": 0}}, nil
+	}
+	if e.rerankModel == nil {
+		return nil, fmt.Errorf("the agent has no rerank model")
+	}
+
+	documents := make([]string, len(candidates))
+	for i, candidate := range candidates {
+		documents[i] = candidate.Content
+	}
+	ranked, err := e.rerankModel.Rerank(ctx, query, documents)
+	if err != nil {
+		return nil, fmt.Errorf("rerank failed: %w", err)
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].RelevanceScore > ranked[j].RelevanceScore })
+
+	topK := node.TopK
+	if topK <= 0 {
+		topK = workflowDefaultRerankTopK
+	}
+	results := make([]*types.SearchResult, 0, topK)
+	for _, rank := range ranked {
+		if len(results) >= topK {
+			break
+		}
+		if rank.Index < 0 || rank.Index >= len(candidates) || rank.RelevanceScore < node.Threshold {
+			continue
+		}
+		reranked := *candidates[rank.Index]
+		reranked.Score = rank.RelevanceScore
+		results = append(results, &reranked)
+	}
+	return &workflowNodeResult{
+		output:  formatWorkflowResults(results),
+		results: results,
+		data: map[string]interface{}{
+			"query":        query,
+			"input_count":  len(candidates),
+			"result_count": len(results),
+		},
+	}, nil
+}
+
+// runLLM calls the node's chat model, or the agent's, with the rendered prompts
+func (e *WorkflowEngine) runLLM(
+	ctx context.Context,
+	run *workflowRun,
+	node *types.WorkflowNode,
+) (*workflowNodeResult, error) {
+	model := e.chatModel
+	if node.ModelID != "" {
+		var err error
+		model, err = e.modelService.GetChatModel(ctx, node.ModelID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load model %s: %w", node.ModelID, err)
+		}
+	}
+
+	messages := make([]chat.Message, 0, 2)
+	if systemPrompt := strings.TrimSpace(run.render(node.SystemPrompt)); systemPrompt != "" {
+		messages = append(messages, chat.Message{Role: "system", Content: systemPrompt})
+	}
+	messages = append(messages, chat.Message{Role: "user", Content: run.render(node.Prompt)})
+
+	temperature := node.Temperature
+	if temperature == 0 {
+		temperature = e.config.Temperature
+	}
+	response, err := model.Chat(ctx, messages, &chat.ChatOptions{
+		Temperature:         temperature,
+		MaxCompletionTokens: node.MaxTokens,
+		Thinking:            e.config.Thinking,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("model call failed: %w", err)
+	}
+	return &workflowNodeResult{
+		output: strings.TrimSpace(response.Content),
+		data:   map[string]interface{}{"model": model.GetModelName()},
+	}, nil
+}
+
+// runTool calls an agent tool with the rendered arguments
+func (e *WorkflowEngine) runTool(
+	ctx context.Context,
+	run *workflowRun,
+	node *types.WorkflowNode,
+) (*workflowNodeResult, error) {
+	if e.approvalTools[node.Tool] {
+		return nil, fmt.Errorf("tool %s requires approval and cannot be called by a workflow", node.Tool)
+	}
+	args, _ := run.renderValue(node.Arguments).(map[string]interface{})
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	rawArgs, err := json.Marshal(args)
+	if err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	result, err := e.toolRegistry.ExecuteTool(ctx, node.Tool, rawArgs)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, fmt.Errorf("tool %s returned no result", node.Tool)
+	}
+	if !result.Success {
+		return nil, fmt.Errorf("%s", result.Error)
+	}
+
+	data := map[string]interface{}{"tool": node.Tool, "arguments": args}
+	for key, value := range result.Data {
+		data[key] = value
+	}
+	return &workflowNodeResult{output: result.Output, data: data}, nil
+}
+
+// runCondition chooses the branch of the first case matching the node's input
+func (r *workflowRun) runCondition(node *types.WorkflowNode) (*workflowNodeResult, error) {
+	input := ""
+	if node.Input != "" {
+		input = r.render(node.Input)
+	} else if len(node.DependsOn) > 0 {
+		input = r.results[node.DependsOn[0]].output
+	}
+
+	branch := types.WorkflowDefaultBranch
+	for _, cond := range node.Cases {
+		matched, err := matchWorkflowCondition(cond, input)
+		if err != nil {
+			return nil, err
+		}
+		if matched {
+			branch = cond.Branch
+			break
+		}
+	}
+	return &workflowNodeResult{
+		output: branch,
+		branch: branch,
+		data:   map[string]interface{}{"input": truncateWorkflowOutput(input)},
+	}, nil
+}
+
+// matchWorkflowCondition reports whether input matches a condition case
+func matchWorkflowCondition(cond types.WorkflowCondition, input string) (bool, error) {
+	switch cond.Operator {
+	case "", types.WorkflowOperatorContains:
+		return strings.Contains(strings.ToLower(input), strings.ToLower(cond.Value)), nil
+	case types.WorkflowOperatorEquals:
+		return strings.EqualFold(strings.TrimSpace(input), strings.TrimSpace(cond.Value)), nil
+	case types.WorkflowOperatorNotEmpty:
+		return strings.TrimSpace(input) != "", nil
+	case types.WorkflowOperatorRegex:
+		re, err := regexp.Compile(cond.Value)
+		if err != nil {
+			return false, fmt.Errorf("invalid regex %q: %w", cond.Value, err)
+		}
+		return re.MatchString(input), nil
+	default:
+		return false, fmt.Errorf("unknown operator %q", cond.Operator)
+	}
+}
+
+// runMerge joins the outputs and search results of the dependencies that ran
+func (r *workflowRun) runMerge(node *types.WorkflowNode) *workflowNodeResult {
+	separator := node.Separator
+	if separator == "" {
+		separator = "\n\n"
+	}
+	outputs := make([]string, 0, len(node.DependsOn))
+	var results []*types.SearchResult
+	merged := make([]string, 0, len(node.DependsOn))
+	for _, dep := range node.DependsOn {
+		result := r.results[dep]
+		if !result.ran {
+			continue
+		}
+		merged = append(merged, dep)
+		if strings.TrimSpace(result.output) != "" {
+			outputs = append(outputs, result.output)
+		}
+		results = append(results, result.results...)
+	}
+	return &workflowNodeResult{
+		output:  strings.Join(outputs, separator),
+		results: dedupeSearchResults(results),
+		data:    map[string]interface{}{"merged": merged},
+	}
+}
+
+// skipReason returns why a node does not run, or an empty string when it runs.
+// A node is skipped when a dependency was skipped, or when it runs for a branch other than the
+// one chosen by the condition node it depends on. Merge nodes run when any dependency ran.
+func (r *workflowRun) skipReason(node *types.WorkflowNode) string {
+	ran := 0
+	reason := ""
+	for _, dep := range node.DependsOn {
+		result := r.results[dep]
+		switch {
+		case !result.ran:
+			reason = fmt.Sprintf("dependency %s was skipped", dep)
+		case node.When != "" && r.nodes[dep].Type == types.WorkflowNodeCondition && result.branch != node.When:
+			return fmt.Sprintf("%s chose branch %s, not %s", dep, result.branch, node.When)
+		default:
+			ran++
+		}
+		if reason != "" && node.Type != types.WorkflowNodeMerge {
+			return reason
+		}
+	}
+	if node.Type == types.WorkflowNodeMerge && ran == 0 {
+		return "no dependency ran"
+	}
+	return ""
+}
+
+// queryOf returns the rendered query of a retrieve or rerank node, the user query by default
+func (r *workflowRun) queryOf(node *types.WorkflowNode) string {
+	if strings.TrimSpace(node.Query) == "" {
+		return r.query
+	}
+	return r.render(node.Query)
+}
+
+// render renders a template with the query and the outputs of the nodes that ran
+func (r *workflowRun) render(template string) string {
+	return types.RenderWorkflowTemplate(template, func(name string, path []string) string {
+		if name == types.WorkflowQueryVariable {
+			return r.query
+		}
+		result := r.results[name]
+		if result == nil || !result.ran {
+			return ""
+		}
+		if len(path) == 0 {
+			return result.output
+		}
+		return workflowJSONField(result.output, path)
+	})
+}
+
+// renderValue renders the strings of a tool argument value
+func (r *workflowRun) renderValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return r.render(v)
+	case map[string]interface{}:
+		rendered := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			rendered[key] = r.renderValue(item)
+		}
+		return rendered
+	case []interface{}:
+		rendered := make([]interface{}, len(v))
+		for i, item := range v {
+			rendered[i] = r.renderValue(item)
+		}
+		return rendered
+	default:
+		return value
+	}
+}
+
+// references returns the search results of the nodes that ran and whose results were not
+// consumed by a rerank or merge node, deduplicated
+func (r *workflowRun) references(order []*types.WorkflowNode) []*types.SearchResult {
+	consumed := make(map[string]bool)
+	for _, node := range order {
+		if (node.Type == types.WorkflowNodeRerank || node.Type == types.WorkflowNodeMerge) && r.results[node.ID].ran {
+			for _, dep := range node.DependsOn {
+				consumed[dep] = true
+			}
+		}
+	}
+	var refs []*types.SearchResult
+	for _, node := range order {
+		if result := r.results[node.ID]; result.ran && !consumed[node.ID] {
+			refs = append(refs, result.results...)
+		}
+	}
+	return dedupeSearchResults(refs)
+}
+
+// workflowJSONField extracts a field from a JSON output, which may be wrapped in a code fence.
+// Strings are returned as is and other values as JSON; missing fields render as empty.
+func workflowJSONField(output string, path []string) string {
+	text := strings.TrimSpace(output)
+	if strings.HasPrefix(text, "```") {
+		text = strings.TrimPrefix(text, "```json")
+		text = strings.TrimPrefix(text, "```")
+		text = strings.TrimSuffix(strings.TrimSpace(text), "```")
+	}
+	var value interface{}
+	if err := json.Unmarshal([]byte(text), &value); err != nil {
+		return ""
+	}
+	for _, key := range path {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			value = v[key]
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return ""
+			}
+			value = v[i]
+		default:
+			return ""
+		}
+	}
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		encoded, _ := json.Marshal(v)
+		return string(encoded)
+	}
+}
+
+// workflowStep records a node run as an agent step for message storage
+func workflowStep(
+	step int,
+	eventID string,
+	node *types.WorkflowNode,
+	result *workflowNodeResult,
+	err error,
+	duration int64,
+) types.AgentStep {
+	name := "workflow_" + node.Type
+	if node.Type == types.WorkflowNodeTool {
+		name = node.Tool
+	}
+	toolResult := &types.ToolResult{Success: err == nil}
+	if err != nil {
+		toolResult.Error = err.Error()
+	} else {
+		toolResult.Output = result.output
+		toolResult.Data = result.data
+	}
+	return types.AgentStep{
+		Iteration: step,
+		Thought:   fmt.Sprintf("Workflow node %s (%s)", node.DisplayName(), node.Type),
+		ToolCalls: []types.ToolCall{{
+			ID:       eventID,
+			Name:     name,
+			Args:     map[string]interface{}{"node_id": node.ID, "node_type": node.Type},
+			Result:   toolResult,
+			Duration: duration,
+		}},
+		Timestamp: time.Now(),
+	}
+}
+
+// formatWorkflowResults formats search results as numbered passages
+func formatWorkflowResults(results []*types.SearchResult) string {
+	var output strings.Builder
+	for i, result := range results {
+		if i > 0 {
+			output.WriteString("\n\n")
+		}
+		fmt.Fprintf(&output, "[%d] %s\n%s", i+1, result.KnowledgeTitle, result.Content)
+	}
+	return output.String()
+}
+
+// dedupeSearchResults removes repeated chunks, keeping the first occurrence
+func dedupeSearchResults(results []*types.SearchResult) []*types.SearchResult {
+	seen := make(map[string]bool, len(results))
+	deduped := make([]*types.SearchResult, 0, len(results))
+	for _, result := range results {
+		if result == nil || seen[result.ID] {
+			continue
+		}
+		seen[result.ID] = true
+		deduped = append(deduped, result)
+	}
+	return deduped
+}
+
+// truncateWorkflowOutput shortens an output for streaming
+func truncateWorkflowOutput(output string) string {
+	runes := []rune(output)
+	if len(runes) <= workflowEventOutputChars {
+		return output
+	}
+	return string(runes[:workflowEventOutputChars]) + "..."
+}
@@ -0,0 +1,162 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/agent/tools"
+	"github.com/Tencent/WeKnora/internal/event"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// workflowTestTool records the arguments it is called with
+type workflowTestTool struct {
+	args map[string]interface{}
+}
+
+func (t *workflowTestTool) Name() string                { return "create_ticket" }
+func (t *workflowTestTool) Description() string         { return "Create a ticket" }
+func (t *workflowTestTool) Parameters() json.RawMessage { return json.RawMessage(`{"type":"object"}`) }
+
+func (t *workflowTestTool) Execute(ctx context.Context, args json.RawMessage) (*types.ToolResult, error) {
+	_ = json.Unmarshal(args, &t.args)
+	return &types.ToolResult{Success: true, Output: "TICKET-42"}, nil
+}
+
+func TestWorkflowEngineExecute(t *testing.T) {
+	config := &types.CustomAgentConfig{
+		AgentMode: types.AgentModeWorkflow,
+		Workflow: &types.WorkflowDefinition{
+			Nodes: []types.WorkflowNode{
+				{ID: "classify", Type: types.WorkflowNodeLLM, Prompt: "Classify: {{query}}"},
+				{ID: "route", Type: types.WorkflowNodeCondition, DependsOn: []string{"classify"},
+					Input: "{{classify.category}}",
+					Cases: []types.WorkflowCondition{{Branch: "bug", Operator: types.WorkflowOperatorEquals, Value: "bug"}}},
+				{ID: "ticket", Type: types.WorkflowNodeTool, DependsOn: []string{"route"}, When: "bug",
+					Tool: "create_ticket", Arguments: map[string]interface{}{
+						"title":  "{{classify.summary}}",
+						"labels": []interface{}{"{{classify.category}}"},
+					}},
+				{ID: "faq", Type: types.WorkflowNodeLLM, DependsOn: []string{"route"}, When: "default",
+					Prompt: "Answer the question"},
+				{ID: "done", Type: types.WorkflowNodeMerge, DependsOn: []string{"ticket", "faq"}},
+			},
+			Output: "Filed {{done}} for {{classify.summary}}",
+		},
+	}
+	if err := config.ValidateWorkflow(); err != nil {
+		t.Fatalf("workflow should be valid: %v", err)
+	}
+
+	model := newScriptedChat("```json\n{\"category\": \"Bug\", \"summary\": \"Login fails\"}\n```")
+	ticketTool := &workflowTestTool{}
+	registry := tools.NewToolRegistry()
+	registry.RegisterTool(ticketTool)
+
+	eventBus := event.NewEventBus()
+	statuses := make(map[string]string)
+	var answer string
+	var completed bool
+	eventBus.On(event.EventWorkflowNode, func(ctx context.Context, evt event.Event) error {
+		data := evt.Data.(event.WorkflowNodeData)
+		statuses[data.NodeID] = data.Status
+		return nil
+	})
+	eventBus.On(event.EventAgentFinalAnswer, func(ctx context.Context, evt event.Event) error {
+		answer = evt.Data.(event.AgentFinalAnswerData).Content
+		return nil
+	})
+	eventBus.On(event.EventAgentComplete, func(ctx context.Context, evt event.Event) error {
+		completed = true
+		return nil
+	})
+
+	engine := NewWorkflowEngine(
+		&types.AgentConfig{Workflow: config.RuntimeWorkflow()},
+		model, nil, registry, eventBus, nil, nil, nil,
+	)
+	state, err := engine.Execute(context.Background(), "s1", "m1", "I cannot log in", nil)
+	if err != nil {
+		t.Fatalf("execute failed: %v", err)
+	}
+
+	if answer != "Filed TICKET-42 for Login fails" || state.FinalAnswer != answer || !completed {
+		t.Errorf("unexpected answer %q (completed: %v)", answer, completed)
+	}
+	if prompts := model.prompts(); len(prompts) != 1 || prompts[0] != "Classify: I cannot log in" {
+		t.Errorf("unexpected prompts: %v", prompts)
+	}
+	if ticketTool.args["title"] != "Login fails" {
+		t.Errorf("unexpected tool arguments: %v", ticketTool.args)
+	}
+	want := map[string]string{
+		"classify": event.WorkflowNodeCompleted,
+		"route":    event.WorkflowNodeCompleted,
+		"ticket":   event.WorkflowNodeCompleted,
+		"faq":      event.WorkflowNodeSkipped,
+		"done":     event.WorkflowNodeCompleted,
+	}
+	for id, status := range want {
+		if statuses[id] != status {
+			t.Errorf("node %s: status %q, want %q", id, statuses[id], status)
+		}
+	}
+	if len(state.RoundSteps) != 4 {
+		t.Errorf("expected 4 recorded steps, got %d", len(state.RoundSteps))
+	}
+}
+
+func TestValidateWorkflow(t *testing.T) {
+	tests := []struct {
+		name  string
+		nodes []types.WorkflowNode
+		err   string
+	}{
+		{
+			name: "cycle",
+			nodes: []types.WorkflowNode{
+				{ID: "a", Type: types.WorkflowNodeLLM, Prompt: "x", DependsOn: []string{"b"}},
+				{ID: "b", Type: types.WorkflowNodeLLM, Prompt: "x", DependsOn: []string{"a"}},
+			},
+			err: "cycle",
+		},
+		{
+			name: "reference outside dependencies",
+			nodes: []types.WorkflowNode{
+				{ID: "a", Type: types.WorkflowNodeLLM, Prompt: "x"},
+				{ID: "b", Type: types.WorkflowNodeLLM, Prompt: "{{a}}"},
+			},
+			err: "not one of its dependencies",
+		},
+		{
+			name: "when without condition",
+			nodes: []types.WorkflowNode{
+				{ID: "a", Type: types.WorkflowNodeRetrieve},
+				{ID: "b", Type: types.WorkflowNodeRerank, DependsOn: []string{"a"}, When: "yes"},
+			},
+			err: "exactly one condition node",
+		},
+		{
+			name: "approval required tool",
+			nodes: []types.WorkflowNode{
+				{ID: "a", Type: types.WorkflowNodeTool, Tool: "delete_user"},
+			},
+			err: "requires approval",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &types.CustomAgentConfig{
+				AgentMode:             types.AgentModeWorkflow,
+				Workflow:              &types.WorkflowDefinition{Nodes: tt.nodes},
+				ApprovalRequiredTools: []string{"delete_user"},
+			}
+			err := config.ValidateWorkflow()
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("expected error containing %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
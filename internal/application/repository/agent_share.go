@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/Tencent/WeKnora/internal/types"
 	"github.com/Tencent/WeKnora/internal/types/interfaces"
@@ -71,6 +72,13 @@ func (r *agentShareRepository) Update(ctx context.Context, share *types.AgentSha
 		Where("id = ?", share.ID).Updates(share).Error
 }
 
+// UpdatePinnedVersion sets the agent version pinned by a share record, 0 unpins it
+func (r *agentShareRepository) UpdatePinnedVersion(ctx context.Context, id string, version int) error {
+	return r.db.WithContext(ctx).Model(&types.AgentShare{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"pinned_version": version, "updated_at": time.Now()}).Error
+}
+
 // Delete soft deletes a share record
 func (r *agentShareRepository) Delete(ctx context.Context, id string) error {
 	return r.db.WithContext(ctx).Where("id = ?", id).Delete(&types.AgentShare{}).Error
@@ -0,0 +1,226 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"gorm.io/gorm"
+)
+
+// agentTaskRepository implements the AgentTaskRepository interface
+type agentTaskRepository struct {
+	db *gorm.DB
+}
+
+// NewAgentTaskRepository creates a new agent task repository
+func NewAgentTaskRepository(db *gorm.DB) interfaces.AgentTaskRepository {
+	return &agentTaskRepository{db: db}
+}
+
+// Create creates a new agent task
+func (r *agentTaskRepository) Create(ctx context.Context, task *types.AgentTask) error {
+	return r.db.WithContext(ctx).Create(task).Error
+}
+
+// GetByID retrieves an agent task of a tenant by ID
+func (r *agentTaskRepository) GetByID(ctx context.Context, tenantID uint64, id string) (*types.AgentTask, error) {
+	var task types.AgentTask
+	err := r.db.WithContext(ctx).
+		Where("id = ? AND tenant_id = ?", id, tenantID).
+		First(&task).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &task, nil
+}
+
+// List retrieves all agent tasks of a tenant
+func (r *agentTaskRepository) List(ctx context.Context, tenantID uint64) ([]*types.AgentTask, error) {
+	var tasks []*types.AgentTask
+	if err := r.db.WithContext(ctx).
+		Where("tenant_id = ?", tenantID).
+		Order("created_at DESC").
+		Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+
+	return tasks, nil
+}
+
+// ListEnabledByTrigger retrieves the enabled agent tasks of a tenant with a trigger
+func (r *agentTaskRepository) ListEnabledByTrigger(
+	ctx context.Context,
+	tenantID uint64,
+	trigger string,
+) ([]*types.AgentTask, error) {
+	var tasks []*types.AgentTask
+	if err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND trigger = ? AND enabled = ?", tenantID, trigger, true).
+		Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+
+	return tasks, nil
+}
+
+// ListDue retrieves the enabled schedule tasks of all tenants due at now, the most overdue first
+func (r *agentTaskRepository) ListDue(ctx context.Context, now time.Time, limit int) ([]*types.AgentTask, error) {
+	var tasks []*types.AgentTask
+	if err := r.db.WithContext(ctx).
+		Where("trigger = ? AND enabled = ? AND next_run_at <= ?", types.AgentTaskTriggerSchedule, true, now).
+		Order("next_run_at").
+		Limit(limit).
+		Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+
+	return tasks, nil
+}
+
+// ClaimNextRun moves the next run of a schedule task only if it is still due,
+// so that a run is enqueued once when several schedulers see the task.
+// A nil next disables the task in the same update.
+func (r *agentTaskRepository) ClaimNextRun(
+	ctx context.Context,
+	task *types.AgentTask,
+	now time.Time,
+	next *time.Time,
+) (bool, error) {
+	updates := map[string]interface{}{"next_run_at": next}
+	if next == nil {
+		updates["enabled"] = false
+	}
+	result := r.db.WithContext(ctx).
+		Model(&types.AgentTask{}).
+		Where("id = ? AND tenant_id = ? AND next_run_at <= ?", task.ID, task.TenantID, now).
+		Updates(updates)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// Update saves an agent task
+func (r *agentTaskRepository) Update(ctx context.Context, task *types.AgentTask) error {
+	return r.db.WithContext(ctx).
+		Model(&types.AgentTask{}).
+		Where("id = ? AND tenant_id = ?", task.ID, task.TenantID).
+		Updates(map[string]interface{}{
+			"name":               task.Name,
+			"description":        task.Description,
+			"agent_id":           task.AgentID,
+			"trigger":            task.Trigger,
+			"schedule":           task.Schedule,
+			"timezone":           task.Timezone,
+			"knowledge_base_ids": task.KnowledgeBaseIDs,
+			"prompt":             task.Prompt,
+			"webhook_id":         task.WebhookID,
+			"enabled":            task.Enabled,
+			"next_run_at":        task.NextRunAt,
+			"updated_at":         task.UpdatedAt,
+		}).Error
+}
+
+// UpdateLastRun saves the start time and status of the last run of a task
+func (r *agentTaskRepository) UpdateLastRun(
+	ctx context.Context,
+	tenantID uint64,
+	id string,
+	at time.Time,
+	status string,
+) error {
+	return r.db.WithContext(ctx).
+		Model(&types.AgentTask{}).
+		Where("id = ? AND tenant_id = ?", id, tenantID).
+		Updates(map[string]interface{}{
+			"last_run_at":     at,
+			"last_run_status": status,
+		}).Error
+}
+
+// Delete deletes an agent task and its runs
+func (r *agentTaskRepository) Delete(ctx context.Context, tenantID uint64, id string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("task_id = ? AND tenant_id = ?", id, tenantID).
+			Delete(&types.AgentTaskRun{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ? AND tenant_id = ?", id, tenantID).
+			Delete(&types.AgentTask{}).Error
+	})
+}
+
+// CreateRun creates a new run record
+func (r *agentTaskRepository) CreateRun(ctx context.Context, run *types.AgentTaskRun) error {
+	return r.db.WithContext(ctx).Create(run).Error
+}
+
+// GetRun retrieves a run of a tenant by ID
+func (r *agentTaskRepository) GetRun(ctx context.Context, tenantID uint64, id string) (*types.AgentTaskRun, error) {
+	var run types.AgentTaskRun
+	err := r.db.WithContext(ctx).
+		Where("id = ? AND tenant_id = ?", id, tenantID).
+		First(&run).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &run, nil
+}
+
+// ListRuns retrieves the runs of a task, newest first
+func (r *agentTaskRepository) ListRuns(
+	ctx context.Context,
+	tenantID uint64,
+	taskID string,
+	page *types.Pagination,
+) ([]*types.AgentTaskRun, int64, error) {
+	query := r.db.WithContext(ctx).
+		Model(&types.AgentTaskRun{}).
+		Where("tenant_id = ? AND task_id = ?", tenantID, taskID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var runs []*types.AgentTaskRun
+	if err := query.
+		Order("created_at DESC").
+		Offset(page.Offset()).
+		Limit(page.Limit()).
+		Find(&runs).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return runs, total, nil
+}
+
+// UpdateRun saves the progress of a run
+func (r *agentTaskRepository) UpdateRun(ctx context.Context, run *types.AgentTaskRun) error {
+	return r.db.WithContext(ctx).
+		Model(&types.AgentTaskRun{}).
+		Where("id = ? AND tenant_id = ?", run.ID, run.TenantID).
+		Updates(map[string]interface{}{
+			"status":            run.Status,
+			"prompt":            run.Prompt,
+			"session_id":        run.SessionID,
+			"message_id":        run.MessageID,
+			"output":            run.Output,
+			"structured_output": run.StructuredOutput,
+			"error":             run.Error,
+			"started_at":        run.StartedAt,
+			"finished_at":       run.FinishedAt,
+			"updated_at":        run.UpdatedAt,
+		}).Error
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// newAgentTaskTestRepository returns a repository holding an enabled schedule task due at due
+func newAgentTaskTestRepository(t *testing.T, due time.Time) (*agentTaskRepository, *types.AgentTask) {
+	t.Helper()
+	db := newTestDB(t)
+	if err := db.AutoMigrate(&types.AgentTask{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	task := &types.AgentTask{
+		ID:        "task-1",
+		TenantID:  1,
+		Trigger:   types.AgentTaskTriggerSchedule,
+		Schedule:  "0 9 * * *",
+		Enabled:   true,
+		NextRunAt: &due,
+	}
+	if err := db.Create(task).Error; err != nil {
+		t.Fatal(err)
+	}
+	return &agentTaskRepository{db: db}, task
+}
+
+func TestClaimNextRunDisablesTaskWithoutNextRun(t *testing.T) {
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	repo, task := newAgentTaskTestRepository(t, now)
+	ctx := context.Background()
+
+	claimed, err := repo.ClaimNextRun(ctx, task, now, nil)
+	if err != nil || !claimed {
+		t.Fatalf("ClaimNextRun() = %v, %v, want claimed", claimed, err)
+	}
+	saved, err := repo.GetByID(ctx, 1, task.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Enabled || saved.NextRunAt != nil {
+		t.Errorf("task after claiming without a next run: enabled %v, next run %v, want disabled", saved.Enabled, saved.NextRunAt)
+	}
+	if due, err := repo.ListDue(ctx, now.Add(time.Hour), 10); err != nil || len(due) != 0 {
+		t.Errorf("ListDue() = %d tasks, %v, want none", len(due), err)
+	}
+}
+
+func TestClaimNextRunOnce(t *testing.T) {
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	repo, task := newAgentTaskTestRepository(t, now)
+	ctx := context.Background()
+	next := now.Add(24 * time.Hour)
+
+	// Two schedulers listed the task with the same stale next run
+	first := *task
+	second := *task
+	claimed, err := repo.ClaimNextRun(ctx, &first, now, &next)
+	if err != nil || !claimed {
+		t.Fatalf("first ClaimNextRun() = %v, %v, want claimed", claimed, err)
+	}
+	claimed, err = repo.ClaimNextRun(ctx, &second, now, &next)
+	if err != nil || claimed {
+		t.Fatalf("second ClaimNextRun() = %v, %v, want not claimed", claimed, err)
+	}
+
+	saved, err := repo.GetByID(ctx, 1, task.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !saved.Enabled || saved.NextRunAt == nil || !saved.NextRunAt.Equal(next) {
+		t.Errorf("task after the claims: enabled %v, next run %v, want enabled at %v", saved.Enabled, saved.NextRunAt, next)
+	}
+
+	// A task of another tenant with the same ID is not claimed
+	other := *task
+	other.TenantID = 2
+	if claimed, err := repo.ClaimNextRun(ctx, &other, next, &next); err != nil || claimed {
+		t.Errorf("ClaimNextRun() of another tenant = %v, %v, want not claimed", claimed, err)
+	}
+	// The next run is claimed once it is due again
+	later := next.Add(24 * time.Hour)
+	if claimed, err := repo.ClaimNextRun(ctx, task, next, &later); err != nil || !claimed {
+		t.Errorf("ClaimNextRun() of the next run = %v, %v, want claimed", claimed, err)
+	}
+}
@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"gorm.io/gorm"
+)
+
+// agentTestRepository implements the AgentTestRepository interface
+type agentTestRepository struct {
+	db *gorm.DB
+}
+
+// NewAgentTestRepository creates a new agent test repository
+func NewAgentTestRepository(db *gorm.DB) interfaces.AgentTestRepository {
+	return &agentTestRepository{db: db}
+}
+
+// CreateCase creates a new test case
+func (r *agentTestRepository) CreateCase(ctx context.Context, testCase *types.AgentTestCase) error {
+	return r.db.WithContext(ctx).Create(testCase).Error
+}
+
+// GetCase retrieves a test case of a tenant by ID
+func (r *agentTestRepository) GetCase(ctx context.Context, tenantID uint64, id string) (*types.AgentTestCase, error) {
+	var testCase types.AgentTestCase
+	err := r.db.WithContext(ctx).
+		Where("id = ? AND tenant_id = ?", id, tenantID).
+		First(&testCase).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &testCase, nil
+}
+
+// ListCases retrieves the test cases of an agent, oldest first
+func (r *agentTestRepository) ListCases(
+	ctx context.Context,
+	tenantID uint64,
+	agentID string,
+) ([]*types.AgentTestCase, error) {
+	var testCases []*types.AgentTestCase
+	if err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND agent_id = ?", tenantID, agentID).
+		Order("created_at").
+		Find(&testCases).Error; err != nil {
+		return nil, err
+	}
+
+	return testCases, nil
+}
+
+// CountCases counts the test cases of an agent
+func (r *agentTestRepository) CountCases(ctx context.Context, tenantID uint64, agentID string) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&types.AgentTestCase{}).
+		Where("tenant_id = ? AND agent_id = ?", tenantID, agentID).
+		Count(&count).Error
+	return count, err
+}
+
+// UpdateCase saves a test case
+func (r *agentTestRepository) UpdateCase(ctx context.Context, testCase *types.AgentTestCase) error {
+	return r.db.WithContext(ctx).
+		Model(&types.AgentTestCase{}).
+		Where("id = ? AND tenant_id = ?", testCase.ID, testCase.TenantID).
+		Updates(map[string]interface{}{
+			"name":               testCase.Name,
+			"query":              testCase.Query,
+			"knowledge_base_ids": testCase.KnowledgeBaseIDs,
+			"knowledge_ids":      testCase.KnowledgeIDs,
+			"assertions":         testCase.Assertions,
+			"max_rounds":         testCase.MaxRounds,
+			"updated_at":         testCase.UpdatedAt,
+		}).Error
+}
+
+// DeleteCase deletes a test case
+func (r *agentTestRepository) DeleteCase(ctx context.Context, tenantID uint64, id string) error {
+	return r.db.WithContext(ctx).
+		Where("id = ? AND tenant_id = ?", id, tenantID).
+		Delete(&types.AgentTestCase{}).Error
+}
+
+// CreateRun creates a new test run
+func (r *agentTestRepository) CreateRun(ctx context.Context, run *types.AgentTestRun) error {
+	return r.db.WithContext(ctx).Create(run).Error
+}
+
+// GetRun retrieves a test run of a tenant by ID
+func (r *agentTestRepository) GetRun(ctx context.Context, tenantID uint64, id string) (*types.AgentTestRun, error) {
+	var run types.AgentTestRun
+	err := r.db.WithContext(ctx).
+		Where("id = ? AND tenant_id = ?", id, tenantID).
+		First(&run).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &run, nil
+}
+
+// ListRuns retrieves the test runs of an agent, newest first.
+// Results are left out, they are returned with a single run.
+func (r *agentTestRepository) ListRuns(
+	ctx context.Context,
+	tenantID uint64,
+	agentID string,
+	page *types.Pagination,
+) ([]*types.AgentTestRun, int64, error) {
+	query := r.db.WithContext(ctx).
+		Model(&types.AgentTestRun{}).
+		Where("tenant_id = ? AND agent_id = ?", tenantID, agentID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var runs []*types.AgentTestRun
+	if err := query.
+		Omit("results").
+		Order("created_at DESC").
+		Offset(page.Offset()).
+		Limit(page.Limit()).
+		Find(&runs).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return runs, total, nil
+}
+
+// UpdateRun saves the progress of a test run
+func (r *agentTestRepository) UpdateRun(ctx context.Context, run *types.AgentTestRun) error {
+	return r.db.WithContext(ctx).
+		Model(&types.AgentTestRun{}).
+		Where("id = ? AND tenant_id = ?", run.ID, run.TenantID).
+		Updates(map[string]interface{}{
+			"status":       run.Status,
+			"agent_config": run.AgentConfig,
+			"config_hash":  run.ConfigHash,
+			"session_id":   run.SessionID,
+			"total":        run.Total,
+			"passed":       run.Passed,
+			"failed":       run.Failed,
+			"results":      run.Results,
+			"error":        run.Error,
+			"started_at":   run.StartedAt,
+			"finished_at":  run.FinishedAt,
+			"updated_at":   run.UpdatedAt,
+		}).Error
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"gorm.io/gorm"
+)
+
+// agentTraceRepository implements the AgentTraceRepository interface
+type agentTraceRepository struct {
+	db *gorm.DB
+}
+
+// NewAgentTraceRepository creates a new agent trace repository
+func NewAgentTraceRepository(db *gorm.DB) interfaces.AgentTraceRepository {
+	return &agentTraceRepository{db: db}
+}
+
+// Create saves the trace of a run
+func (r *agentTraceRepository) Create(ctx context.Context, trace *types.AgentTrace) error {
+	return r.db.WithContext(ctx).Create(trace).Error
+}
+
+// ListByMessage retrieves the traces of the runs of an assistant message, oldest first
+func (r *agentTraceRepository) ListByMessage(
+	ctx context.Context,
+	tenantID uint64,
+	sessionID, messageID string,
+) ([]*types.AgentTrace, error) {
+	var traces []*types.AgentTrace
+	if err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND session_id = ? AND message_id = ?", tenantID, sessionID, messageID).
+		Order("created_at").
+		Find(&traces).Error; err != nil {
+		return nil, err
+	}
+
+	return traces, nil
+}
+
+// DeleteBySession deletes the traces of all runs of a session
+func (r *agentTraceRepository) DeleteBySession(ctx context.Context, tenantID uint64, sessionID string) error {
+	return r.db.WithContext(ctx).
+		Where("tenant_id = ? AND session_id = ?", tenantID, sessionID).
+		Delete(&types.AgentTrace{}).Error
+}
+
+// DeleteByMessage deletes the traces of the runs of an assistant message
+func (r *agentTraceRepository) DeleteByMessage(
+	ctx context.Context,
+	tenantID uint64,
+	sessionID, messageID string,
+) error {
+	return r.db.WithContext(ctx).
+		Where("tenant_id = ? AND session_id = ? AND message_id = ?", tenantID, sessionID, messageID).
+		Delete(&types.AgentTrace{}).Error
+}
+
+// DeleteBefore deletes the traces of all tenants created before the given time
+func (r *agentTraceRepository) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("created_at < ?", before).
+		Delete(&types.AgentTrace{})
+	return result.RowsAffected, result.Error
+}
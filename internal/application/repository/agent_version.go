@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"gorm.io/gorm"
+)
+
+// agentVersionRepository implements the AgentVersionRepository interface
+type agentVersionRepository struct {
+	db *gorm.DB
+}
+
+// NewAgentVersionRepository creates a new agent version repository
+func NewAgentVersionRepository(db *gorm.DB) interfaces.AgentVersionRepository {
+	return &agentVersionRepository{db: db}
+}
+
+// Publish saves a new version and makes it the published version of its agent.
+// The unique index on (tenant_id, agent_id, version) rejects concurrent publishes of the same number.
+func (r *agentVersionRepository) Publish(ctx context.Context, version *types.AgentVersion) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(version).Error; err != nil {
+			return err
+		}
+		return setPublishedVersion(tx, version.TenantID, version.AgentID, version.Version)
+	})
+}
+
+// GetVersion retrieves a version of an agent, nil when it does not exist
+func (r *agentVersionRepository) GetVersion(
+	ctx context.Context,
+	tenantID uint64,
+	agentID string,
+	version int,
+) (*types.AgentVersion, error) {
+	var agentVersion types.AgentVersion
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND agent_id = ? AND version = ?", tenantID, agentID, version).
+		First(&agentVersion).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &agentVersion, nil
+}
+
+// ListVersions retrieves the versions of an agent, newest first
+func (r *agentVersionRepository) ListVersions(
+	ctx context.Context,
+	tenantID uint64,
+	agentID string,
+) ([]*types.AgentVersion, error) {
+	var versions []*types.AgentVersion
+	if err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND agent_id = ?", tenantID, agentID).
+		Order("version DESC").
+		Find(&versions).Error; err != nil {
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+// LatestVersion returns the highest version number of an agent, 0 when it has none
+func (r *agentVersionRepository) LatestVersion(ctx context.Context, tenantID uint64, agentID string) (int, error) {
+	var latest int
+	err := r.db.WithContext(ctx).
+		Model(&types.AgentVersion{}).
+		Where("tenant_id = ? AND agent_id = ?", tenantID, agentID).
+		Select("COALESCE(MAX(version), 0)").
+		Scan(&latest).Error
+	return latest, err
+}
+
+// setPublishedVersion updates the published version of an agent without touching its draft
+func setPublishedVersion(db *gorm.DB, tenantID uint64, agentID string, version int) error {
+	return db.Model(&types.CustomAgent{}).
+		Where("id = ? AND tenant_id = ?", agentID, tenantID).
+		Updates(map[string]interface{}{"published_version": version, "updated_at": time.Now()}).Error
+}
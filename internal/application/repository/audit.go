@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"gorm.io/gorm"
+)
+
+// auditLogRepository implements the AuditLogRepository interface
+type auditLogRepository struct {
+	db *gorm.DB
+}
+
+// NewAuditLogRepository creates a new audit log repository
+func NewAuditLogRepository(db *gorm.DB) interfaces.AuditLogRepository {
+	return &auditLogRepository{db: db}
+}
+
+// Create appends an audit log
+func (r *auditLogRepository) Create(ctx context.Context, log *types.AuditLog) error {
+	return r.db.WithContext(ctx).Create(log).Error
+}
+
+// List retrieves the audit logs of a tenant matching the filter, newest first
+func (r *auditLogRepository) List(
+	ctx context.Context,
+	tenantID uint64,
+	filter *types.AuditLogFilter,
+	page *types.Pagination,
+) ([]*types.AuditLog, int64, error) {
+	query := r.db.WithContext(ctx).
+		Model(&types.AuditLog{}).
+		Where("tenant_id = ?", tenantID)
+	if filter.ActorID != "" {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.ResourceType != "" {
+		query = query.Where("resource_type = ?", filter.ResourceType)
+	}
+	if filter.ResourceID != "" {
+		query = query.Where("resource_id = ?", filter.ResourceID)
+	}
+	if filter.Result != "" {
+		query = query.Where("result = ?", filter.Result)
+	}
+	if filter.StartTime != nil {
+		query = query.Where("created_at >= ?", *filter.StartTime)
+	}
+	if filter.EndTime != nil {
+		query = query.Where("created_at < ?", *filter.EndTime)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var logs []*types.AuditLog
+	if err := query.
+		Order("created_at DESC").
+		Offset(page.Offset()).
+		Limit(page.Limit()).
+		Find(&logs).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return logs, total, nil
+}
+
+// DeleteBefore deletes the audit logs of all tenants created before the given time
+func (r *auditLogRepository) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("created_at < ?", before).
+		Delete(&types.AuditLog{})
+	return result.RowsAffected, result.Error
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/Tencent/WeKnora/internal/common"
 	"github.com/Tencent/WeKnora/internal/types"
@@ -158,12 +159,16 @@ func (r *chunkRepository) ListPagedChunksByKnowledgeID(
 			// FAQ type: search based on searchField
 			// 根据数据库类型使用不同的 JSON 查询语法
 			isPostgres := db.Dialector.Name() == "postgres"
+			// SQLite 将 BLOB 视为 JSONB 二进制格式，需先转换为文本
+			isSQLite := db.Dialector.Name() == "sqlite"
 
 			switch searchField {
 			case "standard_question":
 				// Search only in standard_question field of metadata
 				if isPostgres {
 					db = db.Where("metadata->>'standard_question' ILIKE ?", like)
+				} else if isSQLite {
+					db = db.Where("json_extract(CAST(metadata AS TEXT), '$.standard_question') LIKE ?", like)
 				} else {
 					// MySQL: metadata->>'$.standard_question' (MySQL 5.7.13+)
 					// 也可以用 JSON_UNQUOTE(JSON_EXTRACT(metadata, '$.standard_question'))
@@ -173,6 +178,8 @@ func (r *chunkRepository) ListPagedChunksByKnowledgeID(
 				// Search in similar_questions array of metadata
 				if isPostgres {
 					db = db.Where("metadata->'similar_questions'::text ILIKE ?", like)
+				} else if isSQLite {
+					db = db.Where("json_extract(CAST(metadata AS TEXT), '$.similar_questions') LIKE ?", like)
 				} else {
 					db = db.Where("JSON_EXTRACT(metadata, '$.similar_questions') LIKE ?", like)
 				}
@@ -180,6 +187,8 @@ func (r *chunkRepository) ListPagedChunksByKnowledgeID(
 				// Search in answers array of metadata
 				if isPostgres {
 					db = db.Where("metadata->'answers'::text ILIKE ?", like)
+				} else if isSQLite {
+					db = db.Where("json_extract(CAST(metadata AS TEXT), '$.answers') LIKE ?", like)
 				} else {
 					db = db.Where("JSON_EXTRACT(metadata, '$.answers') LIKE ?", like)
 				}
@@ -335,11 +344,11 @@ func (r *chunkRepository) UpdateChunks(ctx context.Context, chunks []*types.Chun
 	sql := fmt.Sprintf(`
 		UPDATE chunks SET
 			content = CASE %s END,
-			is_enabled = (CASE %s END)::boolean,
+			is_enabled = ((CASE %s END) = 'true'),
 			tag_id = CASE %s END,
-			flags = (CASE %s END)::integer,
-			status = (CASE %s END)::integer,
-			updated_at = NOW()
+			flags = CAST(CASE %s END AS INTEGER),
+			status = CAST(CASE %s END AS INTEGER),
+			updated_at = CURRENT_TIMESTAMP
 		WHERE id IN (%s)
 	`,
 		strings.Join(contentCases, " "),
@@ -586,6 +595,59 @@ func (r *chunkRepository) ListAllFAQChunksForExport(
 	return allChunks, nil
 }
 
+// ListAllChunksForIndexCheck lists all chunks of a knowledge base for index consistency checks
+// Uses keyset pagination on id to handle large knowledge bases
+func (r *chunkRepository) ListAllChunksForIndexCheck(
+	ctx context.Context,
+	tenantID uint64,
+	kbID string,
+) ([]*types.Chunk, error) {
+	const batchSize = 1000
+	var allChunks []*types.Chunk
+	lastID := ""
+
+	for {
+		var batchChunks []*types.Chunk
+		if err := r.db.WithContext(ctx).
+			Select("id, knowledge_id, knowledge_base_id, chunk_type, status, tag_id, is_enabled").
+			Where("tenant_id = ? AND knowledge_base_id = ? AND id > ?", tenantID, kbID, lastID).
+			Order("id ASC").
+			Limit(batchSize).
+			Find(&batchChunks).Error; err != nil {
+			return nil, err
+		}
+		allChunks = append(allChunks, batchChunks...)
+		if len(batchChunks) < batchSize {
+			break
+		}
+		lastID = batchChunks[len(batchChunks)-1].ID
+	}
+
+	return allChunks, nil
+}
+
+// ListChunksForExport lists a page of the chunks of a knowledge base with all fields, ordered by ID
+func (r *chunkRepository) ListChunksForExport(
+	ctx context.Context,
+	tenantID uint64,
+	kbID string,
+	afterID string,
+	limit int,
+) ([]*types.Chunk, error) {
+	var chunks []*types.Chunk
+	if err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND knowledge_base_id = ? AND id > ?", tenantID, kbID, afterID).
+		Where("chunk_type NOT IN ?", []types.ChunkType{
+			types.ChunkTypeEntity, types.ChunkTypeRelationship, types.ChunkTypeWebSearch,
+		}).
+		Order("id ASC").
+		Limit(limit).
+		Find(&chunks).Error; err != nil {
+		return nil, err
+	}
+	return chunks, nil
+}
+
 // UpdateChunkFlagsBatch updates flags for multiple chunks in batch using SQL CASE expressions.
 // This is more efficient than updating chunks one by one.
 // setFlags: map of chunk ID to flags to set (OR operation)
@@ -653,7 +715,7 @@ func (r *chunkRepository) UpdateChunkFlagsBatch(
 	sql := fmt.Sprintf(`
 	UPDATE chunks 
     SET flags = (flags | (%s)) & ~(%s),
-        updated_at = NOW()
+        updated_at = CURRENT_TIMESTAMP
     WHERE tenant_id = ? 
       AND knowledge_base_id = ?
       AND id IN (%s)
@@ -709,7 +771,7 @@ func (r *chunkRepository) UpdateChunkFieldsByTagID(
 
 	// Build update query
 	updates := map[string]interface{}{
-		"updated_at": "NOW()",
+		"updated_at": time.Now(),
 	}
 
 	if isEnabled != nil {
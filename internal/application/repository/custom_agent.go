@@ -51,9 +51,9 @@ func (r *customAgentRepository) ListAgentsByTenantID(ctx context.Context, tenant
 	return agents, nil
 }
 
-// UpdateAgent updates an agent
+// UpdateAgent updates an agent, its published version is only changed by publishing or rolling back
 func (r *customAgentRepository) UpdateAgent(ctx context.Context, agent *types.CustomAgent) error {
-	return r.db.WithContext(ctx).Save(agent).Error
+	return r.db.WithContext(ctx).Omit("published_version").Save(agent).Error
 }
 
 // DeleteAgent deletes an agent (soft delete)
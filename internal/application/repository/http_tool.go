@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"gorm.io/gorm"
+)
+
+// httpToolRepository implements the HTTPToolRepository interface
+type httpToolRepository struct {
+	db *gorm.DB
+}
+
+// NewHTTPToolRepository creates a new HTTP tool repository
+func NewHTTPToolRepository(db *gorm.DB) interfaces.HTTPToolRepository {
+	return &httpToolRepository{db: db}
+}
+
+// Create creates a new HTTP tool
+func (r *httpToolRepository) Create(ctx context.Context, tool *types.HTTPTool) error {
+	return r.db.WithContext(ctx).Create(tool).Error
+}
+
+// GetByID retrieves an HTTP tool of a tenant by ID
+func (r *httpToolRepository) GetByID(ctx context.Context, tenantID uint64, id string) (*types.HTTPTool, error) {
+	var tool types.HTTPTool
+	err := r.db.WithContext(ctx).
+		Where("id = ? AND tenant_id = ?", id, tenantID).
+		First(&tool).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &tool, nil
+}
+
+// GetByName retrieves an HTTP tool of a tenant by name
+func (r *httpToolRepository) GetByName(ctx context.Context, tenantID uint64, name string) (*types.HTTPTool, error) {
+	var tool types.HTTPTool
+	err := r.db.WithContext(ctx).
+		Where("name = ? AND tenant_id = ?", name, tenantID).
+		First(&tool).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &tool, nil
+}
+
+// List retrieves all HTTP tools of a tenant
+func (r *httpToolRepository) List(ctx context.Context, tenantID uint64) ([]*types.HTTPTool, error) {
+	var tools []*types.HTTPTool
+	if err := r.db.WithContext(ctx).
+		Where("tenant_id = ?", tenantID).
+		Order("name ASC").
+		Find(&tools).Error; err != nil {
+		return nil, err
+	}
+
+	return tools, nil
+}
+
+// ListByIDs retrieves the HTTP tools of a tenant with the given IDs
+func (r *httpToolRepository) ListByIDs(ctx context.Context, tenantID uint64, ids []string) ([]*types.HTTPTool, error) {
+	var tools []*types.HTTPTool
+	if len(ids) == 0 {
+		return tools, nil
+	}
+	if err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND id IN ?", tenantID, ids).
+		Order("name ASC").
+		Find(&tools).Error; err != nil {
+		return nil, err
+	}
+
+	return tools, nil
+}
+
+// Update saves an HTTP tool
+func (r *httpToolRepository) Update(ctx context.Context, tool *types.HTTPTool) error {
+	return r.db.WithContext(ctx).
+		Model(&types.HTTPTool{}).
+		Where("id = ? AND tenant_id = ?", tool.ID, tool.TenantID).
+		Updates(map[string]interface{}{
+			"name":            tool.Name,
+			"description":     tool.Description,
+			"method":          tool.Method,
+			"url":             tool.URL,
+			"parameters":      tool.Parameters,
+			"arg_locations":   tool.ArgLocations,
+			"headers":         tool.Headers,
+			"auth_header":     tool.AuthHeader,
+			"auth_secret":     tool.AuthSecret,
+			"response_path":   tool.ResponsePath,
+			"timeout_seconds": tool.TimeoutSeconds,
+			"source":          tool.Source,
+			"enabled":         tool.Enabled,
+			"updated_at":      tool.UpdatedAt,
+		}).Error
+}
+
+// Delete deletes an HTTP tool
+func (r *httpToolRepository) Delete(ctx context.Context, tenantID uint64, id string) error {
+	return r.db.WithContext(ctx).
+		Where("id = ? AND tenant_id = ?", id, tenantID).
+		Delete(&types.HTTPTool{}).Error
+}
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"gorm.io/gorm"
+)
+
+// indexConsistencyRepository implements the IndexConsistencyRepository interface
+type indexConsistencyRepository struct {
+	db *gorm.DB
+}
+
+// NewIndexConsistencyRepository creates a new index consistency report repository
+func NewIndexConsistencyRepository(db *gorm.DB) interfaces.IndexConsistencyRepository {
+	return &indexConsistencyRepository{db: db}
+}
+
+// Create creates a new report
+func (r *indexConsistencyRepository) Create(ctx context.Context, report *types.IndexConsistencyReport) error {
+	return r.db.WithContext(ctx).Create(report).Error
+}
+
+// GetByID retrieves a report of a tenant by ID
+func (r *indexConsistencyRepository) GetByID(
+	ctx context.Context,
+	tenantID uint64,
+	id string,
+) (*types.IndexConsistencyReport, error) {
+	var report types.IndexConsistencyReport
+	err := r.db.WithContext(ctx).
+		Where("id = ? AND tenant_id = ?", id, tenantID).
+		First(&report).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &report, nil
+}
+
+// ListByKnowledgeBase retrieves the latest reports of a knowledge base, newest first
+func (r *indexConsistencyRepository) ListByKnowledgeBase(
+	ctx context.Context,
+	tenantID uint64,
+	kbID string,
+	limit int,
+) ([]*types.IndexConsistencyReport, error) {
+	var reports []*types.IndexConsistencyReport
+	if err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND knowledge_base_id = ?", tenantID, kbID).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&reports).Error; err != nil {
+		return nil, err
+	}
+
+	return reports, nil
+}
+
+// GetActive retrieves the pending or running check of a knowledge base, if any
+func (r *indexConsistencyRepository) GetActive(
+	ctx context.Context,
+	tenantID uint64,
+	kbID string,
+) (*types.IndexConsistencyReport, error) {
+	var report types.IndexConsistencyReport
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND knowledge_base_id = ? AND status IN ?", tenantID, kbID,
+			[]string{types.IndexConsistencyStatusPending, types.IndexConsistencyStatusRunning}).
+		Order("created_at DESC").
+		First(&report).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &report, nil
+}
+
+// Update saves the status and result of a report
+func (r *indexConsistencyRepository) Update(ctx context.Context, report *types.IndexConsistencyReport) error {
+	return r.db.WithContext(ctx).
+		Model(&types.IndexConsistencyReport{}).
+		Where("id = ?", report.ID).
+		Updates(map[string]interface{}{
+			"status":      report.Status,
+			"chunk_count": report.ChunkCount,
+			"drift_count": report.DriftCount,
+			"engines":     report.Engines,
+			"error":       report.Error,
+			"started_at":  report.StartedAt,
+			"finished_at": report.FinishedAt,
+			"updated_at":  report.UpdatedAt,
+		}).Error
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"gorm.io/gorm"
+)
+
+// indexMigrationRepository implements the IndexMigrationRepository interface
+type indexMigrationRepository struct {
+	db *gorm.DB
+}
+
+// NewIndexMigrationRepository creates a new index migration repository
+func NewIndexMigrationRepository(db *gorm.DB) interfaces.IndexMigrationRepository {
+	return &indexMigrationRepository{db: db}
+}
+
+// Create creates a new migration
+func (r *indexMigrationRepository) Create(ctx context.Context, migration *types.IndexMigration) error {
+	return r.db.WithContext(ctx).Create(migration).Error
+}
+
+// GetByID retrieves a migration of a tenant by ID
+func (r *indexMigrationRepository) GetByID(
+	ctx context.Context,
+	tenantID uint64,
+	id string,
+) (*types.IndexMigration, error) {
+	var migration types.IndexMigration
+	err := r.db.WithContext(ctx).
+		Where("id = ? AND tenant_id = ?", id, tenantID).
+		First(&migration).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &migration, nil
+}
+
+// List retrieves the migrations of a tenant, newest first
+func (r *indexMigrationRepository) List(ctx context.Context, tenantID uint64) ([]*types.IndexMigration, error) {
+	var migrations []*types.IndexMigration
+	if err := r.db.WithContext(ctx).
+		Where("tenant_id = ?", tenantID).
+		Order("created_at DESC").
+		Find(&migrations).Error; err != nil {
+		return nil, err
+	}
+
+	return migrations, nil
+}
+
+// GetActive retrieves the pending or running migration of a tenant, if any
+func (r *indexMigrationRepository) GetActive(ctx context.Context, tenantID uint64) (*types.IndexMigration, error) {
+	var migration types.IndexMigration
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND status IN ?", tenantID,
+			[]string{types.IndexMigrationStatusPending, types.IndexMigrationStatusRunning}).
+		Order("created_at DESC").
+		First(&migration).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &migration, nil
+}
+
+// Update saves the status and progress of a migration
+func (r *indexMigrationRepository) Update(ctx context.Context, migration *types.IndexMigration) error {
+	return r.db.WithContext(ctx).
+		Model(&types.IndexMigration{}).
+		Where("id = ?", migration.ID).
+		Updates(map[string]interface{}{
+			"status":          migration.Status,
+			"knowledge_bases": migration.KnowledgeBases,
+			"copied":          migration.Copied,
+			"switched":        migration.Switched,
+			"error":           migration.Error,
+			"started_at":      migration.StartedAt,
+			"finished_at":     migration.FinishedAt,
+			"updated_at":      migration.UpdatedAt,
+		}).Error
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"gorm.io/gorm"
+)
+
+// mcpOAuthCredentialRepository implements the MCPOAuthCredentialRepository interface
+type mcpOAuthCredentialRepository struct {
+	db *gorm.DB
+}
+
+// NewMCPOAuthCredentialRepository creates a new MCP OAuth credential repository
+func NewMCPOAuthCredentialRepository(db *gorm.DB) interfaces.MCPOAuthCredentialRepository {
+	return &mcpOAuthCredentialRepository{db: db}
+}
+
+// GetByServiceID retrieves the OAuth credential of an MCP service
+func (r *mcpOAuthCredentialRepository) GetByServiceID(
+	ctx context.Context,
+	tenantID uint64,
+	serviceID string,
+) (*types.MCPOAuthCredential, error) {
+	var credential types.MCPOAuthCredential
+	err := r.db.WithContext(ctx).
+		Where("service_id = ? AND tenant_id = ?", serviceID, tenantID).
+		First(&credential).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &credential, nil
+}
+
+// GetByPendingState retrieves the credential with an in-flight authorization request
+func (r *mcpOAuthCredentialRepository) GetByPendingState(
+	ctx context.Context,
+	state string,
+) (*types.MCPOAuthCredential, error) {
+	if state == "" {
+		return nil, nil
+	}
+	var credential types.MCPOAuthCredential
+	err := r.db.WithContext(ctx).
+		Where("pending_state = ?", state).
+		First(&credential).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &credential, nil
+}
+
+// Save creates or updates an OAuth credential
+func (r *mcpOAuthCredentialRepository) Save(ctx context.Context, credential *types.MCPOAuthCredential) error {
+	return r.db.WithContext(ctx).Save(credential).Error
+}
+
+// Delete deletes the OAuth credential of an MCP service
+func (r *mcpOAuthCredentialRepository) Delete(ctx context.Context, tenantID uint64, serviceID string) error {
+	return r.db.WithContext(ctx).
+		Where("service_id = ? AND tenant_id = ?", serviceID, tenantID).
+		Delete(&types.MCPOAuthCredential{}).Error
+}
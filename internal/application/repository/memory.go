@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"gorm.io/gorm"
+)
+
+// userMemoryRepository implements the UserMemoryRepository interface
+type userMemoryRepository struct {
+	db *gorm.DB
+}
+
+// NewUserMemoryRepository creates a new user memory repository
+func NewUserMemoryRepository(db *gorm.DB) interfaces.UserMemoryRepository {
+	return &userMemoryRepository{db: db}
+}
+
+// Create creates a new memory
+func (r *userMemoryRepository) Create(ctx context.Context, memory *types.UserMemory) error {
+	return r.db.WithContext(ctx).Create(memory).Error
+}
+
+// GetByID retrieves a memory of a user by ID
+func (r *userMemoryRepository) GetByID(
+	ctx context.Context,
+	tenantID uint64,
+	userID string,
+	id string,
+) (*types.UserMemory, error) {
+	var memory types.UserMemory
+	err := r.db.WithContext(ctx).
+		Where("id = ? AND tenant_id = ? AND user_id = ?", id, tenantID, userID).
+		First(&memory).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &memory, nil
+}
+
+// GetByContent retrieves a memory of a user with exactly the given content
+func (r *userMemoryRepository) GetByContent(
+	ctx context.Context,
+	tenantID uint64,
+	userID string,
+	content string,
+) (*types.UserMemory, error) {
+	var memory types.UserMemory
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND user_id = ? AND content = ?", tenantID, userID, content).
+		First(&memory).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &memory, nil
+}
+
+// List retrieves the memories of a user, newest first
+func (r *userMemoryRepository) List(
+	ctx context.Context,
+	tenantID uint64,
+	userID string,
+	page *types.Pagination,
+) ([]*types.UserMemory, int64, error) {
+	query := r.db.WithContext(ctx).
+		Model(&types.UserMemory{}).
+		Where("tenant_id = ? AND user_id = ?", tenantID, userID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var memories []*types.UserMemory
+	if err := query.
+		Order("updated_at DESC").
+		Offset(page.Offset()).
+		Limit(page.Limit()).
+		Find(&memories).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return memories, total, nil
+}
+
+// ListAll retrieves all memories of a user, newest first, for recall
+func (r *userMemoryRepository) ListAll(
+	ctx context.Context,
+	tenantID uint64,
+	userID string,
+) ([]*types.UserMemory, error) {
+	var memories []*types.UserMemory
+	if err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND user_id = ?", tenantID, userID).
+		Order("updated_at DESC").
+		Find(&memories).Error; err != nil {
+		return nil, err
+	}
+
+	return memories, nil
+}
+
+// Count counts the memories of a user
+func (r *userMemoryRepository) Count(ctx context.Context, tenantID uint64, userID string) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&types.UserMemory{}).
+		Where("tenant_id = ? AND user_id = ?", tenantID, userID).
+		Count(&count).Error
+	return count, err
+}
+
+// Touch refreshes the update time of a memory
+func (r *userMemoryRepository) Touch(ctx context.Context, memory *types.UserMemory) error {
+	memory.UpdatedAt = time.Now()
+	return r.db.WithContext(ctx).
+		Model(&types.UserMemory{}).
+		Where("id = ?", memory.ID).
+		Update("updated_at", memory.UpdatedAt).Error
+}
+
+// Delete deletes a memory of a user
+func (r *userMemoryRepository) Delete(ctx context.Context, tenantID uint64, userID string, id string) error {
+	return r.db.WithContext(ctx).
+		Where("id = ? AND tenant_id = ? AND user_id = ?", id, tenantID, userID).
+		Delete(&types.UserMemory{}).Error
+}
+
+// DeleteAll deletes all memories of a user
+func (r *userMemoryRepository) DeleteAll(ctx context.Context, tenantID uint64, userID string) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND user_id = ?", tenantID, userID).
+		Delete(&types.UserMemory{})
+	return result.RowsAffected, result.Error
+}
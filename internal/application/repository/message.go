@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"slices"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -151,3 +152,145 @@ func (r *messageRepository) GetMessageByRequestID(
 
 	return &message, nil
 }
+
+// GetAllMessagesBySession retrieves all messages of a session across branches, in creation order
+func (r *messageRepository) GetAllMessagesBySession(
+	ctx context.Context, sessionID string,
+) ([]*types.Message, error) {
+	var messages []*types.Message
+	if err := r.db.WithContext(ctx).Where(
+		"session_id = ?", sessionID,
+	).Order("created_at ASC").Find(&messages).Error; err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(messages, func(a, b *types.Message) int {
+		cmp := a.CreatedAt.Compare(b.CreatedAt)
+		if cmp == 0 && a.Role != b.Role {
+			if a.Role == "user" { // User messages come first
+				return -1
+			}
+			return 1 // Assistant messages come last
+		}
+		return cmp
+	})
+	return messages, nil
+}
+
+// ReparentMessages moves the children of a message to another parent
+func (r *messageRepository) ReparentMessages(
+	ctx context.Context, sessionID string, fromParentID string, toParentID string,
+) error {
+	return r.db.WithContext(ctx).Model(&types.Message{}).Where(
+		"session_id = ? AND parent_id = ?", sessionID, fromParentID,
+	).UpdateColumn("parent_id", toParentID).Error
+}
+
+// messageSearchRow is a message matching a history search joined with its session
+type messageSearchRow struct {
+	MessageID    string
+	SessionID    string
+	SessionTitle string
+	RequestID    string
+	Role         string
+	AgentID      string
+	Content      string
+	CreatedAt    time.Time
+}
+
+// SearchMessages finds the user and assistant messages of a tenant containing all terms, newest first
+func (r *messageRepository) SearchMessages(
+	ctx context.Context,
+	tenantID uint64,
+	terms []string,
+	filter *types.MessageSearchFilter,
+	page *types.Pagination,
+) ([]*types.MessageSearchHit, int64, error) {
+	likeOp := caseInsensitiveLike(r.db)
+	query := r.db.WithContext(ctx).
+		Model(&types.Message{}).
+		Joins("JOIN sessions ON sessions.id = messages.session_id AND sessions.deleted_at IS NULL").
+		Where("sessions.tenant_id = ?", tenantID).
+		Where("messages.role IN ?", []string{"user", "assistant"})
+	for _, term := range terms {
+		query = query.Where("messages.content "+likeOp+" ? ESCAPE '\\'", containsPattern(term))
+	}
+	if filter.AgentID != "" {
+		query = query.Where("messages.agent_id = ?", filter.AgentID)
+	}
+	if filter.KnowledgeBaseID != "" {
+		query = query.Where(messageKnowledgeBaseCondition(r.db), filter.KnowledgeBaseID, filter.KnowledgeBaseID)
+	}
+	if filter.StartTime != nil {
+		query = query.Where("messages.created_at >= ?", *filter.StartTime)
+	}
+	if filter.EndTime != nil {
+		query = query.Where("messages.created_at < ?", *filter.EndTime)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var rows []*messageSearchRow
+	if err := query.
+		Select("messages.id AS message_id, messages.session_id, sessions.title AS session_title, " +
+			"messages.request_id, messages.role, messages.agent_id, messages.content, messages.created_at").
+		Order("messages.created_at DESC").
+		Offset(page.Offset()).
+		Limit(page.Limit()).
+		Scan(&rows).Error; err != nil {
+		return nil, 0, err
+	}
+
+	hits := make([]*types.MessageSearchHit, 0, len(rows))
+	for _, row := range rows {
+		hits = append(hits, &types.MessageSearchHit{
+			SessionID:    row.SessionID,
+			SessionTitle: row.SessionTitle,
+			MessageID:    row.MessageID,
+			RequestID:    row.RequestID,
+			Role:         row.Role,
+			AgentID:      row.AgentID,
+			Content:      row.Content,
+			CreatedAt:    row.CreatedAt,
+		})
+	}
+	return hits, total, nil
+}
+
+// caseInsensitiveLike returns the case-insensitive LIKE operator of the database.
+// SQLite has no ILIKE, but its LIKE is already case-insensitive for ASCII.
+func caseInsensitiveLike(db *gorm.DB) string {
+	if db.Dialector.Name() == "sqlite" {
+		return "LIKE"
+	}
+	return "ILIKE"
+}
+
+// likeEscaper escapes the LIKE wildcards and the escape character itself
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// containsPattern returns the LIKE pattern matching values containing term literally.
+// The query must declare ESCAPE '\'.
+func containsPattern(term string) string {
+	return "%" + likeEscaper.Replace(term) + "%"
+}
+
+// messageKnowledgeBaseCondition matches messages citing a document of a knowledge base or @mentioning it.
+// The condition takes the knowledge base ID twice.
+func messageKnowledgeBaseCondition(db *gorm.DB) string {
+	if db.Dialector.Name() == "sqlite" {
+		// SQLite stores JSON columns as BLOB, convert to text first
+		return "(EXISTS (SELECT 1 FROM json_each(CAST(messages.knowledge_references AS TEXT)) AS ref " +
+			"WHERE json_extract(ref.value, '$.knowledge_id') IN (SELECT id FROM knowledges WHERE knowledge_base_id = ?)) " +
+			"OR EXISTS (SELECT 1 FROM json_each(CAST(messages.mentioned_items AS TEXT)) AS item " +
+			"WHERE json_extract(item.value, '$.id') = ?))"
+	}
+	return "(EXISTS (SELECT 1 FROM jsonb_array_elements(CASE WHEN jsonb_typeof(messages.knowledge_references) = 'array' " +
+		"THEN messages.knowledge_references ELSE '[]'::jsonb END) AS ref " +
+		"WHERE ref->>'knowledge_id' IN (SELECT id FROM knowledges WHERE knowledge_base_id = ?)) " +
+		"OR EXISTS (SELECT 1 FROM jsonb_array_elements(CASE WHEN jsonb_typeof(messages.mentioned_items) = 'array' " +
+		"THEN messages.mentioned_items ELSE '[]'::jsonb END) AS item " +
+		"WHERE item->>'id' = ?))"
+}
@@ -87,7 +87,11 @@ func (r *organizationRepository) ListSearchable(ctx context.Context, query strin
 	if query != "" {
 		pattern := "%" + query + "%"
 		// 支持按名称、描述或空间 ID 搜索，便于区分同名空间
-		q = q.Where("name ILIKE ? OR description ILIKE ? OR id::text ILIKE ?", pattern, pattern, pattern)
+		if r.db.Dialector.Name() == "sqlite" {
+			q = q.Where("name LIKE ? OR description LIKE ? OR id LIKE ?", pattern, pattern, pattern)
+		} else {
+			q = q.Where("name ILIKE ? OR description ILIKE ? OR id::text ILIKE ?", pattern, pattern, pattern)
+		}
 	}
 	err := q.Order("created_at DESC").Limit(limit).Find(&orgs).Error
 	if err != nil {
@@ -319,7 +323,7 @@ func (r *organizationRepository) UpdateJoinRequestStatus(ctx context.Context, id
 		Updates(map[string]interface{}{
 			"status":         status,
 			"reviewed_by":    reviewedBy,
-			"reviewed_at":    gorm.Expr("NOW()"),
+			"reviewed_at":    time.Now(),
 			"review_message": reviewMessage,
 		}).Error
 }
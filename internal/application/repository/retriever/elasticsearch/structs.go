@@ -1,6 +1,7 @@
 package elasticsearch
 
 import (
+	"encoding/json"
 	"maps"
 	"slices"
 
@@ -70,3 +71,32 @@ func FromDBVectorEmbeddingWithScore(id string,
 		MatchType:       matchType,
 	}
 }
+
+// indexEntryDocument is a stored document read back for index export
+type indexEntryDocument struct {
+	VectorEmbedding
+	TagID string `json:"tag_id"`
+	// Documents saved before is_enabled existed have no value and are enabled
+	IsEnabled *bool `json:"is_enabled"`
+}
+
+// DecodeIndexEntry converts a stored Elasticsearch document to an IndexEntry with its embedding
+func DecodeIndexEntry(source []byte) (*types.IndexEntry, error) {
+	var doc indexEntryDocument
+	if err := json.Unmarshal(source, &doc); err != nil {
+		return nil, err
+	}
+	return &types.IndexEntry{
+		IndexInfo: types.IndexInfo{
+			Content:         doc.Content,
+			SourceID:        doc.SourceID,
+			SourceType:      types.SourceType(doc.SourceType),
+			ChunkID:         doc.ChunkID,
+			KnowledgeID:     doc.KnowledgeID,
+			KnowledgeBaseID: doc.KnowledgeBaseID,
+			TagID:           doc.TagID,
+			IsEnabled:       doc.IsEnabled == nil || *doc.IsEnabled,
+		},
+		Embedding: doc.Embedding,
+	}, nil
+}
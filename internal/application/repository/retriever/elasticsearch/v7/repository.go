@@ -1296,3 +1296,84 @@ func (e *elasticsearchRepository) BatchUpdateChunkTagID(
 	log.Infof("[ElasticsearchV7] Successfully batch updated chunk tag ID")
 	return nil
 }
+
+// ListIndices lists the documents of a knowledge base ordered by source ID, the cursor is the last source ID returned.
+// search_after is used instead of from/size so that knowledge bases larger than the result window can be listed.
+func (e *elasticsearchRepository) ListIndices(ctx context.Context,
+	knowledgeBaseID string, dimension int, knowledgeType string, cursor string, limit int,
+) ([]*typesLocal.IndexEntry, string, error) {
+	log := logger.GetLogger(ctx)
+
+	queryBody := map[string]interface{}{
+		"query": map[string]interface{}{
+			"term": map[string]interface{}{"knowledge_base_id.keyword": knowledgeBaseID},
+		},
+		"sort": []interface{}{"source_id.keyword"},
+		"size": limit,
+	}
+	if cursor != "" {
+		queryBody["search_after"] = []interface{}{cursor}
+	}
+	queryBytes, err := json.Marshal(queryBody)
+	if err != nil {
+		log.Errorf("[ElasticsearchV7] Failed to marshal query body: %v", err)
+		return nil, "", err
+	}
+
+	response, err := e.client.Search(
+		e.client.Search.WithIndex(e.index),
+		e.client.Search.WithBody(bytes.NewReader(queryBytes)),
+		e.client.Search.WithContext(ctx),
+	)
+	if err != nil {
+		log.Errorf("[ElasticsearchV7] Failed to list indices: %v", err)
+		return nil, "", err
+	}
+	defer response.Body.Close()
+
+	if response.IsError() {
+		log.Errorf("[ElasticsearchV7] Failed to list indices: %s", response.String())
+		return nil, "", fmt.Errorf("failed to list indices: %s", response.String())
+	}
+
+	var searchResult struct {
+		Hits struct {
+			Hits []struct {
+				Source json.RawMessage `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&searchResult); err != nil {
+		log.Errorf("[ElasticsearchV7] Failed to parse list result: %v", err)
+		return nil, "", err
+	}
+
+	entries := make([]*typesLocal.IndexEntry, 0, len(searchResult.Hits.Hits))
+	for _, hit := range searchResult.Hits.Hits {
+		entry, err := elasticsearchRetriever.DecodeIndexEntry(hit.Source)
+		if err != nil {
+			log.Errorf("[ElasticsearchV7] Failed to parse index data: %v", err)
+			return nil, "", err
+		}
+		entries = append(entries, entry)
+	}
+
+	nextCursor := ""
+	if len(entries) == limit {
+		nextCursor = entries[len(entries)-1].SourceID
+	}
+	return entries, nextCursor, nil
+}
+
+// Ping checks that the Elasticsearch cluster is reachable
+func (e *elasticsearchRepository) Ping(ctx context.Context) error {
+	res, err := e.client.Ping(e.client.Ping.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return fmt.Errorf("elasticsearch ping failed: %s", res.Status())
+	}
+	return nil
+}
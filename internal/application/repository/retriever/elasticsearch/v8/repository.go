@@ -742,3 +742,55 @@ func (e *elasticsearchRepository) BatchUpdateChunkTagID(
 	log.Infof("[Elasticsearch] Successfully batch updated chunk tag ID")
 	return nil
 }
+
+// ListIndices lists the documents of a knowledge base ordered by source ID, the cursor is the last source ID returned.
+// search_after is used instead of from/size so that knowledge bases larger than the result window can be listed.
+func (e *elasticsearchRepository) ListIndices(ctx context.Context,
+	knowledgeBaseID string, dimension int, knowledgeType string, cursor string, limit int,
+) ([]*typesLocal.IndexEntry, string, error) {
+	log := logger.GetLogger(ctx)
+
+	request := &search.Request{
+		Query: &types.Query{Term: map[string]types.TermQuery{
+			"knowledge_base_id.keyword": {Value: knowledgeBaseID},
+		}},
+		Sort: []types.SortCombinations{"source_id.keyword"},
+		Size: &limit,
+	}
+	if cursor != "" {
+		request.SearchAfter = []types.FieldValue{cursor}
+	}
+	response, err := e.client.Search().Index(e.index).Request(request).Do(ctx)
+	if err != nil {
+		log.Errorf("[Elasticsearch] Failed to list indices: %v", err)
+		return nil, "", err
+	}
+
+	entries := make([]*typesLocal.IndexEntry, 0, len(response.Hits.Hits))
+	for _, hit := range response.Hits.Hits {
+		entry, err := elasticsearchRetriever.DecodeIndexEntry(hit.Source_)
+		if err != nil {
+			log.Errorf("[Elasticsearch] Failed to parse index data: %v", err)
+			return nil, "", err
+		}
+		entries = append(entries, entry)
+	}
+
+	nextCursor := ""
+	if len(entries) == limit {
+		nextCursor = entries[len(entries)-1].SourceID
+	}
+	return entries, nextCursor, nil
+}
+
+// Ping checks that the Elasticsearch cluster is reachable
+func (e *elasticsearchRepository) Ping(ctx context.Context) error {
+	ok, err := e.client.Ping().Do(ctx)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("elasticsearch ping failed")
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package local
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// BM25 parameters
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// bm25Index is an in-memory inverted index scored with Okapi BM25.
+// Exported fields are persisted with encoding/gob.
+type bm25Index struct {
+	// Postings maps a term to the term frequency in each document
+	Postings map[string]map[string]int
+	// DocTerms keeps the distinct terms of each document so it can be removed
+	DocTerms map[string][]string
+	// DocLens is the number of tokens of each document
+	DocLens  map[string]int
+	TotalLen int64
+}
+
+// newBM25Index creates an empty index
+func newBM25Index() *bm25Index {
+	return &bm25Index{
+		Postings: make(map[string]map[string]int),
+		DocTerms: make(map[string][]string),
+		DocLens:  make(map[string]int),
+	}
+}
+
+// Add indexes the tokens of a document, replacing any previous version
+func (b *bm25Index) Add(docID string, tokens []string) {
+	b.Remove(docID)
+
+	freqs := make(map[string]int, len(tokens))
+	for _, token := range tokens {
+		freqs[token]++
+	}
+	terms := make([]string, 0, len(freqs))
+	for term, tf := range freqs {
+		postings, ok := b.Postings[term]
+		if !ok {
+			postings = make(map[string]int)
+			b.Postings[term] = postings
+		}
+		postings[docID] = tf
+		terms = append(terms, term)
+	}
+	b.DocTerms[docID] = terms
+	b.DocLens[docID] = len(tokens)
+	b.TotalLen += int64(len(tokens))
+}
+
+// Remove drops a document from the index
+func (b *bm25Index) Remove(docID string) {
+	terms, ok := b.DocTerms[docID]
+	if !ok {
+		return
+	}
+	for _, term := range terms {
+		postings := b.Postings[term]
+		delete(postings, docID)
+		if len(postings) == 0 {
+			delete(b.Postings, term)
+		}
+	}
+	b.TotalLen -= int64(b.DocLens[docID])
+	delete(b.DocTerms, docID)
+	delete(b.DocLens, docID)
+}
+
+// bm25Hit is a search result
+type bm25Hit struct {
+	DocID string
+	Score float64
+}
+
+// Search scores the documents containing any query token and returns the best k that pass the filter
+func (b *bm25Index) Search(queryTokens []string, k int, filter func(docID string) bool) []bm25Hit {
+	n := len(b.DocLens)
+	if n == 0 || k <= 0 {
+		return nil
+	}
+	avgLen := float64(b.TotalLen) / float64(n)
+	if avgLen == 0 {
+		avgLen = 1
+	}
+
+	scores := make(map[string]float64)
+	seen := make(map[string]bool, len(queryTokens))
+	for _, term := range queryTokens {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+		postings := b.Postings[term]
+		if len(postings) == 0 {
+			continue
+		}
+		df := float64(len(postings))
+		idf := math.Log(1 + (float64(n)-df+0.5)/(df+0.5))
+		for docID, tf := range postings {
+			if filter != nil && !filter(docID) {
+				continue
+			}
+			norm := bm25K1 * (1 - bm25B + bm25B*float64(b.DocLens[docID])/avgLen)
+			scores[docID] += idf * float64(tf) * (bm25K1 + 1) / (float64(tf) + norm)
+		}
+	}
+
+	hits := make([]bm25Hit, 0, len(scores))
+	for docID, score := range scores {
+		hits = append(hits, bm25Hit{DocID: docID, Score: score})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].DocID < hits[j].DocID
+	})
+	if len(hits) > k {
+		hits = hits[:k]
+	}
+	return hits
+}
+
+// tokenize splits text into lowercase search tokens.
+// It uses jieba in search mode so that Chinese text is segmented into words.
+func tokenize(text string) []string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+	words := types.Jieba.CutForSearch(text, true)
+	tokens := make([]string, 0, len(words))
+	for _, word := range words {
+		word = strings.ToLower(strings.TrimSpace(word))
+		if word == "" || !strings.ContainsFunc(word, isWordRune) {
+			continue
+		}
+		tokens = append(tokens, word)
+	}
+	return tokens
+}
+
+// isWordRune reports whether r is a letter or digit
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
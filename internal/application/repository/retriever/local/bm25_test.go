@@ -0,0 +1,62 @@
+package local
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBM25Scoring(t *testing.T) {
+	index := newBM25Index()
+	index.Add("short", []string{"apple", "banana"})
+	index.Add("long", []string{"apple", "apple", "apple", "cherry", "date", "elder"})
+	index.Add("other", []string{"cherry"})
+
+	hits := index.Search([]string{"apple"}, 10, nil)
+	if len(hits) != 2 {
+		t.Fatalf("Search(apple) returned %d hits, want 2: %v", len(hits), hits)
+	}
+
+	// Expected score of "short" by the Okapi BM25 formula
+	n, df, avgLen := 3.0, 2.0, 9.0/3.0
+	idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+	tf, docLen := 1.0, 2.0
+	want := idf * tf * (bm25K1 + 1) / (tf + bm25K1*(1-bm25B+bm25B*docLen/avgLen))
+	for _, hit := range hits {
+		if hit.DocID == "short" && math.Abs(hit.Score-want) > 1e-9 {
+			t.Errorf("score of short = %v, want %v", hit.Score, want)
+		}
+	}
+
+	// A rarer term weighs more than a common one
+	hits = index.Search([]string{"banana", "cherry"}, 10, nil)
+	if len(hits) != 3 || hits[0].DocID != "short" {
+		t.Errorf("Search(banana cherry) = %v, want short ranked first", hits)
+	}
+
+	// Filter and top k
+	hits = index.Search([]string{"apple"}, 1, func(docID string) bool { return docID != "long" })
+	if len(hits) != 1 || hits[0].DocID != "short" {
+		t.Errorf("filtered Search(apple) = %v, want [short]", hits)
+	}
+}
+
+func TestBM25AddReplaceAndRemove(t *testing.T) {
+	index := newBM25Index()
+	index.Add("doc", []string{"alpha", "beta"})
+	index.Add("doc", []string{"gamma"})
+
+	if hits := index.Search([]string{"alpha"}, 10, nil); len(hits) != 0 {
+		t.Errorf("replaced terms are still indexed: %v", hits)
+	}
+	if index.TotalLen != 1 || index.DocLens["doc"] != 1 {
+		t.Errorf("TotalLen = %d, DocLens = %v after replace", index.TotalLen, index.DocLens)
+	}
+
+	index.Remove("doc")
+	if len(index.Postings) != 0 || len(index.DocTerms) != 0 || index.TotalLen != 0 {
+		t.Errorf("index not empty after Remove: %+v", index)
+	}
+	if hits := index.Search([]string{"gamma"}, 10, nil); len(hits) != 0 {
+		t.Errorf("Search on empty index = %v", hits)
+	}
+}
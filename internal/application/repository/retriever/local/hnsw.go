@@ -0,0 +1,346 @@
+package local
+
+import (
+	"container/heap"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// HNSW parameters, see "Efficient and robust approximate nearest neighbor search
+// using Hierarchical Navigable Small World graphs" (Malkov & Yashunin)
+const (
+	hnswM              = 16  // Max neighbors per node on layers above 0
+	hnswM0             = 32  // Max neighbors per node on layer 0
+	hnswEfConstruction = 200 // Candidate list size while inserting
+	hnswEfSearch       = 64  // Minimum candidate list size while searching
+)
+
+// hnswNode is a vector in the graph. Deleted nodes stay in the graph for navigation
+// until the graph is compacted, but are never returned.
+type hnswNode struct {
+	Key     string
+	Vector  []float32
+	Friends [][]int32
+	Deleted bool
+}
+
+// hnswGraph is an in-memory HNSW index for the normalized vectors of one knowledge base and dimension.
+// Exported fields are persisted with encoding/gob.
+type hnswGraph struct {
+	KnowledgeBaseID string
+	Dimension       int
+	Nodes           []*hnswNode
+	EntryPoint      int32
+	MaxLevel        int
+	Live            int
+
+	lookup map[string]int32
+	rng    *rand.Rand
+}
+
+// newHNSWGraph creates an empty graph for the vectors of a knowledge base with the given dimension
+func newHNSWGraph(knowledgeBaseID string, dimension int) *hnswGraph {
+	g := &hnswGraph{KnowledgeBaseID: knowledgeBaseID, Dimension: dimension, EntryPoint: -1}
+	g.init()
+	return g
+}
+
+// key returns the partition of the graph
+func (g *hnswGraph) key() vectorKey {
+	return vectorKey{KnowledgeBaseID: g.KnowledgeBaseID, Dimension: g.Dimension}
+}
+
+// init rebuilds the derived state after creation or decoding
+func (g *hnswGraph) init() {
+	g.rng = rand.New(rand.NewSource(int64(len(g.Nodes)) + 1))
+	g.lookup = make(map[string]int32, len(g.Nodes))
+	for i, node := range g.Nodes {
+		if !node.Deleted {
+			g.lookup[node.Key] = int32(i)
+		}
+	}
+}
+
+// normalize returns a unit-length copy of v so that cosine similarity is a dot product
+func normalize(v []float32) []float32 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	out := make([]float32, len(v))
+	if sum == 0 {
+		return out
+	}
+	norm := float32(1 / math.Sqrt(sum))
+	for i, x := range v {
+		out[i] = x * norm
+	}
+	return out
+}
+
+// similarity returns the cosine similarity of two normalized vectors
+func similarity(a, b []float32) float32 {
+	var dot float32
+	for i := range a {
+		dot += a[i] * b[i]
+	}
+	return dot
+}
+
+// Vector returns the normalized vector stored for key
+func (g *hnswGraph) Vector(key string) ([]float32, bool) {
+	id, ok := g.lookup[key]
+	if !ok {
+		return nil, false
+	}
+	return g.Nodes[id].Vector, true
+}
+
+// Insert adds or replaces the vector of key
+func (g *hnswGraph) Insert(key string, vector []float32) {
+	g.Delete(key)
+
+	level := g.randomLevel()
+	node := &hnswNode{Key: key, Vector: normalize(vector), Friends: make([][]int32, level+1)}
+	id := int32(len(g.Nodes))
+	g.Nodes = append(g.Nodes, node)
+	g.lookup[key] = id
+	g.Live++
+
+	if g.EntryPoint < 0 {
+		g.EntryPoint = id
+		g.MaxLevel = level
+		return
+	}
+
+	ep := g.EntryPoint
+	for l := g.MaxLevel; l > level; l-- {
+		ep = g.greedyClosest(node.Vector, ep, l)
+	}
+	for l := min(level, g.MaxLevel); l >= 0; l-- {
+		candidates := g.searchLayer(node.Vector, []int32{ep}, hnswEfConstruction, l)
+		neighbors := selectNeighbors(candidates, maxFriends(l))
+		node.Friends[l] = make([]int32, 0, len(neighbors))
+		for _, c := range neighbors {
+			node.Friends[l] = append(node.Friends[l], c.id)
+			g.connect(c.id, id, l)
+		}
+		if len(candidates) > 0 {
+			ep = candidates[0].id
+		}
+	}
+	if level > g.MaxLevel {
+		g.MaxLevel = level
+		g.EntryPoint = id
+	}
+}
+
+// Delete marks the vector of key as deleted
+func (g *hnswGraph) Delete(key string) bool {
+	id, ok := g.lookup[key]
+	if !ok {
+		return false
+	}
+	g.Nodes[id].Deleted = true
+	delete(g.lookup, key)
+	g.Live--
+	return true
+}
+
+// NeedsCompaction reports whether deleted nodes make up most of the graph
+func (g *hnswGraph) NeedsCompaction() bool {
+	return len(g.Nodes) > 1000 && g.Live < len(g.Nodes)/2
+}
+
+// Compact rebuilds the graph from the live nodes
+func (g *hnswGraph) Compact() *hnswGraph {
+	rebuilt := newHNSWGraph(g.KnowledgeBaseID, g.Dimension)
+	for _, node := range g.Nodes {
+		if !node.Deleted {
+			rebuilt.Insert(node.Key, node.Vector)
+		}
+	}
+	return rebuilt
+}
+
+// hnswHit is a search result
+type hnswHit struct {
+	Key   string
+	Score float64
+}
+
+// Search returns up to k live vectors most similar to query that pass the filter.
+// The candidate list grows until enough results pass the filter or the whole graph was visited.
+func (g *hnswGraph) Search(query []float32, k int, filter func(key string) bool) []hnswHit {
+	if g.EntryPoint < 0 || k <= 0 || g.Live == 0 {
+		return nil
+	}
+	q := normalize(query)
+
+	ep := g.EntryPoint
+	for l := g.MaxLevel; l > 0; l-- {
+		ep = g.greedyClosest(q, ep, l)
+	}
+
+	ef := max(hnswEfSearch, k*4)
+	for {
+		candidates := g.searchLayer(q, []int32{ep}, ef, 0)
+		hits := make([]hnswHit, 0, k)
+		for _, c := range candidates {
+			node := g.Nodes[c.id]
+			if node.Deleted || (filter != nil && !filter(node.Key)) {
+				continue
+			}
+			hits = append(hits, hnswHit{Key: node.Key, Score: float64(c.sim)})
+			if len(hits) == k {
+				break
+			}
+		}
+		if len(hits) == k || ef >= len(g.Nodes) {
+			return hits
+		}
+		ef *= 4
+	}
+}
+
+// randomLevel draws the layer of a new node with an exponentially decaying distribution
+func (g *hnswGraph) randomLevel() int {
+	return int(math.Floor(-math.Log(1-g.rng.Float64()) / math.Log(hnswM)))
+}
+
+// maxFriends returns the neighbor limit of a layer
+func maxFriends(level int) int {
+	if level == 0 {
+		return hnswM0
+	}
+	return hnswM
+}
+
+// greedyClosest walks a layer towards the node closest to query
+func (g *hnswGraph) greedyClosest(query []float32, ep int32, level int) int32 {
+	best := ep
+	bestSim := similarity(query, g.Nodes[ep].Vector)
+	for changed := true; changed; {
+		changed = false
+		for _, f := range g.friends(best, level) {
+			if sim := similarity(query, g.Nodes[f].Vector); sim > bestSim {
+				best, bestSim, changed = f, sim, true
+			}
+		}
+	}
+	return best
+}
+
+// friends returns the neighbors of a node on a layer
+func (g *hnswGraph) friends(id int32, level int) []int32 {
+	node := g.Nodes[id]
+	if level >= len(node.Friends) {
+		return nil
+	}
+	return node.Friends[level]
+}
+
+// connect adds a link from -> to on a layer, pruning the neighbor list if it overflows
+func (g *hnswGraph) connect(from, to int32, level int) {
+	node := g.Nodes[from]
+	node.Friends[level] = append(node.Friends[level], to)
+	limit := maxFriends(level)
+	if len(node.Friends[level]) <= limit {
+		return
+	}
+	candidates := make([]candidate, 0, len(node.Friends[level]))
+	for _, f := range node.Friends[level] {
+		candidates = append(candidates, candidate{id: f, sim: similarity(node.Vector, g.Nodes[f].Vector)})
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].sim > candidates[j].sim })
+	kept := selectNeighbors(candidates, limit)
+	node.Friends[level] = node.Friends[level][:0]
+	for _, c := range kept {
+		node.Friends[level] = append(node.Friends[level], c.id)
+	}
+}
+
+// searchLayer runs a best-first search on a layer and returns up to ef candidates, most similar first
+func (g *hnswGraph) searchLayer(query []float32, entryPoints []int32, ef int, level int) []candidate {
+	visited := make(map[int32]struct{}, ef*2)
+	frontier := &maxSimHeap{}
+	results := &minSimHeap{}
+	for _, ep := range entryPoints {
+		c := candidate{id: ep, sim: similarity(query, g.Nodes[ep].Vector)}
+		visited[ep] = struct{}{}
+		heap.Push(frontier, c)
+		heap.Push(results, c)
+	}
+
+	for frontier.Len() > 0 {
+		current := heap.Pop(frontier).(candidate)
+		if results.Len() >= ef && current.sim < (*results)[0].sim {
+			break
+		}
+		for _, f := range g.friends(current.id, level) {
+			if _, seen := visited[f]; seen {
+				continue
+			}
+			visited[f] = struct{}{}
+			c := candidate{id: f, sim: similarity(query, g.Nodes[f].Vector)}
+			if results.Len() < ef || c.sim > (*results)[0].sim {
+				heap.Push(frontier, c)
+				heap.Push(results, c)
+				if results.Len() > ef {
+					heap.Pop(results)
+				}
+			}
+		}
+	}
+
+	out := make([]candidate, results.Len())
+	for i := len(out) - 1; i >= 0; i-- {
+		out[i] = heap.Pop(results).(candidate)
+	}
+	return out
+}
+
+// selectNeighbors keeps the limit most similar candidates (candidates are sorted, most similar first)
+func selectNeighbors(candidates []candidate, limit int) []candidate {
+	if len(candidates) <= limit {
+		return candidates
+	}
+	return candidates[:limit]
+}
+
+// candidate is a node with its similarity to the current query
+type candidate struct {
+	id  int32
+	sim float32
+}
+
+// maxSimHeap pops the most similar candidate first
+type maxSimHeap []candidate
+
+func (h maxSimHeap) Len() int           { return len(h) }
+func (h maxSimHeap) Less(i, j int) bool { return h[i].sim > h[j].sim }
+func (h maxSimHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *maxSimHeap) Push(x any)        { *h = append(*h, x.(candidate)) }
+func (h *maxSimHeap) Pop() any {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+// minSimHeap pops the least similar candidate first
+type minSimHeap []candidate
+
+func (h minSimHeap) Len() int           { return len(h) }
+func (h minSimHeap) Less(i, j int) bool { return h[i].sim < h[j].sim }
+func (h minSimHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *minSimHeap) Push(x any)        { *h = append(*h, x.(candidate)) }
+func (h *minSimHeap) Pop() any {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
@@ -0,0 +1,154 @@
+package local
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// randomVectors returns n deterministic random vectors keyed doc-0, doc-1, ...
+func randomVectors(n, dimension int, seed int64) map[string][]float32 {
+	rng := rand.New(rand.NewSource(seed))
+	vectors := make(map[string][]float32, n)
+	for i := 0; i < n; i++ {
+		v := make([]float32, dimension)
+		for j := range v {
+			v[j] = rng.Float32()*2 - 1
+		}
+		vectors[fmt.Sprintf("doc-%d", i)] = v
+	}
+	return vectors
+}
+
+// exactTopK returns the keys of the k vectors most similar to query by brute force
+func exactTopK(vectors map[string][]float32, query []float32, k int, filter func(string) bool) []string {
+	q := normalize(query)
+	hits := make([]hnswHit, 0, len(vectors))
+	for key, v := range vectors {
+		if filter != nil && !filter(key) {
+			continue
+		}
+		hits = append(hits, hnswHit{Key: key, Score: float64(similarity(q, normalize(v)))})
+	}
+	sort.Slice(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+	keys := make([]string, 0, k)
+	for i := 0; i < k && i < len(hits); i++ {
+		keys = append(keys, hits[i].Key)
+	}
+	return keys
+}
+
+// recall returns the share of the expected keys found in the hits
+func recall(hits []hnswHit, expected []string) float64 {
+	found := make(map[string]bool, len(hits))
+	for _, hit := range hits {
+		found[hit.Key] = true
+	}
+	n := 0
+	for _, key := range expected {
+		if found[key] {
+			n++
+		}
+	}
+	return float64(n) / float64(len(expected))
+}
+
+func TestHNSWInsertDeleteAndRecallAfterCompact(t *testing.T) {
+	const dimension, k = 16, 10
+	vectors := randomVectors(1500, dimension, 1)
+
+	graph := newHNSWGraph("kb-1", dimension)
+	for i := 0; i < len(vectors); i++ {
+		key := fmt.Sprintf("doc-%d", i)
+		graph.Insert(key, vectors[key])
+	}
+	if graph.Live != len(vectors) {
+		t.Fatalf("Live = %d, want %d", graph.Live, len(vectors))
+	}
+
+	// Delete two thirds of the vectors so that the graph needs compaction
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("doc-%d", i)
+		if !graph.Delete(key) {
+			t.Fatalf("Delete(%s) = false", key)
+		}
+		delete(vectors, key)
+	}
+	if graph.Delete("doc-0") {
+		t.Error("Delete of a deleted key should return false")
+	}
+	if !graph.NeedsCompaction() {
+		t.Fatal("NeedsCompaction() = false after deleting most vectors")
+	}
+
+	compacted := graph.Compact()
+	if compacted.Live != len(vectors) || len(compacted.Nodes) != len(vectors) {
+		t.Fatalf("compacted graph has %d live of %d nodes, want %d", compacted.Live, len(compacted.Nodes), len(vectors))
+	}
+	if compacted.KnowledgeBaseID != "kb-1" || compacted.Dimension != dimension {
+		t.Errorf("compacted graph key = %+v", compacted.key())
+	}
+	if _, ok := compacted.Vector("doc-0"); ok {
+		t.Error("deleted vector is still in the compacted graph")
+	}
+
+	// Inserting after compaction keeps the graph searchable
+	extra := randomVectors(1, dimension, 2)["doc-0"]
+	compacted.Insert("doc-new", extra)
+	vectors["doc-new"] = extra
+	if hits := compacted.Search(extra, 1, nil); len(hits) != 1 || hits[0].Key != "doc-new" {
+		t.Fatalf("Search(own vector) = %v, want doc-new", hits)
+	}
+
+	queries := randomVectors(20, dimension, 3)
+	var total float64
+	for _, query := range queries {
+		hits := compacted.Search(query, k, nil)
+		for _, hit := range hits {
+			if _, ok := vectors[hit.Key]; !ok {
+				t.Fatalf("Search returned deleted vector %s", hit.Key)
+			}
+		}
+		total += recall(hits, exactTopK(vectors, query, k, nil))
+	}
+	if avg := total / float64(len(queries)); avg < 0.9 {
+		t.Errorf("average recall@%d after compaction = %.2f, want >= 0.9", k, avg)
+	}
+}
+
+func TestHNSWFilteredSearch(t *testing.T) {
+	const dimension, k = 8, 5
+	vectors := randomVectors(300, dimension, 4)
+	graph := newHNSWGraph("kb-1", dimension)
+	for key, v := range vectors {
+		graph.Insert(key, v)
+	}
+
+	// A selective filter: only 1 in 30 vectors passes, the candidate list has to grow to find k
+	allowed := func(key string) bool {
+		var i int
+		fmt.Sscanf(key, "doc-%d", &i)
+		return i%30 == 0
+	}
+	query := randomVectors(1, dimension, 5)["doc-0"]
+	hits := graph.Search(query, k, allowed)
+	if len(hits) != k {
+		t.Fatalf("Search returned %d hits, want %d", len(hits), k)
+	}
+	for i, hit := range hits {
+		if !allowed(hit.Key) {
+			t.Errorf("hit %s does not pass the filter", hit.Key)
+		}
+		if i > 0 && hit.Score > hits[i-1].Score {
+			t.Errorf("hits are not sorted by score: %v", hits)
+		}
+	}
+	if got := recall(hits, exactTopK(vectors, query, k, allowed)); got < 1 {
+		t.Errorf("filtered recall = %.2f, want 1", got)
+	}
+
+	if hits := graph.Search(query, k, func(string) bool { return false }); len(hits) != 0 {
+		t.Errorf("Search with a filter rejecting everything = %v, want none", hits)
+	}
+}
@@ -0,0 +1,602 @@
+package local
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"github.com/google/uuid"
+)
+
+const fieldEmbedding = "embedding"
+
+// NewLocalRetrieveEngineRepository creates the in-process retrieve engine repository.
+// The index is loaded from dir and written back to it in the background.
+func NewLocalRetrieveEngineRepository(dir string) (interfaces.RetrieveEngineRepository, error) {
+	log := logger.GetLogger(context.Background())
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create index directory %s: %w", dir, err)
+	}
+
+	res := &localRepository{
+		dir:          dir,
+		vectors:      make(map[vectorKey]*hnswGraph),
+		dirtyVectors: make(map[vectorKey]bool),
+		stop:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+	if err := res.load(); err != nil {
+		return nil, fmt.Errorf("failed to load index from %s: %w", dir, err)
+	}
+	go res.runFlusher()
+
+	log.Infof("[Local] Loaded %d indices from %s", len(res.docs), dir)
+	return res, nil
+}
+
+func (r *localRepository) EngineType() types.RetrieverEngineType {
+	return types.LocalRetrieverEngineType
+}
+
+func (r *localRepository) Support() []types.RetrieverType {
+	return []types.RetrieverType{types.KeywordsRetrieverType, types.VectorRetrieverType}
+}
+
+// EstimateStorageSize calculates the estimated storage size for a list of indices
+func (r *localRepository) EstimateStorageSize(ctx context.Context,
+	indexInfoList []*types.IndexInfo, params map[string]any,
+) int64 {
+	var totalStorageSize int64
+	for _, indexInfo := range indexInfoList {
+		entry := toLocalIndexEntry(indexInfo, params)
+		totalStorageSize += calculateStorageSize(entry)
+	}
+	logger.GetLogger(ctx).Infof(
+		"[Local] Storage size for %d indices: %d bytes", len(indexInfoList), totalStorageSize,
+	)
+	return totalStorageSize
+}
+
+// Save stores a single index entry
+func (r *localRepository) Save(ctx context.Context, indexInfo *types.IndexInfo, additionalParams map[string]any) error {
+	logger.GetLogger(ctx).Debugf("[Local] Saving index for source ID: %s", indexInfo.SourceID)
+	return r.BatchSave(ctx, []*types.IndexInfo{indexInfo}, additionalParams)
+}
+
+// BatchSave stores multiple index entries, replacing entries with the same source ID
+func (r *localRepository) BatchSave(ctx context.Context,
+	indexInfoList []*types.IndexInfo, additionalParams map[string]any,
+) error {
+	log := logger.GetLogger(ctx)
+	if len(indexInfoList) == 0 {
+		log.Warn("[Local] Empty list provided to BatchSave, skipping")
+		return nil
+	}
+
+	// Tokenize outside the lock, segmentation is the expensive part
+	entries := make([]*localIndexEntry, 0, len(indexInfoList))
+	for _, indexInfo := range indexInfoList {
+		entry := toLocalIndexEntry(indexInfo, additionalParams)
+		entry.tokens = tokenize(entry.doc.Content)
+		entries = append(entries, entry)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, entry := range entries {
+		r.put(entry)
+	}
+	r.markDirty()
+
+	log.Infof("[Local] Successfully batch saved %d indices", len(entries))
+	return nil
+}
+
+// put adds an entry to the documents, the keywords index and the vector index, the caller must hold r.mu
+func (r *localRepository) put(entry *localIndexEntry) {
+	doc := entry.doc
+	if old, ok := r.docs[doc.SourceID]; ok && vectorKeyOf(old) != vectorKeyOf(doc) {
+		r.removeVector(old)
+	}
+	r.docs[doc.SourceID] = doc
+	r.keywords.Add(doc.SourceID, entry.tokens)
+	if doc.Dimension > 0 {
+		key := vectorKeyOf(doc)
+		graph, ok := r.vectors[key]
+		if !ok {
+			graph = newHNSWGraph(doc.KnowledgeBaseID, doc.Dimension)
+			r.vectors[key] = graph
+		}
+		graph.Insert(doc.SourceID, entry.embedding)
+		r.dirtyVectors[key] = true
+	}
+}
+
+// remove drops a document from all indexes, the caller must hold r.mu
+func (r *localRepository) remove(doc *localIndexDoc) {
+	delete(r.docs, doc.SourceID)
+	r.keywords.Remove(doc.SourceID)
+	r.removeVector(doc)
+}
+
+// removeVector drops the vector of a document, compacting or dropping the graph when needed
+func (r *localRepository) removeVector(doc *localIndexDoc) {
+	key := vectorKeyOf(doc)
+	graph, ok := r.vectors[key]
+	if !ok || !graph.Delete(doc.SourceID) {
+		return
+	}
+	switch {
+	case graph.Live == 0:
+		delete(r.vectors, key)
+		delete(r.dirtyVectors, key)
+		return
+	case graph.NeedsCompaction():
+		r.vectors[key] = graph.Compact()
+	}
+	r.dirtyVectors[key] = true
+}
+
+// vectorKeyOf returns the partition holding the vector of a document
+func vectorKeyOf(doc *localIndexDoc) vectorKey {
+	return vectorKey{KnowledgeBaseID: doc.KnowledgeBaseID, Dimension: doc.Dimension}
+}
+
+// vectorGraphs returns the graphs of a dimension to search: those of the requested knowledge bases,
+// or all of them when the request is not limited to knowledge bases. The caller must hold r.mu.
+func (r *localRepository) vectorGraphs(dimension int, knowledgeBaseIDs []string) []*hnswGraph {
+	var graphs []*hnswGraph
+	if len(knowledgeBaseIDs) > 0 {
+		for knowledgeBaseID := range toSet(knowledgeBaseIDs) {
+			if graph, ok := r.vectors[vectorKey{KnowledgeBaseID: knowledgeBaseID, Dimension: dimension}]; ok {
+				graphs = append(graphs, graph)
+			}
+		}
+		return graphs
+	}
+	for key, graph := range r.vectors {
+		if key.Dimension == dimension {
+			graphs = append(graphs, graph)
+		}
+	}
+	return graphs
+}
+
+// deleteWhere removes the documents matching the predicate and returns how many were removed
+func (r *localRepository) deleteWhere(match func(doc *localIndexDoc) bool) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var matched []*localIndexDoc
+	for _, doc := range r.docs {
+		if match(doc) {
+			matched = append(matched, doc)
+		}
+	}
+	for _, doc := range matched {
+		r.remove(doc)
+	}
+	if len(matched) > 0 {
+		r.markDirty()
+	}
+	return len(matched)
+}
+
+// DeleteByChunkIDList deletes indices by chunk IDs
+func (r *localRepository) DeleteByChunkIDList(ctx context.Context,
+	chunkIDList []string, dimension int, knowledgeType string,
+) error {
+	chunkIDs := toSet(chunkIDList)
+	deleted := r.deleteWhere(func(doc *localIndexDoc) bool { return chunkIDs[doc.ChunkID] })
+	logger.GetLogger(ctx).Infof("[Local] Successfully deleted %d indices by chunk IDs", deleted)
+	return nil
+}
+
+// DeleteBySourceIDList deletes indices by source IDs
+func (r *localRepository) DeleteBySourceIDList(ctx context.Context,
+	sourceIDList []string, dimension int, knowledgeType string,
+) error {
+	sourceIDs := toSet(sourceIDList)
+	deleted := r.deleteWhere(func(doc *localIndexDoc) bool { return sourceIDs[doc.SourceID] })
+	logger.GetLogger(ctx).Infof("[Local] Successfully deleted %d indices by source IDs", deleted)
+	return nil
+}
+
+// DeleteByKnowledgeIDList deletes indices by knowledge IDs
+func (r *localRepository) DeleteByKnowledgeIDList(ctx context.Context,
+	knowledgeIDList []string, dimension int, knowledgeType string,
+) error {
+	knowledgeIDs := toSet(knowledgeIDList)
+	deleted := r.deleteWhere(func(doc *localIndexDoc) bool { return knowledgeIDs[doc.KnowledgeID] })
+	logger.GetLogger(ctx).Infof("[Local] Successfully deleted %d indices by knowledge IDs", deleted)
+	return nil
+}
+
+// BatchUpdateChunkEnabledStatus updates the enabled status of chunks in batch
+func (r *localRepository) BatchUpdateChunkEnabledStatus(ctx context.Context, chunkStatusMap map[string]bool) error {
+	if len(chunkStatusMap) == 0 {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	updated := 0
+	for _, doc := range r.docs {
+		if enabled, ok := chunkStatusMap[doc.ChunkID]; ok && doc.IsEnabled != enabled {
+			doc.IsEnabled = enabled
+			updated++
+		}
+	}
+	if updated > 0 {
+		r.markDirty()
+	}
+	logger.GetLogger(ctx).Infof("[Local] Updated enabled status of %d indices", updated)
+	return nil
+}
+
+// BatchUpdateChunkTagID updates the tag ID of chunks in batch
+func (r *localRepository) BatchUpdateChunkTagID(ctx context.Context, chunkTagMap map[string]string) error {
+	if len(chunkTagMap) == 0 {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	updated := 0
+	for _, doc := range r.docs {
+		if tagID, ok := chunkTagMap[doc.ChunkID]; ok && doc.TagID != tagID {
+			doc.TagID = tagID
+			updated++
+		}
+	}
+	if updated > 0 {
+		r.markDirty()
+	}
+	logger.GetLogger(ctx).Infof("[Local] Updated tag ID of %d indices", updated)
+	return nil
+}
+
+// Retrieve dispatches the retrieval operation to the appropriate method based on retriever type
+func (r *localRepository) Retrieve(ctx context.Context,
+	params types.RetrieveParams,
+) ([]*types.RetrieveResult, error) {
+	log := logger.GetLogger(ctx)
+	log.Debugf("[Local] Processing retrieval request of type: %s", params.RetrieverType)
+
+	switch params.RetrieverType {
+	case types.VectorRetrieverType:
+		return r.VectorRetrieve(ctx, params)
+	case types.KeywordsRetrieverType:
+		return r.KeywordsRetrieve(ctx, params)
+	}
+
+	err := fmt.Errorf("invalid retriever type: %v", params.RetrieverType)
+	log.Errorf("[Local] %v", err)
+	return nil, err
+}
+
+// VectorRetrieve performs an approximate nearest neighbor search on the HNSW graphs of the requested
+// knowledge bases with the query dimension
+func (r *localRepository) VectorRetrieve(ctx context.Context,
+	params types.RetrieveParams,
+) ([]*types.RetrieveResult, error) {
+	log := logger.GetLogger(ctx)
+	dimension := len(params.Embedding)
+	log.Infof("[Local] Vector retrieval: dim=%d, topK=%d, threshold=%.4f",
+		dimension, params.TopK, params.Threshold)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	graphs := r.vectorGraphs(dimension, params.KnowledgeBaseIDs)
+	if len(graphs) == 0 {
+		log.Warnf("[Local] No vectors of dimension %d, returning empty results", dimension)
+		return buildRetrieveResult(nil, types.VectorRetrieverType), nil
+	}
+
+	// Each graph returns its own top K, the best K over all searched graphs are kept
+	match := r.newFilter(params)
+	var hits []hnswHit
+	for _, graph := range graphs {
+		hits = append(hits, graph.Search(params.Embedding, params.TopK, match)...)
+	}
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+	if len(hits) > params.TopK {
+		hits = hits[:params.TopK]
+	}
+
+	results := make([]*types.IndexWithScore, 0, len(hits))
+	for _, hit := range hits {
+		if hit.Score < params.Threshold {
+			break
+		}
+		results = append(results, fromLocalIndexDoc(r.docs[hit.Key], hit.Score, types.MatchTypeEmbedding))
+	}
+
+	if len(results) == 0 {
+		log.Warnf("[Local] No vector matches found that meet threshold %.4f", params.Threshold)
+	} else {
+		log.Infof("[Local] Vector retrieval found %d results", len(results))
+		log.Debugf("[Local] Top result score: %.4f", results[0].Score)
+	}
+	return buildRetrieveResult(results, types.VectorRetrieverType), nil
+}
+
+// KeywordsRetrieve performs a BM25 search on the keywords index
+func (r *localRepository) KeywordsRetrieve(ctx context.Context,
+	params types.RetrieveParams,
+) ([]*types.RetrieveResult, error) {
+	log := logger.GetLogger(ctx)
+	log.Infof("[Local] Keywords retrieval: query=%s, topK=%d", params.Query, params.TopK)
+
+	queryTokens := tokenize(params.Query)
+	if len(queryTokens) == 0 {
+		log.Warnf("[Local] No keywords in query: %s", params.Query)
+		return buildRetrieveResult(nil, types.KeywordsRetrieverType), nil
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	match := r.newFilter(params)
+	hits := r.keywords.Search(queryTokens, params.TopK, match)
+
+	results := make([]*types.IndexWithScore, 0, len(hits))
+	for _, hit := range hits {
+		results = append(results, fromLocalIndexDoc(r.docs[hit.DocID], hit.Score, types.MatchTypeKeywords))
+	}
+
+	if len(results) == 0 {
+		log.Warnf("[Local] No keyword matches found for query: %s", params.Query)
+	} else {
+		log.Infof("[Local] Keywords retrieval found %d results", len(results))
+	}
+	return buildRetrieveResult(results, types.KeywordsRetrieverType), nil
+}
+
+// newFilter builds the predicate applied to candidate documents, the caller must hold r.mu
+func (r *localRepository) newFilter(params types.RetrieveParams) func(sourceID string) bool {
+	knowledgeBaseIDs := toSet(params.KnowledgeBaseIDs)
+	knowledgeIDs := toSet(params.KnowledgeIDs)
+	tagIDs := toSet(params.TagIDs)
+	excludeKnowledgeIDs := toSet(params.ExcludeKnowledgeIDs)
+	excludeChunkIDs := toSet(params.ExcludeChunkIDs)
+
+	return func(sourceID string) bool {
+		doc, ok := r.docs[sourceID]
+		if !ok || !doc.IsEnabled {
+			return false
+		}
+		// KnowledgeBaseIDs and KnowledgeIDs use AND logic
+		if len(knowledgeBaseIDs) > 0 && !knowledgeBaseIDs[doc.KnowledgeBaseID] {
+			return false
+		}
+		if len(knowledgeIDs) > 0 && !knowledgeIDs[doc.KnowledgeID] {
+			return false
+		}
+		if len(tagIDs) > 0 && !tagIDs[doc.TagID] {
+			return false
+		}
+		return !excludeKnowledgeIDs[doc.KnowledgeID] && !excludeChunkIDs[doc.ChunkID]
+	}
+}
+
+// CopyIndices copies index data from source knowledge base to target knowledge base
+func (r *localRepository) CopyIndices(ctx context.Context,
+	sourceKnowledgeBaseID string,
+	sourceToTargetKBIDMap map[string]string,
+	sourceToTargetChunkIDMap map[string]string,
+	targetKnowledgeBaseID string,
+	dimension int,
+	knowledgeType string,
+) error {
+	log := logger.GetLogger(ctx)
+	log.Infof(
+		"[Local] Copying indices from source knowledge base %s to target knowledge base %s, count: %d",
+		sourceKnowledgeBaseID, targetKnowledgeBaseID, len(sourceToTargetChunkIDMap),
+	)
+	if len(sourceToTargetChunkIDMap) == 0 {
+		log.Warn("[Local] Empty mapping, skipping copy")
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var entries []*localIndexEntry
+	for _, source := range r.docs {
+		if source.KnowledgeBaseID != sourceKnowledgeBaseID {
+			continue
+		}
+		targetChunkID, ok := sourceToTargetChunkIDMap[source.ChunkID]
+		if !ok {
+			log.Warnf("[Local] Source chunk %s not found in target mapping, skipping", source.ChunkID)
+			continue
+		}
+		targetKnowledgeID, ok := sourceToTargetKBIDMap[source.KnowledgeID]
+		if !ok {
+			log.Warnf("[Local] Source knowledge %s not found in target mapping, skipping", source.KnowledgeID)
+			continue
+		}
+
+		// Generated questions have SourceID format {chunkID}-{questionID}, regular chunks have SourceID == ChunkID
+		var targetSourceID string
+		if source.SourceID == source.ChunkID {
+			targetSourceID = targetChunkID
+		} else if questionID, found := strings.CutPrefix(source.SourceID, source.ChunkID+"-"); found {
+			targetSourceID = fmt.Sprintf("%s-%s", targetChunkID, questionID)
+		} else {
+			targetSourceID = uuid.New().String()
+		}
+
+		target := *source
+		target.SourceID = targetSourceID
+		target.ChunkID = targetChunkID
+		target.KnowledgeID = targetKnowledgeID
+		target.KnowledgeBaseID = targetKnowledgeBaseID
+		target.IsEnabled = true
+
+		entry := &localIndexEntry{doc: &target, tokens: r.keywords.DocTerms[source.SourceID]}
+		if source.Dimension > 0 {
+			if graph, ok := r.vectors[vectorKeyOf(source)]; ok {
+				entry.embedding, _ = graph.Vector(source.SourceID)
+			}
+			if entry.embedding == nil {
+				target.Dimension = 0
+			}
+		}
+		entries = append(entries, entry)
+	}
+
+	for _, entry := range entries {
+		// DocTerms holds distinct terms, re-tokenize to keep term frequencies exact
+		entry.tokens = tokenize(entry.doc.Content)
+		r.put(entry)
+	}
+	if len(entries) > 0 {
+		r.markDirty()
+	}
+
+	log.Infof("[Local] Index copy completed, total copied: %d", len(entries))
+	return nil
+}
+
+// ListIndices lists the index entries of a knowledge base ordered by source ID, the cursor is the last source ID returned
+func (r *localRepository) ListIndices(ctx context.Context,
+	knowledgeBaseID string, dimension int, knowledgeType string, cursor string, limit int,
+) ([]*types.IndexEntry, string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var sourceIDs []string
+	for sourceID, doc := range r.docs {
+		if doc.KnowledgeBaseID == knowledgeBaseID && sourceID > cursor {
+			sourceIDs = append(sourceIDs, sourceID)
+		}
+	}
+	slices.Sort(sourceIDs)
+	if len(sourceIDs) > limit {
+		sourceIDs = sourceIDs[:limit]
+	}
+
+	entries := make([]*types.IndexEntry, 0, len(sourceIDs))
+	for _, sourceID := range sourceIDs {
+		doc := r.docs[sourceID]
+		entry := &types.IndexEntry{
+			IndexInfo: types.IndexInfo{
+				Content:         doc.Content,
+				SourceID:        doc.SourceID,
+				SourceType:      types.SourceType(doc.SourceType),
+				ChunkID:         doc.ChunkID,
+				KnowledgeID:     doc.KnowledgeID,
+				KnowledgeBaseID: doc.KnowledgeBaseID,
+				TagID:           doc.TagID,
+				IsEnabled:       doc.IsEnabled,
+			},
+		}
+		if graph, ok := r.vectors[vectorKeyOf(doc)]; ok {
+			entry.Embedding, _ = graph.Vector(sourceID)
+		}
+		entries = append(entries, entry)
+	}
+
+	nextCursor := ""
+	if len(sourceIDs) == limit {
+		nextCursor = sourceIDs[len(sourceIDs)-1]
+	}
+	return entries, nextCursor, nil
+}
+
+// buildRetrieveResult wraps the results of a retriever type
+func buildRetrieveResult(results []*types.IndexWithScore, retrieverType types.RetrieverType) []*types.RetrieveResult {
+	return []*types.RetrieveResult{
+		{
+			Results:             results,
+			RetrieverEngineType: types.LocalRetrieverEngineType,
+			RetrieverType:       retrieverType,
+			Error:               nil,
+		},
+	}
+}
+
+// calculateStorageSize estimates the bytes used by an entry: document, postings and HNSW vector with its links
+func calculateStorageSize(entry *localIndexEntry) int64 {
+	doc := entry.doc
+	size := int64(len(doc.Content) + len(doc.SourceID) + len(doc.ChunkID) +
+		len(doc.KnowledgeID) + len(doc.KnowledgeBaseID) + len(doc.TagID) + 8)
+	// Postings store the content again, roughly once
+	size += int64(len(doc.Content))
+	if dimension := int64(len(entry.embedding)); dimension > 0 {
+		size += dimension*4 + hnswM0*4
+	}
+	return size
+}
+
+// toLocalIndexEntry converts IndexInfo to a local index entry
+func toLocalIndexEntry(indexInfo *types.IndexInfo, additionalParams map[string]any) *localIndexEntry {
+	doc := &localIndexDoc{
+		SourceID:        indexInfo.SourceID,
+		SourceType:      int(indexInfo.SourceType),
+		ChunkID:         indexInfo.ChunkID,
+		KnowledgeID:     indexInfo.KnowledgeID,
+		KnowledgeBaseID: indexInfo.KnowledgeBaseID,
+		TagID:           indexInfo.TagID,
+		Content:         indexInfo.Content,
+		IsEnabled:       true, // Default to enabled
+	}
+	entry := &localIndexEntry{doc: doc}
+	if embeddingMap, ok := additionalParams[fieldEmbedding].(map[string][]float32); ok {
+		entry.embedding = embeddingMap[indexInfo.SourceID]
+		doc.Dimension = len(entry.embedding)
+	}
+	if chunkEnabledMap, ok := additionalParams["chunk_enabled"].(map[string]bool); ok {
+		if enabled, exists := chunkEnabledMap[indexInfo.ChunkID]; exists {
+			doc.IsEnabled = enabled
+		}
+	}
+	return entry
+}
+
+// fromLocalIndexDoc converts a document to IndexWithScore domain model
+func fromLocalIndexDoc(doc *localIndexDoc, score float64, matchType types.MatchType) *types.IndexWithScore {
+	return &types.IndexWithScore{
+		ID:              doc.SourceID,
+		SourceID:        doc.SourceID,
+		SourceType:      types.SourceType(doc.SourceType),
+		ChunkID:         doc.ChunkID,
+		KnowledgeID:     doc.KnowledgeID,
+		KnowledgeBaseID: doc.KnowledgeBaseID,
+		TagID:           doc.TagID,
+		Content:         doc.Content,
+		Score:           score,
+		MatchType:       matchType,
+		IsEnabled:       doc.IsEnabled,
+	}
+}
+
+// toSet converts a list of IDs to a set
+func toSet(ids []string) map[string]bool {
+	set := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
+
+// Ping checks that the index directory is still accessible; the index itself lives in memory
+func (r *localRepository) Ping(ctx context.Context) error {
+	if r.dir == "" {
+		return nil
+	}
+	if _, err := os.Stat(r.dir); err != nil {
+		return fmt.Errorf("index directory is not accessible: %w", err)
+	}
+	return nil
+}
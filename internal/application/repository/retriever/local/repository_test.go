@@ -0,0 +1,314 @@
+package local
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// newTestRepository opens a local repository in dir and closes it when the test ends
+func newTestRepository(t *testing.T, dir string) *localRepository {
+	t.Helper()
+	repo, err := NewLocalRetrieveEngineRepository(dir)
+	if err != nil {
+		t.Fatalf("NewLocalRetrieveEngineRepository() error = %v", err)
+	}
+	r := repo.(*localRepository)
+	t.Cleanup(func() { _ = r.Close() })
+	return r
+}
+
+// testIndex describes an entry saved by saveTestIndices
+type testIndex struct {
+	sourceID, knowledgeBaseID, knowledgeID, tagID, content string
+	embedding                                              []float32
+}
+
+// saveTestIndices saves entries whose chunk ID is their source ID
+func saveTestIndices(t *testing.T, r *localRepository, indices ...testIndex) {
+	t.Helper()
+	infos := make([]*types.IndexInfo, 0, len(indices))
+	embeddings := make(map[string][]float32, len(indices))
+	for _, index := range indices {
+		infos = append(infos, &types.IndexInfo{
+			SourceID:        index.sourceID,
+			SourceType:      types.ChunkSourceType,
+			ChunkID:         index.sourceID,
+			KnowledgeID:     index.knowledgeID,
+			KnowledgeBaseID: index.knowledgeBaseID,
+			TagID:           index.tagID,
+			Content:         index.content,
+		})
+		embeddings[index.sourceID] = index.embedding
+	}
+	if err := r.BatchSave(context.Background(), infos, map[string]any{fieldEmbedding: embeddings}); err != nil {
+		t.Fatalf("BatchSave() error = %v", err)
+	}
+}
+
+// retrieveIDs runs a retrieval and returns the source IDs of the results in order
+func retrieveIDs(t *testing.T, r *localRepository, params types.RetrieveParams) []string {
+	t.Helper()
+	results, err := r.Retrieve(context.Background(), params)
+	if err != nil {
+		t.Fatalf("Retrieve() error = %v", err)
+	}
+	var ids []string
+	for _, result := range results[0].Results {
+		ids = append(ids, result.SourceID)
+	}
+	return ids
+}
+
+var testIndices = []testIndex{
+	{"a1", "kb-a", "k-a1", "tag-1", "apple orchard harvest", []float32{1, 0, 0, 0}},
+	{"a2", "kb-a", "k-a2", "tag-2", "banana plantation", []float32{0.9, 0.1, 0, 0}},
+	{"a3", "kb-a", "k-a2", "tag-1", "cherry blossom", []float32{0, 1, 0, 0}},
+	{"b1", "kb-b", "k-b1", "", "apple pie recipe", []float32{1, 0, 0, 0}},
+	{"c1", "kb-c", "k-c1", "", "apple in three dimensions", []float32{1, 0, 0}},
+}
+
+func TestLocalRepositoryPartitionsVectorsByKnowledgeBase(t *testing.T) {
+	r := newTestRepository(t, t.TempDir())
+	saveTestIndices(t, r, testIndices...)
+
+	if len(r.vectors) != 3 {
+		t.Fatalf("got %d graphs, want one per knowledge base and dimension: %v", len(r.vectors), r.vectors)
+	}
+	if graph := r.vectors[vectorKey{KnowledgeBaseID: "kb-a", Dimension: 4}]; graph == nil || graph.Live != 3 {
+		t.Fatalf("graph of kb-a = %+v, want 3 live vectors", graph)
+	}
+
+	query := []float32{1, 0, 0, 0}
+	tests := []struct {
+		name   string
+		params types.RetrieveParams
+		want   []string
+	}{
+		{
+			name:   "single knowledge base",
+			params: types.RetrieveParams{KnowledgeBaseIDs: []string{"kb-a"}},
+			want:   []string{"a1", "a2", "a3"},
+		},
+		{
+			name:   "several knowledge bases are merged by score",
+			params: types.RetrieveParams{KnowledgeBaseIDs: []string{"kb-a", "kb-b"}, TopK: 2},
+			want:   []string{"a1", "b1"},
+		},
+		{
+			name:   "knowledge filter",
+			params: types.RetrieveParams{KnowledgeBaseIDs: []string{"kb-a"}, KnowledgeIDs: []string{"k-a2"}},
+			want:   []string{"a2", "a3"},
+		},
+		{
+			name:   "tag filter",
+			params: types.RetrieveParams{KnowledgeBaseIDs: []string{"kb-a"}, TagIDs: []string{"tag-1"}},
+			want:   []string{"a1", "a3"},
+		},
+		{
+			name:   "excluded chunks",
+			params: types.RetrieveParams{KnowledgeBaseIDs: []string{"kb-a"}, ExcludeChunkIDs: []string{"a1"}},
+			want:   []string{"a2", "a3"},
+		},
+		{
+			name:   "threshold",
+			params: types.RetrieveParams{KnowledgeBaseIDs: []string{"kb-a"}, Threshold: 0.5},
+			want:   []string{"a1", "a2"},
+		},
+		{
+			name:   "unknown knowledge base",
+			params: types.RetrieveParams{KnowledgeBaseIDs: []string{"kb-x"}},
+			want:   nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := tt.params
+			params.RetrieverType = types.VectorRetrieverType
+			params.Embedding = query
+			if params.TopK == 0 {
+				params.TopK = 10
+			}
+			got := retrieveIDs(t, r, params)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+
+	// Disabled chunks are skipped, deleting the last vector of a knowledge base drops its graph
+	if err := r.BatchUpdateChunkEnabledStatus(context.Background(), map[string]bool{"a1": false}); err != nil {
+		t.Fatal(err)
+	}
+	got := retrieveIDs(t, r, types.RetrieveParams{
+		RetrieverType: types.VectorRetrieverType, Embedding: query, KnowledgeBaseIDs: []string{"kb-a"}, TopK: 1,
+	})
+	if len(got) != 1 || got[0] != "a2" {
+		t.Errorf("retrieval with a disabled chunk = %v, want [a2]", got)
+	}
+	if err := r.DeleteByKnowledgeIDList(context.Background(), []string{"k-b1"}, 4, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := r.vectors[vectorKey{KnowledgeBaseID: "kb-b", Dimension: 4}]; ok {
+		t.Error("graph of kb-b was not dropped after its last vector was deleted")
+	}
+}
+
+func TestLocalRepositoryKeywordsRetrieve(t *testing.T) {
+	r := newTestRepository(t, t.TempDir())
+	saveTestIndices(t, r, testIndices...)
+
+	got := retrieveIDs(t, r, types.RetrieveParams{
+		RetrieverType: types.KeywordsRetrieverType, Query: "apple", KnowledgeBaseIDs: []string{"kb-a", "kb-b"}, TopK: 10,
+	})
+	if len(got) != 2 {
+		t.Fatalf("keywords retrieval = %v, want a1 and b1", got)
+	}
+	for _, id := range got {
+		if id != "a1" && id != "b1" {
+			t.Errorf("unexpected keywords result %s", id)
+		}
+	}
+}
+
+func TestLocalRepositorySnapshotRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	r := newTestRepository(t, dir)
+	saveTestIndices(t, r, testIndices...)
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, vectorsPrefix+"*"+vectorsSuffix))
+	if len(files) != 3 {
+		t.Errorf("got %d graph files, want 3: %v", len(files), files)
+	}
+
+	reopened := newTestRepository(t, dir)
+	if len(reopened.docs) != len(testIndices) || len(reopened.vectors) != 3 {
+		t.Fatalf("reopened repository has %d docs and %d graphs", len(reopened.docs), len(reopened.vectors))
+	}
+	got := retrieveIDs(t, reopened, types.RetrieveParams{
+		RetrieverType: types.VectorRetrieverType, Embedding: []float32{1, 0, 0}, KnowledgeBaseIDs: []string{"kb-c"}, TopK: 5,
+	})
+	if len(got) != 1 || got[0] != "c1" {
+		t.Errorf("vector retrieval after reload = %v, want [c1]", got)
+	}
+	got = retrieveIDs(t, reopened, types.RetrieveParams{
+		RetrieverType: types.KeywordsRetrieverType, Query: "cherry", KnowledgeBaseIDs: []string{"kb-a"}, TopK: 5,
+	})
+	if len(got) != 1 || got[0] != "a3" {
+		t.Errorf("keywords retrieval after reload = %v, want [a3]", got)
+	}
+
+	// Deleting a knowledge base removes its graph file on the next flush
+	if err := reopened.DeleteByKnowledgeIDList(context.Background(), []string{"k-c1"}, 3, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := reopened.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	key := vectorKey{KnowledgeBaseID: "kb-c", Dimension: 3}
+	if _, err := os.Stat(filepath.Join(dir, vectorFileName(key))); !os.IsNotExist(err) {
+		t.Errorf("graph file of kb-c still exists after its vectors were deleted: %v", err)
+	}
+}
+
+func TestLocalRepositorySplitsSharedGraphSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	r := newTestRepository(t, dir)
+	saveTestIndices(t, r, testIndices[:4]...)
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Replace the partitioned files with a single graph per dimension, as written by earlier versions
+	files, _ := filepath.Glob(filepath.Join(dir, vectorsPrefix+"*"+vectorsSuffix))
+	for _, file := range files {
+		_ = os.Remove(file)
+	}
+	shared := newHNSWGraph("", 4)
+	for _, index := range testIndices[:4] {
+		shared.Insert(index.sourceID, index.embedding)
+	}
+	legacyFile := filepath.Join(dir, "hnsw_4.gob")
+	if err := writeGob(legacyFile, shared); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened := newTestRepository(t, dir)
+	if len(reopened.vectors) != 2 {
+		t.Fatalf("got %d graphs after loading a shared graph, want 2", len(reopened.vectors))
+	}
+	got := retrieveIDs(t, reopened, types.RetrieveParams{
+		RetrieverType: types.VectorRetrieverType, Embedding: []float32{1, 0, 0, 0}, KnowledgeBaseIDs: []string{"kb-b"}, TopK: 5,
+	})
+	if len(got) != 1 || got[0] != "b1" {
+		t.Errorf("retrieval from a split graph = %v, want [b1]", got)
+	}
+	if err := reopened.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(legacyFile); !os.IsNotExist(err) {
+		t.Errorf("shared graph file still exists after flush: %v", err)
+	}
+}
+
+func TestLocalRepositoryCopyAndListIndices(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepository(t, t.TempDir())
+	saveTestIndices(t, r, testIndices...)
+
+	err := r.CopyIndices(ctx, "kb-a",
+		map[string]string{"k-a1": "k-t1", "k-a2": "k-t2"},
+		map[string]string{"a1": "t1", "a2": "t2"},
+		"kb-t", 4, "")
+	if err != nil {
+		t.Fatalf("CopyIndices() error = %v", err)
+	}
+
+	// a3 has no chunk mapping and is not copied
+	var entries []*types.IndexEntry
+	cursor := ""
+	for {
+		page, next, err := r.ListIndices(ctx, "kb-t", 4, "", cursor, 1)
+		if err != nil {
+			t.Fatalf("ListIndices() error = %v", err)
+		}
+		entries = append(entries, page...)
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	if len(entries) != 2 || entries[0].SourceID != "t1" || entries[1].SourceID != "t2" {
+		t.Fatalf("ListIndices(kb-t) = %v, want t1 and t2", entries)
+	}
+	for _, entry := range entries {
+		if entry.KnowledgeBaseID != "kb-t" || len(entry.Embedding) != 4 || !entry.IsEnabled {
+			t.Errorf("copied entry %s = %+v", entry.SourceID, entry.IndexInfo)
+		}
+	}
+	if entries[1].KnowledgeID != "k-t2" || entries[1].Content != "banana plantation" {
+		t.Errorf("copied entry t2 = %+v", entries[1].IndexInfo)
+	}
+
+	// The copy lives in its own graph and does not change the source knowledge base
+	got := retrieveIDs(t, r, types.RetrieveParams{
+		RetrieverType: types.VectorRetrieverType, Embedding: []float32{1, 0, 0, 0}, KnowledgeBaseIDs: []string{"kb-t"}, TopK: 5,
+	})
+	if len(got) != 2 || got[0] != "t1" {
+		t.Errorf("retrieval from the copy = %v, want [t1 t2]", got)
+	}
+	if source, _, _ := r.ListIndices(ctx, "kb-a", 4, "", "", 10); len(source) != 3 {
+		t.Errorf("source knowledge base has %d entries after copy, want 3", len(source))
+	}
+}
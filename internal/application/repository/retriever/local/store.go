@@ -0,0 +1,226 @@
+package local
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/logger"
+)
+
+const (
+	documentsFile = "documents.gob"
+	keywordsFile  = "bm25.gob"
+	vectorsPrefix = "hnsw_"
+	vectorsSuffix = ".gob"
+
+	// flushInterval is how often pending changes are written to disk
+	flushInterval = 2 * time.Second
+)
+
+// load reads the snapshot files of the index directory, if any
+func (r *localRepository) load() error {
+	if err := readGob(filepath.Join(r.dir, documentsFile), &r.docs); err != nil {
+		return err
+	}
+	if err := readGob(filepath.Join(r.dir, keywordsFile), &r.keywords); err != nil {
+		return err
+	}
+	if r.docs == nil {
+		r.docs = make(map[string]*localIndexDoc)
+	}
+	if r.keywords == nil {
+		r.keywords = newBM25Index()
+	}
+
+	files, err := filepath.Glob(filepath.Join(r.dir, vectorsPrefix+"*"+vectorsSuffix))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		var graph *hnswGraph
+		if err := readGob(file, &graph); err != nil {
+			return err
+		}
+		if graph == nil {
+			continue
+		}
+		graph.init()
+		if graph.KnowledgeBaseID == "" {
+			// Snapshot of the earlier layout with one graph per dimension shared by all knowledge bases
+			r.splitGraph(graph)
+			r.dirty = true
+			continue
+		}
+		r.vectors[graph.key()] = graph
+	}
+	return nil
+}
+
+// splitGraph moves the live vectors of a graph into the graphs of their knowledge bases
+func (r *localRepository) splitGraph(graph *hnswGraph) {
+	for _, node := range graph.Nodes {
+		if node.Deleted {
+			continue
+		}
+		doc, ok := r.docs[node.Key]
+		if !ok || doc.Dimension != graph.Dimension {
+			continue
+		}
+		key := vectorKeyOf(doc)
+		target, ok := r.vectors[key]
+		if !ok {
+			target = newHNSWGraph(doc.KnowledgeBaseID, doc.Dimension)
+			r.vectors[key] = target
+		}
+		target.Insert(node.Key, node.Vector)
+		r.dirtyVectors[key] = true
+	}
+}
+
+// Flush writes the index to disk if it changed since the last flush
+func (r *localRepository) Flush() error {
+	r.flushMu.Lock()
+	defer r.flushMu.Unlock()
+
+	r.mu.Lock()
+	if !r.dirty {
+		r.mu.Unlock()
+		return nil
+	}
+	r.dirty = false
+	changed := r.dirtyVectors
+	r.dirtyVectors = make(map[vectorKey]bool)
+	r.mu.Unlock()
+
+	// Writers wait while the snapshot is encoded so that the files are consistent
+	r.mu.RLock()
+	err := r.writeSnapshot(changed)
+	r.mu.RUnlock()
+	if err != nil {
+		r.mu.Lock()
+		r.dirty = true
+		for key := range changed {
+			r.dirtyVectors[key] = true
+		}
+		r.mu.Unlock()
+	}
+	return err
+}
+
+// writeSnapshot writes the documents, the keywords index and the changed graphs, the caller must hold r.mu
+func (r *localRepository) writeSnapshot(changed map[vectorKey]bool) error {
+	if err := writeGob(filepath.Join(r.dir, documentsFile), r.docs); err != nil {
+		return err
+	}
+	if err := writeGob(filepath.Join(r.dir, keywordsFile), r.keywords); err != nil {
+		return err
+	}
+	for key := range changed {
+		if graph, ok := r.vectors[key]; ok {
+			if err := writeGob(filepath.Join(r.dir, vectorFileName(key)), graph); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Remove the files of graphs that no longer have vectors
+	live := make(map[string]bool, len(r.vectors))
+	for key := range r.vectors {
+		live[vectorFileName(key)] = true
+	}
+	files, err := filepath.Glob(filepath.Join(r.dir, vectorsPrefix+"*"+vectorsSuffix))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if !live[filepath.Base(file)] {
+			_ = os.Remove(file)
+		}
+	}
+	return nil
+}
+
+// runFlusher periodically writes pending changes until Close is called
+func (r *localRepository) runFlusher() {
+	defer close(r.done)
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			if err := r.Flush(); err != nil {
+				logger.GetLogger(context.Background()).Errorf("[Local] Failed to flush index to %s: %v", r.dir, err)
+			}
+		}
+	}
+}
+
+// Close stops the background flusher and writes pending changes
+func (r *localRepository) Close() error {
+	select {
+	case <-r.stop:
+	default:
+		close(r.stop)
+		<-r.done
+	}
+	return r.Flush()
+}
+
+// markDirty records that the index changed, the caller must hold r.mu
+func (r *localRepository) markDirty() {
+	r.dirty = true
+}
+
+// vectorFileName returns the snapshot file of an HNSW graph. The knowledge base ID is hashed
+// to keep the name safe, the graph itself records its knowledge base and dimension.
+func vectorFileName(key vectorKey) string {
+	sum := sha256.Sum256([]byte(key.KnowledgeBaseID))
+	return fmt.Sprintf("%s%d_%s%s", vectorsPrefix, key.Dimension, hex.EncodeToString(sum[:8]), vectorsSuffix)
+}
+
+// readGob decodes a gob file into v, leaving v untouched if the file does not exist
+func readGob(path string, v any) error {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+	if err := gob.NewDecoder(f).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	return nil
+}
+
+// writeGob encodes v into path atomically (write to a temp file, then rename)
+func writeGob(path string, v any) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := gob.NewEncoder(tmp).Encode(v); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to encode %s: %w", path, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
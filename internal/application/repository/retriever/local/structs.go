@@ -0,0 +1,54 @@
+package local
+
+import (
+	"sync"
+)
+
+// localRepository is an in-process retrieve engine: an HNSW graph per knowledge base and
+// embedding dimension for vector retrieval and a BM25 inverted index for keywords retrieval,
+// persisted to local files
+type localRepository struct {
+	dir string
+
+	// mu guards docs, keywords, vectors and the dirty state
+	mu       sync.RWMutex
+	docs     map[string]*localIndexDoc
+	keywords *bm25Index
+	vectors  map[vectorKey]*hnswGraph
+	dirty    bool
+	// dirtyVectors are the graphs changed since the last flush, only those are written again
+	dirtyVectors map[vectorKey]bool
+
+	// flushMu serializes snapshot writes
+	flushMu sync.Mutex
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// vectorKey identifies the HNSW graph holding the vectors of a knowledge base with one dimension.
+// Partitioning by knowledge base keeps searches from walking, and filtering out, other tenants' vectors.
+type vectorKey struct {
+	KnowledgeBaseID string
+	Dimension       int
+}
+
+// localIndexDoc is an indexed entry, keyed by source ID
+type localIndexDoc struct {
+	SourceID        string
+	SourceType      int
+	ChunkID         string
+	KnowledgeID     string
+	KnowledgeBaseID string
+	TagID           string
+	Content         string
+	IsEnabled       bool
+	// Dimension of the embedding stored in the HNSW graph, 0 if the entry has no vector
+	Dimension int
+}
+
+// localIndexEntry is an entry to save together with its embedding and tokens
+type localIndexEntry struct {
+	doc       *localIndexDoc
+	embedding []float32
+	tokens    []string
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/Tencent/WeKnora/internal/common"
@@ -617,3 +618,47 @@ func (g *pgRepository) BatchUpdateChunkTagID(ctx context.Context, chunkTagMap ma
 	logger.GetLogger(ctx).Infof("[Postgres] Successfully batch updated chunk tag ID")
 	return nil
 }
+
+// ListIndices lists the index entries of a knowledge base ordered by ID, the cursor is the last ID returned
+func (g *pgRepository) ListIndices(ctx context.Context,
+	knowledgeBaseID string, dimension int, knowledgeType string, cursor string, limit int,
+) ([]*types.IndexEntry, string, error) {
+	var lastID uint64
+	if cursor != "" {
+		parsed, err := strconv.ParseUint(cursor, 10, 64)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid cursor %q: %w", cursor, err)
+		}
+		lastID = parsed
+	}
+
+	var vectors []*pgVector
+	if err := g.db.WithContext(ctx).
+		Where("knowledge_base_id = ? AND id > ?", knowledgeBaseID, lastID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&vectors).Error; err != nil {
+		logger.GetLogger(ctx).Errorf("[Postgres] Failed to list indices: %v", err)
+		return nil, "", err
+	}
+
+	entries := make([]*types.IndexEntry, 0, len(vectors))
+	for _, vector := range vectors {
+		entries = append(entries, fromDBVectorEmbeddingEntry(vector))
+	}
+
+	nextCursor := ""
+	if len(vectors) == limit {
+		nextCursor = strconv.FormatUint(uint64(vectors[len(vectors)-1].ID), 10)
+	}
+	return entries, nextCursor, nil
+}
+
+// Ping checks that the database holding the embeddings is reachable
+func (g *pgRepository) Ping(ctx context.Context) error {
+	sqlDB, err := g.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
@@ -101,3 +101,23 @@ func fromDBVectorEmbeddingWithScore(embedding *pgVectorWithScore, matchType type
 		MatchType:       matchType,
 	}
 }
+
+// fromDBVectorEmbeddingEntry converts pgVector to an IndexEntry with its embedding
+func fromDBVectorEmbeddingEntry(embedding *pgVector) *types.IndexEntry {
+	entry := &types.IndexEntry{
+		IndexInfo: types.IndexInfo{
+			Content:         embedding.Content,
+			SourceID:        embedding.SourceID,
+			SourceType:      types.SourceType(embedding.SourceType),
+			ChunkID:         embedding.ChunkID,
+			KnowledgeID:     embedding.KnowledgeID,
+			KnowledgeBaseID: embedding.KnowledgeBaseID,
+			TagID:           embedding.TagID,
+			IsEnabled:       embedding.IsEnabled,
+		},
+	}
+	if embedding.Dimension > 0 {
+		entry.Embedding = embedding.Embedding.Slice()
+	}
+	return entry
+}
@@ -856,6 +856,82 @@ func (q *qdrantRepository) CopyIndices(ctx context.Context,
 	return nil
 }
 
+// ListIndices lists the points of a knowledge base in the collection of the dimension,
+// the cursor is the ID of the first point of the next page
+func (q *qdrantRepository) ListIndices(ctx context.Context,
+	knowledgeBaseID string, dimension int, knowledgeType string, cursor string, limit int,
+) ([]*types.IndexEntry, string, error) {
+	log := logger.GetLogger(ctx)
+	collectionName := q.getCollectionName(dimension)
+
+	exists, err := q.client.CollectionExists(ctx, collectionName)
+	if err != nil {
+		log.Errorf("[Qdrant] Failed to check collection existence: %v", err)
+		return nil, "", fmt.Errorf("failed to check collection existence: %w", err)
+	}
+	if !exists {
+		return nil, "", nil
+	}
+
+	// Scroll offsets are inclusive, fetch one extra point to find the next cursor
+	batchSize := uint32(limit + 1)
+	var offset *qdrant.PointId
+	if cursor != "" {
+		offset = qdrant.NewID(cursor)
+	}
+	points, err := q.client.Scroll(ctx, &qdrant.ScrollPoints{
+		CollectionName: collectionName,
+		Filter: &qdrant.Filter{
+			Must: []*qdrant.Condition{
+				qdrant.NewMatch(fieldKnowledgeBaseID, knowledgeBaseID),
+			},
+		},
+		Limit:       &batchSize,
+		Offset:      offset,
+		WithPayload: qdrant.NewWithPayload(true),
+		WithVectors: qdrant.NewWithVectors(true),
+	})
+	if err != nil {
+		log.Errorf("[Qdrant] Failed to list points: %v", err)
+		return nil, "", err
+	}
+
+	nextCursor := ""
+	if len(points) > limit {
+		nextCursor = points[limit].Id.GetUuid()
+		points = points[:limit]
+	}
+
+	entries := make([]*types.IndexEntry, 0, len(points))
+	for _, point := range points {
+		payload := point.Payload
+		// Points saved before is_enabled existed are enabled
+		isEnabled := true
+		if value, ok := payload[fieldIsEnabled]; ok {
+			isEnabled = value.GetBoolValue()
+		}
+		entry := &types.IndexEntry{
+			IndexInfo: types.IndexInfo{
+				Content:         payload[fieldContent].GetStringValue(),
+				SourceID:        payload[fieldSourceID].GetStringValue(),
+				SourceType:      types.SourceType(payload[fieldSourceType].GetIntegerValue()),
+				ChunkID:         payload[fieldChunkID].GetStringValue(),
+				KnowledgeID:     payload[fieldKnowledgeID].GetStringValue(),
+				KnowledgeBaseID: payload[fieldKnowledgeBaseID].GetStringValue(),
+				TagID:           payload[fieldTagID].GetStringValue(),
+				IsEnabled:       isEnabled,
+			},
+		}
+		if vectorOutput := point.Vectors.GetVector(); vectorOutput != nil {
+			if denseVector := vectorOutput.GetDenseVector(); denseVector != nil {
+				entry.Embedding = denseVector.Data
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nextCursor, nil
+}
+
 func createPayload(embedding *QdrantVectorEmbedding) map[string]*qdrant.Value {
 	payload := map[string]any{
 		fieldContent:         embedding.Content,
@@ -930,6 +1006,14 @@ func toQdrantVectorEmbedding(embedding *types.IndexInfo, additionalParams map[st
 			vector.Embedding = embeddingMap[embedding.SourceID]
 		}
 	}
+	// Get is_enabled from additionalParams if available
+	if additionalParams != nil {
+		if chunkEnabledMap, ok := additionalParams["chunk_enabled"].(map[string]bool); ok {
+			if enabled, exists := chunkEnabledMap[embedding.ChunkID]; exists {
+				vector.IsEnabled = enabled
+			}
+		}
+	}
 	return vector
 }
 
@@ -978,3 +1062,9 @@ func tokenizeQuery(query string) []string {
 
 	return result
 }
+
+// Ping checks that the Qdrant server is reachable
+func (q *qdrantRepository) Ping(ctx context.Context) error {
+	_, err := q.client.HealthCheck(ctx)
+	return err
+}
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// newTestDB opens an in-memory SQLite database and runs the given DDL statements
+func newTestDB(t *testing.T, ddl ...string) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	for _, stmt := range ddl {
+		if err := db.Exec(stmt).Error; err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// The tables used by the search tests, as created by the SQLite migrations
+const (
+	testSessionsDDL = `CREATE TABLE sessions (
+		id VARCHAR(36) PRIMARY KEY, title TEXT, description TEXT, tenant_id INTEGER,
+		active_message_id VARCHAR(36), created_at DATETIME, updated_at DATETIME, deleted_at DATETIME)`
+	testMessagesDDL = `CREATE TABLE messages (
+		id VARCHAR(36) PRIMARY KEY, session_id VARCHAR(36), parent_id VARCHAR(36), request_id TEXT,
+		content TEXT, role TEXT, agent_id VARCHAR(36), agent_version INTEGER DEFAULT 0,
+		knowledge_references TEXT, agent_steps TEXT, mentioned_items TEXT, is_completed BOOLEAN,
+		created_at DATETIME, updated_at DATETIME, deleted_at DATETIME)`
+)
+
+func TestContainsPattern(t *testing.T) {
+	tests := []struct {
+		term string
+		want string
+	}{
+		{"plain", "%plain%"},
+		{"100%", `%100\%%`},
+		{"snake_case", `%snake\_case%`},
+		{`C:\temp`, `%C:\\temp%`},
+		{`\%_`, `%\\\%\_%`},
+		{"中文", "%中文%"},
+	}
+	for _, tt := range tests {
+		if got := containsPattern(tt.term); got != tt.want {
+			t.Errorf("containsPattern(%q) = %q, want %q", tt.term, got, tt.want)
+		}
+	}
+}
+
+func TestSearchTreatsWildcardsLiterally(t *testing.T) {
+	db := newTestDB(t, testSessionsDDL, testMessagesDDL)
+	ctx := context.Background()
+	titles := []string{
+		"discount 100% off",
+		"discount 1000 off",
+		"snake_case names",
+		"snakeXcase names",
+		`path C:\temp`,
+		`path C:temp`,
+	}
+	for _, title := range titles {
+		session := &types.Session{TenantID: 1, Title: title}
+		if err := db.Create(session).Error; err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Create(&types.Message{SessionID: session.ID, Role: "user", Content: title}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Another tenant's session never matches
+	if err := db.Create(&types.Session{TenantID: 2, Title: "100% other tenant"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	sessions := NewSessionRepository(db)
+	messages := NewMessageRepository(db)
+	tests := []struct {
+		term string
+		want string
+	}{
+		{"100%", "discount 100% off"},
+		{"snake_case", "snake_case names"},
+		{"SNAKE_CASE", "snake_case names"},
+		{`C:\temp`, `path C:\temp`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.term, func(t *testing.T) {
+			found, err := sessions.SearchByTitle(ctx, 1, []string{tt.term}, &types.MessageSearchFilter{}, 10)
+			if err != nil {
+				t.Fatalf("SearchByTitle() = %v", err)
+			}
+			if len(found) != 1 || found[0].Title != tt.want {
+				t.Errorf("SearchByTitle(%q) found %d sessions, want only %q", tt.term, len(found), tt.want)
+			}
+
+			hits, total, err := messages.SearchMessages(ctx, 1, []string{tt.term}, &types.MessageSearchFilter{},
+				&types.Pagination{Page: 1, PageSize: 10})
+			if err != nil {
+				t.Fatalf("SearchMessages() = %v", err)
+			}
+			if total != 1 || len(hits) != 1 || hits[0].Content != tt.want {
+				t.Errorf("SearchMessages(%q) found %d messages, want only %q", tt.term, total, tt.want)
+			}
+		})
+	}
+}
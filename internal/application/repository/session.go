@@ -83,6 +83,52 @@ func (r *sessionRepository) Update(ctx context.Context, session *types.Session)
 	return r.db.WithContext(ctx).Where("tenant_id = ?", session.TenantID).Save(session).Error
 }
 
+// UpdateActiveMessage sets the last message of the active conversation branch.
+// The column is read-only on the model, so it is written through the table directly.
+func (r *sessionRepository) UpdateActiveMessage(
+	ctx context.Context, tenantID uint64, id string, messageID string,
+) error {
+	return r.db.WithContext(ctx).Table("sessions").
+		Where("id = ? AND tenant_id = ?", id, tenantID).
+		UpdateColumn("active_message_id", messageID).Error
+}
+
+// SearchByTitle finds the sessions of a tenant whose title contains all terms, most recently updated first
+func (r *sessionRepository) SearchByTitle(
+	ctx context.Context,
+	tenantID uint64,
+	terms []string,
+	filter *types.MessageSearchFilter,
+	limit int,
+) ([]*types.Session, error) {
+	likeOp := caseInsensitiveLike(r.db)
+	query := r.db.WithContext(ctx).Where("tenant_id = ?", tenantID)
+	for _, term := range terms {
+		query = query.Where("title "+likeOp+" ? ESCAPE '\\'", containsPattern(term))
+	}
+	if filter.AgentID != "" {
+		query = query.Where("EXISTS (SELECT 1 FROM messages WHERE messages.session_id = sessions.id "+
+			"AND messages.deleted_at IS NULL AND messages.agent_id = ?)", filter.AgentID)
+	}
+	if filter.KnowledgeBaseID != "" {
+		query = query.Where("EXISTS (SELECT 1 FROM messages WHERE messages.session_id = sessions.id "+
+			"AND messages.deleted_at IS NULL AND "+messageKnowledgeBaseCondition(r.db)+")",
+			filter.KnowledgeBaseID, filter.KnowledgeBaseID)
+	}
+	if filter.StartTime != nil {
+		query = query.Where("updated_at >= ?", *filter.StartTime)
+	}
+	if filter.EndTime != nil {
+		query = query.Where("created_at < ?", *filter.EndTime)
+	}
+
+	var sessions []*types.Session
+	if err := query.Order("updated_at DESC").Limit(limit).Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
 // Delete deletes a session
 func (r *sessionRepository) Delete(ctx context.Context, tenantID uint64, id string) error {
 	return r.db.WithContext(ctx).Where("tenant_id = ?", tenantID).Delete(&types.Session{}, "id = ?", id).Error
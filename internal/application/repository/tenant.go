@@ -98,6 +98,14 @@ func (r *tenantRepository) UpdateTenant(ctx context.Context, tenant *types.Tenan
 	return r.db.WithContext(ctx).Model(&types.Tenant{}).Where("id = ?", tenant.ID).Updates(tenant).Error
 }
 
+// UpdateRetrieverEngines replaces the retriever engines of a tenant, leaving its other columns untouched
+func (r *tenantRepository) UpdateRetrieverEngines(ctx context.Context,
+	tenantID uint64, engines types.RetrieverEngines,
+) error {
+	return r.db.WithContext(ctx).Model(&types.Tenant{}).Where("id = ?", tenantID).
+		Update("retriever_engines", engines).Error
+}
+
 // DeleteTenant deletes tenant
 func (r *tenantRepository) DeleteTenant(ctx context.Context, id uint64) error {
 	return r.db.WithContext(ctx).Where("id = ?", id).Delete(&types.Tenant{}).Error
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"gorm.io/gorm"
+)
+
+// toolApprovalRepository implements the ToolApprovalRepository interface
+type toolApprovalRepository struct {
+	db *gorm.DB
+}
+
+// NewToolApprovalRepository creates a new tool approval repository
+func NewToolApprovalRepository(db *gorm.DB) interfaces.ToolApprovalRepository {
+	return &toolApprovalRepository{db: db}
+}
+
+// Create creates a new approval request
+func (r *toolApprovalRepository) Create(ctx context.Context, approval *types.ToolApproval) error {
+	return r.db.WithContext(ctx).Create(approval).Error
+}
+
+// GetByID retrieves an approval request of a session by ID
+func (r *toolApprovalRepository) GetByID(
+	ctx context.Context,
+	sessionID string,
+	id string,
+) (*types.ToolApproval, error) {
+	var approval types.ToolApproval
+	err := r.db.WithContext(ctx).
+		Where("id = ? AND session_id = ?", id, sessionID).
+		First(&approval).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &approval, nil
+}
+
+// ListBySession retrieves the approval requests of a session, optionally filtered by status
+func (r *toolApprovalRepository) ListBySession(
+	ctx context.Context,
+	sessionID string,
+	status string,
+) ([]*types.ToolApproval, error) {
+	var approvals []*types.ToolApproval
+	query := r.db.WithContext(ctx).Where("session_id = ?", sessionID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Order("created_at ASC").Find(&approvals).Error; err != nil {
+		return nil, err
+	}
+
+	return approvals, nil
+}
+
+// Resolve moves a pending approval request to a final status.
+// The update is conditional on the pending status so concurrent decisions cannot both win.
+func (r *toolApprovalRepository) Resolve(ctx context.Context, approval *types.ToolApproval) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&types.ToolApproval{}).
+		Where("id = ? AND status = ?", approval.ID, types.ToolApprovalStatusPending).
+		Updates(map[string]interface{}{
+			"status":     approval.Status,
+			"reason":     approval.Reason,
+			"decided_at": approval.DecidedAt,
+			"updated_at": approval.UpdatedAt,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
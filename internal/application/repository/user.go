@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/Tencent/WeKnora/internal/types"
 	"github.com/Tencent/WeKnora/internal/types/interfaces"
@@ -99,9 +100,14 @@ func (r *userRepository) ListUsers(ctx context.Context, offset, limit int) ([]*t
 func (r *userRepository) SearchUsers(ctx context.Context, query string, limit int) ([]*types.User, error) {
 	var users []*types.User
 	searchPattern := "%" + query + "%"
+	// SQLite has no ILIKE, but its LIKE is already case-insensitive for ASCII
+	likeOp := "ILIKE"
+	if r.db.Dialector.Name() == "sqlite" {
+		likeOp = "LIKE"
+	}
 
 	dbQuery := r.db.WithContext(ctx).
-		Where("username ILIKE ? OR email ILIKE ?", searchPattern, searchPattern).
+		Where("username "+likeOp+" ? OR email "+likeOp+" ?", searchPattern, searchPattern).
 		Where("is_active = ?", true).
 		Order("username ASC")
 
@@ -165,7 +171,7 @@ func (r *authTokenRepository) DeleteToken(ctx context.Context, id string) error
 
 // DeleteExpiredTokens deletes all expired tokens
 func (r *authTokenRepository) DeleteExpiredTokens(ctx context.Context) error {
-	return r.db.WithContext(ctx).Where("expires_at < NOW()").Delete(&types.AuthToken{}).Error
+	return r.db.WithContext(ctx).Where("expires_at < ?", time.Now()).Delete(&types.AuthToken{}).Error
 }
 
 // RevokeTokensByUserID revokes all tokens for a user
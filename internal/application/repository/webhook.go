@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"gorm.io/gorm"
+)
+
+// webhookRepository implements the WebhookRepository interface
+type webhookRepository struct {
+	db *gorm.DB
+}
+
+// NewWebhookRepository creates a new webhook repository
+func NewWebhookRepository(db *gorm.DB) interfaces.WebhookRepository {
+	return &webhookRepository{db: db}
+}
+
+// Create creates a new webhook
+func (r *webhookRepository) Create(ctx context.Context, webhook *types.Webhook) error {
+	return r.db.WithContext(ctx).Create(webhook).Error
+}
+
+// GetByID retrieves a webhook of a tenant by ID
+func (r *webhookRepository) GetByID(ctx context.Context, tenantID uint64, id string) (*types.Webhook, error) {
+	var webhook types.Webhook
+	err := r.db.WithContext(ctx).
+		Where("id = ? AND tenant_id = ?", id, tenantID).
+		First(&webhook).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &webhook, nil
+}
+
+// List retrieves all webhooks of a tenant
+func (r *webhookRepository) List(ctx context.Context, tenantID uint64) ([]*types.Webhook, error) {
+	var webhooks []*types.Webhook
+	if err := r.db.WithContext(ctx).
+		Where("tenant_id = ?", tenantID).
+		Order("created_at DESC").
+		Find(&webhooks).Error; err != nil {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+// ListEnabled retrieves the enabled webhooks of a tenant
+func (r *webhookRepository) ListEnabled(ctx context.Context, tenantID uint64) ([]*types.Webhook, error) {
+	var webhooks []*types.Webhook
+	if err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND enabled = ?", tenantID, true).
+		Find(&webhooks).Error; err != nil {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+// Update saves a webhook
+func (r *webhookRepository) Update(ctx context.Context, webhook *types.Webhook) error {
+	return r.db.WithContext(ctx).
+		Model(&types.Webhook{}).
+		Where("id = ? AND tenant_id = ?", webhook.ID, webhook.TenantID).
+		Updates(map[string]interface{}{
+			"name":        webhook.Name,
+			"description": webhook.Description,
+			"url":         webhook.URL,
+			"secret":      webhook.Secret,
+			"events":      webhook.Events,
+			"enabled":     webhook.Enabled,
+			"updated_at":  webhook.UpdatedAt,
+		}).Error
+}
+
+// Delete deletes a webhook and its deliveries
+func (r *webhookRepository) Delete(ctx context.Context, tenantID uint64, id string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("webhook_id = ? AND tenant_id = ?", id, tenantID).
+			Delete(&types.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ? AND tenant_id = ?", id, tenantID).
+			Delete(&types.Webhook{}).Error
+	})
+}
+
+// CreateDelivery creates a new delivery record
+func (r *webhookRepository) CreateDelivery(ctx context.Context, delivery *types.WebhookDelivery) error {
+	return r.db.WithContext(ctx).Create(delivery).Error
+}
+
+// GetDelivery retrieves a delivery of a tenant by ID
+func (r *webhookRepository) GetDelivery(
+	ctx context.Context,
+	tenantID uint64,
+	id string,
+) (*types.WebhookDelivery, error) {
+	var delivery types.WebhookDelivery
+	err := r.db.WithContext(ctx).
+		Where("id = ? AND tenant_id = ?", id, tenantID).
+		First(&delivery).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &delivery, nil
+}
+
+// ListDeliveries retrieves the deliveries of a webhook, newest first
+func (r *webhookRepository) ListDeliveries(
+	ctx context.Context,
+	tenantID uint64,
+	webhookID string,
+	page *types.Pagination,
+) ([]*types.WebhookDelivery, int64, error) {
+	query := r.db.WithContext(ctx).
+		Model(&types.WebhookDelivery{}).
+		Where("tenant_id = ? AND webhook_id = ?", tenantID, webhookID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var deliveries []*types.WebhookDelivery
+	if err := query.
+		Order("created_at DESC").
+		Offset(page.Offset()).
+		Limit(page.Limit()).
+		Find(&deliveries).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return deliveries, total, nil
+}
+
+// UpdateDelivery saves the result of a delivery attempt
+func (r *webhookRepository) UpdateDelivery(ctx context.Context, delivery *types.WebhookDelivery) error {
+	return r.db.WithContext(ctx).
+		Model(&types.WebhookDelivery{}).
+		Where("id = ?", delivery.ID).
+		Updates(map[string]interface{}{
+			"status":          delivery.Status,
+			"attempts":        delivery.Attempts,
+			"response_status": delivery.ResponseStatus,
+			"response_body":   delivery.ResponseBody,
+			"error":           delivery.Error,
+			"duration_ms":     delivery.DurationMs,
+			"delivered_at":    delivery.DeliveredAt,
+			"updated_at":      delivery.UpdatedAt,
+		}).Error
+}
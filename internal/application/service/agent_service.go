@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/Tencent/WeKnora/internal/agent"
 	"github.com/Tencent/WeKnora/internal/agent/skills"
@@ -40,6 +41,10 @@ type agentService struct {
 	chunkService          interfaces.ChunkService
 	duckdb                *sql.DB
 	webSearchStateService interfaces.WebSearchStateService
+	toolApprovalService   interfaces.ToolApprovalService
+	memoryService         interfaces.UserMemoryService
+	httpToolService       interfaces.HTTPToolService
+	traceRepo             interfaces.AgentTraceRepository
 }
 
 // NewAgentService creates a new agent service
@@ -57,6 +62,10 @@ func NewAgentService(
 	webSearchService interfaces.WebSearchService,
 	duckdb *sql.DB,
 	webSearchStateService interfaces.WebSearchStateService,
+	toolApprovalService interfaces.ToolApprovalService,
+	memoryService interfaces.UserMemoryService,
+	httpToolService interfaces.HTTPToolService,
+	traceRepo interfaces.AgentTraceRepository,
 ) interfaces.AgentService {
 	return &agentService{
 		cfg:                   cfg,
@@ -72,6 +81,10 @@ func NewAgentService(
 		webSearchService:      webSearchService,
 		duckdb:                duckdb,
 		webSearchStateService: webSearchStateService,
+		toolApprovalService:   toolApprovalService,
+		memoryService:         memoryService,
+		httpToolService:       httpToolService,
+		traceRepo:             traceRepo,
 	}
 }
 
@@ -99,6 +112,100 @@ func (s *agentService) CreateAgentEngine(
 	// Note: rerankModel can be nil when no knowledge bases are configured
 	// The registerTools function will filter out knowledge-related tools in this case
 
+	toolRegistry, err := s.buildToolRegistry(ctx, config, chatModel, rerankModel, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get knowledge base detailed information for prompt
+	kbInfos, err := s.getKnowledgeBaseInfos(ctx, config.KnowledgeBases)
+	if err != nil {
+		logger.Warnf(ctx, "Failed to get knowledge base details, using IDs only: %v", err)
+		// Create fallback info with IDs only
+		kbInfos = make([]*agent.KnowledgeBaseInfo, 0, len(config.KnowledgeBases))
+		for _, kbID := range config.KnowledgeBases {
+			kbInfos = append(kbInfos, &agent.KnowledgeBaseInfo{
+				ID:          kbID,
+				Name:        kbID, // Use ID as name when details unavailable
+				Description: "",
+				DocCount:    0,
+			})
+		}
+	}
+
+	// Get selected documents information (user @ mentioned documents)
+	selectedDocs, err := s.getSelectedDocumentInfos(ctx, config.KnowledgeIDs)
+	if err != nil {
+		logger.Warnf(ctx, "Failed to get selected document details: %v", err)
+		selectedDocs = []*agent.SelectedDocumentInfo{}
+	}
+
+	systemPromptTemplate := ""
+	if config.UseCustomSystemPrompt {
+		systemPromptTemplate = config.ResolveSystemPrompt(config.WebSearchEnabled)
+	}
+
+	// Create engine with provided EventBus and contextManager
+	engine := agent.NewAgentEngine(
+		config,
+		chatModel,
+		toolRegistry,
+		eventBus,
+		kbInfos,
+		selectedDocs,
+		contextManager,
+		sessionID,
+		systemPromptTemplate,
+	)
+
+	// Initialize skills manager if skills are enabled
+	if config.SkillsEnabled && len(config.SkillDirs) > 0 {
+		skillsManager, err := s.initializeSkillsManager(ctx, config, toolRegistry)
+		if err != nil {
+			logger.Warnf(ctx, "Failed to initialize skills manager: %v", err)
+		} else if skillsManager != nil {
+			engine.SetSkillsManager(skillsManager)
+			logger.Infof(ctx, "Skills manager initialized with %d skills", len(skillsManager.GetAllMetadata()))
+		}
+	}
+
+	// Inject pinned MCP resources/prompts and recalled user memories into the system prompt
+	pinnedContext := make([]string, 0, 2)
+	if len(config.MCPContextSources) > 0 {
+		pinnedContext = append(pinnedContext, s.BuildMCPContext(ctx, config.MCPContextSources))
+	}
+	if config.MemoryContext != "" {
+		pinnedContext = append(pinnedContext, config.MemoryContext)
+	}
+	if len(pinnedContext) > 0 {
+		engine.SetPinnedContext(strings.Join(pinnedContext, "\n\n"))
+	}
+
+	// Require user approval for sensitive tools
+	if approvalTools := resolveApprovalTools(toolRegistry, config); len(approvalTools) > 0 {
+		engine.SetToolApproval(s.toolApprovalService, approvalTools)
+		logger.Infof(ctx, "Tool approval required for: %v", approvalTools)
+	}
+
+	// Record the full trace of the run for export and replay, if the agent opted in
+	if config.TraceEnabled {
+		engine.SetTraceSink(func(ctx context.Context, trace *types.AgentTrace) {
+			saveAgentTrace(ctx, s.traceRepo, trace)
+		})
+	}
+
+	return engine, nil
+}
+
+// buildToolRegistry registers the tools available to an agent run: the allowed built-in tools,
+// memory tools, MCP tools, HTTP tools and sub-agent tools
+func (s *agentService) buildToolRegistry(
+	ctx context.Context,
+	config *types.AgentConfig,
+	chatModel chat.Chat,
+	rerankModel rerank.Reranker,
+	sessionID string,
+) (*tools.ToolRegistry, error) {
 	// Create tool registry
 	toolRegistry := tools.NewToolRegistry()
 
@@ -107,6 +214,20 @@ func (s *agentService) CreateAgentEngine(
 		return nil, fmt.Errorf("failed to register tools: %w", err)
 	}
 
+	// Register long-term memory tools, memories belong to a user so API key sessions have none
+	if config.MemoryEnabled {
+		if userID, _ := ctx.Value(types.UserIDContextKey).(string); userID != "" {
+			for _, memoryTool := range tools.NewMemoryTools(
+				s.memoryService, config.MemoryAgentID, sessionID, config.MemoryEmbeddingModelID,
+			) {
+				toolRegistry.RegisterTool(memoryTool)
+			}
+			logger.Infof(ctx, "Registered memory tools for user: %s", userID)
+		} else {
+			logger.Infof(ctx, "Memory enabled but no user in context, memory tools skipped")
+		}
+	}
+
 	// Register MCP tools from enabled services for this tenant
 	tenantID := uint64(0)
 	if tid, ok := ctx.Value(types.TenantIDContextKey).(uint64); ok {
@@ -158,64 +279,103 @@ func (s *agentService) CreateAgentEngine(
 					} else {
 						logger.Infof(ctx, "Registered MCP tools from %d enabled services", len(enabledServices))
 					}
+					// Allow the agent to read MCP resources on demand
+					toolRegistry.RegisterTool(tools.NewReadMCPResourceTool(enabledServices, s.mcpManager))
 				}
 			}
 		}
 	}
 
-	// Get knowledge base detailed information for prompt
-	kbInfos, err := s.getKnowledgeBaseInfos(ctx, config.KnowledgeBases)
-	if err != nil {
-		logger.Warnf(ctx, "Failed to get knowledge base details, using IDs only: %v", err)
-		// Create fallback info with IDs only
-		kbInfos = make([]*agent.KnowledgeBaseInfo, 0, len(config.KnowledgeBases))
-		for _, kbID := range config.KnowledgeBases {
-			kbInfos = append(kbInfos, &agent.KnowledgeBaseInfo{
-				ID:          kbID,
-				Name:        kbID, // Use ID as name when details unavailable
-				Description: "",
-				DocCount:    0,
-			})
+	// Register the HTTP tools selected by the agent
+	if tenantID > 0 && len(config.HTTPTools) > 0 {
+		httpTools, err := s.httpToolService.GetAgentTools(ctx, tenantID, config.HTTPTools)
+		if err != nil {
+			logger.Warnf(ctx, "Failed to load HTTP tools: %v", err)
+		} else {
+			for _, httpTool := range httpTools {
+				toolRegistry.RegisterTool(httpTool)
+			}
+			logger.Infof(ctx, "Registered %d HTTP tools from agent config", len(httpTools))
 		}
 	}
 
-	// Get selected documents information (user @ mentioned documents)
-	selectedDocs, err := s.getSelectedDocumentInfos(ctx, config.KnowledgeIDs)
-	if err != nil {
-		logger.Warnf(ctx, "Failed to get selected document details: %v", err)
-		selectedDocs = []*agent.SelectedDocumentInfo{}
+	// Register the agents this agent can delegate to
+	for _, subAgentTool := range config.SubAgentTools {
+		toolRegistry.RegisterTool(subAgentTool)
+	}
+	if len(config.SubAgentTools) > 0 {
+		logger.Infof(ctx, "Registered %d sub-agent tools", len(config.SubAgentTools))
 	}
 
-	systemPromptTemplate := ""
-	if config.UseCustomSystemPrompt {
-		systemPromptTemplate = config.ResolveSystemPrompt(config.WebSearchEnabled)
+	return toolRegistry, nil
+}
+
+// CreateWorkflowEngine creates an engine running config.Workflow, with the same tools as an agent
+// engine for the config. Tools requiring approval cannot be called by workflows.
+func (s *agentService) CreateWorkflowEngine(
+	ctx context.Context,
+	config *types.AgentConfig,
+	chatModel chat.Chat,
+	rerankModel rerank.Reranker,
+	eventBus *event.EventBus,
+	sessionID string,
+) (interfaces.AgentEngine, error) {
+	logger.Infof(ctx, "Creating workflow engine")
+
+	if config.Workflow == nil {
+		return nil, fmt.Errorf("invalid agent config: workflow is required")
+	}
+	if chatModel == nil {
+		return nil, fmt.Errorf("chat model is nil after initialization")
 	}
 
-	// Create engine with provided EventBus and contextManager
-	engine := agent.NewAgentEngine(
+	toolRegistry, err := s.buildToolRegistry(ctx, config, chatModel, rerankModel, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return agent.NewWorkflowEngine(
 		config,
 		chatModel,
+		rerankModel,
 		toolRegistry,
 		eventBus,
-		kbInfos,
-		selectedDocs,
-		contextManager,
-		sessionID,
-		systemPromptTemplate,
-	)
+		s.knowledgeBaseService,
+		s.modelService,
+		resolveApprovalTools(toolRegistry, config),
+	), nil
+}
 
-	// Initialize skills manager if skills are enabled
-	if config.SkillsEnabled && len(config.SkillDirs) > 0 {
-		skillsManager, err := s.initializeSkillsManager(ctx, config, toolRegistry)
+// resolveApprovalTools returns the registered tool names that require user approval,
+// either listed by name or provided by an MCP service marked as requiring approval
+func resolveApprovalTools(toolRegistry *tools.ToolRegistry, config *types.AgentConfig) []string {
+	if len(config.ApprovalRequiredTools) == 0 && len(config.ApprovalRequiredMCPServices) == 0 {
+		return nil
+	}
+	requiredTools := make(map[string]bool, len(config.ApprovalRequiredTools))
+	for _, name := range config.ApprovalRequiredTools {
+		requiredTools[name] = true
+	}
+	requiredServices := make(map[string]bool, len(config.ApprovalRequiredMCPServices))
+	for _, serviceID := range config.ApprovalRequiredMCPServices {
+		requiredServices[serviceID] = true
+	}
+
+	names := make([]string, 0)
+	for _, name := range toolRegistry.ListTools() {
+		if requiredTools[name] {
+			names = append(names, name)
+			continue
+		}
+		tool, err := toolRegistry.GetTool(name)
 		if err != nil {
-			logger.Warnf(ctx, "Failed to initialize skills manager: %v", err)
-		} else if skillsManager != nil {
-			engine.SetSkillsManager(skillsManager)
-			logger.Infof(ctx, "Skills manager initialized with %d skills", len(skillsManager.GetAllMetadata()))
+			continue
+		}
+		if mcpTool, ok := tool.(*tools.MCPTool); ok && requiredServices[mcpTool.ServiceID()] {
+			names = append(names, name)
 		}
 	}
-
-	return engine, nil
+	return names
 }
 
 // initializeSkillsManager creates and initializes the skills manager
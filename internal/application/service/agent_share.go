@@ -28,6 +28,7 @@ type agentShareService struct {
 	disabledRepo interfaces.TenantDisabledSharedAgentRepository
 	orgRepo      interfaces.OrganizationRepository
 	agentRepo    interfaces.CustomAgentRepository
+	versionRepo  interfaces.AgentVersionRepository
 	userRepo     interfaces.UserRepository
 }
 
@@ -37,6 +38,7 @@ func NewAgentShareService(
 	disabledRepo interfaces.TenantDisabledSharedAgentRepository,
 	orgRepo interfaces.OrganizationRepository,
 	agentRepo interfaces.CustomAgentRepository,
+	versionRepo interfaces.AgentVersionRepository,
 	userRepo interfaces.UserRepository,
 ) interfaces.AgentShareService {
 	return &agentShareService{
@@ -44,6 +46,7 @@ func NewAgentShareService(
 		disabledRepo: disabledRepo,
 		orgRepo:      orgRepo,
 		agentRepo:    agentRepo,
+		versionRepo:  versionRepo,
 		userRepo:     userRepo,
 	}
 }
@@ -378,6 +381,7 @@ func (s *agentShareService) SetSharedAgentDisabledByMe(ctx context.Context, tena
 }
 
 // GetSharedAgentForUser returns the shared agent by agentID if the user has access; source tenant is resolved from the user's share. One share lookup + one agent lookup.
+// The agent answers with the version pinned by the share, or its published version.
 func (s *agentShareService) GetSharedAgentForUser(ctx context.Context, userID string, currentTenantID uint64, agentID string) (*types.CustomAgent, error) {
 	if agentID == "" {
 		return nil, ErrAgentShareNotFound
@@ -396,7 +400,51 @@ func (s *agentShareService) GetSharedAgentForUser(ctx context.Context, userID st
 		}
 		return nil, err
 	}
-	return agent, nil
+	version := agent.PublishedVersion
+	if share.PinnedVersion > 0 {
+		version = share.PinnedVersion
+	}
+	return applyAgentVersion(ctx, s.versionRepo, agent, version)
+}
+
+// PinShareVersion pins the version of the agent used by members of the share's organization, 0 follows the published version.
+// Only the agent's tenant and admins of the organization can pin.
+func (s *agentShareService) PinShareVersion(ctx context.Context, agentID string, shareID string, userID string, tenantID uint64, version int) (*types.AgentShare, error) {
+	share, err := s.shareRepo.GetByID(ctx, shareID)
+	if err != nil {
+		if errors.Is(err, repository.ErrAgentShareNotFound) {
+			return nil, ErrAgentShareNotFound
+		}
+		return nil, err
+	}
+	if share.AgentID != agentID {
+		return nil, ErrAgentShareNotFound
+	}
+	if share.SourceTenantID != tenantID {
+		member, err := s.orgRepo.GetMember(ctx, share.OrganizationID, userID)
+		if err != nil || member.Role != types.OrgRoleAdmin {
+			return nil, ErrAgentSharePermission
+		}
+	}
+	if version < 0 {
+		return nil, fmt.Errorf("%w: version must not be negative", ErrAgentVersionInvalid)
+	}
+	if version > 0 {
+		agentVersion, err := s.versionRepo.GetVersion(ctx, share.SourceTenantID, share.AgentID, version)
+		if err != nil {
+			return nil, err
+		}
+		if agentVersion == nil {
+			return nil, fmt.Errorf("%w: version %d", ErrAgentVersionNotFound, version)
+		}
+	}
+
+	if err := s.shareRepo.UpdatePinnedVersion(ctx, share.ID, version); err != nil {
+		return nil, err
+	}
+	logger.Infof(ctx, "Agent share %s pinned to version %d", share.ID, version)
+	share.PinnedVersion = version
+	return share, nil
 }
 
 // UserCanAccessKBViaSomeSharedAgent returns true if the user has at least one shared agent that can access the given KB (used when opening KB detail from space list without agent_id).
@@ -0,0 +1,343 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"github.com/robfig/cron/v3"
+)
+
+const (
+	// agentTaskRunTimeout bounds a single headless agent run
+	agentTaskRunTimeout = 10 * time.Minute
+	// agentTaskScheduleBatch caps the schedule tasks enqueued by one scheduler tick
+	agentTaskScheduleBatch = 100
+)
+
+var (
+	ErrAgentTaskNotFound    = errors.New("agent task not found")
+	ErrAgentTaskRunNotFound = errors.New("agent task run not found")
+	ErrAgentTaskInvalid     = errors.New("invalid agent task")
+)
+
+// agentTaskService implements the AgentTaskService interface.
+// It only records and enqueues runs, so that the knowledge service can fire triggers without
+// depending on the session service; runs are executed by agentTaskRunner.
+type agentTaskService struct {
+	repo               interfaces.AgentTaskRepository
+	customAgentService interfaces.CustomAgentService
+	webhookService     interfaces.WebhookService
+	asynqClient        *asynq.Client
+}
+
+// NewAgentTaskService creates a new agent task service
+func NewAgentTaskService(
+	repo interfaces.AgentTaskRepository,
+	customAgentService interfaces.CustomAgentService,
+	webhookService interfaces.WebhookService,
+	asynqClient *asynq.Client,
+) interfaces.AgentTaskService {
+	return &agentTaskService{
+		repo:               repo,
+		customAgentService: customAgentService,
+		webhookService:     webhookService,
+		asynqClient:        asynqClient,
+	}
+}
+
+// CreateTask creates an agent task for the current tenant
+func (s *agentTaskService) CreateTask(ctx context.Context, task *types.AgentTask) (*types.AgentTask, error) {
+	if err := s.validate(ctx, task); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	task.ID = uuid.New().String()
+	task.TenantID = ctx.Value(types.TenantIDContextKey).(uint64)
+	task.CreatedAt = now
+	task.UpdatedAt = now
+	if err := s.repo.Create(ctx, task); err != nil {
+		return nil, err
+	}
+	logger.Infof(ctx, "[AgentTask] Created task %s of agent %s, trigger %s", task.ID, task.AgentID, task.Trigger)
+	return task, nil
+}
+
+// GetTask returns an agent task of the current tenant
+func (s *agentTaskService) GetTask(ctx context.Context, id string) (*types.AgentTask, error) {
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	task, err := s.repo.GetByID(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if task == nil {
+		return nil, ErrAgentTaskNotFound
+	}
+	return task, nil
+}
+
+// ListTasks lists the agent tasks of the current tenant
+func (s *agentTaskService) ListTasks(ctx context.Context) ([]*types.AgentTask, error) {
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	return s.repo.List(ctx, tenantID)
+}
+
+// UpdateTask updates an agent task of the current tenant, the next run is recomputed
+func (s *agentTaskService) UpdateTask(ctx context.Context, task *types.AgentTask) (*types.AgentTask, error) {
+	if err := s.validate(ctx, task); err != nil {
+		return nil, err
+	}
+	task.TenantID = ctx.Value(types.TenantIDContextKey).(uint64)
+	task.UpdatedAt = time.Now()
+	if err := s.repo.Update(ctx, task); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// DeleteTask deletes an agent task of the current tenant and its run history.
+// Sessions created by its runs are kept.
+func (s *agentTaskService) DeleteTask(ctx context.Context, id string) error {
+	task, err := s.GetTask(ctx, id)
+	if err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, task.TenantID, task.ID)
+}
+
+// RunTask enqueues a run of a task now, whether it is enabled or not
+func (s *agentTaskService) RunTask(ctx context.Context,
+	id string, data map[string]interface{},
+) (*types.AgentTaskRun, error) {
+	task, err := s.GetTask(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.enqueueRun(ctx, task, types.AgentTaskRunTriggerManual, data)
+}
+
+// ListRuns lists the run history of a task of the current tenant
+func (s *agentTaskService) ListRuns(ctx context.Context,
+	taskID string, page *types.Pagination,
+) (*types.PageResult, error) {
+	task, err := s.GetTask(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	runs, total, err := s.repo.ListRuns(ctx, task.TenantID, task.ID, page)
+	if err != nil {
+		return nil, err
+	}
+	return types.NewPageResult(total, page, runs), nil
+}
+
+// GetRun returns a run of a task of the current tenant
+func (s *agentTaskService) GetRun(ctx context.Context, taskID string, runID string) (*types.AgentTaskRun, error) {
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	run, err := s.repo.GetRun(ctx, tenantID, runID)
+	if err != nil {
+		return nil, err
+	}
+	if run == nil || run.TaskID != taskID {
+		return nil, ErrAgentTaskRunNotFound
+	}
+	return run, nil
+}
+
+// Fire enqueues a run of each enabled task of the tenant with the trigger whose knowledge base
+// filter matches the event
+func (s *agentTaskService) Fire(ctx context.Context, tenantID uint64, trigger string, data map[string]interface{}) {
+	if tenantID == 0 {
+		return
+	}
+	// Events are fired at the end of tasks whose context is about to end
+	ctx = context.WithoutCancel(ctx)
+	ctx = context.WithValue(ctx, types.TenantIDContextKey, tenantID)
+
+	tasks, err := s.repo.ListEnabledByTrigger(ctx, tenantID, trigger)
+	if err != nil {
+		logger.Warnf(ctx, "[AgentTask] Failed to list tasks of tenant %d for %s: %v", tenantID, trigger, err)
+		return
+	}
+	for _, task := range tasks {
+		if !task.Matches(trigger, data) {
+			continue
+		}
+		if _, err := s.enqueueRun(ctx, task, trigger, data); err != nil {
+			logger.Warnf(ctx, "[AgentTask] Failed to enqueue run of task %s for %s: %v", task.ID, trigger, err)
+		}
+	}
+}
+
+// ProcessAgentTaskSchedule enqueues a run of each schedule task that is due and moves its next run.
+// Runs missed while the scheduler was down are not caught up, only the latest one runs.
+func (s *agentTaskService) ProcessAgentTaskSchedule(ctx context.Context, t *asynq.Task) error {
+	now := time.Now().UTC()
+	tasks, err := s.repo.ListDue(ctx, now, agentTaskScheduleBatch)
+	if err != nil {
+		return err
+	}
+	for _, task := range tasks {
+		taskCtx := context.WithValue(ctx, types.TenantIDContextKey, task.TenantID)
+		next, err := nextAgentTaskRun(task, now)
+		if err != nil {
+			// The schedule was valid when saved, e.g. the time zone database changed since
+			logger.Warnf(taskCtx, "[AgentTask] Invalid schedule of task %s, disabling it: %v", task.ID, err)
+		}
+		claimed, err := s.repo.ClaimNextRun(taskCtx, task, now, next)
+		if err != nil {
+			logger.Warnf(taskCtx, "[AgentTask] Failed to claim run of task %s: %v", task.ID, err)
+			continue
+		}
+		if !claimed || next == nil {
+			continue
+		}
+		if _, err := s.enqueueRun(taskCtx, task, types.AgentTaskTriggerSchedule, nil); err != nil {
+			logger.Warnf(taskCtx, "[AgentTask] Failed to enqueue scheduled run of task %s: %v", task.ID, err)
+		}
+	}
+	return nil
+}
+
+// enqueueRun records a pending run of the task with the rendered prompt and enqueues it.
+// A run that cannot be enqueued is recorded as failed and reported.
+func (s *agentTaskService) enqueueRun(ctx context.Context,
+	task *types.AgentTask, trigger string, data map[string]interface{},
+) (*types.AgentTaskRun, error) {
+	eventData, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	// Render from the encoded event so that the prompt shows the fields as the run history does
+	var variables map[string]interface{}
+	_ = json.Unmarshal(eventData, &variables)
+	if variables == nil {
+		variables = make(map[string]interface{})
+	}
+	now := time.Now()
+	// Dates in the prompt are in the time zone of the task
+	local := now
+	if loc, err := task.Location(); err == nil {
+		local = now.In(loc)
+	}
+	variables[types.AgentTaskVariableNow] = local.Format(time.RFC3339)
+	variables[types.AgentTaskVariableDate] = local.Format(time.DateOnly)
+	variables[types.AgentTaskVariableTaskName] = task.Name
+	variables[types.AgentTaskVariableLastRunAt] = ""
+	if task.LastRunAt != nil {
+		variables[types.AgentTaskVariableLastRunAt] = task.LastRunAt.In(local.Location()).Format(time.RFC3339)
+	}
+	if data == nil {
+		eventData = nil
+	}
+
+	run := &types.AgentTaskRun{
+		ID:        uuid.New().String(),
+		TenantID:  task.TenantID,
+		TaskID:    task.ID,
+		AgentID:   task.AgentID,
+		Trigger:   trigger,
+		Status:    types.AgentTaskRunStatusPending,
+		EventData: types.JSON(eventData),
+		Prompt:    task.RenderPrompt(variables),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.repo.CreateRun(ctx, run); err != nil {
+		return nil, err
+	}
+
+	payload, err := json.Marshal(types.AgentTaskRunPayload{TenantID: run.TenantID, RunID: run.ID})
+	if err == nil {
+		_, err = s.asynqClient.Enqueue(asynq.NewTask(types.TypeAgentTaskRun, payload,
+			asynq.Queue("default"), asynq.MaxRetry(0), asynq.Timeout(agentTaskRunTimeout+time.Minute)))
+	}
+	if err != nil {
+		finishAgentTaskRun(ctx, s.repo, s.webhookService, task, run, fmt.Errorf("enqueue run: %w", err))
+		return nil, err
+	}
+	logger.Infof(ctx, "[AgentTask] Enqueued run %s of task %s, trigger %s", run.ID, task.ID, trigger)
+	return run, nil
+}
+
+// validate checks the agent, trigger, schedule, prompt and destination of a task,
+// and computes its next run
+func (s *agentTaskService) validate(ctx context.Context, task *types.AgentTask) error {
+	task.Name = strings.TrimSpace(task.Name)
+	if task.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrAgentTaskInvalid)
+	}
+	if task.AgentID == "" {
+		return fmt.Errorf("%w: agent_id is required", ErrAgentTaskInvalid)
+	}
+	if _, err := s.customAgentService.GetAgentByID(ctx, task.AgentID); err != nil {
+		return fmt.Errorf("%w: agent %s not found", ErrAgentTaskInvalid, task.AgentID)
+	}
+	if !slices.Contains(types.AgentTaskTriggers, task.Trigger) {
+		return fmt.Errorf("%w: unsupported trigger %q", ErrAgentTaskInvalid, task.Trigger)
+	}
+	if strings.TrimSpace(task.Prompt) == "" {
+		return fmt.Errorf("%w: prompt is required", ErrAgentTaskInvalid)
+	}
+	if utf8.RuneCountInString(task.Prompt) > types.MaxAgentTaskPromptLength {
+		return fmt.Errorf("%w: prompt must not exceed %d characters", ErrAgentTaskInvalid, types.MaxAgentTaskPromptLength)
+	}
+	if task.WebhookID != "" {
+		if _, err := s.webhookService.GetWebhook(ctx, task.WebhookID); err != nil {
+			return fmt.Errorf("%w: webhook %s not found", ErrAgentTaskInvalid, task.WebhookID)
+		}
+	}
+
+	task.NextRunAt = nil
+	if task.Trigger != types.AgentTaskTriggerSchedule {
+		task.Schedule = ""
+		task.Timezone = ""
+		return nil
+	}
+	next, err := nextAgentTaskRun(task, time.Now())
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrAgentTaskInvalid, err)
+	}
+	if task.Enabled {
+		task.NextRunAt = next
+	}
+	return nil
+}
+
+// agentTaskCronParser parses standard 5-field cron specs and descriptors such as @daily
+var agentTaskCronParser = cron.NewParser(
+	cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor,
+)
+
+// nextAgentTaskRun returns the first run of a schedule task after now
+func nextAgentTaskRun(task *types.AgentTask, now time.Time) (*time.Time, error) {
+	if task.Schedule == "" {
+		return nil, errors.New("schedule is required")
+	}
+	schedule, err := agentTaskCronParser.Parse(task.Schedule)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule: %v", err)
+	}
+	loc, err := task.Location()
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone: %v", err)
+	}
+	next := schedule.Next(now.In(loc))
+	if next.IsZero() {
+		return nil, errors.New("schedule never runs")
+	}
+	// Stored in UTC, SQLite compares the times as text
+	next = next.UTC()
+	return &next, nil
+}
@@ -0,0 +1,325 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/event"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"github.com/hibiken/asynq"
+)
+
+// agentTaskRunner runs agent task runs headlessly: the agent answers the prompt of the run in a new
+// session, and the result is recorded on the run and reported to webhooks
+type agentTaskRunner struct {
+	repo               interfaces.AgentTaskRepository
+	tenantRepo         interfaces.TenantRepository
+	customAgentService interfaces.CustomAgentService
+	sessionService     interfaces.SessionService
+	messageService     interfaces.MessageService
+	webhookService     interfaces.WebhookService
+}
+
+// NewAgentTaskRunner creates the task handler of agent task runs
+func NewAgentTaskRunner(
+	repo interfaces.AgentTaskRepository,
+	tenantRepo interfaces.TenantRepository,
+	customAgentService interfaces.CustomAgentService,
+	sessionService interfaces.SessionService,
+	messageService interfaces.MessageService,
+	webhookService interfaces.WebhookService,
+) interfaces.TaskHandler {
+	return &agentTaskRunner{
+		repo:               repo,
+		tenantRepo:         tenantRepo,
+		customAgentService: customAgentService,
+		sessionService:     sessionService,
+		messageService:     messageService,
+		webhookService:     webhookService,
+	}
+}
+
+// agentTaskAnswer is the outcome of a headless agent run
+type agentTaskAnswer struct {
+	content    string
+	structured json.RawMessage
+	err        error
+}
+
+// Handle handles the asynq agent task run task. Runs are not retried: a failed run is recorded
+// and reported, and the next trigger starts a new one.
+func (r *agentTaskRunner) Handle(ctx context.Context, t *asynq.Task) error {
+	var payload types.AgentTaskRunPayload
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		logger.Errorf(ctx, "[AgentTask] Failed to unmarshal run payload: %v", err)
+		return nil
+	}
+	ctx = logger.WithField(ctx, "agent_task_run", payload.RunID)
+	ctx = context.WithValue(ctx, types.TenantIDContextKey, payload.TenantID)
+	// The QA pipelines expect a request ID, runs use their own ID
+	ctx = context.WithValue(ctx, types.RequestIDContextKey, payload.RunID)
+
+	run, err := r.repo.GetRun(ctx, payload.TenantID, payload.RunID)
+	if err != nil {
+		return err
+	}
+	if run == nil || run.Status != types.AgentTaskRunStatusPending {
+		return nil
+	}
+	task, err := r.repo.GetByID(ctx, payload.TenantID, run.TaskID)
+	if err != nil {
+		return err
+	}
+	if task == nil {
+		run.Status = types.AgentTaskRunStatusFailed
+		run.Error = "agent task was deleted"
+		run.UpdatedAt = time.Now()
+		return r.repo.UpdateRun(ctx, run)
+	}
+
+	tenant, err := r.tenantRepo.GetTenantByID(ctx, payload.TenantID)
+	if err != nil {
+		finishAgentTaskRun(ctx, r.repo, r.webhookService, task, run, fmt.Errorf("load tenant: %w", err))
+		return nil
+	}
+	ctx = context.WithValue(ctx, types.TenantInfoContextKey, tenant)
+
+	startedAt := time.Now()
+	run.Status = types.AgentTaskRunStatusRunning
+	run.StartedAt = &startedAt
+	run.UpdatedAt = startedAt
+	if err := r.repo.UpdateRun(ctx, run); err != nil {
+		logger.Warnf(ctx, "[AgentTask] Failed to save run %s: %v", run.ID, err)
+	}
+	if err := r.repo.UpdateLastRun(ctx, task.TenantID, task.ID, startedAt, run.Status); err != nil {
+		logger.Warnf(ctx, "[AgentTask] Failed to save last run of task %s: %v", task.ID, err)
+	}
+	logger.Infof(ctx, "[AgentTask] Running task %s with agent %s, trigger %s", task.ID, task.AgentID, run.Trigger)
+
+	agent, err := r.customAgentService.GetLiveAgent(ctx, task.AgentID)
+	if err != nil {
+		finishAgentTaskRun(ctx, r.repo, r.webhookService, task, run, fmt.Errorf("load agent %s: %w", task.AgentID, err))
+		return nil
+	}
+	answer := r.execute(ctx, task, agent, run)
+	run.Output = types.TruncateAgentTaskOutput(answer.content)
+	run.StructuredOutput = types.JSON(answer.structured)
+	finishAgentTaskRun(ctx, r.repo, r.webhookService, task, run, answer.err)
+	return nil
+}
+
+// execute answers the prompt of the run with the agent in a new session and waits for the answer.
+// The session and assistant message are set on the run.
+func (r *agentTaskRunner) execute(
+	ctx context.Context,
+	task *types.AgentTask,
+	agent *types.CustomAgent,
+	run *types.AgentTaskRun,
+) agentTaskAnswer {
+	session, err := r.sessionService.CreateSession(ctx, &types.Session{
+		TenantID:    task.TenantID,
+		Title:       task.Name,
+		Description: "agent_task",
+	})
+	if err != nil {
+		return agentTaskAnswer{err: fmt.Errorf("create session: %w", err)}
+	}
+	run.SessionID = session.ID
+	if _, err := r.messageService.CreateMessage(ctx, &types.Message{
+		SessionID:   session.ID,
+		Role:        "user",
+		Content:     run.Prompt,
+		CreatedAt:   time.Now(),
+		IsCompleted: true,
+	}); err != nil {
+		return agentTaskAnswer{err: fmt.Errorf("create user message: %w", err)}
+	}
+	assistantMessage, err := r.messageService.CreateMessage(ctx, &types.Message{
+		SessionID: session.ID,
+		Role:      "assistant",
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return agentTaskAnswer{err: fmt.Errorf("create assistant message: %w", err)}
+	}
+	run.MessageID = assistantMessage.ID
+
+	runCtx, cancel := context.WithTimeout(ctx, agentTaskRunTimeout)
+	defer cancel()
+
+	var (
+		mu         sync.Mutex
+		content    strings.Builder
+		refs       []*types.SearchResult
+		structured json.RawMessage
+		runErr     error
+		once       sync.Once
+	)
+	done := make(chan struct{})
+	finish := func() { once.Do(func() { close(done) }) }
+
+	agentMode := agent.RunsAgentQA()
+	eventBus := event.NewEventBus()
+	eventBus.On(event.EventAgentFinalAnswer, func(ctx context.Context, evt event.Event) error {
+		data, ok := evt.Data.(event.AgentFinalAnswerData)
+		if !ok {
+			return nil
+		}
+		mu.Lock()
+		content.WriteString(data.Content)
+		mu.Unlock()
+		if data.Done && !agentMode {
+			finish()
+		}
+		return nil
+	})
+	eventBus.On(event.EventAgentReferences, func(ctx context.Context, evt event.Event) error {
+		if data, ok := evt.Data.(event.AgentReferencesData); ok {
+			if results, ok := data.References.([]*types.SearchResult); ok {
+				mu.Lock()
+				refs = append(refs, results...)
+				mu.Unlock()
+			}
+		}
+		return nil
+	})
+	eventBus.On(event.EventStructuredOutput, func(ctx context.Context, evt event.Event) error {
+		if data, ok := evt.Data.(event.StructuredOutputData); ok {
+			mu.Lock()
+			if data.Error != "" {
+				runErr = fmt.Errorf("structured output: %s", data.Error)
+			} else {
+				structured = json.RawMessage(data.Raw)
+			}
+			mu.Unlock()
+		}
+		return nil
+	})
+	eventBus.On(event.EventAgentComplete, func(ctx context.Context, evt event.Event) error {
+		if data, ok := evt.Data.(event.AgentCompleteData); ok && data.FinalAnswer != "" {
+			mu.Lock()
+			content.Reset()
+			content.WriteString(data.FinalAnswer)
+			mu.Unlock()
+		}
+		finish()
+		return nil
+	})
+	eventBus.On(event.EventError, func(ctx context.Context, evt event.Event) error {
+		if data, ok := evt.Data.(event.ErrorData); ok {
+			mu.Lock()
+			runErr = errors.New(data.Error)
+			mu.Unlock()
+		}
+		finish()
+		return nil
+	})
+
+	// Knowledge-triggered runs are scoped to the knowledge that fired them
+	kbIDs := []string(task.KnowledgeBaseIDs)
+	var knowledgeIDs []string
+	if run.Trigger == types.AgentTaskTriggerKnowledgeParsed {
+		if data, err := run.EventData.Map(); err == nil {
+			if id, _ := data["knowledge_id"].(string); id != "" {
+				knowledgeIDs = []string{id}
+			}
+		}
+	}
+
+	go func() {
+		var err error
+		if agentMode {
+			err = r.sessionService.AgentQA(runCtx, session, run.Prompt, assistantMessage.ID, "",
+				eventBus, agent, kbIDs, knowledgeIDs, nil)
+		} else {
+			err = r.sessionService.KnowledgeQA(runCtx, session, run.Prompt, kbIDs, knowledgeIDs,
+				assistantMessage.ID, "", false, eventBus, agent, nil)
+		}
+		if err != nil {
+			eventBus.Emit(runCtx, event.Event{
+				Type:      event.EventError,
+				SessionID: session.ID,
+				Data:      event.ErrorData{Error: err.Error(), Stage: "agent_task", SessionID: session.ID},
+			})
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-runCtx.Done():
+	}
+
+	mu.Lock()
+	if runErr == nil {
+		runErr = runCtx.Err()
+	}
+	answer := agentTaskAnswer{content: content.String(), structured: structured, err: runErr}
+	assistantMessage.Content = answer.content
+	assistantMessage.KnowledgeReferences = refs
+	mu.Unlock()
+	assistantMessage.IsCompleted = true
+	assistantMessage.UpdatedAt = time.Now()
+	if err := r.messageService.UpdateMessage(context.WithoutCancel(ctx), assistantMessage); err != nil {
+		logger.Warnf(ctx, "[AgentTask] Failed to save assistant message: %v", err)
+	}
+	return answer
+}
+
+// finishAgentTaskRun records the outcome of a run on the run and its task, and publishes it to
+// the subscribed webhooks and to the destination webhook of the task
+func finishAgentTaskRun(
+	ctx context.Context,
+	repo interfaces.AgentTaskRepository,
+	webhookService interfaces.WebhookService,
+	task *types.AgentTask,
+	run *types.AgentTaskRun,
+	runErr error,
+) {
+	ctx = context.WithoutCancel(ctx)
+	now := time.Now()
+	run.FinishedAt = &now
+	run.UpdatedAt = now
+	if run.StartedAt == nil {
+		run.StartedAt = &now
+	}
+	webhookEvent := types.WebhookEventAgentTaskSucceeded
+	run.Status = types.AgentTaskRunStatusSucceeded
+	if runErr != nil {
+		webhookEvent = types.WebhookEventAgentTaskFailed
+		run.Status = types.AgentTaskRunStatusFailed
+		run.Error = runErr.Error()
+		logger.Warnf(ctx, "[AgentTask] Run %s of task %s failed: %v", run.ID, task.ID, runErr)
+	} else {
+		logger.Infof(ctx, "[AgentTask] Run %s of task %s succeeded in %s", run.ID, task.ID, now.Sub(*run.StartedAt))
+	}
+
+	if err := repo.UpdateRun(ctx, run); err != nil {
+		logger.Warnf(ctx, "[AgentTask] Failed to save run %s: %v", run.ID, err)
+	}
+	if err := repo.UpdateLastRun(ctx, task.TenantID, task.ID, *run.StartedAt, run.Status); err != nil {
+		logger.Warnf(ctx, "[AgentTask] Failed to save last run of task %s: %v", task.ID, err)
+	}
+
+	webhookService.PublishTo(ctx, task.TenantID, task.WebhookID, webhookEvent, map[string]interface{}{
+		"task_id":           task.ID,
+		"task_name":         task.Name,
+		"run_id":            run.ID,
+		"agent_id":          run.AgentID,
+		"trigger":           run.Trigger,
+		"status":            run.Status,
+		"session_id":        run.SessionID,
+		"message_id":        run.MessageID,
+		"output":            run.Output,
+		"structured_output": run.StructuredOutput,
+		"error":             run.Error,
+		"started_at":        run.StartedAt,
+		"finished_at":       run.FinishedAt,
+	})
+}
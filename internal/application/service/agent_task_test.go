@@ -0,0 +1,125 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+func TestNextAgentTaskRun(t *testing.T) {
+	utc := func(year int, month time.Month, day, hour, minute int) time.Time {
+		return time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name     string
+		schedule string
+		timezone string
+		now      time.Time
+		want     time.Time
+		err      string
+	}{
+		{
+			name:     "time zone of the task",
+			schedule: "0 9 * * *",
+			timezone: "Asia/Shanghai",
+			now:      utc(2026, 3, 1, 0, 0),
+			want:     utc(2026, 3, 1, 1, 0),
+		},
+		{
+			name:     "wall clock time kept across the spring DST transition",
+			schedule: "0 9 * * *",
+			timezone: "America/New_York",
+			now:      utc(2026, 3, 7, 15, 0),
+			want:     utc(2026, 3, 8, 13, 0),
+		},
+		{
+			name:     "time skipped by the spring DST transition runs the next day",
+			schedule: "30 2 * * *",
+			timezone: "America/New_York",
+			now:      utc(2026, 3, 7, 12, 0),
+			want:     utc(2026, 3, 9, 6, 30),
+		},
+		{
+			name:     "time repeated by the autumn DST transition runs once, at the first occurrence",
+			schedule: "30 1 * * *",
+			timezone: "America/New_York",
+			now:      utc(2026, 10, 31, 12, 0),
+			want:     utc(2026, 11, 1, 5, 30),
+		},
+		{
+			name:     "every descriptor",
+			schedule: "@every 90m",
+			timezone: "Asia/Tokyo",
+			now:      utc(2026, 3, 7, 15, 0),
+			want:     utc(2026, 3, 7, 16, 30),
+		},
+		{
+			name:     "daily descriptor in the time zone of the task",
+			schedule: "@daily",
+			timezone: "Asia/Tokyo",
+			now:      utc(2026, 3, 7, 15, 0),
+			want:     utc(2026, 3, 8, 15, 0),
+		},
+		{
+			name:     "weekly descriptor",
+			schedule: "@weekly",
+			timezone: "UTC",
+			now:      utc(2026, 3, 4, 10, 0),
+			want:     utc(2026, 3, 8, 0, 0),
+		},
+		{
+			name:     "invalid time zone",
+			schedule: "0 9 * * *",
+			timezone: "Mars/Olympus_Mons",
+			now:      utc(2026, 3, 1, 0, 0),
+			err:      "invalid timezone",
+		},
+		{
+			name:     "invalid schedule",
+			schedule: "61 * * * *",
+			timezone: "UTC",
+			now:      utc(2026, 3, 1, 0, 0),
+			err:      "invalid schedule",
+		},
+		{
+			name:     "seconds field is not accepted",
+			schedule: "0 0 9 * * *",
+			timezone: "UTC",
+			now:      utc(2026, 3, 1, 0, 0),
+			err:      "invalid schedule",
+		},
+		{
+			name:     "empty schedule",
+			timezone: "UTC",
+			now:      utc(2026, 3, 1, 0, 0),
+			err:      "schedule is required",
+		},
+		{
+			name:     "date that never comes",
+			schedule: "0 9 30 2 *",
+			timezone: "UTC",
+			now:      utc(2026, 3, 1, 0, 0),
+			err:      "schedule never runs",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := &types.AgentTask{Schedule: tt.schedule, Timezone: tt.timezone}
+			next, err := nextAgentTaskRun(task, tt.now)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("nextAgentTaskRun() = %v, %v, want error %q", next, err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("nextAgentTaskRun() = %v", err)
+			}
+			if !next.Equal(tt.want) || next.Location() != time.UTC {
+				t.Errorf("nextAgentTaskRun() = %v, want %v", next, tt.want)
+			}
+		})
+	}
+}
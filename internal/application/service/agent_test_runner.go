@@ -0,0 +1,264 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/agent"
+	"github.com/Tencent/WeKnora/internal/event"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/models/chat"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"github.com/hibiken/asynq"
+)
+
+// agentTestRunner runs agent test runs: each test case is answered by the agent in the session of
+// the run, and its assertions are checked on the run
+type agentTestRunner struct {
+	repo               interfaces.AgentTestRepository
+	tenantRepo         interfaces.TenantRepository
+	customAgentService interfaces.CustomAgentService
+	sessionService     interfaces.SessionService
+	messageService     interfaces.MessageService
+	modelService       interfaces.ModelService
+}
+
+// NewAgentTestRunner creates the task handler of agent test runs
+func NewAgentTestRunner(
+	repo interfaces.AgentTestRepository,
+	tenantRepo interfaces.TenantRepository,
+	customAgentService interfaces.CustomAgentService,
+	sessionService interfaces.SessionService,
+	messageService interfaces.MessageService,
+	modelService interfaces.ModelService,
+) interfaces.TaskHandler {
+	return &agentTestRunner{
+		repo:               repo,
+		tenantRepo:         tenantRepo,
+		customAgentService: customAgentService,
+		sessionService:     sessionService,
+		messageService:     messageService,
+		modelService:       modelService,
+	}
+}
+
+// Handle handles the asynq agent test run task. Test runs are not retried, a failed run is recorded
+// with the results of the test cases that ran.
+func (r *agentTestRunner) Handle(ctx context.Context, t *asynq.Task) error {
+	var payload types.AgentTestRunPayload
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		logger.Errorf(ctx, "[AgentTest] Failed to unmarshal run payload: %v", err)
+		return nil
+	}
+	ctx = logger.WithField(ctx, "agent_test_run", payload.RunID)
+	ctx = context.WithValue(ctx, types.TenantIDContextKey, payload.TenantID)
+	ctx = context.WithValue(ctx, types.RequestIDContextKey, payload.RunID)
+
+	run, err := r.repo.GetRun(ctx, payload.TenantID, payload.RunID)
+	if err != nil {
+		return err
+	}
+	if run == nil || run.Status != types.AgentTestRunStatusPending {
+		return nil
+	}
+
+	tenant, err := r.tenantRepo.GetTenantByID(ctx, payload.TenantID)
+	if err != nil {
+		finishAgentTestRun(ctx, r.repo, run, fmt.Errorf("load tenant: %w", err))
+		return nil
+	}
+	ctx = context.WithValue(ctx, types.TenantInfoContextKey, tenant)
+
+	customAgent, err := r.customAgentService.GetAgentByID(ctx, run.AgentID)
+	if err != nil {
+		finishAgentTestRun(ctx, r.repo, run, fmt.Errorf("load agent %s: %w", run.AgentID, err))
+		return nil
+	}
+	// The run tests the agent as it is when the run starts
+	run.AgentConfig, run.ConfigHash = snapshotAgentConfig(customAgent)
+
+	startedAt := time.Now()
+	run.Status = types.AgentTestRunStatusRunning
+	run.StartedAt = &startedAt
+	run.UpdatedAt = startedAt
+	if err := r.repo.UpdateRun(ctx, run); err != nil {
+		logger.Warnf(ctx, "[AgentTest] Failed to save run %s: %v", run.ID, err)
+	}
+
+	testCases, err := r.repo.ListCases(ctx, run.TenantID, run.AgentID)
+	if err != nil {
+		finishAgentTestRun(ctx, r.repo, run, fmt.Errorf("load test cases: %w", err))
+		return nil
+	}
+	judge, err := r.judgeModel(ctx, run, customAgent, testCases)
+	if err != nil {
+		finishAgentTestRun(ctx, r.repo, run, fmt.Errorf("load judge model: %w", err))
+		return nil
+	}
+
+	session, err := r.sessionService.CreateSession(ctx, &types.Session{
+		TenantID:    run.TenantID,
+		Title:       customAgent.Name,
+		Description: "agent_test",
+	})
+	if err != nil {
+		finishAgentTestRun(ctx, r.repo, run, fmt.Errorf("create session: %w", err))
+		return nil
+	}
+	run.SessionID = session.ID
+	logger.Infof(ctx, "[AgentTest] Running %d test cases of agent %s", len(run.CaseIDs), run.AgentID)
+
+	for _, caseID := range run.CaseIDs {
+		var result types.AgentTestResult
+		index := slices.IndexFunc(testCases, func(c *types.AgentTestCase) bool { return c.ID == caseID })
+		if index < 0 {
+			result = types.AgentTestResult{CaseID: caseID, Error: "test case was deleted"}
+		} else {
+			result = r.runCase(ctx, session, customAgent, judge, testCases[index])
+		}
+		run.Results = append(run.Results, result)
+		if result.Passed {
+			run.Passed++
+		} else {
+			run.Failed++
+		}
+		run.UpdatedAt = time.Now()
+		if err := r.repo.UpdateRun(ctx, run); err != nil {
+			logger.Warnf(ctx, "[AgentTest] Failed to save progress of run %s: %v", run.ID, err)
+		}
+		if ctx.Err() != nil {
+			finishAgentTestRun(ctx, r.repo, run, ctx.Err())
+			return nil
+		}
+	}
+	finishAgentTestRun(ctx, r.repo, run, nil)
+	return nil
+}
+
+// judgeModel returns the model judging the llm_judge assertions of the test cases,
+// nil when none of them has one
+func (r *agentTestRunner) judgeModel(
+	ctx context.Context,
+	run *types.AgentTestRun,
+	customAgent *types.CustomAgent,
+	testCases []*types.AgentTestCase,
+) (chat.Chat, error) {
+	needsJudge := false
+	for _, testCase := range testCases {
+		for _, assertion := range testCase.Assertions {
+			if assertion.Type == types.AgentTestAssertLLMJudge && slices.Contains(run.CaseIDs, testCase.ID) {
+				needsJudge = true
+			}
+		}
+	}
+	if !needsJudge {
+		return nil, nil
+	}
+	modelID := run.JudgeModelID
+	if modelID == "" {
+		modelID = customAgent.Config.ModelID
+	}
+	return r.modelService.GetChatModel(ctx, modelID)
+}
+
+// runCase answers the query of a test case with the agent in the session and checks its assertions.
+// The conversation is saved as a user and an assistant message, whose trace shows the full run.
+func (r *agentTestRunner) runCase(
+	ctx context.Context,
+	session *types.Session,
+	customAgent *types.CustomAgent,
+	judge chat.Chat,
+	testCase *types.AgentTestCase,
+) (result types.AgentTestResult) {
+	startedAt := time.Now()
+	result = types.AgentTestResult{
+		CaseID:       testCase.ID,
+		CaseName:     testCase.Name,
+		Query:        testCase.Query,
+		ToolCalls:    []string{},
+		KnowledgeIDs: []string{},
+		Assertions:   []types.AgentTestAssertionResult{},
+	}
+	defer func() { result.DurationMs = time.Since(startedAt).Milliseconds() }()
+
+	if _, err := r.messageService.CreateMessage(ctx, &types.Message{
+		SessionID:   session.ID,
+		Role:        "user",
+		Content:     testCase.Query,
+		CreatedAt:   time.Now(),
+		IsCompleted: true,
+	}); err != nil {
+		result.Error = fmt.Sprintf("create user message: %v", err)
+		return result
+	}
+	assistantMessage, err := r.messageService.CreateMessage(ctx, &types.Message{
+		SessionID: session.ID,
+		Role:      "assistant",
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		result.Error = fmt.Sprintf("create assistant message: %v", err)
+		return result
+	}
+	result.MessageID = assistantMessage.ID
+
+	caseCtx, cancel := context.WithTimeout(ctx, agentTestCaseTimeout)
+	defer cancel()
+	state, err := r.sessionService.RunAgent(caseCtx, session, testCase.Query, assistantMessage.ID,
+		event.NewEventBus(), customAgent, testCase.KnowledgeBaseIDs, testCase.KnowledgeIDs)
+	if err == nil && state == nil {
+		err = errors.New("the agent returned no result")
+	}
+	if err != nil {
+		result.Error = err.Error()
+		assistantMessage.Content = result.Error
+	} else {
+		outcome := agent.NewTestOutcome(state)
+		result.Answer = outcome.Answer
+		result.ToolCalls = outcome.ToolCalls
+		result.KnowledgeIDs = outcome.KnowledgeIDs
+		result.Rounds = outcome.Rounds
+		result.Assertions = agent.EvaluateTestCase(ctx, judge, testCase, outcome)
+		result.Passed = !slices.ContainsFunc(result.Assertions, func(a types.AgentTestAssertionResult) bool {
+			return !a.Passed
+		})
+		assistantMessage.Content = state.FinalAnswer
+		assistantMessage.KnowledgeReferences = state.KnowledgeRefs
+		assistantMessage.AgentSteps = state.RoundSteps
+	}
+
+	assistantMessage.IsCompleted = true
+	assistantMessage.UpdatedAt = time.Now()
+	if err := r.messageService.UpdateMessage(context.WithoutCancel(ctx), assistantMessage); err != nil {
+		logger.Warnf(ctx, "[AgentTest] Failed to save assistant message: %v", err)
+	}
+	logger.Infof(ctx, "[AgentTest] Test case %s passed: %v", testCase.ID, result.Passed)
+	return result
+}
+
+// finishAgentTestRun records the outcome of a test run
+func finishAgentTestRun(ctx context.Context, repo interfaces.AgentTestRepository, run *types.AgentTestRun, runErr error) {
+	ctx = context.WithoutCancel(ctx)
+	now := time.Now()
+	run.FinishedAt = &now
+	run.UpdatedAt = now
+	if run.StartedAt == nil {
+		run.StartedAt = &now
+	}
+	run.Status = types.AgentTestRunStatusCompleted
+	if runErr != nil {
+		run.Status = types.AgentTestRunStatusFailed
+		run.Error = runErr.Error()
+		logger.Warnf(ctx, "[AgentTest] Test run %s failed: %v", run.ID, runErr)
+	} else {
+		logger.Infof(ctx, "[AgentTest] Test run %s completed, %d passed, %d failed", run.ID, run.Passed, run.Failed)
+	}
+	if err := repo.UpdateRun(ctx, run); err != nil {
+		logger.Warnf(ctx, "[AgentTest] Failed to save run %s: %v", run.ID, err)
+	}
+}
@@ -0,0 +1,293 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/agent"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+)
+
+// agentTestCaseTimeout bounds the run of a single test case
+const agentTestCaseTimeout = 5 * time.Minute
+
+var (
+	ErrAgentTestCaseNotFound = errors.New("agent test case not found")
+	ErrAgentTestRunNotFound  = errors.New("agent test run not found")
+	ErrAgentTestInvalid      = errors.New("invalid agent test")
+)
+
+// agentTestService implements the AgentTestService interface.
+// It only records and enqueues test runs, which are executed by agentTestRunner.
+type agentTestService struct {
+	repo               interfaces.AgentTestRepository
+	customAgentService interfaces.CustomAgentService
+	modelService       interfaces.ModelService
+	asynqClient        *asynq.Client
+}
+
+// NewAgentTestService creates a new agent test service
+func NewAgentTestService(
+	repo interfaces.AgentTestRepository,
+	customAgentService interfaces.CustomAgentService,
+	modelService interfaces.ModelService,
+	asynqClient *asynq.Client,
+) interfaces.AgentTestService {
+	return &agentTestService{
+		repo:               repo,
+		customAgentService: customAgentService,
+		modelService:       modelService,
+		asynqClient:        asynqClient,
+	}
+}
+
+// CreateCase creates a test case of an agent of the current tenant
+func (s *agentTestService) CreateCase(
+	ctx context.Context,
+	testCase *types.AgentTestCase,
+) (*types.AgentTestCase, error) {
+	if _, err := s.customAgentService.GetAgentByID(ctx, testCase.AgentID); err != nil {
+		return nil, err
+	}
+	if err := validateAgentTestCase(testCase); err != nil {
+		return nil, err
+	}
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	count, err := s.repo.CountCases(ctx, tenantID, testCase.AgentID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= types.MaxAgentTestCases {
+		return nil, fmt.Errorf("%w: an agent can have at most %d test cases", ErrAgentTestInvalid, types.MaxAgentTestCases)
+	}
+
+	now := time.Now()
+	testCase.ID = uuid.New().String()
+	testCase.TenantID = tenantID
+	testCase.CreatedAt = now
+	testCase.UpdatedAt = now
+	if err := s.repo.CreateCase(ctx, testCase); err != nil {
+		return nil, err
+	}
+	logger.Infof(ctx, "[AgentTest] Created test case %s of agent %s", testCase.ID, testCase.AgentID)
+	return testCase, nil
+}
+
+// ListCases lists the test cases of an agent of the current tenant
+func (s *agentTestService) ListCases(ctx context.Context, agentID string) ([]*types.AgentTestCase, error) {
+	if _, err := s.customAgentService.GetAgentByID(ctx, agentID); err != nil {
+		return nil, err
+	}
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	return s.repo.ListCases(ctx, tenantID, agentID)
+}
+
+// UpdateCase updates a test case of an agent of the current tenant
+func (s *agentTestService) UpdateCase(
+	ctx context.Context,
+	testCase *types.AgentTestCase,
+) (*types.AgentTestCase, error) {
+	existing, err := s.getCase(ctx, testCase.AgentID, testCase.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateAgentTestCase(testCase); err != nil {
+		return nil, err
+	}
+	testCase.TenantID = existing.TenantID
+	testCase.CreatedAt = existing.CreatedAt
+	testCase.UpdatedAt = time.Now()
+	if err := s.repo.UpdateCase(ctx, testCase); err != nil {
+		return nil, err
+	}
+	return testCase, nil
+}
+
+// DeleteCase deletes a test case of an agent of the current tenant, its results in past runs are kept
+func (s *agentTestService) DeleteCase(ctx context.Context, agentID string, id string) error {
+	testCase, err := s.getCase(ctx, agentID, id)
+	if err != nil {
+		return err
+	}
+	return s.repo.DeleteCase(ctx, testCase.TenantID, testCase.ID)
+}
+
+// StartRun records a pending test run of the requested test cases of an agent and enqueues it
+func (s *agentTestService) StartRun(
+	ctx context.Context,
+	agentID string,
+	req *types.AgentTestRunRequest,
+) (*types.AgentTestRun, error) {
+	customAgent, err := s.customAgentService.GetAgentByID(ctx, agentID)
+	if err != nil {
+		return nil, err
+	}
+	if !customAgent.IsAgentMode() {
+		return nil, fmt.Errorf("%w: only smart-reasoning agents can be tested", ErrAgentTestInvalid)
+	}
+	if req.JudgeModelID != "" {
+		if _, err := s.modelService.GetModelByID(ctx, req.JudgeModelID); err != nil {
+			return nil, fmt.Errorf("%w: judge model %s not found", ErrAgentTestInvalid, req.JudgeModelID)
+		}
+	}
+
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	testCases, err := s.repo.ListCases(ctx, tenantID, agentID)
+	if err != nil {
+		return nil, err
+	}
+	caseIDs := make(types.StringArray, 0, len(testCases))
+	for _, testCase := range testCases {
+		caseIDs = append(caseIDs, testCase.ID)
+	}
+	if len(req.CaseIDs) > 0 {
+		for _, id := range req.CaseIDs {
+			if !slices.Contains(caseIDs, id) {
+				return nil, fmt.Errorf("%w: test case %s not found", ErrAgentTestInvalid, id)
+			}
+		}
+		caseIDs = req.CaseIDs
+	}
+	if len(caseIDs) == 0 {
+		return nil, fmt.Errorf("%w: the agent has no test cases", ErrAgentTestInvalid)
+	}
+
+	now := time.Now()
+	run := &types.AgentTestRun{
+		ID:           uuid.New().String(),
+		TenantID:     tenantID,
+		AgentID:      agentID,
+		Label:        strings.TrimSpace(req.Label),
+		Status:       types.AgentTestRunStatusPending,
+		JudgeModelID: req.JudgeModelID,
+		CaseIDs:      caseIDs,
+		Total:        len(caseIDs),
+		Results:      types.AgentTestResults{},
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	run.AgentConfig, run.ConfigHash = snapshotAgentConfig(customAgent)
+	if err := s.repo.CreateRun(ctx, run); err != nil {
+		return nil, err
+	}
+
+	payload, err := json.Marshal(types.AgentTestRunPayload{TenantID: run.TenantID, RunID: run.ID})
+	if err == nil {
+		_, err = s.asynqClient.Enqueue(asynq.NewTask(types.TypeAgentTestRun, payload,
+			asynq.Queue("default"), asynq.MaxRetry(0),
+			asynq.Timeout(time.Duration(run.Total)*agentTestCaseTimeout+time.Minute)))
+	}
+	if err != nil {
+		finishAgentTestRun(ctx, s.repo, run, fmt.Errorf("enqueue run: %w", err))
+		return nil, err
+	}
+	logger.Infof(ctx, "[AgentTest] Enqueued test run %s of agent %s with %d test cases", run.ID, agentID, run.Total)
+	return run, nil
+}
+
+// ListRuns lists the test runs of an agent of the current tenant, without their results
+func (s *agentTestService) ListRuns(ctx context.Context,
+	agentID string, page *types.Pagination,
+) (*types.PageResult, error) {
+	if _, err := s.customAgentService.GetAgentByID(ctx, agentID); err != nil {
+		return nil, err
+	}
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	runs, total, err := s.repo.ListRuns(ctx, tenantID, agentID, page)
+	if err != nil {
+		return nil, err
+	}
+	return types.NewPageResult(total, page, runs), nil
+}
+
+// GetRun returns a test run of an agent of the current tenant
+func (s *agentTestService) GetRun(ctx context.Context, agentID string, runID string) (*types.AgentTestRun, error) {
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	run, err := s.repo.GetRun(ctx, tenantID, runID)
+	if err != nil {
+		return nil, err
+	}
+	if run == nil || run.AgentID != agentID {
+		return nil, ErrAgentTestRunNotFound
+	}
+	return run, nil
+}
+
+// CompareRuns compares the results of the target test run of an agent against the base one
+func (s *agentTestService) CompareRuns(
+	ctx context.Context,
+	agentID string,
+	baseRunID string,
+	targetRunID string,
+) (*types.AgentTestRunComparison, error) {
+	base, err := s.GetRun(ctx, agentID, baseRunID)
+	if err != nil {
+		return nil, err
+	}
+	target, err := s.GetRun(ctx, agentID, targetRunID)
+	if err != nil {
+		return nil, err
+	}
+	return types.CompareAgentTestRuns(base, target), nil
+}
+
+// getCase returns a test case of an agent of the current tenant
+func (s *agentTestService) getCase(ctx context.Context, agentID string, id string) (*types.AgentTestCase, error) {
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	testCase, err := s.repo.GetCase(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if testCase == nil || testCase.AgentID != agentID {
+		return nil, ErrAgentTestCaseNotFound
+	}
+	return testCase, nil
+}
+
+// validateAgentTestCase checks the query, assertions and round limit of a test case
+func validateAgentTestCase(testCase *types.AgentTestCase) error {
+	testCase.Name = strings.TrimSpace(testCase.Name)
+	if testCase.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrAgentTestInvalid)
+	}
+	if strings.TrimSpace(testCase.Query) == "" {
+		return fmt.Errorf("%w: query is required", ErrAgentTestInvalid)
+	}
+	if len(testCase.Assertions) > types.MaxAgentTestAssertions {
+		return fmt.Errorf("%w: a test case can have at most %d assertions", ErrAgentTestInvalid, types.MaxAgentTestAssertions)
+	}
+	for _, assertion := range testCase.Assertions {
+		if err := agent.ValidateTestAssertion(assertion); err != nil {
+			return fmt.Errorf("%w: %v", ErrAgentTestInvalid, err)
+		}
+	}
+	if testCase.MaxRounds < 0 {
+		return fmt.Errorf("%w: max_rounds must not be negative", ErrAgentTestInvalid)
+	}
+	if testCase.Assertions == nil {
+		testCase.Assertions = types.AgentTestAssertions{}
+	}
+	return nil
+}
+
+// snapshotAgentConfig returns the encoded config of an agent and its hash, which identifies the
+// version of the agent a test run ran against
+func snapshotAgentConfig(customAgent *types.CustomAgent) (types.JSON, string) {
+	config, err := json.Marshal(customAgent.Config)
+	if err != nil {
+		return nil, ""
+	}
+	sum := sha256.Sum256(config)
+	return types.JSON(config), hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/agent"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+)
+
+var (
+	ErrAgentTraceNotFound      = errors.New("agent trace not found")
+	ErrAgentTraceNotReplayable = agent.ErrTraceNotReplayable
+)
+
+// agentTraceService implements the AgentTraceService interface
+type agentTraceService struct {
+	repo         interfaces.AgentTraceRepository
+	modelService interfaces.ModelService
+}
+
+// NewAgentTraceService creates a new agent trace service
+func NewAgentTraceService(
+	repo interfaces.AgentTraceRepository,
+	modelService interfaces.ModelService,
+) interfaces.AgentTraceService {
+	return &agentTraceService{
+		repo:         repo,
+		modelService: modelService,
+	}
+}
+
+// GetTrace returns the latest top-level trace of an assistant message of the current tenant,
+// with the traces of the sub-agent runs recorded for the message
+func (s *agentTraceService) GetTrace(ctx context.Context, sessionID, messageID string) (*types.AgentTrace, error) {
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	traces, err := s.repo.ListByMessage(ctx, tenantID, sessionID, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	var trace *types.AgentTrace
+	subTraces := make([]*types.AgentTrace, 0)
+	for _, t := range traces {
+		if t.ParentToolCallID == "" {
+			trace = t
+		} else {
+			subTraces = append(subTraces, t)
+		}
+	}
+	if trace == nil {
+		return nil, ErrAgentTraceNotFound
+	}
+	trace.SubTraces = subTraces
+	return trace, nil
+}
+
+// ReplayTrace re-executes the agent run of an assistant message against the requested model,
+// the model of the run by default
+func (s *agentTraceService) ReplayTrace(
+	ctx context.Context,
+	sessionID, messageID string,
+	req *types.AgentTraceReplayRequest,
+) (*types.AgentTrace, error) {
+	trace, err := s.GetTrace(ctx, sessionID, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	modelID := req.ModelID
+	if modelID == "" {
+		modelID = trace.ModelID
+	}
+	chatModel, err := s.modelService.GetChatModel(ctx, modelID)
+	if err != nil {
+		return nil, fmt.Errorf("load chat model %s: %w", modelID, err)
+	}
+
+	logger.Infof(ctx, "[AgentTrace] Replaying trace %s of message %s with model %s", trace.ID, messageID, modelID)
+	replayed, err := agent.ReplayTrace(ctx, trace, chatModel, req)
+	if err != nil {
+		return nil, err
+	}
+	logger.Infof(ctx, "[AgentTrace] Replayed trace %s in %d rounds, %dms", trace.ID, len(replayed.Rounds), replayed.DurationMs)
+	return replayed, nil
+}
+
+// ProcessAgentTraceCleanup deletes the agent traces older than the retention period
+func (s *agentTraceService) ProcessAgentTraceCleanup(ctx context.Context, t *asynq.Task) error {
+	days := types.AgentTraceRetentionDays()
+	if days == 0 {
+		return nil
+	}
+	before := time.Now().AddDate(0, 0, -days)
+	deleted, err := s.repo.DeleteBefore(ctx, before)
+	if err != nil {
+		logger.Errorf(ctx, "[AgentTrace] Failed to delete traces before %s: %v", before.Format(time.RFC3339), err)
+		return err
+	}
+	logger.Infof(ctx, "[AgentTrace] Deleted %d traces older than %d days", deleted, days)
+	return nil
+}
+
+// saveAgentTrace stores the trace of an agent run. Sub-agent runs are recorded with the tool call
+// that delegated to them.
+func saveAgentTrace(ctx context.Context, repo interfaces.AgentTraceRepository, trace *types.AgentTrace) {
+	ctx = context.WithoutCancel(ctx)
+	tenantID, ok := ctx.Value(types.TenantIDContextKey).(uint64)
+	if !ok || trace.MessageID == "" {
+		return
+	}
+	trace.ID = uuid.New().String()
+	trace.TenantID = tenantID
+	trace.ParentToolCallID, _ = ctx.Value(types.ToolCallIDContextKey).(string)
+	trace.CreatedAt = time.Now()
+	if err := repo.Create(ctx, trace); err != nil {
+		logger.Warnf(ctx, "[AgentTrace] Failed to save trace of message %s: %v", trace.MessageID, err)
+	}
+}
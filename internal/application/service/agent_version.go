@@ -0,0 +1,227 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrAgentVersionNotFound = errors.New("agent version not found")
+	ErrAgentVersionInvalid  = errors.New("invalid agent version operation")
+)
+
+// agentVersionService implements the AgentVersionService interface
+type agentVersionService struct {
+	repo               interfaces.AgentVersionRepository
+	customAgentService interfaces.CustomAgentService
+}
+
+// NewAgentVersionService creates a new agent version service
+func NewAgentVersionService(
+	repo interfaces.AgentVersionRepository,
+	customAgentService interfaces.CustomAgentService,
+) interfaces.AgentVersionService {
+	return &agentVersionService{
+		repo:               repo,
+		customAgentService: customAgentService,
+	}
+}
+
+// Publish publishes the draft of an agent of the current tenant as a new version
+func (s *agentVersionService) Publish(
+	ctx context.Context,
+	agentID string,
+	req *types.PublishAgentRequest,
+) (*types.AgentVersion, error) {
+	agent, err := s.getVersionedAgent(ctx, agentID)
+	if err != nil {
+		return nil, err
+	}
+
+	draft := draftAgentVersion(agent)
+	if agent.PublishedVersion > 0 {
+		published, err := s.repo.GetVersion(ctx, agent.TenantID, agent.ID, agent.PublishedVersion)
+		if err != nil {
+			return nil, err
+		}
+		if published != nil && len(types.DiffAgentVersions(published, draft)) == 0 {
+			return nil, fmt.Errorf("%w: the draft has no changes since version %d",
+				ErrAgentVersionInvalid, agent.PublishedVersion)
+		}
+	}
+	latest, err := s.repo.LatestVersion(ctx, agent.TenantID, agent.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, _ := ctx.Value(types.UserIDContextKey).(string)
+	version := draft
+	version.ID = uuid.New().String()
+	version.Version = latest + 1
+	version.Note = strings.TrimSpace(req.Note)
+	version.PublishedBy = userID
+	version.CreatedAt = time.Now()
+	if err := s.repo.Publish(ctx, version); err != nil {
+		return nil, err
+	}
+	logger.Infof(ctx, "[AgentVersion] Published version %d of agent %s", version.Version, agent.ID)
+	return version, nil
+}
+
+// ListVersions lists the versions of an agent of the current tenant, newest first
+func (s *agentVersionService) ListVersions(ctx context.Context, agentID string) ([]*types.AgentVersion, error) {
+	agent, err := s.getVersionedAgent(ctx, agentID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.ListVersions(ctx, agent.TenantID, agent.ID)
+}
+
+// GetVersion returns a version of an agent of the current tenant, version 0 is the draft
+func (s *agentVersionService) GetVersion(ctx context.Context, agentID string, version int) (*types.AgentVersion, error) {
+	agent, err := s.getVersionedAgent(ctx, agentID)
+	if err != nil {
+		return nil, err
+	}
+	return s.getVersion(ctx, agent, version)
+}
+
+// DiffVersions compares two versions of an agent of the current tenant, version 0 is the draft
+func (s *agentVersionService) DiffVersions(
+	ctx context.Context,
+	agentID string,
+	from int,
+	to int,
+) (*types.AgentVersionDiff, error) {
+	agent, err := s.getVersionedAgent(ctx, agentID)
+	if err != nil {
+		return nil, err
+	}
+	fromVersion, err := s.getVersion(ctx, agent, from)
+	if err != nil {
+		return nil, err
+	}
+	toVersion, err := s.getVersion(ctx, agent, to)
+	if err != nil {
+		return nil, err
+	}
+	return &types.AgentVersionDiff{
+		AgentID:     agent.ID,
+		FromVersion: from,
+		ToVersion:   to,
+		Changes:     types.DiffAgentVersions(fromVersion, toVersion),
+	}, nil
+}
+
+// Rollback publishes an earlier version of an agent of the current tenant again as a new version.
+// Earlier versions and the draft are left as they are.
+func (s *agentVersionService) Rollback(ctx context.Context, agentID string, version int) (*types.CustomAgent, error) {
+	agent, err := s.getVersionedAgent(ctx, agentID)
+	if err != nil {
+		return nil, err
+	}
+	if version <= 0 {
+		return nil, fmt.Errorf("%w: only published versions can be rolled back to", ErrAgentVersionInvalid)
+	}
+	if version == agent.PublishedVersion {
+		return nil, fmt.Errorf("%w: version %d is already published", ErrAgentVersionInvalid, version)
+	}
+	source, err := s.getVersion(ctx, agent, version)
+	if err != nil {
+		return nil, err
+	}
+	latest, err := s.repo.LatestVersion(ctx, agent.TenantID, agent.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, _ := ctx.Value(types.UserIDContextKey).(string)
+	rollback := *source
+	rollback.ID = uuid.New().String()
+	rollback.Version = latest + 1
+	rollback.Note = fmt.Sprintf("Rollback to version %d", version)
+	rollback.PublishedBy = userID
+	rollback.CreatedAt = time.Now()
+	if err := s.repo.Publish(ctx, &rollback); err != nil {
+		return nil, err
+	}
+	logger.Infof(ctx, "[AgentVersion] Rolled agent %s back from version %d to version %d as version %d",
+		agent.ID, agent.PublishedVersion, version, rollback.Version)
+	agent.PublishedVersion = rollback.Version
+	return agent, nil
+}
+
+// getVersionedAgent returns an agent of the current tenant that can be versioned
+func (s *agentVersionService) getVersionedAgent(ctx context.Context, agentID string) (*types.CustomAgent, error) {
+	agent, err := s.customAgentService.GetAgentByID(ctx, agentID)
+	if err != nil {
+		return nil, err
+	}
+	if agent.IsBuiltin {
+		return nil, fmt.Errorf("%w: built-in agents are not versioned", ErrAgentVersionInvalid)
+	}
+	return agent, nil
+}
+
+// getVersion returns a version of an agent, version 0 is the draft
+func (s *agentVersionService) getVersion(
+	ctx context.Context,
+	agent *types.CustomAgent,
+	version int,
+) (*types.AgentVersion, error) {
+	if version == 0 {
+		return draftAgentVersion(agent), nil
+	}
+	agentVersion, err := s.repo.GetVersion(ctx, agent.TenantID, agent.ID, version)
+	if err != nil {
+		return nil, err
+	}
+	if agentVersion == nil {
+		return nil, fmt.Errorf("%w: version %d", ErrAgentVersionNotFound, version)
+	}
+	return agentVersion, nil
+}
+
+// draftAgentVersion returns the draft of an agent as an unsaved version 0
+func draftAgentVersion(agent *types.CustomAgent) *types.AgentVersion {
+	_, configHash := snapshotAgentConfig(agent)
+	return &types.AgentVersion{
+		TenantID:    agent.TenantID,
+		AgentID:     agent.ID,
+		Name:        agent.Name,
+		Description: agent.Description,
+		Avatar:      agent.Avatar,
+		Config:      agent.Config,
+		ConfigHash:  configHash,
+		CreatedAt:   agent.UpdatedAt,
+	}
+}
+
+// applyAgentVersion returns the agent answering with the given published version,
+// the agent itself (its draft) when version is 0
+func applyAgentVersion(
+	ctx context.Context,
+	repo interfaces.AgentVersionRepository,
+	agent *types.CustomAgent,
+	version int,
+) (*types.CustomAgent, error) {
+	if version <= 0 {
+		return agent, nil
+	}
+	agentVersion, err := repo.GetVersion(ctx, agent.TenantID, agent.ID, version)
+	if err != nil {
+		return nil, err
+	}
+	if agentVersion == nil {
+		return nil, fmt.Errorf("%w: version %d of agent %s", ErrAgentVersionNotFound, version, agent.ID)
+	}
+	return agentVersion.Apply(agent), nil
+}
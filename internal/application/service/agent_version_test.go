@@ -0,0 +1,347 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+)
+
+// agentVersionTestStore holds the agent and the versions shared by the fakes below
+type agentVersionTestStore struct {
+	agent    *types.CustomAgent
+	versions []*types.AgentVersion
+}
+
+// fakeAgentVersionRepository keeps versions in memory
+type fakeAgentVersionRepository struct {
+	interfaces.AgentVersionRepository
+	store *agentVersionTestStore
+}
+
+func (f *fakeAgentVersionRepository) Publish(ctx context.Context, version *types.AgentVersion) error {
+	for _, v := range f.store.versions {
+		if v.AgentID == version.AgentID && v.Version == version.Version {
+			return fmt.Errorf("duplicate version %d", version.Version)
+		}
+	}
+	saved := *version
+	f.store.versions = append(f.store.versions, &saved)
+	f.store.agent.PublishedVersion = version.Version
+	return nil
+}
+
+func (f *fakeAgentVersionRepository) GetVersion(
+	ctx context.Context,
+	tenantID uint64,
+	agentID string,
+	version int,
+) (*types.AgentVersion, error) {
+	for _, v := range f.store.versions {
+		if v.TenantID == tenantID && v.AgentID == agentID && v.Version == version {
+			found := *v
+			return &found, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeAgentVersionRepository) ListVersions(
+	ctx context.Context,
+	tenantID uint64,
+	agentID string,
+) ([]*types.AgentVersion, error) {
+	var versions []*types.AgentVersion
+	for i := len(f.store.versions) - 1; i >= 0; i-- {
+		versions = append(versions, f.store.versions[i])
+	}
+	return versions, nil
+}
+
+func (f *fakeAgentVersionRepository) LatestVersion(ctx context.Context, tenantID uint64, agentID string) (int, error) {
+	latest := 0
+	for _, v := range f.store.versions {
+		if v.AgentID == agentID && v.Version > latest {
+			latest = v.Version
+		}
+	}
+	return latest, nil
+}
+
+// fakeVersionedAgentService returns a copy of the stored agent, like the database does
+type fakeVersionedAgentService struct {
+	interfaces.CustomAgentService
+	store *agentVersionTestStore
+}
+
+func (f *fakeVersionedAgentService) GetAgentByID(ctx context.Context, id string) (*types.CustomAgent, error) {
+	if id != f.store.agent.ID {
+		return nil, ErrAgentNotFound
+	}
+	agent := *f.store.agent
+	return &agent, nil
+}
+
+// fakeVersionedAgentRepository returns a copy of the stored agent of its source tenant
+type fakeVersionedAgentRepository struct {
+	interfaces.CustomAgentRepository
+	store *agentVersionTestStore
+}
+
+func (f *fakeVersionedAgentRepository) GetAgentByID(
+	ctx context.Context,
+	id string,
+	tenantID uint64,
+) (*types.CustomAgent, error) {
+	agent := *f.store.agent
+	return &agent, nil
+}
+
+// fakePinnedShareRepository returns a share of the agent pinned to a version
+type fakePinnedShareRepository struct {
+	interfaces.AgentShareRepository
+	pinnedVersion int
+}
+
+func (f *fakePinnedShareRepository) GetShareByAgentIDForUser(
+	ctx context.Context,
+	userID, agentID string,
+	excludeTenantID uint64,
+) (*types.AgentShare, error) {
+	return &types.AgentShare{AgentID: agentID, SourceTenantID: 1, PinnedVersion: f.pinnedVersion}, nil
+}
+
+// newAgentVersionTest returns a version service of an agent whose draft has the given system prompt
+func newAgentVersionTest(prompt string) (*agentVersionService, *agentVersionTestStore, context.Context) {
+	store := &agentVersionTestStore{agent: &types.CustomAgent{
+		ID:       "agent-1",
+		TenantID: 1,
+		Name:     "Support",
+		Config:   types.CustomAgentConfig{SystemPrompt: prompt, ModelID: "model-1"},
+	}}
+	service := &agentVersionService{
+		repo:               &fakeAgentVersionRepository{store: store},
+		customAgentService: &fakeVersionedAgentService{store: store},
+	}
+	ctx := context.WithValue(context.Background(), types.TenantIDContextKey, uint64(1))
+	ctx = context.WithValue(ctx, types.UserIDContextKey, "user-1")
+	return service, store, ctx
+}
+
+// publishPrompts edits the draft to each prompt and publishes it
+func publishPrompts(
+	ctx context.Context,
+	t *testing.T,
+	service *agentVersionService,
+	store *agentVersionTestStore,
+	prompts ...string,
+) {
+	t.Helper()
+	for _, prompt := range prompts {
+		store.agent.Config.SystemPrompt = prompt
+		if _, err := service.Publish(ctx, store.agent.ID, &types.PublishAgentRequest{}); err != nil {
+			t.Fatalf("Publish(%q) = %v", prompt, err)
+		}
+	}
+}
+
+func TestAgentVersionPublishIncrementsVersion(t *testing.T) {
+	service, store, ctx := newAgentVersionTest("v1")
+
+	for i, prompt := range []string{"v1", "v2", "v3"} {
+		store.agent.Config.SystemPrompt = prompt
+		version, err := service.Publish(ctx, store.agent.ID, &types.PublishAgentRequest{Note: "  release  "})
+		if err != nil {
+			t.Fatalf("Publish(%q) = %v", prompt, err)
+		}
+		if version.Version != i+1 || store.agent.PublishedVersion != i+1 {
+			t.Errorf("Publish(%q) = version %d, published %d, want %d",
+				prompt, version.Version, store.agent.PublishedVersion, i+1)
+		}
+		if version.Config.SystemPrompt != prompt || version.Note != "release" || version.PublishedBy != "user-1" {
+			t.Errorf("Publish(%q) = prompt %q, note %q, published by %q",
+				prompt, version.Config.SystemPrompt, version.Note, version.PublishedBy)
+		}
+	}
+
+	if _, err := service.Publish(ctx, store.agent.ID, &types.PublishAgentRequest{}); !errors.Is(err, ErrAgentVersionInvalid) {
+		t.Errorf("Publish() of an unchanged draft = %v, want %v", err, ErrAgentVersionInvalid)
+	}
+	if len(store.versions) != 3 {
+		t.Errorf("got %d versions, want 3", len(store.versions))
+	}
+}
+
+func TestAgentVersionRollbackPublishesNewVersion(t *testing.T) {
+	service, store, ctx := newAgentVersionTest("v1")
+	publishPrompts(ctx, t, service, store, "v1", "v2")
+	store.agent.Config.SystemPrompt = "draft"
+	history := make([]types.AgentVersion, len(store.versions))
+	for i, v := range store.versions {
+		history[i] = *v
+	}
+
+	agent, err := service.Rollback(ctx, store.agent.ID, 1)
+	if err != nil {
+		t.Fatalf("Rollback(1) = %v", err)
+	}
+	if agent.PublishedVersion != 3 || store.agent.PublishedVersion != 3 {
+		t.Errorf("Rollback(1) published version %d, stored %d, want 3", agent.PublishedVersion, store.agent.PublishedVersion)
+	}
+	if agent.Config.SystemPrompt != "draft" || store.agent.Config.SystemPrompt != "draft" {
+		t.Errorf("Rollback(1) changed the draft to %q", store.agent.Config.SystemPrompt)
+	}
+
+	if len(store.versions) != 3 {
+		t.Fatalf("got %d versions after the rollback, want 3", len(store.versions))
+	}
+	for i := range history {
+		if !reflect.DeepEqual(*store.versions[i], history[i]) {
+			t.Errorf("version %d changed by the rollback: %+v, want %+v", history[i].Version, *store.versions[i], history[i])
+		}
+	}
+	rollback := store.versions[2]
+	if rollback.Version != 3 || rollback.ID == history[0].ID || rollback.Config.SystemPrompt != "v1" ||
+		rollback.ConfigHash != history[0].ConfigHash || rollback.Note != "Rollback to version 1" {
+		t.Errorf("rollback version = %+v, want version 3 copying version 1", *rollback)
+	}
+	if diff, err := service.DiffVersions(ctx, store.agent.ID, 1, 3); err != nil || len(diff.Changes) != 0 {
+		t.Errorf("DiffVersions(1, 3) = %+v, %v, want no changes", diff, err)
+	}
+
+	for _, tt := range []struct {
+		version int
+		err     error
+	}{
+		{version: 0, err: ErrAgentVersionInvalid},
+		{version: 3, err: ErrAgentVersionInvalid},
+		{version: 9, err: ErrAgentVersionNotFound},
+	} {
+		if _, err := service.Rollback(ctx, store.agent.ID, tt.version); !errors.Is(err, tt.err) {
+			t.Errorf("Rollback(%d) = %v, want %v", tt.version, err, tt.err)
+		}
+	}
+	if len(store.versions) != 3 {
+		t.Errorf("got %d versions after the rejected rollbacks, want 3", len(store.versions))
+	}
+}
+
+func TestSharedAgentUsesPinnedVersion(t *testing.T) {
+	versions, store, ctx := newAgentVersionTest("v1")
+	share := &fakePinnedShareRepository{}
+	shares := &agentShareService{
+		shareRepo:   share,
+		agentRepo:   &fakeVersionedAgentRepository{store: store},
+		versionRepo: versions.repo,
+	}
+	get := func(pinned int) (*types.CustomAgent, error) {
+		share.pinnedVersion = pinned
+		return shares.GetSharedAgentForUser(ctx, "user-2", 2, store.agent.ID)
+	}
+
+	// Never published: members use the draft
+	agent, err := get(0)
+	if err != nil || agent.Config.SystemPrompt != "v1" || agent.Version != 0 {
+		t.Fatalf("unpublished agent = %+v, %v, want the draft", agent, err)
+	}
+
+	publishPrompts(ctx, t, versions, store, "v1", "v2")
+	store.agent.Config.SystemPrompt = "draft"
+	tests := []struct {
+		name    string
+		pinned  int
+		prompt  string
+		version int
+	}{
+		{name: "follows the published version", pinned: 0, prompt: "v2", version: 2},
+		{name: "pinned to an earlier version", pinned: 1, prompt: "v1", version: 1},
+		{name: "pinned to the published version", pinned: 2, prompt: "v2", version: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agent, err := get(tt.pinned)
+			if err != nil {
+				t.Fatalf("GetSharedAgentForUser() = %v", err)
+			}
+			if agent.Config.SystemPrompt != tt.prompt || agent.Version != tt.version {
+				t.Errorf("GetSharedAgentForUser() = prompt %q, version %d, want %q, %d",
+					agent.Config.SystemPrompt, agent.Version, tt.prompt, tt.version)
+			}
+		})
+	}
+
+	// A pin survives later publishes and rollbacks
+	if _, err := versions.Rollback(ctx, store.agent.ID, 1); err != nil {
+		t.Fatal(err)
+	}
+	if agent, err := get(2); err != nil || agent.Config.SystemPrompt != "v2" {
+		t.Errorf("agent pinned to version 2 after a rollback = %+v, %v, want version 2", agent, err)
+	}
+	if _, err := get(9); !errors.Is(err, ErrAgentVersionNotFound) {
+		t.Errorf("agent pinned to a missing version = %v, want %v", err, ErrAgentVersionNotFound)
+	}
+}
+
+func TestAgentVersionDiff(t *testing.T) {
+	service, store, ctx := newAgentVersionTest("v1")
+	publishPrompts(ctx, t, service, store, "v1")
+	store.agent.Name = "Helpdesk"
+	publishPrompts(ctx, t, service, store, "v2")
+	store.agent.Config.ModelID = "model-2"
+
+	tests := []struct {
+		name     string
+		from, to int
+		want     []types.AgentConfigChange
+	}{
+		{
+			name: "two published versions",
+			from: 1,
+			to:   2,
+			want: []types.AgentConfigChange{
+				{Path: "config.system_prompt", From: "v1", To: "v2"},
+				{Path: "name", From: "Support", To: "Helpdesk"},
+			},
+		},
+		{
+			name: "reversed",
+			from: 2,
+			to:   1,
+			want: []types.AgentConfigChange{
+				{Path: "config.system_prompt", From: "v2", To: "v1"},
+				{Path: "name", From: "Helpdesk", To: "Support"},
+			},
+		},
+		{
+			name: "published version and the draft",
+			from: 2,
+			to:   0,
+			want: []types.AgentConfigChange{{Path: "config.model_id", From: "model-1", To: "model-2"}},
+		},
+		{
+			name: "same version",
+			from: 1,
+			to:   1,
+			want: []types.AgentConfigChange{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff, err := service.DiffVersions(ctx, store.agent.ID, tt.from, tt.to)
+			if err != nil {
+				t.Fatalf("DiffVersions() = %v", err)
+			}
+			if diff.FromVersion != tt.from || diff.ToVersion != tt.to || !reflect.DeepEqual(diff.Changes, tt.want) {
+				t.Errorf("DiffVersions() = %v..%v %+v, want %+v", diff.FromVersion, diff.ToVersion, diff.Changes, tt.want)
+			}
+		})
+	}
+
+	if _, err := service.DiffVersions(ctx, store.agent.ID, 1, 5); !errors.Is(err, ErrAgentVersionNotFound) {
+		t.Errorf("DiffVersions() with a missing version = %v, want %v", err, ErrAgentVersionNotFound)
+	}
+}
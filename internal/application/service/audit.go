@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+)
+
+// auditLogService implements the AuditLogService interface
+type auditLogService struct {
+	repo interfaces.AuditLogRepository
+}
+
+// NewAuditLogService creates a new audit log service
+func NewAuditLogService(repo interfaces.AuditLogRepository) interfaces.AuditLogService {
+	return &auditLogService{repo: repo}
+}
+
+// Record appends an audit log, failures are only logged
+func (s *auditLogService) Record(ctx context.Context, log *types.AuditLog) {
+	if log.TenantID == 0 {
+		return
+	}
+	// The audited request may already be finished or cancelled
+	ctx = context.WithoutCancel(ctx)
+
+	log.ID = uuid.New().String()
+	if log.CreatedAt.IsZero() {
+		log.CreatedAt = time.Now()
+	}
+	if err := s.repo.Create(ctx, log); err != nil {
+		logger.Errorf(ctx, "[Audit] Failed to record %s %s %s: %v",
+			log.Action, log.ResourceType, log.ResourceID, err)
+	}
+}
+
+// ListAuditLogs lists the audit logs of the current tenant, newest first
+func (s *auditLogService) ListAuditLogs(ctx context.Context,
+	filter *types.AuditLogFilter, page *types.Pagination,
+) (*types.PageResult, error) {
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	logs, total, err := s.repo.List(ctx, tenantID, filter, page)
+	if err != nil {
+		return nil, err
+	}
+	return types.NewPageResult(total, page, logs), nil
+}
+
+// ProcessAuditLogCleanup deletes the audit logs older than the retention period
+func (s *auditLogService) ProcessAuditLogCleanup(ctx context.Context, t *asynq.Task) error {
+	days := types.AuditLogRetentionDays()
+	if days == 0 {
+		return nil
+	}
+	before := time.Now().AddDate(0, 0, -days)
+	deleted, err := s.repo.DeleteBefore(ctx, before)
+	if err != nil {
+		logger.Errorf(ctx, "[Audit] Failed to delete audit logs before %s: %v", before.Format(time.RFC3339), err)
+		return err
+	}
+	logger.Infof(ctx, "[Audit] Deleted %d audit logs older than %d days", deleted, days)
+	return nil
+}
@@ -0,0 +1,554 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/application/repository"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+)
+
+const (
+	// bundleBatchSize is the number of chunks or vectors read, written or indexed at a time
+	bundleBatchSize = 200
+	// bundleMaxJSONSize bounds the JSON files of a bundle read into memory
+	bundleMaxJSONSize = 64 << 20
+)
+
+var (
+	ErrBundleInvalid  = errors.New("invalid bundle")
+	ErrBundleConflict = errors.New("bundle conflicts with existing objects")
+)
+
+// bundleService implements the BundleService interface
+type bundleService struct {
+	customAgentService interfaces.CustomAgentService
+	kbService          interfaces.KnowledgeBaseService
+	kbRepo             interfaces.KnowledgeBaseRepository
+	knowledgeService   interfaces.KnowledgeService
+	knowledgeRepo      interfaces.KnowledgeRepository
+	chunkRepo          interfaces.ChunkRepository
+	tagRepo            interfaces.KnowledgeTagRepository
+	modelService       interfaces.ModelService
+	skillService       interfaces.SkillService
+	mcpService         interfaces.MCPServiceService
+	httpToolService    interfaces.HTTPToolService
+	fileService        interfaces.FileService
+	tenantRepo         interfaces.TenantRepository
+	registry           interfaces.RetrieveEngineRegistry
+}
+
+// NewBundleService creates a new bundle service
+func NewBundleService(
+	customAgentService interfaces.CustomAgentService,
+	kbService interfaces.KnowledgeBaseService,
+	kbRepo interfaces.KnowledgeBaseRepository,
+	knowledgeService interfaces.KnowledgeService,
+	knowledgeRepo interfaces.KnowledgeRepository,
+	chunkRepo interfaces.ChunkRepository,
+	tagRepo interfaces.KnowledgeTagRepository,
+	modelService interfaces.ModelService,
+	skillService interfaces.SkillService,
+	mcpService interfaces.MCPServiceService,
+	httpToolService interfaces.HTTPToolService,
+	fileService interfaces.FileService,
+	tenantRepo interfaces.TenantRepository,
+	registry interfaces.RetrieveEngineRegistry,
+) interfaces.BundleService {
+	return &bundleService{
+		customAgentService: customAgentService,
+		kbService:          kbService,
+		kbRepo:             kbRepo,
+		knowledgeService:   knowledgeService,
+		knowledgeRepo:      knowledgeRepo,
+		chunkRepo:          chunkRepo,
+		tagRepo:            tagRepo,
+		modelService:       modelService,
+		skillService:       skillService,
+		mcpService:         mcpService,
+		httpToolService:    httpToolService,
+		fileService:        fileService,
+		tenantRepo:         tenantRepo,
+		registry:           registry,
+	}
+}
+
+// bundleExport collects the content of a bundle being exported
+type bundleExport struct {
+	tenantID       uint64
+	opts           *types.BundleExportOptions
+	manifest       *types.BundleManifest
+	agents         []*types.CustomAgent
+	models         []*types.BundleModel
+	skills         []*types.BundleSkill
+	mcpServices    []*types.BundleMCPService
+	httpTools      []*types.BundleHTTPTool
+	knowledgeBases []*types.BundleKnowledgeBase
+}
+
+// warn records a reference that could not be exported
+func (e *bundleExport) warn(format string, args ...interface{}) {
+	e.manifest.Warnings = append(e.manifest.Warnings, fmt.Sprintf(format, args...))
+}
+
+// ExportAgent writes a bundle of a custom agent of the current tenant and everything it references
+func (s *bundleService) ExportAgent(ctx context.Context,
+	agentID string, opts *types.BundleExportOptions, w io.Writer,
+) error {
+	if opts.IncludeVectors && !opts.IncludeKnowledge {
+		return fmt.Errorf("%w: vectors can only be exported together with the knowledge", ErrBundleInvalid)
+	}
+	root, err := s.customAgentService.GetLiveAgent(ctx, agentID)
+	if err != nil {
+		return err
+	}
+	if root.IsBuiltin {
+		return fmt.Errorf("%w: built-in agents cannot be exported", ErrBundleInvalid)
+	}
+
+	export := s.newExport(ctx, opts)
+	export.manifest.AgentIDs = []string{root.ID}
+	if err := s.collectAgents(ctx, export, root); err != nil {
+		return err
+	}
+
+	var kbIDs, mcpIDs, httpToolIDs, skillNames []string
+	for _, agent := range export.agents {
+		kbIDs = append(kbIDs, agent.Config.ReferencedKnowledgeBaseIDs()...)
+		mcpIDs = append(mcpIDs, agent.Config.MCPServices...)
+		mcpIDs = append(mcpIDs, agent.Config.ApprovalRequiredMCPServices...)
+		for _, source := range agent.Config.MCPContextSources {
+			mcpIDs = append(mcpIDs, source.ServiceID)
+		}
+		httpToolIDs = append(httpToolIDs, agent.Config.HTTPTools...)
+		skillNames = append(skillNames, agent.Config.SelectedSkills...)
+	}
+	if err := s.collectKnowledgeBases(ctx, export, distinct(kbIDs)); err != nil {
+		return err
+	}
+	if err := s.collectMCPServices(ctx, export, distinct(mcpIDs)); err != nil {
+		return err
+	}
+	if err := s.collectHTTPTools(ctx, export, distinct(httpToolIDs)); err != nil {
+		return err
+	}
+	s.collectSkills(ctx, export, distinct(skillNames))
+
+	var modelIDs []string
+	for _, agent := range export.agents {
+		modelIDs = append(modelIDs, agent.Config.ReferencedModelIDs()...)
+	}
+	if err := s.writeBundle(ctx, export, modelIDs, w); err != nil {
+		return err
+	}
+	logger.Infof(ctx, "[Bundle] Exported agent %s with %d agents and %d knowledge bases, knowledge: %v, vectors: %v",
+		root.ID, len(export.agents), len(export.knowledgeBases), opts.IncludeKnowledge, opts.IncludeVectors)
+	return nil
+}
+
+// ExportKnowledgeBase writes a bundle of a knowledge base of the current tenant and its content
+func (s *bundleService) ExportKnowledgeBase(ctx context.Context,
+	kbID string, opts *types.BundleExportOptions, w io.Writer,
+) error {
+	opts.IncludeKnowledge = true
+	export := s.newExport(ctx, opts)
+	if _, err := s.kbRepo.GetKnowledgeBaseByIDAndTenant(ctx, kbID, export.tenantID); err != nil {
+		return err
+	}
+	if err := s.collectKnowledgeBases(ctx, export, []string{kbID}); err != nil {
+		return err
+	}
+	if err := s.writeBundle(ctx, export, nil, w); err != nil {
+		return err
+	}
+	logger.Infof(ctx, "[Bundle] Exported knowledge base %s, vectors: %v", kbID, opts.IncludeVectors)
+	return nil
+}
+
+// newExport starts a bundle of the current tenant
+func (s *bundleService) newExport(ctx context.Context, opts *types.BundleExportOptions) *bundleExport {
+	return &bundleExport{
+		tenantID:       ctx.Value(types.TenantIDContextKey).(uint64),
+		opts:           opts,
+		agents:         []*types.CustomAgent{},
+		models:         []*types.BundleModel{},
+		skills:         []*types.BundleSkill{},
+		mcpServices:    []*types.BundleMCPService{},
+		httpTools:      []*types.BundleHTTPTool{},
+		knowledgeBases: []*types.BundleKnowledgeBase{},
+		manifest: &types.BundleManifest{
+			Format:           types.BundleFormat,
+			Version:          types.BundleFormatVersion,
+			ExportedAt:       time.Now(),
+			IncludeKnowledge: opts.IncludeKnowledge,
+			IncludeVectors:   opts.IncludeVectors,
+		},
+	}
+}
+
+// collectAgents adds an agent and, transitively, the custom agents it delegates to in their published versions
+func (s *bundleService) collectAgents(ctx context.Context, export *bundleExport, root *types.CustomAgent) error {
+	seen := map[string]bool{root.ID: true}
+	queue := []*types.CustomAgent{root}
+	for len(queue) > 0 {
+		agent := queue[0]
+		queue = queue[1:]
+		export.agents = append(export.agents, agent)
+		for _, id := range agent.Config.SubAgents {
+			if seen[id] || types.IsBuiltinAgentID(id) {
+				continue
+			}
+			seen[id] = true
+			subAgent, err := s.customAgentService.GetLiveAgent(ctx, id)
+			if err != nil {
+				if errors.Is(err, ErrAgentNotFound) {
+					export.warn("sub agent %s of agent %s not found", id, agent.ID)
+					continue
+				}
+				return err
+			}
+			queue = append(queue, subAgent)
+		}
+	}
+	return nil
+}
+
+// collectKnowledgeBases adds the knowledge bases of the current tenant among ids.
+// Knowledge bases of other tenants, such as shared ones, cannot be exported.
+func (s *bundleService) collectKnowledgeBases(ctx context.Context, export *bundleExport, ids []string) error {
+	for _, id := range ids {
+		kb, err := s.kbRepo.GetKnowledgeBaseByIDAndTenant(ctx, id, export.tenantID)
+		if err != nil {
+			if errors.Is(err, repository.ErrKnowledgeBaseNotFound) {
+				export.warn("knowledge base %s not found in the tenant", id)
+				continue
+			}
+			return err
+		}
+		entry := &types.BundleKnowledgeBase{
+			KnowledgeBase:  kb,
+			Included:       export.opts.IncludeKnowledge,
+			RemovedSecrets: kb.RemoveSecrets(),
+		}
+		export.knowledgeBases = append(export.knowledgeBases, entry)
+		if entry.Included {
+			export.manifest.KnowledgeBaseIDs = append(export.manifest.KnowledgeBaseIDs, kb.ID)
+		}
+	}
+	return nil
+}
+
+// collectMCPServices adds the MCP services among ids without their secrets
+func (s *bundleService) collectMCPServices(ctx context.Context, export *bundleExport, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	services, err := s.mcpService.ListMCPServicesByIDs(ctx, export.tenantID, ids)
+	if err != nil {
+		return err
+	}
+	for _, service := range services {
+		export.mcpServices = append(export.mcpServices, &types.BundleMCPService{
+			Service:        service,
+			RemovedSecrets: service.RemoveSecrets(),
+		})
+	}
+	if len(services) < len(ids) {
+		export.warn("%d referenced MCP services not found", len(ids)-len(services))
+	}
+	return nil
+}
+
+// collectHTTPTools adds the HTTP tools among ids without their auth secrets
+func (s *bundleService) collectHTTPTools(ctx context.Context, export *bundleExport, ids []string) error {
+	for _, id := range ids {
+		tool, err := s.httpToolService.GetTool(ctx, id)
+		if err != nil {
+			if errors.Is(err, ErrHTTPToolNotFound) {
+				export.warn("HTTP tool %s not found", id)
+				continue
+			}
+			return err
+		}
+		entry := &types.BundleHTTPTool{Tool: tool}
+		if tool.AuthSecret != "" {
+			entry.RemovedSecrets = []string{"auth_secret"}
+		}
+		tool.AuthSecret = ""
+		tool.HasAuthSecret = false
+		export.httpTools = append(export.httpTools, entry)
+	}
+	return nil
+}
+
+// collectSkills adds the preloaded skills among names
+func (s *bundleService) collectSkills(ctx context.Context, export *bundleExport, names []string) {
+	for _, name := range names {
+		skill, err := s.skillService.GetSkillByName(ctx, name)
+		if err != nil {
+			export.warn("skill %s not found", name)
+			continue
+		}
+		export.skills = append(export.skills, &types.BundleSkill{Name: skill.Name, Description: skill.Description})
+	}
+}
+
+// collectModels adds the models among ids, together with the models of the knowledge bases
+func (s *bundleService) collectModels(ctx context.Context, export *bundleExport, ids []string) error {
+	for _, entry := range export.knowledgeBases {
+		ids = append(ids, entry.KnowledgeBase.ReferencedModelIDs()...)
+	}
+	for _, id := range distinct(ids) {
+		model, err := s.modelService.GetModelByID(ctx, id)
+		if err != nil || model == nil {
+			export.warn("model %s not found", id)
+			continue
+		}
+		export.models = append(export.models, &types.BundleModel{
+			ID:     model.ID,
+			Name:   model.Name,
+			Type:   model.Type,
+			Source: model.Source,
+		})
+	}
+	return nil
+}
+
+// writeBundle writes the collected content as a zip archive, the manifest last so that it carries all warnings
+func (s *bundleService) writeBundle(ctx context.Context,
+	export *bundleExport, modelIDs []string, w io.Writer,
+) error {
+	if err := s.collectModels(ctx, export, modelIDs); err != nil {
+		return err
+	}
+
+	zw := zip.NewWriter(w)
+	for _, entry := range export.knowledgeBases {
+		if !entry.Included {
+			continue
+		}
+		if err := s.writeKnowledgeBase(ctx, zw, export, entry); err != nil {
+			return fmt.Errorf("export knowledge base %s: %w", entry.KnowledgeBase.ID, err)
+		}
+	}
+
+	files := []struct {
+		name  string
+		value interface{}
+	}{
+		{types.BundleAgentsFile, export.agents},
+		{types.BundleModelsFile, export.models},
+		{types.BundleSkillsFile, export.skills},
+		{types.BundleMCPServicesFile, export.mcpServices},
+		{types.BundleHTTPToolsFile, export.httpTools},
+		{types.BundleKnowledgeBasesFile, export.knowledgeBases},
+		{types.BundleManifestFile, export.manifest},
+	}
+	for _, file := range files {
+		if err := writeBundleJSON(zw, export, file.name, file.value); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// writeKnowledgeBase writes the tags, completed knowledge, original documents, chunks and
+// optionally the vectors of a knowledge base
+func (s *bundleService) writeKnowledgeBase(ctx context.Context,
+	zw *zip.Writer, export *bundleExport, entry *types.BundleKnowledgeBase,
+) error {
+	kb := entry.KnowledgeBase
+	for page := 1; ; page++ {
+		tags, _, err := s.tagRepo.ListByKB(ctx, export.tenantID, kb.ID, &types.Pagination{Page: page, PageSize: 100}, "")
+		if err != nil {
+			return err
+		}
+		entry.Tags = append(entry.Tags, tags...)
+		if len(tags) < 100 {
+			break
+		}
+	}
+
+	knowledgeList, err := s.knowledgeRepo.ListKnowledgeByKnowledgeBaseID(ctx, export.tenantID, kb.ID)
+	if err != nil {
+		return err
+	}
+	knowledgeList = slices.DeleteFunc(knowledgeList, func(k *types.Knowledge) bool {
+		return k.ParseStatus != types.ParseStatusCompleted
+	})
+	exported := make(map[string]bool, len(knowledgeList))
+	lines, err := createBundleFile(zw, export, types.BundleKnowledgeBasePath(kb.ID, types.BundleKnowledgeFile))
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(lines)
+	for _, knowledge := range knowledgeList {
+		if err := encoder.Encode(knowledge); err != nil {
+			return err
+		}
+		exported[knowledge.ID] = true
+	}
+	entry.KnowledgeCount = int64(len(knowledgeList))
+
+	for _, knowledge := range knowledgeList {
+		if knowledge.FilePath == "" {
+			continue
+		}
+		if err := s.writeDocument(ctx, zw, export, knowledge); err != nil {
+			export.warn("document of knowledge %s (%s) not exported: %v", knowledge.ID, knowledge.FileName, err)
+		}
+	}
+
+	chunkIDs := make(map[string]bool)
+	lines, err = createBundleFile(zw, export, types.BundleKnowledgeBasePath(kb.ID, types.BundleChunksFile))
+	if err != nil {
+		return err
+	}
+	encoder = json.NewEncoder(lines)
+	for afterID := ""; ; {
+		chunks, err := s.chunkRepo.ListChunksForExport(ctx, export.tenantID, kb.ID, afterID, bundleBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, chunk := range chunks {
+			if !exported[chunk.KnowledgeID] {
+				continue
+			}
+			if err := encoder.Encode(chunk); err != nil {
+				return err
+			}
+			chunkIDs[chunk.ID] = true
+		}
+		if len(chunks) < bundleBatchSize {
+			break
+		}
+		afterID = chunks[len(chunks)-1].ID
+	}
+	entry.ChunkCount = int64(len(chunkIDs))
+
+	if export.opts.IncludeVectors {
+		return s.writeVectors(ctx, zw, export, entry, chunkIDs)
+	}
+	return nil
+}
+
+// writeDocument copies the original document of a knowledge into the bundle
+func (s *bundleService) writeDocument(ctx context.Context,
+	zw *zip.Writer, export *bundleExport, knowledge *types.Knowledge,
+) error {
+	file, err := s.fileService.GetFile(ctx, knowledge.FilePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	w, err := createBundleFile(zw, export, types.BundleDocumentPath(knowledge.ID))
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, file)
+	return err
+}
+
+// writeVectors writes the index entries of the exported chunks with their embeddings,
+// read from the first vector engine of the tenant
+func (s *bundleService) writeVectors(ctx context.Context,
+	zw *zip.Writer, export *bundleExport, entry *types.BundleKnowledgeBase, chunkIDs map[string]bool,
+) error {
+	kb := entry.KnowledgeBase
+	tenant := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
+	var engine interfaces.RetrieveEngineService
+	for _, params := range tenant.GetServingEngines() {
+		if params.RetrieverType == types.VectorRetrieverType {
+			var err error
+			if engine, err = s.registry.GetRetrieveEngineService(params.RetrieverEngineType); err != nil {
+				return err
+			}
+			break
+		}
+	}
+	if engine == nil || kb.EmbeddingModelID == "" {
+		export.warn("vectors of knowledge base %s not exported: no vector index", kb.ID)
+		return nil
+	}
+	embeddingModel, err := s.modelService.GetEmbeddingModel(ctx, kb.EmbeddingModelID)
+	if err != nil {
+		return err
+	}
+	dimension := embeddingModel.GetDimensions()
+
+	lines, err := createBundleFile(zw, export, types.BundleKnowledgeBasePath(kb.ID, types.BundleVectorsFile))
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(lines)
+	for cursor := ""; ; {
+		entries, next, err := engine.ListIndices(ctx, kb.ID, dimension, kb.Type, cursor, bundleBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, index := range entries {
+			if !chunkIDs[index.ChunkID] || len(index.Embedding) != dimension {
+				continue
+			}
+			if err := encoder.Encode(&types.BundleVector{
+				SourceID:      index.SourceID,
+				ChunkID:       index.ChunkID,
+				Content:       index.Content,
+				KnowledgeType: index.KnowledgeType,
+				IsEnabled:     index.IsEnabled,
+				IsRecommended: index.IsRecommended,
+				Embedding:     index.Embedding,
+			}); err != nil {
+				return err
+			}
+			entry.VectorCount++
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	if entry.VectorCount > 0 {
+		entry.VectorDimension = dimension
+	}
+	return nil
+}
+
+// createBundleFile adds a compressed file dated at the export time to a bundle
+func createBundleFile(zw *zip.Writer, export *bundleExport, name string) (io.Writer, error) {
+	return zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: export.manifest.ExportedAt,
+	})
+}
+
+// writeBundleJSON writes a JSON file into a bundle
+func writeBundleJSON(zw *zip.Writer, export *bundleExport, name string, value interface{}) error {
+	w, err := createBundleFile(zw, export, name)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// distinct returns the distinct non-empty values in order
+func distinct(values []string) []string {
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if value != "" && !slices.Contains(result, value) {
+			result = append(result, value)
+		}
+	}
+	return result
+}
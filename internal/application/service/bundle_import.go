@@ -0,0 +1,1028 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/agent/skills"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"github.com/Tencent/WeKnora/internal/utils"
+	"github.com/google/uuid"
+)
+
+// bundleImport holds the content and the plan of a bundle being imported
+type bundleImport struct {
+	tenantID       uint64
+	opts           *types.BundleImportOptions
+	files          map[string]*zip.File
+	manifest       *types.BundleManifest
+	agents         []*types.CustomAgent
+	models         []*types.BundleModel
+	skills         []*types.BundleSkill
+	mcpServices    []*types.BundleMCPService
+	httpTools      []*types.BundleHTTPTool
+	knowledgeBases []*types.BundleKnowledgeBase
+
+	report *types.BundleImportReport
+	ids    *types.BundleIDMap
+	// Items of the report keyed by kind and source ID
+	items map[string]*types.BundleImportItem
+	order []*types.BundleImportItem
+	// Undo the objects created so far, run in reverse order when the import fails
+	rollbacks []func(context.Context)
+	// Bytes of the original documents stored so far, counted against the storage quota of the tenant
+	storageSize int64
+}
+
+// Import imports a bundle into the current tenant. Every object gets a new ID; references between the objects
+// of the bundle are rewritten, models and skills are resolved by name and knowledge bases that were only
+// referenced are looked up by name. Any failure removes the objects created so far.
+func (s *bundleService) Import(ctx context.Context,
+	r io.ReaderAt, size int64, opts *types.BundleImportOptions,
+) (*types.BundleImportReport, error) {
+	if opts.OnConflict == "" {
+		opts.OnConflict = types.BundleConflictFail
+	}
+	switch opts.OnConflict {
+	case types.BundleConflictFail, types.BundleConflictRename, types.BundleConflictReuse:
+	default:
+		return nil, fmt.Errorf("%w: unsupported conflict mode %q", ErrBundleInvalid, opts.OnConflict)
+	}
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBundleInvalid, err)
+	}
+
+	imp := &bundleImport{
+		tenantID: ctx.Value(types.TenantIDContextKey).(uint64),
+		opts:     opts,
+		files:    make(map[string]*zip.File, len(zr.File)),
+		report: &types.BundleImportReport{
+			DryRun:     opts.DryRun,
+			OnConflict: opts.OnConflict,
+			Items:      []types.BundleImportItem{},
+			Conflicts:  []types.BundleConflict{},
+			Warnings:   []string{},
+		},
+		ids: &types.BundleIDMap{
+			Models:         make(map[string]string),
+			KnowledgeBases: make(map[string]string),
+			MCPServices:    make(map[string]string),
+			HTTPTools:      make(map[string]string),
+			HTTPToolNames:  make(map[string]string),
+			Agents:         make(map[string]string),
+		},
+		items: make(map[string]*types.BundleImportItem),
+	}
+	for _, file := range zr.File {
+		imp.files[file.Name] = file
+	}
+	if err := imp.read(); err != nil {
+		return nil, err
+	}
+	if err := s.plan(ctx, imp); err != nil {
+		return nil, err
+	}
+	if opts.DryRun {
+		imp.finish()
+		return imp.report, nil
+	}
+	if len(imp.report.Conflicts) > 0 {
+		imp.finish()
+		return imp.report, ErrBundleConflict
+	}
+
+	if err := s.execute(ctx, imp); err != nil {
+		logger.Errorf(ctx, "[Bundle] Import failed, rolling back %d objects: %v", len(imp.rollbacks), err)
+		for _, rollback := range slices.Backward(imp.rollbacks) {
+			rollback(ctx)
+		}
+		return nil, err
+	}
+	imp.report.Imported = true
+	imp.finish()
+	logger.Infof(ctx, "[Bundle] Imported bundle with %d agents and %d knowledge bases, chunks: %d, vectors: %d",
+		len(imp.agents), len(imp.knowledgeBases), imp.report.ChunkCount, imp.report.VectorCount)
+	return imp.report, nil
+}
+
+// read reads and validates the JSON files of the bundle
+func (imp *bundleImport) read() error {
+	if err := imp.readJSON(types.BundleManifestFile, &imp.manifest); err != nil {
+		return err
+	}
+	if imp.manifest == nil || imp.manifest.Format != types.BundleFormat {
+		return fmt.Errorf("%w: missing or unknown %s", ErrBundleInvalid, types.BundleManifestFile)
+	}
+	if imp.manifest.Version < 1 || imp.manifest.Version > types.BundleFormatVersion {
+		return fmt.Errorf("%w: unsupported bundle version %d", ErrBundleInvalid, imp.manifest.Version)
+	}
+	files := []struct {
+		name  string
+		value interface{}
+	}{
+		{types.BundleAgentsFile, &imp.agents},
+		{types.BundleModelsFile, &imp.models},
+		{types.BundleSkillsFile, &imp.skills},
+		{types.BundleMCPServicesFile, &imp.mcpServices},
+		{types.BundleHTTPToolsFile, &imp.httpTools},
+		{types.BundleKnowledgeBasesFile, &imp.knowledgeBases},
+	}
+	for _, file := range files {
+		if _, ok := imp.files[file.name]; !ok {
+			continue
+		}
+		if err := imp.readJSON(file.name, file.value); err != nil {
+			return err
+		}
+	}
+
+	for _, agent := range imp.agents {
+		if agent == nil || agent.ID == "" {
+			return fmt.Errorf("%w: agent without ID", ErrBundleInvalid)
+		}
+	}
+	for _, model := range imp.models {
+		if model == nil || model.ID == "" {
+			return fmt.Errorf("%w: model without ID", ErrBundleInvalid)
+		}
+	}
+	for _, service := range imp.mcpServices {
+		if service == nil || service.Service == nil || service.Service.ID == "" {
+			return fmt.Errorf("%w: MCP service without ID", ErrBundleInvalid)
+		}
+	}
+	for _, tool := range imp.httpTools {
+		if tool == nil || tool.Tool == nil || tool.Tool.ID == "" {
+			return fmt.Errorf("%w: HTTP tool without ID", ErrBundleInvalid)
+		}
+	}
+	for _, entry := range imp.knowledgeBases {
+		if entry == nil || entry.KnowledgeBase == nil || entry.KnowledgeBase.ID == "" {
+			return fmt.Errorf("%w: knowledge base without ID", ErrBundleInvalid)
+		}
+		if !entry.Included {
+			continue
+		}
+		for _, name := range []string{types.BundleKnowledgeFile, types.BundleChunksFile} {
+			if _, ok := imp.files[types.BundleKnowledgeBasePath(entry.KnowledgeBase.ID, name)]; !ok {
+				return fmt.Errorf("%w: missing %s of knowledge base %s", ErrBundleInvalid, name, entry.KnowledgeBase.ID)
+			}
+		}
+	}
+	return nil
+}
+
+// readJSON decodes a JSON file of the bundle
+func (imp *bundleImport) readJSON(name string, value interface{}) error {
+	file, ok := imp.files[name]
+	if !ok {
+		return fmt.Errorf("%w: missing %s", ErrBundleInvalid, name)
+	}
+	if file.UncompressedSize64 > bundleMaxJSONSize {
+		return fmt.Errorf("%w: %s is too large", ErrBundleInvalid, name)
+	}
+	reader, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBundleInvalid, err)
+	}
+	defer reader.Close()
+	if err := json.NewDecoder(io.LimitReader(reader, bundleMaxJSONSize)).Decode(value); err != nil {
+		return fmt.Errorf("%w: decode %s: %v", ErrBundleInvalid, name, err)
+	}
+	return nil
+}
+
+// readJSONLines calls fn with each line of a JSON Lines file of the bundle, a missing file has no lines
+func readJSONLines[T any](imp *bundleImport, name string, fn func(*T) error) error {
+	file, ok := imp.files[name]
+	if !ok {
+		return nil
+	}
+	reader, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBundleInvalid, err)
+	}
+	defer reader.Close()
+	decoder := json.NewDecoder(reader)
+	for {
+		value := new(T)
+		if err := decoder.Decode(value); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("%w: decode %s: %v", ErrBundleInvalid, name, err)
+		}
+		if err := fn(value); err != nil {
+			return err
+		}
+	}
+}
+
+// addItem records what the import does with an object of the bundle
+func (imp *bundleImport) addItem(kind, sourceID, name, action string) *types.BundleImportItem {
+	item := &types.BundleImportItem{Kind: kind, SourceID: sourceID, Name: name, Action: action}
+	imp.items[kind+"/"+sourceID] = item
+	imp.order = append(imp.order, item)
+	return item
+}
+
+// item returns the recorded item of an object of the bundle
+func (imp *bundleImport) item(kind, sourceID string) *types.BundleImportItem {
+	return imp.items[kind+"/"+sourceID]
+}
+
+// conflict records a problem that prevents the bundle from being imported
+func (imp *bundleImport) conflict(kind, sourceID, name, reason string) {
+	imp.report.Conflicts = append(imp.report.Conflicts, types.BundleConflict{
+		Kind: kind, SourceID: sourceID, Name: name, Reason: reason,
+	})
+}
+
+// warn records something the import cannot carry over
+func (imp *bundleImport) warn(format string, args ...interface{}) {
+	imp.report.Warnings = append(imp.report.Warnings, fmt.Sprintf(format, args...))
+}
+
+// finish copies the items into the report
+func (imp *bundleImport) finish() {
+	for _, item := range imp.order {
+		imp.report.Items = append(imp.report.Items, *item)
+	}
+}
+
+// resolveName plans an object of the bundle against the names taken in the tenant, which maps names to the IDs
+// of existing objects. Names taken by objects of the bundle itself map to an empty ID and are never reused.
+func (imp *bundleImport) resolveName(kind, sourceID, name string,
+	taken map[string]string, rename func(name string, n int) string,
+) *types.BundleImportItem {
+	existingID, ok := taken[name]
+	if !ok {
+		taken[name] = ""
+		item := imp.addItem(kind, sourceID, name, types.BundleActionCreate)
+		item.TargetName = name
+		return item
+	}
+	if imp.opts.OnConflict == types.BundleConflictReuse && existingID != "" {
+		item := imp.addItem(kind, sourceID, name, types.BundleActionReuse)
+		item.TargetID = existingID
+		item.TargetName = name
+		return item
+	}
+	if imp.opts.OnConflict == types.BundleConflictFail {
+		imp.conflict(kind, sourceID, name, "name already exists")
+		return imp.addItem(kind, sourceID, name, types.BundleActionSkip)
+	}
+	for n := 2; ; n++ {
+		candidate := rename(name, n)
+		if _, ok := taken[candidate]; !ok {
+			taken[candidate] = ""
+			item := imp.addItem(kind, sourceID, name, types.BundleActionRename)
+			item.TargetName = candidate
+			return item
+		}
+	}
+}
+
+// renameBundleObject returns the n-th alternative display name
+func renameBundleObject(name string, n int) string {
+	return fmt.Sprintf("%s (%d)", name, n)
+}
+
+// renameBundleHTTPTool returns the n-th alternative HTTP tool name, which must stay a valid tool name
+func renameBundleHTTPTool(name string, n int) string {
+	suffix := fmt.Sprintf("_%d", n)
+	if len(name)+len(suffix) > 48 {
+		name = name[:48-len(suffix)]
+	}
+	return name + suffix
+}
+
+// plan decides what to do with each object of the bundle without writing anything
+func (s *bundleService) plan(ctx context.Context, imp *bundleImport) error {
+	imp.report.Warnings = append(imp.report.Warnings, imp.manifest.Warnings...)
+
+	models, err := s.modelService.ListModels(ctx)
+	if err != nil {
+		return err
+	}
+	for _, model := range imp.models {
+		var target *types.Model
+		for _, candidate := range models {
+			if candidate.ID == model.ID {
+				target = candidate
+				break
+			}
+			if target == nil && candidate.Name == model.Name && candidate.Type == model.Type {
+				target = candidate
+			}
+		}
+		if target == nil {
+			imp.addItem(types.BundleKindModel, model.ID, model.Name, types.BundleActionSkip)
+			imp.warn("model %s (%s) not found, references to it are dropped", model.Name, model.Type)
+			continue
+		}
+		item := imp.addItem(types.BundleKindModel, model.ID, model.Name, types.BundleActionReuse)
+		item.TargetID = target.ID
+		item.TargetName = target.Name
+		imp.ids.Models[model.ID] = target.ID
+	}
+
+	if len(imp.skills) > 0 {
+		preloaded, err := s.skillService.ListPreloadedSkills(ctx)
+		if err != nil {
+			return err
+		}
+		for _, skill := range imp.skills {
+			if slices.ContainsFunc(preloaded, func(m *skills.SkillMetadata) bool { return m.Name == skill.Name }) {
+				item := imp.addItem(types.BundleKindSkill, "", skill.Name, types.BundleActionReuse)
+				item.TargetName = skill.Name
+				continue
+			}
+			imp.addItem(types.BundleKindSkill, "", skill.Name, types.BundleActionSkip)
+			imp.warn("skill %s not found, agents using it will not load it", skill.Name)
+		}
+	}
+
+	if err := s.planMCPServices(ctx, imp); err != nil {
+		return err
+	}
+	if err := s.planHTTPTools(ctx, imp); err != nil {
+		return err
+	}
+	if err := s.planKnowledgeBases(ctx, imp); err != nil {
+		return err
+	}
+	return s.planAgents(ctx, imp)
+}
+
+// planMCPServices plans the MCP services, stdio services cannot be created and are skipped
+func (s *bundleService) planMCPServices(ctx context.Context, imp *bundleImport) error {
+	if len(imp.mcpServices) == 0 {
+		return nil
+	}
+	existing, err := s.mcpService.ListMCPServices(ctx, imp.tenantID)
+	if err != nil {
+		return err
+	}
+	taken := make(map[string]string, len(existing))
+	for _, service := range existing {
+		taken[service.Name] = service.ID
+	}
+	for _, entry := range imp.mcpServices {
+		service := entry.Service
+		if service.TransportType == types.MCPTransportStdio {
+			imp.addItem(types.BundleKindMCPService, service.ID, service.Name, types.BundleActionSkip)
+			imp.warn("MCP service %s uses the stdio transport, which cannot be imported", service.Name)
+			continue
+		}
+		item := imp.resolveName(types.BundleKindMCPService, service.ID, service.Name, taken, renameBundleObject)
+		if item.Action == types.BundleActionReuse {
+			imp.ids.MCPServices[service.ID] = item.TargetID
+		} else if item.Action != types.BundleActionSkip && len(entry.RemovedSecrets) > 0 {
+			imp.warn("MCP service %s: fill in %s after import",
+				item.TargetName, strings.Join(entry.RemovedSecrets, ", "))
+		}
+	}
+	return nil
+}
+
+// planHTTPTools plans the HTTP tools, renamed tools keep a valid tool name
+func (s *bundleService) planHTTPTools(ctx context.Context, imp *bundleImport) error {
+	if len(imp.httpTools) == 0 {
+		return nil
+	}
+	existing, err := s.httpToolService.ListTools(ctx)
+	if err != nil {
+		return err
+	}
+	taken := make(map[string]string, len(existing))
+	for _, tool := range existing {
+		taken[tool.Name] = tool.ID
+	}
+	for _, entry := range imp.httpTools {
+		tool := entry.Tool
+		item := imp.resolveName(types.BundleKindHTTPTool, tool.ID, tool.Name, taken, renameBundleHTTPTool)
+		if item.Action == types.BundleActionSkip {
+			continue
+		}
+		imp.ids.HTTPToolNames[tool.Name] = item.TargetName
+		if item.Action == types.BundleActionReuse {
+			imp.ids.HTTPTools[tool.ID] = item.TargetID
+		} else if len(entry.RemovedSecrets) > 0 {
+			imp.warn("HTTP tool %s: fill in %s after import",
+				item.TargetName, strings.Join(entry.RemovedSecrets, ", "))
+		}
+	}
+	return nil
+}
+
+// planKnowledgeBases plans the knowledge bases. Included knowledge bases are created with their content,
+// the others are looked up by name.
+func (s *bundleService) planKnowledgeBases(ctx context.Context, imp *bundleImport) error {
+	if len(imp.knowledgeBases) == 0 {
+		return nil
+	}
+	existing, err := s.kbService.ListKnowledgeBases(ctx)
+	if err != nil {
+		return err
+	}
+	taken := make(map[string]string, len(existing))
+	for _, kb := range existing {
+		taken[kb.Name] = kb.ID
+	}
+	for _, entry := range imp.knowledgeBases {
+		kb := entry.KnowledgeBase
+		if !entry.Included {
+			if id := taken[kb.Name]; id != "" {
+				item := imp.addItem(types.BundleKindKnowledgeBase, kb.ID, kb.Name, types.BundleActionReuse)
+				item.TargetID = id
+				item.TargetName = kb.Name
+				imp.ids.KnowledgeBases[kb.ID] = id
+				continue
+			}
+			imp.addItem(types.BundleKindKnowledgeBase, kb.ID, kb.Name, types.BundleActionSkip)
+			imp.warn("knowledge base %s not found, references to it are dropped", kb.Name)
+			continue
+		}
+
+		item := imp.resolveName(types.BundleKindKnowledgeBase, kb.ID, kb.Name, taken, renameBundleObject)
+		switch item.Action {
+		case types.BundleActionReuse:
+			imp.ids.KnowledgeBases[kb.ID] = item.TargetID
+			continue
+		case types.BundleActionSkip:
+			continue
+		}
+		if kb.EmbeddingModelID != "" && imp.ids.Models[kb.EmbeddingModelID] == "" {
+			imp.conflict(types.BundleKindKnowledgeBase, kb.ID, kb.Name, "embedding model not found")
+		}
+		for _, id := range []string{kb.SummaryModelID, kb.ImageProcessingConfig.ModelID, kb.VLMConfig.ModelID} {
+			if id != "" && imp.ids.Models[id] == "" {
+				imp.warn("knowledge base %s: model %s not found and removed", item.TargetName, id)
+			}
+		}
+		if len(entry.RemovedSecrets) > 0 {
+			imp.warn("knowledge base %s: fill in %s after import",
+				item.TargetName, strings.Join(entry.RemovedSecrets, ", "))
+		}
+	}
+	return nil
+}
+
+// planAgents plans the agents and reports the references that will be dropped
+func (s *bundleService) planAgents(ctx context.Context, imp *bundleImport) error {
+	if len(imp.agents) == 0 {
+		return nil
+	}
+	existing, err := s.customAgentService.ListAgents(ctx)
+	if err != nil {
+		return err
+	}
+	taken := make(map[string]string, len(existing))
+	for _, agent := range existing {
+		taken[agent.Name] = agent.ID
+	}
+	for _, agent := range imp.agents {
+		item := imp.resolveName(types.BundleKindAgent, agent.ID, agent.Name, taken, renameBundleObject)
+		if item.Action == types.BundleActionReuse {
+			imp.ids.Agents[agent.ID] = item.TargetID
+		}
+	}
+
+	// Objects still to be created keep their source IDs here, only to find the references that will be dropped
+	planned := imp.plannedIDs()
+	for _, agent := range imp.agents {
+		item := imp.item(types.BundleKindAgent, agent.ID)
+		if item.Action == types.BundleActionSkip || item.Action == types.BundleActionReuse {
+			continue
+		}
+		config, err := cloneAgentConfig(&agent.Config)
+		if err != nil {
+			return fmt.Errorf("%w: agent %s: %v", ErrBundleInvalid, agent.ID, err)
+		}
+		for _, dropped := range config.RemapReferences(planned) {
+			imp.warn("agent %s: %s not available, reference removed", item.TargetName, dropped)
+		}
+	}
+	return nil
+}
+
+// plannedIDs returns the ID map the plan leads to, objects to be created map to their source IDs
+func (imp *bundleImport) plannedIDs() *types.BundleIDMap {
+	planned := &types.BundleIDMap{
+		Models:         imp.ids.Models,
+		KnowledgeBases: make(map[string]string),
+		MCPServices:    make(map[string]string),
+		HTTPTools:      make(map[string]string),
+		HTTPToolNames:  imp.ids.HTTPToolNames,
+		Agents:         make(map[string]string),
+	}
+	kinds := map[string]map[string]string{
+		types.BundleKindKnowledgeBase: planned.KnowledgeBases,
+		types.BundleKindMCPService:    planned.MCPServices,
+		types.BundleKindHTTPTool:      planned.HTTPTools,
+		types.BundleKindAgent:         planned.Agents,
+	}
+	for _, item := range imp.order {
+		ids, ok := kinds[item.Kind]
+		if !ok {
+			continue
+		}
+		switch item.Action {
+		case types.BundleActionReuse:
+			ids[item.SourceID] = item.TargetID
+		case types.BundleActionCreate, types.BundleActionRename:
+			ids[item.SourceID] = item.SourceID
+		}
+	}
+	return planned
+}
+
+// cloneAgentConfig returns a deep copy of an agent config
+func cloneAgentConfig(config *types.CustomAgentConfig) (*types.CustomAgentConfig, error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	var clone types.CustomAgentConfig
+	if err := json.Unmarshal(data, &clone); err != nil {
+		return nil, err
+	}
+	return &clone, nil
+}
+
+// creates reports whether the plan creates an object of the bundle
+func (imp *bundleImport) creates(kind, sourceID string) (*types.BundleImportItem, bool) {
+	item := imp.item(kind, sourceID)
+	if item == nil {
+		return nil, false
+	}
+	return item, item.Action == types.BundleActionCreate || item.Action == types.BundleActionRename
+}
+
+// execute creates the planned objects, dependencies first
+func (s *bundleService) execute(ctx context.Context, imp *bundleImport) error {
+	for _, entry := range imp.mcpServices {
+		item, ok := imp.creates(types.BundleKindMCPService, entry.Service.ID)
+		if !ok {
+			continue
+		}
+		service := *entry.Service
+		service.ID = ""
+		service.TenantID = imp.tenantID
+		service.Name = item.TargetName
+		service.DeletedAt = types.MCPService{}.DeletedAt
+		if err := s.mcpService.CreateMCPService(ctx, &service); err != nil {
+			return fmt.Errorf("create MCP service %s: %w", item.TargetName, err)
+		}
+		id := service.ID
+		imp.rollbacks = append(imp.rollbacks, func(ctx context.Context) {
+			if err := s.mcpService.DeleteMCPService(ctx, imp.tenantID, id); err != nil {
+				logger.Warnf(ctx, "[Bundle] Failed to roll back MCP service %s: %v", id, err)
+			}
+		})
+		item.TargetID = id
+		imp.ids.MCPServices[entry.Service.ID] = id
+	}
+
+	for _, entry := range imp.httpTools {
+		item, ok := imp.creates(types.BundleKindHTTPTool, entry.Tool.ID)
+		if !ok {
+			continue
+		}
+		tool := *entry.Tool
+		tool.Name = item.TargetName
+		created, err := s.httpToolService.CreateTool(ctx, &tool)
+		if err != nil {
+			return fmt.Errorf("create HTTP tool %s: %w", item.TargetName, err)
+		}
+		id := created.ID
+		imp.rollbacks = append(imp.rollbacks, func(ctx context.Context) {
+			if err := s.httpToolService.DeleteTool(ctx, id); err != nil {
+				logger.Warnf(ctx, "[Bundle] Failed to roll back HTTP tool %s: %v", id, err)
+			}
+		})
+		item.TargetID = id
+		imp.ids.HTTPTools[entry.Tool.ID] = id
+	}
+
+	for _, entry := range imp.knowledgeBases {
+		item, ok := imp.creates(types.BundleKindKnowledgeBase, entry.KnowledgeBase.ID)
+		if !ok {
+			continue
+		}
+		if err := s.importKnowledgeBase(ctx, imp, entry, item); err != nil {
+			return fmt.Errorf("import knowledge base %s: %w", item.TargetName, err)
+		}
+	}
+
+	return s.importAgents(ctx, imp)
+}
+
+// importAgents creates the planned agents. Agents are created without sub-agents first, so that agents
+// delegating to each other can be created in any order, then their sub-agents are set.
+func (s *bundleService) importAgents(ctx context.Context, imp *bundleImport) error {
+	var created []*types.CustomAgent
+	var subAgents [][]string
+	for _, source := range imp.agents {
+		item, ok := imp.creates(types.BundleKindAgent, source.ID)
+		if !ok {
+			continue
+		}
+		agent := &types.CustomAgent{
+			ID:          uuid.New().String(),
+			Name:        item.TargetName,
+			Description: source.Description,
+			Avatar:      source.Avatar,
+			Config:      source.Config,
+		}
+		imp.ids.Agents[source.ID] = agent.ID
+		item.TargetID = agent.ID
+		created = append(created, agent)
+	}
+	for i, agent := range created {
+		agent.Config.RemapReferences(imp.ids)
+		subAgents = append(subAgents, agent.Config.SubAgents)
+		agent.Config.SubAgents = nil
+		if _, err := s.customAgentService.CreateAgent(ctx, agent); err != nil {
+			return fmt.Errorf("create agent %s: %w", agent.Name, err)
+		}
+		id := created[i].ID
+		imp.rollbacks = append(imp.rollbacks, func(ctx context.Context) {
+			if err := s.customAgentService.DeleteAgent(ctx, id); err != nil {
+				logger.Warnf(ctx, "[Bundle] Failed to roll back agent %s: %v", id, err)
+			}
+		})
+	}
+	for i, agent := range created {
+		if len(subAgents[i]) == 0 {
+			continue
+		}
+		agent.Config.SubAgents = subAgents[i]
+		if _, err := s.customAgentService.UpdateAgent(ctx, agent); err != nil {
+			return fmt.Errorf("set sub agents of agent %s: %w", agent.Name, err)
+		}
+	}
+	return nil
+}
+
+// bundleChunkTarget is the imported copy of a chunk, used to index it
+type bundleChunkTarget struct {
+	id          string
+	knowledgeID string
+	tagID       string
+}
+
+// importKnowledgeBase creates a knowledge base with its tags, knowledge, original documents and chunks,
+// and indexes the chunks from the bundled vectors when they fit its embedding model, otherwise by embedding them
+func (s *bundleService) importKnowledgeBase(ctx context.Context,
+	imp *bundleImport, entry *types.BundleKnowledgeBase, item *types.BundleImportItem,
+) error {
+	source := entry.KnowledgeBase
+	kb := *source
+	kb.ID = ""
+	kb.Name = item.TargetName
+	kb.DeletedAt = types.KnowledgeBase{}.DeletedAt
+	kb.RemapModels(imp.ids.Models)
+	created, err := s.kbService.CreateKnowledgeBase(ctx, &kb)
+	if err != nil {
+		return err
+	}
+	imp.rollbacks = append(imp.rollbacks, func(ctx context.Context) {
+		if err := s.kbService.DeleteKnowledgeBase(ctx, created.ID); err != nil {
+			logger.Warnf(ctx, "[Bundle] Failed to roll back knowledge base %s: %v", created.ID, err)
+		}
+	})
+	item.TargetID = created.ID
+	imp.ids.KnowledgeBases[source.ID] = created.ID
+
+	tagIDs := make(map[string]string, len(entry.Tags))
+	for _, tag := range entry.Tags {
+		if tag == nil {
+			continue
+		}
+		newTag := &types.KnowledgeTag{
+			ID:              uuid.New().String(),
+			TenantID:        imp.tenantID,
+			KnowledgeBaseID: created.ID,
+			Name:            tag.Name,
+			Color:           tag.Color,
+			SortOrder:       tag.SortOrder,
+		}
+		if err := s.tagRepo.Create(ctx, newTag); err != nil {
+			return fmt.Errorf("create tag %s: %w", tag.Name, err)
+		}
+		tagIDs[tag.ID] = newTag.ID
+	}
+
+	knowledgeIDs, err := s.importKnowledge(ctx, imp, source.ID, created, tagIDs)
+	if err != nil {
+		return err
+	}
+
+	// Chunks link to chunks later in the file, so all IDs are assigned before any chunk is created
+	chunkIDs := make(map[string]string)
+	chunksFile := types.BundleKnowledgeBasePath(source.ID, types.BundleChunksFile)
+	if err := readJSONLines(imp, chunksFile, func(chunk *struct {
+		ID          string `json:"id"`
+		KnowledgeID string `json:"knowledge_id"`
+	},
+	) error {
+		if knowledgeIDs[chunk.KnowledgeID] != "" {
+			chunkIDs[chunk.ID] = uuid.New().String()
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	useVectors, dimension, err := s.usableVectors(ctx, imp, entry, created)
+	if err != nil {
+		return err
+	}
+	targets := make(map[string]bundleChunkTarget, len(chunkIDs))
+	batch := make([]*types.Chunk, 0, bundleBatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := s.chunkRepo.CreateChunks(ctx, batch); err != nil {
+			return err
+		}
+		imp.report.ChunkCount += int64(len(batch))
+		if !useVectors {
+			if err := s.reindexChunks(ctx, imp, created, batch); err != nil {
+				return err
+			}
+		}
+		batch = batch[:0]
+		return nil
+	}
+	now := time.Now()
+	if err := readJSONLines(imp, chunksFile, func(chunk *types.Chunk) error {
+		newID, ok := chunkIDs[chunk.ID]
+		if !ok {
+			return nil
+		}
+		target := *chunk
+		target.ID = newID
+		target.SeqID = 0
+		target.TenantID = imp.tenantID
+		target.KnowledgeID = knowledgeIDs[chunk.KnowledgeID]
+		target.KnowledgeBaseID = created.ID
+		target.TagID = tagIDs[chunk.TagID]
+		target.PreChunkID = chunkIDs[chunk.PreChunkID]
+		target.NextChunkID = chunkIDs[chunk.NextChunkID]
+		target.ParentChunkID = chunkIDs[chunk.ParentChunkID]
+		target.RelationChunks = nil
+		target.IndirectRelationChunks = nil
+		target.CreatedAt = now
+		target.UpdatedAt = now
+		target.DeletedAt = types.Chunk{}.DeletedAt
+		targets[chunk.ID] = bundleChunkTarget{id: newID, knowledgeID: target.KnowledgeID, tagID: target.TagID}
+		batch = append(batch, &target)
+		if len(batch) == bundleBatchSize {
+			return flush()
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+
+	if useVectors {
+		return s.importVectors(ctx, imp, entry, created, dimension, targets)
+	}
+	return nil
+}
+
+// importKnowledge creates the knowledge of a knowledge base with their original documents,
+// returning the IDs of the created knowledge by source ID
+func (s *bundleService) importKnowledge(ctx context.Context,
+	imp *bundleImport, sourceKBID string, kb *types.KnowledgeBase, tagIDs map[string]string,
+) (map[string]string, error) {
+	knowledgeIDs := make(map[string]string)
+	var storageSize int64
+	err := readJSONLines(imp, types.BundleKnowledgeBasePath(sourceKBID, types.BundleKnowledgeFile),
+		func(knowledge *types.Knowledge) error {
+			target := *knowledge
+			target.ID = uuid.New().String()
+			target.TenantID = imp.tenantID
+			target.KnowledgeBaseID = kb.ID
+			target.TagID = tagIDs[knowledge.TagID]
+			target.EmbeddingModelID = kb.EmbeddingModelID
+			target.FilePath = ""
+			target.CreatedAt = time.Now()
+			target.UpdatedAt = target.CreatedAt
+			target.DeletedAt = types.Knowledge{}.DeletedAt
+			// The size comes from the stored document, the one in the bundle is not trusted
+			target.StorageSize = 0
+			if file, ok := imp.files[types.BundleDocumentPath(knowledge.ID)]; ok {
+				path, size, err := s.saveDocument(ctx, imp, file, target.FileName)
+				if err != nil {
+					return fmt.Errorf("save document of %s: %w", knowledge.FileName, err)
+				}
+				target.FilePath = path
+				target.StorageSize = size
+			} else if knowledge.FilePath != "" {
+				imp.warn("knowledge %s: original document not included", knowledge.FileName)
+			}
+			if err := s.knowledgeRepo.CreateKnowledge(ctx, &target); err != nil {
+				if target.FilePath != "" {
+					_ = s.fileService.DeleteFile(ctx, target.FilePath)
+				}
+				return err
+			}
+			knowledgeIDs[knowledge.ID] = target.ID
+			storageSize += target.StorageSize
+			imp.report.KnowledgeCount++
+			return nil
+		})
+	// Deleting the knowledge base on rollback releases the storage of every knowledge created so far
+	if storageSize != 0 {
+		if adjustErr := s.tenantRepo.AdjustStorageUsed(ctx, imp.tenantID, storageSize); adjustErr != nil {
+			logger.Warnf(ctx, "[Bundle] Failed to adjust tenant storage: %v", adjustErr)
+		}
+	}
+	return knowledgeIDs, err
+}
+
+// saveDocument stores an original document of the bundle in the storage of the tenant, returning its path
+// and size. Documents are bounded by the upload size limit and must fit the storage quota of the tenant.
+func (s *bundleService) saveDocument(ctx context.Context,
+	imp *bundleImport, file *zip.File, fileName string,
+) (string, int64, error) {
+	maxSize := utils.GetMaxFileSize()
+	if file.UncompressedSize64 > uint64(maxSize) {
+		return "", 0, fmt.Errorf("%w: document %s exceeds %dMB", ErrBundleInvalid, fileName, utils.GetMaxFileSizeMB())
+	}
+	reader, err := file.Open()
+	if err != nil {
+		return "", 0, fmt.Errorf("%w: %v", ErrBundleInvalid, err)
+	}
+	defer reader.Close()
+	// The declared size is not trusted, a larger entry is cut off one byte past the limit
+	data, err := io.ReadAll(io.LimitReader(reader, maxSize+1))
+	if err != nil {
+		return "", 0, fmt.Errorf("%w: %v", ErrBundleInvalid, err)
+	}
+	if int64(len(data)) > maxSize {
+		return "", 0, fmt.Errorf("%w: document %s exceeds %dMB", ErrBundleInvalid, fileName, utils.GetMaxFileSizeMB())
+	}
+	size := int64(len(data))
+	if tenant, ok := ctx.Value(types.TenantInfoContextKey).(*types.Tenant); ok && tenant.StorageQuota > 0 &&
+		tenant.StorageUsed+imp.storageSize+size > tenant.StorageQuota {
+		return "", 0, types.NewStorageQuotaExceededError()
+	}
+	path, err := s.fileService.SaveBytes(ctx, data, imp.tenantID, fileName, false)
+	if err != nil {
+		return "", 0, err
+	}
+	imp.storageSize += size
+	return path, size, nil
+}
+
+// usableVectors reports whether the bundled vectors of a knowledge base fit its embedding model,
+// returning the dimension of the model
+func (s *bundleService) usableVectors(ctx context.Context,
+	imp *bundleImport, entry *types.BundleKnowledgeBase, kb *types.KnowledgeBase,
+) (bool, int, error) {
+	if kb.EmbeddingModelID == "" {
+		if entry.ChunkCount > 0 {
+			imp.warn("knowledge base %s has no embedding model, its chunks are not indexed", kb.Name)
+		}
+		return false, 0, nil
+	}
+	_, ok := imp.files[types.BundleKnowledgeBasePath(entry.KnowledgeBase.ID, types.BundleVectorsFile)]
+	if !ok || entry.VectorDimension == 0 {
+		return false, 0, nil
+	}
+	embeddingModel, err := s.modelService.GetEmbeddingModel(ctx, kb.EmbeddingModelID)
+	if err != nil {
+		return false, 0, err
+	}
+	dimension := embeddingModel.GetDimensions()
+	if dimension != entry.VectorDimension {
+		imp.warn("knowledge base %s: bundled vectors have dimension %d but the embedding model has %d, chunks are embedded again",
+			kb.Name, entry.VectorDimension, dimension)
+		return false, 0, nil
+	}
+	return true, dimension, nil
+}
+
+// reindexChunks embeds and indexes imported chunks
+func (s *bundleService) reindexChunks(ctx context.Context,
+	imp *bundleImport, kb *types.KnowledgeBase, chunks []*types.Chunk,
+) error {
+	if kb.EmbeddingModelID == "" {
+		return nil
+	}
+	if err := s.knowledgeService.ReindexChunks(ctx, kb, chunks); err != nil {
+		return err
+	}
+	imp.report.ReindexedChunkCount += int64(len(chunks))
+	return nil
+}
+
+// importVectors saves the bundled index entries into every engine of the tenant,
+// then embeds the chunks that had no bundled vector
+func (s *bundleService) importVectors(ctx context.Context,
+	imp *bundleImport, entry *types.BundleKnowledgeBase, kb *types.KnowledgeBase,
+	dimension int, targets map[string]bundleChunkTarget,
+) error {
+	tenant := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
+	var engines []interfaces.RetrieveEngineService
+	var engineTypes []types.RetrieverEngineType
+	for _, params := range tenant.GetEffectiveEngines() {
+		if slices.Contains(engineTypes, params.RetrieverEngineType) {
+			continue
+		}
+		engine, err := s.registry.GetRetrieveEngineService(params.RetrieverEngineType)
+		if err != nil {
+			return err
+		}
+		engineTypes = append(engineTypes, params.RetrieverEngineType)
+		engines = append(engines, engine)
+	}
+
+	indexed := make(map[string]bool, len(targets))
+	batch := make([]*types.IndexEntry, 0, bundleBatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		for _, engine := range engines {
+			if err := engine.SaveIndices(ctx, batch); err != nil {
+				return err
+			}
+		}
+		imp.report.VectorCount += int64(len(batch))
+		batch = batch[:0]
+		return nil
+	}
+	vectorsFile := types.BundleKnowledgeBasePath(entry.KnowledgeBase.ID, types.BundleVectorsFile)
+	if err := readJSONLines(imp, vectorsFile, func(vector *types.BundleVector) error {
+		target, ok := targets[vector.ChunkID]
+		if !ok || len(vector.Embedding) != dimension {
+			return nil
+		}
+		indexed[vector.ChunkID] = true
+		batch = append(batch, &types.IndexEntry{
+			IndexInfo: types.IndexInfo{
+				Content:         vector.Content,
+				SourceID:        target.id + strings.TrimPrefix(vector.SourceID, vector.ChunkID),
+				SourceType:      types.ChunkSourceType,
+				ChunkID:         target.id,
+				KnowledgeID:     target.knowledgeID,
+				KnowledgeBaseID: kb.ID,
+				KnowledgeType:   vector.KnowledgeType,
+				TagID:           target.tagID,
+				IsEnabled:       vector.IsEnabled,
+				IsRecommended:   vector.IsRecommended,
+			},
+			Embedding: vector.Embedding,
+		})
+		if len(batch) == bundleBatchSize {
+			return flush()
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+	if len(indexed) == len(targets) {
+		return nil
+	}
+
+	// Chunks without bundled vectors, e.g. added after the vectors were listed, are embedded again
+	chunkIDs := make([]string, 0, len(targets)-len(indexed))
+	for sourceID, target := range targets {
+		if !indexed[sourceID] {
+			chunkIDs = append(chunkIDs, target.id)
+		}
+	}
+	for ids := range slices.Chunk(chunkIDs, bundleBatchSize) {
+		chunks, err := s.chunkRepo.ListChunksByID(ctx, imp.tenantID, ids)
+		if err != nil {
+			return err
+		}
+		if err := s.reindexChunks(ctx, imp, kb, chunks); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,449 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/application/repository"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"github.com/google/uuid"
+)
+
+// bundleTestStore holds the objects of one tenant for the fake services and repositories of a bundle service
+type bundleTestStore struct {
+	tenantID  uint64
+	agents    []*types.CustomAgent
+	kbs       []*types.KnowledgeBase
+	knowledge []*types.Knowledge
+	chunks    []*types.Chunk
+	tags      []*types.KnowledgeTag
+}
+
+type fakeBundleAgentService struct {
+	interfaces.CustomAgentService
+	store *bundleTestStore
+}
+
+func (f *fakeBundleAgentService) GetLiveAgent(ctx context.Context, id string) (*types.CustomAgent, error) {
+	for _, agent := range f.store.agents {
+		if agent.ID == id {
+			copied := *agent
+			return &copied, nil
+		}
+	}
+	return nil, ErrAgentNotFound
+}
+
+func (f *fakeBundleAgentService) ListAgents(ctx context.Context) ([]*types.CustomAgent, error) {
+	return f.store.agents, nil
+}
+
+func (f *fakeBundleAgentService) CreateAgent(ctx context.Context, agent *types.CustomAgent) (*types.CustomAgent, error) {
+	copied := *agent
+	copied.TenantID = f.store.tenantID
+	f.store.agents = append(f.store.agents, &copied)
+	return &copied, nil
+}
+
+func (f *fakeBundleAgentService) UpdateAgent(ctx context.Context, agent *types.CustomAgent) (*types.CustomAgent, error) {
+	for i, existing := range f.store.agents {
+		if existing.ID == agent.ID {
+			copied := *agent
+			f.store.agents[i] = &copied
+			return &copied, nil
+		}
+	}
+	return nil, ErrAgentNotFound
+}
+
+type fakeBundleKBService struct {
+	interfaces.KnowledgeBaseService
+	store *bundleTestStore
+}
+
+func (f *fakeBundleKBService) ListKnowledgeBases(ctx context.Context) ([]*types.KnowledgeBase, error) {
+	return f.store.kbs, nil
+}
+
+func (f *fakeBundleKBService) CreateKnowledgeBase(ctx context.Context, kb *types.KnowledgeBase) (*types.KnowledgeBase, error) {
+	kb.ID = uuid.New().String()
+	kb.TenantID = f.store.tenantID
+	f.store.kbs = append(f.store.kbs, kb)
+	return kb, nil
+}
+
+type fakeBundleKBRepository struct {
+	interfaces.KnowledgeBaseRepository
+	store *bundleTestStore
+}
+
+func (f *fakeBundleKBRepository) GetKnowledgeBaseByIDAndTenant(ctx context.Context,
+	id string, tenantID uint64,
+) (*types.KnowledgeBase, error) {
+	for _, kb := range f.store.kbs {
+		if kb.ID == id && kb.TenantID == tenantID {
+			copied := *kb
+			return &copied, nil
+		}
+	}
+	return nil, repository.ErrKnowledgeBaseNotFound
+}
+
+type fakeBundleKnowledgeRepository struct {
+	interfaces.KnowledgeRepository
+	store *bundleTestStore
+}
+
+func (f *fakeBundleKnowledgeRepository) ListKnowledgeByKnowledgeBaseID(ctx context.Context,
+	tenantID uint64, kbID string,
+) ([]*types.Knowledge, error) {
+	var result []*types.Knowledge
+	for _, knowledge := range f.store.knowledge {
+		if knowledge.TenantID == tenantID && knowledge.KnowledgeBaseID == kbID {
+			result = append(result, knowledge)
+		}
+	}
+	return result, nil
+}
+
+func (f *fakeBundleKnowledgeRepository) CreateKnowledge(ctx context.Context, knowledge *types.Knowledge) error {
+	f.store.knowledge = append(f.store.knowledge, knowledge)
+	return nil
+}
+
+type fakeBundleChunkRepository struct {
+	interfaces.ChunkRepository
+	store *bundleTestStore
+}
+
+func (f *fakeBundleChunkRepository) ListChunksForExport(ctx context.Context,
+	tenantID uint64, kbID string, afterID string, limit int,
+) ([]*types.Chunk, error) {
+	var result []*types.Chunk
+	for _, chunk := range f.store.chunks {
+		if chunk.TenantID == tenantID && chunk.KnowledgeBaseID == kbID && chunk.ID > afterID {
+			result = append(result, chunk)
+		}
+	}
+	slices.SortFunc(result, func(a, b *types.Chunk) int { return strings.Compare(a.ID, b.ID) })
+	return result[:min(limit, len(result))], nil
+}
+
+func (f *fakeBundleChunkRepository) CreateChunks(ctx context.Context, chunks []*types.Chunk) error {
+	f.store.chunks = append(f.store.chunks, chunks...)
+	return nil
+}
+
+type fakeBundleTagRepository struct {
+	interfaces.KnowledgeTagRepository
+	store *bundleTestStore
+}
+
+func (f *fakeBundleTagRepository) ListByKB(ctx context.Context,
+	tenantID uint64, kbID string, page *types.Pagination, keyword string,
+) ([]*types.KnowledgeTag, int64, error) {
+	var result []*types.KnowledgeTag
+	for _, tag := range f.store.tags {
+		if tag.TenantID == tenantID && tag.KnowledgeBaseID == kbID {
+			result = append(result, tag)
+		}
+	}
+	return result, int64(len(result)), nil
+}
+
+func (f *fakeBundleTagRepository) Create(ctx context.Context, tag *types.KnowledgeTag) error {
+	f.store.tags = append(f.store.tags, tag)
+	return nil
+}
+
+type fakeBundleModelService struct {
+	interfaces.ModelService
+}
+
+func (f *fakeBundleModelService) ListModels(ctx context.Context) ([]*types.Model, error) {
+	return nil, nil
+}
+
+// newBundleTestService returns a bundle service working on the objects of store
+func newBundleTestService(store *bundleTestStore) *bundleService {
+	return &bundleService{
+		customAgentService: &fakeBundleAgentService{store: store},
+		kbService:          &fakeBundleKBService{store: store},
+		kbRepo:             &fakeBundleKBRepository{store: store},
+		knowledgeRepo:      &fakeBundleKnowledgeRepository{store: store},
+		chunkRepo:          &fakeBundleChunkRepository{store: store},
+		tagRepo:            &fakeBundleTagRepository{store: store},
+		modelService:       &fakeBundleModelService{},
+	}
+}
+
+// newBundleSourceStore returns a tenant with an agent delegating to a sub-agent, both searching a knowledge base
+// whose knowledge and chunks are tagged
+func newBundleSourceStore() *bundleTestStore {
+	store := &bundleTestStore{tenantID: 1}
+	store.agents = []*types.CustomAgent{
+		{ID: "agent-support", TenantID: 1, Name: "Support", Config: types.CustomAgentConfig{
+			KnowledgeBases: []string{"kb-manual"},
+			SubAgents:      []string{"agent-helper"},
+		}},
+		{ID: "agent-helper", TenantID: 1, Name: "Helper", Config: types.CustomAgentConfig{
+			KnowledgeBases: []string{"kb-manual"},
+		}},
+	}
+	store.kbs = []*types.KnowledgeBase{{ID: "kb-manual", TenantID: 1, Name: "Manual"}}
+	store.tags = []*types.KnowledgeTag{{ID: "tag-faq", TenantID: 1, KnowledgeBaseID: "kb-manual", Name: "FAQ"}}
+	store.knowledge = []*types.Knowledge{
+		{ID: "knowledge-1", TenantID: 1, KnowledgeBaseID: "kb-manual", TagID: "tag-faq",
+			Title: "Guide", ParseStatus: types.ParseStatusCompleted},
+		{ID: "knowledge-parsing", TenantID: 1, KnowledgeBaseID: "kb-manual", ParseStatus: "processing"},
+	}
+	for i := 1; i <= 5; i++ {
+		chunk := &types.Chunk{
+			ID:              fmt.Sprintf("chunk-%d", i),
+			TenantID:        1,
+			KnowledgeID:     "knowledge-1",
+			KnowledgeBaseID: "kb-manual",
+			TagID:           "tag-faq",
+			Content:         fmt.Sprintf("content %d", i),
+			ChunkIndex:      i,
+			IsEnabled:       true,
+			// Each chunk has metadata of a different length, so that a decoder buffer shared
+			// between the lines would show up as corrupted metadata
+			Metadata: types.JSON(fmt.Sprintf(`{"page":%d,"question":"%s"}`, i, strings.Repeat("q", 10*i))),
+		}
+		if i > 1 {
+			chunk.PreChunkID = fmt.Sprintf("chunk-%d", i-1)
+			chunk.ParentChunkID = "chunk-1"
+		}
+		if i < 5 {
+			chunk.NextChunkID = fmt.Sprintf("chunk-%d", i+1)
+		}
+		store.chunks = append(store.chunks, chunk)
+	}
+	store.chunks = append(store.chunks, &types.Chunk{
+		ID: "chunk-parsing", TenantID: 1, KnowledgeID: "knowledge-parsing", KnowledgeBaseID: "kb-manual",
+	})
+	return store
+}
+
+// exportBundleTest exports the support agent with its knowledge
+func exportBundleTest(t *testing.T) []byte {
+	t.Helper()
+	ctx := context.WithValue(context.Background(), types.TenantIDContextKey, uint64(1))
+	var buf bytes.Buffer
+	err := newBundleTestService(newBundleSourceStore()).
+		ExportAgent(ctx, "agent-support", &types.BundleExportOptions{IncludeKnowledge: true}, &buf)
+	if err != nil {
+		t.Fatalf("ExportAgent() = %v", err)
+	}
+	return buf.Bytes()
+}
+
+// findAgent returns the agent of a store with a name
+func (s *bundleTestStore) findAgent(name string) *types.CustomAgent {
+	for _, agent := range s.agents {
+		if agent.Name == name {
+			return agent
+		}
+	}
+	return nil
+}
+
+// findKB returns the knowledge base of a store with a name
+func (s *bundleTestStore) findKB(name string) *types.KnowledgeBase {
+	for _, kb := range s.kbs {
+		if kb.Name == name {
+			return kb
+		}
+	}
+	return nil
+}
+
+// compactJSON returns data without insignificant whitespace
+func compactJSON(t *testing.T, data []byte) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err != nil {
+		t.Fatalf("invalid JSON %q: %v", data, err)
+	}
+	return buf.String()
+}
+
+func TestBundleRoundTripRemapsReferences(t *testing.T) {
+	bundle := exportBundleTest(t)
+	source := newBundleSourceStore()
+	target := &bundleTestStore{tenantID: 2}
+	ctx := context.WithValue(context.Background(), types.TenantIDContextKey, uint64(2))
+
+	report, err := newBundleTestService(target).Import(ctx, bytes.NewReader(bundle), int64(len(bundle)),
+		&types.BundleImportOptions{})
+	if err != nil {
+		t.Fatalf("Import() = %v", err)
+	}
+	if !report.Imported || report.KnowledgeCount != 1 || report.ChunkCount != 5 {
+		t.Errorf("report = %+v, want 1 knowledge and 5 chunks imported", report)
+	}
+
+	support, helper, kb := target.findAgent("Support"), target.findAgent("Helper"), target.findKB("Manual")
+	if support == nil || helper == nil || kb == nil || len(target.tags) != 1 || len(target.knowledge) != 1 {
+		t.Fatalf("imported agents %v, knowledge bases %v, tags %v, knowledge %v",
+			target.agents, target.kbs, target.tags, target.knowledge)
+	}
+	if support.ID == "agent-support" || helper.ID == "agent-helper" || kb.ID == "kb-manual" || kb.TenantID != 2 {
+		t.Errorf("imported objects kept their source IDs: %s, %s, %s", support.ID, helper.ID, kb.ID)
+	}
+
+	// Agent to knowledge base and sub-agent
+	if !slices.Equal(support.Config.KnowledgeBases, []string{kb.ID}) || !slices.Equal(helper.Config.KnowledgeBases, []string{kb.ID}) {
+		t.Errorf("agent knowledge bases = %v and %v, want [%s]", support.Config.KnowledgeBases, helper.Config.KnowledgeBases, kb.ID)
+	}
+	if !slices.Equal(support.Config.SubAgents, []string{helper.ID}) {
+		t.Errorf("sub agents = %v, want [%s]", support.Config.SubAgents, helper.ID)
+	}
+
+	// Knowledge base to tag, knowledge to knowledge base and tag
+	tag := target.tags[0]
+	if tag.ID == "tag-faq" || tag.KnowledgeBaseID != kb.ID || tag.TenantID != 2 || tag.Name != "FAQ" {
+		t.Errorf("tag = %+v, want FAQ of knowledge base %s", tag, kb.ID)
+	}
+	knowledge := target.knowledge[0]
+	if knowledge.ID == "knowledge-1" || knowledge.KnowledgeBaseID != kb.ID || knowledge.TagID != tag.ID || knowledge.Title != "Guide" {
+		t.Errorf("knowledge = %+v, want Guide in knowledge base %s with tag %s", knowledge, kb.ID, tag.ID)
+	}
+
+	// Chunk to knowledge, tag and the chunks it links to, metadata unchanged
+	if len(target.chunks) != 5 {
+		t.Fatalf("imported %d chunks, want 5", len(target.chunks))
+	}
+	newIDs := make(map[string]string)
+	for _, chunk := range target.chunks {
+		newIDs[fmt.Sprintf("chunk-%d", chunk.ChunkIndex)] = chunk.ID
+	}
+	for _, chunk := range target.chunks {
+		want := source.chunks[chunk.ChunkIndex-1]
+		if chunk.ID == want.ID || chunk.KnowledgeID != knowledge.ID || chunk.KnowledgeBaseID != kb.ID ||
+			chunk.TagID != tag.ID || chunk.TenantID != 2 || chunk.Content != want.Content {
+			t.Errorf("chunk %d = %+v, want it in knowledge %s with tag %s", chunk.ChunkIndex, chunk, knowledge.ID, tag.ID)
+		}
+		if chunk.PreChunkID != newIDs[want.PreChunkID] || chunk.NextChunkID != newIDs[want.NextChunkID] ||
+			chunk.ParentChunkID != newIDs[want.ParentChunkID] {
+			t.Errorf("chunk %d links pre %q, next %q, parent %q, want the imported chunks of %q, %q, %q",
+				chunk.ChunkIndex, chunk.PreChunkID, chunk.NextChunkID, chunk.ParentChunkID,
+				want.PreChunkID, want.NextChunkID, want.ParentChunkID)
+		}
+		if got, want := compactJSON(t, chunk.Metadata), compactJSON(t, want.Metadata); got != want {
+			t.Errorf("chunk %d metadata = %s, want %s", chunk.ChunkIndex, got, want)
+		}
+	}
+}
+
+func TestBundleImportConflictModes(t *testing.T) {
+	bundle := exportBundleTest(t)
+	// The target tenant already has an agent and a knowledge base with the names of the bundle
+	newTarget := func() *bundleTestStore {
+		return &bundleTestStore{
+			tenantID: 2,
+			agents:   []*types.CustomAgent{{ID: "existing-support", TenantID: 2, Name: "Support"}},
+			kbs:      []*types.KnowledgeBase{{ID: "existing-manual", TenantID: 2, Name: "Manual"}},
+		}
+	}
+	ctx := context.WithValue(context.Background(), types.TenantIDContextKey, uint64(2))
+
+	tests := []struct {
+		name       string
+		mode       string
+		dryRun     bool
+		err        error
+		conflicts  int
+		agentNames []string
+		kbNames    []string
+		// Knowledge base the imported helper agent searches
+		helperKB string
+		chunks   int
+	}{
+		{
+			name:       "fail",
+			mode:       types.BundleConflictFail,
+			err:        ErrBundleConflict,
+			conflicts:  2,
+			agentNames: []string{"Support"},
+			kbNames:    []string{"Manual"},
+		},
+		{
+			name:       "dry run reports without writing",
+			mode:       types.BundleConflictRename,
+			dryRun:     true,
+			agentNames: []string{"Support"},
+			kbNames:    []string{"Manual"},
+		},
+		{
+			name:       "rename",
+			mode:       types.BundleConflictRename,
+			agentNames: []string{"Support", "Support (2)", "Helper"},
+			kbNames:    []string{"Manual", "Manual (2)"},
+			helperKB:   "Manual (2)",
+			chunks:     5,
+		},
+		{
+			name:       "reuse",
+			mode:       types.BundleConflictReuse,
+			agentNames: []string{"Support", "Helper"},
+			kbNames:    []string{"Manual"},
+			helperKB:   "Manual",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := newTarget()
+			report, err := newBundleTestService(target).Import(ctx, bytes.NewReader(bundle), int64(len(bundle)),
+				&types.BundleImportOptions{OnConflict: tt.mode, DryRun: tt.dryRun})
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Import() = %v, want %v", err, tt.err)
+			}
+			if report == nil || len(report.Conflicts) != tt.conflicts || report.Imported == (tt.err != nil || tt.dryRun) {
+				t.Fatalf("report = %+v, want %d conflicts", report, tt.conflicts)
+			}
+
+			var agentNames, kbNames []string
+			for _, agent := range target.agents {
+				agentNames = append(agentNames, agent.Name)
+			}
+			for _, kb := range target.kbs {
+				kbNames = append(kbNames, kb.Name)
+			}
+			if !slices.Equal(agentNames, tt.agentNames) || !slices.Equal(kbNames, tt.kbNames) {
+				t.Errorf("agents %v, knowledge bases %v, want %v, %v", agentNames, kbNames, tt.agentNames, tt.kbNames)
+			}
+			if len(target.chunks) != tt.chunks {
+				t.Errorf("imported %d chunks, want %d", len(target.chunks), tt.chunks)
+			}
+			if tt.helperKB == "" {
+				return
+			}
+			helper, kb := target.findAgent("Helper"), target.findKB(tt.helperKB)
+			if !slices.Equal(helper.Config.KnowledgeBases, []string{kb.ID}) {
+				t.Errorf("helper searches %v, want %s (%s)", helper.Config.KnowledgeBases, tt.helperKB, kb.ID)
+			}
+			if tt.mode != types.BundleConflictReuse {
+				return
+			}
+			// The reused agent is left as it is
+			if support := target.findAgent("Support"); support.ID != "existing-support" || len(support.Config.SubAgents) != 0 {
+				t.Errorf("reused agent = %+v, want it unchanged", support)
+			}
+			for _, item := range report.Items {
+				if item.Kind == types.BundleKindAgent && item.Name == "Support" &&
+					(item.Action != types.BundleActionReuse || item.TargetID != "existing-support") {
+					t.Errorf("report item of the support agent = %+v, want a reuse of existing-support", item)
+				}
+			}
+		})
+	}
+}
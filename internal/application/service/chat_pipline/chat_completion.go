@@ -3,6 +3,7 @@ package chatpipline
 import (
 	"context"
 
+	"github.com/Tencent/WeKnora/internal/common"
 	"github.com/Tencent/WeKnora/internal/types"
 	"github.com/Tencent/WeKnora/internal/types/interfaces"
 )
@@ -72,5 +73,22 @@ func (p *PluginChatCompletion) OnEvent(
 		"prompt_tokens":     chatResponse.Usage.PromptTokens,
 	})
 	chatManage.ChatResponse = chatResponse
+
+	// Validate the answer against the output schema, asking the model to repair it if needed
+	if len(chatManage.OutputSchema) > 0 {
+		schema, err := chatManage.OutputSchema.Compile()
+		if err != nil {
+			return ErrOutputSchema.WithError(err)
+		}
+		output, raw, err := common.RepairStructuredOutput(ctx, chatModel, chatMessages, opt, schema, chatResponse.Content)
+		if err != nil {
+			pipelineError(ctx, "Completion", "structured_output", map[string]interface{}{
+				"error": err.Error(),
+			})
+			return ErrStructuredOutput.WithError(err)
+		}
+		chatResponse.Content = raw
+		chatManage.StructuredOutput = output
+	}
 	return next()
 }
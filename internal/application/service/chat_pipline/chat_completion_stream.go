@@ -5,10 +5,13 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/Tencent/WeKnora/internal/common"
 	"github.com/Tencent/WeKnora/internal/event"
 	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/models/chat"
 	"github.com/Tencent/WeKnora/internal/types"
 	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	jsonschema "github.com/google/jsonschema-go/jsonschema"
 	"github.com/google/uuid"
 )
 
@@ -52,6 +55,14 @@ func (p *PluginChatCompletionStream) OnEvent(ctx context.Context,
 	if err != nil {
 		return ErrGetChatModel.WithError(err)
 	}
+	var schema *jsonschema.Resolved
+	if len(chatManage.OutputSchema) > 0 {
+		if schema, err = chatManage.OutputSchema.Compile(); err != nil {
+			return ErrOutputSchema.WithError(err)
+		}
+	}
+	// Citations are checked once the whole answer is streamed, a JSON answer cites nothing
+	checkCitations := schema == nil && len(chatManage.CitationSources) > 0
 
 	// Prepare base messages without history
 
@@ -106,6 +117,7 @@ func (p *PluginChatCompletionStream) OnEvent(ctx context.Context,
 		var finalContent string
 		var thinkingStarted bool
 		var thinkingEnded bool
+		var answerDone bool
 
 		for response := range responseChan {
 			// Handle error responses from the stream
@@ -174,13 +186,16 @@ func (p *PluginChatCompletionStream) OnEvent(ctx context.Context,
 					}
 				}
 				finalContent += response.Content
+				answerDone = answerDone || response.Done
 				if err := eventBus.Emit(ctx, types.Event{
 					ID:        answerID,
 					Type:      types.EventType(event.EventAgentFinalAnswer),
 					SessionID: chatManage.SessionID,
 					Data: event.AgentFinalAnswerData{
 						Content: response.Content,
-						Done:    response.Done,
+						// With an output schema the answer is done once it has been validated,
+						// with citations once they have been checked
+						Done: response.Done && schema == nil && !checkCitations,
 					},
 				}); err != nil {
 					logger.Errorf(ctx, "Failed to emit answer event: %v", err)
@@ -188,6 +203,33 @@ func (p *PluginChatCompletionStream) OnEvent(ctx context.Context,
 			}
 		}
 
+		if (schema != nil || checkCitations) && answerDone {
+			if schema != nil {
+				emitStructuredOutput(ctx, eventBus, chatManage.SessionID,
+					chatModel, chatMessages, opt, schema, finalContent)
+			}
+			var citations *types.CitationReport
+			if checkCitations {
+				var verifier chat.Chat
+				if chatManage.CitationModelCheck {
+					verifier = chatModel
+				}
+				citations = common.CheckCitations(ctx, finalContent, chatManage.CitationSources, verifier)
+			}
+			if err := eventBus.Emit(ctx, types.Event{
+				ID:        answerID,
+				Type:      types.EventType(event.EventAgentFinalAnswer),
+				SessionID: chatManage.SessionID,
+				Data: event.AgentFinalAnswerData{
+					Content:   "",
+					Done:      true,
+					Citations: citations,
+				},
+			}); err != nil {
+				logger.Errorf(ctx, "Failed to emit answer done event: %v", err)
+			}
+		}
+
 		pipelineInfo(ctx, "Stream", "channel_close", map[string]interface{}{
 			"session_id": chatManage.SessionID,
 		})
@@ -195,3 +237,29 @@ func (p *PluginChatCompletionStream) OnEvent(ctx context.Context,
 
 	return next()
 }
+
+// emitStructuredOutput validates the streamed answer against the output schema,
+// repairs it with the model when needed and emits the outcome
+func emitStructuredOutput(ctx context.Context, eventBus types.EventBusInterface, sessionID string,
+	chatModel chat.Chat, chatMessages []chat.Message, opt *chat.ChatOptions,
+	schema *jsonschema.Resolved, content string,
+) {
+	data := event.StructuredOutputData{}
+	output, raw, err := common.RepairStructuredOutput(ctx, chatModel, chatMessages, opt, schema, content)
+	data.Output, data.Raw = output, raw
+	if err != nil {
+		pipelineError(ctx, "Stream", "structured_output", map[string]interface{}{
+			"session_id": sessionID,
+			"error":      err.Error(),
+		})
+		data.Error = err.Error()
+	}
+	if err := eventBus.Emit(ctx, types.Event{
+		ID:        fmt.Sprintf("%s-structured-output", uuid.New().String()[:8]),
+		Type:      types.EventType(event.EventStructuredOutput),
+		SessionID: sessionID,
+		Data:      data,
+	}); err != nil {
+		logger.Errorf(ctx, "Failed to emit structured output event: %v", err)
+	}
+}
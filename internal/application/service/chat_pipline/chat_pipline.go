@@ -2,7 +2,9 @@ package chatpipline
 
 import (
 	"context"
+	"time"
 
+	"github.com/Tencent/WeKnora/internal/metrics"
 	"github.com/Tencent/WeKnora/internal/types"
 )
 
@@ -72,7 +74,18 @@ func (e *EventManager) Trigger(ctx context.Context,
 	eventType types.EventType, chatManage *types.ChatManage,
 ) *PluginError {
 	if handler, ok := e.handlers[eventType]; ok {
-		return handler(ctx, eventType, chatManage)
+		start := time.Now()
+		err := handler(ctx, eventType, chatManage)
+		// Label failures with the plugin error type, e.g. search_nothing
+		result := metrics.ResultSuccess
+		if err != nil {
+			result = metrics.ResultError
+			if err.ErrorType != "" {
+				result = err.ErrorType
+			}
+		}
+		metrics.ObservePipelineStage(string(eventType), result, time.Since(start))
+		return err
 	}
 	return nil
 }
@@ -122,6 +135,14 @@ var (
 		Description: "Failed to get conversation history",
 		ErrorType:   "get_history_failed",
 	}
+	ErrOutputSchema = &PluginError{
+		Description: "Invalid output schema",
+		ErrorType:   "invalid_output_schema",
+	}
+	ErrStructuredOutput = &PluginError{
+		Description: "Answer does not conform to the output schema",
+		ErrorType:   "structured_output_invalid",
+	}
 )
 
 // clone creates a copy of the PluginError
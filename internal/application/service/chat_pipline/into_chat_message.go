@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/Tencent/WeKnora/internal/common"
 	"github.com/Tencent/WeKnora/internal/types"
 	"github.com/Tencent/WeKnora/internal/utils"
 )
@@ -78,27 +79,31 @@ func (p *PluginIntoChatMessage) OnEvent(ctx context.Context,
 	weekdayName := []string{"星期日", "星期一", "星期二", "星期三", "星期四", "星期五", "星期六"}
 
 	var contextsBuilder strings.Builder
+	chatManage.CitationSources = nil
 
 	// Build contexts string based on FAQ priority strategy
 	if chatManage.FAQPriorityEnabled && len(faqResults) > 0 {
 		// Build structured context with FAQ prioritization
 		contextsBuilder.WriteString("### 资料来源 1：标准问答库 (FAQ)\n")
 		contextsBuilder.WriteString("【高置信度 - 请优先参考】\n")
+		// FAQ and documents share one numbering so that the answer can cite them as [n]
 		for i, result := range faqResults {
 			passage := getEnrichedPassageForChat(ctx, result)
+			chatManage.CitationSources = append(chatManage.CitationSources, result)
 			if hasHighConfidenceFAQ && i == 0 {
-				contextsBuilder.WriteString(fmt.Sprintf("[FAQ-%d] ⭐ 精准匹配: %s\n", i+1, passage))
+				contextsBuilder.WriteString(fmt.Sprintf("[%d] ⭐ FAQ 精准匹配: %s\n", len(chatManage.CitationSources), passage))
 			} else {
-				contextsBuilder.WriteString(fmt.Sprintf("[FAQ-%d] %s\n", i+1, passage))
+				contextsBuilder.WriteString(fmt.Sprintf("[%d] FAQ: %s\n", len(chatManage.CitationSources), passage))
 			}
 		}
 
 		if len(docResults) > 0 {
 			contextsBuilder.WriteString("\n### 资料来源 2：参考文档\n")
 			contextsBuilder.WriteString("【补充资料 - 仅在FAQ无法解答时参考】\n")
-			for i, result := range docResults {
+			for _, result := range docResults {
 				passage := getEnrichedPassageForChat(ctx, result)
-				contextsBuilder.WriteString(fmt.Sprintf("[DOC-%d] %s\n", i+1, passage))
+				chatManage.CitationSources = append(chatManage.CitationSources, result)
+				contextsBuilder.WriteString(fmt.Sprintf("[%d] %s\n", len(chatManage.CitationSources), passage))
 			}
 		}
 	} else {
@@ -113,6 +118,7 @@ func (p *PluginIntoChatMessage) OnEvent(ctx context.Context,
 			}
 			contextsBuilder.WriteString(fmt.Sprintf("[%d] %s", i+1, passage))
 		}
+		chatManage.CitationSources = chatManage.MergeResult
 	}
 
 	// Replace placeholders in context template
@@ -121,6 +127,10 @@ func (p *PluginIntoChatMessage) OnEvent(ctx context.Context,
 	userContent = strings.ReplaceAll(userContent, "{{contexts}}", contextsBuilder.String())
 	userContent = strings.ReplaceAll(userContent, "{{current_time}}", time.Now().Format("2006-01-02 15:04:05"))
 	userContent = strings.ReplaceAll(userContent, "{{current_week}}", weekdayName[time.Now().Weekday()])
+	// Ask for inline citations, unless the answer must be a JSON object
+	if len(chatManage.CitationSources) > 0 && len(chatManage.OutputSchema) == 0 {
+		userContent += "\n\n" + common.CitationInstruction
+	}
 
 	// Set formatted content back to chat management
 	chatManage.UserContent = userContent
@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strings"
 	"time"
 
@@ -19,17 +20,23 @@ var (
 	ErrCannotModifyBuiltin = errors.New("cannot modify built-in agent basic info")
 	ErrCannotDeleteBuiltin = errors.New("cannot delete built-in agent")
 	ErrAgentNameRequired   = errors.New("agent name is required")
+	ErrInvalidAgentConfig  = errors.New("invalid agent config")
 )
 
 // customAgentService implements the CustomAgentService interface
 type customAgentService struct {
-	repo interfaces.CustomAgentRepository
+	repo        interfaces.CustomAgentRepository
+	versionRepo interfaces.AgentVersionRepository
 }
 
 // NewCustomAgentService creates a new custom agent service
-func NewCustomAgentService(repo interfaces.CustomAgentRepository) interfaces.CustomAgentService {
+func NewCustomAgentService(
+	repo interfaces.CustomAgentRepository,
+	versionRepo interfaces.AgentVersionRepository,
+) interfaces.CustomAgentService {
 	return &customAgentService{
-		repo: repo,
+		repo:        repo,
+		versionRepo: versionRepo,
 	}
 }
 
@@ -39,6 +46,21 @@ func (s *customAgentService) CreateAgent(ctx context.Context, agent *types.Custo
 	if strings.TrimSpace(agent.Name) == "" {
 		return nil, ErrAgentNameRequired
 	}
+	if err := agent.Config.ValidateMCPContextSources(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAgentConfig, err)
+	}
+	if err := agent.Config.ValidateToolApproval(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAgentConfig, err)
+	}
+	if err := agent.Config.ValidateWorkflow(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAgentConfig, err)
+	}
+	if err := agent.Config.ValidateOutputSchema(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAgentConfig, err)
+	}
+	if err := s.validateSubAgents(ctx, agent); err != nil {
+		return nil, err
+	}
 
 	// Generate UUID and set creation timestamps
 	if agent.ID == "" {
@@ -82,6 +104,26 @@ func (s *customAgentService) CreateAgent(ctx context.Context, agent *types.Custo
 	return agent, nil
 }
 
+// validateSubAgents checks that the agents an agent delegates to exist and run in agent mode
+func (s *customAgentService) validateSubAgents(ctx context.Context, agent *types.CustomAgent) error {
+	if err := agent.Config.ValidateSubAgents(agent.ID); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidAgentConfig, err)
+	}
+	for _, id := range agent.Config.SubAgents {
+		subAgent, err := s.GetAgentByID(ctx, id)
+		if err != nil {
+			if errors.Is(err, ErrAgentNotFound) {
+				return fmt.Errorf("%w: sub agent %s not found", ErrInvalidAgentConfig, id)
+			}
+			return err
+		}
+		if subAgent.Config.AgentMode != types.AgentModeSmartReasoning {
+			return fmt.Errorf("%w: sub agent %s is not in smart-reasoning mode", ErrInvalidAgentConfig, id)
+		}
+	}
+	return nil
+}
+
 // GetAgentByID retrieves an agent by its ID (including built-in agents)
 func (s *customAgentService) GetAgentByID(ctx context.Context, id string) (*types.CustomAgent, error) {
 	if id == "" {
@@ -124,6 +166,16 @@ func (s *customAgentService) GetAgentByID(ctx context.Context, id string) (*type
 	return agent, nil
 }
 
+// GetLiveAgent retrieves an agent by ID as it answers in conversations: its published version,
+// or its draft when it has never been published
+func (s *customAgentService) GetLiveAgent(ctx context.Context, id string) (*types.CustomAgent, error) {
+	agent, err := s.GetAgentByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return applyAgentVersion(ctx, s.versionRepo, agent, agent.PublishedVersion)
+}
+
 // GetAgentByIDAndTenant retrieves an agent by ID and tenant (for shared agents; does not resolve built-in)
 func (s *customAgentService) GetAgentByIDAndTenant(ctx context.Context, id string, tenantID uint64) (*types.CustomAgent, error) {
 	if id == "" {
@@ -209,6 +261,22 @@ func (s *customAgentService) UpdateAgent(ctx context.Context, agent *types.Custo
 		return nil, ErrInvalidTenantID
 	}
 
+	if err := agent.Config.ValidateMCPContextSources(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAgentConfig, err)
+	}
+	if err := agent.Config.ValidateToolApproval(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAgentConfig, err)
+	}
+	if err := agent.Config.ValidateWorkflow(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAgentConfig, err)
+	}
+	if err := agent.Config.ValidateOutputSchema(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAgentConfig, err)
+	}
+	if err := s.validateSubAgents(ctx, agent); err != nil {
+		return nil, err
+	}
+
 	// Handle built-in agents specially using registry
 	if types.IsBuiltinAgentID(agent.ID) {
 		return s.updateBuiltinAgent(ctx, agent, tenantID)
@@ -165,3 +165,9 @@ func (s *cosFileService) GetFileURL(ctx context.Context, filePath string) (strin
 
 	return presignedURL.String(), nil
 }
+
+// Ping checks that the COS bucket is reachable
+func (s *cosFileService) Ping(ctx context.Context) error {
+	_, err := s.client.Bucket.Head(ctx)
+	return err
+}
@@ -46,3 +46,8 @@ func (s *DummyFileService) SaveBytes(ctx context.Context, data []byte, tenantID
 func (s *DummyFileService) GetFileURL(ctx context.Context, filePath string) (string, error) {
 	return filePath, nil
 }
+
+// Ping always succeeds as dummy service has no backend
+func (s *DummyFileService) Ping(ctx context.Context) error {
+	return nil
+}
@@ -144,3 +144,12 @@ func (s *localFileService) GetFileURL(ctx context.Context, filePath string) (str
 	// Local storage doesn't support URLs, return the path
 	return filePath, nil
 }
+
+// Ping checks that the base directory is accessible. A missing directory is fine,
+// it is created on the first save.
+func (s *localFileService) Ping(ctx context.Context) error {
+	if _, err := os.Stat(s.baseDir); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("storage directory is not accessible: %w", err)
+	}
+	return nil
+}
@@ -167,3 +167,15 @@ func (s *minioFileService) GetFileURL(ctx context.Context, filePath string) (str
 
 	return presignedURL.String(), nil
 }
+
+// Ping checks that the MinIO bucket is reachable
+func (s *minioFileService) Ping(ctx context.Context) error {
+	exists, err := s.client.BucketExists(ctx, s.bucketName)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("bucket %s does not exist", s.bucketName)
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Tencent/WeKnora/docreader/client"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"github.com/neo4j/neo4j-go-driver/v6/neo4j"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const (
+	// healthProbeTimeout bounds each dependency probe
+	healthProbeTimeout = 3 * time.Second
+	// healthCacheTTL is how long a readiness report is reused
+	healthCacheTTL = 5 * time.Second
+)
+
+// healthProbe is a named dependency check
+type healthProbe struct {
+	name     string
+	required bool
+	check    func(ctx context.Context) error
+}
+
+// healthService probes the database, redis, docreader, retriever engines, neo4j and file storage
+type healthService struct {
+	probes []healthProbe
+
+	mu     sync.Mutex
+	cached *types.HealthReport
+}
+
+// NewHealthService creates a health service.
+// The database, redis and retriever engines are required; the instance cannot serve
+// chat or search without them. DocReader, Neo4j and file storage only affect uploads
+// and graph retrieval, so their failure degrades the report without making it not ready.
+func NewHealthService(
+	db *gorm.DB,
+	redisClient *redis.Client,
+	docReader *client.Client,
+	neo4jDriver neo4j.Driver,
+	registry interfaces.RetrieveEngineRegistry,
+	fileService interfaces.FileService,
+) interfaces.HealthService {
+	probes := []healthProbe{
+		{name: "database", required: true, check: func(ctx context.Context) error {
+			sqlDB, err := db.DB()
+			if err != nil {
+				return err
+			}
+			return sqlDB.PingContext(ctx)
+		}},
+		{name: "redis", required: true, check: func(ctx context.Context) error {
+			return redisClient.Ping(ctx).Err()
+		}},
+		{name: "docreader", check: docReader.HealthCheck},
+		{name: "file_storage", check: fileService.Ping},
+	}
+	if neo4jDriver != nil {
+		probes = append(probes, healthProbe{name: "neo4j", check: neo4jDriver.VerifyConnectivity})
+	}
+
+	engines := registry.GetAllRetrieveEngineServices()
+	sort.Slice(engines, func(i, j int) bool { return engines[i].EngineType() < engines[j].EngineType() })
+	for _, engine := range engines {
+		probes = append(probes, healthProbe{
+			name:     fmt.Sprintf("retriever:%s", engine.EngineType()),
+			required: true,
+			check:    engine.Ping,
+		})
+	}
+	return &healthService{probes: probes}
+}
+
+// Ready probes all dependencies, reusing the last report within healthCacheTTL
+func (s *healthService) Ready(ctx context.Context) *types.HealthReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cached != nil && time.Since(s.cached.CheckedAt) < healthCacheTTL {
+		report := *s.cached
+		report.Cached = true
+		return &report
+	}
+
+	// The report is shared with later callers, so a cancelled request must not fail the probes
+	s.cached = s.probe(context.WithoutCancel(ctx))
+	return s.cached
+}
+
+// probe runs all probes concurrently and builds the report
+func (s *healthService) probe(ctx context.Context) *types.HealthReport {
+	checks := make([]types.DependencyHealth, len(s.probes))
+	var wg sync.WaitGroup
+	for i, p := range s.probes {
+		wg.Add(1)
+		go func(i int, p healthProbe) {
+			defer wg.Done()
+			checks[i] = runHealthProbe(ctx, p)
+		}(i, p)
+	}
+	wg.Wait()
+
+	status := types.HealthStatusUp
+	for _, check := range checks {
+		if check.Status == types.HealthStatusUp {
+			continue
+		}
+		if check.Required {
+			status = types.HealthStatusDown
+			break
+		}
+		status = types.HealthStatusDegraded
+	}
+	return &types.HealthReport{
+		Status:    status,
+		Checks:    checks,
+		CheckedAt: time.Now(),
+	}
+}
+
+// runHealthProbe runs a single probe with a timeout
+func runHealthProbe(ctx context.Context, p healthProbe) types.DependencyHealth {
+	ctx, cancel := context.WithTimeout(ctx, healthProbeTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := p.check(ctx)
+	result := types.DependencyHealth{
+		Name:      p.name,
+		Status:    types.HealthStatusUp,
+		Required:  p.required,
+		LatencyMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		result.Status = types.HealthStatusDown
+		result.Error = err.Error()
+	}
+	return result
+}
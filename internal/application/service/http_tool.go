@@ -0,0 +1,287 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/agent/tools"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	secutils "github.com/Tencent/WeKnora/internal/utils"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrHTTPToolNotFound     = errors.New("http tool not found")
+	ErrHTTPToolInvalid      = errors.New("invalid http tool")
+	ErrHTTPToolNameConflict = errors.New("http tool name already exists")
+)
+
+var (
+	// httpToolNameRegex matches tool names, the agent sees them as http_{name}
+	httpToolNameRegex = regexp.MustCompile(`^[a-z0-9_]{1,48}$`)
+	// httpToolMethods lists the supported HTTP methods
+	httpToolMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+	// httpToolArgLocations lists the valid argument locations
+	httpToolArgLocations = []string{
+		types.HTTPToolArgPath, types.HTTPToolArgQuery, types.HTTPToolArgHeader,
+		types.HTTPToolArgBody, types.HTTPToolArgBodyRaw,
+	}
+)
+
+// httpToolService implements the HTTPToolService interface
+type httpToolService struct {
+	repo         interfaces.HTTPToolRepository
+	httpClient   *http.Client
+	allowPrivate bool
+}
+
+// NewHTTPToolService creates a new HTTP tool service.
+// Like webhooks, tool URLs must resolve to public addresses unless HTTP_TOOL_ALLOW_PRIVATE_URLS
+// is true, which self-hosted deployments can set to call services on the internal network.
+func NewHTTPToolService(repo interfaces.HTTPToolRepository) interfaces.HTTPToolService {
+	allowPrivate, _ := strconv.ParseBool(os.Getenv("HTTP_TOOL_ALLOW_PRIVATE_URLS"))
+
+	var httpClient *http.Client
+	if allowPrivate {
+		httpClient = &http.Client{}
+	} else {
+		config := secutils.DefaultSSRFSafeHTTPClientConfig()
+		// Each call is bounded by the timeout of the tool
+		config.Timeout = 0
+		config.MaxRedirects = 3
+		httpClient = secutils.NewSSRFSafeHTTPClient(config)
+	}
+	return &httpToolService{
+		repo:         repo,
+		httpClient:   httpClient,
+		allowPrivate: allowPrivate,
+	}
+}
+
+// CreateTool creates an HTTP tool for the current tenant
+func (s *httpToolService) CreateTool(ctx context.Context, tool *types.HTTPTool) (*types.HTTPTool, error) {
+	tool.TenantID = ctx.Value(types.TenantIDContextKey).(uint64)
+	if tool.Source == "" {
+		tool.Source = types.HTTPToolSourceManual
+	}
+	if err := s.validate(ctx, tool); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	tool.ID = uuid.New().String()
+	tool.CreatedAt = now
+	tool.UpdatedAt = now
+	if err := s.repo.Create(ctx, tool); err != nil {
+		return nil, err
+	}
+	tool.HasAuthSecret = tool.AuthSecret != ""
+	logger.Infof(ctx, "[HTTPTool] Created HTTP tool %s (%s %s)", tool.ID, tool.Method, tool.Name)
+	return tool, nil
+}
+
+// GetTool returns an HTTP tool of the current tenant
+func (s *httpToolService) GetTool(ctx context.Context, id string) (*types.HTTPTool, error) {
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	tool, err := s.repo.GetByID(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if tool == nil {
+		return nil, ErrHTTPToolNotFound
+	}
+	tool.HasAuthSecret = tool.AuthSecret != ""
+	return tool, nil
+}
+
+// ListTools lists the HTTP tools of the current tenant
+func (s *httpToolService) ListTools(ctx context.Context) ([]*types.HTTPTool, error) {
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	httpTools, err := s.repo.List(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	for _, tool := range httpTools {
+		tool.HasAuthSecret = tool.AuthSecret != ""
+	}
+	return httpTools, nil
+}
+
+// UpdateTool updates an HTTP tool of the current tenant
+func (s *httpToolService) UpdateTool(ctx context.Context, tool *types.HTTPTool) (*types.HTTPTool, error) {
+	tool.TenantID = ctx.Value(types.TenantIDContextKey).(uint64)
+	if err := s.validate(ctx, tool); err != nil {
+		return nil, err
+	}
+	tool.UpdatedAt = time.Now()
+	if err := s.repo.Update(ctx, tool); err != nil {
+		return nil, err
+	}
+	tool.HasAuthSecret = tool.AuthSecret != ""
+	return tool, nil
+}
+
+// DeleteTool deletes an HTTP tool of the current tenant
+func (s *httpToolService) DeleteTool(ctx context.Context, id string) error {
+	tool, err := s.GetTool(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, tool.TenantID, tool.ID); err != nil {
+		return err
+	}
+	logger.Infof(ctx, "[HTTPTool] Deleted HTTP tool %s", tool.ID)
+	return nil
+}
+
+// TestTool calls an HTTP tool of the current tenant, disabled tools can be tested as well
+func (s *httpToolService) TestTool(ctx context.Context, id string, args json.RawMessage) (*types.ToolResult, error) {
+	tool, err := s.GetTool(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	result, err := tools.NewHTTPTool(tool, s.httpClient, s.allowPrivate).Execute(ctx, args)
+	if err != nil && result == nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetAgentTools returns the enabled HTTP tools among ids as agent tools
+func (s *httpToolService) GetAgentTools(ctx context.Context, tenantID uint64, ids []string) ([]types.Tool, error) {
+	httpTools, err := s.repo.ListByIDs(ctx, tenantID, ids)
+	if err != nil {
+		return nil, err
+	}
+	agentTools := make([]types.Tool, 0, len(httpTools))
+	for _, tool := range httpTools {
+		if tool.Enabled {
+			agentTools = append(agentTools, tools.NewHTTPTool(tool, s.httpClient, s.allowPrivate))
+		}
+	}
+	return agentTools, nil
+}
+
+// validate normalizes and checks an HTTP tool, including that its name is unique in the tenant
+func (s *httpToolService) validate(ctx context.Context, tool *types.HTTPTool) error {
+	tool.Name = strings.ToLower(strings.TrimSpace(tool.Name))
+	if !httpToolNameRegex.MatchString(tool.Name) {
+		return fmt.Errorf("%w: name must be 1-48 letters, digits or underscores", ErrHTTPToolInvalid)
+	}
+	tool.Method = strings.ToUpper(strings.TrimSpace(tool.Method))
+	if tool.Method == "" {
+		tool.Method = http.MethodGet
+	}
+	if !slices.Contains(httpToolMethods, tool.Method) {
+		return fmt.Errorf("%w: unsupported method %s", ErrHTTPToolInvalid, tool.Method)
+	}
+	if err := s.validateURLTemplate(tool.URL); err != nil {
+		return err
+	}
+	if err := validateHTTPToolParameters(tool); err != nil {
+		return err
+	}
+	for name, location := range tool.ArgLocations {
+		if !slices.Contains(httpToolArgLocations, location) {
+			return fmt.Errorf("%w: invalid location %q of argument %s", ErrHTTPToolInvalid, location, name)
+		}
+	}
+	if tool.ResponsePath != "" {
+		if err := tools.ValidateJSONPath(tool.ResponsePath); err != nil {
+			return fmt.Errorf("%w: %v", ErrHTTPToolInvalid, err)
+		}
+	}
+	if tool.TimeoutSeconds < 0 || tool.TimeoutSeconds > tools.HTTPToolMaxTimeoutSeconds {
+		return fmt.Errorf("%w: timeout must be between 0 and %d seconds", ErrHTTPToolInvalid, tools.HTTPToolMaxTimeoutSeconds)
+	}
+	if tool.AuthSecret != "" && tool.AuthHeader == "" {
+		tool.AuthHeader = "Authorization"
+	}
+
+	existing, err := s.repo.GetByName(ctx, tool.TenantID, tool.Name)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.ID != tool.ID {
+		return fmt.Errorf("%w: %s", ErrHTTPToolNameConflict, tool.Name)
+	}
+	return nil
+}
+
+// validateURLTemplate rejects non http(s) URLs, templated hosts, and private addresses unless they are allowed
+func (s *httpToolService) validateURLTemplate(template string) error {
+	if !strings.HasPrefix(template, "http://") && !strings.HasPrefix(template, "https://") {
+		return fmt.Errorf("%w: only http and https urls are supported", ErrHTTPToolInvalid)
+	}
+	sample := httpToolPlaceholderSample(template)
+	u, err := url.Parse(sample)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("%w: invalid url", ErrHTTPToolInvalid)
+	}
+	host := template[strings.Index(template, "://")+3:]
+	if end := strings.IndexAny(host, "/?#"); end != -1 {
+		host = host[:end]
+	}
+	if strings.Contains(host, "{") {
+		return fmt.Errorf("%w: the host of the url cannot contain parameters", ErrHTTPToolInvalid)
+	}
+	if s.allowPrivate {
+		return nil
+	}
+	if safe, reason := secutils.IsSSRFSafeURL(sample); !safe {
+		return fmt.Errorf("%w: %s", ErrHTTPToolInvalid, reason)
+	}
+	return nil
+}
+
+// validateHTTPToolParameters checks that the parameters are an object schema declaring the URL parameters
+func validateHTTPToolParameters(tool *types.HTTPTool) error {
+	if len(tool.Parameters) == 0 {
+		tool.Parameters = types.HTTPToolSchema(`{"type":"object","properties":{}}`)
+	}
+	var schema struct {
+		Type       string                     `json:"type"`
+		Properties map[string]json.RawMessage `json:"properties"`
+	}
+	if err := json.Unmarshal(tool.Parameters, &schema); err != nil {
+		return fmt.Errorf("%w: parameters must be a JSON schema object: %v", ErrHTTPToolInvalid, err)
+	}
+	if schema.Type != "object" {
+		return fmt.Errorf("%w: parameters must be a schema of type object", ErrHTTPToolInvalid)
+	}
+	for _, name := range httpToolPlaceholders(tool.URL) {
+		if _, ok := schema.Properties[name]; !ok {
+			return fmt.Errorf("%w: url parameter %s is not declared in parameters", ErrHTTPToolInvalid, name)
+		}
+	}
+	return nil
+}
+
+// httpToolPlaceholderRegex matches the {name} parameters of a URL template
+var httpToolPlaceholderRegex = regexp.MustCompile(`\{([A-Za-z0-9_.\-]+)\}`)
+
+// httpToolPlaceholders returns the parameter names of a URL template
+func httpToolPlaceholders(template string) []string {
+	var names []string
+	for _, match := range httpToolPlaceholderRegex.FindAllStringSubmatch(template, -1) {
+		names = append(names, match[1])
+	}
+	return names
+}
+
+// httpToolPlaceholderSample fills the parameters of a URL template to validate it
+func httpToolPlaceholderSample(template string) string {
+	return httpToolPlaceholderRegex.ReplaceAllString(template, "x")
+}
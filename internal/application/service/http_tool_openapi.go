@@ -0,0 +1,389 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// maxOpenAPIImportTools caps the operations imported from one document
+	maxOpenAPIImportTools = 100
+	// maxOpenAPIRefDepth bounds the resolution of nested and recursive $refs
+	maxOpenAPIRefDepth = 8
+	// maxOpenAPIDescriptionChars caps the tool descriptions taken from operations
+	maxOpenAPIDescriptionChars = 1024
+)
+
+var ErrHTTPToolInvalidSpec = errors.New("invalid openapi document")
+
+// openAPIMethods lists the operations imported from a path item, in import order
+var openAPIMethods = []string{"get", "post", "put", "patch", "delete"}
+
+// openAPINameRegex matches the characters not allowed in tool names
+var openAPINameRegex = regexp.MustCompile(`[^a-z0-9]+`)
+
+// ImportOpenAPI creates or updates an HTTP tool for each operation of an OpenAPI 3 document.
+// The document is validated as a whole before any tool is saved.
+func (s *httpToolService) ImportOpenAPI(ctx context.Context,
+	req *types.HTTPToolImportRequest,
+) ([]*types.HTTPTool, error) {
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+
+	parsed, err := parseOpenAPITools(req.Spec, req.BaseURL, req.Operations)
+	if err != nil {
+		return nil, err
+	}
+
+	enabled := req.Enabled == nil || *req.Enabled
+	now := time.Now()
+	for _, tool := range parsed {
+		tool.TenantID = tenantID
+		tool.Enabled = enabled
+		tool.AuthHeader = req.AuthHeader
+		tool.AuthSecret = types.EncryptedString(req.AuthSecret)
+		tool.UpdatedAt = now
+
+		existing, err := s.repo.GetByName(ctx, tenantID, strings.ToLower(tool.Name))
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil && existing.Source != types.HTTPToolSourceOpenAPI {
+			return nil, fmt.Errorf("%w: %s", ErrHTTPToolNameConflict, existing.Name)
+		}
+		if existing != nil {
+			// Re-importing keeps the identity, secret and settings that are not part of the document
+			tool.ID = existing.ID
+			tool.CreatedAt = existing.CreatedAt
+			tool.Headers = existing.Headers
+			tool.ResponsePath = existing.ResponsePath
+			tool.TimeoutSeconds = existing.TimeoutSeconds
+			if req.Enabled == nil {
+				tool.Enabled = existing.Enabled
+			}
+			if req.AuthSecret == "" {
+				tool.AuthHeader = existing.AuthHeader
+				tool.AuthSecret = existing.AuthSecret
+			}
+		}
+		if err := s.validate(ctx, tool); err != nil {
+			return nil, fmt.Errorf("operation %s: %w", tool.Name, err)
+		}
+	}
+
+	for _, tool := range parsed {
+		if tool.ID != "" {
+			if err := s.repo.Update(ctx, tool); err != nil {
+				return nil, err
+			}
+		} else {
+			tool.ID = uuid.New().String()
+			tool.CreatedAt = now
+			if err := s.repo.Create(ctx, tool); err != nil {
+				return nil, err
+			}
+		}
+		tool.HasAuthSecret = tool.AuthSecret != ""
+	}
+	logger.Infof(ctx, "[HTTPTool] Imported %d HTTP tools from OpenAPI document", len(parsed))
+	return parsed, nil
+}
+
+// openAPIDocument is a decoded OpenAPI document with its components, used to resolve $refs
+type openAPIDocument struct {
+	root map[string]interface{}
+}
+
+// parseOpenAPITools converts the operations of an OpenAPI 3 document to HTTP tools.
+// Path, query and header parameters become arguments at the same location; the properties of an
+// application/json object request body become body arguments, other request bodies a "body" argument.
+func parseOpenAPITools(spec string, baseURL string, operations []string) ([]*types.HTTPTool, error) {
+	var decoded interface{}
+	if err := yaml.Unmarshal([]byte(spec), &decoded); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrHTTPToolInvalidSpec, err)
+	}
+	root, ok := normalizeYAML(decoded).(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: not an object", ErrHTTPToolInvalidSpec)
+	}
+	if version, _ := root["openapi"].(string); !strings.HasPrefix(version, "3.") {
+		return nil, fmt.Errorf("%w: only OpenAPI 3 documents are supported", ErrHTTPToolInvalidSpec)
+	}
+	doc := &openAPIDocument{root: root}
+
+	if baseURL == "" {
+		baseURL = doc.serverURL()
+	}
+	if !strings.HasPrefix(baseURL, "http://") && !strings.HasPrefix(baseURL, "https://") {
+		return nil, fmt.Errorf("%w: the document has no absolute server url, set base_url", ErrHTTPToolInvalidSpec)
+	}
+	baseURL = strings.TrimRight(baseURL, "/")
+
+	paths, _ := root["paths"].(map[string]interface{})
+	pathNames := make([]string, 0, len(paths))
+	for path := range paths {
+		pathNames = append(pathNames, path)
+	}
+	sort.Strings(pathNames)
+
+	var result []*types.HTTPTool
+	names := make(map[string]bool)
+	for _, path := range pathNames {
+		item, _ := doc.resolve(paths[path], 0).(map[string]interface{})
+		for _, method := range openAPIMethods {
+			operation, ok := item[method].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			operationID, _ := operation["operationId"].(string)
+			name := openAPIToolName(operationID, method, path)
+			if len(operations) > 0 && !slices.Contains(operations, operationID) && !slices.Contains(operations, name) {
+				continue
+			}
+			if names[name] {
+				return nil, fmt.Errorf("%w: duplicate operation name %s", ErrHTTPToolInvalidSpec, name)
+			}
+			names[name] = true
+
+			tool, err := doc.operationTool(name, method, baseURL+path, item, operation)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, tool)
+			if len(result) > maxOpenAPIImportTools {
+				return nil, fmt.Errorf("%w: more than %d operations, select operations to import",
+					ErrHTTPToolInvalidSpec, maxOpenAPIImportTools)
+			}
+		}
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("%w: no operations to import", ErrHTTPToolInvalidSpec)
+	}
+	return result, nil
+}
+
+// operationTool converts an operation to an HTTP tool
+func (d *openAPIDocument) operationTool(name, method, url string,
+	item map[string]interface{}, operation map[string]interface{},
+) (*types.HTTPTool, error) {
+	properties := make(map[string]interface{})
+	locations := make(types.HTTPToolStrings)
+	var required []string
+
+	// Operation parameters override path item parameters with the same name and location
+	parameters := make(map[string]map[string]interface{})
+	var order []string
+	for _, list := range []interface{}{item["parameters"], operation["parameters"]} {
+		entries, _ := list.([]interface{})
+		for _, entry := range entries {
+			parameter, ok := d.resolve(entry, 0).(map[string]interface{})
+			if !ok {
+				continue
+			}
+			key := fmt.Sprintf("%v:%v", parameter["in"], parameter["name"])
+			if _, seen := parameters[key]; !seen {
+				order = append(order, key)
+			}
+			parameters[key] = parameter
+		}
+	}
+	for _, key := range order {
+		parameter := parameters[key]
+		paramName, _ := parameter["name"].(string)
+		in, _ := parameter["in"].(string)
+		if paramName == "" || (in != types.HTTPToolArgPath && in != types.HTTPToolArgQuery && in != types.HTTPToolArgHeader) {
+			// Cookie parameters are not supported
+			continue
+		}
+		schema, ok := d.resolve(parameter["schema"], 0).(map[string]interface{})
+		if !ok {
+			schema = map[string]interface{}{"type": "string"}
+		}
+		if description, ok := parameter["description"].(string); ok && schema["description"] == nil {
+			schema["description"] = description
+		}
+		properties[paramName] = schema
+		locations[paramName] = in
+		if isRequired, _ := parameter["required"].(bool); isRequired || in == types.HTTPToolArgPath {
+			required = append(required, paramName)
+		}
+	}
+
+	if requestBody, ok := d.resolve(operation["requestBody"], 0).(map[string]interface{}); ok {
+		bodyRequired, _ := requestBody["required"].(bool)
+		content, _ := requestBody["content"].(map[string]interface{})
+		media, _ := content["application/json"].(map[string]interface{})
+		schema, _ := d.resolve(media["schema"], 0).(map[string]interface{})
+		bodyProperties, isObject := schema["properties"].(map[string]interface{})
+		switch {
+		case schema == nil:
+		case isObject:
+			bodyRequiredFields, _ := schema["required"].([]interface{})
+			for propName, propSchema := range bodyProperties {
+				if _, exists := properties[propName]; exists {
+					return nil, fmt.Errorf("%w: operation %s has parameter and body field %s",
+						ErrHTTPToolInvalidSpec, name, propName)
+				}
+				properties[propName] = propSchema
+				locations[propName] = types.HTTPToolArgBody
+			}
+			for _, field := range bodyRequiredFields {
+				if fieldName, ok := field.(string); ok {
+					required = append(required, fieldName)
+				}
+			}
+		default:
+			properties["body"] = schema
+			locations["body"] = types.HTTPToolArgBodyRaw
+			if bodyRequired {
+				required = append(required, "body")
+			}
+		}
+	}
+
+	parametersSchema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		sort.Strings(required)
+		parametersSchema["required"] = required
+	}
+	encoded, err := json.Marshal(parametersSchema)
+	if err != nil {
+		return nil, fmt.Errorf("%w: operation %s: %v", ErrHTTPToolInvalidSpec, name, err)
+	}
+
+	var description []string
+	for _, key := range []string{"summary", "description"} {
+		if text, ok := operation[key].(string); ok && strings.TrimSpace(text) != "" {
+			description = append(description, strings.TrimSpace(text))
+		}
+	}
+	return &types.HTTPTool{
+		Name:         name,
+		Description:  truncateRunes(strings.Join(description, "\n\n"), maxOpenAPIDescriptionChars),
+		Method:       strings.ToUpper(method),
+		URL:          url,
+		Parameters:   types.HTTPToolSchema(encoded),
+		ArgLocations: locations,
+		Source:       types.HTTPToolSourceOpenAPI,
+	}, nil
+}
+
+// serverURL returns the first server URL with its variables set to their defaults
+func (d *openAPIDocument) serverURL() string {
+	servers, _ := d.root["servers"].([]interface{})
+	if len(servers) == 0 {
+		return ""
+	}
+	server, _ := servers[0].(map[string]interface{})
+	serverURL, _ := server["url"].(string)
+	variables, _ := server["variables"].(map[string]interface{})
+	for name, variable := range variables {
+		if v, ok := variable.(map[string]interface{}); ok {
+			serverURL = strings.ReplaceAll(serverURL, "{"+name+"}", fmt.Sprint(v["default"]))
+		}
+	}
+	return serverURL
+}
+
+// resolve returns a copy of node with local $refs (#/components/...) replaced by their targets.
+// Refs nested deeper than maxOpenAPIRefDepth, e.g. recursive schemas, become empty schemas.
+func (d *openAPIDocument) resolve(node interface{}, depth int) interface{} {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		if ref, ok := v["$ref"].(string); ok {
+			if depth >= maxOpenAPIRefDepth || !strings.HasPrefix(ref, "#/") {
+				return map[string]interface{}{}
+			}
+			var target interface{} = d.root
+			for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+				part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+				m, ok := target.(map[string]interface{})
+				if !ok {
+					return map[string]interface{}{}
+				}
+				target = m[part]
+			}
+			return d.resolve(target, depth+1)
+		}
+		resolved := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			resolved[key] = d.resolve(value, depth)
+		}
+		return resolved
+	case []interface{}:
+		resolved := make([]interface{}, len(v))
+		for i, value := range v {
+			resolved[i] = d.resolve(value, depth)
+		}
+		return resolved
+	default:
+		return v
+	}
+}
+
+// normalizeYAML converts the maps decoded from YAML to map[string]interface{}, so they encode to JSON
+func normalizeYAML(node interface{}) interface{} {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = normalizeYAML(value)
+		}
+		return v
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			converted[fmt.Sprint(key)] = normalizeYAML(value)
+		}
+		return converted
+	case []interface{}:
+		for i, value := range v {
+			v[i] = normalizeYAML(value)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// openAPIToolName derives a snake_case tool name from the operation ID, or from the method and path
+func openAPIToolName(operationID string, method string, path string) string {
+	source := operationID
+	if source == "" {
+		source = method + "_" + path
+	}
+	var builder strings.Builder
+	runes := []rune(source)
+	for i, r := range runes {
+		// Split camelCase words
+		if unicode.IsUpper(r) && i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])) {
+			builder.WriteRune('_')
+		}
+		builder.WriteRune(unicode.ToLower(r))
+	}
+	name := strings.Trim(openAPINameRegex.ReplaceAllString(builder.String(), "_"), "_")
+	if len(name) > 48 {
+		name = strings.TrimRight(name[:48], "_")
+	}
+	return name
+}
+
+// truncateRunes cuts a text to maxChars characters
+func truncateRunes(text string, maxChars int) string {
+	runes := []rune(text)
+	if len(runes) <= maxChars {
+		return text
+	}
+	return string(runes[:maxChars])
+}
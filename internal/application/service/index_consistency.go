@@ -0,0 +1,443 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+)
+
+const (
+	// indexCheckReportLimit is the number of reports returned per knowledge base
+	indexCheckReportLimit = 20
+	// indexCheckRepairBatchSize is the number of chunks or entries repaired per call
+	indexCheckRepairBatchSize = 200
+	// indexCheckTimeout bounds a single check task
+	indexCheckTimeout = 2 * time.Hour
+)
+
+var (
+	ErrIndexCheckReportNotFound = errors.New("index consistency report not found")
+	ErrIndexCheckActive         = errors.New("an index consistency check is already running for this knowledge base")
+)
+
+// indexConsistencyService implements the IndexConsistencyService interface
+type indexConsistencyService struct {
+	repo             interfaces.IndexConsistencyRepository
+	tenantRepo       interfaces.TenantRepository
+	kbRepo           interfaces.KnowledgeBaseRepository
+	chunkRepo        interfaces.ChunkRepository
+	modelService     interfaces.ModelService
+	knowledgeService interfaces.KnowledgeService
+	registry         interfaces.RetrieveEngineRegistry
+	asynqClient      *asynq.Client
+}
+
+// NewIndexConsistencyService creates a new index consistency service
+func NewIndexConsistencyService(
+	repo interfaces.IndexConsistencyRepository,
+	tenantRepo interfaces.TenantRepository,
+	kbRepo interfaces.KnowledgeBaseRepository,
+	chunkRepo interfaces.ChunkRepository,
+	modelService interfaces.ModelService,
+	knowledgeService interfaces.KnowledgeService,
+	registry interfaces.RetrieveEngineRegistry,
+	asynqClient *asynq.Client,
+) interfaces.IndexConsistencyService {
+	return &indexConsistencyService{
+		repo:             repo,
+		tenantRepo:       tenantRepo,
+		kbRepo:           kbRepo,
+		chunkRepo:        chunkRepo,
+		modelService:     modelService,
+		knowledgeService: knowledgeService,
+		registry:         registry,
+		asynqClient:      asynqClient,
+	}
+}
+
+// StartCheck enqueues a consistency check of a knowledge base of the current tenant
+func (s *indexConsistencyService) StartCheck(ctx context.Context,
+	kbID string, repair bool,
+) (*types.IndexConsistencyReport, error) {
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	if _, err := s.kbRepo.GetKnowledgeBaseByIDAndTenant(ctx, kbID, tenantID); err != nil {
+		return nil, err
+	}
+	return s.createCheck(ctx, tenantID, kbID, types.IndexConsistencyTriggerManual, repair)
+}
+
+// GetReport returns a report of a knowledge base of the current tenant
+func (s *indexConsistencyService) GetReport(ctx context.Context,
+	kbID string, id string,
+) (*types.IndexConsistencyReport, error) {
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	report, err := s.repo.GetByID(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if report == nil || report.KnowledgeBaseID != kbID {
+		return nil, ErrIndexCheckReportNotFound
+	}
+	return report, nil
+}
+
+// ListReports lists the latest reports of a knowledge base of the current tenant
+func (s *indexConsistencyService) ListReports(ctx context.Context,
+	kbID string,
+) ([]*types.IndexConsistencyReport, error) {
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	return s.repo.ListByKnowledgeBase(ctx, tenantID, kbID, indexCheckReportLimit)
+}
+
+// createCheck creates a pending report and enqueues its task
+func (s *indexConsistencyService) createCheck(ctx context.Context,
+	tenantID uint64, kbID string, trigger string, repair bool,
+) (*types.IndexConsistencyReport, error) {
+	active, err := s.repo.GetActive(ctx, tenantID, kbID)
+	if err != nil {
+		return nil, err
+	}
+	if active != nil {
+		return nil, ErrIndexCheckActive
+	}
+
+	now := time.Now()
+	report := &types.IndexConsistencyReport{
+		ID:              uuid.New().String(),
+		TenantID:        tenantID,
+		KnowledgeBaseID: kbID,
+		Trigger:         trigger,
+		Repair:          repair,
+		Status:          types.IndexConsistencyStatusPending,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	if err := s.repo.Create(ctx, report); err != nil {
+		return nil, err
+	}
+
+	payload, err := json.Marshal(types.IndexConsistencyCheckPayload{TenantID: tenantID, ReportID: report.ID})
+	if err != nil {
+		return nil, err
+	}
+	task := asynq.NewTask(types.TypeIndexCheck, payload,
+		asynq.Queue("low"), asynq.MaxRetry(0), asynq.Timeout(indexCheckTimeout))
+	if _, err := s.asynqClient.Enqueue(task); err != nil {
+		logger.Errorf(ctx, "[IndexCheck] Failed to enqueue check of knowledge base %s: %v", kbID, err)
+		s.finish(ctx, report, err)
+		return nil, err
+	}
+	logger.Infof(ctx, "[IndexCheck] Enqueued %s check %s of knowledge base %s, repair: %v",
+		trigger, report.ID, kbID, repair)
+	return report, nil
+}
+
+// ProcessIndexCheckScan handles the periodic task that checks all knowledge bases.
+// Drift is repaired automatically when INDEX_CHECK_AUTO_REPAIR is true.
+func (s *indexConsistencyService) ProcessIndexCheckScan(ctx context.Context, t *asynq.Task) error {
+	repair, _ := strconv.ParseBool(os.Getenv("INDEX_CHECK_AUTO_REPAIR"))
+
+	kbs, err := s.kbRepo.ListKnowledgeBases(ctx)
+	if err != nil {
+		return err
+	}
+	started := 0
+	for _, kb := range kbs {
+		if kb.IsTemporary {
+			continue
+		}
+		_, err := s.createCheck(ctx, kb.TenantID, kb.ID, types.IndexConsistencyTriggerScheduled, repair)
+		if err != nil {
+			if !errors.Is(err, ErrIndexCheckActive) {
+				logger.Warnf(ctx, "[IndexCheck] Failed to schedule check of knowledge base %s: %v", kb.ID, err)
+			}
+			continue
+		}
+		started++
+	}
+	logger.Infof(ctx, "[IndexCheck] Scheduled checks of %d knowledge bases", started)
+	return nil
+}
+
+// ProcessIndexCheck handles the asynq index consistency check task
+func (s *indexConsistencyService) ProcessIndexCheck(ctx context.Context, t *asynq.Task) error {
+	var payload types.IndexConsistencyCheckPayload
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		logger.Errorf(ctx, "[IndexCheck] Failed to unmarshal payload: %v", err)
+		return err
+	}
+
+	// Set tenant context for downstream services, re-indexing needs the tenant's engines
+	ctx = context.WithValue(ctx, types.TenantIDContextKey, payload.TenantID)
+	tenant, err := s.tenantRepo.GetTenantByID(ctx, payload.TenantID)
+	if err != nil {
+		return err
+	}
+	ctx = context.WithValue(ctx, types.TenantInfoContextKey, tenant)
+
+	report, err := s.repo.GetByID(ctx, payload.TenantID, payload.ReportID)
+	if err != nil {
+		return err
+	}
+	if report == nil || !report.IsActive() {
+		logger.Infof(ctx, "[IndexCheck] Check %s is not active, skipping", payload.ReportID)
+		return nil
+	}
+
+	now := time.Now()
+	report.Status = types.IndexConsistencyStatusRunning
+	report.StartedAt = &now
+	report.UpdatedAt = now
+	if err := s.repo.Update(ctx, report); err != nil {
+		return err
+	}
+
+	err = s.check(ctx, tenant, report)
+	s.finish(ctx, report, err)
+	if err != nil {
+		logger.Errorf(ctx, "[IndexCheck] Check %s of knowledge base %s failed: %v",
+			report.ID, report.KnowledgeBaseID, err)
+		return err
+	}
+	logger.Infof(ctx, "[IndexCheck] Check %s of knowledge base %s completed, chunks: %d, drift: %d",
+		report.ID, report.KnowledgeBaseID, report.ChunkCount, report.DriftCount)
+	return nil
+}
+
+// finish records the final status of a check
+func (s *indexConsistencyService) finish(ctx context.Context, report *types.IndexConsistencyReport, err error) {
+	now := time.Now()
+	report.Status = types.IndexConsistencyStatusCompleted
+	if err != nil {
+		report.Status = types.IndexConsistencyStatusFailed
+		report.Error = err.Error()
+	}
+	report.FinishedAt = &now
+	report.UpdatedAt = now
+	if saveErr := s.repo.Update(context.WithoutCancel(ctx), report); saveErr != nil {
+		logger.Errorf(ctx, "[IndexCheck] Failed to save report %s: %v", report.ID, saveErr)
+	}
+}
+
+// engineDrift is the full drift of an engine, the report only keeps samples
+type engineDrift struct {
+	missing         []string
+	orphans         []string
+	enabledMismatch map[string]bool
+	tagMismatch     map[string]string
+}
+
+// check compares the chunks of the knowledge base with every engine of the tenant and repairs drift if requested
+func (s *indexConsistencyService) check(ctx context.Context,
+	tenant *types.Tenant, report *types.IndexConsistencyReport,
+) error {
+	kb, err := s.kbRepo.GetKnowledgeBaseByIDAndTenant(ctx, report.KnowledgeBaseID, report.TenantID)
+	if err != nil {
+		return err
+	}
+	dimension := 0
+	if kb.EmbeddingModelID != "" {
+		embeddingModel, err := s.modelService.GetEmbeddingModel(ctx, kb.EmbeddingModelID)
+		if err != nil {
+			return fmt.Errorf("failed to get embedding model: %w", err)
+		}
+		dimension = embeddingModel.GetDimensions()
+	}
+
+	chunks, err := s.chunkRepo.ListAllChunksForIndexCheck(ctx, report.TenantID, kb.ID)
+	if err != nil {
+		return err
+	}
+	chunkByID := make(map[string]*types.Chunk, len(chunks))
+	for _, chunk := range chunks {
+		chunkByID[chunk.ID] = chunk
+		if isIndexedChunk(chunk) {
+			report.ChunkCount++
+		}
+	}
+
+	var engineTypes []types.RetrieverEngineType
+	for _, engine := range tenant.GetServingEngines() {
+		if !slices.Contains(engineTypes, engine.RetrieverEngineType) {
+			engineTypes = append(engineTypes, engine.RetrieverEngineType)
+		}
+	}
+
+	report.Engines = make(types.IndexConsistencyEngineReports, 0, len(engineTypes))
+	report.DriftCount = 0
+	missingChunks := make(map[string]bool)
+	for _, engineType := range engineTypes {
+		engine, err := s.registry.GetRetrieveEngineService(engineType)
+		if err != nil {
+			return err
+		}
+		engineReport, drift, err := compareEngine(ctx, engine, kb, dimension, chunks, chunkByID)
+		if err != nil {
+			return fmt.Errorf("compare engine %s: %w", engineType, err)
+		}
+		if report.Repair && engineReport.DriftCount() > 0 {
+			if err := repairEngine(ctx, engine, kb, dimension, drift); err != nil {
+				engineReport.RepairError = err.Error()
+			} else {
+				engineReport.Repaired = true
+			}
+			for _, chunkID := range drift.missing {
+				missingChunks[chunkID] = true
+			}
+		}
+		report.DriftCount += engineReport.DriftCount()
+		report.Engines = append(report.Engines, *engineReport)
+	}
+
+	// Missing chunks are re-indexed once into all engines of the tenant
+	if len(missingChunks) > 0 {
+		if err := s.reindexChunks(ctx, kb, missingChunks); err != nil {
+			for i := range report.Engines {
+				if report.Engines[i].MissingCount > 0 {
+					report.Engines[i].Repaired = false
+					report.Engines[i].RepairError = err.Error()
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// reindexChunks loads the missing chunks with their content and rebuilds their index entries
+func (s *indexConsistencyService) reindexChunks(ctx context.Context,
+	kb *types.KnowledgeBase, chunkIDs map[string]bool,
+) error {
+	ids := make([]string, 0, len(chunkIDs))
+	for id := range chunkIDs {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	for batch := range slices.Chunk(ids, indexCheckRepairBatchSize) {
+		chunks, err := s.chunkRepo.ListChunksByID(ctx, kb.TenantID, batch)
+		if err != nil {
+			return err
+		}
+		if err := s.knowledgeService.ReindexChunks(ctx, kb, chunks); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compareEngine lists the entries of the knowledge base in an engine and compares them with the chunks
+func compareEngine(ctx context.Context,
+	engine interfaces.RetrieveEngineService, kb *types.KnowledgeBase, dimension int,
+	chunks []*types.Chunk, chunkByID map[string]*types.Chunk,
+) (*types.IndexConsistencyEngineReport, *engineDrift, error) {
+	report := &types.IndexConsistencyEngineReport{Engine: engine.EngineType()}
+	drift := &engineDrift{
+		enabledMismatch: make(map[string]bool),
+		tagMismatch:     make(map[string]string),
+	}
+
+	indexed := make(map[string]bool)
+	err := listAllIndices(ctx, engine, kb.ID, dimension, kb.Type, func(entries []*types.IndexEntry) error {
+		for _, entry := range entries {
+			report.EntryCount++
+			chunk, ok := chunkByID[entry.ChunkID]
+			if !ok {
+				drift.orphans = append(drift.orphans, entry.SourceID)
+				continue
+			}
+			indexed[chunk.ID] = true
+			if entry.IsEnabled != chunk.IsEnabled {
+				drift.enabledMismatch[chunk.ID] = chunk.IsEnabled
+			}
+			if entry.TagID != chunk.TagID {
+				drift.tagMismatch[chunk.ID] = chunk.TagID
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, chunk := range chunks {
+		if isIndexedChunk(chunk) && !indexed[chunk.ID] {
+			drift.missing = append(drift.missing, chunk.ID)
+		}
+	}
+
+	report.MissingCount = int64(len(drift.missing))
+	report.MissingChunkIDs = sampleIDs(drift.missing)
+	report.OrphanCount = int64(len(drift.orphans))
+	report.OrphanSourceIDs = sampleIDs(drift.orphans)
+	report.EnabledMismatchCount = int64(len(drift.enabledMismatch))
+	report.EnabledMismatchChunkIDs = sampleIDs(sortedKeys(drift.enabledMismatch))
+	report.TagMismatchCount = int64(len(drift.tagMismatch))
+	report.TagMismatchChunkIDs = sampleIDs(sortedKeys(drift.tagMismatch))
+	return report, drift, nil
+}
+
+// repairEngine deletes orphan entries and fixes enabled status and tags in an engine.
+// Missing chunks are re-indexed separately.
+func repairEngine(ctx context.Context,
+	engine interfaces.RetrieveEngineService, kb *types.KnowledgeBase, dimension int, drift *engineDrift,
+) error {
+	for batch := range slices.Chunk(drift.orphans, indexCheckRepairBatchSize) {
+		if err := engine.DeleteBySourceIDList(ctx, batch, dimension, kb.Type); err != nil {
+			return fmt.Errorf("delete orphan entries: %w", err)
+		}
+	}
+	if len(drift.enabledMismatch) > 0 {
+		if err := engine.BatchUpdateChunkEnabledStatus(ctx, drift.enabledMismatch); err != nil {
+			return fmt.Errorf("update enabled status: %w", err)
+		}
+	}
+	if len(drift.tagMismatch) > 0 {
+		if err := engine.BatchUpdateChunkTagID(ctx, drift.tagMismatch); err != nil {
+			return fmt.Errorf("update tags: %w", err)
+		}
+	}
+	logger.Infof(ctx, "[IndexCheck] Repaired engine %s for knowledge base %s, orphans: %d, enabled: %d, tags: %d",
+		engine.EngineType(), kb.ID, len(drift.orphans), len(drift.enabledMismatch), len(drift.tagMismatch))
+	return nil
+}
+
+// isIndexedChunk reports whether a chunk is expected to have index entries.
+// Graph chunks are stored in the graph database and stored chunks are still being processed.
+func isIndexedChunk(chunk *types.Chunk) bool {
+	switch chunk.ChunkType {
+	case types.ChunkTypeEntity, types.ChunkTypeRelationship, types.ChunkTypeWebSearch:
+		return false
+	}
+	return chunk.Status != int(types.ChunkStatusStored)
+}
+
+// sampleIDs returns at most IndexConsistencySampleSize IDs
+func sampleIDs(ids []string) []string {
+	if ids == nil {
+		return []string{}
+	}
+	if len(ids) > types.IndexConsistencySampleSize {
+		return ids[:types.IndexConsistencySampleSize]
+	}
+	return ids
+}
+
+// sortedKeys returns the keys of a map in ascending order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,571 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+)
+
+const (
+	// indexMigrationBatchSize is the number of entries read from the source engine per page
+	indexMigrationBatchSize = 200
+	// indexMigrationTimeout bounds a single run of the task; an interrupted run resumes from the saved cursors
+	indexMigrationTimeout = 24 * time.Hour
+)
+
+var (
+	ErrIndexMigrationNotFound          = errors.New("index migration not found")
+	ErrIndexMigrationActive            = errors.New("another index migration is already running for this tenant")
+	ErrIndexMigrationNotResumable      = errors.New("only failed index migrations can be resumed")
+	ErrIndexMigrationSameEngine        = errors.New("source and target engines must be different")
+	ErrIndexMigrationEngineNotFound    = errors.New("retriever engine is not available")
+	ErrIndexMigrationEngineNotUsed     = errors.New("source engine is not used by the tenant")
+	ErrIndexMigrationEngineUnsupported = errors.New("target engine does not support the retriever types served by the source engine")
+)
+
+// indexMigrationService implements the IndexMigrationService interface
+type indexMigrationService struct {
+	repo         interfaces.IndexMigrationRepository
+	tenantRepo   interfaces.TenantRepository
+	kbRepo       interfaces.KnowledgeBaseRepository
+	modelService interfaces.ModelService
+	registry     interfaces.RetrieveEngineRegistry
+	asynqClient  *asynq.Client
+}
+
+// NewIndexMigrationService creates a new index migration service
+func NewIndexMigrationService(
+	repo interfaces.IndexMigrationRepository,
+	tenantRepo interfaces.TenantRepository,
+	kbRepo interfaces.KnowledgeBaseRepository,
+	modelService interfaces.ModelService,
+	registry interfaces.RetrieveEngineRegistry,
+	asynqClient *asynq.Client,
+) interfaces.IndexMigrationService {
+	return &indexMigrationService{
+		repo:         repo,
+		tenantRepo:   tenantRepo,
+		kbRepo:       kbRepo,
+		modelService: modelService,
+		registry:     registry,
+		asynqClient:  asynqClient,
+	}
+}
+
+// StartMigration validates the engines, snapshots the tenant's knowledge bases and enqueues the migration task
+func (s *indexMigrationService) StartMigration(ctx context.Context,
+	sourceEngine types.RetrieverEngineType, targetEngine types.RetrieverEngineType,
+) (*types.IndexMigration, error) {
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+
+	if sourceEngine == targetEngine {
+		return nil, ErrIndexMigrationSameEngine
+	}
+	if _, err := s.registry.GetRetrieveEngineService(sourceEngine); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrIndexMigrationEngineNotFound, sourceEngine)
+	}
+	target, err := s.registry.GetRetrieveEngineService(targetEngine)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrIndexMigrationEngineNotFound, targetEngine)
+	}
+
+	tenant, err := s.tenantRepo.GetTenantByID(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	var served []types.RetrieverType
+	for _, engine := range tenant.GetServingEngines() {
+		if engine.RetrieverEngineType == sourceEngine {
+			served = append(served, engine.RetrieverType)
+		}
+	}
+	if len(served) == 0 {
+		return nil, ErrIndexMigrationEngineNotUsed
+	}
+	for _, retrieverType := range served {
+		if !slices.Contains(target.Support(), retrieverType) {
+			return nil, fmt.Errorf("%w: %s", ErrIndexMigrationEngineUnsupported, retrieverType)
+		}
+	}
+
+	active, err := s.repo.GetActive(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if active != nil {
+		return nil, ErrIndexMigrationActive
+	}
+
+	kbs, err := s.kbRepo.ListKnowledgeBasesByTenantID(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	progress := make(types.IndexMigrationKnowledgeBases, 0, len(kbs))
+	for _, kb := range kbs {
+		dimension := 0
+		if kb.EmbeddingModelID != "" {
+			embeddingModel, err := s.modelService.GetEmbeddingModel(ctx, kb.EmbeddingModelID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get embedding model of knowledge base %s: %w", kb.ID, err)
+			}
+			dimension = embeddingModel.GetDimensions()
+		}
+		progress = append(progress, types.IndexMigrationKnowledgeBase{
+			KnowledgeBaseID: kb.ID,
+			Name:            kb.Name,
+			Type:            kb.Type,
+			Dimension:       dimension,
+			Phase:           types.IndexMigrationPhaseCopy,
+		})
+	}
+
+	now := time.Now()
+	migration := &types.IndexMigration{
+		ID:             uuid.New().String(),
+		TenantID:       tenantID,
+		SourceEngine:   sourceEngine,
+		TargetEngine:   targetEngine,
+		Status:         types.IndexMigrationStatusPending,
+		KnowledgeBases: progress,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if err := s.repo.Create(ctx, migration); err != nil {
+		return nil, err
+	}
+	if err := s.mirror(ctx, migration); err != nil {
+		return nil, err
+	}
+	if err := s.enqueue(ctx, migration); err != nil {
+		return nil, err
+	}
+
+	logger.Infof(ctx, "[IndexMigration] Started migration %s from %s to %s, knowledge bases: %d",
+		migration.ID, sourceEngine, targetEngine, len(progress))
+	return migration, nil
+}
+
+// GetMigration returns the progress of a migration of the current tenant
+func (s *indexMigrationService) GetMigration(ctx context.Context, id string) (*types.IndexMigration, error) {
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	migration, err := s.repo.GetByID(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if migration == nil {
+		return nil, ErrIndexMigrationNotFound
+	}
+	return migration, nil
+}
+
+// ListMigrations lists the migrations of the current tenant
+func (s *indexMigrationService) ListMigrations(ctx context.Context) ([]*types.IndexMigration, error) {
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	return s.repo.List(ctx, tenantID)
+}
+
+// ResumeMigration re-enqueues a failed migration, which continues from its saved cursors
+func (s *indexMigrationService) ResumeMigration(ctx context.Context, id string) (*types.IndexMigration, error) {
+	migration, err := s.GetMigration(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if migration.Status != types.IndexMigrationStatusFailed {
+		return nil, ErrIndexMigrationNotResumable
+	}
+	active, err := s.repo.GetActive(ctx, migration.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	if active != nil {
+		return nil, ErrIndexMigrationActive
+	}
+
+	if !migration.Switched {
+		// Changes made while the migration was stopped only reached the source engine,
+		// so verified knowledge bases are reconciled again
+		for i := range migration.KnowledgeBases {
+			if migration.KnowledgeBases[i].Phase == types.IndexMigrationPhaseDone {
+				migration.KnowledgeBases[i].Phase = types.IndexMigrationPhaseVerify
+			}
+		}
+		if err := s.mirror(ctx, migration); err != nil {
+			return nil, err
+		}
+	}
+	migration.Status = types.IndexMigrationStatusPending
+	migration.Error = ""
+	migration.FinishedAt = nil
+	migration.UpdatedAt = time.Now()
+	if err := s.repo.Update(ctx, migration); err != nil {
+		return nil, err
+	}
+	if err := s.enqueue(ctx, migration); err != nil {
+		return nil, err
+	}
+	logger.Infof(ctx, "[IndexMigration] Resumed migration %s", migration.ID)
+	return migration, nil
+}
+
+// enqueue submits the migration task
+func (s *indexMigrationService) enqueue(ctx context.Context, migration *types.IndexMigration) error {
+	payload, err := json.Marshal(types.IndexMigrationPayload{
+		TenantID:    migration.TenantID,
+		MigrationID: migration.ID,
+	})
+	if err != nil {
+		return err
+	}
+	task := asynq.NewTask(types.TypeIndexMigration, payload,
+		asynq.MaxRetry(3), asynq.Timeout(indexMigrationTimeout))
+	info, err := s.asynqClient.Enqueue(task)
+	if err != nil {
+		logger.Errorf(ctx, "[IndexMigration] Failed to enqueue migration %s: %v", migration.ID, err)
+		// Do not leave a pending migration behind that blocks new ones
+		migration.Status = types.IndexMigrationStatusFailed
+		migration.Error = err.Error()
+		if saveErr := s.save(ctx, migration); saveErr != nil {
+			logger.Errorf(ctx, "[IndexMigration] Failed to save migration %s: %v", migration.ID, saveErr)
+		}
+		s.unmirror(ctx, migration)
+		return err
+	}
+	logger.Infof(ctx, "[IndexMigration] Enqueued migration %s, task ID: %s", migration.ID, info.ID)
+	return nil
+}
+
+// ProcessIndexMigration handles the asynq index migration task
+func (s *indexMigrationService) ProcessIndexMigration(ctx context.Context, t *asynq.Task) error {
+	var payload types.IndexMigrationPayload
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		logger.Errorf(ctx, "[IndexMigration] Failed to unmarshal payload: %v", err)
+		return err
+	}
+
+	// Set tenant context for downstream services
+	ctx = context.WithValue(ctx, types.TenantIDContextKey, payload.TenantID)
+
+	migration, err := s.repo.GetByID(ctx, payload.TenantID, payload.MigrationID)
+	if err != nil {
+		return err
+	}
+	if migration == nil || !migration.IsActive() {
+		logger.Infof(ctx, "[IndexMigration] Migration %s is not active, skipping", payload.MigrationID)
+		return nil
+	}
+
+	now := time.Now()
+	migration.Status = types.IndexMigrationStatusRunning
+	migration.Error = ""
+	if migration.StartedAt == nil {
+		migration.StartedAt = &now
+	}
+	if err := s.save(ctx, migration); err != nil {
+		return err
+	}
+
+	if err := s.run(ctx, migration); err != nil {
+		logger.Errorf(ctx, "[IndexMigration] Migration %s failed: %v", migration.ID, err)
+		migration.Error = err.Error()
+		// Keep the migration running while asynq still retries it
+		retryCount, _ := asynq.GetRetryCount(ctx)
+		maxRetry, _ := asynq.GetMaxRetry(ctx)
+		if retryCount >= maxRetry {
+			finishedAt := time.Now()
+			migration.Status = types.IndexMigrationStatusFailed
+			migration.FinishedAt = &finishedAt
+			s.unmirror(context.WithoutCancel(ctx), migration)
+		}
+		if saveErr := s.save(context.WithoutCancel(ctx), migration); saveErr != nil {
+			logger.Errorf(ctx, "[IndexMigration] Failed to save migration %s: %v", migration.ID, saveErr)
+		}
+		return err
+	}
+
+	finishedAt := time.Now()
+	migration.Status = types.IndexMigrationStatusCompleted
+	migration.FinishedAt = &finishedAt
+	logger.Infof(ctx, "[IndexMigration] Migration %s completed, copied entries: %d", migration.ID, migration.Copied)
+	return s.save(ctx, migration)
+}
+
+// run copies and verifies every knowledge base, then switches the tenant to the target engine
+func (s *indexMigrationService) run(ctx context.Context, migration *types.IndexMigration) error {
+	source, err := s.registry.GetRetrieveEngineService(migration.SourceEngine)
+	if err != nil {
+		return err
+	}
+	target, err := s.registry.GetRetrieveEngineService(migration.TargetEngine)
+	if err != nil {
+		return err
+	}
+
+	for i := range migration.KnowledgeBases {
+		kb := &migration.KnowledgeBases[i]
+		if kb.Phase == types.IndexMigrationPhaseCopy {
+			if err := s.copyKnowledgeBase(ctx, migration, kb, source, target); err != nil {
+				return fmt.Errorf("copy knowledge base %s: %w", kb.KnowledgeBaseID, err)
+			}
+		}
+		if kb.Phase == types.IndexMigrationPhaseVerify {
+			if err := s.verifyKnowledgeBase(ctx, migration, kb, source, target); err != nil {
+				return fmt.Errorf("verify knowledge base %s: %w", kb.KnowledgeBaseID, err)
+			}
+		}
+	}
+
+	if !migration.Switched {
+		if err := s.switchEngine(ctx, migration); err != nil {
+			return fmt.Errorf("switch engine: %w", err)
+		}
+	}
+	return nil
+}
+
+// copyKnowledgeBase streams the entries of a knowledge base from the source to the target engine,
+// saving the cursor after every page so that an interrupted copy resumes where it stopped
+func (s *indexMigrationService) copyKnowledgeBase(ctx context.Context,
+	migration *types.IndexMigration, kb *types.IndexMigrationKnowledgeBase,
+	source, target interfaces.RetrieveEngineService,
+) error {
+	logger.Infof(ctx, "[IndexMigration] Copying knowledge base %s, cursor: %q", kb.KnowledgeBaseID, kb.Cursor)
+	for {
+		entries, next, err := source.ListIndices(ctx,
+			kb.KnowledgeBaseID, kb.Dimension, kb.Type, kb.Cursor, indexMigrationBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(entries) > 0 {
+			// Mirrored writes or a resumed copy may have written part of this page already
+			if err := target.DeleteBySourceIDList(ctx, entrySourceIDs(entries), kb.Dimension, kb.Type); err != nil {
+				return err
+			}
+			if err := target.SaveIndices(ctx, entries); err != nil {
+				return err
+			}
+			kb.Copied += int64(len(entries))
+			migration.Copied += int64(len(entries))
+		}
+		kb.Cursor = next
+		if next == "" {
+			kb.Phase = types.IndexMigrationPhaseVerify
+		}
+		if err := s.save(ctx, migration); err != nil {
+			return err
+		}
+		if next == "" {
+			return nil
+		}
+	}
+}
+
+// indexEntryState is what verification compares between the source and target copies of an entry
+type indexEntryState struct {
+	enabled bool
+	tagID   string
+	count   int
+}
+
+// verifyKnowledgeBase reconciles entries added, changed or removed in the source engine during the copy,
+// then requires both engines to hold the same number of entries
+func (s *indexMigrationService) verifyKnowledgeBase(ctx context.Context,
+	migration *types.IndexMigration, kb *types.IndexMigrationKnowledgeBase,
+	source, target interfaces.RetrieveEngineService,
+) error {
+	logger.Infof(ctx, "[IndexMigration] Verifying knowledge base %s", kb.KnowledgeBaseID)
+
+	targetStates := make(map[string]*indexEntryState)
+	err := listAllIndices(ctx, target, kb.KnowledgeBaseID, kb.Dimension, kb.Type, func(entries []*types.IndexEntry) error {
+		for _, entry := range entries {
+			if state, ok := targetStates[entry.SourceID]; ok {
+				state.count++
+				continue
+			}
+			targetStates[entry.SourceID] = &indexEntryState{enabled: entry.IsEnabled, tagID: entry.TagID, count: 1}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var sourceCount, repaired int64
+	seen := make(map[string]bool, len(targetStates))
+	err = listAllIndices(ctx, source, kb.KnowledgeBaseID, kb.Dimension, kb.Type, func(entries []*types.IndexEntry) error {
+		var stale []string
+		var missing []*types.IndexEntry
+		for _, entry := range entries {
+			sourceCount++
+			seen[entry.SourceID] = true
+			state, ok := targetStates[entry.SourceID]
+			if ok && state.count == 1 && state.enabled == entry.IsEnabled && state.tagID == entry.TagID {
+				continue
+			}
+			if ok {
+				stale = append(stale, entry.SourceID)
+			}
+			missing = append(missing, entry)
+		}
+		if len(stale) > 0 {
+			if err := target.DeleteBySourceIDList(ctx, stale, kb.Dimension, kb.Type); err != nil {
+				return err
+			}
+		}
+		if len(missing) == 0 {
+			return nil
+		}
+		if err := target.SaveIndices(ctx, missing); err != nil {
+			return err
+		}
+		repaired += int64(len(missing))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Entries deleted from the source during the copy
+	var removed []string
+	for sourceID := range targetStates {
+		if !seen[sourceID] {
+			removed = append(removed, sourceID)
+		}
+	}
+	for batch := range slices.Chunk(removed, indexMigrationBatchSize) {
+		if err := target.DeleteBySourceIDList(ctx, batch, kb.Dimension, kb.Type); err != nil {
+			return err
+		}
+	}
+
+	var targetCount int64
+	err = listAllIndices(ctx, target, kb.KnowledgeBaseID, kb.Dimension, kb.Type, func(entries []*types.IndexEntry) error {
+		targetCount += int64(len(entries))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	kb.SourceCount = sourceCount
+	kb.TargetCount = targetCount
+	migration.Copied += repaired
+	if sourceCount != targetCount {
+		if saveErr := s.save(ctx, migration); saveErr != nil {
+			return saveErr
+		}
+		return fmt.Errorf("entry count mismatch: source %d, target %d", sourceCount, targetCount)
+	}
+	kb.Phase = types.IndexMigrationPhaseDone
+	logger.Infof(ctx, "[IndexMigration] Knowledge base %s verified, entries: %d, repaired: %d, removed: %d",
+		kb.KnowledgeBaseID, sourceCount, repaired, len(removed))
+	return s.save(ctx, migration)
+}
+
+// switchEngine points every retriever type served by the source engine at the target engine
+// and stops mirroring the writes, in a single update
+func (s *indexMigrationService) switchEngine(ctx context.Context, migration *types.IndexMigration) error {
+	tenant, err := s.tenantRepo.GetTenantByID(ctx, migration.TenantID)
+	if err != nil {
+		return err
+	}
+	engines := slices.Clone(tenant.GetServingEngines())
+	for i := range engines {
+		if engines[i].RetrieverEngineType == migration.SourceEngine {
+			engines[i].RetrieverEngineType = migration.TargetEngine
+		}
+	}
+	if err := s.tenantRepo.UpdateRetrieverEngines(ctx, migration.TenantID,
+		types.RetrieverEngines{Engines: engines}); err != nil {
+		return err
+	}
+	migration.Switched = true
+	logger.Infof(ctx, "[IndexMigration] Tenant %d switched from %s to %s",
+		migration.TenantID, migration.SourceEngine, migration.TargetEngine)
+	return s.save(ctx, migration)
+}
+
+// mirror makes the target engine receive every write of the retriever types served by the source engine,
+// so that entries indexed or deleted during the copy reach the target before the tenant is switched
+func (s *indexMigrationService) mirror(ctx context.Context, migration *types.IndexMigration) error {
+	tenant, err := s.tenantRepo.GetTenantByID(ctx, migration.TenantID)
+	if err != nil {
+		return err
+	}
+	engines := tenant.RetrieverEngines
+	engines.Mirrors = nil
+	for _, engine := range tenant.GetServingEngines() {
+		if engine.RetrieverEngineType == migration.SourceEngine {
+			engines.Mirrors = append(engines.Mirrors, types.RetrieverEngineParams{
+				RetrieverEngineType: migration.TargetEngine,
+				RetrieverType:       engine.RetrieverType,
+			})
+		}
+	}
+	if err := s.tenantRepo.UpdateRetrieverEngines(ctx, migration.TenantID, engines); err != nil {
+		return fmt.Errorf("mirror writes to %s: %w", migration.TargetEngine, err)
+	}
+	return nil
+}
+
+// unmirror stops writing to the target engine of a migration that gave up before the switch
+func (s *indexMigrationService) unmirror(ctx context.Context, migration *types.IndexMigration) {
+	if migration.Switched {
+		return
+	}
+	tenant, err := s.tenantRepo.GetTenantByID(ctx, migration.TenantID)
+	if err == nil {
+		engines := tenant.RetrieverEngines
+		engines.Mirrors = nil
+		err = s.tenantRepo.UpdateRetrieverEngines(ctx, migration.TenantID, engines)
+	}
+	if err != nil {
+		logger.Errorf(ctx, "[IndexMigration] Failed to stop mirroring writes of migration %s: %v", migration.ID, err)
+	}
+}
+
+// save persists the progress of a migration
+func (s *indexMigrationService) save(ctx context.Context, migration *types.IndexMigration) error {
+	migration.UpdatedAt = time.Now()
+	return s.repo.Update(ctx, migration)
+}
+
+// listAllIndices pages through all entries of a knowledge base in an engine
+func listAllIndices(ctx context.Context,
+	engine interfaces.RetrieveEngineService, kbID string, dimension int, knowledgeType string,
+	fn func(entries []*types.IndexEntry) error,
+) error {
+	cursor := ""
+	for {
+		entries, next, err := engine.ListIndices(ctx, kbID, dimension, knowledgeType, cursor, indexMigrationBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(entries) > 0 {
+			if err := fn(entries); err != nil {
+				return err
+			}
+		}
+		if next == "" {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// entrySourceIDs returns the source IDs of index entries
+func entrySourceIDs(entries []*types.IndexEntry) []string {
+	ids := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		ids = append(ids, entry.SourceID)
+	}
+	return ids
+}
@@ -0,0 +1,339 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"github.com/hibiken/asynq"
+)
+
+// fakeIndexEngine keeps the index entries of an engine in memory, paged by offset
+type fakeIndexEngine struct {
+	interfaces.RetrieveEngineService
+	engineType types.RetrieverEngineType
+	entries    []*types.IndexEntry
+	// Cursors passed to ListIndices
+	cursors []string
+	// Drop saved entries, as a target that silently loses writes
+	dropSaves bool
+}
+
+func (e *fakeIndexEngine) EngineType() types.RetrieverEngineType { return e.engineType }
+
+func (e *fakeIndexEngine) Support() []types.RetrieverType {
+	return []types.RetrieverType{types.KeywordsRetrieverType, types.VectorRetrieverType}
+}
+
+func (e *fakeIndexEngine) ListIndices(ctx context.Context,
+	knowledgeBaseID string, dimension int, knowledgeType string, cursor string, limit int,
+) ([]*types.IndexEntry, string, error) {
+	e.cursors = append(e.cursors, cursor)
+	var entries []*types.IndexEntry
+	for _, entry := range e.entries {
+		if entry.KnowledgeBaseID == knowledgeBaseID {
+			entries = append(entries, entry)
+		}
+	}
+	offset := 0
+	if cursor != "" {
+		offset, _ = strconv.Atoi(cursor)
+	}
+	end := min(offset+limit, len(entries))
+	next := ""
+	if end < len(entries) {
+		next = strconv.Itoa(end)
+	}
+	return entries[offset:end], next, nil
+}
+
+func (e *fakeIndexEngine) SaveIndices(ctx context.Context, entries []*types.IndexEntry) error {
+	if !e.dropSaves {
+		e.entries = append(e.entries, entries...)
+	}
+	return nil
+}
+
+func (e *fakeIndexEngine) DeleteBySourceIDList(ctx context.Context,
+	sourceIDList []string, dimension int, knowledgeType string,
+) error {
+	e.entries = slices.DeleteFunc(e.entries, func(entry *types.IndexEntry) bool {
+		return slices.Contains(sourceIDList, entry.SourceID)
+	})
+	return nil
+}
+
+// state returns the source ID, tag and enabled flag of the entries of a knowledge base, sorted
+func (e *fakeIndexEngine) state(knowledgeBaseID string) []string {
+	var state []string
+	for _, entry := range e.entries {
+		if entry.KnowledgeBaseID == knowledgeBaseID {
+			state = append(state, fmt.Sprintf("%s/%s/%t", entry.SourceID, entry.TagID, entry.IsEnabled))
+		}
+	}
+	slices.Sort(state)
+	return state
+}
+
+// fakeEngineRegistry returns the fake engines by type
+type fakeEngineRegistry struct {
+	interfaces.RetrieveEngineRegistry
+	engines []*fakeIndexEngine
+}
+
+func (r *fakeEngineRegistry) GetRetrieveEngineService(
+	engineType types.RetrieverEngineType,
+) (interfaces.RetrieveEngineService, error) {
+	for _, engine := range r.engines {
+		if engine.engineType == engineType {
+			return engine, nil
+		}
+	}
+	return nil, fmt.Errorf("engine %s not registered", engineType)
+}
+
+// fakeIndexMigrationRepository keeps one migration in memory
+type fakeIndexMigrationRepository struct {
+	interfaces.IndexMigrationRepository
+	migration *types.IndexMigration
+}
+
+func (r *fakeIndexMigrationRepository) GetByID(ctx context.Context, tenantID uint64, id string) (*types.IndexMigration, error) {
+	if r.migration == nil || r.migration.TenantID != tenantID || r.migration.ID != id {
+		return nil, nil
+	}
+	return r.migration, nil
+}
+
+func (r *fakeIndexMigrationRepository) Update(ctx context.Context, migration *types.IndexMigration) error {
+	r.migration = migration
+	return nil
+}
+
+// fakeMigrationTenantRepository keeps one tenant in memory and calls onUpdate before its engines change
+type fakeMigrationTenantRepository struct {
+	interfaces.TenantRepository
+	tenant   *types.Tenant
+	onUpdate func(engines types.RetrieverEngines)
+}
+
+func (r *fakeMigrationTenantRepository) GetTenantByID(ctx context.Context, id uint64) (*types.Tenant, error) {
+	copied := *r.tenant
+	return &copied, nil
+}
+
+func (r *fakeMigrationTenantRepository) UpdateRetrieverEngines(ctx context.Context,
+	tenantID uint64, engines types.RetrieverEngines,
+) error {
+	if r.onUpdate != nil {
+		r.onUpdate(engines)
+	}
+	r.tenant.RetrieverEngines = engines
+	return nil
+}
+
+// migrationTestEntry returns an index entry of a knowledge base
+func migrationTestEntry(kbID, sourceID, tagID string, enabled bool) *types.IndexEntry {
+	return &types.IndexEntry{
+		IndexInfo: types.IndexInfo{
+			SourceID: sourceID, ChunkID: sourceID, KnowledgeBaseID: kbID, TagID: tagID, IsEnabled: enabled,
+		},
+		Embedding: []float32{0.1, 0.2},
+	}
+}
+
+var (
+	migrationTestServing = []types.RetrieverEngineParams{
+		{RetrieverType: types.KeywordsRetrieverType, RetrieverEngineType: types.PostgresRetrieverEngineType},
+		{RetrieverType: types.VectorRetrieverType, RetrieverEngineType: types.PostgresRetrieverEngineType},
+	}
+	migrationTestMirrors = []types.RetrieverEngineParams{
+		{RetrieverType: types.KeywordsRetrieverType, RetrieverEngineType: types.QdrantRetrieverEngineType},
+		{RetrieverType: types.VectorRetrieverType, RetrieverEngineType: types.QdrantRetrieverEngineType},
+	}
+)
+
+type migrationTest struct {
+	svc        *indexMigrationService
+	repo       *fakeIndexMigrationRepository
+	tenantRepo *fakeMigrationTenantRepository
+	source     *fakeIndexEngine
+	target     *fakeIndexEngine
+	migration  *types.IndexMigration
+}
+
+// newMigrationTest returns a running postgres to qdrant migration of one knowledge base,
+// with the tenant's writes mirrored to qdrant as StartMigration leaves them
+func newMigrationTest(sourceEntries, targetEntries []*types.IndexEntry) *migrationTest {
+	source := &fakeIndexEngine{engineType: types.PostgresRetrieverEngineType, entries: sourceEntries}
+	target := &fakeIndexEngine{engineType: types.QdrantRetrieverEngineType, entries: targetEntries}
+	migration := &types.IndexMigration{
+		ID:           "migration-1",
+		TenantID:     1,
+		SourceEngine: types.PostgresRetrieverEngineType,
+		TargetEngine: types.QdrantRetrieverEngineType,
+		Status:       types.IndexMigrationStatusPending,
+		KnowledgeBases: types.IndexMigrationKnowledgeBases{
+			{KnowledgeBaseID: "kb-1", Dimension: 2, Phase: types.IndexMigrationPhaseCopy},
+		},
+	}
+	repo := &fakeIndexMigrationRepository{migration: migration}
+	tenantRepo := &fakeMigrationTenantRepository{tenant: &types.Tenant{ID: 1, RetrieverEngines: types.RetrieverEngines{
+		Engines: slices.Clone(migrationTestServing),
+		Mirrors: slices.Clone(migrationTestMirrors),
+	}}}
+	svc := &indexMigrationService{
+		repo:       repo,
+		tenantRepo: tenantRepo,
+		registry:   &fakeEngineRegistry{engines: []*fakeIndexEngine{source, target}},
+	}
+	return &migrationTest{svc: svc, repo: repo, tenantRepo: tenantRepo, source: source, target: target, migration: migration}
+}
+
+// process runs the migration task. Without asynq metadata in the context a run counts as the final retry.
+func (m *migrationTest) process(t *testing.T) error {
+	t.Helper()
+	payload, err := json.Marshal(types.IndexMigrationPayload{TenantID: 1, MigrationID: m.migration.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m.svc.ProcessIndexMigration(context.Background(), asynq.NewTask(types.TypeIndexMigration, payload))
+}
+
+func TestIndexMigrationResumesFromSavedCursor(t *testing.T) {
+	var sourceEntries []*types.IndexEntry
+	for i := 1; i <= 5; i++ {
+		sourceEntries = append(sourceEntries, migrationTestEntry("kb-1", fmt.Sprintf("s%d", i), "", true))
+	}
+	sourceEntries = append(sourceEntries, migrationTestEntry("kb-2", "other", "", true))
+	// The interrupted run copied the first page of two entries
+	m := newMigrationTest(sourceEntries, []*types.IndexEntry{
+		migrationTestEntry("kb-1", "s1", "", true),
+		migrationTestEntry("kb-1", "s2", "", true),
+	})
+	kb := &m.migration.KnowledgeBases[0]
+	kb.Cursor, kb.Copied, m.migration.Copied = "2", 2, 2
+
+	if err := m.svc.copyKnowledgeBase(context.Background(), m.migration, kb, m.source, m.target); err != nil {
+		t.Fatalf("copyKnowledgeBase() = %v", err)
+	}
+	if !slices.Equal(m.source.cursors, []string{"2"}) {
+		t.Errorf("source listed from cursors %q, want the saved cursor only", m.source.cursors)
+	}
+	if kb.Phase != types.IndexMigrationPhaseVerify || kb.Cursor != "" || kb.Copied != 5 || m.migration.Copied != 5 {
+		t.Errorf("progress after the copy = %+v, copied %d", kb, m.migration.Copied)
+	}
+	if got, want := m.target.state("kb-1"), m.source.state("kb-1"); !slices.Equal(got, want) {
+		t.Errorf("target holds %q, want %q", got, want)
+	}
+	if got := m.target.state("kb-2"); len(got) != 0 {
+		t.Errorf("entries of another knowledge base were copied: %q", got)
+	}
+}
+
+func TestIndexMigrationVerifyRepairsTarget(t *testing.T) {
+	m := newMigrationTest(
+		[]*types.IndexEntry{
+			migrationTestEntry("kb-1", "same", "tag-1", true),
+			migrationTestEntry("kb-1", "retagged", "tag-2", true),
+			migrationTestEntry("kb-1", "disabled", "", false),
+			migrationTestEntry("kb-1", "duplicated", "", true),
+			migrationTestEntry("kb-1", "missing", "", true),
+		},
+		[]*types.IndexEntry{
+			migrationTestEntry("kb-1", "same", "tag-1", true),
+			migrationTestEntry("kb-1", "retagged", "tag-1", true),
+			migrationTestEntry("kb-1", "disabled", "", true),
+			migrationTestEntry("kb-1", "duplicated", "", true),
+			migrationTestEntry("kb-1", "duplicated", "", true),
+			migrationTestEntry("kb-1", "deleted", "", true),
+		},
+	)
+	kb := &m.migration.KnowledgeBases[0]
+	kb.Phase = types.IndexMigrationPhaseVerify
+
+	if err := m.svc.verifyKnowledgeBase(context.Background(), m.migration, kb, m.source, m.target); err != nil {
+		t.Fatalf("verifyKnowledgeBase() = %v", err)
+	}
+	if got, want := m.target.state("kb-1"), m.source.state("kb-1"); !slices.Equal(got, want) {
+		t.Errorf("target holds %q, want %q", got, want)
+	}
+	if kb.Phase != types.IndexMigrationPhaseDone || kb.SourceCount != 5 || kb.TargetCount != 5 {
+		t.Errorf("progress after verify = %+v", kb)
+	}
+	// retagged, disabled, duplicated and missing were saved again
+	if m.migration.Copied != 4 {
+		t.Errorf("Copied = %d, want 4 repaired entries", m.migration.Copied)
+	}
+}
+
+func TestIndexMigrationSwitchesAfterVerify(t *testing.T) {
+	sourceEntries := []*types.IndexEntry{
+		migrationTestEntry("kb-1", "s1", "", true),
+		migrationTestEntry("kb-1", "s2", "", true),
+		migrationTestEntry("kb-1", "s3", "", true),
+	}
+	m := newMigrationTest(sourceEntries, nil)
+	m.tenantRepo.onUpdate = func(engines types.RetrieverEngines) {
+		for _, kb := range m.repo.migration.KnowledgeBases {
+			if kb.Phase != types.IndexMigrationPhaseDone {
+				t.Errorf("engines switched to %+v while knowledge base %s is in phase %s",
+					engines.Engines, kb.KnowledgeBaseID, kb.Phase)
+			}
+		}
+	}
+
+	if err := m.process(t); err != nil {
+		t.Fatalf("ProcessIndexMigration() = %v", err)
+	}
+	migration := m.repo.migration
+	if migration.Status != types.IndexMigrationStatusCompleted || !migration.Switched || migration.FinishedAt == nil {
+		t.Errorf("migration = %+v, want completed and switched", migration)
+	}
+	engines := m.tenantRepo.tenant.RetrieverEngines
+	if len(engines.Mirrors) != 0 || len(engines.Engines) != len(migrationTestServing) {
+		t.Fatalf("tenant engines = %+v, want the serving engines without mirrors", engines)
+	}
+	for _, engine := range engines.Engines {
+		if engine.RetrieverEngineType != types.QdrantRetrieverEngineType {
+			t.Errorf("tenant engine %+v was not switched to qdrant", engine)
+		}
+	}
+	if got, want := m.target.state("kb-1"), m.source.state("kb-1"); !slices.Equal(got, want) {
+		t.Errorf("target holds %q, want %q", got, want)
+	}
+}
+
+func TestIndexMigrationCountMismatchFails(t *testing.T) {
+	m := newMigrationTest([]*types.IndexEntry{
+		migrationTestEntry("kb-1", "s1", "", true),
+		migrationTestEntry("kb-1", "s2", "", true),
+	}, nil)
+	m.target.dropSaves = true
+
+	err := m.process(t)
+	if err == nil || !strings.Contains(err.Error(), "entry count mismatch: source 2, target 0") {
+		t.Fatalf("ProcessIndexMigration() = %v, want a count mismatch", err)
+	}
+	migration := m.repo.migration
+	if migration.Status != types.IndexMigrationStatusFailed || migration.Switched ||
+		migration.FinishedAt == nil || migration.Error != err.Error() {
+		t.Errorf("migration = %+v, want failed without switching", migration)
+	}
+	kb := migration.KnowledgeBases[0]
+	if kb.Phase != types.IndexMigrationPhaseVerify || kb.SourceCount != 2 || kb.TargetCount != 0 {
+		t.Errorf("progress = %+v, want the counts recorded in the verify phase", kb)
+	}
+
+	// The final retry gave up: the tenant keeps its engines and stops mirroring writes to the target
+	engines := m.tenantRepo.tenant.RetrieverEngines
+	if !slices.Equal(engines.Engines, migrationTestServing) || len(engines.Mirrors) != 0 {
+		t.Errorf("tenant engines = %+v, want the source engines without mirrors", engines)
+	}
+}
@@ -55,22 +55,24 @@ var (
 // knowledgeService implements the knowledge service interface
 // service 实现知识服务接口
 type knowledgeService struct {
-	config          *config.Config
-	retrieveEngine  interfaces.RetrieveEngineRegistry
-	repo            interfaces.KnowledgeRepository
-	kbService       interfaces.KnowledgeBaseService
-	tenantRepo      interfaces.TenantRepository
-	docReaderClient *client.Client
-	chunkService    interfaces.ChunkService
-	chunkRepo       interfaces.ChunkRepository
-	tagRepo         interfaces.KnowledgeTagRepository
-	tagService      interfaces.KnowledgeTagService
-	fileSvc         interfaces.FileService
-	modelService    interfaces.ModelService
-	task            *asynq.Client
-	graphEngine     interfaces.RetrieveGraphRepository
-	redisClient     *redis.Client
-	kbShareService  interfaces.KBShareService
+	config           *config.Config
+	retrieveEngine   interfaces.RetrieveEngineRegistry
+	repo             interfaces.KnowledgeRepository
+	kbService        interfaces.KnowledgeBaseService
+	tenantRepo       interfaces.TenantRepository
+	docReaderClient  *client.Client
+	chunkService     interfaces.ChunkService
+	chunkRepo        interfaces.ChunkRepository
+	tagRepo          interfaces.KnowledgeTagRepository
+	tagService       interfaces.KnowledgeTagService
+	fileSvc          interfaces.FileService
+	modelService     interfaces.ModelService
+	task             *asynq.Client
+	graphEngine      interfaces.RetrieveGraphRepository
+	redisClient      *redis.Client
+	kbShareService   interfaces.KBShareService
+	webhookService   interfaces.WebhookService
+	agentTaskService interfaces.AgentTaskService
 }
 
 const (
@@ -97,24 +99,28 @@ func NewKnowledgeService(
 	retrieveEngine interfaces.RetrieveEngineRegistry,
 	redisClient *redis.Client,
 	kbShareService interfaces.KBShareService,
+	webhookService interfaces.WebhookService,
+	agentTaskService interfaces.AgentTaskService,
 ) (interfaces.KnowledgeService, error) {
 	return &knowledgeService{
-		config:          config,
-		repo:            repo,
-		kbService:       kbService,
-		tenantRepo:      tenantRepo,
-		docReaderClient: docReaderClient,
-		chunkService:    chunkService,
-		chunkRepo:       chunkRepo,
-		tagRepo:         tagRepo,
-		tagService:      tagService,
-		fileSvc:         fileSvc,
-		modelService:    modelService,
-		task:            task,
-		graphEngine:     graphEngine,
-		retrieveEngine:  retrieveEngine,
-		redisClient:     redisClient,
-		kbShareService:  kbShareService,
+		config:           config,
+		repo:             repo,
+		kbService:        kbService,
+		tenantRepo:       tenantRepo,
+		docReaderClient:  docReaderClient,
+		chunkService:     chunkService,
+		chunkRepo:        chunkRepo,
+		tagRepo:          tagRepo,
+		tagService:       tagService,
+		fileSvc:          fileSvc,
+		modelService:     modelService,
+		task:             task,
+		graphEngine:      graphEngine,
+		retrieveEngine:   retrieveEngine,
+		redisClient:      redisClient,
+		kbShareService:   kbShareService,
+		webhookService:   webhookService,
+		agentTaskService: agentTaskService,
 	}, nil
 }
 
@@ -1030,6 +1036,58 @@ func (s *knowledgeService) processDocumentFromPassage(ctx context.Context,
 	s.processChunks(ctx, kb, knowledge, chunks)
 }
 
+// publishParseResult notifies webhooks that parsing of a knowledge finished.
+// Knowledge that is still processing or being deleted is ignored.
+func (s *knowledgeService) publishParseResult(ctx context.Context, knowledge *types.Knowledge) {
+	var event string
+	switch knowledge.ParseStatus {
+	case types.ParseStatusCompleted:
+		event = types.WebhookEventKnowledgeParseCompleted
+	case types.ParseStatusFailed:
+		event = types.WebhookEventKnowledgeParseFailed
+	default:
+		return
+	}
+	data := map[string]interface{}{
+		"knowledge_id":      knowledge.ID,
+		"knowledge_base_id": knowledge.KnowledgeBaseID,
+		"type":              knowledge.Type,
+		"title":             knowledge.Title,
+		"file_name":         knowledge.FileName,
+		"file_type":         knowledge.FileType,
+		"parse_status":      knowledge.ParseStatus,
+		"error_message":     knowledge.ErrorMessage,
+		"processed_at":      knowledge.ProcessedAt,
+	}
+	s.webhookService.Publish(ctx, knowledge.TenantID, event, data)
+	if event == types.WebhookEventKnowledgeParseCompleted {
+		s.agentTaskService.Fire(ctx, knowledge.TenantID, types.AgentTaskTriggerKnowledgeParsed, data)
+	}
+}
+
+// publishFAQImportFinished notifies webhooks that an FAQ import task completed or failed,
+// and fires the agent tasks triggered by completed imports
+func (s *knowledgeService) publishFAQImportFinished(ctx context.Context, progress *types.FAQImportProgress) {
+	tenantID, _ := ctx.Value(types.TenantIDContextKey).(uint64)
+	data := map[string]interface{}{
+		"task_id":            progress.TaskID,
+		"knowledge_base_id":  progress.KBID,
+		"knowledge_id":       progress.KnowledgeID,
+		"status":             progress.Status,
+		"dry_run":            progress.DryRun,
+		"total":              progress.Total,
+		"success_count":      progress.SuccessCount,
+		"failed_count":       progress.FailedCount,
+		"failed_entries_url": progress.FailedEntriesURL,
+		"message":            progress.Message,
+		"error":              progress.Error,
+	}
+	s.webhookService.Publish(ctx, tenantID, types.WebhookEventFAQImportFinished, data)
+	if progress.Status == types.FAQImportStatusCompleted && !progress.DryRun {
+		s.agentTaskService.Fire(ctx, tenantID, types.AgentTaskTriggerFAQImported, data)
+	}
+}
+
 // ProcessChunksOptions contains options for processing chunks
 type ProcessChunksOptions struct {
 	EnableQuestionGeneration bool
@@ -1049,6 +1107,7 @@ func (s *knowledgeService) processChunks(ctx context.Context,
 
 	ctx, span := tracing.ContextWithSpan(ctx, "knowledgeService.processChunks")
 	defer span.End()
+	defer s.publishParseResult(ctx, knowledge)
 	span.SetAttributes(
 		attribute.Int("tenant_id", int(knowledge.TenantID)),
 		attribute.String("knowledge_base_id", knowledge.KnowledgeBaseID),
@@ -1714,6 +1773,12 @@ func (s *knowledgeService) ProcessSummaryGeneration(ctx context.Context, t *asyn
 		logger.Errorf(ctx, "Failed to update knowledge description: %v", err)
 		return fmt.Errorf("failed to update knowledge: %w", err)
 	}
+	s.webhookService.Publish(ctx, knowledge.TenantID, types.WebhookEventSummaryGenerated, map[string]interface{}{
+		"knowledge_id":      knowledge.ID,
+		"knowledge_base_id": knowledge.KnowledgeBaseID,
+		"title":             knowledge.Title,
+		"summary":           summary,
+	})
 
 	// Create summary chunk and index it
 	if strings.TrimSpace(summary) != "" {
@@ -2575,6 +2640,92 @@ func (s *knowledgeService) updateChunkVector(ctx context.Context, kbID string, c
 	return nil
 }
 
+// ReindexChunks rebuilds the index entries of chunks in all engines of the tenant
+func (s *knowledgeService) ReindexChunks(ctx context.Context, kb *types.KnowledgeBase, chunks []*types.Chunk) error {
+	if len(chunks) == 0 {
+		return nil
+	}
+	embeddingModel, err := s.modelService.GetEmbeddingModel(ctx, kb.EmbeddingModelID)
+	if err != nil {
+		return err
+	}
+
+	indexInfoList := make([]*types.IndexInfo, 0, len(chunks))
+	chunkIDs := make([]string, 0, len(chunks))
+	disabled := make(map[string]bool)
+	for _, chunk := range chunks {
+		if chunk.KnowledgeBaseID != kb.ID {
+			logger.Warnf(ctx, "Knowledge base ID mismatch: %s != %s", chunk.KnowledgeBaseID, kb.ID)
+			continue
+		}
+		chunkIDs = append(chunkIDs, chunk.ID)
+		if !chunk.IsEnabled {
+			disabled[chunk.ID] = false
+		}
+
+		if chunk.ChunkType == types.ChunkTypeFAQ {
+			infoList, err := s.buildFAQIndexInfoList(ctx, kb, chunk)
+			if err != nil {
+				return err
+			}
+			indexInfoList = append(indexInfoList, infoList...)
+			continue
+		}
+
+		indexInfoList = append(indexInfoList, &types.IndexInfo{
+			Content:         chunk.Content,
+			SourceID:        chunk.ID,
+			SourceType:      types.ChunkSourceType,
+			ChunkID:         chunk.ID,
+			KnowledgeID:     chunk.KnowledgeID,
+			KnowledgeBaseID: chunk.KnowledgeBaseID,
+			TagID:           chunk.TagID,
+			IsEnabled:       chunk.IsEnabled,
+		})
+		meta, err := chunk.DocumentMetadata()
+		if err != nil {
+			logger.Warnf(ctx, "Failed to parse document metadata of chunk %s: %v", chunk.ID, err)
+			continue
+		}
+		if meta == nil {
+			continue
+		}
+		for _, gq := range meta.GeneratedQuestions {
+			indexInfoList = append(indexInfoList, &types.IndexInfo{
+				Content:         gq.Question,
+				SourceID:        fmt.Sprintf("%s-%s", chunk.ID, gq.ID),
+				SourceType:      types.ChunkSourceType,
+				ChunkID:         chunk.ID,
+				KnowledgeID:     chunk.KnowledgeID,
+				KnowledgeBaseID: chunk.KnowledgeBaseID,
+				TagID:           chunk.TagID,
+				IsEnabled:       chunk.IsEnabled,
+			})
+		}
+	}
+
+	tenantInfo := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
+	retrieveEngine, err := retriever.NewCompositeRetrieveEngine(s.retrieveEngine, tenantInfo.GetEffectiveEngines())
+	if err != nil {
+		return err
+	}
+	if err := retrieveEngine.DeleteByChunkIDList(ctx, chunkIDs, embeddingModel.GetDimensions(), kb.Type); err != nil {
+		return err
+	}
+	if err := retrieveEngine.BatchIndex(ctx, embeddingModel, indexInfoList); err != nil {
+		return err
+	}
+	// New entries are enabled by default
+	if len(disabled) > 0 {
+		if err := retrieveEngine.BatchUpdateChunkEnabledStatus(ctx, disabled); err != nil {
+			return err
+		}
+	}
+	logger.Infof(ctx, "Reindexed %d chunks of knowledge base %s, index entries: %d",
+		len(chunkIDs), kb.ID, len(indexInfoList))
+	return nil
+}
+
 func (s *knowledgeService) UpdateImageInfo(
 	ctx context.Context,
 	knowledgeID string,
@@ -5627,7 +5778,13 @@ func (s *knowledgeService) updateFAQImportProgressStatus(
 		}
 	}
 
-	return s.saveFAQImportProgress(ctx, existingProgress)
+	if err := s.saveFAQImportProgress(ctx, existingProgress); err != nil {
+		return err
+	}
+	if status == types.FAQImportStatusCompleted || status == types.FAQImportStatusFailed {
+		s.publishFAQImportFinished(ctx, existingProgress)
+	}
+	return nil
 }
 
 // cleanupFAQEntriesFileOnFinalFailure 在任务最终失败时清理对象存储中的 entries 文件
@@ -6354,6 +6511,7 @@ func (s *knowledgeService) triggerManualProcessing(ctx context.Context,
 			knowledge.ErrorMessage = cfgErr.Error()
 			knowledge.UpdatedAt = time.Now()
 			s.repo.UpdateKnowledge(ctx, knowledge)
+			s.publishParseResult(ctx, knowledge)
 			return
 		}
 		if cfg == nil {
@@ -6395,6 +6553,7 @@ func (s *knowledgeService) triggerManualProcessing(ctx context.Context,
 		knowledge.ErrorMessage = err.Error()
 		knowledge.UpdatedAt = time.Now()
 		s.repo.UpdateKnowledge(ctx, knowledge)
+		s.publishParseResult(ctx, knowledge)
 		return
 	}
 
@@ -6581,6 +6740,14 @@ func (s *knowledgeService) ProcessDocument(ctx context.Context, t *asynq.Task) e
 		logger.Warnf(ctx, "Unexpected parse status: %s for knowledge: %s", knowledge.ParseStatus, payload.KnowledgeID)
 	}
 
+	// processChunks publishes its own result, failures before it are published here
+	chunksProcessed := false
+	defer func() {
+		if !chunksProcessed {
+			s.publishParseResult(ctx, knowledge)
+		}
+	}()
+
 	// 获取知识库信息
 	kb, err := s.kbService.GetKnowledgeBaseByID(ctx, payload.KnowledgeBaseID)
 	if err != nil {
@@ -6690,6 +6857,7 @@ func (s *knowledgeService) ProcessDocument(ctx context.Context, t *asynq.Task) e
 			chunks = append(chunks, chunk)
 		}
 		// 直接处理chunks，不需要调用docReader
+		chunksProcessed = true
 		s.processChunks(ctx, kb, knowledge, chunks)
 		return nil
 	} else {
@@ -6761,6 +6929,7 @@ func (s *knowledgeService) ProcessDocument(ctx context.Context, t *asynq.Task) e
 	}
 
 	// 处理chunks（这会更新状态为completed）
+	chunksProcessed = true
 	s.processChunks(ctx, kb, knowledge, chunks, ProcessChunksOptions{
 		EnableQuestionGeneration: payload.EnableQuestionGeneration,
 		QuestionCount:            payload.QuestionCount,
@@ -7616,7 +7785,14 @@ func (s *knowledgeService) saveKBCloneProgress(ctx context.Context, progress *ty
 	if err != nil {
 		return fmt.Errorf("failed to marshal progress: %w", err)
 	}
-	return s.redisClient.Set(ctx, key, data, kbCloneProgressTTL).Err()
+	if err := s.redisClient.Set(ctx, key, data, kbCloneProgressTTL).Err(); err != nil {
+		return err
+	}
+	if progress.Status == types.KBCloneStatusCompleted || progress.Status == types.KBCloneStatusFailed {
+		tenantID, _ := ctx.Value(types.TenantIDContextKey).(uint64)
+		s.webhookService.Publish(ctx, tenantID, types.WebhookEventKBCloneFinished, progress)
+	}
+	return nil
 }
 
 // SaveKBCloneProgress saves the KB clone progress to Redis (public method for handler use)
@@ -15,16 +15,19 @@ import (
 // mcpServiceService implements MCPServiceService interface
 type mcpServiceService struct {
 	mcpServiceRepo interfaces.MCPServiceRepository
+	oauthRepo      interfaces.MCPOAuthCredentialRepository
 	mcpManager     *mcp.MCPManager
 }
 
 // NewMCPServiceService creates a new MCP service service
 func NewMCPServiceService(
 	mcpServiceRepo interfaces.MCPServiceRepository,
+	oauthRepo interfaces.MCPOAuthCredentialRepository,
 	mcpManager *mcp.MCPManager,
 ) interfaces.MCPServiceService {
 	return &mcpServiceService{
 		mcpServiceRepo: mcpServiceRepo,
+		oauthRepo:      oauthRepo,
 		mcpManager:     mcpManager,
 	}
 }
@@ -240,6 +243,11 @@ func (s *mcpServiceService) DeleteMCPService(ctx context.Context, tenantID uint6
 		return fmt.Errorf("failed to delete MCP service: %w", err)
 	}
 
+	// Drop stored OAuth tokens of the service
+	if err := s.oauthRepo.Delete(ctx, tenantID, id); err != nil {
+		logger.GetLogger(ctx).Warnf("Failed to delete OAuth credential of MCP service %s: %v", id, err)
+	}
+
 	logger.GetLogger(ctx).Infof("MCP service deleted: %s (ID: %s)", secutils.SanitizeForLog(existing.Name), id)
 	return nil
 }
@@ -260,8 +268,12 @@ func (s *mcpServiceService) TestMCPService(
 	}
 
 	// Create temporary client for testing
-	config := &mcp.ClientConfig{
-		Service: service,
+	config, err := s.mcpManager.ClientConfigFor(service)
+	if err != nil {
+		return &types.MCPTestResult{
+			Success: false,
+			Message: fmt.Sprintf("Failed to prepare client: %v", err),
+		}, nil
 	}
 
 	client, err := mcp.NewMCPClient(config)
@@ -307,6 +319,13 @@ func (s *mcpServiceService) TestMCPService(
 		resources = []*types.MCPResource{}
 	}
 
+	// List prompts
+	prompts, err := client.ListPrompts(testCtx)
+	if err != nil {
+		logger.GetLogger(ctx).Warnf("Failed to list prompts: %v", err)
+		prompts = []*types.MCPPrompt{}
+	}
+
 	return &types.MCPTestResult{
 		Success: true,
 		Message: fmt.Sprintf(
@@ -316,6 +335,7 @@ func (s *mcpServiceService) TestMCPService(
 		),
 		Tools:     tools,
 		Resources: resources,
+		Prompts:   prompts,
 	}, nil
 }
 
@@ -379,6 +399,174 @@ func (s *mcpServiceService) GetMCPServiceResources(
 	return resources, nil
 }
 
+// GetMCPServicePrompts retrieves the list of prompts from an MCP service
+func (s *mcpServiceService) GetMCPServicePrompts(
+	ctx context.Context,
+	tenantID uint64,
+	id string,
+) ([]*types.MCPPrompt, error) {
+	// Get service
+	service, err := s.mcpServiceRepo.GetByID(ctx, tenantID, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get MCP service: %w", err)
+	}
+	if service == nil {
+		return nil, fmt.Errorf("MCP service not found")
+	}
+
+	// Get or create client
+	client, err := s.mcpManager.GetOrCreateClient(service)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get MCP client: %w", err)
+	}
+
+	// List prompts
+	prompts, err := client.ListPrompts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list prompts: %w", err)
+	}
+
+	return prompts, nil
+}
+
+// GetMCPServicePrompt renders a prompt of an MCP service with the given arguments
+func (s *mcpServiceService) GetMCPServicePrompt(
+	ctx context.Context,
+	tenantID uint64,
+	id string,
+	name string,
+	args map[string]string,
+) (*types.MCPPromptResult, error) {
+	// Get service
+	service, err := s.mcpServiceRepo.GetByID(ctx, tenantID, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get MCP service: %w", err)
+	}
+	if service == nil {
+		return nil, fmt.Errorf("MCP service not found")
+	}
+
+	// Get or create client
+	client, err := s.mcpManager.GetOrCreateClient(service)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get MCP client: %w", err)
+	}
+
+	// Render prompt
+	result, err := client.GetPrompt(ctx, name, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get prompt: %w", err)
+	}
+
+	return result, nil
+}
+
+// StartMCPServiceOAuth starts the OAuth authorization flow and returns the authorization URL
+func (s *mcpServiceService) StartMCPServiceOAuth(
+	ctx context.Context,
+	tenantID uint64,
+	id string,
+	redirectURI string,
+) (*types.MCPOAuthAuthorization, error) {
+	service, err := s.mcpServiceRepo.GetByID(ctx, tenantID, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get MCP service: %w", err)
+	}
+	if service == nil {
+		return nil, fmt.Errorf("MCP service not found")
+	}
+
+	authorization, err := s.mcpManager.StartOAuthAuthorization(ctx, service, redirectURI)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.GetLogger(ctx).Infof("OAuth authorization started for MCP service: %s (ID: %s)",
+		secutils.SanitizeForLog(service.Name), service.ID)
+	return authorization, nil
+}
+
+// CompleteMCPServiceOAuth exchanges the authorization code of a callback for tokens
+func (s *mcpServiceService) CompleteMCPServiceOAuth(
+	ctx context.Context,
+	state string,
+	code string,
+) (*types.MCPService, error) {
+	credential, err := s.oauthRepo.GetByPendingState(ctx, state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load OAuth credential: %w", err)
+	}
+	if credential == nil {
+		return nil, mcp.ErrOAuthInvalidState
+	}
+
+	service, err := s.mcpServiceRepo.GetByID(ctx, credential.TenantID, credential.ServiceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get MCP service: %w", err)
+	}
+	if service == nil {
+		return nil, fmt.Errorf("MCP service not found")
+	}
+
+	if err := s.mcpManager.CompleteOAuthAuthorization(ctx, service, credential, state, code); err != nil {
+		return nil, err
+	}
+
+	logger.GetLogger(ctx).Infof("OAuth authorization completed for MCP service: %s (ID: %s)",
+		secutils.SanitizeForLog(service.Name), service.ID)
+	return service, nil
+}
+
+// GetMCPServiceOAuthStatus returns the OAuth authorization status of an MCP service
+func (s *mcpServiceService) GetMCPServiceOAuthStatus(
+	ctx context.Context,
+	tenantID uint64,
+	id string,
+) (*types.MCPOAuthStatus, error) {
+	service, err := s.mcpServiceRepo.GetByID(ctx, tenantID, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get MCP service: %w", err)
+	}
+	if service == nil {
+		return nil, fmt.Errorf("MCP service not found")
+	}
+
+	status := &types.MCPOAuthStatus{Enabled: service.UsesOAuth()}
+	credential, err := s.oauthRepo.GetByServiceID(ctx, tenantID, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load OAuth credential: %w", err)
+	}
+	if credential != nil {
+		status.ClientID = credential.ClientID
+		status.Scope = credential.Scope
+		status.ExpiresAt = credential.ExpiresAt
+		status.Refreshable = credential.RefreshToken != ""
+		status.Authorized = credential.AccessToken != "" &&
+			(credential.ExpiresAt == nil || time.Now().Before(*credential.ExpiresAt) || status.Refreshable)
+	}
+	return status, nil
+}
+
+// RevokeMCPServiceOAuth removes the stored OAuth tokens of an MCP service
+func (s *mcpServiceService) RevokeMCPServiceOAuth(ctx context.Context, tenantID uint64, id string) error {
+	service, err := s.mcpServiceRepo.GetByID(ctx, tenantID, id)
+	if err != nil {
+		return fmt.Errorf("failed to get MCP service: %w", err)
+	}
+	if service == nil {
+		return fmt.Errorf("MCP service not found")
+	}
+
+	s.mcpManager.CloseClient(id)
+	if err := s.oauthRepo.Delete(ctx, tenantID, id); err != nil {
+		return fmt.Errorf("failed to delete OAuth credential: %w", err)
+	}
+
+	logger.GetLogger(ctx).Infof("OAuth authorization revoked for MCP service: %s (ID: %s)",
+		secutils.SanitizeForLog(service.Name), service.ID)
+	return nil
+}
+
 // equalStringSlices compares two string slices for equality
 func equalStringSlices(a, b []string) bool {
 	if len(a) != len(b) {
@@ -0,0 +1,330 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"github.com/google/uuid"
+)
+
+const (
+	// maxUserMemories caps the memories kept per user, recall ranks all of them in memory
+	maxUserMemories = 500
+	// maxMemoryContentChars caps the length of a single memory
+	maxMemoryContentChars = 1000
+	// memoryContextLimit is the number of memories injected into the system prompt
+	memoryContextLimit = 10
+	// minMemoryRecallScore filters out unrelated memories
+	minMemoryRecallScore = 0.2
+)
+
+var (
+	ErrMemoryNoUser        = errors.New("long-term memory requires a signed-in user")
+	ErrMemoryNotFound      = errors.New("memory not found")
+	ErrMemoryEmptyContent  = errors.New("memory content cannot be empty")
+	ErrMemoryContentTooBig = fmt.Errorf("memory content exceeds %d characters", maxMemoryContentChars)
+	ErrMemoryLimitReached  = fmt.Errorf("memory limit of %d reached, forget outdated memories first", maxUserMemories)
+)
+
+// userMemoryService implements the UserMemoryService interface
+type userMemoryService struct {
+	repo         interfaces.UserMemoryRepository
+	modelService interfaces.ModelService
+}
+
+// NewUserMemoryService creates a new user memory service
+func NewUserMemoryService(
+	repo interfaces.UserMemoryRepository,
+	modelService interfaces.ModelService,
+) interfaces.UserMemoryService {
+	return &userMemoryService{repo: repo, modelService: modelService}
+}
+
+// currentUser returns the tenant and user of the context
+func (s *userMemoryService) currentUser(ctx context.Context) (uint64, string, error) {
+	tenantID, _ := ctx.Value(types.TenantIDContextKey).(uint64)
+	userID, _ := ctx.Value(types.UserIDContextKey).(string)
+	if tenantID == 0 || userID == "" {
+		return 0, "", ErrMemoryNoUser
+	}
+	return tenantID, userID, nil
+}
+
+// SaveMemory saves a memory of the current user, saving the same content again refreshes it
+func (s *userMemoryService) SaveMemory(ctx context.Context,
+	content string, agentID string, sessionID string, embeddingModelID string,
+) (*types.UserMemory, error) {
+	tenantID, userID, err := s.currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return nil, ErrMemoryEmptyContent
+	}
+	if len([]rune(content)) > maxMemoryContentChars {
+		return nil, ErrMemoryContentTooBig
+	}
+
+	existing, err := s.repo.GetByContent(ctx, tenantID, userID, content)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		if err := s.repo.Touch(ctx, existing); err != nil {
+			return nil, err
+		}
+		return existing, nil
+	}
+
+	count, err := s.repo.Count(ctx, tenantID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxUserMemories {
+		return nil, ErrMemoryLimitReached
+	}
+
+	memory := &types.UserMemory{
+		ID:        uuid.New().String(),
+		TenantID:  tenantID,
+		UserID:    userID,
+		AgentID:   agentID,
+		SessionID: sessionID,
+		Content:   content,
+	}
+	if embeddingModelID != "" {
+		if embedding, err := s.embed(ctx, embeddingModelID, content); err != nil {
+			// The memory is still recalled by keyword matching
+			logger.Warnf(ctx, "[Memory] Failed to embed memory with model %s: %v", embeddingModelID, err)
+		} else {
+			memory.Embedding = embedding
+			memory.EmbeddingModelID = embeddingModelID
+		}
+	}
+
+	now := time.Now()
+	memory.CreatedAt = now
+	memory.UpdatedAt = now
+	if err := s.repo.Create(ctx, memory); err != nil {
+		return nil, err
+	}
+	logger.Infof(ctx, "[Memory] Saved memory %s for user %s", memory.ID, userID)
+	return memory, nil
+}
+
+// RecallMemories ranks the memories of the current user against the query.
+// Memories embedded with the same model are scored by cosine similarity,
+// the others (and all memories when no model is set) by character bigram overlap.
+func (s *userMemoryService) RecallMemories(ctx context.Context,
+	query string, embeddingModelID string, limit int,
+) ([]*types.UserMemoryRecall, error) {
+	tenantID, userID, err := s.currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	memories, err := s.repo.ListAll(ctx, tenantID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(memories) == 0 {
+		return []*types.UserMemoryRecall{}, nil
+	}
+
+	query = strings.TrimSpace(query)
+	if query == "" {
+		// Without a query the most recent memories are the most relevant
+		results := make([]*types.UserMemoryRecall, 0, limit)
+		for i := 0; i < len(memories) && i < limit; i++ {
+			results = append(results, &types.UserMemoryRecall{Memory: memories[i], Score: 1})
+		}
+		return results, nil
+	}
+
+	var queryEmbedding []float32
+	if embeddingModelID != "" {
+		queryEmbedding, err = s.embed(ctx, embeddingModelID, query)
+		if err != nil {
+			logger.Warnf(ctx, "[Memory] Failed to embed query with model %s, falling back to keyword recall: %v",
+				embeddingModelID, err)
+		}
+	}
+
+	queryBigrams := bigrams(query)
+	results := make([]*types.UserMemoryRecall, 0, len(memories))
+	for _, memory := range memories {
+		var score float64
+		if queryEmbedding != nil && memory.EmbeddingModelID == embeddingModelID && len(memory.Embedding) > 0 {
+			score = cosineSimilarity(queryEmbedding, memory.Embedding)
+		} else {
+			score = bigramOverlap(queryBigrams, bigrams(memory.Content))
+		}
+		if score >= minMemoryRecallScore {
+			results = append(results, &types.UserMemoryRecall{Memory: memory, Score: score})
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// ListMemories lists the memories of the current user, newest first
+func (s *userMemoryService) ListMemories(ctx context.Context, page *types.Pagination) (*types.PageResult, error) {
+	tenantID, userID, err := s.currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	memories, total, err := s.repo.List(ctx, tenantID, userID, page)
+	if err != nil {
+		return nil, err
+	}
+	return types.NewPageResult(total, page, memories), nil
+}
+
+// DeleteMemory deletes a memory of the current user
+func (s *userMemoryService) DeleteMemory(ctx context.Context, id string) error {
+	tenantID, userID, err := s.currentUser(ctx)
+	if err != nil {
+		return err
+	}
+	memory, err := s.repo.GetByID(ctx, tenantID, userID, id)
+	if err != nil {
+		return err
+	}
+	if memory == nil {
+		return ErrMemoryNotFound
+	}
+	if err := s.repo.Delete(ctx, tenantID, userID, id); err != nil {
+		return err
+	}
+	logger.Infof(ctx, "[Memory] Deleted memory %s of user %s", id, userID)
+	return nil
+}
+
+// ClearMemories deletes all memories of the current user
+func (s *userMemoryService) ClearMemories(ctx context.Context) (int64, error) {
+	tenantID, userID, err := s.currentUser(ctx)
+	if err != nil {
+		return 0, err
+	}
+	deleted, err := s.repo.DeleteAll(ctx, tenantID, userID)
+	if err != nil {
+		return 0, err
+	}
+	logger.Infof(ctx, "[Memory] Cleared %d memories of user %s", deleted, userID)
+	return deleted, nil
+}
+
+// BuildMemoryContext renders the memories relevant to the query, filled up with the most recent ones,
+// as a system prompt section
+func (s *userMemoryService) BuildMemoryContext(ctx context.Context, query string, embeddingModelID string) string {
+	recalled, err := s.RecallMemories(ctx, query, embeddingModelID, memoryContextLimit)
+	if err != nil {
+		if !errors.Is(err, ErrMemoryNoUser) {
+			logger.Warnf(ctx, "[Memory] Failed to recall memories: %v", err)
+		}
+		return ""
+	}
+	if len(recalled) < memoryContextLimit {
+		// Stable preferences rarely match the wording of a question, fill up with the most recent memories
+		recent, err := s.RecallMemories(ctx, "", "", memoryContextLimit)
+		if err != nil {
+			logger.Warnf(ctx, "[Memory] Failed to list recent memories: %v", err)
+		}
+		included := make(map[string]bool, len(recalled))
+		for _, r := range recalled {
+			included[r.Memory.ID] = true
+		}
+		for _, r := range recent {
+			if len(recalled) >= memoryContextLimit {
+				break
+			}
+			if !included[r.Memory.ID] {
+				recalled = append(recalled, r)
+			}
+		}
+	}
+	if len(recalled) == 0 {
+		return ""
+	}
+
+	var builder strings.Builder
+	builder.WriteString("## User Memory\n")
+	builder.WriteString("Facts and preferences the user shared in earlier conversations. ")
+	builder.WriteString("Use them to personalize the answer when relevant; ")
+	builder.WriteString("if the user contradicts one, follow the user and update or forget the memory.\n")
+	for _, r := range recalled {
+		fmt.Fprintf(&builder, "- [%s] %s (saved %s)\n",
+			r.Memory.ID, r.Memory.Content, r.Memory.UpdatedAt.Format("2006-01-02"))
+	}
+	return builder.String()
+}
+
+// embed embeds a text with the given embedding model
+func (s *userMemoryService) embed(ctx context.Context, modelID string, text string) ([]float32, error) {
+	embedder, err := s.modelService.GetEmbeddingModel(ctx, modelID)
+	if err != nil {
+		return nil, err
+	}
+	return embedder.Embed(ctx, text)
+}
+
+// cosineSimilarity returns the cosine similarity of two vectors, 0 when their dimensions differ
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// bigrams returns the lowercase character bigrams of a text, ignoring spaces and punctuation.
+// Character bigrams work for both Chinese and space-separated languages.
+func bigrams(text string) map[string]bool {
+	runes := make([]rune, 0, len(text))
+	for _, r := range strings.ToLower(text) {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			runes = append(runes, r)
+		}
+	}
+	result := make(map[string]bool, len(runes))
+	for i := 0; i+1 < len(runes); i++ {
+		result[string(runes[i:i+2])] = true
+	}
+	return result
+}
+
+// bigramOverlap returns the fraction of query bigrams found in the memory
+func bigramOverlap(query, memory map[string]bool) float64 {
+	if len(query) == 0 {
+		return 0
+	}
+	matched := 0
+	for g := range query {
+		if memory[g] {
+			matched++
+		}
+	}
+	return float64(matched) / float64(len(query))
+}
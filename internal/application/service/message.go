@@ -5,6 +5,7 @@ import (
 	"errors"
 	"time"
 
+	"github.com/Tencent/WeKnora/internal/application/service/llmcontext"
 	"github.com/Tencent/WeKnora/internal/logger"
 	"github.com/Tencent/WeKnora/internal/types"
 	"github.com/Tencent/WeKnora/internal/types/interfaces"
@@ -13,22 +14,34 @@ import (
 // messageService implements the MessageService interface for managing messaging operations
 // It handles creating, retrieving, updating, and deleting messages within sessions
 type messageService struct {
-	messageRepo interfaces.MessageRepository // Repository for message storage operations
-	sessionRepo interfaces.SessionRepository // Repository for session validation
+	messageRepo    interfaces.MessageRepository    // Repository for message storage operations
+	sessionRepo    interfaces.SessionRepository    // Repository for session validation
+	webhookService interfaces.WebhookService       // Notifies webhooks of completed answers
+	contextStorage llmcontext.ContextStorage       // LLM context rebuilt when switching branches
+	traceRepo      interfaces.AgentTraceRepository // Traces of the agent runs of messages
 }
 
 // NewMessageService creates a new message service instance with the required repositories
 // Parameters:
 //   - messageRepo: Repository for persisting and retrieving messages
 //   - sessionRepo: Repository for validating session existence
+//   - webhookService: Service for notifying webhooks of completed answers
+//   - contextStorage: Storage of the LLM context, rebuilt when switching branches
+//   - traceRepo: Repository of the agent run traces, deleted with their messages
 //
 // Returns an implementation of the MessageService interface
 func NewMessageService(messageRepo interfaces.MessageRepository,
 	sessionRepo interfaces.SessionRepository,
+	webhookService interfaces.WebhookService,
+	contextStorage llmcontext.ContextStorage,
+	traceRepo interfaces.AgentTraceRepository,
 ) interfaces.MessageService {
 	return &messageService{
-		messageRepo: messageRepo,
-		sessionRepo: sessionRepo,
+		messageRepo:    messageRepo,
+		sessionRepo:    sessionRepo,
+		webhookService: webhookService,
+		contextStorage: contextStorage,
+		traceRepo:      traceRepo,
 	}
 }
 
@@ -49,7 +62,8 @@ func sessionTenantIDForLookup(ctx context.Context) (uint64, bool) {
 }
 
 // CreateMessage creates a new message within an existing session
-// It validates that the session exists before creating the message
+// It validates that the session exists before creating the message,
+// and appends the message to the active branch of the session
 // Parameters:
 //   - ctx: Context containing tenant information
 //   - message: The message to be created
@@ -62,12 +76,20 @@ func (s *messageService) CreateMessage(ctx context.Context, message *types.Messa
 	// Check if the session exists to validate the message belongs to a valid session
 	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
 	logger.Infof(ctx, "Checking if session exists, tenant ID: %d, session ID: %s", tenantID, message.SessionID)
-	_, err := s.sessionRepo.Get(ctx, tenantID, message.SessionID)
+	session, err := s.sessionRepo.Get(ctx, tenantID, message.SessionID)
 	if err != nil {
 		logger.Errorf(ctx, "Failed to get session: %v", err)
 		return nil, err
 	}
 
+	// Continue the active branch of the conversation
+	parentID, err := s.activeLeafID(ctx, session)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get active message: %v", err)
+		return nil, err
+	}
+	message.ParentID = parentID
+
 	// Create the message in the repository
 	logger.Info(ctx, "Session exists, creating message")
 	createdMessage, err := s.messageRepo.CreateMessage(ctx, message)
@@ -77,6 +99,7 @@ func (s *messageService) CreateMessage(ctx context.Context, message *types.Messa
 		})
 		return nil, err
 	}
+	s.setActiveMessage(ctx, session, createdMessage.ID)
 
 	logger.Infof(ctx, "Message created successfully, ID: %s", createdMessage.ID)
 	return createdMessage, nil
@@ -156,7 +179,7 @@ func (s *messageService) GetMessagesBySession(ctx context.Context,
 	return messages, nil
 }
 
-// GetRecentMessagesBySession retrieves the most recent messages from a session
+// GetRecentMessagesBySession retrieves the most recent messages of the active branch of a session
 // This is typically used for loading the initial conversation history
 // Parameters:
 //   - ctx: Context containing tenant information
@@ -177,15 +200,15 @@ func (s *messageService) GetRecentMessagesBySession(ctx context.Context,
 		return nil, errors.New("tenant ID not found in context")
 	}
 	logger.Infof(ctx, "Checking if session exists, tenant ID: %d", tenantID)
-	_, err := s.sessionRepo.Get(ctx, tenantID, sessionID)
+	session, err := s.sessionRepo.Get(ctx, tenantID, sessionID)
 	if err != nil {
 		logger.Errorf(ctx, "Failed to get session: %v", err)
 		return nil, err
 	}
 
-	// Retrieve the most recent messages
+	// Retrieve the most recent messages of the active branch
 	logger.Info(ctx, "Session exists, getting recent messages")
-	messages, err := s.messageRepo.GetRecentMessagesBySession(ctx, sessionID, limit)
+	messages, err := s.activeBranch(ctx, session)
 	if err != nil {
 		logger.ErrorWithFields(ctx, err, map[string]interface{}{
 			"session_id": sessionID,
@@ -193,12 +216,15 @@ func (s *messageService) GetRecentMessagesBySession(ctx context.Context,
 		})
 		return nil, err
 	}
+	if len(messages) > limit {
+		messages = messages[len(messages)-limit:]
+	}
 
 	logger.Infof(ctx, "Retrieved %d recent messages successfully", len(messages))
 	return messages, nil
 }
 
-// GetMessagesBySessionBeforeTime retrieves messages sent before a specific time
+// GetMessagesBySessionBeforeTime retrieves messages of the active branch sent before a specific time
 // This is typically used for pagination when scrolling through conversation history
 // Parameters:
 //   - ctx: Context containing tenant information
@@ -220,15 +246,15 @@ func (s *messageService) GetMessagesBySessionBeforeTime(ctx context.Context,
 		return nil, errors.New("tenant ID not found in context")
 	}
 	logger.Infof(ctx, "Checking if session exists, tenant ID: %d", tenantID)
-	_, err := s.sessionRepo.Get(ctx, tenantID, sessionID)
+	session, err := s.sessionRepo.Get(ctx, tenantID, sessionID)
 	if err != nil {
 		logger.Errorf(ctx, "Failed to get session: %v", err)
 		return nil, err
 	}
 
-	// Retrieve messages before the specified time
+	// Retrieve messages of the active branch before the specified time
 	logger.Info(ctx, "Session exists, getting messages before time")
-	messages, err := s.messageRepo.GetMessagesBySessionBeforeTime(ctx, sessionID, beforeTime, limit)
+	branch, err := s.activeBranch(ctx, session)
 	if err != nil {
 		logger.ErrorWithFields(ctx, err, map[string]interface{}{
 			"session_id":  sessionID,
@@ -237,6 +263,15 @@ func (s *messageService) GetMessagesBySessionBeforeTime(ctx context.Context,
 		})
 		return nil, err
 	}
+	messages := make([]*types.Message, 0, limit)
+	for _, m := range branch {
+		if m.CreatedAt.Before(beforeTime) {
+			messages = append(messages, m)
+		}
+	}
+	if len(messages) > limit {
+		messages = messages[len(messages)-limit:]
+	}
 
 	logger.Infof(ctx, "Retrieved %d messages before time successfully", len(messages))
 	return messages, nil
@@ -272,6 +307,17 @@ func (s *messageService) UpdateMessage(ctx context.Context, message *types.Messa
 		return err
 	}
 
+	// Assistant messages are saved as completed once the answer has been generated
+	if message.Role == "assistant" && message.IsCompleted {
+		s.webhookService.Publish(ctx, tenantID, types.WebhookEventMessageCompleted, map[string]interface{}{
+			"session_id":      message.SessionID,
+			"message_id":      message.ID,
+			"request_id":      message.RequestID,
+			"content":         message.Content,
+			"reference_count": len(message.KnowledgeReferences),
+		})
+	}
+
 	logger.Info(ctx, "Message updated successfully")
 	return nil
 }
@@ -290,11 +336,16 @@ func (s *messageService) DeleteMessage(ctx context.Context, sessionID string, me
 	// Verify the session exists before deleting the message
 	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
 	logger.Infof(ctx, "Checking if session exists, tenant ID: %d", tenantID)
-	_, err := s.sessionRepo.Get(ctx, tenantID, sessionID)
+	session, err := s.sessionRepo.Get(ctx, tenantID, sessionID)
 	if err != nil {
 		logger.Errorf(ctx, "Failed to get session: %v", err)
 		return err
 	}
+	message, err := s.messageRepo.GetMessage(ctx, sessionID, messageID)
+	if err != nil {
+		logger.Warnf(ctx, "Failed to get message before deleting: %v", err)
+		message = nil
+	}
 
 	// Delete the message from the repository
 	logger.Info(ctx, "Session exists, deleting message")
@@ -307,6 +358,24 @@ func (s *messageService) DeleteMessage(ctx context.Context, sessionID string, me
 		return err
 	}
 
+	// Keep the conversation tree connected
+	if message != nil {
+		if err := s.messageRepo.ReparentMessages(ctx, sessionID, messageID, message.ParentID); err != nil {
+			logger.ErrorWithFields(ctx, err, map[string]interface{}{
+				"session_id": sessionID,
+				"message_id": messageID,
+			})
+			return err
+		}
+		if session.ActiveMessageID == messageID {
+			s.setActiveMessage(ctx, session, message.ParentID)
+		}
+	}
+
+	if err := s.traceRepo.DeleteByMessage(ctx, tenantID, sessionID, messageID); err != nil {
+		logger.Warnf(ctx, "Failed to delete agent traces of message %s: %v", messageID, err)
+	}
+
 	logger.Info(ctx, "Message deleted successfully")
 	return nil
 }
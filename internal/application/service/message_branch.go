@@ -0,0 +1,325 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"sort"
+
+	werrors "github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/models/chat"
+	"github.com/Tencent/WeKnora/internal/types"
+	"gorm.io/gorm"
+)
+
+var ErrBranchMessageNotFound = errors.New("message not found in session")
+
+// thinkTagRegex removes the thinking process from answers written back to the LLM context
+var thinkTagRegex = regexp.MustCompile(`(?s)<think>.*?</think>`)
+
+// messageTree is the conversation tree of a session, linked by Message.ParentID
+type messageTree struct {
+	ordered  []*types.Message
+	byID     map[string]*types.Message
+	children map[string][]*types.Message
+}
+
+// newMessageTree builds the conversation tree from the messages of a session in creation order
+func newMessageTree(messages []*types.Message) *messageTree {
+	tree := &messageTree{
+		ordered:  messages,
+		byID:     make(map[string]*types.Message, len(messages)),
+		children: make(map[string][]*types.Message),
+	}
+	for _, m := range messages {
+		tree.byID[m.ID] = m
+	}
+	for _, m := range messages {
+		tree.children[tree.parentOf(m)] = append(tree.children[tree.parentOf(m)], m)
+	}
+	return tree
+}
+
+// parentOf returns the parent ID of a message, empty when the parent no longer exists
+func (t *messageTree) parentOf(m *types.Message) string {
+	if _, ok := t.byID[m.ParentID]; ok {
+		return m.ParentID
+	}
+	return ""
+}
+
+// activeLeaf returns the last message of the active branch,
+// falling back to the latest message when the active message is not set or no longer exists
+func (t *messageTree) activeLeaf(activeMessageID string) *types.Message {
+	if m, ok := t.byID[activeMessageID]; ok {
+		return m
+	}
+	if len(t.ordered) == 0 {
+		return nil
+	}
+	return t.ordered[len(t.ordered)-1]
+}
+
+// latestLeaf follows the most recent child from a message down to the end of its branch
+func (t *messageTree) latestLeaf(m *types.Message) *types.Message {
+	visited := map[string]bool{}
+	for !visited[m.ID] {
+		visited[m.ID] = true
+		children := t.children[m.ID]
+		if len(children) == 0 {
+			break
+		}
+		m = children[len(children)-1]
+	}
+	return m
+}
+
+// path returns the messages from the first message to the leaf, with the sibling IDs of edited
+// or regenerated messages filled in
+func (t *messageTree) path(leaf *types.Message) []*types.Message {
+	if leaf == nil {
+		return []*types.Message{}
+	}
+	var reversed []*types.Message
+	visited := map[string]bool{}
+	for m := leaf; m != nil && !visited[m.ID]; m = t.byID[t.parentOf(m)] {
+		visited[m.ID] = true
+		reversed = append(reversed, m)
+	}
+
+	path := make([]*types.Message, 0, len(reversed))
+	for i := len(reversed) - 1; i >= 0; i-- {
+		m := reversed[i]
+		if siblings := t.children[t.parentOf(m)]; len(siblings) > 1 {
+			m.SiblingIDs = make([]string, 0, len(siblings))
+			for _, sibling := range siblings {
+				m.SiblingIDs = append(m.SiblingIDs, sibling.ID)
+			}
+		}
+		path = append(path, m)
+	}
+	return path
+}
+
+// leaves returns the last messages of all branches
+func (t *messageTree) leaves() []*types.Message {
+	var leaves []*types.Message
+	for _, m := range t.ordered {
+		if len(t.children[m.ID]) == 0 {
+			leaves = append(leaves, m)
+		}
+	}
+	return leaves
+}
+
+// getBranchSession gets a session of the current tenant
+func (s *messageService) getBranchSession(ctx context.Context, sessionID string) (*types.Session, error) {
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	session, err := s.sessionRepo.Get(ctx, tenantID, sessionID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get session: %v", err)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, werrors.ErrSessionNotFound
+		}
+		return nil, err
+	}
+	return session, nil
+}
+
+// loadMessageTree loads the conversation tree of a session
+func (s *messageService) loadMessageTree(ctx context.Context, sessionID string) (*messageTree, error) {
+	messages, err := s.messageRepo.GetAllMessagesBySession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return newMessageTree(messages), nil
+}
+
+// activeBranch returns the messages of the active branch of a session
+func (s *messageService) activeBranch(ctx context.Context, session *types.Session) ([]*types.Message, error) {
+	tree, err := s.loadMessageTree(ctx, session.ID)
+	if err != nil {
+		return nil, err
+	}
+	return tree.path(tree.activeLeaf(session.ActiveMessageID)), nil
+}
+
+// activeLeafID returns the ID of the last message of the active branch, empty for a session without messages
+func (s *messageService) activeLeafID(ctx context.Context, session *types.Session) (string, error) {
+	if session.ActiveMessageID != "" {
+		return session.ActiveMessageID, nil
+	}
+	// Sessions created before branching have no active message, continue from the latest one
+	latest, err := s.messageRepo.GetRecentMessagesBySession(ctx, session.ID, 1)
+	if err != nil {
+		return "", err
+	}
+	if len(latest) == 0 {
+		return "", nil
+	}
+	return latest[len(latest)-1].ID, nil
+}
+
+// CreateBranchMessage creates a message as a child of parentID and makes it the active branch.
+// The LLM context of the session is rebuilt from the new branch so that the agent does not see
+// the answers of the branch the user moved away from.
+func (s *messageService) CreateBranchMessage(ctx context.Context,
+	message *types.Message, parentID string,
+) (*types.Message, error) {
+	logger.Infof(ctx, "Creating branch message for session ID: %s, parent ID: %s", message.SessionID, parentID)
+
+	session, err := s.getBranchSession(ctx, message.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	tree, err := s.loadMessageTree(ctx, session.ID)
+	if err != nil {
+		return nil, err
+	}
+	var parent *types.Message
+	if parentID != "" {
+		var ok bool
+		if parent, ok = tree.byID[parentID]; !ok {
+			return nil, ErrBranchMessageNotFound
+		}
+	}
+
+	message.ParentID = parentID
+	createdMessage, err := s.messageRepo.CreateMessage(ctx, message)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"session_id": message.SessionID,
+			"parent_id":  parentID,
+		})
+		return nil, err
+	}
+	s.setActiveMessage(ctx, session, createdMessage.ID)
+	s.resetBranchContext(ctx, session.ID, tree.path(parent))
+
+	logger.Infof(ctx, "Branch message created successfully, ID: %s", createdMessage.ID)
+	return createdMessage, nil
+}
+
+// ListBranches lists the branches of a session, most recently updated first
+func (s *messageService) ListBranches(ctx context.Context, sessionID string) ([]*types.MessageBranch, error) {
+	session, err := s.getBranchSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	tree, err := s.loadMessageTree(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	active := tree.activeLeaf(session.ActiveMessageID)
+
+	branches := make([]*types.MessageBranch, 0)
+	for _, leaf := range tree.leaves() {
+		path := tree.path(leaf)
+		branch := &types.MessageBranch{
+			LeafID:       leaf.ID,
+			MessageCount: len(path),
+			IsActive:     active != nil && active.ID == leaf.ID,
+			UpdatedAt:    leaf.UpdatedAt,
+		}
+		for _, m := range path {
+			if len(m.SiblingIDs) > 1 {
+				branch.ForkMessageID = m.ID
+			}
+			if m.Role == "user" {
+				branch.LastQuery = m.Content
+			}
+		}
+		branches = append(branches, branch)
+	}
+	sort.SliceStable(branches, func(i, j int) bool {
+		return branches[i].UpdatedAt.After(branches[j].UpdatedAt)
+	})
+	return branches, nil
+}
+
+// SwitchBranch makes the most recent branch containing the message active and returns its messages
+func (s *messageService) SwitchBranch(ctx context.Context,
+	sessionID string, messageID string,
+) ([]*types.Message, error) {
+	logger.Infof(ctx, "Switching branch, session ID: %s, message ID: %s", sessionID, messageID)
+
+	session, err := s.getBranchSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	tree, err := s.loadMessageTree(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	message, ok := tree.byID[messageID]
+	if !ok {
+		return nil, ErrBranchMessageNotFound
+	}
+
+	leaf := tree.latestLeaf(message)
+	s.setActiveMessage(ctx, session, leaf.ID)
+	branch := tree.path(leaf)
+	s.resetBranchContext(ctx, sessionID, branch)
+
+	logger.Infof(ctx, "Switched to branch ending at message %s", leaf.ID)
+	return branch, nil
+}
+
+// setActiveMessage records the last message of the active branch, failures only affect
+// which branch is shown and continued, so they are logged
+func (s *messageService) setActiveMessage(ctx context.Context, session *types.Session, messageID string) {
+	if err := s.sessionRepo.UpdateActiveMessage(ctx, session.TenantID, session.ID, messageID); err != nil {
+		logger.Warnf(ctx, "Failed to update active message of session %s: %v", session.ID, err)
+		return
+	}
+	session.ActiveMessageID = messageID
+}
+
+// resetBranchContext rewrites the LLM context of a session with the completed turns of a branch.
+// Tool calls of earlier turns are not stored with the messages and are not restored.
+func (s *messageService) resetBranchContext(ctx context.Context, sessionID string, branch []*types.Message) {
+	if s.contextStorage == nil {
+		return
+	}
+	// The question being answered is added by the agent itself
+	for len(branch) > 0 && branch[len(branch)-1].Role == "user" {
+		branch = branch[:len(branch)-1]
+	}
+
+	existing, err := s.contextStorage.Load(ctx, sessionID)
+	if err != nil {
+		logger.Warnf(ctx, "Failed to load LLM context of session %s: %v", sessionID, err)
+	}
+	messages := make([]chat.Message, 0, len(branch)+1)
+	for _, m := range existing {
+		if m.Role == "system" {
+			messages = append(messages, m)
+		}
+	}
+	for _, m := range branch {
+		if m.Role != "user" && m.Role != "assistant" {
+			continue
+		}
+		if m.Role == "assistant" && !m.IsCompleted {
+			continue
+		}
+		content := m.Content
+		if m.Role == "assistant" {
+			content = thinkTagRegex.ReplaceAllString(content, "")
+		}
+		if content == "" {
+			continue
+		}
+		messages = append(messages, chat.Message{Role: m.Role, Content: content})
+	}
+
+	if err := s.contextStorage.Save(ctx, sessionID, messages); err != nil {
+		logger.Warnf(ctx, "Failed to rebuild LLM context of session %s: %v", sessionID, err)
+		return
+	}
+	logger.Infof(ctx, "Rebuilt LLM context of session %s from %d branch messages", sessionID, len(messages))
+}
@@ -0,0 +1,329 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	werrors "github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/models/chat"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"gorm.io/gorm"
+)
+
+// fakeBranchMessageRepository keeps the messages of all sessions in creation order
+type fakeBranchMessageRepository struct {
+	interfaces.MessageRepository
+	messages []*types.Message
+	clock    time.Time
+}
+
+func (f *fakeBranchMessageRepository) CreateMessage(ctx context.Context, message *types.Message) (*types.Message, error) {
+	if message.ID == "" {
+		message.ID = fmt.Sprintf("m%d", len(f.messages)+1)
+	}
+	f.clock = f.clock.Add(time.Minute)
+	message.UpdatedAt = f.clock
+	saved := *message
+	f.messages = append(f.messages, &saved)
+	return message, nil
+}
+
+// GetAllMessagesBySession returns copies, the tree fills in the sibling IDs of what it loads
+func (f *fakeBranchMessageRepository) GetAllMessagesBySession(
+	ctx context.Context,
+	sessionID string,
+) ([]*types.Message, error) {
+	var messages []*types.Message
+	for _, m := range f.messages {
+		if m.SessionID == sessionID {
+			message := *m
+			messages = append(messages, &message)
+		}
+	}
+	return messages, nil
+}
+
+// fakeBranchSessionRepository holds sessions of several tenants
+type fakeBranchSessionRepository struct {
+	interfaces.SessionRepository
+	sessions []*types.Session
+}
+
+func (f *fakeBranchSessionRepository) Get(ctx context.Context, tenantID uint64, id string) (*types.Session, error) {
+	for _, s := range f.sessions {
+		if s.TenantID == tenantID && s.ID == id {
+			session := *s
+			return &session, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f *fakeBranchSessionRepository) UpdateActiveMessage(
+	ctx context.Context,
+	tenantID uint64,
+	id string,
+	messageID string,
+) error {
+	for _, s := range f.sessions {
+		if s.TenantID == tenantID && s.ID == id {
+			s.ActiveMessageID = messageID
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+// fakeContextStorage keeps the LLM context of sessions in memory
+type fakeContextStorage map[string][]chat.Message
+
+func (f fakeContextStorage) Save(ctx context.Context, sessionID string, messages []chat.Message) error {
+	f[sessionID] = messages
+	return nil
+}
+
+func (f fakeContextStorage) Load(ctx context.Context, sessionID string) ([]chat.Message, error) {
+	return f[sessionID], nil
+}
+
+func (f fakeContextStorage) Delete(ctx context.Context, sessionID string) error {
+	delete(f, sessionID)
+	return nil
+}
+
+// branchTest is a session "s1" of tenant 1 with the conversation q1 a1 q2 a2 q3 a3 (messages m1 to m6),
+// a session "s2" of the same tenant and a session "s3" of tenant 2
+type branchTest struct {
+	service  *messageService
+	messages *fakeBranchMessageRepository
+	sessions *fakeBranchSessionRepository
+	context  fakeContextStorage
+	ctx      context.Context
+}
+
+func newBranchTest(t *testing.T) *branchTest {
+	t.Helper()
+	test := &branchTest{
+		messages: &fakeBranchMessageRepository{clock: time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)},
+		sessions: &fakeBranchSessionRepository{sessions: []*types.Session{
+			{ID: "s1", TenantID: 1},
+			{ID: "s2", TenantID: 1},
+			{ID: "s3", TenantID: 2},
+		}},
+		context: fakeContextStorage{"s1": {{Role: "system", Content: "You are a helpful assistant."}}},
+		ctx:     context.WithValue(context.Background(), types.TenantIDContextKey, uint64(1)),
+	}
+	test.service = &messageService{
+		messageRepo:    test.messages,
+		sessionRepo:    test.sessions,
+		contextStorage: test.context,
+	}
+
+	parentID := ""
+	for i, content := range []string{"q1", "<think>hmm</think>a1", "q2", "a2", "q3", "a3"} {
+		role := "user"
+		if i%2 == 1 {
+			role = "assistant"
+		}
+		m, err := test.service.CreateBranchMessage(test.ctx, &types.Message{
+			SessionID:   "s1",
+			Role:        role,
+			Content:     content,
+			IsCompleted: true,
+		}, parentID)
+		if err != nil {
+			t.Fatalf("CreateBranchMessage(%q) = %v", content, err)
+		}
+		parentID = m.ID
+	}
+	if _, err := test.service.CreateBranchMessage(test.ctx,
+		&types.Message{SessionID: "s2", Role: "user", Content: "other", IsCompleted: true}, ""); err != nil {
+		t.Fatal(err)
+	}
+	return test
+}
+
+// activeMessage returns the active message of session s1
+func (b *branchTest) activeMessage() string {
+	return b.sessions.sessions[0].ActiveMessageID
+}
+
+// contents returns the contents of messages
+func contents(messages []*types.Message) []string {
+	contents := make([]string, 0, len(messages))
+	for _, m := range messages {
+		contents = append(contents, m.Content)
+	}
+	return contents
+}
+
+func TestCreateBranchMessageFromMiddleMessage(t *testing.T) {
+	b := newBranchTest(t)
+
+	// Regenerate the answer to q2
+	answer, err := b.service.CreateBranchMessage(b.ctx,
+		&types.Message{SessionID: "s1", Role: "assistant", Content: "a2 again"}, "m3")
+	if err != nil {
+		t.Fatalf("CreateBranchMessage() = %v", err)
+	}
+	if answer.ParentID != "m3" || b.activeMessage() != answer.ID {
+		t.Errorf("new answer parent %q, active message %q, want parent m3 and active %s",
+			answer.ParentID, b.activeMessage(), answer.ID)
+	}
+
+	// The agent continues from the turns before the question, without the thinking process
+	// and without the answers of the branch the user moved away from
+	want := []chat.Message{
+		{Role: "system", Content: "You are a helpful assistant."},
+		{Role: "user", Content: "q1"},
+		{Role: "assistant", Content: "a1"},
+	}
+	if !reflect.DeepEqual(b.context["s1"], want) {
+		t.Errorf("LLM context = %+v, want %+v", b.context["s1"], want)
+	}
+
+	branch, err := b.service.SwitchBranch(b.ctx, "s1", answer.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := contents(branch); !reflect.DeepEqual(got, []string{"q1", "<think>hmm</think>a1", "q2", "a2 again"}) {
+		t.Errorf("new branch = %v", got)
+	}
+	if got := branch[3].SiblingIDs; !reflect.DeepEqual(got, []string{"m4", answer.ID}) {
+		t.Errorf("sibling IDs of the new answer = %v, want [m4 %s]", got, answer.ID)
+	}
+}
+
+func TestCreateBranchMessageEditsUserMessage(t *testing.T) {
+	b := newBranchTest(t)
+
+	// Editing q2 creates a sibling of q2 under a1
+	edited, err := b.service.CreateBranchMessage(b.ctx,
+		&types.Message{SessionID: "s1", Role: "user", Content: "q2 edited", IsCompleted: true}, "m2")
+	if err != nil {
+		t.Fatalf("CreateBranchMessage() = %v", err)
+	}
+	answer, err := b.service.CreateBranchMessage(b.ctx,
+		&types.Message{SessionID: "s1", Role: "assistant", Content: "a2 edited", IsCompleted: true}, edited.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.activeMessage() != answer.ID {
+		t.Errorf("active message = %q, want %q", b.activeMessage(), answer.ID)
+	}
+
+	branch, err := b.service.SwitchBranch(b.ctx, "s1", edited.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := contents(branch); !reflect.DeepEqual(got, []string{"q1", "<think>hmm</think>a1", "q2 edited", "a2 edited"}) {
+		t.Errorf("edited branch = %v", got)
+	}
+	if got := branch[2].SiblingIDs; !reflect.DeepEqual(got, []string{"m3", edited.ID}) {
+		t.Errorf("sibling IDs of the edited message = %v, want [m3 %s]", got, edited.ID)
+	}
+	if branch[0].SiblingIDs != nil || branch[3].SiblingIDs != nil {
+		t.Errorf("messages without siblings got sibling IDs %v, %v", branch[0].SiblingIDs, branch[3].SiblingIDs)
+	}
+
+	// The original conversation is kept and can be switched back to
+	original, err := b.service.SwitchBranch(b.ctx, "s1", "m3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := contents(original); !reflect.DeepEqual(got, []string{"q1", "<think>hmm</think>a1", "q2", "a2", "q3", "a3"}) {
+		t.Errorf("original branch = %v", got)
+	}
+	if b.activeMessage() != "m6" {
+		t.Errorf("active message after switching back = %q, want m6", b.activeMessage())
+	}
+	wantContext := []chat.Message{
+		{Role: "system", Content: "You are a helpful assistant."},
+		{Role: "user", Content: "q1"},
+		{Role: "assistant", Content: "a1"},
+		{Role: "user", Content: "q2"},
+		{Role: "assistant", Content: "a2"},
+		{Role: "user", Content: "q3"},
+		{Role: "assistant", Content: "a3"},
+	}
+	if !reflect.DeepEqual(b.context["s1"], wantContext) {
+		t.Errorf("LLM context after switching back = %+v, want %+v", b.context["s1"], wantContext)
+	}
+}
+
+func TestListBranches(t *testing.T) {
+	b := newBranchTest(t)
+	edited, err := b.service.CreateBranchMessage(b.ctx,
+		&types.Message{SessionID: "s1", Role: "user", Content: "q2 edited", IsCompleted: true}, "m2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	regenerated, err := b.service.CreateBranchMessage(b.ctx,
+		&types.Message{SessionID: "s1", Role: "assistant", Content: "a3 again", IsCompleted: true}, "m5")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	branches, err := b.service.ListBranches(b.ctx, "s1")
+	if err != nil {
+		t.Fatalf("ListBranches() = %v", err)
+	}
+	type branch struct {
+		leaf, fork, query string
+		count             int
+		active            bool
+	}
+	got := make([]branch, 0, len(branches))
+	for _, br := range branches {
+		got = append(got, branch{br.LeafID, br.ForkMessageID, br.LastQuery, br.MessageCount, br.IsActive})
+	}
+	// Most recently updated first
+	want := []branch{
+		{leaf: regenerated.ID, fork: regenerated.ID, query: "q3", count: 6, active: true},
+		{leaf: edited.ID, fork: edited.ID, query: "q2 edited", count: 3},
+		{leaf: "m6", fork: "m6", query: "q3", count: 6},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ListBranches() = %+v, want %+v", got, want)
+	}
+}
+
+func TestBranchRejectsMessagesOfOtherSessions(t *testing.T) {
+	b := newBranchTest(t)
+	created := len(b.messages.messages)
+
+	// m7 is the first message of session s2
+	if _, err := b.service.CreateBranchMessage(b.ctx,
+		&types.Message{SessionID: "s1", Role: "user", Content: "q"}, "m7"); !errors.Is(err, ErrBranchMessageNotFound) {
+		t.Errorf("CreateBranchMessage() from a message of another session = %v, want %v", err, ErrBranchMessageNotFound)
+	}
+	if _, err := b.service.CreateBranchMessage(b.ctx,
+		&types.Message{SessionID: "s1", Role: "user", Content: "q"}, "missing"); !errors.Is(err, ErrBranchMessageNotFound) {
+		t.Errorf("CreateBranchMessage() from a missing message = %v, want %v", err, ErrBranchMessageNotFound)
+	}
+	if _, err := b.service.CreateBranchMessage(b.ctx,
+		&types.Message{SessionID: "s3", Role: "user", Content: "q"}, ""); !errors.Is(err, werrors.ErrSessionNotFound) {
+		t.Errorf("CreateBranchMessage() in a session of another tenant = %v, want %v", err, werrors.ErrSessionNotFound)
+	}
+	if len(b.messages.messages) != created {
+		t.Errorf("rejected branches created %d messages", len(b.messages.messages)-created)
+	}
+
+	if _, err := b.service.SwitchBranch(b.ctx, "s1", "m7"); !errors.Is(err, ErrBranchMessageNotFound) {
+		t.Errorf("SwitchBranch() to a message of another session = %v, want %v", err, ErrBranchMessageNotFound)
+	}
+	if _, err := b.service.SwitchBranch(b.ctx, "s3", "m1"); !errors.Is(err, werrors.ErrSessionNotFound) {
+		t.Errorf("SwitchBranch() in a session of another tenant = %v, want %v", err, werrors.ErrSessionNotFound)
+	}
+	if _, err := b.service.ListBranches(b.ctx, "s3"); !errors.Is(err, werrors.ErrSessionNotFound) {
+		t.Errorf("ListBranches() of a session of another tenant = %v, want %v", err, werrors.ErrSessionNotFound)
+	}
+	if b.activeMessage() != "m6" {
+		t.Errorf("active message after the rejected requests = %q, want m6", b.activeMessage())
+	}
+}
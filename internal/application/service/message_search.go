@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+const (
+	// maxSearchTerms caps the terms of a history search, each term is one LIKE condition
+	maxSearchTerms = 5
+	// sessionSearchLimit is the number of sessions matched by title returned with the first page
+	sessionSearchLimit = 10
+	// snippetLength is the length of a message snippet in characters
+	snippetLength = 160
+	// snippetLeadLength is the context kept before the first match of a snippet
+	snippetLeadLength = 40
+)
+
+var ErrEmptySearchQuery = errors.New("search query cannot be empty")
+
+// SearchMessages searches the messages and session titles of the current tenant.
+// Messages of all branches are searched, a hit on another branch can be opened with SwitchBranch.
+func (s *messageService) SearchMessages(ctx context.Context,
+	filter *types.MessageSearchFilter, page *types.Pagination,
+) (*types.MessageSearchResult, error) {
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	terms := searchTerms(filter.Query)
+	if len(terms) == 0 {
+		return nil, ErrEmptySearchQuery
+	}
+
+	hits, total, err := s.messageRepo.SearchMessages(ctx, tenantID, terms, filter, page)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to search messages: %v", err)
+		return nil, err
+	}
+	for _, hit := range hits {
+		// Prefer a match in the visible answer over one in the thinking process
+		hit.Snippet, hit.Highlights = buildSnippet(thinkTagRegex.ReplaceAllString(hit.Content, ""), terms)
+		if len(hit.Highlights) == 0 {
+			hit.Snippet, hit.Highlights = buildSnippet(hit.Content, terms)
+		}
+	}
+
+	result := &types.MessageSearchResult{
+		Sessions: make([]*types.SessionSearchHit, 0),
+		Messages: types.NewPageResult(total, page, hits),
+	}
+	if page.GetPage() == 1 {
+		sessions, err := s.sessionRepo.SearchByTitle(ctx, tenantID, terms, filter, sessionSearchLimit)
+		if err != nil {
+			logger.Errorf(ctx, "Failed to search sessions: %v", err)
+			return nil, err
+		}
+		for _, session := range sessions {
+			result.Sessions = append(result.Sessions, &types.SessionSearchHit{
+				SessionID:  session.ID,
+				Title:      session.Title,
+				Highlights: findSpans([]rune(session.Title), terms),
+				UpdatedAt:  session.UpdatedAt,
+			})
+		}
+	}
+
+	logger.Infof(ctx, "Searched history of tenant %d, terms: %d, messages: %d, sessions: %d",
+		tenantID, len(terms), total, len(result.Sessions))
+	return result, nil
+}
+
+// searchTerms splits a search query into distinct terms
+func searchTerms(query string) []string {
+	var terms []string
+	seen := make(map[string]bool)
+	for _, term := range strings.Fields(query) {
+		key := strings.ToLower(term)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		terms = append(terms, term)
+		if len(terms) == maxSearchTerms {
+			break
+		}
+	}
+	return terms
+}
+
+// buildSnippet cuts the part of the content around the first match and highlights the terms in it
+func buildSnippet(content string, terms []string) (string, []types.TextSpan) {
+	runes := []rune(strings.Join(strings.Fields(content), " "))
+	spans := findSpans(runes, terms)
+
+	start := 0
+	if len(spans) > 0 {
+		start = max(spans[0].Start-snippetLeadLength, 0)
+	}
+	end := min(start+snippetLength, len(runes))
+	start = max(end-snippetLength, 0)
+
+	prefix, suffix := "", ""
+	if start > 0 {
+		prefix = "…"
+	}
+	if end < len(runes) {
+		suffix = "…"
+	}
+	offset := len([]rune(prefix)) - start
+
+	highlights := make([]types.TextSpan, 0, len(spans))
+	for _, span := range spans {
+		if span.Start >= end {
+			break
+		}
+		if span.End <= start {
+			continue
+		}
+		highlights = append(highlights, types.TextSpan{
+			Start: max(span.Start, start) + offset,
+			End:   min(span.End, end) + offset,
+		})
+	}
+	return prefix + string(runes[start:end]) + suffix, highlights
+}
+
+// findSpans returns the sorted, merged ranges of a text matching any of the terms, ignoring case
+func findSpans(text []rune, terms []string) []types.TextSpan {
+	lower := make([]rune, len(text))
+	for i, r := range text {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	var spans []types.TextSpan
+	for _, term := range terms {
+		needle := []rune(term)
+		for i, r := range needle {
+			needle[i] = unicode.ToLower(r)
+		}
+		for i := 0; i+len(needle) <= len(lower); {
+			if slices.Equal(lower[i:i+len(needle)], needle) {
+				spans = append(spans, types.TextSpan{Start: i, End: i + len(needle)})
+				i += len(needle)
+				continue
+			}
+			i++
+		}
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].Start < spans[j].Start })
+
+	merged := make([]types.TextSpan, 0, len(spans))
+	for _, span := range spans {
+		if n := len(merged); n > 0 && span.Start <= merged[n-1].End {
+			merged[n-1].End = max(merged[n-1].End, span.End)
+			continue
+		}
+		merged = append(merged, span)
+	}
+	return merged
+}
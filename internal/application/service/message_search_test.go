@@ -0,0 +1,162 @@
+package service
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+func TestSearchTerms(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"blank", " \t\n", nil},
+		{"whitespace separated", "  retry\tpolicy\nwebhook ", []string{"retry", "policy", "webhook"}},
+		{"duplicates ignoring case", "Go go GO rust", []string{"Go", "rust"}},
+		{"CJK terms", "北京 天气 北京", []string{"北京", "天气"}},
+		{"capped", "a b c d e f g", []string{"a", "b", "c", "d", "e"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := searchTerms(tt.query); !slices.Equal(got, tt.want) {
+				t.Errorf("searchTerms(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindSpans(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		terms []string
+		want  []types.TextSpan
+	}{
+		{"no match", "hello world", []string{"absent"}, []types.TextSpan{}},
+		{"case folding", "Hello WORLD", []string{"world"}, []types.TextSpan{{Start: 6, End: 11}}},
+		{"non-ASCII case folding", "ÜBER über", []string{"über"}, []types.TextSpan{{Start: 0, End: 4}, {Start: 5, End: 9}}},
+		{"repeated term", "go, go and go", []string{"go"}, []types.TextSpan{{Start: 0, End: 2}, {Start: 4, End: 6}, {Start: 11, End: 13}}},
+		{"adjacent matches merge", "abab ab", []string{"ab"}, []types.TextSpan{{Start: 0, End: 4}, {Start: 5, End: 7}}},
+		{"overlapping terms merge", "foobar", []string{"obar", "foob"}, []types.TextSpan{{Start: 0, End: 6}}},
+		{"nested terms merge", "database", []string{"base", "database", "data"}, []types.TextSpan{{Start: 0, End: 8}}},
+		{"CJK offsets are in characters", "我爱北京天安门，北京", []string{"北京"}, []types.TextSpan{{Start: 2, End: 4}, {Start: 8, End: 10}}},
+		{"term at start and end", "needle in a needle", []string{"needle"}, []types.TextSpan{{Start: 0, End: 6}, {Start: 12, End: 18}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := findSpans([]rune(tt.text), tt.terms); !slices.Equal(got, tt.want) {
+				t.Errorf("findSpans(%q, %q) = %v, want %v", tt.text, tt.terms, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildSnippet(t *testing.T) {
+	filler := strings.Repeat("x", 200)
+	tests := []struct {
+		name    string
+		content string
+		terms   []string
+		// prefix and suffix of the snippet, and its length in characters
+		prefix, suffix string
+		length         int
+		// text of each highlight in the snippet
+		marked []string
+	}{
+		{
+			name:    "content shorter than the window",
+			content: "Retry  the\n webhook delivery",
+			terms:   []string{"WEBHOOK"},
+			prefix:  "Retry the webhook",
+			suffix:  "delivery",
+			length:  26,
+			marked:  []string{"webhook"},
+		},
+		{
+			name:    "no match keeps the beginning",
+			content: "start " + filler,
+			terms:   []string{"absent"},
+			prefix:  "start ",
+			suffix:  "x…",
+			length:  snippetLength + 1,
+		},
+		{
+			name:    "term at the start",
+			content: "Needle " + filler,
+			terms:   []string{"needle"},
+			prefix:  "Needle ",
+			suffix:  "x…",
+			length:  snippetLength + 1,
+			marked:  []string{"Needle"},
+		},
+		{
+			name:    "term at the end",
+			content: filler + " NEEDLE",
+			terms:   []string{"needle"},
+			prefix:  "…x",
+			suffix:  " NEEDLE",
+			length:  snippetLength + 1,
+			marked:  []string{"NEEDLE"},
+		},
+		{
+			name:    "term in the middle keeps the lead context",
+			content: filler + "needle" + filler,
+			terms:   []string{"needle"},
+			prefix:  "…" + strings.Repeat("x", snippetLeadLength) + "needle",
+			suffix:  "x…",
+			length:  snippetLength + 2,
+			marked:  []string{"needle"},
+		},
+		{
+			name:    "CJK content",
+			content: strings.Repeat("文", 100) + "北京" + strings.Repeat("字", 200),
+			terms:   []string{"北京"},
+			prefix:  "…" + strings.Repeat("文", snippetLeadLength) + "北京",
+			suffix:  "字…",
+			length:  snippetLength + 2,
+			marked:  []string{"北京"},
+		},
+		{
+			name:    "overlapping and repeated terms",
+			content: filler + "foobar and foo" + filler,
+			terms:   []string{"foo", "obar"},
+			prefix:  "…",
+			suffix:  "…",
+			length:  snippetLength + 2,
+			marked:  []string{"foobar", "foo"},
+		},
+		{
+			name:    "matches outside the window are not highlighted",
+			content: "needle" + filler + "needle",
+			terms:   []string{"needle"},
+			prefix:  "needle",
+			suffix:  "x…",
+			length:  snippetLength + 1,
+			marked:  []string{"needle"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snippet, highlights := buildSnippet(tt.content, tt.terms)
+			runes := []rune(snippet)
+			if !strings.HasPrefix(snippet, tt.prefix) || !strings.HasSuffix(snippet, tt.suffix) || len(runes) != tt.length {
+				t.Errorf("snippet = %q (%d characters), want %q…%q with %d characters",
+					snippet, len(runes), tt.prefix, tt.suffix, tt.length)
+			}
+			marked := make([]string, 0, len(highlights))
+			for _, h := range highlights {
+				if h.Start < 0 || h.End > len(runes) || h.Start >= h.End {
+					t.Fatalf("highlight %v out of the snippet of %d characters", h, len(runes))
+				}
+				marked = append(marked, string(runes[h.Start:h.End]))
+			}
+			if len(marked) != len(tt.marked) || (len(marked) > 0 && !slices.Equal(marked, tt.marked)) {
+				t.Errorf("highlighted %q, want %q", marked, tt.marked)
+			}
+		})
+	}
+}
@@ -7,9 +7,11 @@ import (
 	"slices"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/Tencent/WeKnora/internal/common"
 	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/metrics"
 	"github.com/Tencent/WeKnora/internal/models/embedding"
 	"github.com/Tencent/WeKnora/internal/tracing"
 	"github.com/Tencent/WeKnora/internal/types"
@@ -21,10 +23,21 @@ import (
 type engineInfo struct {
 	retrieveEngine interfaces.RetrieveEngineService
 	retrieverType  []types.RetrieverType
+	// Retriever types the engine only indexes, as the mirror of another engine
+	mirrorType []types.RetrieverType
+}
+
+// indexTypes returns the retriever types the engine indexes
+func (e *engineInfo) indexTypes() []types.RetrieverType {
+	if len(e.mirrorType) == 0 {
+		return e.retrieverType
+	}
+	return append(slices.Clone(e.retrieverType), e.mirrorType...)
 }
 
 // CompositeRetrieveEngine implements a composite pattern for retrieval engines,
-// delegating operations to all registered engines
+// delegating operations to all registered engines. Write-only engines receive the writes
+// but never serve retrieval.
 type CompositeRetrieveEngine struct {
 	engineInfos []*engineInfo
 }
@@ -42,7 +55,10 @@ func (c *CompositeRetrieveEngine) Retrieve(ctx context.Context,
 					continue
 				}
 				if slices.Contains(engineInfo.retrieverType, param.RetrieverType) {
+					start := time.Now()
 					result, err := engineInfo.retrieveEngine.Retrieve(ctx, param)
+					metrics.ObserveRetrieve(string(engineInfo.retrieveEngine.EngineType()),
+						string(param.RetrieverType), err, time.Since(start))
 					if err != nil {
 						return err
 					}
@@ -76,14 +92,15 @@ func NewCompositeRetrieveEngine(
 			return nil, fmt.Errorf("retrieval engine %s does not support retriever type: %s",
 				repo.EngineType(), engineParam.RetrieverType)
 		}
-		if _, exists := engineInfos[repo.EngineType()]; exists {
-			engineInfos[repo.EngineType()].retrieverType = append(engineInfos[repo.EngineType()].retrieverType,
-				engineParam.RetrieverType)
-			continue
+		info, exists := engineInfos[repo.EngineType()]
+		if !exists {
+			info = &engineInfo{retrieveEngine: repo}
+			engineInfos[repo.EngineType()] = info
 		}
-		engineInfos[repo.EngineType()] = &engineInfo{
-			retrieveEngine: repo,
-			retrieverType:  []types.RetrieverType{engineParam.RetrieverType},
+		if engineParam.WriteOnly {
+			info.mirrorType = append(info.mirrorType, engineParam.RetrieverType)
+		} else {
+			info.retrieverType = append(info.retrieverType, engineParam.RetrieverType)
 		}
 	}
 	return &CompositeRetrieveEngine{engineInfos: slices.Collect(maps.Values(engineInfos))}, nil
@@ -203,7 +220,7 @@ func (c *CompositeRetrieveEngine) Index(ctx context.Context,
 	ctx, span := tracing.ContextWithSpan(ctx, "CompositeRetrieveEngine.Index")
 	defer span.End()
 	err := c.concurrentExecWithError(ctx, func(ctx context.Context, engineInfo *engineInfo) error {
-		if err := engineInfo.retrieveEngine.Index(ctx, embedder, indexInfo, engineInfo.retrieverType); err != nil {
+		if err := engineInfo.retrieveEngine.Index(ctx, embedder, indexInfo, engineInfo.indexTypes()); err != nil {
 			logger.Errorf(ctx, "Repository %s failed to save: %v", engineInfo.retrieveEngine.EngineType(), err)
 			return err
 		}
@@ -230,7 +247,7 @@ func (c *CompositeRetrieveEngine) BatchIndex(ctx context.Context,
 			ctx,
 			embedder,
 			indexInfoList,
-			engineInfo.retrieverType,
+			engineInfo.indexTypes(),
 		); err != nil {
 			logger.Errorf(ctx, "Repository %s failed to batch save: %v", engineInfo.retrieveEngine.EngineType(), err)
 			return err
@@ -322,7 +339,10 @@ func (c *CompositeRetrieveEngine) EstimateStorageSize(ctx context.Context,
 	defer span.End()
 	sum := atomic.Int64{}
 	err := c.concurrentExecWithError(ctx, func(ctx context.Context, engineInfo *engineInfo) error {
-		sum.Add(engineInfo.retrieveEngine.EstimateStorageSize(ctx, embedder, indexInfoList, engineInfo.retrieverType))
+		// Mirrored copies are temporary and not counted
+		if len(engineInfo.retrieverType) > 0 {
+			sum.Add(engineInfo.retrieveEngine.EstimateStorageSize(ctx, embedder, indexInfoList, engineInfo.retrieverType))
+		}
 		return nil
 	})
 	span.RecordError(err)
@@ -0,0 +1,119 @@
+package retriever
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+)
+
+// fakeEngine records the retrievals and writes it receives
+type fakeEngine struct {
+	interfaces.RetrieveEngineService
+	engineType types.RetrieverEngineType
+
+	mu        sync.Mutex
+	retrieved []types.RetrieverType
+	updates   int
+}
+
+func (e *fakeEngine) EngineType() types.RetrieverEngineType { return e.engineType }
+
+func (e *fakeEngine) Support() []types.RetrieverType {
+	return []types.RetrieverType{types.KeywordsRetrieverType, types.VectorRetrieverType}
+}
+
+func (e *fakeEngine) Retrieve(ctx context.Context, params types.RetrieveParams) ([]*types.RetrieveResult, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.retrieved = append(e.retrieved, params.RetrieverType)
+	return []*types.RetrieveResult{{RetrieverEngineType: e.engineType, RetrieverType: params.RetrieverType}}, nil
+}
+
+func (e *fakeEngine) BatchUpdateChunkEnabledStatus(ctx context.Context, chunkStatusMap map[string]bool) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.updates++
+	return nil
+}
+
+// fakeRegistry returns the fake engines by type
+type fakeRegistry struct {
+	interfaces.RetrieveEngineRegistry
+	engines map[types.RetrieverEngineType]*fakeEngine
+}
+
+func (r *fakeRegistry) GetRetrieveEngineService(engineType types.RetrieverEngineType) (interfaces.RetrieveEngineService, error) {
+	engine, ok := r.engines[engineType]
+	if !ok {
+		return nil, fmt.Errorf("engine %s not registered", engineType)
+	}
+	return engine, nil
+}
+
+func TestCompositeWriteOnlyMirrorNeverServesRetrieval(t *testing.T) {
+	postgres := &fakeEngine{engineType: types.PostgresRetrieverEngineType}
+	qdrant := &fakeEngine{engineType: types.QdrantRetrieverEngineType}
+	registry := &fakeRegistry{engines: map[types.RetrieverEngineType]*fakeEngine{
+		types.PostgresRetrieverEngineType: postgres,
+		types.QdrantRetrieverEngineType:   qdrant,
+	}}
+	// A tenant migrating its vector index from postgres to qdrant
+	tenant := &types.Tenant{RetrieverEngines: types.RetrieverEngines{
+		Engines: []types.RetrieverEngineParams{
+			{RetrieverType: types.KeywordsRetrieverType, RetrieverEngineType: types.PostgresRetrieverEngineType},
+			{RetrieverType: types.VectorRetrieverType, RetrieverEngineType: types.PostgresRetrieverEngineType},
+		},
+		Mirrors: []types.RetrieverEngineParams{
+			{RetrieverType: types.VectorRetrieverType, RetrieverEngineType: types.QdrantRetrieverEngineType},
+		},
+	}}
+
+	engine, err := NewCompositeRetrieveEngine(registry, tenant.GetEffectiveEngines())
+	if err != nil {
+		t.Fatalf("NewCompositeRetrieveEngine() = %v", err)
+	}
+	results, err := engine.Retrieve(context.Background(), []types.RetrieveParams{
+		{RetrieverType: types.KeywordsRetrieverType},
+		{RetrieverType: types.VectorRetrieverType},
+	})
+	if err != nil {
+		t.Fatalf("Retrieve() = %v", err)
+	}
+	for _, result := range results {
+		if result.RetrieverEngineType != types.PostgresRetrieverEngineType {
+			t.Errorf("%s retrieval was served by %s", result.RetrieverType, result.RetrieverEngineType)
+		}
+	}
+	if len(postgres.retrieved) != 2 || len(qdrant.retrieved) != 0 {
+		t.Errorf("retrievals: postgres %v, qdrant %v, want all on postgres", postgres.retrieved, qdrant.retrieved)
+	}
+
+	// The mirror supports the vector type but is not an engine serving it
+	qdrantOnly, err := NewCompositeRetrieveEngine(registry, []types.RetrieverEngineParams{
+		{RetrieverType: types.VectorRetrieverType, RetrieverEngineType: types.QdrantRetrieverEngineType, WriteOnly: true},
+	})
+	if err != nil {
+		t.Fatalf("NewCompositeRetrieveEngine() = %v", err)
+	}
+	if qdrantOnly.SupportRetriever(types.VectorRetrieverType) {
+		t.Error("a write-only engine reports that it supports retrieval")
+	}
+	if _, err := qdrantOnly.Retrieve(context.Background(), []types.RetrieveParams{{RetrieverType: types.VectorRetrieverType}}); err == nil {
+		t.Error("Retrieve() on a write-only engine succeeded")
+	}
+	if len(qdrant.retrieved) != 0 {
+		t.Errorf("the write-only engine served %v", qdrant.retrieved)
+	}
+
+	// Writes still reach the mirror
+	if err := engine.BatchUpdateChunkEnabledStatus(context.Background(), map[string]bool{"chunk-1": false}); err != nil {
+		t.Fatalf("BatchUpdateChunkEnabledStatus() = %v", err)
+	}
+	if postgres.updates != 1 || qdrant.updates != 1 {
+		t.Errorf("updates: postgres %d, qdrant %d, want 1 each", postgres.updates, qdrant.updates)
+	}
+}
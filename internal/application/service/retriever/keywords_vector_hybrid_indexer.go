@@ -283,3 +283,40 @@ func (v *KeywordsVectorHybridRetrieveEngineService) BatchUpdateChunkTagID(
 ) error {
 	return v.indexRepository.BatchUpdateChunkTagID(ctx, chunkTagMap)
 }
+
+// ListIndices lists the index entries of a knowledge base with their embeddings
+func (v *KeywordsVectorHybridRetrieveEngineService) ListIndices(ctx context.Context,
+	knowledgeBaseID string, dimension int, knowledgeType string, cursor string, limit int,
+) ([]*types.IndexEntry, string, error) {
+	return v.indexRepository.ListIndices(ctx, knowledgeBaseID, dimension, knowledgeType, cursor, limit)
+}
+
+// Ping checks that the engine backend is reachable
+func (v *KeywordsVectorHybridRetrieveEngineService) Ping(ctx context.Context) error {
+	return v.indexRepository.Ping(ctx)
+}
+
+// SaveIndices saves index entries with their existing embeddings and enabled status
+func (v *KeywordsVectorHybridRetrieveEngineService) SaveIndices(ctx context.Context,
+	entries []*types.IndexEntry,
+) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	indexInfoList := make([]*types.IndexInfo, 0, len(entries))
+	embeddingMap := make(map[string][]float32)
+	chunkEnabledMap := make(map[string]bool)
+	for _, entry := range entries {
+		indexInfo := entry.IndexInfo
+		indexInfoList = append(indexInfoList, &indexInfo)
+		if len(entry.Embedding) > 0 {
+			embeddingMap[entry.SourceID] = entry.Embedding
+		}
+		chunkEnabledMap[entry.ChunkID] = entry.IsEnabled
+	}
+	params := map[string]any{
+		"embedding":     embeddingMap,
+		"chunk_enabled": chunkEnabledMap,
+	}
+	return v.indexRepository.BatchSave(ctx, indexInfoList, params)
+}
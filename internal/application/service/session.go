@@ -44,6 +44,9 @@ type sessionService struct {
 	chunkService         interfaces.ChunkService          // Service for chunk operations
 	webSearchStateRepo   interfaces.WebSearchStateService // Service for web search state
 	kbShareService       interfaces.KBShareService        // Service for KB sharing operations
+	memoryService        interfaces.UserMemoryService     // Service for long-term user memory
+	customAgentService   interfaces.CustomAgentService    // Service for loading the sub-agents of agents
+	traceRepo            interfaces.AgentTraceRepository  // Repository for the traces of agent runs
 }
 
 // NewSessionService creates a new session service instance with all required dependencies
@@ -60,6 +63,9 @@ func NewSessionService(cfg *config.Config,
 	sessionStorage llmcontext.ContextStorage,
 	webSearchStateRepo interfaces.WebSearchStateService,
 	kbShareService interfaces.KBShareService,
+	memoryService interfaces.UserMemoryService,
+	customAgentService interfaces.CustomAgentService,
+	traceRepo interfaces.AgentTraceRepository,
 ) interfaces.SessionService {
 	return &sessionService{
 		cfg:                  cfg,
@@ -75,6 +81,9 @@ func NewSessionService(cfg *config.Config,
 		sessionStorage:       sessionStorage,
 		webSearchStateRepo:   webSearchStateRepo,
 		kbShareService:       kbShareService,
+		memoryService:        memoryService,
+		customAgentService:   customAgentService,
+		traceRepo:            traceRepo,
 	}
 }
 
@@ -222,6 +231,11 @@ func (s *sessionService) DeleteSession(ctx context.Context, id string) error {
 		return err
 	}
 
+	// Agent traces hold the prompts and retrieved content of the session
+	if err := s.traceRepo.DeleteBySession(ctx, tenantID, id); err != nil {
+		logger.Warnf(ctx, "Failed to delete agent traces of session %s: %v", id, err)
+	}
+
 	return nil
 }
 
@@ -411,6 +425,7 @@ func (s *sessionService) KnowledgeQA(
 	webSearchEnabled bool,
 	eventBus *event.EventBus,
 	customAgent *types.CustomAgent,
+	outputSchema types.OutputSchema,
 ) error {
 	logger.Infof(
 		ctx,
@@ -497,6 +512,14 @@ func (s *sessionService) KnowledgeQA(
 			summaryConfig.ContextTemplate = customAgent.Config.ContextTemplate
 			logger.Infof(ctx, "Using custom agent's context_template")
 		}
+		// Append pinned MCP resources/prompts to the system prompt
+		if len(customAgent.Config.MCPContextSources) > 0 {
+			if mcpContext := s.agentService.BuildMCPContext(ctx, customAgent.Config.MCPContextSources); mcpContext != "" {
+				summaryConfig.Prompt += "\n\n" + mcpContext
+				logger.Infof(ctx, "Appended %d pinned MCP context sources to system prompt",
+					len(customAgent.Config.MCPContextSources))
+			}
+		}
 		// Override temperature
 		if customAgent.Config.Temperature > 0 {
 			summaryConfig.Temperature = customAgent.Config.Temperature
@@ -624,6 +647,8 @@ func (s *sessionService) KnowledgeQA(
 		FallbackStrategy:     fallbackStrategy,
 		FallbackResponse:     fallbackResponse,
 		FallbackPrompt:       fallbackPrompt,
+		OutputSchema:         types.EffectiveOutputSchema(outputSchema, customAgent),
+		CitationModelCheck:   customAgent != nil && customAgent.Config.CitationModelCheck,
 		EventBus:             eventBus.AsEventBusInterface(), // NEW: For pipeline to emit events directly
 		WebSearchEnabled:     webSearchEnabled,
 		TenantID:             retrievalTenantID, // Effective tenant for retrieval (shared agent = agent's tenant)
@@ -1223,6 +1248,7 @@ func (s *sessionService) AgentQA(
 	customAgent *types.CustomAgent,
 	knowledgeBaseIDs []string,
 	knowledgeIDs []string,
+	outputSchema types.OutputSchema,
 ) error {
 	sessionID := session.ID
 	sessionJSON, err := json.Marshal(session)
@@ -1237,13 +1263,173 @@ func (s *sessionService) AgentQA(
 		return errors.New("custom agent configuration is required for agent QA")
 	}
 
+	logger.Infof(ctx, "Start agent-based question answering, session ID: %s, agent ID: %s, query: %s, session: %s",
+		sessionID, customAgent.ID, query, string(sessionJSON))
+
+	agentConfig, summaryModel, rerankModel, err := s.buildAgentRuntime(
+		ctx, session, customAgent, query, summaryModelID, knowledgeBaseIDs, knowledgeIDs,
+	)
+	if err != nil {
+		return err
+	}
+
+	// Request's output schema takes precedence over the agent's
+	agentConfig.OutputSchema = types.EffectiveOutputSchema(outputSchema, customAgent)
+
+	// Agents the custom agent can delegate sub-questions to
+	agentConfig.SubAgentTools = s.buildSubAgentTools(ctx, customAgent, &subAgentRun{
+		session:      session,
+		messageID:    assistantMessageID,
+		eventBus:     eventBus,
+		traceEnabled: agentConfig.TraceEnabled,
+	})
+
+	// Workflow agents run their workflow instead of the ReAct loop, without conversation context
+	if customAgent.IsWorkflowMode() {
+		agentConfig.Workflow = customAgent.Config.RuntimeWorkflow()
+		engine, err := s.agentService.CreateWorkflowEngine(ctx, agentConfig, summaryModel, rerankModel, eventBus, sessionID)
+		if err != nil {
+			logger.Errorf(ctx, "Failed to create workflow engine: %v", err)
+			return err
+		}
+		// The engine emits the error event itself
+		logger.Info(ctx, "Executing workflow with streaming")
+		if _, err := engine.Execute(ctx, sessionID, assistantMessageID, query, nil); err != nil {
+			logger.Errorf(ctx, "Workflow execution failed: %v", err)
+		}
+		return nil
+	}
+
+	// Get or create contextManager for this session
+	contextManager := s.getContextManagerForSession(ctx, session, summaryModel)
+
+	// Set system prompt for the current agent in context manager
+	// This ensures the context uses the correct system prompt when switching agents
+	systemPrompt := agentConfig.ResolveSystemPrompt(agentConfig.WebSearchEnabled)
+	if systemPrompt != "" {
+		if err := contextManager.SetSystemPrompt(ctx, sessionID, systemPrompt); err != nil {
+			logger.Warnf(ctx, "Failed to set system prompt in context manager: %v", err)
+		} else {
+			logger.Infof(ctx, "System prompt updated in context manager for agent")
+		}
+	}
+
+	// Get LLM context from context manager
+	llmContext, err := s.getContextForSession(ctx, contextManager, sessionID)
+	if err != nil {
+		logger.Warnf(ctx, "Failed to get LLM context: %v, continuing without history", err)
+		llmContext = []chat.Message{}
+	}
+	logger.Infof(ctx, "Loaded %d messages from LLM context manager", len(llmContext))
+
+	// Apply multi-turn configuration for Agent mode
+	// Note: In Agent mode, context is managed by contextManager with compression strategies,
+	// so we don't apply HistoryTurns limit here. HistoryTurns is used in normal (KnowledgeQA) mode.
+	if !agentConfig.MultiTurnEnabled {
+		// Multi-turn disabled, clear history
+		logger.Infof(ctx, "Multi-turn disabled for this agent, clearing history context")
+		llmContext = []chat.Message{}
+	}
+
+	// Create agent engine with EventBus and ContextManager
+	logger.Info(ctx, "Creating agent engine")
+	engine, err := s.agentService.CreateAgentEngine(
+		ctx,
+		agentConfig,
+		summaryModel,
+		rerankModel,
+		eventBus,
+		contextManager,
+		session.ID,
+	)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to create agent engine: %v", err)
+		return err
+	}
+
+	// Execute agent with streaming (asynchronously)
+	// Events will be emitted to EventBus and handled by the Handler layer
+	logger.Info(ctx, "Executing agent with streaming")
+	if _, err := engine.Execute(ctx, sessionID, assistantMessageID, query, llmContext); err != nil {
+		logger.Errorf(ctx, "Agent execution failed: %v", err)
+		// Emit error event to the EventBus used by this agent
+		eventBus.Emit(ctx, event.Event{
+			Type:      event.EventError,
+			SessionID: sessionID,
+			Data: event.ErrorData{
+				Error:     err.Error(),
+				Stage:     "agent_execution",
+				SessionID: sessionID,
+			},
+		})
+	}
+	// Return empty - events will be handled by Handler via EventBus subscription
+	return nil
+}
+
+// RunAgent runs a smart-reasoning custom agent for query without conversation history, with the
+// agent's own output schema and sub-agents, and returns the final state of the run
+func (s *sessionService) RunAgent(
+	ctx context.Context,
+	session *types.Session,
+	query string,
+	assistantMessageID string,
+	eventBus *event.EventBus,
+	customAgent *types.CustomAgent,
+	knowledgeBaseIDs []string,
+	knowledgeIDs []string,
+) (*types.AgentState, error) {
+	if customAgent == nil || !customAgent.IsAgentMode() {
+		return nil, errors.New("only smart-reasoning agents can run headlessly")
+	}
+	logger.Infof(ctx, "Running agent %s headlessly, session ID: %s", customAgent.ID, session.ID)
+
+	agentConfig, summaryModel, rerankModel, err := s.buildAgentRuntime(
+		ctx, session, customAgent, query, "", knowledgeBaseIDs, knowledgeIDs,
+	)
+	if err != nil {
+		return nil, err
+	}
+	agentConfig.OutputSchema = types.EffectiveOutputSchema(nil, customAgent)
+	agentConfig.SubAgentTools = s.buildSubAgentTools(ctx, customAgent, &subAgentRun{
+		session:      session,
+		messageID:    assistantMessageID,
+		eventBus:     eventBus,
+		traceEnabled: agentConfig.TraceEnabled,
+	})
+
+	engine, err := s.agentService.CreateAgentEngine(
+		ctx,
+		agentConfig,
+		summaryModel,
+		rerankModel,
+		eventBus,
+		nil,
+		session.ID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return engine.Execute(ctx, session.ID, assistantMessageID, query, nil)
+}
+
+// buildAgentRuntime resolves the runtime agent config, chat model and rerank model of a custom agent.
+// Request-level @ mentions (knowledgeBaseIDs, knowledgeIDs) take priority over the agent's knowledge bases.
+func (s *sessionService) buildAgentRuntime(
+	ctx context.Context,
+	session *types.Session,
+	customAgent *types.CustomAgent,
+	query string,
+	summaryModelID string,
+	knowledgeBaseIDs []string,
+	knowledgeIDs []string,
+) (*types.AgentConfig, chat.Chat, rerank.Reranker, error) {
 	// Use agent's tenant for retrieval and tenant-scoped config (handler has validated access)
 	agentTenantID := customAgent.TenantID
 	if agentTenantID == 0 {
 		agentTenantID = session.TenantID
 	}
-	logger.Infof(ctx, "Start agent-based question answering, session ID: %s, agent tenant ID: %d, query: %s, session: %s",
-		sessionID, agentTenantID, query, string(sessionJSON))
+	logger.Infof(ctx, "Building agent runtime, agent ID: %s, agent tenant ID: %d", customAgent.ID, agentTenantID)
 
 	var tenantInfo *types.Tenant
 	if v := ctx.Value(types.TenantInfoContextKey); v != nil {
@@ -1278,8 +1464,24 @@ func (s *sessionService) AgentQA(
 		HistoryTurns:                customAgent.Config.HistoryTurns,
 		MCPSelectionMode:            customAgent.Config.MCPSelectionMode,
 		MCPServices:                 customAgent.Config.MCPServices,
+		MCPContextSources:           customAgent.Config.MCPContextSources,
+		HTTPTools:                   customAgent.Config.HTTPTools,
+		ApprovalRequiredTools:       customAgent.Config.ApprovalRequiredTools,
+		ApprovalRequiredMCPServices: customAgent.Config.ApprovalRequiredMCPServices,
+		ToolApprovalTimeoutSeconds:  customAgent.Config.ToolApprovalTimeoutSeconds,
+		ToolApprovalTimeoutAction:   customAgent.Config.ToolApprovalTimeoutAction,
 		Thinking:                    customAgent.Config.Thinking,
+		CitationModelCheck:          customAgent.Config.CitationModelCheck,
+		TraceEnabled:                customAgent.Config.TraceEnabled,
 		RetrieveKBOnlyWhenMentioned: customAgent.Config.RetrieveKBOnlyWhenMentioned,
+		MemoryEnabled:               customAgent.Config.MemoryEnabled,
+		MemoryEmbeddingModelID:      customAgent.Config.MemoryEmbeddingModelID,
+		MemoryAgentID:               customAgent.ID,
+	}
+
+	// Recall the user's long-term memories relevant to this query into the system prompt
+	if agentConfig.MemoryEnabled {
+		agentConfig.MemoryContext = s.memoryService.BuildMemoryContext(ctx, query, agentConfig.MemoryEmbeddingModelID)
 	}
 
 	// Configure skills based on CustomAgentConfig
@@ -1334,7 +1536,7 @@ func (s *sessionService) AgentQA(
 		}
 	}
 
-	logger.Infof(ctx, "Merged agent config from tenant %d and session %s", tenantInfo.ID, sessionID)
+	logger.Infof(ctx, "Merged agent config from tenant %d and agent %s", tenantInfo.ID, customAgent.ID)
 
 	// Log knowledge bases if present
 	if len(agentConfig.KnowledgeBases) > 0 {
@@ -1362,7 +1564,7 @@ func (s *sessionService) AgentQA(
 	}
 	if effectiveModelID == "" {
 		logger.Warnf(ctx, "No summary model configured for custom agent %s", customAgent.ID)
-		return errors.New("summary model (model_id) is not configured in custom agent settings")
+		return nil, nil, nil, errors.New("summary model (model_id) is not configured in custom agent settings")
 	}
 	if summaryModelID != "" {
 		logger.Infof(ctx, "Using request's summary model override: %s", effectiveModelID)
@@ -1373,7 +1575,7 @@ func (s *sessionService) AgentQA(
 	summaryModel, err := s.modelService.GetChatModel(ctx, effectiveModelID)
 	if err != nil {
 		logger.Warnf(ctx, "Failed to get chat model: %v", err)
-		return fmt.Errorf("failed to get chat model: %w", err)
+		return nil, nil, nil, fmt.Errorf("failed to get chat model: %w", err)
 	}
 
 	// Get rerank model from custom agent config (only required when knowledge bases are configured)
@@ -1383,83 +1585,19 @@ func (s *sessionService) AgentQA(
 		rerankModelID := customAgent.Config.RerankModelID
 		if rerankModelID == "" {
 			logger.Warnf(ctx, "No rerank model configured for custom agent %s, but knowledge bases are specified", customAgent.ID)
-			return errors.New("rerank model (rerank_model_id) is not configured in custom agent settings")
+			return nil, nil, nil, errors.New("rerank model (rerank_model_id) is not configured in custom agent settings")
 		}
 
 		rerankModel, err = s.modelService.GetRerankModel(ctx, rerankModelID)
 		if err != nil {
 			logger.Warnf(ctx, "Failed to get rerank model: %v", err)
-			return fmt.Errorf("failed to get rerank model: %w", err)
+			return nil, nil, nil, fmt.Errorf("failed to get rerank model: %w", err)
 		}
 	} else {
 		logger.Infof(ctx, "No knowledge bases configured, skipping rerank model initialization")
 	}
 
-	// Get or create contextManager for this session
-	contextManager := s.getContextManagerForSession(ctx, session, summaryModel)
-
-	// Set system prompt for the current agent in context manager
-	// This ensures the context uses the correct system prompt when switching agents
-	systemPrompt := agentConfig.ResolveSystemPrompt(agentConfig.WebSearchEnabled)
-	if systemPrompt != "" {
-		if err := contextManager.SetSystemPrompt(ctx, sessionID, systemPrompt); err != nil {
-			logger.Warnf(ctx, "Failed to set system prompt in context manager: %v", err)
-		} else {
-			logger.Infof(ctx, "System prompt updated in context manager for agent")
-		}
-	}
-
-	// Get LLM context from context manager
-	llmContext, err := s.getContextForSession(ctx, contextManager, sessionID)
-	if err != nil {
-		logger.Warnf(ctx, "Failed to get LLM context: %v, continuing without history", err)
-		llmContext = []chat.Message{}
-	}
-	logger.Infof(ctx, "Loaded %d messages from LLM context manager", len(llmContext))
-
-	// Apply multi-turn configuration for Agent mode
-	// Note: In Agent mode, context is managed by contextManager with compression strategies,
-	// so we don't apply HistoryTurns limit here. HistoryTurns is used in normal (KnowledgeQA) mode.
-	if !agentConfig.MultiTurnEnabled {
-		// Multi-turn disabled, clear history
-		logger.Infof(ctx, "Multi-turn disabled for this agent, clearing history context")
-		llmContext = []chat.Message{}
-	}
-
-	// Create agent engine with EventBus and ContextManager
-	logger.Info(ctx, "Creating agent engine")
-	engine, err := s.agentService.CreateAgentEngine(
-		ctx,
-		agentConfig,
-		summaryModel,
-		rerankModel,
-		eventBus,
-		contextManager,
-		session.ID,
-	)
-	if err != nil {
-		logger.Errorf(ctx, "Failed to create agent engine: %v", err)
-		return err
-	}
-
-	// Execute agent with streaming (asynchronously)
-	// Events will be emitted to EventBus and handled by the Handler layer
-	logger.Info(ctx, "Executing agent with streaming")
-	if _, err := engine.Execute(ctx, sessionID, assistantMessageID, query, llmContext); err != nil {
-		logger.Errorf(ctx, "Agent execution failed: %v", err)
-		// Emit error event to the EventBus used by this agent
-		eventBus.Emit(ctx, event.Event{
-			Type:      event.EventError,
-			SessionID: sessionID,
-			Data: event.ErrorData{
-				Error:     err.Error(),
-				Stage:     "agent_execution",
-				SessionID: sessionID,
-			},
-		})
-	}
-	// Return empty - events will be handled by Handler via EventBus subscription
-	return nil
+	return agentConfig, summaryModel, rerankModel, nil
 }
 
 // getContextManagerForSession creates a context manager for the session based on configuration
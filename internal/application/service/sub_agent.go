@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+
+	"github.com/Tencent/WeKnora/internal/agent/tools"
+	"github.com/Tencent/WeKnora/internal/event"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// subAgentForwardedEvents are the events of a delegated run streamed to the delegating run.
+// Completion and error events are not forwarded: the outcome is reported by the tool result.
+var subAgentForwardedEvents = []event.EventType{
+	event.EventAgentThought,
+	event.EventAgentToolCall,
+	event.EventAgentToolResult,
+	event.EventAgentToolApproval,
+	event.EventAgentToolApprovalResolved,
+	event.EventAgentReflection,
+	event.EventAgentFinalAnswer,
+	event.EventAgentSubAgentAnswer,
+}
+
+// subAgentRun is an agent run that can delegate to sub-agents
+type subAgentRun struct {
+	session   *types.Session
+	messageID string          // Assistant message the run answers
+	eventBus  *event.EventBus // Event bus of the run
+	depth     int             // Nesting depth of the run, the top-level run is 0
+	// Whether traces are recorded, nested runs follow the top-level agent so that their traces
+	// are only kept alongside the trace of the run that delegated to them
+	traceEnabled bool
+}
+
+// buildSubAgentTools returns a delegation tool for each sub-agent of caller, which runs in run.
+// Sub-agents that cannot be loaded or do not run in agent mode are skipped, and no tools are
+// returned once run has reached types.MaxSubAgentDepth.
+func (s *sessionService) buildSubAgentTools(
+	ctx context.Context,
+	caller *types.CustomAgent,
+	run *subAgentRun,
+) []types.Tool {
+	if len(caller.Config.SubAgents) == 0 {
+		return nil
+	}
+	if run.depth >= types.MaxSubAgentDepth {
+		logger.Infof(ctx, "Sub-agent depth limit %d reached, agent %s cannot delegate further",
+			types.MaxSubAgentDepth, caller.ID)
+		return nil
+	}
+
+	// Sub-agents belong to the tenant of the caller, which differs from the user's for shared agents
+	callerTenantID := caller.TenantID
+	if callerTenantID == 0 {
+		callerTenantID = run.session.TenantID
+	}
+	lookupCtx := context.WithValue(ctx, types.TenantIDContextKey, callerTenantID)
+
+	maxIterations := caller.Config.SubAgentMaxIterations
+	if maxIterations <= 0 {
+		maxIterations = types.DefaultSubAgentMaxIterations
+	}
+
+	subAgentTools := make([]types.Tool, 0, len(caller.Config.SubAgents))
+	for _, id := range caller.Config.SubAgents {
+		if id == caller.ID {
+			continue
+		}
+		subAgent, err := s.customAgentService.GetLiveAgent(lookupCtx, id)
+		if err != nil {
+			logger.Warnf(ctx, "Failed to load sub-agent %s of agent %s: %v", id, caller.ID, err)
+			continue
+		}
+		if subAgent.Config.AgentMode != types.AgentModeSmartReasoning {
+			logger.Warnf(ctx, "Sub-agent %s of agent %s is not in smart-reasoning mode, skipped", id, caller.ID)
+			continue
+		}
+		subAgentTools = append(subAgentTools, tools.NewSubAgentTool(subAgent,
+			func(ctx context.Context, query, parentToolCallID string) (*types.AgentState, error) {
+				return s.runSubAgent(ctx, run, subAgent, maxIterations, query, parentToolCallID)
+			},
+		))
+	}
+	return subAgentTools
+}
+
+// runSubAgent runs subAgent for query as a nested run of run, with the sub-agent's own config and
+// knowledge base scope and at most maxIterations iterations. Its events are streamed to the event
+// bus of run, tagged with parentToolCallID.
+func (s *sessionService) runSubAgent(
+	ctx context.Context,
+	run *subAgentRun,
+	subAgent *types.CustomAgent,
+	maxIterations int,
+	query string,
+	parentToolCallID string,
+) (*types.AgentState, error) {
+	logger.Infof(ctx, "Running sub-agent %s at depth %d for tool call %s", subAgent.ID, run.depth+1, parentToolCallID)
+
+	agentConfig, chatModel, rerankModel, err := s.buildAgentRuntime(ctx, run.session, subAgent, query, "", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if agentConfig.MaxIterations > maxIterations {
+		agentConfig.MaxIterations = maxIterations
+	}
+	agentConfig.TraceEnabled = run.traceEnabled
+
+	nested := &subAgentRun{
+		session:      run.session,
+		messageID:    run.messageID,
+		eventBus:     event.NewEventBus(),
+		depth:        run.depth + 1,
+		traceEnabled: run.traceEnabled,
+	}
+	forwardSubAgentEvents(nested.eventBus, run.eventBus, map[string]interface{}{
+		event.MetadataParentToolCallID: parentToolCallID,
+		event.MetadataSubAgentID:       subAgent.ID,
+		event.MetadataSubAgentName:     subAgent.Name,
+		event.MetadataSubAgentDepth:    nested.depth,
+	})
+	agentConfig.SubAgentTools = s.buildSubAgentTools(ctx, subAgent, nested)
+
+	// The nested run neither reads nor writes the conversation context of the session
+	engine, err := s.agentService.CreateAgentEngine(
+		ctx,
+		agentConfig,
+		chatModel,
+		rerankModel,
+		nested.eventBus,
+		nil,
+		run.session.ID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return engine.Execute(ctx, run.session.ID, run.messageID, query, nil)
+}
+
+// forwardSubAgentEvents streams the events of a nested run on child to parent, tagged with metadata.
+// Events forwarded from deeper runs keep their own tags. The final answer of the nested run is
+// forwarded as a sub_agent_answer event so that it is not taken for the answer of the parent run.
+func forwardSubAgentEvents(child, parent *event.EventBus, metadata map[string]interface{}) {
+	forward := func(ctx context.Context, evt event.Event) error {
+		if evt.Type == event.EventAgentFinalAnswer {
+			evt.Type = event.EventAgentSubAgentAnswer
+		}
+		if _, nested := evt.Metadata[event.MetadataParentToolCallID]; !nested {
+			tags := make(map[string]interface{}, len(evt.Metadata)+len(metadata))
+			for key, value := range evt.Metadata {
+				tags[key] = value
+			}
+			for key, value := range metadata {
+				tags[key] = value
+			}
+			evt.Metadata = tags
+		}
+		return parent.Emit(ctx, evt)
+	}
+	for _, eventType := range subAgentForwardedEvents {
+		child.On(eventType, forward)
+	}
+}
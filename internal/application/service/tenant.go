@@ -2,26 +2,18 @@ package service
 
 import (
 	"context"
-	"crypto/aes"
-	"crypto/cipher"
-	"crypto/rand"
 	"encoding/base64"
 	"encoding/binary"
 	"errors"
-	"io"
-	"os"
 	"strings"
 	"time"
 
 	"github.com/Tencent/WeKnora/internal/logger"
 	"github.com/Tencent/WeKnora/internal/types"
 	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"github.com/Tencent/WeKnora/internal/utils"
 )
 
-var apiKeySecret = func() []byte {
-	return []byte(os.Getenv("TENANT_AES_KEY"))
-}
-
 // ListTenantsParams defines parameters for listing tenants with filtering and pagination
 type ListTenantsParams struct {
 	Page     int    // Page number for pagination
@@ -213,26 +205,13 @@ func (r *tenantService) generateApiKey(tenantID uint64) string {
 	idBytes := make([]byte, 8)
 	binary.LittleEndian.PutUint64(idBytes, uint64(tenantID))
 
-	// 2. Encrypt tenant_id using AES-GCM
-	block, err := aes.NewCipher(apiKeySecret())
-	if err != nil {
-		panic("Failed to create AES cipher: " + err.Error())
-	}
-
-	nonce := make([]byte, 12)
-	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
-		panic(err.Error())
-	}
-
-	aesgcm, err := cipher.NewGCM(block)
+	// 2. Encrypt tenant_id using AES-GCM (nonce + ciphertext)
+	combined, err := utils.EncryptAESGCM(idBytes)
 	if err != nil {
-		panic("Failed to create GCM cipher: " + err.Error())
+		panic("Failed to encrypt API key: " + err.Error())
 	}
 
-	ciphertext := aesgcm.Seal(nil, nonce, idBytes, nil)
-
-	// 3. Combine nonce and ciphertext, then encode with base64
-	combined := append(nonce, ciphertext...)
+	// 3. Encode with base64
 	encoded := base64.RawURLEncoding.EncodeToString(combined)
 
 	// Create final API Key in format: sk-{encrypted_part}
@@ -253,28 +232,20 @@ func (r *tenantService) ExtractTenantIDFromAPIKey(apiKey string) (uint64, error)
 		return 0, errors.New("invalid API key encoding")
 	}
 
-	// 3. Separate nonce and ciphertext
+	// 3. Check the length of nonce + ciphertext
 	if len(encryptedData) < 12 {
 		return 0, errors.New("invalid API key length")
 	}
-	nonce, ciphertext := encryptedData[:12], encryptedData[12:]
 
 	// 4. Decrypt
-	block, err := aes.NewCipher(apiKeySecret())
-	if err != nil {
-		return 0, errors.New("decryption error")
-	}
-
-	aesgcm, err := cipher.NewGCM(block)
+	plaintext, err := utils.DecryptAESGCM(encryptedData)
 	if err != nil {
+		if errors.Is(err, utils.ErrInvalidCiphertext) {
+			return 0, errors.New("API key is invalid or has been tampered with")
+		}
 		return 0, errors.New("decryption error")
 	}
 
-	plaintext, err := aesgcm.Open(nil, nonce, ciphertext, nil)
-	if err != nil {
-		return 0, errors.New("API key is invalid or has been tampered with")
-	}
-
 	// 5. Convert back to tenant_id
 	tenantID := binary.LittleEndian.Uint64(plaintext)
 
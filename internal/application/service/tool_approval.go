@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"github.com/google/uuid"
+)
+
+// toolApprovalPollInterval is how often a paused agent run checks for a decision.
+// Decisions are read from the database so they can be made on any instance.
+const toolApprovalPollInterval = time.Second
+
+var (
+	ErrToolApprovalNotFound = errors.New("tool approval not found")
+	ErrToolApprovalResolved = errors.New("tool approval has already been resolved")
+	ErrToolApprovalExpired  = errors.New("tool approval has expired")
+)
+
+// toolApprovalService implements the ToolApprovalService interface
+type toolApprovalService struct {
+	repo interfaces.ToolApprovalRepository
+}
+
+// NewToolApprovalService creates a new tool approval service
+func NewToolApprovalService(repo interfaces.ToolApprovalRepository) interfaces.ToolApprovalService {
+	return &toolApprovalService{repo: repo}
+}
+
+// RequestApproval persists a pending approval request for a tool call
+func (s *toolApprovalService) RequestApproval(ctx context.Context, approval *types.ToolApproval) error {
+	now := time.Now()
+	if approval.ID == "" {
+		approval.ID = uuid.New().String()
+	}
+	if approval.TenantID == 0 {
+		if tenantID, ok := ctx.Value(types.TenantIDContextKey).(uint64); ok {
+			approval.TenantID = tenantID
+		}
+	}
+	if approval.TimeoutAction == "" {
+		approval.TimeoutAction = types.ToolApprovalTimeoutDeny
+	}
+	if approval.ExpiresAt.IsZero() {
+		approval.ExpiresAt = now.Add(types.DefaultToolApprovalTimeoutSeconds * time.Second)
+	}
+	approval.Status = types.ToolApprovalStatusPending
+	approval.CreatedAt = now
+	approval.UpdatedAt = now
+
+	return s.repo.Create(ctx, approval)
+}
+
+// WaitForDecision blocks until the request is approved, denied or expired (or ctx is done)
+func (s *toolApprovalService) WaitForDecision(
+	ctx context.Context,
+	approval *types.ToolApproval,
+) (*types.ToolApproval, error) {
+	ticker := time.NewTicker(toolApprovalPollInterval)
+	defer ticker.Stop()
+
+	for {
+		current, err := s.repo.GetByID(ctx, approval.SessionID, approval.ID)
+		if err != nil {
+			logger.Warnf(ctx, "[ToolApproval] Failed to load approval %s: %v", approval.ID, err)
+		} else if current == nil {
+			return nil, ErrToolApprovalNotFound
+		} else if current.Status != types.ToolApprovalStatusPending {
+			return current, nil
+		} else if time.Now().After(current.ExpiresAt) {
+			return s.expire(ctx, current, "no decision before the deadline")
+		}
+
+		select {
+		case <-ctx.Done():
+			// The run was stopped: close the request so it no longer shows as pending
+			if _, err := s.expire(context.WithoutCancel(ctx), approval, "agent run stopped"); err != nil {
+				logger.Warnf(ctx, "[ToolApproval] Failed to expire approval %s: %v", approval.ID, err)
+			}
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// expire marks a pending request as expired, returning the final state if it was resolved concurrently
+func (s *toolApprovalService) expire(
+	ctx context.Context,
+	approval *types.ToolApproval,
+	reason string,
+) (*types.ToolApproval, error) {
+	now := time.Now()
+	expired := *approval
+	expired.Status = types.ToolApprovalStatusExpired
+	expired.Reason = reason
+	expired.DecidedAt = &now
+	expired.UpdatedAt = now
+
+	ok, err := s.repo.Resolve(ctx, &expired)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		current, err := s.repo.GetByID(ctx, approval.SessionID, approval.ID)
+		if err != nil {
+			return nil, err
+		}
+		if current == nil {
+			return nil, ErrToolApprovalNotFound
+		}
+		return current, nil
+	}
+	logger.Infof(ctx, "[ToolApproval] Approval %s expired (%s), timeout action: %s",
+		approval.ID, reason, approval.TimeoutAction)
+	return &expired, nil
+}
+
+// Decide approves or denies a pending approval request of a session
+func (s *toolApprovalService) Decide(
+	ctx context.Context,
+	sessionID string,
+	id string,
+	approved bool,
+	reason string,
+) (*types.ToolApproval, error) {
+	approval, err := s.repo.GetByID(ctx, sessionID, id)
+	if err != nil {
+		return nil, err
+	}
+	if approval == nil {
+		return nil, ErrToolApprovalNotFound
+	}
+	if approval.Status != types.ToolApprovalStatusPending {
+		return nil, ErrToolApprovalResolved
+	}
+	now := time.Now()
+	if now.After(approval.ExpiresAt) {
+		return nil, ErrToolApprovalExpired
+	}
+
+	approval.Status = types.ToolApprovalStatusDenied
+	if approved {
+		approval.Status = types.ToolApprovalStatusApproved
+	}
+	approval.Reason = reason
+	approval.DecidedAt = &now
+	approval.UpdatedAt = now
+
+	ok, err := s.repo.Resolve(ctx, approval)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrToolApprovalResolved
+	}
+	logger.Infof(ctx, "[ToolApproval] Approval %s for tool %s %s", approval.ID, approval.ToolName, approval.Status)
+	return approval, nil
+}
+
+// ListPending lists the pending approval requests of a session
+func (s *toolApprovalService) ListPending(ctx context.Context, sessionID string) ([]*types.ToolApproval, error) {
+	approvals, err := s.repo.ListBySession(ctx, sessionID, types.ToolApprovalStatusPending)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	pending := make([]*types.ToolApproval, 0, len(approvals))
+	for _, approval := range approvals {
+		if now.Before(approval.ExpiresAt) {
+			pending = append(pending, approval)
+		}
+	}
+	return pending, nil
+}
@@ -0,0 +1,440 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	secutils "github.com/Tencent/WeKnora/internal/utils"
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+)
+
+const (
+	// webhookMaxRetry is the number of retries of a failed delivery, spaced by asynq's exponential backoff
+	webhookMaxRetry = 5
+	// webhookRequestTimeout bounds a single delivery attempt
+	webhookRequestTimeout = 10 * time.Second
+	// webhookResponseBodyLimit is the number of response bytes kept in the delivery log
+	webhookResponseBodyLimit = 2048
+)
+
+var (
+	ErrWebhookNotFound     = errors.New("webhook not found")
+	ErrWebhookInvalidURL   = errors.New("invalid webhook url")
+	ErrWebhookInvalidEvent = errors.New("invalid webhook event")
+)
+
+// webhookService implements the WebhookService interface
+type webhookService struct {
+	repo         interfaces.WebhookRepository
+	asynqClient  *asynq.Client
+	httpClient   *http.Client
+	allowPrivate bool
+}
+
+// NewWebhookService creates a new webhook service.
+// Webhook URLs must resolve to public addresses unless WEBHOOK_ALLOW_PRIVATE_URLS is true,
+// which self-hosted deployments can set to notify systems on the internal network.
+func NewWebhookService(repo interfaces.WebhookRepository, asynqClient *asynq.Client) interfaces.WebhookService {
+	allowPrivate, _ := strconv.ParseBool(os.Getenv("WEBHOOK_ALLOW_PRIVATE_URLS"))
+
+	var httpClient *http.Client
+	if allowPrivate {
+		httpClient = &http.Client{Timeout: webhookRequestTimeout}
+	} else {
+		config := secutils.DefaultSSRFSafeHTTPClientConfig()
+		config.Timeout = webhookRequestTimeout
+		config.MaxRedirects = 3
+		httpClient = secutils.NewSSRFSafeHTTPClient(config)
+	}
+	return &webhookService{
+		repo:         repo,
+		asynqClient:  asynqClient,
+		httpClient:   httpClient,
+		allowPrivate: allowPrivate,
+	}
+}
+
+// CreateWebhook creates a webhook for the current tenant and returns its signing secret
+func (s *webhookService) CreateWebhook(ctx context.Context,
+	webhook *types.Webhook,
+) (*types.Webhook, string, error) {
+	if err := s.validate(webhook); err != nil {
+		return nil, "", err
+	}
+	if webhook.Secret == "" {
+		secret, err := generateWebhookSecret()
+		if err != nil {
+			return nil, "", err
+		}
+		webhook.Secret = types.EncryptedString(secret)
+	}
+
+	now := time.Now()
+	webhook.ID = uuid.New().String()
+	webhook.TenantID = ctx.Value(types.TenantIDContextKey).(uint64)
+	webhook.CreatedAt = now
+	webhook.UpdatedAt = now
+	if err := s.repo.Create(ctx, webhook); err != nil {
+		return nil, "", err
+	}
+	logger.Infof(ctx, "[Webhook] Created webhook %s for events %v", webhook.ID, webhook.Events)
+	return webhook, string(webhook.Secret), nil
+}
+
+// GetWebhook returns a webhook of the current tenant
+func (s *webhookService) GetWebhook(ctx context.Context, id string) (*types.Webhook, error) {
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	webhook, err := s.repo.GetByID(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if webhook == nil {
+		return nil, ErrWebhookNotFound
+	}
+	return webhook, nil
+}
+
+// ListWebhooks lists the webhooks of the current tenant
+func (s *webhookService) ListWebhooks(ctx context.Context) ([]*types.Webhook, error) {
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	return s.repo.List(ctx, tenantID)
+}
+
+// UpdateWebhook updates a webhook of the current tenant
+func (s *webhookService) UpdateWebhook(ctx context.Context, webhook *types.Webhook) (*types.Webhook, error) {
+	if err := s.validate(webhook); err != nil {
+		return nil, err
+	}
+	webhook.TenantID = ctx.Value(types.TenantIDContextKey).(uint64)
+	webhook.UpdatedAt = time.Now()
+	if err := s.repo.Update(ctx, webhook); err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+// DeleteWebhook deletes a webhook of the current tenant
+func (s *webhookService) DeleteWebhook(ctx context.Context, id string) error {
+	webhook, err := s.GetWebhook(ctx, id)
+	if err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, webhook.TenantID, webhook.ID)
+}
+
+// TestWebhook sends a test event synchronously, regardless of the subscribed events
+func (s *webhookService) TestWebhook(ctx context.Context, id string) (*types.WebhookDelivery, error) {
+	webhook, err := s.GetWebhook(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery := newWebhookDelivery(webhook, newWebhookEvent(webhook.TenantID, types.WebhookEventTest, map[string]interface{}{
+		"webhook_id": webhook.ID,
+		"message":    "This is a test event from WeKnora",
+	}))
+	if err := s.repo.CreateDelivery(ctx, delivery); err != nil {
+		return nil, err
+	}
+
+	if err := s.send(ctx, webhook, delivery); err != nil {
+		delivery.Status = types.WebhookDeliveryStatusFailed
+	}
+	if err := s.repo.UpdateDelivery(ctx, delivery); err != nil {
+		logger.Warnf(ctx, "[Webhook] Failed to save test delivery %s: %v", delivery.ID, err)
+	}
+	return delivery, nil
+}
+
+// ListDeliveries lists the delivery log of a webhook of the current tenant
+func (s *webhookService) ListDeliveries(ctx context.Context,
+	webhookID string, page *types.Pagination,
+) (*types.PageResult, error) {
+	webhook, err := s.GetWebhook(ctx, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	deliveries, total, err := s.repo.ListDeliveries(ctx, webhook.TenantID, webhook.ID, page)
+	if err != nil {
+		return nil, err
+	}
+	return types.NewPageResult(total, page, deliveries), nil
+}
+
+// Publish creates a delivery for each enabled webhook of the tenant subscribed to the event
+// and enqueues it. Failures are only logged.
+func (s *webhookService) Publish(ctx context.Context, tenantID uint64, event string, data interface{}) {
+	s.publish(ctx, tenantID, event, data, "")
+}
+
+// PublishTo publishes the event like Publish, and also delivers it to the webhook webhookID
+// when that webhook is enabled but does not subscribe to the event
+func (s *webhookService) PublishTo(ctx context.Context,
+	tenantID uint64, webhookID string, event string, data interface{},
+) {
+	s.publish(ctx, tenantID, event, data, webhookID)
+}
+
+// publish delivers the event to the subscribed webhooks of the tenant and to webhook extraID
+func (s *webhookService) publish(ctx context.Context,
+	tenantID uint64, event string, data interface{}, extraID string,
+) {
+	if tenantID == 0 {
+		return
+	}
+	// Events are often published at the end of a request or task whose context is about to end
+	ctx = context.WithoutCancel(ctx)
+
+	webhooks, err := s.repo.ListEnabled(ctx, tenantID)
+	if err != nil {
+		logger.Warnf(ctx, "[Webhook] Failed to list webhooks of tenant %d for %s: %v", tenantID, event, err)
+		return
+	}
+
+	var payload *types.WebhookEvent
+	for _, webhook := range webhooks {
+		if !webhook.Subscribes(event) && webhook.ID != extraID {
+			continue
+		}
+		if payload == nil {
+			payload = newWebhookEvent(tenantID, event, data)
+		}
+		delivery := newWebhookDelivery(webhook, payload)
+		if err := s.repo.CreateDelivery(ctx, delivery); err != nil {
+			logger.Warnf(ctx, "[Webhook] Failed to create delivery of %s to webhook %s: %v", event, webhook.ID, err)
+			continue
+		}
+		if err := s.enqueue(delivery); err != nil {
+			logger.Warnf(ctx, "[Webhook] Failed to enqueue delivery %s: %v", delivery.ID, err)
+			delivery.Status = types.WebhookDeliveryStatusFailed
+			delivery.Error = err.Error()
+			delivery.UpdatedAt = time.Now()
+			_ = s.repo.UpdateDelivery(ctx, delivery)
+		}
+	}
+}
+
+// ProcessWebhookDelivery handles the asynq webhook delivery task.
+// A failed attempt returns an error so that asynq retries it with backoff;
+// the delivery is marked failed after the last retry.
+func (s *webhookService) ProcessWebhookDelivery(ctx context.Context, t *asynq.Task) error {
+	var payload types.WebhookDeliveryPayload
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		logger.Errorf(ctx, "[Webhook] Failed to unmarshal delivery payload: %v", err)
+		return nil
+	}
+	retryCount, _ := asynq.GetRetryCount(ctx)
+	maxRetry, _ := asynq.GetMaxRetry(ctx)
+	return s.processDelivery(ctx, payload, retryCount >= maxRetry)
+}
+
+// processDelivery makes one attempt of a delivery. A failed attempt is marked retrying and returns
+// the error, or is marked failed when it is the last attempt.
+func (s *webhookService) processDelivery(ctx context.Context,
+	payload types.WebhookDeliveryPayload, lastAttempt bool,
+) error {
+	delivery, err := s.repo.GetDelivery(ctx, payload.TenantID, payload.DeliveryID)
+	if err != nil {
+		return err
+	}
+	if delivery == nil || delivery.Status == types.WebhookDeliveryStatusSuccess {
+		return nil
+	}
+
+	webhook, err := s.repo.GetByID(ctx, payload.TenantID, delivery.WebhookID)
+	if err != nil {
+		return err
+	}
+	if webhook == nil || !webhook.Enabled {
+		delivery.Status = types.WebhookDeliveryStatusFailed
+		delivery.Error = "webhook was deleted or disabled"
+		delivery.UpdatedAt = time.Now()
+		return s.repo.UpdateDelivery(ctx, delivery)
+	}
+
+	sendErr := s.send(ctx, webhook, delivery)
+	if sendErr != nil {
+		if lastAttempt {
+			delivery.Status = types.WebhookDeliveryStatusFailed
+		} else {
+			delivery.Status = types.WebhookDeliveryStatusRetrying
+		}
+		logger.Warnf(ctx, "[Webhook] Delivery %s of %s to webhook %s failed (attempt %d): %v",
+			delivery.ID, delivery.Event, webhook.ID, delivery.Attempts, sendErr)
+	}
+	if err := s.repo.UpdateDelivery(ctx, delivery); err != nil {
+		logger.Warnf(ctx, "[Webhook] Failed to save delivery %s: %v", delivery.ID, err)
+	}
+	if sendErr != nil && delivery.Status == types.WebhookDeliveryStatusRetrying {
+		return sendErr
+	}
+	return nil
+}
+
+// send posts the event of a delivery to the webhook and records the attempt on the delivery.
+// Only 2xx responses count as success.
+func (s *webhookService) send(ctx context.Context, webhook *types.Webhook, delivery *types.WebhookDelivery) error {
+	start := time.Now()
+	delivery.Attempts++
+	delivery.ResponseStatus = 0
+	delivery.ResponseBody = ""
+	delivery.Error = ""
+	defer func() {
+		delivery.DurationMs = time.Since(start).Milliseconds()
+		delivery.UpdatedAt = time.Now()
+	}()
+
+	err := s.post(ctx, webhook, delivery)
+	if err != nil {
+		delivery.Error = err.Error()
+		return err
+	}
+	now := time.Now()
+	delivery.Status = types.WebhookDeliveryStatusSuccess
+	delivery.DeliveredAt = &now
+	return nil
+}
+
+// post signs and sends the request
+func (s *webhookService) post(ctx context.Context, webhook *types.Webhook, delivery *types.WebhookDelivery) error {
+	// Validate again at delivery time, the URL may predate a configuration change
+	if err := s.validateURL(webhook.URL); err != nil {
+		return err
+	}
+	body, err := json.Marshal(delivery.Payload)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, webhookRequestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "WeKnora-Webhook/1.0")
+	req.Header.Set(types.WebhookHeaderEvent, delivery.Event)
+	req.Header.Set(types.WebhookHeaderDelivery, delivery.ID)
+	req.Header.Set(types.WebhookHeaderTimestamp, timestamp)
+	req.Header.Set(types.WebhookHeaderSignature, SignWebhookPayload(string(webhook.Secret), timestamp, body))
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, webhookResponseBodyLimit))
+	delivery.ResponseStatus = resp.StatusCode
+	delivery.ResponseBody = strings.ToValidUTF8(string(respBody), "")
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook endpoint returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// enqueue enqueues the delivery task of a delivery
+func (s *webhookService) enqueue(delivery *types.WebhookDelivery) error {
+	payload, err := json.Marshal(types.WebhookDeliveryPayload{TenantID: delivery.TenantID, DeliveryID: delivery.ID})
+	if err != nil {
+		return err
+	}
+	task := asynq.NewTask(types.TypeWebhookDelivery, payload,
+		asynq.Queue("default"), asynq.MaxRetry(webhookMaxRetry), asynq.Timeout(2*webhookRequestTimeout))
+	_, err = s.asynqClient.Enqueue(task)
+	return err
+}
+
+// validate checks the URL and the subscribed events of a webhook
+func (s *webhookService) validate(webhook *types.Webhook) error {
+	if err := s.validateURL(webhook.URL); err != nil {
+		return err
+	}
+	if len(webhook.Events) == 0 {
+		return fmt.Errorf("%w: at least one event is required", ErrWebhookInvalidEvent)
+	}
+	for _, event := range webhook.Events {
+		if !slices.Contains(types.WebhookEvents, event) {
+			return fmt.Errorf("%w: %s", ErrWebhookInvalidEvent, event)
+		}
+	}
+	return nil
+}
+
+// validateURL rejects non http(s) URLs, and private addresses unless they are allowed
+func (s *webhookService) validateURL(rawURL string) error {
+	if !strings.HasPrefix(rawURL, "http://") && !strings.HasPrefix(rawURL, "https://") {
+		return fmt.Errorf("%w: only http and https are supported", ErrWebhookInvalidURL)
+	}
+	if s.allowPrivate {
+		return nil
+	}
+	if safe, reason := secutils.IsSSRFSafeURL(rawURL); !safe {
+		return fmt.Errorf("%w: %s", ErrWebhookInvalidURL, reason)
+	}
+	return nil
+}
+
+// SignWebhookPayload returns the X-WeKnora-Signature header value of a request body
+func SignWebhookPayload(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// generateWebhookSecret returns a random signing secret
+func generateWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// newWebhookEvent builds the event body posted to webhooks
+func newWebhookEvent(tenantID uint64, event string, data interface{}) *types.WebhookEvent {
+	return &types.WebhookEvent{
+		ID:        uuid.New().String(),
+		Type:      event,
+		TenantID:  tenantID,
+		CreatedAt: time.Now(),
+		Data:      data,
+	}
+}
+
+// newWebhookDelivery builds a pending delivery of an event to a webhook
+func newWebhookDelivery(webhook *types.Webhook, event *types.WebhookEvent) *types.WebhookDelivery {
+	now := time.Now()
+	return &types.WebhookDelivery{
+		ID:        uuid.New().String(),
+		TenantID:  webhook.TenantID,
+		WebhookID: webhook.ID,
+		Event:     event.Type,
+		Payload:   *event,
+		Status:    types.WebhookDeliveryStatusPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+)
+
+func TestSignWebhookPayload(t *testing.T) {
+	// HMAC-SHA256 of "1700000000.<body>" keyed by the secret, computed independently
+	body := []byte(`{"event":"ping","data":{"message":"hello"}}`)
+	want := "sha256=3e78c2c957bfe14192c74c463e89268829767a7e2d21a2ae867d0869ab8e2436"
+	if got := SignWebhookPayload("whsec_test", "1700000000", body); got != want {
+		t.Errorf("SignWebhookPayload() = %s, want %s", got, want)
+	}
+	if got := SignWebhookPayload("whsec_test", "1700000001", body); got == want {
+		t.Error("signature does not depend on the timestamp")
+	}
+}
+
+// fakeWebhookRepository keeps one webhook and one delivery in memory
+type fakeWebhookRepository struct {
+	interfaces.WebhookRepository
+	webhook  *types.Webhook
+	delivery *types.WebhookDelivery
+	updates  []string
+}
+
+func (r *fakeWebhookRepository) GetByID(ctx context.Context, tenantID uint64, id string) (*types.Webhook, error) {
+	if r.webhook == nil || r.webhook.ID != id {
+		return nil, nil
+	}
+	return r.webhook, nil
+}
+
+func (r *fakeWebhookRepository) GetDelivery(ctx context.Context, tenantID uint64, id string) (*types.WebhookDelivery, error) {
+	if r.delivery.ID != id {
+		return nil, nil
+	}
+	copied := *r.delivery
+	return &copied, nil
+}
+
+func (r *fakeWebhookRepository) UpdateDelivery(ctx context.Context, delivery *types.WebhookDelivery) error {
+	copied := *delivery
+	r.delivery = &copied
+	r.updates = append(r.updates, delivery.Status)
+	return nil
+}
+
+// newWebhookTestServer returns an endpoint that checks the signature and fails the first failures requests
+func newWebhookTestServer(t *testing.T, secret string, failures int32) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := hits.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		signature := SignWebhookPayload(secret, r.Header.Get(types.WebhookHeaderTimestamp), body)
+		if r.Header.Get(types.WebhookHeaderSignature) != signature {
+			t.Errorf("request %d has signature %q, want %q", n, r.Header.Get(types.WebhookHeaderSignature), signature)
+		}
+		if r.Header.Get(types.WebhookHeaderDelivery) != "delivery-1" {
+			t.Errorf("request %d has delivery header %q", n, r.Header.Get(types.WebhookHeaderDelivery))
+		}
+		if n <= failures {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+	return server, &hits
+}
+
+// newWebhookTestService returns a service delivering to url with a pending delivery
+func newWebhookTestService(url, secret string) (*webhookService, *fakeWebhookRepository) {
+	webhook := &types.Webhook{ID: "webhook-1", TenantID: 1, URL: url, Secret: types.EncryptedString(secret), Enabled: true}
+	event := newWebhookEvent(1, types.WebhookEvents[0], map[string]string{"message": "hello"})
+	delivery := newWebhookDelivery(webhook, event)
+	delivery.ID = "delivery-1"
+	repo := &fakeWebhookRepository{webhook: webhook, delivery: delivery}
+	return &webhookService{repo: repo, httpClient: &http.Client{Timeout: 5 * time.Second}, allowPrivate: true}, repo
+}
+
+func TestProcessWebhookDeliveryRetriesUntilFailed(t *testing.T) {
+	server, hits := newWebhookTestServer(t, "secret", 100)
+	svc, repo := newWebhookTestService(server.URL, "secret")
+	payload := types.WebhookDeliveryPayload{TenantID: 1, DeliveryID: "delivery-1"}
+
+	for attempt := 0; attempt <= webhookMaxRetry; attempt++ {
+		lastAttempt := attempt == webhookMaxRetry
+		err := svc.processDelivery(context.Background(), payload, lastAttempt)
+		if lastAttempt {
+			if err != nil {
+				t.Errorf("last attempt returned %v, want nil so that the task is not retried", err)
+			}
+			break
+		}
+		if err == nil {
+			t.Fatalf("attempt %d returned nil, want the error so that the task is retried", attempt)
+		}
+		if repo.delivery.Status != types.WebhookDeliveryStatusRetrying {
+			t.Fatalf("status after attempt %d = %q, want %q", attempt, repo.delivery.Status, types.WebhookDeliveryStatusRetrying)
+		}
+	}
+
+	delivery := repo.delivery
+	if delivery.Status != types.WebhookDeliveryStatusFailed {
+		t.Errorf("final status = %q, want %q", delivery.Status, types.WebhookDeliveryStatusFailed)
+	}
+	if delivery.Attempts != webhookMaxRetry+1 || hits.Load() != webhookMaxRetry+1 {
+		t.Errorf("attempts = %d, requests = %d, want %d", delivery.Attempts, hits.Load(), webhookMaxRetry+1)
+	}
+	if delivery.ResponseStatus != http.StatusServiceUnavailable || delivery.Error == "" || delivery.DeliveredAt != nil {
+		t.Errorf("failed delivery = %+v", delivery)
+	}
+}
+
+func TestProcessWebhookDeliverySucceedsOnRetry(t *testing.T) {
+	server, hits := newWebhookTestServer(t, "secret", 1)
+	svc, repo := newWebhookTestService(server.URL, "secret")
+	payload := types.WebhookDeliveryPayload{TenantID: 1, DeliveryID: "delivery-1"}
+
+	if err := svc.processDelivery(context.Background(), payload, false); err == nil {
+		t.Fatal("first attempt returned nil, want an error")
+	}
+	if err := svc.processDelivery(context.Background(), payload, false); err != nil {
+		t.Fatalf("second attempt returned %v", err)
+	}
+	delivery := repo.delivery
+	if delivery.Status != types.WebhookDeliveryStatusSuccess || delivery.Attempts != 2 ||
+		delivery.ResponseStatus != http.StatusNoContent || delivery.Error != "" || delivery.DeliveredAt == nil {
+		t.Errorf("delivered delivery = %+v", delivery)
+	}
+
+	// A delivered event is not sent again
+	if err := svc.processDelivery(context.Background(), payload, false); err != nil || hits.Load() != 2 {
+		t.Errorf("redelivery returned %v after %d requests, want no request", err, hits.Load())
+	}
+}
+
+func TestProcessWebhookDeliveryDisabledWebhook(t *testing.T) {
+	server, hits := newWebhookTestServer(t, "secret", 0)
+	svc, repo := newWebhookTestService(server.URL, "secret")
+	repo.webhook.Enabled = false
+
+	err := svc.processDelivery(context.Background(), types.WebhookDeliveryPayload{TenantID: 1, DeliveryID: "delivery-1"}, false)
+	if err != nil {
+		t.Fatalf("processDelivery() = %v", err)
+	}
+	if repo.delivery.Status != types.WebhookDeliveryStatusFailed || hits.Load() != 0 {
+		t.Errorf("status = %q after %d requests, want failed without a request", repo.delivery.Status, hits.Load())
+	}
+}
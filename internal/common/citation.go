@@ -0,0 +1,332 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/models/chat"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// CitationOverlapThreshold is the share of a cited sentence's terms that must appear
+// in the cited references for the sentence to count as supported
+const CitationOverlapThreshold = 0.4
+
+const (
+	// citationModelCheckMaxClaims caps the sentences sent to the model in one check,
+	// later sentences keep the lexical verdict
+	citationModelCheckMaxClaims = 20
+	// citationModelCheckMaxSourceRunes truncates each reference quoted to the model
+	citationModelCheckMaxSourceRunes = 1500
+)
+
+// CitationInstruction asks the model to cite the numbered references of the context inline
+const CitationInstruction = `回答要求：引用参考资料的内容时，在对应句子的句末用资料编号标注来源，如 [1] 或 [1][3]；` +
+	`只能使用参考资料中出现的编号，不要编造编号，不要把所有引用集中放在回答末尾。`
+
+// citationCheckPrompt asks the model whether each cited sentence is supported by its sources
+const citationCheckPrompt = `You check citations in an answer. For each numbered claim, decide whether its sources support it:
+the claim must be stated in the sources or follow directly from them. Ignore wording and language differences.
+Reply with only a JSON array containing one object per claim, without any explanation or code fences:
+[{"claim": 1, "supported": true}]`
+
+var (
+	// citationMarkerPattern matches numbered markers such as [1], [1,2] and [1、3]
+	citationMarkerPattern = regexp.MustCompile(`\[(\d+(?:\s*[,，、]\s*\d+)*)\]`)
+	// kbCitationPattern matches knowledge citation tags such as <kb doc="..." chunk_id="..." />
+	kbCitationPattern = regexp.MustCompile(`<kb\b[^>]*?\bchunk_id="([^"]*)"[^>]*>`)
+	// anyCitationPattern matches either kind of marker with the spaces before it
+	anyCitationPattern = regexp.MustCompile(`[ \t]*(?:\[\d+(?:\s*[,，、]\s*\d+)*\]|<kb\b[^>]*>)`)
+	// leadingCitationPattern matches markers placed right after the end of a sentence
+	leadingCitationPattern = regexp.MustCompile(`^` + anyCitationPattern.String())
+)
+
+// citationStopWords are frequent English words ignored by the lexical overlap check
+var citationStopWords = map[string]bool{
+	"the": true, "an": true, "of": true, "to": true, "in": true, "is": true, "are": true, "was": true,
+	"were": true, "and": true, "or": true, "for": true, "on": true, "with": true, "by": true, "as": true,
+	"at": true, "be": true, "it": true, "its": true, "this": true, "that": true, "from": true,
+}
+
+// CheckCitations maps the citation markers of an answer to its references, where [n] refers to refs[n-1]
+// and <kb chunk_id="..."/> tags to the reference with that ID, and checks that each cited sentence is
+// supported by the lexical overlap with the references it cites. When model is not nil, the model also
+// judges the sentences and its verdict takes precedence. It returns nil when there are no references.
+func CheckCitations(ctx context.Context, answer string, refs []*types.SearchResult, model chat.Chat) *types.CitationReport {
+	if len(refs) == 0 {
+		return nil
+	}
+	numbers := make(map[string]int, len(refs))
+	for i, ref := range refs {
+		if _, ok := numbers[ref.ID]; !ok {
+			numbers[ref.ID] = i + 1
+		}
+	}
+	refTerms := make(map[int]map[string]bool)
+
+	report := &types.CitationReport{
+		Sentences:         make([]types.CitationSentence, 0),
+		UnsupportedClaims: make([]types.CitationSentence, 0),
+		Check:             types.CitationCheckLexical,
+	}
+	index := 0
+	for _, raw := range splitSentences(thinkBlockPattern.ReplaceAllString(answer, "")) {
+		citations, unknown := sentenceCitations(raw, numbers)
+		text := strings.TrimSpace(anyCitationPattern.ReplaceAllString(raw, ""))
+		if text == "" {
+			continue
+		}
+		index++
+		if len(citations) == 0 && !unknown {
+			continue
+		}
+
+		sentence := types.CitationSentence{
+			Index:        index - 1,
+			Text:         text,
+			Citations:    make([]int, 0, len(citations)),
+			ReferenceIDs: make([]string, 0, len(citations)),
+		}
+		sources := make(map[string]bool)
+		for _, n := range citations {
+			if n > len(refs) {
+				unknown = true
+				continue
+			}
+			sentence.Citations = append(sentence.Citations, n)
+			sentence.ReferenceIDs = append(sentence.ReferenceIDs, refs[n-1].ID)
+			if refTerms[n] == nil {
+				refTerms[n] = citationTerms(refs[n-1].Content)
+			}
+			for term := range refTerms[n] {
+				sources[term] = true
+			}
+		}
+		sentence.Overlap = termOverlap(citationTerms(text), sources)
+		switch {
+		case unknown:
+			sentence.Reason = types.CitationReasonUnknownReference
+		case sentence.Overlap < CitationOverlapThreshold:
+			sentence.Reason = types.CitationReasonLowOverlap
+		default:
+			sentence.Supported = true
+		}
+		report.Sentences = append(report.Sentences, sentence)
+	}
+
+	if model != nil && len(report.Sentences) > 0 {
+		if err := checkCitationsWithModel(ctx, model, report, refs); err != nil {
+			logger.Warnf(ctx, "Citation check with the model failed, keeping the lexical check: %v", err)
+			PipelineWarn(ctx, "Citation", "model_check_failed", map[string]interface{}{
+				"error": err.Error(),
+			})
+		} else {
+			report.Check = types.CitationCheckModel
+		}
+	}
+
+	for _, sentence := range report.Sentences {
+		if !sentence.Supported {
+			report.UnsupportedClaims = append(report.UnsupportedClaims, sentence)
+		}
+	}
+	PipelineInfo(ctx, "Citation", "checked", map[string]interface{}{
+		"references":  len(refs),
+		"cited":       len(report.Sentences),
+		"unsupported": len(report.UnsupportedClaims),
+		"check":       report.Check,
+	})
+	return report
+}
+
+// checkCitationsWithModel asks the model whether the cited references support each sentence
+// and applies its verdicts. Sentences citing unknown numbers are left unsupported.
+func checkCitationsWithModel(
+	ctx context.Context,
+	model chat.Chat,
+	report *types.CitationReport,
+	refs []*types.SearchResult,
+) error {
+	var claims []int
+	var content strings.Builder
+	for i, sentence := range report.Sentences {
+		if sentence.Reason == types.CitationReasonUnknownReference {
+			continue
+		}
+		if len(claims) == citationModelCheckMaxClaims {
+			break
+		}
+		claims = append(claims, i)
+		fmt.Fprintf(&content, "Claim %d: %s\nSources:\n", len(claims), sentence.Text)
+		for _, n := range sentence.Citations {
+			fmt.Fprintf(&content, "[%d] %s\n", n, truncateRunes(refs[n-1].Content, citationModelCheckMaxSourceRunes))
+		}
+		content.WriteString("\n")
+	}
+	if len(claims) == 0 {
+		return nil
+	}
+
+	response, err := model.Chat(ctx, []chat.Message{
+		{Role: "system", Content: citationCheckPrompt},
+		{Role: "user", Content: content.String()},
+	}, &chat.ChatOptions{Temperature: 0})
+	if err != nil {
+		return err
+	}
+	var verdicts []struct {
+		Claim     int  `json:"claim"`
+		Supported bool `json:"supported"`
+	}
+	if err := ParseLLMJsonResponse(strings.TrimSpace(thinkBlockPattern.ReplaceAllString(response.Content, "")), &verdicts); err != nil {
+		return fmt.Errorf("parse verdicts: %w", err)
+	}
+	for _, verdict := range verdicts {
+		if verdict.Claim < 1 || verdict.Claim > len(claims) {
+			continue
+		}
+		sentence := &report.Sentences[claims[verdict.Claim-1]]
+		sentence.Supported = verdict.Supported
+		sentence.Reason = ""
+		if !verdict.Supported {
+			sentence.Reason = types.CitationReasonModelRejected
+		}
+	}
+	return nil
+}
+
+// splitSentences splits text at sentence-ending punctuation and line breaks.
+// Citation markers right after the punctuation stay with the sentence they follow.
+func splitSentences(text string) []string {
+	var sentences []string
+	start := 0
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+		if !isSentenceEnd(r, text[i:]) {
+			continue
+		}
+		for {
+			loc := leadingCitationPattern.FindStringIndex(text[i:])
+			if loc == nil {
+				break
+			}
+			i += loc[1]
+		}
+		sentences = append(sentences, text[start:i])
+		start = i
+	}
+	if start < len(text) {
+		sentences = append(sentences, text[start:])
+	}
+	return sentences
+}
+
+// isSentenceEnd reports whether r ends a sentence, rest is the text after r.
+// A full stop only ends a sentence before whitespace so that decimals and domains are kept whole.
+func isSentenceEnd(r rune, rest string) bool {
+	switch r {
+	case '。', '！', '？', '；', '!', '?', ';', '\n':
+		return true
+	case '.':
+		next, _ := utf8.DecodeRuneInString(rest)
+		return rest == "" || unicode.IsSpace(next)
+	}
+	return false
+}
+
+// sentenceCitations returns the distinct citation numbers of a sentence in order of appearance,
+// and whether it cites a knowledge chunk that is not among the references
+func sentenceCitations(sentence string, numbers map[string]int) ([]int, bool) {
+	var citations []int
+	seen := make(map[int]bool)
+	add := func(n int) {
+		if n > 0 && !seen[n] {
+			seen[n] = true
+			citations = append(citations, n)
+		}
+	}
+	for _, match := range citationMarkerPattern.FindAllStringSubmatch(sentence, -1) {
+		for _, part := range strings.FieldsFunc(match[1], func(r rune) bool {
+			return r == ',' || r == '，' || r == '、' || unicode.IsSpace(r)
+		}) {
+			n, _ := strconv.Atoi(part)
+			add(n)
+		}
+	}
+	unknown := false
+	for _, match := range kbCitationPattern.FindAllStringSubmatch(sentence, -1) {
+		if n, ok := numbers[match[1]]; ok {
+			add(n)
+		} else {
+			unknown = true
+		}
+	}
+	return citations, unknown
+}
+
+// citationTerms returns the terms compared by the overlap check: lower-cased words and numbers,
+// and character bigrams of Chinese text (the character itself for a single character)
+func citationTerms(text string) map[string]bool {
+	terms := make(map[string]bool)
+	var word, han []rune
+	flush := func() {
+		if w := string(word); len(word) > 1 && !citationStopWords[w] || len(word) == 1 && unicode.IsDigit(word[0]) {
+			terms[w] = true
+		}
+		if len(han) == 1 {
+			terms[string(han)] = true
+		}
+		for i := 0; i+1 < len(han); i++ {
+			terms[string(han[i:i+2])] = true
+		}
+		word, han = word[:0], han[:0]
+	}
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			if len(word) > 0 {
+				flush()
+			}
+			han = append(han, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if len(han) > 0 {
+				flush()
+			}
+			word = append(word, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return terms
+}
+
+// termOverlap returns the share of terms found in sources, 1 when there are no terms
+func termOverlap(terms, sources map[string]bool) float64 {
+	if len(terms) == 0 {
+		return 1
+	}
+	matched := 0
+	for term := range terms {
+		if sources[term] {
+			matched++
+		}
+	}
+	return float64(matched) / float64(len(terms))
+}
+
+// truncateRunes shortens s to at most n runes
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "..."
+}
@@ -0,0 +1,238 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/models/chat"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// fakeCitationChat returns a fixed response and records the prompt it was sent
+type fakeCitationChat struct {
+	content string
+	err     error
+	prompt  string
+}
+
+func (f *fakeCitationChat) Chat(ctx context.Context, messages []chat.Message, opts *chat.ChatOptions) (*types.ChatResponse, error) {
+	f.prompt = messages[len(messages)-1].Content
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &types.ChatResponse{Content: f.content}, nil
+}
+
+func (f *fakeCitationChat) ChatStream(ctx context.Context, messages []chat.Message, opts *chat.ChatOptions) (<-chan types.StreamResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakeCitationChat) GetModelName() string { return "fake" }
+
+func (f *fakeCitationChat) GetModelID() string { return "fake" }
+
+var citationTestRefs = []*types.SearchResult{
+	{ID: "chunk-1", Content: "Paris is the capital of France and its largest city."},
+	{ID: "chunk-2", Content: "The Eiffel Tower was completed in 1889 for the World's Fair."},
+	{ID: "chunk-3", Content: "北京是中华人民共和国的首都，也是全国的政治中心。"},
+}
+
+func TestCheckCitations(t *testing.T) {
+	tests := []struct {
+		name   string
+		answer string
+		want   []types.CitationSentence
+	}{
+		{
+			name:   "single marker",
+			answer: "Paris is the capital of France [1]. It is sunny today.",
+			want: []types.CitationSentence{
+				{Index: 0, Text: "Paris is the capital of France.", Citations: []int{1}, Supported: true},
+			},
+		},
+		{
+			name:   "comma separated markers",
+			answer: "Paris has the Eiffel Tower, completed in 1889 [1,2].",
+			want: []types.CitationSentence{
+				{Index: 0, Text: "Paris has the Eiffel Tower, completed in 1889.", Citations: []int{1, 2}, Supported: true},
+			},
+		},
+		{
+			name:   "enumeration comma and repeated markers",
+			answer: "北京是首都[1、3][3]。",
+			want: []types.CitationSentence{
+				{Index: 0, Text: "北京是首都。", Citations: []int{1, 3}, Supported: true},
+			},
+		},
+		{
+			name:   "markers after the end of the sentence stay with it",
+			answer: "The Eiffel Tower was completed in 1889. [2] Paris is the capital of France.[1]",
+			want: []types.CitationSentence{
+				{Index: 0, Text: "The Eiffel Tower was completed in 1889.", Citations: []int{2}, Supported: true},
+				{Index: 1, Text: "Paris is the capital of France.", Citations: []int{1}, Supported: true},
+			},
+		},
+		{
+			name:   "knowledge citation tags",
+			answer: `Paris is the capital of France <kb doc="a.md" chunk_id="chunk-1" />. The tower opened in 1889 <kb chunk_id="missing"/>.`,
+			want: []types.CitationSentence{
+				{Index: 0, Text: "Paris is the capital of France.", Citations: []int{1}, Supported: true},
+				{Index: 1, Text: "The tower opened in 1889.", Reason: types.CitationReasonUnknownReference},
+			},
+		},
+		{
+			name:   "unknown numbers",
+			answer: "Paris is the capital of France [1][7].",
+			want: []types.CitationSentence{
+				{Index: 0, Text: "Paris is the capital of France.", Citations: []int{1}, Reason: types.CitationReasonUnknownReference},
+			},
+		},
+		{
+			name:   "CJK sentences and uncited sentences keep their index",
+			answer: "这是开场白。北京是中国的首都[3]！上海是中国最大的港口城市[3]。",
+			want: []types.CitationSentence{
+				{Index: 1, Text: "北京是中国的首都！", Citations: []int{3}, Supported: true},
+				{Index: 2, Text: "上海是中国最大的港口城市。", Citations: []int{3}, Reason: types.CitationReasonLowOverlap},
+			},
+		},
+		{
+			name:   "decimals do not end a sentence",
+			answer: "The tower was completed in 1889.5 years ago, roughly [2]. Done",
+			want: []types.CitationSentence{
+				{Index: 0, Text: "The tower was completed in 1889.5 years ago, roughly.", Citations: []int{2}, Supported: true},
+			},
+		},
+		{
+			name:   "think blocks are ignored",
+			answer: "<think>Paris [1].</think>No citations here.",
+			want:   nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := CheckCitations(context.Background(), tt.answer, citationTestRefs, nil)
+			if report == nil {
+				t.Fatal("CheckCitations() = nil")
+			}
+			if report.Check != types.CitationCheckLexical {
+				t.Errorf("Check = %q, want %q", report.Check, types.CitationCheckLexical)
+			}
+			if len(report.Sentences) != len(tt.want) {
+				t.Fatalf("got %d sentences %+v, want %d", len(report.Sentences), report.Sentences, len(tt.want))
+			}
+			unsupported := 0
+			for i, want := range tt.want {
+				got := report.Sentences[i]
+				if got.Index != want.Index || got.Text != want.Text || !slices.Equal(got.Citations, want.Citations) ||
+					got.Supported != want.Supported || got.Reason != want.Reason {
+					t.Errorf("sentence %d = %+v, want %+v", i, got, want)
+				}
+				for j, n := range got.Citations {
+					if got.ReferenceIDs[j] != citationTestRefs[n-1].ID {
+						t.Errorf("sentence %d reference IDs = %v, want the IDs of %v", i, got.ReferenceIDs, got.Citations)
+					}
+				}
+				if !want.Supported {
+					unsupported++
+				}
+			}
+			if len(report.UnsupportedClaims) != unsupported {
+				t.Errorf("got %d unsupported claims, want %d", len(report.UnsupportedClaims), unsupported)
+			}
+		})
+	}
+}
+
+func TestCheckCitationsWithoutReferences(t *testing.T) {
+	if report := CheckCitations(context.Background(), "Paris [1].", nil, nil); report != nil {
+		t.Errorf("CheckCitations() without references = %+v, want nil", report)
+	}
+}
+
+func TestCheckCitationsOverlapThreshold(t *testing.T) {
+	refs := []*types.SearchResult{{ID: "r", Content: "alpha beta"}}
+	tests := []struct {
+		name      string
+		answer    string
+		overlap   float64
+		supported bool
+	}{
+		// 2 of the 5 terms are in the reference: exactly the threshold
+		{"at threshold", "alpha beta gamma delta epsilon [1].", 0.4, true},
+		{"below threshold", "alpha gamma delta epsilon zeta [1].", 0.2, false},
+		// Stop words are not counted as terms
+		{"stop words ignored", "the alpha of the beta and gamma [1].", 2.0 / 3.0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := CheckCitations(context.Background(), tt.answer, refs, nil)
+			if len(report.Sentences) != 1 {
+				t.Fatalf("got sentences %+v, want one", report.Sentences)
+			}
+			got := report.Sentences[0]
+			if got.Overlap != tt.overlap || got.Supported != tt.supported {
+				t.Errorf("overlap = %v, supported = %v, want %v, %v", got.Overlap, got.Supported, tt.overlap, tt.supported)
+			}
+		})
+	}
+}
+
+func TestCheckCitationsWithModel(t *testing.T) {
+	answer := "Paris is the capital of France [1]. The Eiffel Tower was completed in 1889 [2]. Unknown claim [9]."
+
+	tests := []struct {
+		name      string
+		model     *fakeCitationChat
+		check     string
+		supported []bool
+		reasons   []string
+	}{
+		{
+			name:      "verdicts override the lexical check",
+			model:     &fakeCitationChat{content: "```json\n[{\"claim\": 1, \"supported\": false}, {\"claim\": 2, \"supported\": true}, {\"claim\": 5, \"supported\": true}]\n```"},
+			check:     types.CitationCheckModel,
+			supported: []bool{false, true, false},
+			reasons:   []string{types.CitationReasonModelRejected, "", types.CitationReasonUnknownReference},
+		},
+		{
+			name:      "unparsable verdict keeps the lexical check",
+			model:     &fakeCitationChat{content: "Both claims look fine to me."},
+			check:     types.CitationCheckLexical,
+			supported: []bool{true, true, false},
+			reasons:   []string{"", "", types.CitationReasonUnknownReference},
+		},
+		{
+			name:      "model error keeps the lexical check",
+			model:     &fakeCitationChat{err: errors.New("timeout")},
+			check:     types.CitationCheckLexical,
+			supported: []bool{true, true, false},
+			reasons:   []string{"", "", types.CitationReasonUnknownReference},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := CheckCitations(context.Background(), answer, citationTestRefs, tt.model)
+			if report.Check != tt.check {
+				t.Errorf("Check = %q, want %q", report.Check, tt.check)
+			}
+			if len(report.Sentences) != len(tt.supported) {
+				t.Fatalf("got sentences %+v", report.Sentences)
+			}
+			for i, sentence := range report.Sentences {
+				if sentence.Supported != tt.supported[i] || sentence.Reason != tt.reasons[i] {
+					t.Errorf("sentence %d: supported = %v, reason = %q, want %v, %q",
+						i, sentence.Supported, sentence.Reason, tt.supported[i], tt.reasons[i])
+				}
+			}
+
+			// Sentences citing unknown references are not sent to the model
+			if !strings.Contains(tt.model.prompt, "Claim 2: The Eiffel Tower") ||
+				strings.Contains(tt.model.prompt, "Claim 3") || strings.Contains(tt.model.prompt, "Unknown claim") {
+				t.Errorf("unexpected prompt:\n%s", tt.model.prompt)
+			}
+		})
+	}
+}
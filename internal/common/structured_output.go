@@ -0,0 +1,81 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/models/chat"
+	jsonschema "github.com/google/jsonschema-go/jsonschema"
+)
+
+// StructuredOutputRepairAttempts is how many times the model is asked to fix an answer
+// that does not conform to the output schema
+const StructuredOutputRepairAttempts = 2
+
+// structuredOutputRepairPrompt asks the model to rewrite an answer that failed validation
+const structuredOutputRepairPrompt = `Your previous answer is not valid: %s
+
+Reply again with only a JSON object that conforms to this JSON Schema, without any explanation or code fences:
+%s`
+
+var thinkBlockPattern = regexp.MustCompile(`(?s)<think>.*?</think>`)
+
+// ParseStructuredOutput parses an LLM answer as JSON and validates it against the schema.
+// Thinking blocks and ```json code fences around the object are ignored.
+func ParseStructuredOutput(content string, schema *jsonschema.Resolved) (interface{}, error) {
+	content = strings.TrimSpace(thinkBlockPattern.ReplaceAllString(content, ""))
+	if content == "" {
+		return nil, fmt.Errorf("the answer is empty")
+	}
+	var value interface{}
+	if err := ParseLLMJsonResponse(content, &value); err != nil {
+		return nil, fmt.Errorf("the answer is not JSON: %v", err)
+	}
+	if err := schema.Validate(value); err != nil {
+		return nil, fmt.Errorf("the answer does not conform to the schema: %v", err)
+	}
+	return value, nil
+}
+
+// RepairStructuredOutput validates content and, while it does not conform to the schema,
+// asks the model to answer again with the validation error.
+// messages are the ones that produced content, opts should request the schema through Format.
+// It returns the parsed object and the raw JSON text it was parsed from.
+func RepairStructuredOutput(
+	ctx context.Context,
+	model chat.Chat,
+	messages []chat.Message,
+	opts *chat.ChatOptions,
+	schema *jsonschema.Resolved,
+	content string,
+) (interface{}, string, error) {
+	value, err := ParseStructuredOutput(content, schema)
+	for attempt := 1; err != nil && attempt <= StructuredOutputRepairAttempts; attempt++ {
+		PipelineWarn(ctx, "StructuredOutput", "repair", map[string]interface{}{
+			"attempt": attempt,
+			"error":   err.Error(),
+		})
+		schemaJSON, _ := json.Marshal(schema.Schema())
+		messages = append(messages,
+			chat.Message{Role: "assistant", Content: content},
+			chat.Message{Role: "user", Content: fmt.Sprintf(structuredOutputRepairPrompt, err.Error(), schemaJSON)},
+		)
+		response, chatErr := model.Chat(ctx, messages, opts)
+		if chatErr != nil {
+			return nil, content, fmt.Errorf("repair structured output: %w", chatErr)
+		}
+		content = response.Content
+		value, err = ParseStructuredOutput(content, schema)
+	}
+	if err != nil {
+		logger.Warnf(ctx, "Structured output still invalid after %d repair attempts: %v",
+			StructuredOutputRepairAttempts, err)
+		return nil, content, err
+	}
+	raw, _ := json.Marshal(value)
+	return value, string(raw), nil
+}
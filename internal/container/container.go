@@ -7,6 +7,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"io"
 	"net/url"
 	"os"
 	"slices"
@@ -23,12 +24,14 @@ import (
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/dig"
 	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 
 	"github.com/Tencent/WeKnora/docreader/client"
 	"github.com/Tencent/WeKnora/internal/application/repository"
 	elasticsearchRepoV7 "github.com/Tencent/WeKnora/internal/application/repository/retriever/elasticsearch/v7"
 	elasticsearchRepoV8 "github.com/Tencent/WeKnora/internal/application/repository/retriever/elasticsearch/v8"
+	localRepo "github.com/Tencent/WeKnora/internal/application/repository/retriever/local"
 	neo4jRepo "github.com/Tencent/WeKnora/internal/application/repository/retriever/neo4j"
 	postgresRepo "github.com/Tencent/WeKnora/internal/application/repository/retriever/postgres"
 	qdrantRepo "github.com/Tencent/WeKnora/internal/application/repository/retriever/qdrant"
@@ -110,7 +113,19 @@ func BuildContainer(container *dig.Container) *dig.Container {
 	must(container.Provide(repository.NewAuthTokenRepository))
 	must(container.Provide(neo4jRepo.NewNeo4jRepository))
 	must(container.Provide(repository.NewMCPServiceRepository))
+	must(container.Provide(repository.NewMCPOAuthCredentialRepository))
+	must(container.Provide(repository.NewToolApprovalRepository))
+	must(container.Provide(repository.NewIndexMigrationRepository))
+	must(container.Provide(repository.NewIndexConsistencyRepository))
+	must(container.Provide(repository.NewWebhookRepository))
+	must(container.Provide(repository.NewAgentTaskRepository))
+	must(container.Provide(repository.NewAgentTestRepository))
+	must(container.Provide(repository.NewAgentTraceRepository))
+	must(container.Provide(repository.NewHTTPToolRepository))
+	must(container.Provide(repository.NewAuditLogRepository))
+	must(container.Provide(repository.NewUserMemoryRepository))
 	must(container.Provide(repository.NewCustomAgentRepository))
+	must(container.Provide(repository.NewAgentVersionRepository))
 	must(container.Provide(repository.NewOrganizationRepository))
 	must(container.Provide(repository.NewKBShareRepository))
 	must(container.Provide(repository.NewAgentShareRepository))
@@ -144,6 +159,19 @@ func BuildContainer(container *dig.Container) *dig.Container {
 	must(container.Provide(service.NewMessageService))
 	must(container.Provide(service.NewMCPServiceService))
 	must(container.Provide(service.NewCustomAgentService))
+	must(container.Provide(service.NewToolApprovalService))
+	must(container.Provide(service.NewIndexMigrationService))
+	must(container.Provide(service.NewIndexConsistencyService))
+	must(container.Provide(service.NewWebhookService))
+	must(container.Provide(service.NewAgentTaskService))
+	must(container.Provide(service.NewAgentTestService))
+	must(container.Provide(service.NewAgentVersionService))
+	must(container.Provide(service.NewAgentTraceService))
+	must(container.Provide(service.NewBundleService))
+	must(container.Provide(service.NewHTTPToolService))
+	must(container.Provide(service.NewAuditLogService))
+	must(container.Provide(service.NewUserMemoryService))
+	must(container.Provide(service.NewHealthService))
 
 	// Web search service (needed by AgentService)
 	logger.Debugf(ctx, "[Container] Registering web search registry and providers...")
@@ -161,6 +189,9 @@ func BuildContainer(container *dig.Container) *dig.Container {
 	// SessionService is created after AgentService and passes itself to AgentService.CreateAgentEngine when needed
 	logger.Debugf(ctx, "[Container] Registering session service...")
 	must(container.Provide(service.NewSessionService))
+	must(container.Provide(mcp.NewKnowledgeServer))
+	must(container.Provide(service.NewAgentTaskRunner, dig.Name("agentTaskRunner")))
+	must(container.Provide(service.NewAgentTestRunner, dig.Name("agentTestRunner")))
 
 	logger.Debugf(ctx, "[Container] Registering asynq client and server...")
 	must(container.Provide(router.NewAsyncqClient))
@@ -201,18 +232,31 @@ func BuildContainer(container *dig.Container) *dig.Container {
 	must(container.Provide(handler.NewInitializationHandler))
 	must(container.Provide(handler.NewAuthHandler))
 	must(container.Provide(handler.NewSystemHandler))
+	must(container.Provide(handler.NewHealthHandler))
 	must(container.Provide(handler.NewMCPServiceHandler))
 	must(container.Provide(handler.NewWebSearchHandler))
 	must(container.Provide(handler.NewCustomAgentHandler))
 	must(container.Provide(service.NewSkillService))
 	must(container.Provide(handler.NewSkillHandler))
 	must(container.Provide(handler.NewOrganizationHandler))
+	must(container.Provide(handler.NewIndexMigrationHandler))
+	must(container.Provide(handler.NewIndexConsistencyHandler))
+	must(container.Provide(handler.NewWebhookHandler))
+	must(container.Provide(handler.NewAgentTaskHandler))
+	must(container.Provide(handler.NewAgentTestHandler))
+	must(container.Provide(handler.NewAgentVersionHandler))
+	must(container.Provide(handler.NewAgentTraceHandler))
+	must(container.Provide(handler.NewBundleHandler))
+	must(container.Provide(handler.NewHTTPToolHandler))
+	must(container.Provide(handler.NewAuditLogHandler))
+	must(container.Provide(handler.NewMemoryHandler))
 	logger.Debugf(ctx, "[Container] HTTP handlers registered")
 
 	// Router configuration
 	logger.Debugf(ctx, "[Container] Registering router and starting asynq server...")
 	must(container.Provide(router.NewRouter))
 	must(container.Invoke(router.RunAsynqServer))
+	must(container.Invoke(router.RunAsynqScheduler))
 
 	logger.Infof(ctx, "[Container] Container initialization completed successfully")
 	return container
@@ -240,14 +284,25 @@ func initTracer() (*tracing.Tracer, error) {
 	return tracing.InitTracer()
 }
 
-func initRedisClient() (*redis.Client, error) {
-	db, err := strconv.Atoi(os.Getenv("REDIS_DB"))
+func initRedisClient(cleaner interfaces.ResourceCleaner) (*redis.Client, error) {
+	db := 0
+	if dbStr := os.Getenv("REDIS_DB"); dbStr != "" {
+		parsed, err := strconv.Atoi(dbStr)
+		if err != nil {
+			return nil, err
+		}
+		db = parsed
+	}
+
+	// 轻量模式下未配置 REDIS_ADDR 时使用内嵌 Redis
+	addr, err := database.RedisAddr()
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("获取Redis地址失败: %w", err)
 	}
+	cleaner.RegisterWithName("EmbeddedRedis", database.CloseEmbeddedRedis)
 
 	client := redis.NewClient(&redis.Options{
-		Addr:     os.Getenv("REDIS_ADDR"),
+		Addr:     addr,
 		Username: os.Getenv("REDIS_USERNAME"),
 		Password: os.Getenv("REDIS_PASSWORD"),
 		DB:       db,
@@ -272,7 +327,7 @@ func initContextStorage(redisClient *redis.Client) (llmcontext.ContextStorage, e
 
 // initDatabase initializes database connection
 // Creates and configures database connection based on environment configuration
-// Supports multiple database backends (PostgreSQL)
+// Supports multiple database backends (PostgreSQL, SQLite)
 // Parameters:
 //   - cfg: Application configuration
 //
@@ -326,6 +381,16 @@ func initDatabase(cfg *config.Config) (*gorm.DB, error) {
 			os.Getenv("DB_PORT"),
 			os.Getenv("DB_NAME"),
 		)
+	case "sqlite":
+		// Lite mode: a single database file, no external server required
+		dbPath := database.SQLitePath()
+		gormDSN, err := database.SQLiteDSN(dbPath)
+		if err != nil {
+			return nil, err
+		}
+		dialector = sqlite.Open(gormDSN)
+		migrateDSN = database.SQLiteMigrateDSN(dbPath)
+		logger.Infof(context.Background(), "DB Config: sqlite path=%s", dbPath)
 	default:
 		return nil, fmt.Errorf("unsupported database driver: %s", os.Getenv("DB_DRIVER"))
 	}
@@ -333,6 +398,11 @@ func initDatabase(cfg *config.Config) (*gorm.DB, error) {
 	if err != nil {
 		return nil, err
 	}
+	if db.Dialector.Name() == "sqlite" {
+		if err := database.RegisterSQLiteCallbacks(db); err != nil {
+			return nil, err
+		}
+	}
 
 	// Run database migrations automatically (optional, can be disabled via env var)
 	// To disable auto-migration, set AUTO_MIGRATE=false
@@ -366,6 +436,10 @@ func initDatabase(cfg *config.Config) (*gorm.DB, error) {
 	}
 
 	// Configure connection pool parameters
+	// SQLite allows a single writer, so all queries share one connection
+	if db.Dialector.Name() == "sqlite" {
+		sqlDB.SetMaxOpenConns(1)
+	}
 	sqlDB.SetMaxIdleConns(10)
 	sqlDB.SetConnMaxLifetime(time.Duration(10) * time.Minute)
 
@@ -425,15 +499,18 @@ func initFileService(cfg *config.Config) (interfaces.FileService, error) {
 
 // initRetrieveEngineRegistry initializes the retrieval engine registry
 // Sets up and configures various search engine backends based on configuration
-// Supports multiple retrieval engines (PostgreSQL, ElasticsearchV7, ElasticsearchV8)
+// Supports multiple retrieval engines (PostgreSQL, ElasticsearchV7, ElasticsearchV8, Qdrant, Local)
 // Parameters:
 //   - db: Database connection
 //   - cfg: Application configuration
+//   - cleaner: Resource cleaner, flushes the local index on shutdown
 //
 // Returns:
 //   - Configured retrieval engine registry
 //   - Error if initialization fails
-func initRetrieveEngineRegistry(db *gorm.DB, cfg *config.Config) (interfaces.RetrieveEngineRegistry, error) {
+func initRetrieveEngineRegistry(
+	db *gorm.DB, cfg *config.Config, cleaner interfaces.ResourceCleaner,
+) (interfaces.RetrieveEngineRegistry, error) {
 	registry := retriever.NewRetrieveEngineRegistry()
 	retrieveDriver := strings.Split(os.Getenv("RETRIEVE_DRIVER"), ",")
 	log := logger.GetLogger(context.Background())
@@ -539,6 +616,31 @@ func initRetrieveEngineRegistry(db *gorm.DB, cfg *config.Config) (interfaces.Ret
 			}
 		}
 	}
+
+	if slices.Contains(retrieveDriver, "local") {
+		indexDir := os.Getenv("LOCAL_INDEX_DIR")
+		if indexDir == "" {
+			indexDir = "data/index"
+		}
+		localRepository, err := localRepo.NewLocalRetrieveEngineRepository(indexDir)
+		if err != nil {
+			log.Errorf("Create local retrieve engine failed: %v", err)
+		} else {
+			// Write pending index changes before exit
+			if closer, ok := localRepository.(io.Closer); ok {
+				cleaner.RegisterWithName("LocalRetrieveEngine", closer.Close)
+			}
+			if err := registry.Register(
+				retriever.NewKVHybridRetrieveEngine(
+					localRepository, types.LocalRetrieverEngineType,
+				),
+			); err != nil {
+				log.Errorf("Register local retrieve engine failed: %v", err)
+			} else {
+				log.Infof("Register local retrieve engine success")
+			}
+		}
+	}
 	return registry, nil
 }
 
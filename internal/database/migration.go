@@ -4,10 +4,12 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/Tencent/WeKnora/internal/logger"
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/database/sqlite3"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 )
 
@@ -30,8 +32,7 @@ func RunMigrationsWithOptions(dsn string, opts MigrationOptions) error {
 
 	logger.Infof(ctx, "Starting database migration...")
 
-	// Use versioned migrations directory
-	migrationsPath := "file://migrations/versioned"
+	migrationsPath := migrationsPathForDSN(dsn)
 
 	m, err := migrate.New(migrationsPath, dsn)
 	if err != nil {
@@ -192,6 +193,15 @@ func recoverFromDirtyState(ctx context.Context, m *migrate.Migrate, dirtyVersion
 	return nil
 }
 
+// migrationsPathForDSN returns the migrations directory matching the database of the DSN
+// SQLite uses its own consolidated schema, everything else uses the versioned PostgreSQL migrations
+func migrationsPathForDSN(dsn string) string {
+	if strings.HasPrefix(dsn, "sqlite3://") {
+		return "file://migrations/sqlite"
+	}
+	return "file://migrations/versioned"
+}
+
 // GetMigrationVersion returns the current migration version
 func GetMigrationVersion() (uint, bool, error) {
 	dbURL := fmt.Sprintf(
@@ -202,10 +212,11 @@ func GetMigrationVersion() (uint, bool, error) {
 		os.Getenv("DB_PORT"),
 		os.Getenv("DB_NAME"),
 	)
+	if os.Getenv("DB_DRIVER") == "sqlite" {
+		dbURL = SQLiteMigrateDSN(SQLitePath())
+	}
 
-	migrationsPath := "file://migrations/versioned"
-
-	m, err := migrate.New(migrationsPath, dbURL)
+	m, err := migrate.New(migrationsPathForDSN(dbURL), dbURL)
 	if err != nil {
 		return 0, false, fmt.Errorf("failed to create migrate instance: %w", err)
 	}
@@ -0,0 +1,53 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/alicebob/miniredis/v2"
+)
+
+var (
+	embeddedRedis     *miniredis.Miniredis
+	embeddedRedisErr  error
+	embeddedRedisOnce sync.Once
+)
+
+// ErrRedisAddrNotSet is returned when REDIS_ADDR is empty outside of lite mode
+var ErrRedisAddrNotSet = errors.New("REDIS_ADDR is not set")
+
+// RedisAddr returns the Redis address from REDIS_ADDR.
+// In lite mode (DB_DRIVER=sqlite) an empty REDIS_ADDR starts an in-process Redis server once and returns
+// its address, so that the task queue, context storage and stream manager work without an external Redis.
+// The embedded server keeps everything in memory, so other deployments must set REDIS_ADDR.
+func RedisAddr() (string, error) {
+	if addr := os.Getenv("REDIS_ADDR"); addr != "" {
+		return addr, nil
+	}
+	if os.Getenv("DB_DRIVER") != "sqlite" {
+		return "", ErrRedisAddrNotSet
+	}
+	embeddedRedisOnce.Do(func() {
+		embeddedRedis, embeddedRedisErr = miniredis.Run()
+		if embeddedRedisErr == nil {
+			logger.GetLogger(context.Background()).Infof(
+				"REDIS_ADDR is not set, using embedded redis at %s", embeddedRedis.Addr(),
+			)
+		}
+	})
+	if embeddedRedisErr != nil {
+		return "", embeddedRedisErr
+	}
+	return embeddedRedis.Addr(), nil
+}
+
+// CloseEmbeddedRedis stops the in-process Redis server, if it was started
+func CloseEmbeddedRedis() error {
+	if embeddedRedis != nil {
+		embeddedRedis.Close()
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// DefaultSQLitePath is the database file used when DB_PATH is not set
+const DefaultSQLitePath = "data/weknora.db"
+
+// sqliteSeqStarts holds the first seq_id of each table that has one, matching the PostgreSQL sequences
+var sqliteSeqStarts = map[string]int64{
+	"chunks":         100000000,
+	"knowledge_tags": 10000000,
+}
+
+// SQLitePath returns the SQLite database file path from DB_PATH
+func SQLitePath() string {
+	if path := os.Getenv("DB_PATH"); path != "" {
+		return path
+	}
+	return DefaultSQLitePath
+}
+
+// SQLiteDSN returns the DSN used by GORM to open the SQLite database file.
+// The parent directory is created if it does not exist.
+func SQLiteDSN(path string) (string, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return "", fmt.Errorf("failed to create sqlite directory %s: %w", dir, err)
+		}
+	}
+	return fmt.Sprintf("file:%s?_foreign_keys=1&_busy_timeout=5000&_journal_mode=WAL", path), nil
+}
+
+// SQLiteMigrateDSN returns the DSN used by golang-migrate for the SQLite database file
+func SQLiteMigrateDSN(path string) string {
+	return "sqlite3://" + path
+}
+
+// RegisterSQLiteCallbacks registers the GORM callbacks that emulate PostgreSQL defaults on SQLite.
+// SQLite has no sequences, so seq_id values are assigned before insert from an in-process counter
+// seeded with the current maximum. The connection pool must be limited to a single connection.
+func RegisterSQLiteCallbacks(db *gorm.DB) error {
+	allocator := &seqAllocator{next: make(map[string]int64)}
+	return db.Callback().Create().Before("gorm:create").Register("weknora:sqlite_seq_id", allocator.assign)
+}
+
+// seqAllocator hands out seq_id values per table
+type seqAllocator struct {
+	mu   sync.Mutex
+	next map[string]int64
+}
+
+// assign sets seq_id on every record of the statement that does not have one yet
+func (a *seqAllocator) assign(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Schema == nil {
+		return
+	}
+	table := db.Statement.Schema.Table
+	start, ok := sqliteSeqStarts[table]
+	if !ok {
+		return
+	}
+	field := db.Statement.Schema.LookUpField("seq_id")
+	if field == nil {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, ok := a.next[table]; !ok {
+		// NewDB keeps the statement's connection, so this also works inside the create transaction
+		var maxSeq sql.NullInt64
+		if err := db.Session(&gorm.Session{NewDB: true}).
+			Table(table).Select("MAX(seq_id)").Scan(&maxSeq).Error; err != nil {
+			db.AddError(err)
+			return
+		}
+		a.next[table] = max(start, maxSeq.Int64+1)
+	}
+
+	ctx := db.Statement.Context
+	setSeq := func(record reflect.Value) {
+		if _, zero := field.ValueOf(ctx, record); !zero {
+			return
+		}
+		if err := field.Set(ctx, record, a.next[table]); err != nil {
+			db.AddError(err)
+			return
+		}
+		a.next[table]++
+	}
+
+	switch rv := reflect.Indirect(db.Statement.ReflectValue); rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			setSeq(reflect.Indirect(rv.Index(i)))
+		}
+	case reflect.Struct:
+		setSeq(rv)
+	}
+}
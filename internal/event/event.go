@@ -53,6 +53,15 @@ const (
 	EventAgentReflection  EventType = "reflection"   // Agent 反思
 	EventAgentReferences  EventType = "references"   // 知识引用
 	EventAgentFinalAnswer EventType = "final_answer" // 最终答案
+	// 工具审批事件（人工确认敏感工具调用）
+	EventAgentToolApproval         EventType = "tool_approval"          // 等待用户审批工具调用
+	EventAgentToolApprovalResolved EventType = "tool_approval_resolved" // 工具调用审批结果
+	// 子智能体事件（委派给其他智能体的嵌套运行）
+	EventAgentSubAgentAnswer EventType = "sub_agent_answer" // 子智能体的最终答案
+	// 工作流事件（工作流模式的节点执行）
+	EventWorkflowNode EventType = "workflow_node" // 工作流节点开始或结束
+	// 结构化输出事件（按 JSON Schema 约束的最终答案）
+	EventStructuredOutput EventType = "structured_output" // 校验后的结构化答案
 
 	// Error events
 	EventError EventType = "error" // 错误事件
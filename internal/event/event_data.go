@@ -1,5 +1,11 @@
 package event
 
+import (
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
 // EventData contains common event data structures for different stages
 
 // QueryData represents query-related event data
@@ -143,6 +149,8 @@ type AgentCompleteData struct {
 	MessageID       string                 `json:"message_id,omitempty"` // Assistant message ID
 	RequestID       string                 `json:"request_id,omitempty"`
 	Extra           map[string]interface{} `json:"extra,omitempty"`
+	// Citations of the final answer mapped to the knowledge references, nil when nothing could be cited
+	Citations *types.CitationReport `json:"citations,omitempty"`
 }
 
 // === Streaming Event Data Structures ===
@@ -175,16 +183,70 @@ type AgentToolResultData struct {
 	Data       map[string]interface{} `json:"data,omitempty"` // Structured data from tool result (e.g., display_type, formatted results)
 }
 
+// AgentToolApprovalData represents a tool call waiting for (or resolved by) user approval
+type AgentToolApprovalData struct {
+	ApprovalID    string         `json:"approval_id"`
+	ToolCallID    string         `json:"tool_call_id"` // Tool call ID for tracking
+	ToolName      string         `json:"tool_name"`
+	Arguments     map[string]any `json:"arguments,omitempty"`
+	Status        string         `json:"status"` // pending, approved, denied, expired
+	Reason        string         `json:"reason,omitempty"`
+	TimeoutAction string         `json:"timeout_action"`
+	ExpiresAt     time.Time      `json:"expires_at"`
+	Iteration     int            `json:"iteration"`
+}
+
 // AgentReferencesData represents knowledge references data
 type AgentReferencesData struct {
 	References interface{} `json:"references"` // []*types.SearchResult
 	Iteration  int         `json:"iteration"`
 }
 
+// Metadata keys of the events forwarded from a delegated sub-agent run
+const (
+	MetadataParentToolCallID = "parent_tool_call_id" // Tool call of the delegating agent
+	MetadataSubAgentID       = "sub_agent_id"        // Agent running the nested run
+	MetadataSubAgentName     = "sub_agent_name"      // Name of the agent running the nested run
+	MetadataSubAgentDepth    = "sub_agent_depth"     // Nesting depth, 1 for a sub-agent of the top-level agent
+)
+
+// Workflow node statuses
+const (
+	WorkflowNodeRunning   = "running"
+	WorkflowNodeCompleted = "completed"
+	WorkflowNodeSkipped   = "skipped"
+	WorkflowNodeFailed    = "failed"
+)
+
+// WorkflowNodeData represents the start or outcome of a workflow node.
+// Both events of a node run share the event ID.
+type WorkflowNodeData struct {
+	NodeID   string                 `json:"node_id"`
+	NodeType string                 `json:"node_type"`
+	NodeName string                 `json:"node_name"`
+	Status   string                 `json:"status"`           // running, completed, skipped, failed
+	Output   string                 `json:"output,omitempty"` // Output of the node, truncated
+	Branch   string                 `json:"branch,omitempty"` // Branch chosen by a condition node
+	Error    string                 `json:"error,omitempty"`
+	Duration int64                  `json:"duration_ms,omitempty"`
+	Step     int                    `json:"step"` // Position of the node in the run order
+	Data     map[string]interface{} `json:"data,omitempty"`
+}
+
+// StructuredOutputData represents the final answer parsed against the requested output schema.
+// Output is nil and Error is set when the answer still does not conform after the repair attempts.
+type StructuredOutputData struct {
+	Output interface{} `json:"output"`
+	Raw    string      `json:"raw"` // JSON text of the output, or the last invalid answer
+	Error  string      `json:"error,omitempty"`
+}
+
 // AgentFinalAnswerData represents final answer streaming data
 type AgentFinalAnswerData struct {
 	Content string `json:"content"`
 	Done    bool   `json:"done"`
+	// Citations of the whole answer, set on the done event of a knowledge QA answer
+	Citations *types.CitationReport `json:"citations,omitempty"`
 }
 
 // AgentReflectionData represents agent reflection data
@@ -0,0 +1,378 @@
+package handler
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"github.com/Tencent/WeKnora/internal/application/service"
+	"github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	secutils "github.com/Tencent/WeKnora/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// AgentTaskHandler handles HTTP requests for scheduled and event-triggered agent tasks
+type AgentTaskHandler struct {
+	service interfaces.AgentTaskService
+}
+
+// NewAgentTaskHandler creates a new agent task handler
+func NewAgentTaskHandler(service interfaces.AgentTaskService) *AgentTaskHandler {
+	return &AgentTaskHandler{service: service}
+}
+
+// CreateAgentTaskRequest is the request body of CreateAgentTask
+type CreateAgentTaskRequest struct {
+	Name        string `json:"name"        binding:"required"`
+	Description string `json:"description"`
+	AgentID     string `json:"agent_id"    binding:"required"`
+	// schedule, knowledge.parse_completed or faq.import_finished
+	Trigger string `json:"trigger" binding:"required"`
+	// Cron spec and time zone of schedule tasks
+	Schedule         string   `json:"schedule"`
+	Timezone         string   `json:"timezone"`
+	KnowledgeBaseIDs []string `json:"knowledge_base_ids"`
+	Prompt           string   `json:"prompt"             binding:"required"`
+	// Webhook receiving the result of every run
+	WebhookID string `json:"webhook_id"`
+	Enabled   *bool  `json:"enabled"`
+}
+
+// UpdateAgentTaskRequest is the request body of UpdateAgentTask, omitted fields are left unchanged
+type UpdateAgentTaskRequest struct {
+	Name             *string   `json:"name"`
+	Description      *string   `json:"description"`
+	AgentID          *string   `json:"agent_id"`
+	Trigger          *string   `json:"trigger"`
+	Schedule         *string   `json:"schedule"`
+	Timezone         *string   `json:"timezone"`
+	KnowledgeBaseIDs *[]string `json:"knowledge_base_ids"`
+	Prompt           *string   `json:"prompt"`
+	WebhookID        *string   `json:"webhook_id"`
+	Enabled          *bool     `json:"enabled"`
+}
+
+// RunAgentTaskRequest is the request body of RunAgentTask
+type RunAgentTaskRequest struct {
+	// Event fields available to the prompt template, e.g. to try a knowledge-triggered task
+	Data map[string]interface{} `json:"data"`
+}
+
+// CreateAgentTask godoc
+// @Summary      创建智能体任务
+// @Description  创建按 cron 定时执行，或在知识解析完成、FAQ 导入完成时触发的智能体任务。每次运行以渲染后的提示词在新会话中调用智能体，结果记录在运行历史中并推送到 Webhook
+// @Tags         智能体任务
+// @Accept       json
+// @Produce      json
+// @Param        request  body      CreateAgentTaskRequest  true  "任务配置"
+// @Success      200      {object}  map[string]interface{}  "创建的任务"
+// @Failure      400      {object}  errors.AppError         "配置无效"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /agent-tasks [post]
+func (h *AgentTaskHandler) CreateAgentTask(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req CreateAgentTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error(ctx, "Failed to parse request parameters", err)
+		c.Error(errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	task := &types.AgentTask{
+		Name:             req.Name,
+		Description:      req.Description,
+		AgentID:          req.AgentID,
+		Trigger:          req.Trigger,
+		Schedule:         req.Schedule,
+		Timezone:         req.Timezone,
+		KnowledgeBaseIDs: req.KnowledgeBaseIDs,
+		Prompt:           req.Prompt,
+		WebhookID:        req.WebhookID,
+		Enabled:          req.Enabled == nil || *req.Enabled,
+	}
+	task, err := h.service.CreateTask(ctx, task)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		h.handleError(c, err, "Failed to create agent task: ")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    task,
+	})
+}
+
+// ListAgentTasks godoc
+// @Summary      获取智能体任务列表
+// @Description  获取当前租户的所有智能体任务，包含下次运行时间和最近一次运行状态
+// @Tags         智能体任务
+// @Produce      json
+// @Success      200  {object}  map[string]interface{}  "任务列表"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /agent-tasks [get]
+func (h *AgentTaskHandler) ListAgentTasks(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	tasks, err := h.service.ListTasks(ctx)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(errors.NewInternalServerError("Failed to list agent tasks: " + err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    tasks,
+	})
+}
+
+// GetAgentTask godoc
+// @Summary      获取智能体任务详情
+// @Tags         智能体任务
+// @Produce      json
+// @Param        id   path      string  true  "任务 ID"
+// @Success      200  {object}  map[string]interface{}  "任务"
+// @Failure      404  {object}  errors.AppError         "任务不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /agent-tasks/{id} [get]
+func (h *AgentTaskHandler) GetAgentTask(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := secutils.SanitizeForLog(c.Param("id"))
+
+	task, err := h.service.GetTask(ctx, id)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"agent_task_id": id})
+		h.handleError(c, err, "Failed to get agent task: ")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    task,
+	})
+}
+
+// UpdateAgentTask godoc
+// @Summary      更新智能体任务
+// @Description  更新智能体任务，未传的字段保持不变。定时任务的下次运行时间按新的 cron 表达式重新计算
+// @Tags         智能体任务
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                  true  "任务 ID"
+// @Param        request  body      UpdateAgentTaskRequest  true  "更新内容"
+// @Success      200      {object}  map[string]interface{}  "更新后的任务"
+// @Failure      400      {object}  errors.AppError         "配置无效"
+// @Failure      404      {object}  errors.AppError         "任务不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /agent-tasks/{id} [put]
+func (h *AgentTaskHandler) UpdateAgentTask(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := secutils.SanitizeForLog(c.Param("id"))
+
+	var req UpdateAgentTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error(ctx, "Failed to parse request parameters", err)
+		c.Error(errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	task, err := h.service.GetTask(ctx, id)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"agent_task_id": id})
+		h.handleError(c, err, "Failed to get agent task: ")
+		return
+	}
+	if req.Name != nil {
+		task.Name = *req.Name
+	}
+	if req.Description != nil {
+		task.Description = *req.Description
+	}
+	if req.AgentID != nil {
+		task.AgentID = *req.AgentID
+	}
+	if req.Trigger != nil {
+		task.Trigger = *req.Trigger
+	}
+	if req.Schedule != nil {
+		task.Schedule = *req.Schedule
+	}
+	if req.Timezone != nil {
+		task.Timezone = *req.Timezone
+	}
+	if req.KnowledgeBaseIDs != nil {
+		task.KnowledgeBaseIDs = *req.KnowledgeBaseIDs
+	}
+	if req.Prompt != nil {
+		task.Prompt = *req.Prompt
+	}
+	if req.WebhookID != nil {
+		task.WebhookID = *req.WebhookID
+	}
+	if req.Enabled != nil {
+		task.Enabled = *req.Enabled
+	}
+
+	task, err = h.service.UpdateTask(ctx, task)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"agent_task_id": id})
+		h.handleError(c, err, "Failed to update agent task: ")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    task,
+	})
+}
+
+// DeleteAgentTask godoc
+// @Summary      删除智能体任务
+// @Description  删除智能体任务及其运行历史，运行产生的会话保留
+// @Tags         智能体任务
+// @Produce      json
+// @Param        id   path      string  true  "任务 ID"
+// @Success      200  {object}  map[string]interface{}  "删除成功"
+// @Failure      404  {object}  errors.AppError         "任务不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /agent-tasks/{id} [delete]
+func (h *AgentTaskHandler) DeleteAgentTask(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := secutils.SanitizeForLog(c.Param("id"))
+
+	if err := h.service.DeleteTask(ctx, id); err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"agent_task_id": id})
+		h.handleError(c, err, "Failed to delete agent task: ")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
+
+// RunAgentTask godoc
+// @Summary      立即运行智能体任务
+// @Description  立即异步运行一次任务（无论是否启用），返回待执行的运行记录。data 中的字段可在提示词模板中引用，便于调试事件触发的任务
+// @Tags         智能体任务
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                  true   "任务 ID"
+// @Param        request  body      RunAgentTaskRequest     false  "事件字段"
+// @Success      200      {object}  map[string]interface{}  "运行记录"
+// @Failure      404      {object}  errors.AppError         "任务不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /agent-tasks/{id}/run [post]
+func (h *AgentTaskHandler) RunAgentTask(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := secutils.SanitizeForLog(c.Param("id"))
+
+	var req RunAgentTaskRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			logger.Error(ctx, "Failed to parse request parameters", err)
+			c.Error(errors.NewBadRequestError(err.Error()))
+			return
+		}
+	}
+
+	run, err := h.service.RunTask(ctx, id, req.Data)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"agent_task_id": id})
+		h.handleError(c, err, "Failed to run agent task: ")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    run,
+	})
+}
+
+// ListAgentTaskRuns godoc
+// @Summary      获取智能体任务运行历史
+// @Description  分页获取任务的运行记录，按创建时间倒序，包含触发事件、提示词、会话、输出和错误信息
+// @Tags         智能体任务
+// @Produce      json
+// @Param        id         path      string  true   "任务 ID"
+// @Param        page       query     int     false  "页码"
+// @Param        page_size  query     int     false  "每页数量"
+// @Success      200        {object}  map[string]interface{}  "运行记录列表"
+// @Failure      404        {object}  errors.AppError         "任务不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /agent-tasks/{id}/runs [get]
+func (h *AgentTaskHandler) ListAgentTaskRuns(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := secutils.SanitizeForLog(c.Param("id"))
+
+	var page types.Pagination
+	if err := c.ShouldBindQuery(&page); err != nil {
+		logger.Error(ctx, "Failed to parse pagination parameters", err)
+		c.Error(errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	result, err := h.service.ListRuns(ctx, id, &page)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"agent_task_id": id})
+		h.handleError(c, err, "Failed to list agent task runs: ")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+	})
+}
+
+// GetAgentTaskRun godoc
+// @Summary      获取智能体任务运行详情
+// @Tags         智能体任务
+// @Produce      json
+// @Param        id      path      string  true  "任务 ID"
+// @Param        run_id  path      string  true  "运行 ID"
+// @Success      200     {object}  map[string]interface{}  "运行记录"
+// @Failure      404     {object}  errors.AppError         "任务或运行记录不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /agent-tasks/{id}/runs/{run_id} [get]
+func (h *AgentTaskHandler) GetAgentTaskRun(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := secutils.SanitizeForLog(c.Param("id"))
+	runID := secutils.SanitizeForLog(c.Param("run_id"))
+
+	run, err := h.service.GetRun(ctx, id, runID)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"agent_task_id": id, "run_id": runID})
+		h.handleError(c, err, "Failed to get agent task run: ")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    run,
+	})
+}
+
+// handleError maps agent task service errors to HTTP errors
+func (h *AgentTaskHandler) handleError(c *gin.Context, err error, message string) {
+	switch {
+	case stderrors.Is(err, service.ErrAgentTaskNotFound), stderrors.Is(err, service.ErrAgentTaskRunNotFound):
+		c.Error(errors.NewNotFoundError(err.Error()))
+	case stderrors.Is(err, service.ErrAgentTaskInvalid):
+		c.Error(errors.NewBadRequestError(err.Error()))
+	default:
+		c.Error(errors.NewInternalServerError(message + err.Error()))
+	}
+}
@@ -0,0 +1,343 @@
+package handler
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"github.com/Tencent/WeKnora/internal/application/service"
+	"github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	secutils "github.com/Tencent/WeKnora/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// AgentTestHandler handles HTTP requests for the regression test cases and test runs of custom agents
+type AgentTestHandler struct {
+	service interfaces.AgentTestService
+}
+
+// NewAgentTestHandler creates a new agent test handler
+func NewAgentTestHandler(service interfaces.AgentTestService) *AgentTestHandler {
+	return &AgentTestHandler{service: service}
+}
+
+// AgentTestCaseRequest is the request body of CreateAgentTestCase and UpdateAgentTestCase
+type AgentTestCaseRequest struct {
+	Name  string `json:"name"  binding:"required"`
+	Query string `json:"query" binding:"required"`
+	// Knowledge bases and knowledge mentioned with the query
+	KnowledgeBaseIDs []string `json:"knowledge_base_ids"`
+	KnowledgeIDs     []string `json:"knowledge_ids"`
+	// tool_called, knowledge_cited, answer_contains, answer_matches or llm_judge assertions
+	Assertions []types.AgentTestAssertion `json:"assertions"`
+	// The agent must answer within this many rounds, unchecked when 0
+	MaxRounds int `json:"max_rounds"`
+}
+
+// testCase returns the test case of the request
+func (r *AgentTestCaseRequest) testCase(agentID string) *types.AgentTestCase {
+	return &types.AgentTestCase{
+		AgentID:          agentID,
+		Name:             r.Name,
+		Query:            r.Query,
+		KnowledgeBaseIDs: r.KnowledgeBaseIDs,
+		KnowledgeIDs:     r.KnowledgeIDs,
+		Assertions:       r.Assertions,
+		MaxRounds:        r.MaxRounds,
+	}
+}
+
+// CreateAgentTestCase godoc
+// @Summary      创建智能体测试用例
+// @Description  为智能体创建回归测试用例：提问、@提及的知识库/文档、断言（必须调用的工具、必须引用的知识、回答包含的文本、回答匹配的正则、由模型判定的标准）和最大轮次
+// @Tags         智能体测试
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                  true  "智能体 ID"
+// @Param        request  body      AgentTestCaseRequest    true  "测试用例"
+// @Success      200      {object}  map[string]interface{}  "创建的测试用例"
+// @Failure      400      {object}  errors.AppError         "测试用例无效"
+// @Failure      404      {object}  errors.AppError         "智能体不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /agents/{id}/test-cases [post]
+func (h *AgentTestHandler) CreateAgentTestCase(c *gin.Context) {
+	ctx := c.Request.Context()
+	agentID := secutils.SanitizeForLog(c.Param("id"))
+
+	var req AgentTestCaseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error(ctx, "Failed to parse request parameters", err)
+		c.Error(errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	testCase, err := h.service.CreateCase(ctx, req.testCase(agentID))
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"agent_id": agentID})
+		h.handleError(c, err, "Failed to create agent test case: ")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    testCase,
+	})
+}
+
+// ListAgentTestCases godoc
+// @Summary      获取智能体测试用例列表
+// @Description  获取智能体的所有测试用例，按创建时间排序
+// @Tags         智能体测试
+// @Produce      json
+// @Param        id   path      string  true  "智能体 ID"
+// @Success      200  {object}  map[string]interface{}  "测试用例列表"
+// @Failure      404  {object}  errors.AppError         "智能体不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /agents/{id}/test-cases [get]
+func (h *AgentTestHandler) ListAgentTestCases(c *gin.Context) {
+	ctx := c.Request.Context()
+	agentID := secutils.SanitizeForLog(c.Param("id"))
+
+	testCases, err := h.service.ListCases(ctx, agentID)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"agent_id": agentID})
+		h.handleError(c, err, "Failed to list agent test cases: ")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    testCases,
+	})
+}
+
+// UpdateAgentTestCase godoc
+// @Summary      更新智能体测试用例
+// @Description  整体替换测试用例的提问、知识范围、断言和最大轮次
+// @Tags         智能体测试
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                  true  "智能体 ID"
+// @Param        case_id  path      string                  true  "测试用例 ID"
+// @Param        request  body      AgentTestCaseRequest    true  "测试用例"
+// @Success      200      {object}  map[string]interface{}  "更新后的测试用例"
+// @Failure      400      {object}  errors.AppError         "测试用例无效"
+// @Failure      404      {object}  errors.AppError         "测试用例不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /agents/{id}/test-cases/{case_id} [put]
+func (h *AgentTestHandler) UpdateAgentTestCase(c *gin.Context) {
+	ctx := c.Request.Context()
+	agentID := secutils.SanitizeForLog(c.Param("id"))
+	caseID := secutils.SanitizeForLog(c.Param("case_id"))
+
+	var req AgentTestCaseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error(ctx, "Failed to parse request parameters", err)
+		c.Error(errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	testCase := req.testCase(agentID)
+	testCase.ID = caseID
+	testCase, err := h.service.UpdateCase(ctx, testCase)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"agent_id": agentID, "case_id": caseID})
+		h.handleError(c, err, "Failed to update agent test case: ")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    testCase,
+	})
+}
+
+// DeleteAgentTestCase godoc
+// @Summary      删除智能体测试用例
+// @Description  删除测试用例，历史测试运行中该用例的结果保留
+// @Tags         智能体测试
+// @Produce      json
+// @Param        id       path      string  true  "智能体 ID"
+// @Param        case_id  path      string  true  "测试用例 ID"
+// @Success      200      {object}  map[string]interface{}  "删除成功"
+// @Failure      404      {object}  errors.AppError         "测试用例不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /agents/{id}/test-cases/{case_id} [delete]
+func (h *AgentTestHandler) DeleteAgentTestCase(c *gin.Context) {
+	ctx := c.Request.Context()
+	agentID := secutils.SanitizeForLog(c.Param("id"))
+	caseID := secutils.SanitizeForLog(c.Param("case_id"))
+
+	if err := h.service.DeleteCase(ctx, agentID, caseID); err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"agent_id": agentID, "case_id": caseID})
+		h.handleError(c, err, "Failed to delete agent test case: ")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
+
+// StartAgentTestRun godoc
+// @Summary      运行智能体测试
+// @Description  使用智能体当前配置异步运行测试用例（默认全部），返回待执行的测试运行。每个用例在测试运行的会话中以无历史上下文的方式执行，运行轨迹可通过助手消息导出
+// @Tags         智能体测试
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                     true   "智能体 ID"
+// @Param        request  body      types.AgentTestRunRequest  false  "运行参数"
+// @Success      200      {object}  map[string]interface{}     "测试运行"
+// @Failure      400      {object}  errors.AppError            "智能体不支持测试、没有测试用例或参数无效"
+// @Failure      404      {object}  errors.AppError            "智能体不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /agents/{id}/test-runs [post]
+func (h *AgentTestHandler) StartAgentTestRun(c *gin.Context) {
+	ctx := c.Request.Context()
+	agentID := secutils.SanitizeForLog(c.Param("id"))
+
+	var req types.AgentTestRunRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			logger.Error(ctx, "Failed to parse request parameters", err)
+			c.Error(errors.NewBadRequestError(err.Error()))
+			return
+		}
+	}
+
+	run, err := h.service.StartRun(ctx, agentID, &req)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"agent_id": agentID})
+		h.handleError(c, err, "Failed to start agent test run: ")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    run,
+	})
+}
+
+// ListAgentTestRuns godoc
+// @Summary      获取智能体测试运行列表
+// @Description  分页获取智能体的测试运行，按创建时间倒序，包含通过/失败数量和配置哈希，不包含各用例的结果
+// @Tags         智能体测试
+// @Produce      json
+// @Param        id         path      string  true   "智能体 ID"
+// @Param        page       query     int     false  "页码"
+// @Param        page_size  query     int     false  "每页数量"
+// @Success      200        {object}  map[string]interface{}  "测试运行列表"
+// @Failure      404        {object}  errors.AppError         "智能体不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /agents/{id}/test-runs [get]
+func (h *AgentTestHandler) ListAgentTestRuns(c *gin.Context) {
+	ctx := c.Request.Context()
+	agentID := secutils.SanitizeForLog(c.Param("id"))
+
+	var page types.Pagination
+	if err := c.ShouldBindQuery(&page); err != nil {
+		logger.Error(ctx, "Failed to parse pagination parameters", err)
+		c.Error(errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	result, err := h.service.ListRuns(ctx, agentID, &page)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"agent_id": agentID})
+		h.handleError(c, err, "Failed to list agent test runs: ")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+	})
+}
+
+// GetAgentTestRun godoc
+// @Summary      获取智能体测试运行报告
+// @Description  获取测试运行的状态、运行时的智能体配置和各用例的结果：回答、调用的工具、引用的知识、轮次、每条断言的结果
+// @Tags         智能体测试
+// @Produce      json
+// @Param        id      path      string  true  "智能体 ID"
+// @Param        run_id  path      string  true  "测试运行 ID"
+// @Success      200     {object}  map[string]interface{}  "测试运行"
+// @Failure      404     {object}  errors.AppError         "测试运行不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /agents/{id}/test-runs/{run_id} [get]
+func (h *AgentTestHandler) GetAgentTestRun(c *gin.Context) {
+	ctx := c.Request.Context()
+	agentID := secutils.SanitizeForLog(c.Param("id"))
+	runID := secutils.SanitizeForLog(c.Param("run_id"))
+
+	run, err := h.service.GetRun(ctx, agentID, runID)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"agent_id": agentID, "run_id": runID})
+		h.handleError(c, err, "Failed to get agent test run: ")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    run,
+	})
+}
+
+// CompareAgentTestRuns godoc
+// @Summary      对比智能体测试运行
+// @Description  逐个用例对比两次测试运行的结果，标记修复（fixed）和回退（regressed）的用例，用于比较智能体修改前后的表现
+// @Tags         智能体测试
+// @Produce      json
+// @Param        id      path      string  true  "智能体 ID"
+// @Param        base    query     string  true  "基准测试运行 ID"
+// @Param        target  query     string  true  "对比测试运行 ID"
+// @Success      200     {object}  map[string]interface{}  "对比结果"
+// @Failure      404     {object}  errors.AppError         "测试运行不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /agents/{id}/test-runs/compare [get]
+func (h *AgentTestHandler) CompareAgentTestRuns(c *gin.Context) {
+	ctx := c.Request.Context()
+	agentID := secutils.SanitizeForLog(c.Param("id"))
+	baseRunID := secutils.SanitizeForLog(c.Query("base"))
+	targetRunID := secutils.SanitizeForLog(c.Query("target"))
+	if baseRunID == "" || targetRunID == "" {
+		c.Error(errors.NewBadRequestError("base and target are required"))
+		return
+	}
+
+	comparison, err := h.service.CompareRuns(ctx, agentID, baseRunID, targetRunID)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"agent_id": agentID})
+		h.handleError(c, err, "Failed to compare agent test runs: ")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    comparison,
+	})
+}
+
+// handleError maps agent test service errors to HTTP errors
+func (h *AgentTestHandler) handleError(c *gin.Context, err error, message string) {
+	switch {
+	case stderrors.Is(err, service.ErrAgentNotFound),
+		stderrors.Is(err, service.ErrAgentTestCaseNotFound),
+		stderrors.Is(err, service.ErrAgentTestRunNotFound):
+		c.Error(errors.NewNotFoundError(err.Error()))
+	case stderrors.Is(err, service.ErrAgentTestInvalid):
+		c.Error(errors.NewBadRequestError(err.Error()))
+	default:
+		c.Error(errors.NewInternalServerError(message + err.Error()))
+	}
+}
@@ -0,0 +1,115 @@
+package handler
+
+import (
+	stderrors "errors"
+	"fmt"
+	"net/http"
+
+	"github.com/Tencent/WeKnora/internal/application/service"
+	"github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	secutils "github.com/Tencent/WeKnora/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// AgentTraceHandler handles HTTP requests for exporting and replaying agent run traces
+type AgentTraceHandler struct {
+	service interfaces.AgentTraceService
+}
+
+// NewAgentTraceHandler creates a new agent trace handler
+func NewAgentTraceHandler(service interfaces.AgentTraceService) *AgentTraceHandler {
+	return &AgentTraceHandler{service: service}
+}
+
+// GetAgentTrace godoc
+// @Summary      导出智能体运行轨迹
+// @Description  导出助手消息对应的智能体运行轨迹：系统提示词、工具定义、每轮发送给模型的完整消息和请求参数、模型回复、工具调用的参数和结果、耗时及 token 用量。子智能体的运行轨迹在 sub_traces 中返回。download=true 时以 JSON 文件下载
+// @Tags         消息
+// @Produce      json
+// @Param        session_id  path      string  true   "会话ID"
+// @Param        id          path      string  true   "助手消息ID"
+// @Param        download    query     bool    false  "以文件形式下载"
+// @Success      200         {object}  map[string]interface{}  "运行轨迹"
+// @Failure      404         {object}  errors.AppError         "没有该消息的运行轨迹"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /messages/{session_id}/{id}/trace [get]
+func (h *AgentTraceHandler) GetAgentTrace(c *gin.Context) {
+	ctx := c.Request.Context()
+	sessionID := secutils.SanitizeForLog(c.Param("session_id"))
+	messageID := secutils.SanitizeForLog(c.Param("id"))
+
+	trace, err := h.service.GetTrace(ctx, sessionID, messageID)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"session_id": sessionID, "message_id": messageID})
+		h.handleError(c, err, "Failed to get agent trace: ")
+		return
+	}
+
+	if c.Query("download") == "true" {
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=agent_trace_%s.json", trace.MessageID))
+		c.IndentedJSON(http.StatusOK, trace)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    trace,
+	})
+}
+
+// ReplayAgentTrace godoc
+// @Summary      重放智能体运行
+// @Description  从记录的第一次模型请求开始，使用指定模型重新执行智能体运行。工具调用不会真正执行，而是返回记录的结果，可替换系统提示词和温度，用于确定性地调试提示词修改。返回重放的运行轨迹，重放结果不保存
+// @Tags         消息
+// @Accept       json
+// @Produce      json
+// @Param        session_id  path      string                         true   "会话ID"
+// @Param        id          path      string                         true   "助手消息ID"
+// @Param        request     body      types.AgentTraceReplayRequest  false  "重放参数"
+// @Success      200         {object}  map[string]interface{}         "重放的运行轨迹"
+// @Failure      400         {object}  errors.AppError                "模型不存在或轨迹无法重放"
+// @Failure      404         {object}  errors.AppError                "没有该消息的运行轨迹"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /messages/{session_id}/{id}/trace/replay [post]
+func (h *AgentTraceHandler) ReplayAgentTrace(c *gin.Context) {
+	ctx := c.Request.Context()
+	sessionID := secutils.SanitizeForLog(c.Param("session_id"))
+	messageID := secutils.SanitizeForLog(c.Param("id"))
+
+	var req types.AgentTraceReplayRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			logger.Error(ctx, "Failed to parse request parameters", err)
+			c.Error(errors.NewBadRequestError(err.Error()))
+			return
+		}
+	}
+
+	trace, err := h.service.ReplayTrace(ctx, sessionID, messageID, &req)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"session_id": sessionID, "message_id": messageID})
+		h.handleError(c, err, "Failed to replay agent trace: ")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    trace,
+	})
+}
+
+// handleError maps agent trace errors to HTTP errors
+func (h *AgentTraceHandler) handleError(c *gin.Context, err error, message string) {
+	switch {
+	case stderrors.Is(err, service.ErrAgentTraceNotFound):
+		c.Error(errors.NewNotFoundError(err.Error()))
+	case stderrors.Is(err, service.ErrAgentTraceNotReplayable), stderrors.Is(err, service.ErrModelNotFound):
+		c.Error(errors.NewBadRequestError(err.Error()))
+	default:
+		c.Error(errors.NewInternalServerError(message + err.Error()))
+	}
+}
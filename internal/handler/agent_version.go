@@ -0,0 +1,234 @@
+package handler
+
+import (
+	stderrors "errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Tencent/WeKnora/internal/application/service"
+	"github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	secutils "github.com/Tencent/WeKnora/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// draftVersionName names the draft of an agent in version parameters
+const draftVersionName = "draft"
+
+// AgentVersionHandler handles HTTP requests for publishing, comparing and rolling back custom agent versions
+type AgentVersionHandler struct {
+	service interfaces.AgentVersionService
+}
+
+// NewAgentVersionHandler creates a new agent version handler
+func NewAgentVersionHandler(service interfaces.AgentVersionService) *AgentVersionHandler {
+	return &AgentVersionHandler{service: service}
+}
+
+// PublishAgentVersion godoc
+// @Summary      发布智能体版本
+// @Description  将智能体的草稿发布为新的版本号，并作为对话中使用的版本。修改智能体只会修改草稿，发布后才对使用者生效
+// @Tags         智能体版本
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                     true   "智能体 ID"
+// @Param        request  body      types.PublishAgentRequest  false  "发布说明"
+// @Success      200      {object}  map[string]interface{}     "发布的版本"
+// @Failure      400      {object}  errors.AppError            "内置智能体或草稿没有变化"
+// @Failure      404      {object}  errors.AppError            "智能体不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /agents/{id}/versions [post]
+func (h *AgentVersionHandler) PublishAgentVersion(c *gin.Context) {
+	ctx := c.Request.Context()
+	agentID := secutils.SanitizeForLog(c.Param("id"))
+
+	var req types.PublishAgentRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			logger.Error(ctx, "Failed to parse request parameters", err)
+			c.Error(errors.NewBadRequestError(err.Error()))
+			return
+		}
+	}
+
+	version, err := h.service.Publish(ctx, agentID, &req)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"agent_id": agentID})
+		h.handleError(c, err, "Failed to publish agent version: ")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    version,
+	})
+}
+
+// ListAgentVersions godoc
+// @Summary      获取智能体版本列表
+// @Description  获取智能体已发布的所有版本，按版本号倒序
+// @Tags         智能体版本
+// @Produce      json
+// @Param        id   path      string  true  "智能体 ID"
+// @Success      200  {object}  map[string]interface{}  "版本列表"
+// @Failure      404  {object}  errors.AppError         "智能体不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /agents/{id}/versions [get]
+func (h *AgentVersionHandler) ListAgentVersions(c *gin.Context) {
+	ctx := c.Request.Context()
+	agentID := secutils.SanitizeForLog(c.Param("id"))
+
+	versions, err := h.service.ListVersions(ctx, agentID)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"agent_id": agentID})
+		h.handleError(c, err, "Failed to list agent versions: ")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    versions,
+	})
+}
+
+// GetAgentVersion godoc
+// @Summary      获取智能体版本详情
+// @Description  获取智能体某个版本的名称、描述、头像和配置，版本号为 draft 时返回草稿
+// @Tags         智能体版本
+// @Produce      json
+// @Param        id       path      string  true  "智能体 ID"
+// @Param        version  path      string  true  "版本号或 draft"
+// @Success      200      {object}  map[string]interface{}  "版本详情"
+// @Failure      400      {object}  errors.AppError         "版本号无效"
+// @Failure      404      {object}  errors.AppError         "智能体或版本不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /agents/{id}/versions/{version} [get]
+func (h *AgentVersionHandler) GetAgentVersion(c *gin.Context) {
+	ctx := c.Request.Context()
+	agentID := secutils.SanitizeForLog(c.Param("id"))
+	version, ok := parseAgentVersion(c, c.Param("version"))
+	if !ok {
+		return
+	}
+
+	agentVersion, err := h.service.GetVersion(ctx, agentID, version)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"agent_id": agentID, "version": version})
+		h.handleError(c, err, "Failed to get agent version: ")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    agentVersion,
+	})
+}
+
+// DiffAgentVersions godoc
+// @Summary      对比智能体版本
+// @Description  逐字段对比智能体两个版本的名称、描述、头像和配置，版本号为 draft 时表示草稿
+// @Tags         智能体版本
+// @Produce      json
+// @Param        id    path      string  true  "智能体 ID"
+// @Param        from  query     string  true  "基准版本号或 draft"
+// @Param        to    query     string  true  "目标版本号或 draft"
+// @Success      200   {object}  map[string]interface{}  "变更的字段"
+// @Failure      400   {object}  errors.AppError         "版本号无效"
+// @Failure      404   {object}  errors.AppError         "智能体或版本不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /agents/{id}/versions/diff [get]
+func (h *AgentVersionHandler) DiffAgentVersions(c *gin.Context) {
+	ctx := c.Request.Context()
+	agentID := secutils.SanitizeForLog(c.Param("id"))
+	if c.Query("from") == "" || c.Query("to") == "" {
+		c.Error(errors.NewBadRequestError("from and to are required"))
+		return
+	}
+	from, ok := parseAgentVersion(c, c.Query("from"))
+	if !ok {
+		return
+	}
+	to, ok := parseAgentVersion(c, c.Query("to"))
+	if !ok {
+		return
+	}
+
+	diff, err := h.service.DiffVersions(ctx, agentID, from, to)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"agent_id": agentID})
+		h.handleError(c, err, "Failed to diff agent versions: ")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    diff,
+	})
+}
+
+// RollbackAgentVersion godoc
+// @Summary      回滚智能体版本
+// @Description  以某个历史版本的配置发布一个新版本并在对话中使用，草稿和历史版本保持不变
+// @Tags         智能体版本
+// @Produce      json
+// @Param        id       path      string  true  "智能体 ID"
+// @Param        version  path      int     true  "版本号"
+// @Success      200      {object}  map[string]interface{}  "回滚后的智能体"
+// @Failure      400      {object}  errors.AppError         "版本号无效"
+// @Failure      404      {object}  errors.AppError         "智能体或版本不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /agents/{id}/versions/{version}/rollback [post]
+func (h *AgentVersionHandler) RollbackAgentVersion(c *gin.Context) {
+	ctx := c.Request.Context()
+	agentID := secutils.SanitizeForLog(c.Param("id"))
+	version, ok := parseAgentVersion(c, c.Param("version"))
+	if !ok {
+		return
+	}
+
+	agent, err := h.service.Rollback(ctx, agentID, version)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"agent_id": agentID, "version": version})
+		h.handleError(c, err, "Failed to roll back agent version: ")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    agent,
+	})
+}
+
+// parseAgentVersion parses a version number, "draft" is version 0.
+// It reports a bad request and returns false when the value is invalid.
+func parseAgentVersion(c *gin.Context, value string) (int, bool) {
+	if value == draftVersionName {
+		return 0, true
+	}
+	version, err := strconv.Atoi(value)
+	if err != nil || version <= 0 {
+		c.Error(errors.NewBadRequestError("invalid version: " + secutils.SanitizeForLog(value)))
+		return 0, false
+	}
+	return version, true
+}
+
+// handleError maps agent version service errors to HTTP errors
+func (h *AgentVersionHandler) handleError(c *gin.Context, err error, message string) {
+	switch {
+	case stderrors.Is(err, service.ErrAgentNotFound),
+		stderrors.Is(err, service.ErrAgentVersionNotFound):
+		c.Error(errors.NewNotFoundError(err.Error()))
+	case stderrors.Is(err, service.ErrAgentVersionInvalid):
+		c.Error(errors.NewBadRequestError(err.Error()))
+	default:
+		c.Error(errors.NewInternalServerError(message + err.Error()))
+	}
+}
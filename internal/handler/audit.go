@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"github.com/gin-gonic/gin"
+)
+
+// AuditLogHandler handles HTTP requests for audit logs
+type AuditLogHandler struct {
+	service interfaces.AuditLogService
+}
+
+// NewAuditLogHandler creates a new audit log handler
+func NewAuditLogHandler(service interfaces.AuditLogService) *AuditLogHandler {
+	return &AuditLogHandler{service: service}
+}
+
+// ListAuditLogs godoc
+// @Summary      查询审计日志
+// @Description  分页查询当前租户的审计日志，按时间倒序，支持按操作人、动作、资源类型、资源ID、结果和时间范围过滤
+// @Tags         审计日志
+// @Produce      json
+// @Param        actor_id       query     string  false  "操作人ID（用户ID或脱敏后的 API Key）"
+// @Param        action         query     string  false  "动作，如 create、delete、share"
+// @Param        resource_type  query     string  false  "资源类型，如 knowledge、kb_share、organization"
+// @Param        resource_id    query     string  false  "资源ID"
+// @Param        result         query     string  false  "结果：success 或 failed"
+// @Param        start_time     query     string  false  "开始时间（RFC3339，包含）"
+// @Param        end_time       query     string  false  "结束时间（RFC3339，不包含）"
+// @Param        page           query     int     false  "页码"
+// @Param        page_size      query     int     false  "每页数量"
+// @Success      200            {object}  map[string]interface{}  "审计日志列表"
+// @Failure      400            {object}  errors.AppError         "请求参数错误"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /audit-logs [get]
+func (h *AuditLogHandler) ListAuditLogs(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var filter types.AuditLogFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		logger.Error(ctx, "Failed to parse audit log filter", err)
+		c.Error(errors.NewBadRequestError(err.Error()))
+		return
+	}
+	var page types.Pagination
+	if err := c.ShouldBindQuery(&page); err != nil {
+		logger.Error(ctx, "Failed to parse pagination parameters", err)
+		c.Error(errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	result, err := h.service.ListAuditLogs(ctx, &filter, &page)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(errors.NewInternalServerError("Failed to list audit logs: " + err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+	})
+}
+
+// setAuditBefore records the state of a resource before it is changed or deleted,
+// it is written to the audit log by the audit middleware
+func setAuditBefore(c *gin.Context, before types.AuditChange) {
+	c.Set(types.AuditBeforeContextKey.String(), before)
+}
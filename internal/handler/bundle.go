@@ -0,0 +1,179 @@
+package handler
+
+import (
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/application/repository"
+	"github.com/Tencent/WeKnora/internal/application/service"
+	"github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	secutils "github.com/Tencent/WeKnora/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// BundleHandler handles HTTP requests for exporting and importing agent and knowledge base bundles
+type BundleHandler struct {
+	service interfaces.BundleService
+}
+
+// NewBundleHandler creates a new bundle handler
+func NewBundleHandler(service interfaces.BundleService) *BundleHandler {
+	return &BundleHandler{service: service}
+}
+
+// ExportAgentBundle godoc
+// @Summary      导出智能体
+// @Description  将智能体（当前发布的版本）打包为 zip 文件下载，包含其调用的子智能体，以及引用的 MCP 服务、HTTP 工具、技能、模型和知识库的定义。MCP 服务、HTTP 工具和知识库中的密钥不会导出。include_knowledge=true 时同时导出知识库的文档和分块，include_vectors=true 时再导出分块的向量，导入时无需重新向量化
+// @Tags         导入导出
+// @Produce      application/zip
+// @Param        id                 path      string  true   "智能体ID"
+// @Param        include_knowledge  query     bool    false  "导出知识库的文档和分块"
+// @Param        include_vectors    query     bool    false  "导出分块的向量，需同时导出知识库内容"
+// @Success      200                {file}    file    "智能体包"
+// @Failure      400                {object}  errors.AppError  "参数错误或内置智能体"
+// @Failure      404                {object}  errors.AppError  "智能体不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /agents/{id}/export [get]
+func (h *BundleHandler) ExportAgentBundle(c *gin.Context) {
+	ctx := c.Request.Context()
+	agentID := secutils.SanitizeForLog(c.Param("id"))
+
+	var opts types.BundleExportOptions
+	if err := c.ShouldBindQuery(&opts); err != nil {
+		c.Error(errors.NewBadRequestError(err.Error()))
+		return
+	}
+	h.export(c, "agent_"+agentID, func(file *os.File) error {
+		return h.service.ExportAgent(ctx, agentID, &opts, file)
+	})
+}
+
+// ExportKnowledgeBaseBundle godoc
+// @Summary      导出知识库
+// @Description  将知识库及其标签、文档和分块打包为 zip 文件下载，存储配置中的密钥不会导出。include_vectors=true 时同时导出分块的向量，导入时无需重新向量化
+// @Tags         导入导出
+// @Produce      application/zip
+// @Param        id               path      string  true   "知识库ID"
+// @Param        include_vectors  query     bool    false  "导出分块的向量"
+// @Success      200              {file}    file    "知识库包"
+// @Failure      404              {object}  errors.AppError  "知识库不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge-bases/{id}/export [get]
+func (h *BundleHandler) ExportKnowledgeBaseBundle(c *gin.Context) {
+	ctx := c.Request.Context()
+	kbID := secutils.SanitizeForLog(c.Param("id"))
+
+	var opts types.BundleExportOptions
+	if err := c.ShouldBindQuery(&opts); err != nil {
+		c.Error(errors.NewBadRequestError(err.Error()))
+		return
+	}
+	h.export(c, "knowledge_base_"+kbID, func(file *os.File) error {
+		return h.service.ExportKnowledgeBase(ctx, kbID, &opts, file)
+	})
+}
+
+// export writes a bundle to a temporary file and sends it once it is complete,
+// so that a failed export is reported as an error instead of a truncated download
+func (h *BundleHandler) export(c *gin.Context, name string, write func(*os.File) error) {
+	ctx := c.Request.Context()
+	file, err := os.CreateTemp("", "weknora-bundle-*.zip")
+	if err != nil {
+		c.Error(errors.NewInternalServerError("Failed to create bundle file: " + err.Error()))
+		return
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	if err := write(file); err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"bundle": name})
+		h.handleError(c, err, "Failed to export bundle: ")
+		return
+	}
+	c.FileAttachment(file.Name(), fmt.Sprintf("%s_%s.zip", name, time.Now().Format("20060102150405")))
+}
+
+// ImportBundle godoc
+// @Summary      导入智能体或知识库
+// @Description  导入由导出接口生成的 zip 包。所有对象使用新的 ID 创建，对象之间的引用随之改写；模型和技能按名称匹配当前租户已有的对象，仅被引用而未导出内容的知识库按名称匹配，找不到时移除引用并给出警告。名称冲突按 on_conflict 处理：fail（默认）报告冲突且不导入，rename 以新名称导入，reuse 使用同名的已有对象。dry_run=true 时只返回导入计划。导入失败时已创建的对象会被删除
+// @Tags         导入导出
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        file         formData  file    true   "导出的 zip 包"
+// @Param        dry_run      query     bool    false  "只返回导入计划"
+// @Param        on_conflict  query     string  false  "名称冲突处理方式：fail、rename、reuse"
+// @Success      200          {object}  map[string]interface{}  "导入报告"
+// @Failure      400          {object}  errors.AppError         "文件无效"
+// @Failure      403          {object}  errors.AppError         "超出存储配额"
+// @Failure      409          {object}  errors.AppError         "存在冲突，details 为导入报告"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /bundles/import [post]
+func (h *BundleHandler) ImportBundle(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var opts types.BundleImportOptions
+	if err := c.ShouldBindQuery(&opts); err != nil {
+		c.Error(errors.NewBadRequestError(err.Error()))
+		return
+	}
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.Error(errors.NewBadRequestError("File upload failed").WithDetails(err.Error()))
+		return
+	}
+	if maxSizeMB := secutils.GetMaxBundleSizeMB(); header.Size > maxSizeMB*1024*1024 {
+		c.Error(errors.NewBadRequestError(fmt.Sprintf("文件大小不能超过%dMB", maxSizeMB)))
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		c.Error(errors.NewBadRequestError("File upload failed").WithDetails(err.Error()))
+		return
+	}
+	defer file.Close()
+
+	logger.Infof(ctx, "Importing bundle %s, size: %d, dry run: %v, on conflict: %s",
+		secutils.SanitizeForLog(header.Filename), header.Size, opts.DryRun, secutils.SanitizeForLog(opts.OnConflict))
+	report, err := h.service.Import(ctx, file, header.Size, &opts)
+	if err != nil {
+		if stderrors.Is(err, service.ErrBundleConflict) {
+			c.Error(errors.NewConflictError(err.Error()).WithDetails(report))
+			return
+		}
+		logger.ErrorWithFields(ctx, err, nil)
+		h.handleError(c, err, "Failed to import bundle: ")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    report,
+	})
+}
+
+// handleError maps bundle errors to HTTP errors
+func (h *BundleHandler) handleError(c *gin.Context, err error, message string) {
+	var quotaErr *types.StorageQuotaExceededError
+	switch {
+	case stderrors.As(err, &quotaErr):
+		c.Error(errors.NewForbiddenError(err.Error()))
+	case stderrors.Is(err, service.ErrAgentNotFound), stderrors.Is(err, repository.ErrKnowledgeBaseNotFound):
+		c.Error(errors.NewNotFoundError(err.Error()))
+	case stderrors.Is(err, service.ErrBundleInvalid),
+		stderrors.Is(err, service.ErrInvalidAgentConfig),
+		stderrors.Is(err, service.ErrHTTPToolInvalid),
+		stderrors.Is(err, service.ErrHTTPToolNameConflict):
+		c.Error(errors.NewBadRequestError(err.Error()))
+	default:
+		c.Error(errors.NewInternalServerError(message + err.Error()))
+	}
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	stderrors "errors"
 	"net/http"
 
 	"github.com/Tencent/WeKnora/internal/application/service"
@@ -82,7 +83,7 @@ func (h *CustomAgentHandler) CreateAgent(c *gin.Context) {
 	createdAgent, err := h.service.CreateAgent(ctx, agent)
 	if err != nil {
 		logger.ErrorWithFields(ctx, err, nil)
-		if err == service.ErrAgentNameRequired {
+		if err == service.ErrAgentNameRequired || stderrors.Is(err, service.ErrInvalidAgentConfig) {
 			c.Error(errors.NewBadRequestError(err.Error()))
 			return
 		}
@@ -235,6 +236,10 @@ func (h *CustomAgentHandler) UpdateAgent(c *gin.Context) {
 		case service.ErrAgentNameRequired:
 			c.Error(errors.NewBadRequestError(err.Error()))
 		default:
+			if stderrors.Is(err, service.ErrInvalidAgentConfig) {
+				c.Error(errors.NewBadRequestError(err.Error()))
+				return
+			}
 			c.Error(errors.NewInternalServerError(err.Error()))
 		}
 		return
@@ -275,6 +280,9 @@ func (h *CustomAgentHandler) DeleteAgent(c *gin.Context) {
 	}
 
 	logger.Infof(ctx, "Deleting custom agent, ID: %s", secutils.SanitizeForLog(id))
+	if agent, err := h.service.GetAgentByID(ctx, id); err == nil && agent != nil {
+		setAuditBefore(c, types.AuditChange{"name": agent.Name})
+	}
 
 	// Delete the agent
 	err := h.service.DeleteAgent(ctx, id)
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"github.com/gin-gonic/gin"
+)
+
+// HealthHandler serves the liveness and readiness probes
+type HealthHandler struct {
+	service interfaces.HealthService
+}
+
+// NewHealthHandler creates a new health handler
+func NewHealthHandler(service interfaces.HealthService) *HealthHandler {
+	return &HealthHandler{service: service}
+}
+
+// Live godoc
+// @Summary      存活检查
+// @Description  进程能够处理 HTTP 请求即返回 200，不检查外部依赖，用于 Kubernetes livenessProbe
+// @Tags         系统
+// @Produce      json
+// @Success      200  {object}  map[string]interface{}  "存活"
+// @Router       /health/live [get]
+func (h *HealthHandler) Live(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":     types.HealthStatusUp,
+		"checked_at": time.Now(),
+	})
+}
+
+// Ready godoc
+// @Summary      就绪检查
+// @Description  探测数据库、Redis、DocReader、各检索引擎、Neo4j（如启用）和文件存储，返回每个依赖的状态。
+// @Description  必需依赖不可用时返回 503；仅可选依赖不可用时状态为 degraded 并返回 200。结果会短暂缓存。
+// @Tags         系统
+// @Produce      json
+// @Success      200  {object}  types.HealthReport  "就绪"
+// @Failure      503  {object}  types.HealthReport  "未就绪"
+// @Router       /health/ready [get]
+func (h *HealthHandler) Ready(c *gin.Context) {
+	report := h.service.Ready(c.Request.Context())
+	status := http.StatusOK
+	if report.Status == types.HealthStatusDown {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
@@ -0,0 +1,360 @@
+package handler
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"net/http"
+
+	"github.com/Tencent/WeKnora/internal/application/service"
+	"github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	secutils "github.com/Tencent/WeKnora/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// HTTPToolHandler handles HTTP requests for the HTTP tools of custom agents
+type HTTPToolHandler struct {
+	service interfaces.HTTPToolService
+}
+
+// NewHTTPToolHandler creates a new HTTP tool handler
+func NewHTTPToolHandler(service interfaces.HTTPToolService) *HTTPToolHandler {
+	return &HTTPToolHandler{service: service}
+}
+
+// CreateHTTPToolRequest is the request body of CreateHTTPTool
+type CreateHTTPToolRequest struct {
+	Name         string               `json:"name"          binding:"required"`
+	Description  string               `json:"description"`
+	Method       string               `json:"method"`
+	URL          string               `json:"url"           binding:"required"`
+	Parameters   types.HTTPToolSchema `json:"parameters"`
+	ArgLocations map[string]string    `json:"arg_locations"`
+	Headers      map[string]string    `json:"headers"`
+	AuthHeader   string               `json:"auth_header"`
+	// Auth secret sent in the auth header, encrypted at rest and never returned
+	AuthSecret     string `json:"auth_secret"`
+	ResponsePath   string `json:"response_path"`
+	TimeoutSeconds int    `json:"timeout_seconds"`
+	Enabled        *bool  `json:"enabled"`
+}
+
+// UpdateHTTPToolRequest is the request body of UpdateHTTPTool, omitted fields are left unchanged
+type UpdateHTTPToolRequest struct {
+	Name         *string               `json:"name"`
+	Description  *string               `json:"description"`
+	Method       *string               `json:"method"`
+	URL          *string               `json:"url"`
+	Parameters   *types.HTTPToolSchema `json:"parameters"`
+	ArgLocations *map[string]string    `json:"arg_locations"`
+	Headers      *map[string]string    `json:"headers"`
+	AuthHeader   *string               `json:"auth_header"`
+	// Replaces the auth secret, an empty string removes it
+	AuthSecret     *string `json:"auth_secret"`
+	ResponsePath   *string `json:"response_path"`
+	TimeoutSeconds *int    `json:"timeout_seconds"`
+	Enabled        *bool   `json:"enabled"`
+}
+
+// TestHTTPToolRequest is the request body of TestHTTPTool
+type TestHTTPToolRequest struct {
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+// CreateHTTPTool godoc
+// @Summary      创建 HTTP 工具
+// @Description  手动定义一个 HTTP 工具：请求方法、URL 模板（{参数名} 占位）、参数 JSON Schema、鉴权头及 JSONPath 响应提取。
+// @Description  鉴权密钥加密存储且不会返回。自定义智能体在 http_tools 中选择后即可调用，工具名为 http_{name}
+// @Tags         HTTP工具
+// @Accept       json
+// @Produce      json
+// @Param        request  body      CreateHTTPToolRequest   true  "工具定义"
+// @Success      200      {object}  map[string]interface{}  "创建的工具"
+// @Failure      400      {object}  errors.AppError         "工具定义无效或名称重复"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /http-tools [post]
+func (h *HTTPToolHandler) CreateHTTPTool(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req CreateHTTPToolRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error(ctx, "Failed to parse request parameters", err)
+		c.Error(errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	tool := &types.HTTPTool{
+		Name:           req.Name,
+		Description:    req.Description,
+		Method:         req.Method,
+		URL:            req.URL,
+		Parameters:     req.Parameters,
+		ArgLocations:   req.ArgLocations,
+		Headers:        req.Headers,
+		AuthHeader:     req.AuthHeader,
+		AuthSecret:     types.EncryptedString(req.AuthSecret),
+		ResponsePath:   req.ResponsePath,
+		TimeoutSeconds: req.TimeoutSeconds,
+		Enabled:        req.Enabled == nil || *req.Enabled,
+	}
+	tool, err := h.service.CreateTool(ctx, tool)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		h.handleError(c, err, "Failed to create http tool: ")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    tool,
+	})
+}
+
+// ImportOpenAPITools godoc
+// @Summary      从 OpenAPI 文档导入 HTTP 工具
+// @Description  解析 OpenAPI 3 文档（JSON 或 YAML），为每个操作创建一个 HTTP 工具，可通过 operations 只导入部分操作。
+// @Description  再次导入同一文档会按名称更新已导入的工具，未传鉴权密钥时保留原密钥
+// @Tags         HTTP工具
+// @Accept       json
+// @Produce      json
+// @Param        request  body      types.HTTPToolImportRequest  true  "OpenAPI 文档及导入选项"
+// @Success      200      {object}  map[string]interface{}       "导入的工具"
+// @Failure      400      {object}  errors.AppError              "文档无效或名称冲突"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /http-tools/import-openapi [post]
+func (h *HTTPToolHandler) ImportOpenAPITools(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req types.HTTPToolImportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error(ctx, "Failed to parse request parameters", err)
+		c.Error(errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	tools, err := h.service.ImportOpenAPI(ctx, &req)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		h.handleError(c, err, "Failed to import openapi document: ")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    tools,
+	})
+}
+
+// ListHTTPTools godoc
+// @Summary      获取 HTTP 工具列表
+// @Description  获取当前租户的所有 HTTP 工具
+// @Tags         HTTP工具
+// @Produce      json
+// @Success      200  {object}  map[string]interface{}  "工具列表"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /http-tools [get]
+func (h *HTTPToolHandler) ListHTTPTools(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	tools, err := h.service.ListTools(ctx)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(errors.NewInternalServerError("Failed to list http tools: " + err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    tools,
+	})
+}
+
+// GetHTTPTool godoc
+// @Summary      获取 HTTP 工具详情
+// @Tags         HTTP工具
+// @Produce      json
+// @Param        id   path      string  true  "工具ID"
+// @Success      200  {object}  map[string]interface{}  "工具"
+// @Failure      404  {object}  errors.AppError         "工具不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /http-tools/{id} [get]
+func (h *HTTPToolHandler) GetHTTPTool(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := secutils.SanitizeForLog(c.Param("id"))
+
+	tool, err := h.service.GetTool(ctx, id)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"http_tool_id": id})
+		h.handleError(c, err, "Failed to get http tool: ")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    tool,
+	})
+}
+
+// UpdateHTTPTool godoc
+// @Summary      更新 HTTP 工具
+// @Description  更新 HTTP 工具的定义或启用状态，未传的字段保持不变。auth_secret 传空字符串时移除鉴权密钥
+// @Tags         HTTP工具
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                  true  "工具ID"
+// @Param        request  body      UpdateHTTPToolRequest   true  "更新内容"
+// @Success      200      {object}  map[string]interface{}  "更新后的工具"
+// @Failure      400      {object}  errors.AppError         "工具定义无效或名称重复"
+// @Failure      404      {object}  errors.AppError         "工具不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /http-tools/{id} [put]
+func (h *HTTPToolHandler) UpdateHTTPTool(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := secutils.SanitizeForLog(c.Param("id"))
+
+	var req UpdateHTTPToolRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error(ctx, "Failed to parse request parameters", err)
+		c.Error(errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	tool, err := h.service.GetTool(ctx, id)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"http_tool_id": id})
+		h.handleError(c, err, "Failed to get http tool: ")
+		return
+	}
+	if req.Name != nil {
+		tool.Name = *req.Name
+	}
+	if req.Description != nil {
+		tool.Description = *req.Description
+	}
+	if req.Method != nil {
+		tool.Method = *req.Method
+	}
+	if req.URL != nil {
+		tool.URL = *req.URL
+	}
+	if req.Parameters != nil {
+		tool.Parameters = *req.Parameters
+	}
+	if req.ArgLocations != nil {
+		tool.ArgLocations = *req.ArgLocations
+	}
+	if req.Headers != nil {
+		tool.Headers = *req.Headers
+	}
+	if req.AuthHeader != nil {
+		tool.AuthHeader = *req.AuthHeader
+	}
+	if req.AuthSecret != nil {
+		tool.AuthSecret = types.EncryptedString(*req.AuthSecret)
+	}
+	if req.ResponsePath != nil {
+		tool.ResponsePath = *req.ResponsePath
+	}
+	if req.TimeoutSeconds != nil {
+		tool.TimeoutSeconds = *req.TimeoutSeconds
+	}
+	if req.Enabled != nil {
+		tool.Enabled = *req.Enabled
+	}
+
+	tool, err = h.service.UpdateTool(ctx, tool)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"http_tool_id": id})
+		h.handleError(c, err, "Failed to update http tool: ")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    tool,
+	})
+}
+
+// DeleteHTTPTool godoc
+// @Summary      删除 HTTP 工具
+// @Description  删除 HTTP 工具，已选择该工具的智能体不再加载它
+// @Tags         HTTP工具
+// @Produce      json
+// @Param        id   path      string  true  "工具ID"
+// @Success      200  {object}  map[string]interface{}  "删除成功"
+// @Failure      404  {object}  errors.AppError         "工具不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /http-tools/{id} [delete]
+func (h *HTTPToolHandler) DeleteHTTPTool(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := secutils.SanitizeForLog(c.Param("id"))
+
+	if err := h.service.DeleteTool(ctx, id); err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"http_tool_id": id})
+		h.handleError(c, err, "Failed to delete http tool: ")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
+
+// TestHTTPTool godoc
+// @Summary      测试 HTTP 工具
+// @Description  使用给定参数立即调用 HTTP 工具，返回与智能体调用时相同的结果，可用于验证 URL、鉴权和 JSONPath 提取。禁用的工具也可测试
+// @Tags         HTTP工具
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                  true  "工具ID"
+// @Param        request  body      TestHTTPToolRequest     true  "调用参数"
+// @Success      200      {object}  map[string]interface{}  "工具执行结果"
+// @Failure      404      {object}  errors.AppError         "工具不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /http-tools/{id}/test [post]
+func (h *HTTPToolHandler) TestHTTPTool(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := secutils.SanitizeForLog(c.Param("id"))
+
+	var req TestHTTPToolRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error(ctx, "Failed to parse request parameters", err)
+		c.Error(errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	result, err := h.service.TestTool(ctx, id, req.Arguments)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"http_tool_id": id})
+		h.handleError(c, err, "Failed to test http tool: ")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+	})
+}
+
+// handleError maps HTTP tool service errors to HTTP errors
+func (h *HTTPToolHandler) handleError(c *gin.Context, err error, message string) {
+	switch {
+	case stderrors.Is(err, service.ErrHTTPToolNotFound):
+		c.Error(errors.NewNotFoundError(err.Error()))
+	case stderrors.Is(err, service.ErrHTTPToolInvalid),
+		stderrors.Is(err, service.ErrHTTPToolInvalidSpec),
+		stderrors.Is(err, service.ErrHTTPToolNameConflict):
+		c.Error(errors.NewBadRequestError(err.Error()))
+	default:
+		c.Error(errors.NewInternalServerError(message + err.Error()))
+	}
+}
@@ -0,0 +1,144 @@
+package handler
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"github.com/Tencent/WeKnora/internal/application/repository"
+	"github.com/Tencent/WeKnora/internal/application/service"
+	"github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	secutils "github.com/Tencent/WeKnora/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// IndexConsistencyHandler handles HTTP requests for index consistency checks of knowledge bases
+type IndexConsistencyHandler struct {
+	service interfaces.IndexConsistencyService
+}
+
+// NewIndexConsistencyHandler creates a new index consistency handler
+func NewIndexConsistencyHandler(service interfaces.IndexConsistencyService) *IndexConsistencyHandler {
+	return &IndexConsistencyHandler{service: service}
+}
+
+// StartIndexCheckRequest is the request body of StartIndexCheck
+type StartIndexCheckRequest struct {
+	// Repair drift after it is detected
+	Repair bool `json:"repair"`
+}
+
+// StartIndexCheck godoc
+// @Summary      发起索引一致性检查
+// @Description  比较知识库的分块与租户各检索引擎中的索引（ID、启用状态、标签），生成差异报告，可选自动修复
+// @Tags         知识库
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                  true   "知识库ID"
+// @Param        request  body      StartIndexCheckRequest  false  "检查参数"
+// @Success      200      {object}  map[string]interface{}  "检查报告"
+// @Failure      404      {object}  errors.AppError         "知识库不存在"
+// @Failure      409      {object}  errors.AppError         "已有进行中的检查"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge-bases/{id}/index-check [post]
+func (h *IndexConsistencyHandler) StartIndexCheck(c *gin.Context) {
+	ctx := c.Request.Context()
+	kbID := secutils.SanitizeForLog(c.Param("id"))
+
+	var req StartIndexCheckRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			logger.Error(ctx, "Failed to parse request parameters", err)
+			c.Error(errors.NewBadRequestError(err.Error()))
+			return
+		}
+	}
+
+	report, err := h.service.StartCheck(ctx, kbID, req.Repair)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"knowledge_base_id": kbID})
+		switch {
+		case stderrors.Is(err, repository.ErrKnowledgeBaseNotFound):
+			c.Error(errors.NewNotFoundError(err.Error()))
+		case stderrors.Is(err, service.ErrIndexCheckActive):
+			c.Error(errors.NewConflictError(err.Error()))
+		default:
+			c.Error(errors.NewInternalServerError("Failed to start index check: " + err.Error()))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    report,
+	})
+}
+
+// ListIndexCheckReports godoc
+// @Summary      获取索引一致性报告列表
+// @Description  获取知识库最近的索引一致性检查报告，按创建时间倒序
+// @Tags         知识库
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "知识库ID"
+// @Success      200  {object}  map[string]interface{}  "报告列表"
+// @Failure      500  {object}  errors.AppError         "服务器错误"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge-bases/{id}/index-check/reports [get]
+func (h *IndexConsistencyHandler) ListIndexCheckReports(c *gin.Context) {
+	ctx := c.Request.Context()
+	kbID := secutils.SanitizeForLog(c.Param("id"))
+
+	reports, err := h.service.ListReports(ctx, kbID)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"knowledge_base_id": kbID})
+		c.Error(errors.NewInternalServerError("Failed to list index check reports: " + err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    reports,
+	})
+}
+
+// GetIndexCheckReport godoc
+// @Summary      获取索引一致性报告
+// @Description  获取检查状态以及每个检索引擎的缺失索引、孤立索引、启用状态和标签不一致的数量与示例ID
+// @Tags         知识库
+// @Accept       json
+// @Produce      json
+// @Param        id         path      string  true  "知识库ID"
+// @Param        report_id  path      string  true  "报告ID"
+// @Success      200        {object}  map[string]interface{}  "检查报告"
+// @Failure      404        {object}  errors.AppError         "报告不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge-bases/{id}/index-check/reports/{report_id} [get]
+func (h *IndexConsistencyHandler) GetIndexCheckReport(c *gin.Context) {
+	ctx := c.Request.Context()
+	kbID := secutils.SanitizeForLog(c.Param("id"))
+	reportID := secutils.SanitizeForLog(c.Param("report_id"))
+
+	report, err := h.service.GetReport(ctx, kbID, reportID)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"knowledge_base_id": kbID,
+			"report_id":         reportID,
+		})
+		if stderrors.Is(err, service.ErrIndexCheckReportNotFound) {
+			c.Error(errors.NewNotFoundError(err.Error()))
+			return
+		}
+		c.Error(errors.NewInternalServerError("Failed to get index check report: " + err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    report,
+	})
+}
@@ -0,0 +1,179 @@
+package handler
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"github.com/Tencent/WeKnora/internal/application/service"
+	"github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	secutils "github.com/Tencent/WeKnora/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// IndexMigrationHandler handles HTTP requests for moving indices between retriever engines
+type IndexMigrationHandler struct {
+	service interfaces.IndexMigrationService
+}
+
+// NewIndexMigrationHandler creates a new index migration handler
+func NewIndexMigrationHandler(service interfaces.IndexMigrationService) *IndexMigrationHandler {
+	return &IndexMigrationHandler{service: service}
+}
+
+// StartIndexMigrationRequest is the request body of StartIndexMigration
+type StartIndexMigrationRequest struct {
+	SourceEngine types.RetrieverEngineType `json:"source_engine" binding:"required"`
+	TargetEngine types.RetrieverEngineType `json:"target_engine" binding:"required"`
+}
+
+// StartIndexMigration godoc
+// @Summary      发起索引迁移
+// @Description  将当前租户所有知识库的索引从源检索引擎迁移到目标检索引擎（无需重新向量化），校验数量后切换租户的引擎配置
+// @Tags         租户管理
+// @Accept       json
+// @Produce      json
+// @Param        request  body      StartIndexMigrationRequest  true  "迁移请求"
+// @Success      200      {object}  map[string]interface{}      "迁移任务"
+// @Failure      400      {object}  errors.AppError             "请求参数错误"
+// @Failure      409      {object}  errors.AppError             "已有进行中的迁移"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /tenants/index-migrations [post]
+func (h *IndexMigrationHandler) StartIndexMigration(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req StartIndexMigrationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error(ctx, "Failed to parse request parameters", err)
+		c.Error(errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	migration, err := h.service.StartMigration(ctx, req.SourceEngine, req.TargetEngine)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"source_engine": req.SourceEngine,
+			"target_engine": req.TargetEngine,
+		})
+		switch {
+		case stderrors.Is(err, service.ErrIndexMigrationActive):
+			c.Error(errors.NewConflictError(err.Error()))
+		case stderrors.Is(err, service.ErrIndexMigrationSameEngine),
+			stderrors.Is(err, service.ErrIndexMigrationEngineNotFound),
+			stderrors.Is(err, service.ErrIndexMigrationEngineNotUsed),
+			stderrors.Is(err, service.ErrIndexMigrationEngineUnsupported):
+			c.Error(errors.NewBadRequestError(err.Error()))
+		default:
+			c.Error(errors.NewInternalServerError("Failed to start index migration: " + err.Error()))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    migration,
+	})
+}
+
+// ListIndexMigrations godoc
+// @Summary      获取索引迁移列表
+// @Description  获取当前租户的索引迁移任务，按创建时间倒序
+// @Tags         租户管理
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  map[string]interface{}  "迁移任务列表"
+// @Failure      500  {object}  errors.AppError         "服务器错误"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /tenants/index-migrations [get]
+func (h *IndexMigrationHandler) ListIndexMigrations(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	migrations, err := h.service.ListMigrations(ctx)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(errors.NewInternalServerError("Failed to list index migrations: " + err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    migrations,
+	})
+}
+
+// GetIndexMigration godoc
+// @Summary      获取索引迁移进度
+// @Description  获取索引迁移任务的状态以及每个知识库的迁移阶段和数量
+// @Tags         租户管理
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "迁移任务ID"
+// @Success      200  {object}  map[string]interface{}  "迁移任务"
+// @Failure      404  {object}  errors.AppError         "迁移任务不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /tenants/index-migrations/{id} [get]
+func (h *IndexMigrationHandler) GetIndexMigration(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := secutils.SanitizeForLog(c.Param("id"))
+
+	migration, err := h.service.GetMigration(ctx, id)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"migration_id": id})
+		if stderrors.Is(err, service.ErrIndexMigrationNotFound) {
+			c.Error(errors.NewNotFoundError(err.Error()))
+			return
+		}
+		c.Error(errors.NewInternalServerError("Failed to get index migration: " + err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    migration,
+	})
+}
+
+// ResumeIndexMigration godoc
+// @Summary      恢复索引迁移
+// @Description  重新执行失败的索引迁移任务，从已保存的进度继续
+// @Tags         租户管理
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "迁移任务ID"
+// @Success      200  {object}  map[string]interface{}  "迁移任务"
+// @Failure      400  {object}  errors.AppError         "迁移任务不可恢复"
+// @Failure      404  {object}  errors.AppError         "迁移任务不存在"
+// @Failure      409  {object}  errors.AppError         "已有进行中的迁移"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /tenants/index-migrations/{id}/resume [post]
+func (h *IndexMigrationHandler) ResumeIndexMigration(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := secutils.SanitizeForLog(c.Param("id"))
+
+	migration, err := h.service.ResumeMigration(ctx, id)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"migration_id": id})
+		switch {
+		case stderrors.Is(err, service.ErrIndexMigrationNotFound):
+			c.Error(errors.NewNotFoundError(err.Error()))
+		case stderrors.Is(err, service.ErrIndexMigrationNotResumable):
+			c.Error(errors.NewBadRequestError(err.Error()))
+		case stderrors.Is(err, service.ErrIndexMigrationActive):
+			c.Error(errors.NewConflictError(err.Error()))
+		default:
+			c.Error(errors.NewInternalServerError("Failed to resume index migration: " + err.Error()))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    migration,
+	})
+}
@@ -643,11 +643,17 @@ func (h *KnowledgeHandler) DeleteKnowledge(c *gin.Context) {
 		return
 	}
 
-	_, effCtx, err := h.resolveKnowledgeAndValidateKBAccess(c, id, types.OrgRoleEditor)
+	knowledge, effCtx, err := h.resolveKnowledgeAndValidateKBAccess(c, id, types.OrgRoleEditor)
 	if err != nil {
 		c.Error(err)
 		return
 	}
+	setAuditBefore(c, types.AuditChange{
+		"knowledge_base_id": knowledge.KnowledgeBaseID,
+		"type":              knowledge.Type,
+		"title":             knowledge.Title,
+		"file_name":         knowledge.FileName,
+	})
 	logger.Infof(ctx, "Deleting knowledge, ID: %s", secutils.SanitizeForLog(id))
 	err = h.kgService.DeleteKnowledge(effCtx, id)
 	if err != nil {
@@ -480,6 +480,7 @@ func (h *KnowledgeBaseHandler) DeleteKnowledgeBase(c *gin.Context) {
 		return
 	}
 
+	setAuditBefore(c, types.AuditChange{"name": kb.Name, "type": kb.Type})
 	logger.Infof(ctx, "Deleting knowledge base, ID: %s, name: %s",
 		secutils.SanitizeForLog(id), secutils.SanitizeForLog(kb.Name))
 
@@ -0,0 +1,220 @@
+package handler
+
+import (
+	stderrors "errors"
+	"fmt"
+	"html"
+	"html/template"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/mcp"
+	"github.com/Tencent/WeKnora/internal/types"
+	secutils "github.com/Tencent/WeKnora/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// MCPOAuthCallbackPath is the public callback route of the MCP OAuth authorization flow
+const MCPOAuthCallbackPath = "/api/v1/mcp-oauth/callback"
+
+// errMCPOAuthRedirectURLNotSet is returned when the OAuth callback URL is not configured
+var errMCPOAuthRedirectURLNotSet = stderrors.New("MCP_OAUTH_REDIRECT_URL is not set")
+
+// mcpOAuthRedirectURI returns the callback URL registered with the authorization server.
+// It is read from MCP_OAUTH_REDIRECT_URL only: deriving it from the request would let a client
+// choose, through Host or X-Forwarded-* headers, where the authorization code is sent.
+func mcpOAuthRedirectURI() (string, error) {
+	raw := strings.TrimSpace(os.Getenv("MCP_OAUTH_REDIRECT_URL"))
+	if raw == "" {
+		return "", errMCPOAuthRedirectURLNotSet
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("MCP_OAUTH_REDIRECT_URL is not an absolute http(s) URL: %s", raw)
+	}
+	return raw, nil
+}
+
+// StartMCPServiceOAuth godoc
+// @Summary      发起MCP服务OAuth授权
+// @Description  发现授权服务器、按需动态注册客户端，并返回带PKCE参数的授权地址（需配置MCP_OAUTH_REDIRECT_URL）
+// @Tags         MCP服务
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "MCP服务ID"
+// @Success      200  {object}  map[string]interface{}  "授权地址"
+// @Failure      400  {object}  errors.AppError         "请求参数错误或未配置回调地址"
+// @Failure      500  {object}  errors.AppError         "服务器错误"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /mcp-services/{id}/oauth/authorize [post]
+func (h *MCPServiceHandler) StartMCPServiceOAuth(c *gin.Context) {
+	ctx := c.Request.Context()
+	serviceID := secutils.SanitizeForLog(c.Param("id"))
+
+	tenantID := c.GetUint64(types.TenantIDContextKey.String())
+	if tenantID == 0 {
+		logger.Error(ctx, "Tenant ID is empty")
+		c.Error(errors.NewBadRequestError("Tenant ID cannot be empty"))
+		return
+	}
+
+	redirectURI, err := mcpOAuthRedirectURI()
+	if err != nil {
+		logger.Error(ctx, "Failed to resolve MCP OAuth callback URL", err)
+		c.Error(errors.NewBadRequestError("OAuth callback URL is not configured: " + err.Error()))
+		return
+	}
+
+	authorization, err := h.mcpServiceService.StartMCPServiceOAuth(ctx, tenantID, serviceID, redirectURI)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"service_id": serviceID})
+		if stderrors.Is(err, mcp.ErrOAuthNotEnabled) {
+			c.Error(errors.NewBadRequestError(err.Error()))
+			return
+		}
+		c.Error(errors.NewInternalServerError("Failed to start OAuth authorization: " + err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    authorization,
+	})
+}
+
+// GetMCPServiceOAuthStatus godoc
+// @Summary      获取MCP服务OAuth授权状态
+// @Description  获取MCP服务的OAuth授权状态（是否已授权、过期时间等）
+// @Tags         MCP服务
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "MCP服务ID"
+// @Success      200  {object}  map[string]interface{}  "授权状态"
+// @Failure      500  {object}  errors.AppError         "服务器错误"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /mcp-services/{id}/oauth [get]
+func (h *MCPServiceHandler) GetMCPServiceOAuthStatus(c *gin.Context) {
+	ctx := c.Request.Context()
+	serviceID := secutils.SanitizeForLog(c.Param("id"))
+
+	tenantID := c.GetUint64(types.TenantIDContextKey.String())
+	if tenantID == 0 {
+		logger.Error(ctx, "Tenant ID is empty")
+		c.Error(errors.NewBadRequestError("Tenant ID cannot be empty"))
+		return
+	}
+
+	status, err := h.mcpServiceService.GetMCPServiceOAuthStatus(ctx, tenantID, serviceID)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"service_id": serviceID})
+		c.Error(errors.NewInternalServerError("Failed to get OAuth status: " + err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    status,
+	})
+}
+
+// RevokeMCPServiceOAuth godoc
+// @Summary      撤销MCP服务OAuth授权
+// @Description  删除MCP服务已保存的OAuth令牌并断开连接
+// @Tags         MCP服务
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "MCP服务ID"
+// @Success      200  {object}  map[string]interface{}  "撤销成功"
+// @Failure      500  {object}  errors.AppError         "服务器错误"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /mcp-services/{id}/oauth [delete]
+func (h *MCPServiceHandler) RevokeMCPServiceOAuth(c *gin.Context) {
+	ctx := c.Request.Context()
+	serviceID := secutils.SanitizeForLog(c.Param("id"))
+
+	tenantID := c.GetUint64(types.TenantIDContextKey.String())
+	if tenantID == 0 {
+		logger.Error(ctx, "Tenant ID is empty")
+		c.Error(errors.NewBadRequestError("Tenant ID cannot be empty"))
+		return
+	}
+
+	if err := h.mcpServiceService.RevokeMCPServiceOAuth(ctx, tenantID, serviceID); err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"service_id": serviceID})
+		c.Error(errors.NewInternalServerError("Failed to revoke OAuth authorization: " + err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "OAuth authorization revoked",
+	})
+}
+
+// MCPOAuthCallback godoc
+// @Summary      MCP服务OAuth回调
+// @Description  授权服务器重定向回调地址，使用授权码换取令牌并加密保存（无需认证，依赖state校验）
+// @Tags         MCP服务
+// @Produce      html
+// @Param        code   query     string  false  "授权码"
+// @Param        state  query     string  true   "授权请求state"
+// @Param        error  query     string  false  "授权错误"
+// @Success      200    {string}  string  "授权结果页面"
+// @Router       /mcp-oauth/callback [get]
+func (h *MCPServiceHandler) MCPOAuthCallback(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	if authErr := c.Query("error"); authErr != "" {
+		description := c.Query("error_description")
+		logger.Warnf(ctx, "MCP OAuth authorization denied: %s %s",
+			secutils.SanitizeForLog(authErr), secutils.SanitizeForLog(description))
+		renderMCPOAuthResult(c, http.StatusBadRequest, "", false, "Authorization failed: "+authErr+" "+description)
+		return
+	}
+
+	state := c.Query("state")
+	code := c.Query("code")
+	if state == "" || code == "" {
+		renderMCPOAuthResult(c, http.StatusBadRequest, "", false, "Missing code or state parameter")
+		return
+	}
+
+	service, err := h.mcpServiceService.CompleteMCPServiceOAuth(ctx, state, code)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		status := http.StatusInternalServerError
+		if stderrors.Is(err, mcp.ErrOAuthInvalidState) || stderrors.Is(err, mcp.ErrOAuthStateExpired) {
+			status = http.StatusBadRequest
+		}
+		renderMCPOAuthResult(c, status, "", false, "Authorization failed: "+err.Error())
+		return
+	}
+
+	renderMCPOAuthResult(c, http.StatusOK, service.ID, true,
+		fmt.Sprintf("MCP service \"%s\" has been authorized. You can close this window.", service.Name))
+}
+
+// renderMCPOAuthResult renders the callback page and notifies the opener window
+func renderMCPOAuthResult(c *gin.Context, status int, serviceID string, success bool, message string) {
+	page := fmt.Sprintf(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>MCP OAuth</title></head>
+<body>
+<p>%s</p>
+<script>
+if (window.opener) {
+  window.opener.postMessage({type: "mcp-oauth", success: %t, service_id: "%s"}, "*");
+  if (%t) { setTimeout(function () { window.close(); }, 1500); }
+}
+</script>
+</body>
+</html>`, html.EscapeString(message), success, template.JSEscapeString(serviceID), success)
+	c.Data(status, "text/html; charset=utf-8", []byte(page))
+}
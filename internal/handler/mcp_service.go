@@ -290,6 +290,14 @@ func (h *MCPServiceHandler) DeleteMCPService(c *gin.Context) {
 		return
 	}
 
+	if svc, err := h.mcpServiceService.GetMCPServiceByID(ctx, tenantID, serviceID); err == nil && svc != nil {
+		setAuditBefore(c, types.AuditChange{
+			"name":           svc.Name,
+			"transport_type": string(svc.TransportType),
+			"enabled":        svc.Enabled,
+		})
+	}
+
 	if err := h.mcpServiceService.DeleteMCPService(ctx, tenantID, serviceID); err != nil {
 		logger.ErrorWithFields(ctx, err, map[string]interface{}{"service_id": secutils.SanitizeForLog(serviceID)})
 		c.Error(errors.NewInternalServerError("Failed to delete MCP service: " + err.Error()))
@@ -419,3 +427,96 @@ func (h *MCPServiceHandler) GetMCPServiceResources(c *gin.Context) {
 		"data":    resources,
 	})
 }
+
+// GetMCPServicePrompts godoc
+// @Summary      获取MCP服务提示词列表
+// @Description  获取MCP服务提供的提示词模板列表
+// @Tags         MCP服务
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "MCP服务ID"
+// @Success      200  {object}  map[string]interface{}  "提示词列表"
+// @Failure      500  {object}  errors.AppError         "服务器错误"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /mcp-services/{id}/prompts [get]
+func (h *MCPServiceHandler) GetMCPServicePrompts(c *gin.Context) {
+	ctx := c.Request.Context()
+	serviceID := secutils.SanitizeForLog(c.Param("id"))
+
+	tenantID := c.GetUint64(types.TenantIDContextKey.String())
+	if tenantID == 0 {
+		logger.Error(ctx, "Tenant ID is empty")
+		c.Error(errors.NewBadRequestError("Tenant ID cannot be empty"))
+		return
+	}
+
+	prompts, err := h.mcpServiceService.GetMCPServicePrompts(ctx, tenantID, serviceID)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"service_id": secutils.SanitizeForLog(serviceID)})
+		c.Error(errors.NewInternalServerError("Failed to get MCP service prompts: " + err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    prompts,
+	})
+}
+
+// GetMCPServicePromptRequest is the request body for rendering an MCP prompt
+type GetMCPServicePromptRequest struct {
+	Arguments map[string]string `json:"arguments"`
+}
+
+// GetMCPServicePrompt godoc
+// @Summary      获取MCP服务提示词内容
+// @Description  使用给定参数渲染MCP服务提供的提示词模板
+// @Tags         MCP服务
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                      true   "MCP服务ID"
+// @Param        name     path      string                      true   "提示词名称"
+// @Param        request  body      GetMCPServicePromptRequest  false  "提示词参数"
+// @Success      200      {object}  map[string]interface{}      "渲染后的提示词"
+// @Failure      400      {object}  errors.AppError             "请求参数错误"
+// @Failure      500      {object}  errors.AppError             "服务器错误"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /mcp-services/{id}/prompts/{name} [post]
+func (h *MCPServiceHandler) GetMCPServicePrompt(c *gin.Context) {
+	ctx := c.Request.Context()
+	serviceID := secutils.SanitizeForLog(c.Param("id"))
+	promptName := c.Param("name")
+
+	tenantID := c.GetUint64(types.TenantIDContextKey.String())
+	if tenantID == 0 {
+		logger.Error(ctx, "Tenant ID is empty")
+		c.Error(errors.NewBadRequestError("Tenant ID cannot be empty"))
+		return
+	}
+
+	var req GetMCPServicePromptRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			logger.Error(ctx, "Failed to parse MCP prompt request", err)
+			c.Error(errors.NewBadRequestError(err.Error()))
+			return
+		}
+	}
+
+	result, err := h.mcpServiceService.GetMCPServicePrompt(ctx, tenantID, serviceID, promptName, req.Arguments)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"service_id":  secutils.SanitizeForLog(serviceID),
+			"prompt_name": secutils.SanitizeForLog(promptName),
+		})
+		c.Error(errors.NewInternalServerError("Failed to get MCP service prompt: " + err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+	})
+}
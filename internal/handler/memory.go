@@ -0,0 +1,121 @@
+package handler
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"github.com/Tencent/WeKnora/internal/application/service"
+	"github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	secutils "github.com/Tencent/WeKnora/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// MemoryHandler handles HTTP requests for the long-term memory of the current user
+type MemoryHandler struct {
+	service interfaces.UserMemoryService
+}
+
+// NewMemoryHandler creates a new memory handler
+func NewMemoryHandler(service interfaces.UserMemoryService) *MemoryHandler {
+	return &MemoryHandler{service: service}
+}
+
+// ListMemories godoc
+// @Summary      获取我的长期记忆
+// @Description  分页获取智能体为当前用户保存的长期记忆，按更新时间倒序。仅支持用户登录态
+// @Tags         长期记忆
+// @Produce      json
+// @Param        page       query     int  false  "页码"
+// @Param        page_size  query     int  false  "每页数量"
+// @Success      200        {object}  map[string]interface{}  "记忆列表"
+// @Failure      403        {object}  errors.AppError         "未以用户身份登录"
+// @Security     Bearer
+// @Router       /memories [get]
+func (h *MemoryHandler) ListMemories(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var page types.Pagination
+	if err := c.ShouldBindQuery(&page); err != nil {
+		logger.Error(ctx, "Failed to parse pagination parameters", err)
+		c.Error(errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	result, err := h.service.ListMemories(ctx, &page)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		h.handleError(c, err, "Failed to list memories: ")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+	})
+}
+
+// DeleteMemory godoc
+// @Summary      删除一条长期记忆
+// @Description  删除当前用户的一条长期记忆，删除后智能体不再记得该内容
+// @Tags         长期记忆
+// @Produce      json
+// @Param        id   path      string  true  "记忆ID"
+// @Success      200  {object}  map[string]interface{}  "删除成功"
+// @Failure      404  {object}  errors.AppError         "记忆不存在"
+// @Security     Bearer
+// @Router       /memories/{id} [delete]
+func (h *MemoryHandler) DeleteMemory(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := secutils.SanitizeForLog(c.Param("id"))
+
+	if err := h.service.DeleteMemory(ctx, id); err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"memory_id": id})
+		h.handleError(c, err, "Failed to delete memory: ")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Memory deleted successfully",
+	})
+}
+
+// ClearMemories godoc
+// @Summary      清空我的长期记忆
+// @Description  删除当前用户的全部长期记忆
+// @Tags         长期记忆
+// @Produce      json
+// @Success      200  {object}  map[string]interface{}  "删除的记忆数量"
+// @Failure      403  {object}  errors.AppError         "未以用户身份登录"
+// @Security     Bearer
+// @Router       /memories [delete]
+func (h *MemoryHandler) ClearMemories(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	deleted, err := h.service.ClearMemories(ctx)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		h.handleError(c, err, "Failed to clear memories: ")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    gin.H{"deleted": deleted},
+	})
+}
+
+// handleError maps memory service errors to HTTP errors
+func (h *MemoryHandler) handleError(c *gin.Context, err error, message string) {
+	switch {
+	case stderrors.Is(err, service.ErrMemoryNotFound):
+		c.Error(errors.NewNotFoundError(err.Error()))
+	case stderrors.Is(err, service.ErrMemoryNoUser):
+		c.Error(errors.NewForbiddenError(err.Error()))
+	default:
+		c.Error(errors.NewInternalServerError(message + err.Error()))
+	}
+}
@@ -1,14 +1,17 @@
 package handler
 
 import (
+	stderrors "errors"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/Tencent/WeKnora/internal/application/service"
 	"github.com/Tencent/WeKnora/internal/errors"
 	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
 	"github.com/Tencent/WeKnora/internal/types/interfaces"
 	secutils "github.com/Tencent/WeKnora/internal/utils"
 )
@@ -156,3 +159,133 @@ func (h *MessageHandler) DeleteMessage(c *gin.Context) {
 		"message": "Message deleted successfully",
 	})
 }
+
+// SearchMessages godoc
+// @Summary      搜索历史对话
+// @Description  在当前租户的全部历史对话中搜索消息内容和会话标题，多个关键词以空格分隔且需同时出现。返回消息片段、高亮位置（按字符计）以及会话ID和消息ID，便于跳转
+// @Tags         消息
+// @Produce      json
+// @Param        q                  query     string  true   "搜索关键词"
+// @Param        agent_id           query     string  false  "只搜索向该智能体提问的消息"
+// @Param        knowledge_base_id  query     string  false  "只搜索引用了该知识库文档或@提及该知识库的消息"
+// @Param        start_time         query     string  false  "开始时间（RFC3339，包含）"
+// @Param        end_time           query     string  false  "结束时间（RFC3339，不包含）"
+// @Param        page               query     int     false  "页码"
+// @Param        page_size          query     int     false  "每页数量"
+// @Success      200                {object}  map[string]interface{}  "搜索结果"
+// @Failure      400                {object}  errors.AppError         "请求参数错误"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /messages/search [get]
+func (h *MessageHandler) SearchMessages(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var filter types.MessageSearchFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		logger.Error(ctx, "Failed to parse message search parameters", err)
+		c.Error(errors.NewBadRequestError(err.Error()))
+		return
+	}
+	var page types.Pagination
+	if err := c.ShouldBindQuery(&page); err != nil {
+		logger.Error(ctx, "Failed to parse pagination parameters", err)
+		c.Error(errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	result, err := h.MessageService.SearchMessages(ctx, &filter, &page)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		if stderrors.Is(err, service.ErrEmptySearchQuery) {
+			c.Error(errors.NewBadRequestError(err.Error()))
+			return
+		}
+		c.Error(errors.NewInternalServerError("Failed to search messages: " + err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+	})
+}
+
+// SwitchBranchRequest represents the request to switch the active branch of a session
+type SwitchBranchRequest struct {
+	MessageID string `json:"message_id" binding:"required"` // Any message on the branch to switch to
+}
+
+// ListBranches godoc
+// @Summary      获取会话分支
+// @Description  编辑历史提问或重新生成回答会产生新的对话分支。返回会话的所有分支（以分支末尾消息标识），按更新时间倒序
+// @Tags         消息
+// @Produce      json
+// @Param        session_id  path      string  true  "会话ID"
+// @Success      200         {object}  map[string]interface{}  "分支列表"
+// @Failure      404         {object}  errors.AppError         "会话不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /messages/{session_id}/branches [get]
+func (h *MessageHandler) ListBranches(c *gin.Context) {
+	ctx := c.Request.Context()
+	sessionID := secutils.SanitizeForLog(c.Param("session_id"))
+
+	branches, err := h.MessageService.ListBranches(ctx, sessionID)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"session_id": sessionID})
+		h.handleBranchError(c, err, "Failed to list branches: ")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    branches,
+	})
+}
+
+// SwitchBranch godoc
+// @Summary      切换会话分支
+// @Description  切换到包含指定消息的分支（有多个时取最近更新的一个），后续提问将在该分支上继续。返回切换后分支的全部消息
+// @Tags         消息
+// @Accept       json
+// @Produce      json
+// @Param        session_id  path      string               true  "会话ID"
+// @Param        request     body      SwitchBranchRequest  true  "分支上的任意消息ID"
+// @Success      200         {object}  map[string]interface{}  "分支消息列表"
+// @Failure      404         {object}  errors.AppError         "会话或消息不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /messages/{session_id}/branches/switch [post]
+func (h *MessageHandler) SwitchBranch(c *gin.Context) {
+	ctx := c.Request.Context()
+	sessionID := secutils.SanitizeForLog(c.Param("session_id"))
+
+	var request SwitchBranchRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Error(ctx, "Failed to parse request data", err)
+		c.Error(errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	messages, err := h.MessageService.SwitchBranch(ctx, sessionID, secutils.SanitizeForLog(request.MessageID))
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"session_id": sessionID})
+		h.handleBranchError(c, err, "Failed to switch branch: ")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    messages,
+	})
+}
+
+// handleBranchError maps branch errors to HTTP errors
+func (h *MessageHandler) handleBranchError(c *gin.Context, err error, message string) {
+	switch {
+	case stderrors.Is(err, errors.ErrSessionNotFound), stderrors.Is(err, service.ErrBranchMessageNotFound):
+		c.Error(errors.NewNotFoundError(err.Error()))
+	default:
+		c.Error(errors.NewInternalServerError(message + err.Error()))
+	}
+}
@@ -340,6 +340,10 @@ func (h *ModelHandler) DeleteModel(c *gin.Context) {
 		return
 	}
 
+	if model, err := h.service.GetModelByID(ctx, id); err == nil && model != nil {
+		setAuditBefore(c, types.AuditChange{"name": model.Name, "type": string(model.Type), "source": string(model.Source)})
+	}
+
 	logger.Infof(ctx, "Deleting model, ID: %s", id)
 	if err := h.service.DeleteModel(ctx, id); err != nil {
 		if err == service.ErrModelNotFound {
@@ -329,6 +329,10 @@ func (h *OrganizationHandler) DeleteOrganization(c *gin.Context) {
 	orgID := c.Param("id")
 	userID := c.GetString(types.UserIDContextKey.String())
 
+	if org, err := h.orgService.GetOrganization(ctx, orgID); err == nil && org != nil {
+		setAuditBefore(c, types.AuditChange{"name": org.Name})
+	}
+
 	if err := h.orgService.DeleteOrganization(ctx, orgID, userID); err != nil {
 		logger.Errorf(ctx, "Failed to delete organization: %v", err)
 		c.Error(apperrors.NewForbiddenError("Permission denied or organization not found"))
@@ -414,6 +418,10 @@ func (h *OrganizationHandler) UpdateMemberRole(c *gin.Context) {
 		return
 	}
 
+	if member, err := h.orgService.GetMember(ctx, orgID, memberUserID); err == nil && member != nil {
+		setAuditBefore(c, types.AuditChange{"user_id": member.UserID, "role": string(member.Role)})
+	}
+
 	if err := h.orgService.UpdateMemberRole(ctx, orgID, memberUserID, req.Role, operatorUserID); err != nil {
 		logger.Errorf(ctx, "Failed to update member role: %v", err)
 		c.Error(apperrors.NewForbiddenError("Permission denied or invalid operation"))
@@ -443,6 +451,10 @@ func (h *OrganizationHandler) RemoveMember(c *gin.Context) {
 	memberUserID := c.Param("user_id")
 	operatorUserID := c.GetString(types.UserIDContextKey.String())
 
+	if member, err := h.orgService.GetMember(ctx, orgID, memberUserID); err == nil && member != nil {
+		setAuditBefore(c, types.AuditChange{"user_id": member.UserID, "role": string(member.Role)})
+	}
+
 	if err := h.orgService.RemoveMember(ctx, orgID, memberUserID, operatorUserID); err != nil {
 		logger.Errorf(ctx, "Failed to remove member: %v", err)
 		c.Error(apperrors.NewForbiddenError("Permission denied or invalid operation"))
@@ -1097,6 +1109,8 @@ func (h *OrganizationHandler) UpdateSharePermission(c *gin.Context) {
 		return
 	}
 
+	h.setKBShareAuditBefore(c, shareID)
+
 	if err := h.shareService.UpdateSharePermission(ctx, shareID, req.Permission, userID); err != nil {
 		logger.Errorf(ctx, "Failed to update share permission: %v", err)
 		c.Error(apperrors.NewForbiddenError("Permission denied"))
@@ -1125,6 +1139,8 @@ func (h *OrganizationHandler) RemoveShare(c *gin.Context) {
 	shareID := c.Param("share_id")
 	userID := c.GetString(types.UserIDContextKey.String())
 
+	h.setKBShareAuditBefore(c, shareID)
+
 	if err := h.shareService.RemoveShare(ctx, shareID, userID); err != nil {
 		logger.Errorf(ctx, "Failed to remove share: %v", err)
 		c.Error(apperrors.NewForbiddenError("Permission denied"))
@@ -1137,6 +1153,19 @@ func (h *OrganizationHandler) RemoveShare(c *gin.Context) {
 	})
 }
 
+// setKBShareAuditBefore records the knowledge base share before it is changed, for the audit log
+func (h *OrganizationHandler) setKBShareAuditBefore(c *gin.Context, shareID string) {
+	share, err := h.shareService.GetShare(c.Request.Context(), shareID)
+	if err != nil || share == nil {
+		return
+	}
+	setAuditBefore(c, types.AuditChange{
+		"knowledge_base_id": share.KnowledgeBaseID,
+		"organization_id":   share.OrganizationID,
+		"permission":        string(share.Permission),
+	})
+}
+
 // ListOrgShares lists all knowledge bases shared to a specific organization
 // @Summary      获取组织的共享知识库列表
 // @Description  获取共享到指定组织的所有知识库
@@ -1286,7 +1315,7 @@ func (h *OrganizationHandler) ListAgentShares(c *gin.Context) {
 		resp := types.AgentShareResponse{
 			ID: s.ID, AgentID: s.AgentID, OrganizationID: s.OrganizationID,
 			SharedByUserID: s.SharedByUserID, SourceTenantID: s.SourceTenantID,
-			Permission: string(s.Permission), CreatedAt: s.CreatedAt,
+			Permission: string(s.Permission), PinnedVersion: s.PinnedVersion, CreatedAt: s.CreatedAt,
 		}
 		if s.Organization != nil {
 			resp.OrganizationName = s.Organization.Name
@@ -1296,11 +1325,59 @@ func (h *OrganizationHandler) ListAgentShares(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"success": true, "data": gin.H{"shares": response, "total": len(response)}})
 }
 
+// UpdateAgentShareRequest is the request body of UpdateAgentShare
+type UpdateAgentShareRequest struct {
+	// Version of the agent members of the organization use, 0 to follow the published version
+	PinnedVersion *int `json:"pinned_version" binding:"required"`
+}
+
+// UpdateAgentShare pins the version of a shared agent used by the organization
+func (h *OrganizationHandler) UpdateAgentShare(c *gin.Context) {
+	ctx := c.Request.Context()
+	agentID := c.Param("id")
+	shareID := c.Param("share_id")
+	userID := c.GetString(types.UserIDContextKey.String())
+	tenantID := c.GetUint64(types.TenantIDContextKey.String())
+
+	var req UpdateAgentShareRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperrors.NewValidationError("Invalid request parameters").WithDetails(err.Error()))
+		return
+	}
+	if share, err := h.agentShareService.GetShare(ctx, shareID); err == nil && share != nil {
+		setAuditBefore(c, types.AuditChange{"pinned_version": share.PinnedVersion})
+	}
+
+	share, err := h.agentShareService.PinShareVersion(ctx, agentID, shareID, userID, tenantID, *req.PinnedVersion)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to update agent share: %v", err)
+		switch {
+		case errors.Is(err, service.ErrAgentShareNotFound), errors.Is(err, service.ErrAgentVersionNotFound):
+			c.Error(apperrors.NewNotFoundError(err.Error()))
+		case errors.Is(err, service.ErrAgentVersionInvalid):
+			c.Error(apperrors.NewValidationError(err.Error()))
+		case errors.Is(err, service.ErrAgentSharePermission):
+			c.Error(apperrors.NewForbiddenError("Permission denied"))
+		default:
+			c.Error(apperrors.NewInternalServerError("Failed to update share"))
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": share})
+}
+
 // RemoveAgentShare removes an agent share
 func (h *OrganizationHandler) RemoveAgentShare(c *gin.Context) {
 	ctx := c.Request.Context()
 	shareID := c.Param("share_id")
 	userID := c.GetString(types.UserIDContextKey.String())
+	if share, err := h.agentShareService.GetShare(ctx, shareID); err == nil && share != nil {
+		setAuditBefore(c, types.AuditChange{
+			"agent_id":        share.AgentID,
+			"organization_id": share.OrganizationID,
+			"permission":      string(share.Permission),
+		})
+	}
 	if err := h.agentShareService.RemoveShare(ctx, shareID, userID); err != nil {
 		logger.Errorf(ctx, "Failed to remove agent share: %v", err)
 		c.Error(apperrors.NewForbiddenError("Permission denied"))
@@ -1335,7 +1412,7 @@ func (h *OrganizationHandler) ListOrgAgentShares(c *gin.Context) {
 		resp := types.AgentShareResponse{
 			ID: s.ID, AgentID: s.AgentID, OrganizationID: s.OrganizationID,
 			SharedByUserID: s.SharedByUserID, SourceTenantID: s.SourceTenantID,
-			Permission: string(s.Permission), MyRoleInOrg: string(myRoleInOrg), MyPermission: string(effectivePerm), CreatedAt: s.CreatedAt,
+			Permission: string(s.Permission), PinnedVersion: s.PinnedVersion, MyRoleInOrg: string(myRoleInOrg), MyPermission: string(effectivePerm), CreatedAt: s.CreatedAt,
 		}
 		if s.Agent != nil {
 			resp.AgentName = s.Agent.Name
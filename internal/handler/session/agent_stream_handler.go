@@ -60,9 +60,14 @@ func (h *AgentStreamHandler) Subscribe() {
 	h.eventBus.On(event.EventAgentThought, h.handleThought)
 	h.eventBus.On(event.EventAgentToolCall, h.handleToolCall)
 	h.eventBus.On(event.EventAgentToolResult, h.handleToolResult)
+	h.eventBus.On(event.EventAgentToolApproval, h.handleToolApproval)
+	h.eventBus.On(event.EventAgentToolApprovalResolved, h.handleToolApproval)
 	h.eventBus.On(event.EventAgentReferences, h.handleReferences)
 	h.eventBus.On(event.EventAgentFinalAnswer, h.handleFinalAnswer)
+	h.eventBus.On(event.EventAgentSubAgentAnswer, h.handleSubAgentAnswer)
 	h.eventBus.On(event.EventAgentReflection, h.handleReflection)
+	h.eventBus.On(event.EventWorkflowNode, h.handleWorkflowNode)
+	h.eventBus.On(event.EventStructuredOutput, h.handleStructuredOutput)
 	h.eventBus.On(event.EventError, h.handleError)
 	h.eventBus.On(event.EventSessionTitle, h.handleSessionTitle)
 	h.eventBus.On(event.EventAgentComplete, h.handleComplete)
@@ -108,7 +113,7 @@ func (h *AgentStreamHandler) handleThought(ctx context.Context, evt event.Event)
 		Content:   data.Content, // Just this chunk
 		Done:      data.Done,
 		Timestamp: time.Now(),
-		Data:      metadata,
+		Data:      addSubAgentMetadata(evt, metadata),
 	}); err != nil {
 		logger.GetLogger(h.ctx).Error("Append thought event to stream failed", "error", err)
 	}
@@ -141,7 +146,7 @@ func (h *AgentStreamHandler) handleToolCall(ctx context.Context, evt event.Event
 		Content:   fmt.Sprintf("Calling tool: %s", data.ToolName),
 		Done:      false,
 		Timestamp: time.Now(),
-		Data:      metadata,
+		Data:      addSubAgentMetadata(evt, metadata),
 	}); err != nil {
 		logger.GetLogger(h.ctx).Error("Append tool call event to stream failed", "error", err)
 	}
@@ -202,7 +207,7 @@ func (h *AgentStreamHandler) handleToolResult(ctx context.Context, evt event.Eve
 		Content:   content,
 		Done:      false,
 		Timestamp: time.Now(),
-		Data:      metadata,
+		Data:      addSubAgentMetadata(evt, metadata),
 	}); err != nil {
 		logger.GetLogger(h.ctx).Error("Append tool result event to stream failed", "error", err)
 	}
@@ -210,6 +215,42 @@ func (h *AgentStreamHandler) handleToolResult(ctx context.Context, evt event.Eve
 	return nil
 }
 
+// handleToolApproval handles tool approval request and resolution events
+// The frontend shows approve/deny controls while the event is not done
+func (h *AgentStreamHandler) handleToolApproval(ctx context.Context, evt event.Event) error {
+	data, ok := evt.Data.(event.AgentToolApprovalData)
+	if !ok {
+		return nil
+	}
+
+	content := fmt.Sprintf("Waiting for approval: %s", data.ToolName)
+	if evt.Type == event.EventAgentToolApprovalResolved {
+		content = fmt.Sprintf("Tool call %s: %s", data.Status, data.ToolName)
+	}
+
+	if err := h.streamManager.AppendEvent(h.ctx, h.sessionID, h.assistantMessageID, interfaces.StreamEvent{
+		ID:        evt.ID,
+		Type:      types.ResponseTypeToolApproval,
+		Content:   content,
+		Done:      evt.Type == event.EventAgentToolApprovalResolved,
+		Timestamp: time.Now(),
+		Data: addSubAgentMetadata(evt, map[string]interface{}{
+			"approval_id":    data.ApprovalID,
+			"tool_call_id":   data.ToolCallID,
+			"tool_name":      data.ToolName,
+			"arguments":      data.Arguments,
+			"status":         data.Status,
+			"reason":         data.Reason,
+			"timeout_action": data.TimeoutAction,
+			"expires_at":     data.ExpiresAt,
+		}),
+	}); err != nil {
+		logger.GetLogger(h.ctx).Error("Append tool approval event to stream failed", "error", err)
+	}
+
+	return nil
+}
+
 // handleReferences handles knowledge references events
 func (h *AgentStreamHandler) handleReferences(ctx context.Context, evt event.Event) error {
 	data, ok := evt.Data.(event.AgentReferencesData)
@@ -324,6 +365,44 @@ func (h *AgentStreamHandler) handleFinalAnswer(ctx context.Context, evt event.Ev
 	return nil
 }
 
+// handleSubAgentAnswer handles final answer events of delegated sub-agent runs.
+// The answer is returned to the delegating agent as a tool result, so it is streamed
+// for display but not added to the assistant message.
+func (h *AgentStreamHandler) handleSubAgentAnswer(ctx context.Context, evt event.Event) error {
+	data, ok := evt.Data.(event.AgentFinalAnswerData)
+	if !ok {
+		return nil
+	}
+
+	if err := h.streamManager.AppendEvent(h.ctx, h.sessionID, h.assistantMessageID, interfaces.StreamEvent{
+		ID:        evt.ID,
+		Type:      types.ResponseTypeSubAgentAnswer,
+		Content:   data.Content, // Just this chunk
+		Done:      data.Done,
+		Timestamp: time.Now(),
+		Data:      addSubAgentMetadata(evt, map[string]interface{}{"event_id": evt.ID}),
+	}); err != nil {
+		logger.GetLogger(h.ctx).Error("Append sub-agent answer event to stream failed", "error", err)
+	}
+
+	return nil
+}
+
+// addSubAgentMetadata copies the tags of an event forwarded from a sub-agent run into the stream data,
+// so that clients can nest the event under the delegating tool call
+func addSubAgentMetadata(evt event.Event, data map[string]interface{}) map[string]interface{} {
+	if _, ok := evt.Metadata[event.MetadataParentToolCallID]; !ok {
+		return data
+	}
+	if data == nil {
+		data = make(map[string]interface{}, len(evt.Metadata))
+	}
+	for key, value := range evt.Metadata {
+		data[key] = value
+	}
+	return data
+}
+
 // handleReflection handles agent reflection events
 func (h *AgentStreamHandler) handleReflection(ctx context.Context, evt event.Event) error {
 	data, ok := evt.Data.(event.AgentReflectionData)
@@ -338,6 +417,7 @@ func (h *AgentStreamHandler) handleReflection(ctx context.Context, evt event.Eve
 		Content:   data.Content, // Just this chunk
 		Done:      data.Done,
 		Timestamp: time.Now(),
+		Data:      addSubAgentMetadata(evt, nil),
 	}); err != nil {
 		logger.GetLogger(h.ctx).Error("Append reflection event to stream failed", "error", err)
 	}
@@ -345,6 +425,66 @@ func (h *AgentStreamHandler) handleReflection(ctx context.Context, evt event.Eve
 	return nil
 }
 
+// handleWorkflowNode handles workflow node events.
+// The start and the outcome of a node share the event ID, the outcome is the done event.
+func (h *AgentStreamHandler) handleWorkflowNode(ctx context.Context, evt event.Event) error {
+	data, ok := evt.Data.(event.WorkflowNodeData)
+	if !ok {
+		return nil
+	}
+
+	content := data.Output
+	if data.Status == event.WorkflowNodeFailed {
+		content = data.Error
+	}
+	if err := h.streamManager.AppendEvent(h.ctx, h.sessionID, h.assistantMessageID, interfaces.StreamEvent{
+		ID:        evt.ID,
+		Type:      types.ResponseTypeWorkflowNode,
+		Content:   content,
+		Done:      data.Status != event.WorkflowNodeRunning,
+		Timestamp: time.Now(),
+		Data: map[string]interface{}{
+			"node_id":     data.NodeID,
+			"node_type":   data.NodeType,
+			"node_name":   data.NodeName,
+			"status":      data.Status,
+			"branch":      data.Branch,
+			"error":       data.Error,
+			"duration_ms": data.Duration,
+			"step":        data.Step,
+			"data":        data.Data,
+		},
+	}); err != nil {
+		logger.GetLogger(h.ctx).Error("Append workflow node event to stream failed", "error", err)
+	}
+
+	return nil
+}
+
+// handleStructuredOutput handles structured output events
+func (h *AgentStreamHandler) handleStructuredOutput(ctx context.Context, evt event.Event) error {
+	data, ok := evt.Data.(event.StructuredOutputData)
+	if !ok {
+		return nil
+	}
+
+	if err := h.streamManager.AppendEvent(h.ctx, h.sessionID, h.assistantMessageID, interfaces.StreamEvent{
+		ID:        evt.ID,
+		Type:      types.ResponseTypeStructuredOutput,
+		Content:   data.Raw,
+		Done:      true,
+		Timestamp: time.Now(),
+		Data: map[string]interface{}{
+			"output": data.Output,
+			"error":  data.Error,
+		},
+	}); err != nil {
+		logger.GetLogger(h.ctx).Error("Append structured output event to stream failed", "error", err)
+	}
+
+	return nil
+}
+
 // handleError handles error events
 func (h *AgentStreamHandler) handleError(ctx context.Context, evt event.Event) error {
 	data, ok := evt.Data.(event.ErrorData)
@@ -435,6 +575,17 @@ func (h *AgentStreamHandler) handleComplete(ctx context.Context, evt event.Event
 		}
 	}
 
+	completeData := map[string]interface{}{
+		"total_steps":       data.TotalSteps,
+		"total_duration_ms": data.TotalDurationMs,
+	}
+	// Sentences of the answer mapped to the references they cite
+	if data.Citations != nil {
+		completeData["citations"] = data.Citations.Sentences
+		completeData["unsupported_claims"] = data.Citations.UnsupportedClaims
+		completeData["citation_check"] = data.Citations.Check
+	}
+
 	// Send completion event to stream manager so SSE can detect completion
 	if err := h.streamManager.AppendEvent(h.ctx, h.sessionID, h.assistantMessageID, interfaces.StreamEvent{
 		ID:        evt.ID,
@@ -442,10 +593,7 @@ func (h *AgentStreamHandler) handleComplete(ctx context.Context, evt event.Event
 		Content:   "",
 		Done:      true,
 		Timestamp: time.Now(),
-		Data: map[string]interface{}{
-			"total_steps":       data.TotalSteps,
-			"total_duration_ms": data.TotalDurationMs,
-		},
+		Data:      completeData,
 	}); err != nil {
 		logger.GetLogger(h.ctx).Errorf("Append complete event to stream failed: %v", err)
 	}
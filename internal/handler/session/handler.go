@@ -22,6 +22,7 @@ type Handler struct {
 	customAgentService   interfaces.CustomAgentService   // Service for managing custom agents
 	tenantService        interfaces.TenantService        // Service for loading tenant (shared agent context)
 	agentShareService    interfaces.AgentShareService    // Service for resolving shared agents (KB scope in retrieval)
+	toolApprovalService  interfaces.ToolApprovalService  // Service for human-in-the-loop tool approval
 }
 
 // NewHandler creates a new instance of Handler with all necessary dependencies
@@ -34,6 +35,7 @@ func NewHandler(
 	customAgentService interfaces.CustomAgentService,
 	tenantService interfaces.TenantService,
 	agentShareService interfaces.AgentShareService,
+	toolApprovalService interfaces.ToolApprovalService,
 ) *Handler {
 	return &Handler{
 		sessionService:       sessionService,
@@ -44,6 +46,7 @@ func NewHandler(
 		customAgentService:   customAgentService,
 		tenantService:        tenantService,
 		agentShareService:    agentShareService,
+		toolApprovalService:  toolApprovalService,
 	}
 }
 
@@ -122,12 +122,13 @@ func createAgentQueryEvent(sessionID, assistantMessageID string) interfaces.Stre
 }
 
 // createUserMessage creates a user message
-func (h *Handler) createUserMessage(ctx context.Context, sessionID, query, requestID string, mentionedItems types.MentionedItems) error {
+func (h *Handler) createUserMessage(ctx context.Context, sessionID, query, requestID, agentID string, mentionedItems types.MentionedItems) error {
 	_, err := h.messageService.CreateMessage(ctx, &types.Message{
 		SessionID:      sessionID,
 		Role:           "user",
 		Content:        query,
 		RequestID:      requestID,
+		AgentID:        agentID,
 		CreatedAt:      time.Now(),
 		IsCompleted:    true,
 		MentionedItems: mentionedItems,
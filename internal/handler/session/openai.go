@@ -0,0 +1,721 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/event"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	secutils "github.com/Tencent/WeKnora/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// openAIKBModelPrefix marks a model name that addresses a knowledge base instead of a custom agent,
+// e.g. "kb:3f2a..." answers with the built-in quick-answer agent scoped to that knowledge base
+const openAIKBModelPrefix = "kb:"
+
+// openAIOwner is reported as owned_by for every model exposed through the compatible API
+const openAIOwner = "weknora"
+
+// OpenAIChatMessage is a single message of an OpenAI chat completion request
+// Content is either a plain string or an array of content parts; only text parts are used
+type OpenAIChatMessage struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
+}
+
+// OpenAIChatCompletionRequest is the OpenAI-compatible chat completion request
+type OpenAIChatCompletionRequest struct {
+	Model    string              `json:"model"    binding:"required"` // Custom agent ID, or "kb:<knowledge_base_id>"
+	Messages []OpenAIChatMessage `json:"messages" binding:"required"`
+	Stream   bool                `json:"stream"`
+	User     string              `json:"user"`
+	// Requests a JSON answer, validated against the schema for "json_schema"
+	ResponseFormat *OpenAIResponseFormat `json:"response_format"`
+
+	// Extension: continue an existing session instead of creating a new one
+	SessionID string `json:"session_id"`
+	// Extension: enable web search for this request
+	WebSearchEnabled bool `json:"web_search_enabled"`
+}
+
+// OpenAIResponseFormat is the response_format of an OpenAI chat completion request
+type OpenAIResponseFormat struct {
+	Type       string            `json:"type"` // "text", "json_object" or "json_schema"
+	JSONSchema *OpenAIJSONSchema `json:"json_schema"`
+}
+
+// OpenAIJSONSchema is the schema of a "json_schema" response format
+type OpenAIJSONSchema struct {
+	Name   string             `json:"name"`
+	Schema types.OutputSchema `json:"schema"`
+	Strict bool               `json:"strict"`
+}
+
+// outputSchema returns the output schema requested by the response format, nil for plain text
+func (f *OpenAIResponseFormat) outputSchema() (types.OutputSchema, error) {
+	if f == nil {
+		return nil, nil
+	}
+	switch f.Type {
+	case "", "text":
+		return nil, nil
+	case "json_object":
+		return types.OutputSchema{"type": "object"}, nil
+	case "json_schema":
+		if f.JSONSchema == nil || len(f.JSONSchema.Schema) == 0 {
+			return nil, fmt.Errorf("response_format.json_schema.schema is required")
+		}
+		if _, err := f.JSONSchema.Schema.Compile(); err != nil {
+			return nil, err
+		}
+		return f.JSONSchema.Schema, nil
+	default:
+		return nil, fmt.Errorf("unsupported response_format type %q", f.Type)
+	}
+}
+
+// OpenAIChatCompletionDelta is the incremental message content of a streaming chunk
+type OpenAIChatCompletionDelta struct {
+	Role             string `json:"role,omitempty"`
+	Content          string `json:"content,omitempty"`
+	ReasoningContent string `json:"reasoning_content,omitempty"`
+}
+
+// OpenAIChatCompletionChunkChoice is a choice of a streaming chunk
+type OpenAIChatCompletionChunkChoice struct {
+	Index        int                       `json:"index"`
+	Delta        OpenAIChatCompletionDelta `json:"delta"`
+	FinishReason *string                   `json:"finish_reason"`
+}
+
+// OpenAIChatCompletionChunk is a single "chat.completion.chunk" SSE payload
+type OpenAIChatCompletionChunk struct {
+	ID      string                            `json:"id"`
+	Object  string                            `json:"object"`
+	Created int64                             `json:"created"`
+	Model   string                            `json:"model"`
+	Choices []OpenAIChatCompletionChunkChoice `json:"choices"`
+
+	// Extension fields
+	SessionID  string           `json:"session_id,omitempty"`
+	References types.References `json:"references,omitempty"`
+}
+
+// OpenAIChatCompletionMessage is the assistant message of a non-streaming completion
+type OpenAIChatCompletionMessage struct {
+	Role             string `json:"role"`
+	Content          string `json:"content"`
+	ReasoningContent string `json:"reasoning_content,omitempty"`
+}
+
+// OpenAIChatCompletionChoice is a choice of a non-streaming completion
+type OpenAIChatCompletionChoice struct {
+	Index        int                         `json:"index"`
+	Message      OpenAIChatCompletionMessage `json:"message"`
+	FinishReason string                      `json:"finish_reason"`
+}
+
+// OpenAIChatCompletion is the "chat.completion" response for non-streaming requests
+type OpenAIChatCompletion struct {
+	ID      string                       `json:"id"`
+	Object  string                       `json:"object"`
+	Created int64                        `json:"created"`
+	Model   string                       `json:"model"`
+	Choices []OpenAIChatCompletionChoice `json:"choices"`
+
+	// Extension fields
+	SessionID  string           `json:"session_id"`
+	References types.References `json:"references"`
+}
+
+// OpenAIModel describes a model entry of the /v1/models endpoint
+type OpenAIModel struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Created int64  `json:"created"`
+	OwnedBy string `json:"owned_by"`
+	// Extension: human readable name of the agent or knowledge base
+	Name string `json:"name"`
+}
+
+// openAICompletionEvent is a translated agent event forwarded to the HTTP writer
+type openAICompletionEvent struct {
+	content    string
+	reasoning  string
+	references types.References
+	err        string
+	done       bool
+}
+
+// openAICompletionCollector translates EventBus events into OpenAI deltas.
+// In agent mode every round streams its text as a thought; a round is only known to be
+// reasoning once it is followed by tool calls, so thought text is buffered until then.
+// With an output schema the answer text is reported as reasoning and the validated
+// JSON object becomes the content.
+type openAICompletionCollector struct {
+	mu         sync.Mutex
+	ctx        context.Context
+	events     chan openAICompletionEvent
+	thought    strings.Builder
+	references types.References
+	structured bool
+	finished   bool
+}
+
+func newOpenAICompletionCollector(ctx context.Context, structured bool) *openAICompletionCollector {
+	return &openAICompletionCollector{
+		ctx:        ctx,
+		events:     make(chan openAICompletionEvent, 256),
+		structured: structured,
+	}
+}
+
+// send forwards an event to the writer unless the request context is gone
+func (o *openAICompletionCollector) send(evt openAICompletionEvent) {
+	select {
+	case o.events <- evt:
+	case <-o.ctx.Done():
+	}
+}
+
+// flushThought emits the buffered thought text as reasoning or answer content
+func (o *openAICompletionCollector) flushThought(asAnswer bool) {
+	text := o.thought.String()
+	o.thought.Reset()
+	if text == "" {
+		return
+	}
+	if asAnswer {
+		o.send(openAICompletionEvent{content: text})
+	} else {
+		o.send(openAICompletionEvent{reasoning: text})
+	}
+}
+
+// finish emits the terminal event exactly once
+func (o *openAICompletionCollector) finish(errMsg string) {
+	if o.finished {
+		return
+	}
+	o.finished = true
+	o.send(openAICompletionEvent{err: errMsg, done: true})
+}
+
+// subscribe registers the collector on the request EventBus
+func (o *openAICompletionCollector) subscribe(eventBus *event.EventBus) {
+	eventBus.On(event.EventAgentThought, func(ctx context.Context, evt event.Event) error {
+		data, ok := evt.Data.(event.AgentThoughtData)
+		if !ok {
+			return nil
+		}
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		o.thought.WriteString(data.Content)
+		return nil
+	})
+	eventBus.On(event.EventAgentToolCall, func(ctx context.Context, evt event.Event) error {
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		o.flushThought(false)
+		return nil
+	})
+	eventBus.On(event.EventAgentFinalAnswer, func(ctx context.Context, evt event.Event) error {
+		data, ok := evt.Data.(event.AgentFinalAnswerData)
+		if !ok {
+			return nil
+		}
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		o.flushThought(!o.structured)
+		if data.Content == "" {
+			return nil
+		}
+		if o.structured {
+			o.send(openAICompletionEvent{reasoning: data.Content})
+		} else {
+			o.send(openAICompletionEvent{content: data.Content})
+		}
+		return nil
+	})
+	eventBus.On(event.EventStructuredOutput, func(ctx context.Context, evt event.Event) error {
+		data, ok := evt.Data.(event.StructuredOutputData)
+		if !ok {
+			return nil
+		}
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		if data.Error != "" {
+			o.finish(data.Error)
+			return nil
+		}
+		o.send(openAICompletionEvent{content: data.Raw})
+		return nil
+	})
+	eventBus.On(event.EventAgentReferences, func(ctx context.Context, evt event.Event) error {
+		data, ok := evt.Data.(event.AgentReferencesData)
+		if !ok {
+			return nil
+		}
+		refs, ok := data.References.([]*types.SearchResult)
+		if !ok || len(refs) == 0 {
+			return nil
+		}
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		o.references = append(o.references, refs...)
+		o.send(openAICompletionEvent{references: refs})
+		return nil
+	})
+	eventBus.On(event.EventAgentComplete, func(ctx context.Context, evt event.Event) error {
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		o.flushThought(!o.structured)
+		o.finish("")
+		return nil
+	})
+	eventBus.On(event.EventError, func(ctx context.Context, evt event.Event) error {
+		data, ok := evt.Data.(event.ErrorData)
+		if !ok {
+			return nil
+		}
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		o.finish(data.Error)
+		return nil
+	})
+}
+
+// ChatCompletions godoc
+// @Summary      OpenAI 兼容对话接口
+// @Description  兼容 OpenAI Chat Completions 协议，model 为智能体ID或 "kb:<知识库ID>"，支持SSE流式响应；引用通过 references 扩展字段返回
+// @Tags         OpenAI兼容
+// @Accept       json
+// @Produce      json
+// @Produce      text/event-stream
+// @Param        request  body      OpenAIChatCompletionRequest  true  "对话请求"
+// @Success      200      {object}  OpenAIChatCompletion         "对话结果"
+// @Failure      400      {object}  errors.AppError              "请求参数错误"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /v1/chat/completions [post]
+func (h *Handler) ChatCompletions(c *gin.Context) {
+	ctx := logger.CloneContext(c.Request.Context())
+	logger.Info(ctx, "[OpenAI] Start processing chat completion request")
+
+	var request OpenAIChatCompletionRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Error(ctx, "Failed to parse request data", err)
+		c.Error(errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	if len(request.Messages) == 0 {
+		c.Error(errors.NewBadRequestError("messages cannot be empty"))
+		return
+	}
+	last := request.Messages[len(request.Messages)-1]
+	query := openAIMessageText(last.Content)
+	if last.Role != "user" || query == "" {
+		c.Error(errors.NewBadRequestError("the last message must be a non-empty user message"))
+		return
+	}
+
+	outputSchema, err := request.ResponseFormat.outputSchema()
+	if err != nil {
+		c.Error(errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	customAgent, kbIDs, err := h.resolveOpenAIModel(ctx, request.Model)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	session, err := h.resolveOpenAISession(ctx, c, &request)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	logger.Infof(ctx, "[OpenAI] Chat completion: model=%s, session=%s, stream=%v",
+		secutils.SanitizeForLog(request.Model), session.ID, request.Stream)
+
+	requestID := c.GetString(types.RequestIDContextKey.String())
+	reqCtx := &qaRequestContext{
+		ctx:         ctx,
+		c:           c,
+		sessionID:   session.ID,
+		requestID:   secutils.SanitizeForLog(requestID),
+		query:       secutils.SanitizeForLog(query),
+		session:     session,
+		customAgent: customAgent,
+		assistantMessage: &types.Message{
+			SessionID:   session.ID,
+			Role:        "assistant",
+			RequestID:   requestID,
+			IsCompleted: false,
+		},
+		knowledgeBaseIDs: kbIDs,
+		webSearchEnabled: request.WebSearchEnabled,
+		outputSchema:     outputSchema,
+	}
+
+	h.executeOpenAICompletion(reqCtx, request.Model, request.Stream)
+}
+
+// ListModels godoc
+// @Summary      OpenAI 兼容模型列表
+// @Description  列出可作为 model 使用的智能体与知识库（知识库以 "kb:" 前缀标识）
+// @Tags         OpenAI兼容
+// @Produce      json
+// @Success      200  {object}  map[string]interface{}  "模型列表"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /v1/models [get]
+func (h *Handler) ListModels(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	models := make([]OpenAIModel, 0)
+	agents, err := h.customAgentService.ListAgents(ctx)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(errors.NewInternalServerError(err.Error()))
+		return
+	}
+	for _, agent := range agents {
+		models = append(models, OpenAIModel{
+			ID:      agent.ID,
+			Object:  "model",
+			Created: agent.CreatedAt.Unix(),
+			OwnedBy: openAIOwner,
+			Name:    agent.Name,
+		})
+	}
+
+	kbs, err := h.knowledgebaseService.ListKnowledgeBases(ctx)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(errors.NewInternalServerError(err.Error()))
+		return
+	}
+	for _, kb := range kbs {
+		models = append(models, OpenAIModel{
+			ID:      openAIKBModelPrefix + kb.ID,
+			Object:  "model",
+			Created: kb.CreatedAt.Unix(),
+			OwnedBy: openAIOwner,
+			Name:    kb.Name,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"object": "list",
+		"data":   models,
+	})
+}
+
+// resolveOpenAIModel maps the OpenAI model name to a custom agent and optional knowledge base scope
+func (h *Handler) resolveOpenAIModel(ctx context.Context, model string) (*types.CustomAgent, []string, error) {
+	if kbID, ok := strings.CutPrefix(model, openAIKBModelPrefix); ok {
+		kb, err := h.knowledgebaseService.GetKnowledgeBaseByID(ctx, kbID)
+		if err != nil || kb == nil {
+			return nil, nil, errors.NewNotFoundError("model not found: " + model)
+		}
+		agent, err := h.customAgentService.GetLiveAgent(ctx, types.BuiltinQuickAnswerID)
+		if err != nil {
+			return nil, nil, errors.NewInternalServerError(err.Error())
+		}
+		return agent, []string{kb.ID}, nil
+	}
+
+	agent, err := h.customAgentService.GetLiveAgent(ctx, model)
+	if err != nil || agent == nil {
+		return nil, nil, errors.NewNotFoundError("model not found: " + model)
+	}
+	return agent, nil, nil
+}
+
+// resolveOpenAISession loads the session named by the session_id extension, or creates a new one
+// seeded with the earlier turns of the request so that history-aware pipelines can use them
+func (h *Handler) resolveOpenAISession(
+	ctx context.Context,
+	c *gin.Context,
+	request *OpenAIChatCompletionRequest,
+) (*types.Session, error) {
+	if request.SessionID != "" {
+		session, err := h.sessionService.GetSession(ctx, request.SessionID)
+		if err != nil {
+			return nil, errors.NewNotFoundError("Session not found")
+		}
+		return session, nil
+	}
+
+	session, err := h.sessionService.CreateSession(ctx, &types.Session{
+		TenantID:    c.GetUint64(types.TenantIDContextKey.String()),
+		Description: "openai-compatible",
+	})
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	for _, msg := range request.Messages[:len(request.Messages)-1] {
+		if msg.Role != "user" && msg.Role != "assistant" {
+			continue
+		}
+		content := openAIMessageText(msg.Content)
+		if content == "" {
+			continue
+		}
+		if _, err := h.messageService.CreateMessage(ctx, &types.Message{
+			SessionID:   session.ID,
+			Role:        msg.Role,
+			Content:     content,
+			CreatedAt:   time.Now(),
+			IsCompleted: true,
+		}); err != nil {
+			return nil, errors.NewInternalServerError(err.Error())
+		}
+	}
+	return session, nil
+}
+
+// executeOpenAICompletion runs the QA flow for the resolved agent and writes the OpenAI response
+func (h *Handler) executeOpenAICompletion(reqCtx *qaRequestContext, model string, stream bool) {
+	ctx := reqCtx.ctx
+	sessionID := reqCtx.sessionID
+
+	reqCtx.assistantMessage.AgentID = reqCtx.agentID()
+	reqCtx.assistantMessage.AgentVersion = reqCtx.agentVersion()
+	if err := h.createUserMessage(ctx, sessionID, reqCtx.query, reqCtx.requestID, reqCtx.agentID(), nil); err != nil {
+		reqCtx.c.Error(errors.NewInternalServerError(err.Error()))
+		return
+	}
+	assistantMessage, err := h.createAssistantMessage(ctx, reqCtx.assistantMessage)
+	if err != nil {
+		reqCtx.c.Error(errors.NewInternalServerError(err.Error()))
+		return
+	}
+	reqCtx.assistantMessage = assistantMessage
+
+	eventBus := event.NewEventBus()
+	asyncCtx, cancel := context.WithCancel(logger.CloneContext(ctx))
+
+	// Honor the regular stop endpoint and mirror events into the StreamManager so the
+	// conversation also renders in the web UI
+	h.setupStopEventHandler(eventBus, sessionID, reqCtx.session.TenantID, assistantMessage, cancel)
+	h.setupStreamHandler(asyncCtx, sessionID, assistantMessage.ID, reqCtx.requestID, assistantMessage, eventBus)
+
+	structured := len(types.EffectiveOutputSchema(reqCtx.outputSchema, reqCtx.customAgent)) > 0
+	collector := newOpenAICompletionCollector(asyncCtx, structured)
+	collector.subscribe(eventBus)
+
+	agentMode := reqCtx.customAgent != nil && reqCtx.customAgent.RunsAgentQA()
+	if !agentMode {
+		// Normal mode streams the answer as final_answer chunks and never emits agent.complete itself
+		eventBus.On(event.EventAgentFinalAnswer, func(ctx context.Context, evt event.Event) error {
+			data, ok := evt.Data.(event.AgentFinalAnswerData)
+			if !ok {
+				return nil
+			}
+			assistantMessage.Content += data.Content
+			if data.Done {
+				eventBus.Emit(asyncCtx, event.Event{
+					Type:      event.EventAgentComplete,
+					SessionID: sessionID,
+					Data:      event.AgentCompleteData{FinalAnswer: assistantMessage.Content},
+				})
+			}
+			return nil
+		})
+	}
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				buf := make([]byte, 10240)
+				runtime.Stack(buf, true)
+				logger.ErrorWithFields(asyncCtx,
+					errors.NewInternalServerError(fmt.Sprintf("OpenAI completion panicked: %v\n%s", r, string(buf))), nil)
+			}
+			// The run may have been cancelled by a client disconnect; still persist what was produced
+			updateCtx := context.WithValue(context.WithoutCancel(asyncCtx), types.TenantIDContextKey, reqCtx.session.TenantID)
+			h.completeAssistantMessage(updateCtx, assistantMessage)
+		}()
+
+		var err error
+		if agentMode {
+			err = h.sessionService.AgentQA(asyncCtx, reqCtx.session, reqCtx.query, assistantMessage.ID,
+				"", eventBus, reqCtx.customAgent, reqCtx.knowledgeBaseIDs, nil, reqCtx.outputSchema)
+		} else {
+			err = h.sessionService.KnowledgeQA(asyncCtx, reqCtx.session, reqCtx.query, reqCtx.knowledgeBaseIDs,
+				nil, assistantMessage.ID, "", reqCtx.webSearchEnabled, eventBus, reqCtx.customAgent, reqCtx.outputSchema)
+		}
+		if err != nil {
+			logger.ErrorWithFields(asyncCtx, err, nil)
+			eventBus.Emit(asyncCtx, event.Event{
+				Type:      event.EventError,
+				SessionID: sessionID,
+				Data: event.ErrorData{
+					Error:     err.Error(),
+					Stage:     "openai_completion",
+					SessionID: sessionID,
+				},
+			})
+		}
+	}()
+
+	completionID := "chatcmpl-" + assistantMessage.ID
+	created := time.Now().Unix()
+	if stream {
+		h.writeOpenAIStream(reqCtx.c, collector, completionID, created, model, sessionID)
+	} else {
+		h.writeOpenAICompletion(reqCtx.c, collector, completionID, created, model, sessionID)
+	}
+
+	// API clients cannot resume a stream, so a disconnect stops the run
+	if reqCtx.c.Request.Context().Err() != nil {
+		cancel()
+	}
+}
+
+// writeOpenAIStream writes collector events as chat.completion.chunk SSE frames terminated by [DONE]
+func (h *Handler) writeOpenAIStream(
+	c *gin.Context,
+	collector *openAICompletionCollector,
+	completionID string,
+	created int64,
+	model, sessionID string,
+) {
+	setSSEHeaders(c)
+
+	writeChunk := func(delta OpenAIChatCompletionDelta, finishReason *string, refs types.References) {
+		chunk := OpenAIChatCompletionChunk{
+			ID:      completionID,
+			Object:  "chat.completion.chunk",
+			Created: created,
+			Model:   model,
+			Choices: []OpenAIChatCompletionChunkChoice{
+				{Index: 0, Delta: delta, FinishReason: finishReason},
+			},
+			SessionID:  sessionID,
+			References: refs,
+		}
+		payload, _ := json.Marshal(chunk)
+		fmt.Fprintf(c.Writer, "data: %s\n\n", payload)
+		c.Writer.Flush()
+	}
+
+	writeChunk(OpenAIChatCompletionDelta{Role: "assistant"}, nil, nil)
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			logger.Infof(c.Request.Context(), "[OpenAI] Client disconnected, session=%s", sessionID)
+			return
+		case evt := <-collector.events:
+			if evt.done {
+				if evt.err != "" {
+					payload, _ := json.Marshal(gin.H{"error": gin.H{"message": evt.err, "type": "server_error"}})
+					fmt.Fprintf(c.Writer, "data: %s\n\n", payload)
+				} else {
+					stop := "stop"
+					writeChunk(OpenAIChatCompletionDelta{}, &stop, nil)
+				}
+				fmt.Fprint(c.Writer, "data: [DONE]\n\n")
+				c.Writer.Flush()
+				return
+			}
+			writeChunk(OpenAIChatCompletionDelta{
+				Content:          evt.content,
+				ReasoningContent: evt.reasoning,
+			}, nil, evt.references)
+		}
+	}
+}
+
+// writeOpenAICompletion waits for the run to finish and writes a single chat.completion object
+func (h *Handler) writeOpenAICompletion(
+	c *gin.Context,
+	collector *openAICompletionCollector,
+	completionID string,
+	created int64,
+	model, sessionID string,
+) {
+	var content, reasoning strings.Builder
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case evt := <-collector.events:
+			if !evt.done {
+				content.WriteString(evt.content)
+				reasoning.WriteString(evt.reasoning)
+				continue
+			}
+			if evt.err != "" {
+				c.Error(errors.NewInternalServerError(evt.err))
+				return
+			}
+			collector.mu.Lock()
+			refs := collector.references
+			collector.mu.Unlock()
+			if refs == nil {
+				refs = types.References{}
+			}
+			c.JSON(http.StatusOK, OpenAIChatCompletion{
+				ID:      completionID,
+				Object:  "chat.completion",
+				Created: created,
+				Model:   model,
+				Choices: []OpenAIChatCompletionChoice{
+					{
+						Index: 0,
+						Message: OpenAIChatCompletionMessage{
+							Role:             "assistant",
+							Content:          content.String(),
+							ReasoningContent: reasoning.String(),
+						},
+						FinishReason: "stop",
+					},
+				},
+				SessionID:  sessionID,
+				References: refs,
+			})
+			return
+		}
+	}
+}
+
+// openAIMessageText extracts the text of an OpenAI message content (string or content parts)
+func openAIMessageText(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return strings.TrimSpace(text)
+	}
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(raw, &parts); err != nil {
+		return ""
+	}
+	texts := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part.Type == "text" && part.Text != "" {
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.TrimSpace(strings.Join(texts, "\n"))
+}
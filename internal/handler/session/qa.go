@@ -31,7 +31,32 @@ type qaRequestContext struct {
 	summaryModelID    string
 	webSearchEnabled  bool
 	mentionedItems    types.MentionedItems
-	effectiveTenantID uint64 // when using shared agent, tenant ID for model/KB/MCP resolution; 0 = use context tenant
+	effectiveTenantID uint64    // when using shared agent, tenant ID for model/KB/MCP resolution; 0 = use context tenant
+	branch            *qaBranch // set when editing or regenerating a past message
+	outputSchema      types.OutputSchema
+}
+
+// agentID returns the ID of the custom agent of the request, empty when no agent is selected
+func (r *qaRequestContext) agentID() string {
+	if r.customAgent == nil {
+		return ""
+	}
+	return r.customAgent.ID
+}
+
+// agentVersion returns the published version of the custom agent answering the request, 0 for its draft
+func (r *qaRequestContext) agentVersion() int {
+	if r.customAgent == nil {
+		return 0
+	}
+	return r.customAgent.Version
+}
+
+// qaBranch describes where the messages of an edit or regenerate request start a new branch
+type qaBranch struct {
+	parentID        string // parent of the first message of the new branch
+	skipUserMessage bool   // regenerating reuses the existing user message
+	requestID       string // request ID of the reused user message
 }
 
 // parseQARequest parses and validates a QA request, returns the request context
@@ -53,12 +78,25 @@ func (h *Handler) parseQARequest(c *gin.Context, logPrefix string) (*qaRequestCo
 		return nil, nil, errors.NewBadRequestError(err.Error())
 	}
 
+	// Resolve the message being edited or regenerated
+	branch, err := h.resolveQABranch(ctx, sessionID, &request)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	// Validate query content
 	if request.Query == "" {
 		logger.Error(ctx, "Query content is empty")
 		return nil, nil, errors.NewBadRequestError("Query content cannot be empty")
 	}
 
+	// Validate the requested output schema
+	if len(request.OutputSchema) > 0 {
+		if _, err := request.OutputSchema.Compile(); err != nil {
+			return nil, nil, errors.NewBadRequestError(err.Error())
+		}
+	}
+
 	// Log request details
 	if requestJSON, err := json.Marshal(request); err == nil {
 		logger.Infof(ctx, "[%s] Request: session_id=%s, request=%s",
@@ -90,7 +128,7 @@ func (h *Handler) parseQARequest(c *gin.Context, logPrefix string) (*qaRequestCo
 			}
 		}
 		if customAgent == nil {
-			agent, err := h.customAgentService.GetAgentByID(ctx, request.AgentID)
+			agent, err := h.customAgentService.GetLiveAgent(ctx, request.AgentID)
 			if err == nil {
 				customAgent = agent
 				logger.Infof(ctx, "Using own agent: ID=%s, Name=%s, AgentMode=%s",
@@ -168,11 +206,119 @@ func (h *Handler) parseQARequest(c *gin.Context, logPrefix string) (*qaRequestCo
 		webSearchEnabled:  request.WebSearchEnabled,
 		mentionedItems:    convertMentionedItems(request.MentionedItems),
 		effectiveTenantID: effectiveTenantID,
+		branch:            branch,
+		outputSchema:      request.OutputSchema,
+	}
+	if branch != nil && branch.skipUserMessage {
+		// The regenerated answer is paired with the original question when loading history
+		reqCtx.assistantMessage.RequestID = branch.requestID
 	}
 
 	return reqCtx, &request, nil
 }
 
+// resolveQABranch validates the edit_message_id or regenerate_message_id of a request.
+// Regenerating fills in the query of the original question when the request has none.
+func (h *Handler) resolveQABranch(ctx context.Context,
+	sessionID string, request *CreateKnowledgeQARequest,
+) (*qaBranch, error) {
+	switch {
+	case request.EditMessageID != "" && request.RegenerateMessageID != "":
+		return nil, errors.NewBadRequestError("edit_message_id and regenerate_message_id cannot be used together")
+	case request.EditMessageID != "":
+		message, err := h.messageService.GetMessage(ctx, sessionID, request.EditMessageID)
+		if err != nil || message == nil {
+			logger.Warnf(ctx, "Message to edit not found, session ID: %s, message ID: %s",
+				sessionID, secutils.SanitizeForLog(request.EditMessageID))
+			return nil, errors.NewNotFoundError("Message to edit not found")
+		}
+		if message.Role != "user" {
+			return nil, errors.NewBadRequestError("Only user messages can be edited")
+		}
+		return &qaBranch{parentID: message.ParentID}, nil
+	case request.RegenerateMessageID != "":
+		message, err := h.messageService.GetMessage(ctx, sessionID, request.RegenerateMessageID)
+		if err != nil || message == nil {
+			logger.Warnf(ctx, "Message to regenerate not found, session ID: %s, message ID: %s",
+				sessionID, secutils.SanitizeForLog(request.RegenerateMessageID))
+			return nil, errors.NewNotFoundError("Message to regenerate not found")
+		}
+		if message.Role != "assistant" || message.ParentID == "" {
+			return nil, errors.NewBadRequestError("Only answers to a question can be regenerated")
+		}
+		question, err := h.messageService.GetMessage(ctx, sessionID, message.ParentID)
+		if err != nil || question == nil || question.Role != "user" {
+			return nil, errors.NewBadRequestError("Only answers to a question can be regenerated")
+		}
+		if request.Query == "" {
+			request.Query = question.Content
+		}
+		if len(request.MentionedItems) == 0 {
+			for _, item := range question.MentionedItems {
+				request.MentionedItems = append(request.MentionedItems, MentionedItemRequest{
+					ID: item.ID, Name: item.Name, Type: item.Type, KBType: item.KBType,
+				})
+			}
+		}
+		return &qaBranch{parentID: question.ID, skipUserMessage: true, requestID: question.RequestID}, nil
+	}
+	return nil, nil
+}
+
+// createQAMessages creates the user and assistant messages of a QA request,
+// on a new branch when a past message is edited or regenerated
+func (h *Handler) createQAMessages(reqCtx *qaRequestContext) error {
+	ctx := reqCtx.ctx
+	reqCtx.assistantMessage.AgentID = reqCtx.agentID()
+	reqCtx.assistantMessage.AgentVersion = reqCtx.agentVersion()
+	if reqCtx.branch == nil {
+		if err := h.createUserMessage(ctx, reqCtx.sessionID, reqCtx.query, reqCtx.requestID,
+			reqCtx.agentID(), reqCtx.mentionedItems); err != nil {
+			return err
+		}
+		assistantMessage, err := h.createAssistantMessage(ctx, reqCtx.assistantMessage)
+		if err != nil {
+			return err
+		}
+		reqCtx.assistantMessage = assistantMessage
+		return nil
+	}
+
+	parentID := reqCtx.branch.parentID
+	if !reqCtx.branch.skipUserMessage {
+		userMessage, err := h.messageService.CreateBranchMessage(ctx, &types.Message{
+			SessionID:      reqCtx.sessionID,
+			Role:           "user",
+			Content:        reqCtx.query,
+			RequestID:      reqCtx.requestID,
+			AgentID:        reqCtx.agentID(),
+			CreatedAt:      time.Now(),
+			IsCompleted:    true,
+			MentionedItems: reqCtx.mentionedItems,
+		}, parentID)
+		if err != nil {
+			return err
+		}
+		// The assistant message follows the new question on the branch just created
+		assistantMessage, err := h.createAssistantMessage(ctx, reqCtx.assistantMessage)
+		if err != nil {
+			return err
+		}
+		reqCtx.assistantMessage = assistantMessage
+		logger.Infof(ctx, "Edited message on new branch, user message ID: %s", userMessage.ID)
+		return nil
+	}
+
+	reqCtx.assistantMessage.CreatedAt = time.Now()
+	assistantMessage, err := h.messageService.CreateBranchMessage(ctx, reqCtx.assistantMessage, parentID)
+	if err != nil {
+		return err
+	}
+	reqCtx.assistantMessage = assistantMessage
+	logger.Infof(ctx, "Regenerating answer on new branch, assistant message ID: %s", assistantMessage.ID)
+	return nil
+}
+
 // sseStreamContext holds the context for SSE streaming
 type sseStreamContext struct {
 	eventBus         *event.EventBus
@@ -327,6 +473,12 @@ func (h *Handler) KnowledgeQA(c *gin.Context) {
 		return
 	}
 
+	// Workflow agents only run through the agent path
+	if reqCtx.customAgent != nil && reqCtx.customAgent.IsWorkflowMode() {
+		h.executeAgentModeQA(reqCtx)
+		return
+	}
+
 	// Execute normal mode QA, generate title unless disabled
 	h.executeNormalModeQA(reqCtx, !request.DisableTitle)
 }
@@ -353,10 +505,10 @@ func (h *Handler) AgentQA(c *gin.Context) {
 	}
 
 	// Determine if agent mode should be enabled
-	// Priority: customAgent.IsAgentMode() > request.AgentEnabled
+	// Priority: customAgent.RunsAgentQA() > request.AgentEnabled
 	agentModeEnabled := request.AgentEnabled
 	if reqCtx.customAgent != nil {
-		agentModeEnabled = reqCtx.customAgent.IsAgentMode()
+		agentModeEnabled = reqCtx.customAgent.RunsAgentQA()
 		logger.Infof(reqCtx.ctx, "Agent mode determined by custom agent: %v (config.agent_mode=%s)",
 			agentModeEnabled, reqCtx.customAgent.Config.AgentMode)
 	}
@@ -376,14 +528,8 @@ func (h *Handler) executeNormalModeQA(reqCtx *qaRequestContext, generateTitle bo
 	ctx := reqCtx.ctx
 	sessionID := reqCtx.sessionID
 
-	// Create user message
-	if err := h.createUserMessage(ctx, sessionID, reqCtx.query, reqCtx.requestID, reqCtx.mentionedItems); err != nil {
-		reqCtx.c.Error(errors.NewInternalServerError(err.Error()))
-		return
-	}
-
-	// Create assistant message
-	if _, err := h.createAssistantMessage(ctx, reqCtx.assistantMessage); err != nil {
+	// Create user and assistant messages
+	if err := h.createQAMessages(reqCtx); err != nil {
 		reqCtx.c.Error(errors.NewInternalServerError(err.Error()))
 		return
 	}
@@ -421,7 +567,10 @@ func (h *Handler) executeNormalModeQA(reqCtx *qaRequestContext, generateTitle bo
 			streamCtx.eventBus.Emit(streamCtx.asyncCtx, event.Event{
 				Type:      event.EventAgentComplete,
 				SessionID: sessionID,
-				Data:      event.AgentCompleteData{FinalAnswer: streamCtx.assistantMessage.Content},
+				Data: event.AgentCompleteData{
+					FinalAnswer: streamCtx.assistantMessage.Content,
+					Citations:   data.Citations,
+				},
 			})
 		}
 		return nil
@@ -449,6 +598,7 @@ func (h *Handler) executeNormalModeQA(reqCtx *qaRequestContext, generateTitle bo
 			reqCtx.webSearchEnabled,
 			streamCtx.eventBus,
 			reqCtx.customAgent,
+			reqCtx.outputSchema,
 		)
 		if err != nil {
 			logger.ErrorWithFields(streamCtx.asyncCtx, err, nil)
@@ -490,19 +640,11 @@ func (h *Handler) executeAgentModeQA(reqCtx *qaRequestContext) {
 		return
 	}
 
-	// Create user message
-	if err := h.createUserMessage(ctx, sessionID, reqCtx.query, reqCtx.requestID, reqCtx.mentionedItems); err != nil {
-		reqCtx.c.Error(errors.NewInternalServerError(err.Error()))
-		return
-	}
-
-	// Create assistant message
-	assistantMessagePtr, err := h.createAssistantMessage(ctx, reqCtx.assistantMessage)
-	if err != nil {
+	// Create user and assistant messages
+	if err := h.createQAMessages(reqCtx); err != nil {
 		reqCtx.c.Error(errors.NewInternalServerError(err.Error()))
 		return
 	}
-	reqCtx.assistantMessage = assistantMessagePtr
 
 	logger.Infof(ctx, "Calling agent QA service, session ID: %s", sessionID)
 
@@ -535,6 +677,7 @@ func (h *Handler) executeAgentModeQA(reqCtx *qaRequestContext) {
 			reqCtx.customAgent,
 			reqCtx.knowledgeBaseIDs,
 			reqCtx.knowledgeIDs,
+			reqCtx.outputSchema,
 		)
 		if err != nil {
 			logger.ErrorWithFields(streamCtx.asyncCtx, err, nil)
@@ -0,0 +1,106 @@
+package session
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"github.com/Tencent/WeKnora/internal/application/service"
+	"github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/logger"
+	secutils "github.com/Tencent/WeKnora/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// ListToolApprovals godoc
+// @Summary      获取待审批的工具调用
+// @Description  获取会话中正在等待用户审批的Agent工具调用
+// @Tags         会话
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "会话ID"
+// @Success      200  {object}  map[string]interface{}  "待审批列表"
+// @Failure      404  {object}  errors.AppError         "会话不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /sessions/{id}/tool-approvals [get]
+func (h *Handler) ListToolApprovals(c *gin.Context) {
+	ctx := c.Request.Context()
+	sessionID := secutils.SanitizeForLog(c.Param("id"))
+
+	// Verify the session belongs to the current tenant
+	if _, err := h.sessionService.GetSession(ctx, sessionID); err != nil {
+		logger.Warnf(ctx, "Session not found for tool approvals, ID: %s", sessionID)
+		c.Error(errors.NewNotFoundError(errors.ErrSessionNotFound.Error()))
+		return
+	}
+
+	approvals, err := h.toolApprovalService.ListPending(ctx, sessionID)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"session_id": sessionID})
+		c.Error(errors.NewInternalServerError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    approvals,
+	})
+}
+
+// DecideToolApproval godoc
+// @Summary      审批工具调用
+// @Description  批准或拒绝Agent暂停等待的敏感工具调用，Agent随后继续执行或跳过该调用
+// @Tags         会话
+// @Accept       json
+// @Produce      json
+// @Param        session_id   path      string                     true  "会话ID"
+// @Param        approval_id  path      string                     true  "审批ID"
+// @Param        request      body      DecideToolApprovalRequest  true  "审批结果"
+// @Success      200          {object}  map[string]interface{}     "审批结果"
+// @Failure      400          {object}  errors.AppError            "请求参数错误"
+// @Failure      404          {object}  errors.AppError            "会话或审批不存在"
+// @Failure      409          {object}  errors.AppError            "审批已处理或已过期"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /sessions/{session_id}/tool-approvals/{approval_id} [post]
+func (h *Handler) DecideToolApproval(c *gin.Context) {
+	ctx := c.Request.Context()
+	sessionID := secutils.SanitizeForLog(c.Param("session_id"))
+	approvalID := secutils.SanitizeForLog(c.Param("approval_id"))
+
+	var req DecideToolApprovalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error(ctx, "Failed to parse tool approval request", err)
+		c.Error(errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	// Verify the session belongs to the current tenant
+	if _, err := h.sessionService.GetSession(ctx, sessionID); err != nil {
+		logger.Warnf(ctx, "Session not found for tool approval, ID: %s", sessionID)
+		c.Error(errors.NewNotFoundError(errors.ErrSessionNotFound.Error()))
+		return
+	}
+
+	approval, err := h.toolApprovalService.Decide(ctx, sessionID, approvalID, req.Approved, req.Reason)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"session_id":  sessionID,
+			"approval_id": approvalID,
+		})
+		switch {
+		case stderrors.Is(err, service.ErrToolApprovalNotFound):
+			c.Error(errors.NewNotFoundError(err.Error()))
+		case stderrors.Is(err, service.ErrToolApprovalResolved), stderrors.Is(err, service.ErrToolApprovalExpired):
+			c.Error(errors.NewConflictError(err.Error()))
+		default:
+			c.Error(errors.NewInternalServerError(err.Error()))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    approval,
+	})
+}
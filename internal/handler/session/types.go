@@ -29,15 +29,18 @@ type MentionedItemRequest struct {
 
 // CreateKnowledgeQARequest defines the request structure for knowledge QA
 type CreateKnowledgeQARequest struct {
-	Query            string                 `json:"query"              binding:"required"` // Query text for knowledge base search
-	KnowledgeBaseIDs []string               `json:"knowledge_base_ids"`                    // Selected knowledge base ID for this request
-	KnowledgeIds     []string               `json:"knowledge_ids"`                         // Selected knowledge ID for this request
-	AgentEnabled     bool                   `json:"agent_enabled"`                         // Whether agent mode is enabled for this request
-	AgentID          string                 `json:"agent_id"`                              // Selected custom agent ID (backend resolves shared agent and its tenant from share relation)
-	WebSearchEnabled bool                   `json:"web_search_enabled"`                    // Whether web search is enabled for this request
-	SummaryModelID   string                 `json:"summary_model_id"`                      // Optional summary model ID for this request (overrides session default)
-	MentionedItems   []MentionedItemRequest `json:"mentioned_items"`                       // @mentioned knowledge bases and files
-	DisableTitle     bool                   `json:"disable_title"`                         // Whether to disable auto title generation
+	Query               string                 `json:"query"`                 // Query text for knowledge base search
+	KnowledgeBaseIDs    []string               `json:"knowledge_base_ids"`    // Selected knowledge base ID for this request
+	KnowledgeIds        []string               `json:"knowledge_ids"`         // Selected knowledge ID for this request
+	AgentEnabled        bool                   `json:"agent_enabled"`         // Whether agent mode is enabled for this request
+	AgentID             string                 `json:"agent_id"`              // Selected custom agent ID (backend resolves shared agent and its tenant from share relation)
+	WebSearchEnabled    bool                   `json:"web_search_enabled"`    // Whether web search is enabled for this request
+	SummaryModelID      string                 `json:"summary_model_id"`      // Optional summary model ID for this request (overrides session default)
+	MentionedItems      []MentionedItemRequest `json:"mentioned_items"`       // @mentioned knowledge bases and files
+	DisableTitle        bool                   `json:"disable_title"`         // Whether to disable auto title generation
+	EditMessageID       string                 `json:"edit_message_id"`       // Past user message to edit, the query is asked again from there on a new branch
+	RegenerateMessageID string                 `json:"regenerate_message_id"` // Past assistant message to answer again on a new branch, query may be empty
+	OutputSchema        types.OutputSchema     `json:"output_schema"`         // Optional JSON Schema the answer must conform to (overrides the agent's)
 }
 
 // SearchKnowledgeRequest defines the request structure for searching knowledge without LLM summarization
@@ -52,3 +55,9 @@ type SearchKnowledgeRequest struct {
 type StopSessionRequest struct {
 	MessageID string `json:"message_id" binding:"required"`
 }
+
+// DecideToolApprovalRequest represents the approve/deny request for a pending tool call
+type DecideToolApprovalRequest struct {
+	Approved bool   `json:"approved"`         // Whether the tool call is approved
+	Reason   string `json:"reason,omitempty"` // Optional reason passed back to the agent
+}
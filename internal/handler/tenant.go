@@ -216,6 +216,9 @@ func (h *TenantHandler) DeleteTenant(c *gin.Context) {
 	}
 
 	logger.Infof(ctx, "Deleting tenant, ID: %d", id)
+	if tenant, err := h.service.GetTenantByID(ctx, id); err == nil && tenant != nil {
+		setAuditBefore(c, types.AuditChange{"name": tenant.Name})
+	}
 
 	if err := h.service.DeleteTenant(ctx, id); err != nil {
 		// Check if this is an application-specific error
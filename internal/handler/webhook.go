@@ -0,0 +1,310 @@
+package handler
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"github.com/Tencent/WeKnora/internal/application/service"
+	"github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	secutils "github.com/Tencent/WeKnora/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// WebhookHandler handles HTTP requests for tenant webhooks
+type WebhookHandler struct {
+	service interfaces.WebhookService
+}
+
+// NewWebhookHandler creates a new webhook handler
+func NewWebhookHandler(service interfaces.WebhookService) *WebhookHandler {
+	return &WebhookHandler{service: service}
+}
+
+// CreateWebhookRequest is the request body of CreateWebhook
+type CreateWebhookRequest struct {
+	Name        string   `json:"name"        binding:"required"`
+	Description string   `json:"description"`
+	URL         string   `json:"url"         binding:"required"`
+	Events      []string `json:"events"      binding:"required"`
+	// Signing secret, generated when empty
+	Secret  string `json:"secret"`
+	Enabled *bool  `json:"enabled"`
+}
+
+// UpdateWebhookRequest is the request body of UpdateWebhook, omitted fields are left unchanged
+type UpdateWebhookRequest struct {
+	Name        *string   `json:"name"`
+	Description *string   `json:"description"`
+	URL         *string   `json:"url"`
+	Events      *[]string `json:"events"`
+	// Rotates the signing secret
+	Secret  *string `json:"secret"`
+	Enabled *bool   `json:"enabled"`
+}
+
+// CreateWebhook godoc
+// @Summary      创建 Webhook
+// @Description  为当前租户创建 Webhook，订阅的事件发生时以 HMAC-SHA256 签名的 JSON 请求推送。未指定密钥时自动生成，密钥仅在创建时返回一次
+// @Tags         Webhook
+// @Accept       json
+// @Produce      json
+// @Param        request  body      CreateWebhookRequest    true  "Webhook 配置"
+// @Success      200      {object}  map[string]interface{}  "创建的 Webhook 及签名密钥"
+// @Failure      400      {object}  errors.AppError         "URL 或事件无效"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /webhooks [post]
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error(ctx, "Failed to parse request parameters", err)
+		c.Error(errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	webhook := &types.Webhook{
+		Name:        req.Name,
+		Description: req.Description,
+		URL:         req.URL,
+		Secret:      types.EncryptedString(req.Secret),
+		Events:      req.Events,
+		Enabled:     req.Enabled == nil || *req.Enabled,
+	}
+	webhook, secret, err := h.service.CreateWebhook(ctx, webhook)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		h.handleError(c, err, "Failed to create webhook: ")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"webhook": webhook,
+			"secret":  secret,
+		},
+	})
+}
+
+// ListWebhooks godoc
+// @Summary      获取 Webhook 列表
+// @Description  获取当前租户的所有 Webhook
+// @Tags         Webhook
+// @Produce      json
+// @Success      200  {object}  map[string]interface{}  "Webhook 列表"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /webhooks [get]
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	webhooks, err := h.service.ListWebhooks(ctx)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(errors.NewInternalServerError("Failed to list webhooks: " + err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    webhooks,
+	})
+}
+
+// GetWebhook godoc
+// @Summary      获取 Webhook 详情
+// @Tags         Webhook
+// @Produce      json
+// @Param        id   path      string  true  "Webhook ID"
+// @Success      200  {object}  map[string]interface{}  "Webhook"
+// @Failure      404  {object}  errors.AppError         "Webhook 不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /webhooks/{id} [get]
+func (h *WebhookHandler) GetWebhook(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := secutils.SanitizeForLog(c.Param("id"))
+
+	webhook, err := h.service.GetWebhook(ctx, id)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"webhook_id": id})
+		h.handleError(c, err, "Failed to get webhook: ")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    webhook,
+	})
+}
+
+// UpdateWebhook godoc
+// @Summary      更新 Webhook
+// @Description  更新 Webhook 的名称、URL、订阅事件或启用状态，未传的字段保持不变。传入 secret 时轮换签名密钥
+// @Tags         Webhook
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                  true  "Webhook ID"
+// @Param        request  body      UpdateWebhookRequest    true  "更新内容"
+// @Success      200      {object}  map[string]interface{}  "更新后的 Webhook"
+// @Failure      400      {object}  errors.AppError         "URL 或事件无效"
+// @Failure      404      {object}  errors.AppError         "Webhook 不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /webhooks/{id} [put]
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := secutils.SanitizeForLog(c.Param("id"))
+
+	var req UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error(ctx, "Failed to parse request parameters", err)
+		c.Error(errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	webhook, err := h.service.GetWebhook(ctx, id)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"webhook_id": id})
+		h.handleError(c, err, "Failed to get webhook: ")
+		return
+	}
+	if req.Name != nil {
+		webhook.Name = *req.Name
+	}
+	if req.Description != nil {
+		webhook.Description = *req.Description
+	}
+	if req.URL != nil {
+		webhook.URL = *req.URL
+	}
+	if req.Events != nil {
+		webhook.Events = *req.Events
+	}
+	if req.Secret != nil && *req.Secret != "" {
+		webhook.Secret = types.EncryptedString(*req.Secret)
+	}
+	if req.Enabled != nil {
+		webhook.Enabled = *req.Enabled
+	}
+
+	webhook, err = h.service.UpdateWebhook(ctx, webhook)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"webhook_id": id})
+		h.handleError(c, err, "Failed to update webhook: ")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    webhook,
+	})
+}
+
+// DeleteWebhook godoc
+// @Summary      删除 Webhook
+// @Description  删除 Webhook 及其投递记录
+// @Tags         Webhook
+// @Produce      json
+// @Param        id   path      string  true  "Webhook ID"
+// @Success      200  {object}  map[string]interface{}  "删除成功"
+// @Failure      404  {object}  errors.AppError         "Webhook 不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /webhooks/{id} [delete]
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := secutils.SanitizeForLog(c.Param("id"))
+
+	if err := h.service.DeleteWebhook(ctx, id); err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"webhook_id": id})
+		h.handleError(c, err, "Failed to delete webhook: ")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
+
+// TestWebhook godoc
+// @Summary      测试 Webhook
+// @Description  立即向 Webhook 同步发送一个 webhook.test 事件（不重试），返回本次投递记录，可用于验证连通性和签名校验
+// @Tags         Webhook
+// @Produce      json
+// @Param        id   path      string  true  "Webhook ID"
+// @Success      200  {object}  map[string]interface{}  "投递记录"
+// @Failure      404  {object}  errors.AppError         "Webhook 不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /webhooks/{id}/test [post]
+func (h *WebhookHandler) TestWebhook(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := secutils.SanitizeForLog(c.Param("id"))
+
+	delivery, err := h.service.TestWebhook(ctx, id)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"webhook_id": id})
+		h.handleError(c, err, "Failed to test webhook: ")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    delivery,
+	})
+}
+
+// ListWebhookDeliveries godoc
+// @Summary      获取 Webhook 投递记录
+// @Description  分页获取 Webhook 的投递记录，按创建时间倒序，包含事件内容、状态、尝试次数和最后一次响应
+// @Tags         Webhook
+// @Produce      json
+// @Param        id         path      string  true   "Webhook ID"
+// @Param        page       query     int     false  "页码"
+// @Param        page_size  query     int     false  "每页数量"
+// @Success      200        {object}  map[string]interface{}  "投递记录列表"
+// @Failure      404        {object}  errors.AppError         "Webhook 不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /webhooks/{id}/deliveries [get]
+func (h *WebhookHandler) ListWebhookDeliveries(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := secutils.SanitizeForLog(c.Param("id"))
+
+	var page types.Pagination
+	if err := c.ShouldBindQuery(&page); err != nil {
+		logger.Error(ctx, "Failed to parse pagination parameters", err)
+		c.Error(errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	result, err := h.service.ListDeliveries(ctx, id, &page)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"webhook_id": id})
+		h.handleError(c, err, "Failed to list webhook deliveries: ")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+	})
+}
+
+// handleError maps webhook service errors to HTTP errors
+func (h *WebhookHandler) handleError(c *gin.Context, err error, message string) {
+	switch {
+	case stderrors.Is(err, service.ErrWebhookNotFound):
+		c.Error(errors.NewNotFoundError(err.Error()))
+	case stderrors.Is(err, service.ErrWebhookInvalidURL), stderrors.Is(err, service.ErrWebhookInvalidEvent):
+		c.Error(errors.NewBadRequestError(err.Error()))
+	default:
+		c.Error(errors.NewInternalServerError(message + err.Error()))
+	}
+}
@@ -39,6 +39,12 @@ type MCPClient interface {
 	// ReadResource reads a resource from the MCP service
 	ReadResource(ctx context.Context, uri string) (*ReadResourceResult, error)
 
+	// ListPrompts retrieves the list of available prompts from the MCP service
+	ListPrompts(ctx context.Context) ([]*types.MCPPrompt, error)
+
+	// GetPrompt renders a prompt from the MCP service with the given arguments
+	GetPrompt(ctx context.Context, name string, args map[string]string) (*types.MCPPromptResult, error)
+
 	// IsConnected returns true if the client is connected
 	IsConnected() bool
 
@@ -49,6 +55,7 @@ type MCPClient interface {
 // ClientConfig represents configuration for creating an MCP client
 type ClientConfig struct {
 	Service *types.MCPService
+	OAuth   *transport.OAuthConfig // OAuth configuration, set when the service uses OAuth
 }
 
 // mcpGoClient wraps mark3labs/mcp-go client to implement our MCPClient interface
@@ -100,10 +107,14 @@ func NewMCPClient(config *ClientConfig) (MCPClient, error) {
 		if config.Service.URL == nil || *config.Service.URL == "" {
 			return nil, fmt.Errorf("URL is required for SSE transport")
 		}
-		mcpClient, err = client.NewSSEMCPClient(*config.Service.URL,
+		options := []transport.ClientOption{
 			client.WithHTTPClient(httpClient),
 			client.WithHeaders(headers),
-		)
+		}
+		if config.OAuth != nil {
+			options = append(options, transport.WithOAuth(*config.OAuth))
+		}
+		mcpClient, err = client.NewSSEMCPClient(*config.Service.URL, options...)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create SSE client: %w", err)
 		}
@@ -112,10 +123,14 @@ func NewMCPClient(config *ClientConfig) (MCPClient, error) {
 			return nil, fmt.Errorf("URL is required for HTTP Streamable transport")
 		}
 		// For HTTP streamable, we need to use transport options
-		mcpClient, err = client.NewStreamableHttpClient(*config.Service.URL,
+		options := []transport.StreamableHTTPCOption{
 			transport.WithHTTPBasicClient(httpClient),
 			transport.WithHTTPHeaders(headers),
-		)
+		}
+		if config.OAuth != nil {
+			options = append(options, transport.WithHTTPOAuth(*config.OAuth))
+		}
+		mcpClient, err = client.NewStreamableHttpClient(*config.Service.URL, options...)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create HTTP streamable client: %w", err)
 		}
@@ -361,6 +376,82 @@ func (c *mcpGoClient) ReadResource(ctx context.Context, uri string) (*ReadResour
 	}, nil
 }
 
+// ListPrompts retrieves the list of available prompts
+func (c *mcpGoClient) ListPrompts(ctx context.Context) ([]*types.MCPPrompt, error) {
+	if !c.initialized {
+		return nil, ErrNotConnected
+	}
+
+	req := mcp.ListPromptsRequest{}
+	result, err := c.client.ListPrompts(ctx, req)
+	if err != nil {
+		c.checkErrorAndDisconnectIfNeeded(err)
+		return nil, fmt.Errorf("failed to list prompts: %w", err)
+	}
+
+	// Convert to our types
+	prompts := make([]*types.MCPPrompt, len(result.Prompts))
+	for i, prompt := range result.Prompts {
+		arguments := make([]types.MCPPromptArgument, len(prompt.Arguments))
+		for j, arg := range prompt.Arguments {
+			arguments[j] = types.MCPPromptArgument{
+				Name:        arg.Name,
+				Description: arg.Description,
+				Required:    arg.Required,
+			}
+		}
+		prompts[i] = &types.MCPPrompt{
+			Name:        prompt.Name,
+			Description: prompt.Description,
+			Arguments:   arguments,
+		}
+	}
+
+	return prompts, nil
+}
+
+// GetPrompt renders a prompt from the MCP service
+func (c *mcpGoClient) GetPrompt(ctx context.Context, name string, args map[string]string) (*types.MCPPromptResult, error) {
+	if !c.initialized {
+		return nil, ErrNotConnected
+	}
+
+	req := mcp.GetPromptRequest{
+		Params: mcp.GetPromptParams{
+			Name:      name,
+			Arguments: args,
+		},
+	}
+
+	result, err := c.client.GetPrompt(ctx, req)
+	if err != nil {
+		c.checkErrorAndDisconnectIfNeeded(err)
+		return nil, fmt.Errorf("failed to get prompt: %w", err)
+	}
+
+	// Convert to our types, keeping only the textual parts of each message
+	messages := make([]types.MCPPromptMessage, 0, len(result.Messages))
+	for _, msg := range result.Messages {
+		message := types.MCPPromptMessage{Role: string(msg.Role)}
+		if text, ok := mcp.AsTextContent(msg.Content); ok {
+			message.Content = text.Text
+		} else if embedded, ok := mcp.AsEmbeddedResource(msg.Content); ok {
+			if text, ok := mcp.AsTextResourceContents(embedded.Resource); ok {
+				message.Content = text.Text
+			}
+		}
+		if message.Content == "" {
+			continue
+		}
+		messages = append(messages, message)
+	}
+
+	return &types.MCPPromptResult{
+		Description: result.Description,
+		Messages:    messages,
+	}, nil
+}
+
 // IsConnected returns true if the client is connected
 func (c *mcpGoClient) IsConnected() bool {
 	return c.connected
@@ -29,4 +29,16 @@ var (
 
 	// ErrConnectionClosed is returned when connection is closed unexpectedly
 	ErrConnectionClosed = errors.New("connection closed")
+
+	// ErrOAuthNotEnabled is returned when an OAuth operation targets a service without OAuth
+	ErrOAuthNotEnabled = errors.New("OAuth is not enabled for this MCP service")
+
+	// ErrOAuthAuthorizationRequired is returned when the service has no usable OAuth token
+	ErrOAuthAuthorizationRequired = errors.New("OAuth authorization required")
+
+	// ErrOAuthInvalidState is returned when the callback state does not match a pending request
+	ErrOAuthInvalidState = errors.New("invalid OAuth state")
+
+	// ErrOAuthStateExpired is returned when the authorization request has expired
+	ErrOAuthStateExpired = errors.New("OAuth authorization request expired")
 )
@@ -8,24 +8,29 @@ import (
 
 	"github.com/Tencent/WeKnora/internal/logger"
 	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
 )
 
 // MCPManager manages MCP client connections
 type MCPManager struct {
 	clients   map[string]MCPClient // serviceID -> client
 	clientsMu sync.RWMutex
+	resources *resourceCache // cached resource reads
+	oauthRepo interfaces.MCPOAuthCredentialRepository
 	ctx       context.Context
 	cancel    context.CancelFunc
 }
 
 // NewMCPManager creates a new MCP manager
-func NewMCPManager() *MCPManager {
+func NewMCPManager(oauthRepo interfaces.MCPOAuthCredentialRepository) *MCPManager {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	manager := &MCPManager{
-		clients: make(map[string]MCPClient),
-		ctx:     ctx,
-		cancel:  cancel,
+		clients:   make(map[string]MCPClient),
+		resources: newResourceCache(),
+		oauthRepo: oauthRepo,
+		ctx:       ctx,
+		cancel:    cancel,
 	}
 
 	// Start cleanup goroutine
@@ -48,6 +53,14 @@ func (m *MCPManager) GetOrCreateClient(service *types.MCPService) (MCPClient, er
 		return nil, fmt.Errorf("stdio transport is disabled for security reasons; please use SSE or HTTP Streamable transport instead")
 	}
 
+	// For OAuth services, make sure a valid token exists (refreshing it when about to expire).
+	// The transport reads the token from the credential store on every request, so cached
+	// clients pick up refreshed tokens without reconnecting.
+	config, err := m.ClientConfigFor(service)
+	if err != nil {
+		return nil, err
+	}
+
 	// For SSE/HTTP Streamable, check if client already exists and reuse
 	m.clientsMu.RLock()
 	client, exists := m.clients[service.ID]
@@ -68,11 +81,7 @@ func (m *MCPManager) GetOrCreateClient(service *types.MCPService) (MCPClient, er
 	}
 
 	// Create new client
-	config := &ClientConfig{
-		Service: service,
-	}
-
-	client, err := NewMCPClient(config)
+	client, err = NewMCPClient(config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create MCP client: %w", err)
 	}
@@ -130,6 +139,8 @@ func (m *MCPManager) GetClient(serviceID string) (MCPClient, bool) {
 
 // CloseClient closes and removes a specific client
 func (m *MCPManager) CloseClient(serviceID string) error {
+	m.resources.invalidate(serviceID)
+
 	m.clientsMu.Lock()
 	defer m.clientsMu.Unlock()
 
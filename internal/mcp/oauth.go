@@ -0,0 +1,340 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"github.com/mark3labs/mcp-go/client/transport"
+)
+
+const (
+	// oauthClientName is the client name used for dynamic client registration
+	oauthClientName = "WeKnora"
+	// oauthPendingTTL bounds how long an authorization request can wait for its callback
+	oauthPendingTTL = 10 * time.Minute
+	// oauthRefreshSkew refreshes access tokens slightly before they expire
+	oauthRefreshSkew = time.Minute
+	// oauthRequestTimeout bounds discovery, registration and token requests
+	oauthRequestTimeout = 30 * time.Second
+)
+
+// oauthTokenStore implements transport.TokenStore on top of the encrypted credential table,
+// so that tokens refreshed by the transport are persisted and shared across clients.
+type oauthTokenStore struct {
+	repo      interfaces.MCPOAuthCredentialRepository
+	tenantID  uint64
+	serviceID string
+}
+
+// GetToken returns the stored token of the service
+func (s *oauthTokenStore) GetToken(ctx context.Context) (*transport.Token, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	credential, err := s.repo.GetByServiceID(ctx, s.tenantID, s.serviceID)
+	if err != nil {
+		return nil, err
+	}
+	if credential == nil || credential.AccessToken == "" {
+		return nil, transport.ErrNoToken
+	}
+	token := &transport.Token{
+		AccessToken:  string(credential.AccessToken),
+		TokenType:    credential.TokenType,
+		RefreshToken: string(credential.RefreshToken),
+		Scope:        credential.Scope,
+	}
+	if credential.ExpiresAt != nil {
+		token.ExpiresAt = *credential.ExpiresAt
+	}
+	return token, nil
+}
+
+// SaveToken persists a token obtained from the authorization server
+func (s *oauthTokenStore) SaveToken(ctx context.Context, token *transport.Token) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	credential, err := s.repo.GetByServiceID(ctx, s.tenantID, s.serviceID)
+	if err != nil {
+		return err
+	}
+	if credential == nil {
+		credential = &types.MCPOAuthCredential{ServiceID: s.serviceID, TenantID: s.tenantID}
+	}
+	credential.AccessToken = types.EncryptedString(token.AccessToken)
+	if token.RefreshToken != "" {
+		credential.RefreshToken = types.EncryptedString(token.RefreshToken)
+	}
+	credential.TokenType = token.TokenType
+	if token.Scope != "" {
+		credential.Scope = token.Scope
+	}
+	credential.ExpiresAt = nil
+	if !token.ExpiresAt.IsZero() {
+		expiresAt := token.ExpiresAt
+		credential.ExpiresAt = &expiresAt
+	}
+	credential.UpdatedAt = time.Now()
+	return s.repo.Save(ctx, credential)
+}
+
+// newOAuthConfig builds the mcp-go OAuth configuration of a service
+func (m *MCPManager) newOAuthConfig(
+	service *types.MCPService,
+	credential *types.MCPOAuthCredential,
+) transport.OAuthConfig {
+	oauth := service.AuthConfig.OAuth
+	config := transport.OAuthConfig{
+		ClientID:              credential.ClientID,
+		ClientSecret:          string(credential.ClientSecret),
+		RedirectURI:           credential.RedirectURI,
+		Scopes:                oauth.Scopes,
+		AuthServerMetadataURL: oauth.AuthServerMetadataURL,
+		PKCEEnabled:           true,
+		TokenStore: &oauthTokenStore{
+			repo:      m.oauthRepo,
+			tenantID:  service.TenantID,
+			serviceID: service.ID,
+		},
+	}
+	return config
+}
+
+// newOAuthHandler creates an OAuth handler whose discovery starts at the MCP server origin
+func (m *MCPManager) newOAuthHandler(
+	service *types.MCPService,
+	credential *types.MCPOAuthCredential,
+) (*transport.OAuthHandler, error) {
+	baseURL, err := serviceOrigin(service)
+	if err != nil {
+		return nil, err
+	}
+	handler := transport.NewOAuthHandler(m.newOAuthConfig(service, credential))
+	handler.SetBaseURL(baseURL)
+	return handler, nil
+}
+
+// serviceOrigin returns scheme://host of the service URL
+func serviceOrigin(service *types.MCPService) (string, error) {
+	if service.URL == nil || *service.URL == "" {
+		return "", fmt.Errorf("URL is required for OAuth authorization")
+	}
+	parsed, err := url.Parse(*service.URL)
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		return "", fmt.Errorf("invalid MCP service URL")
+	}
+	return parsed.Scheme + "://" + parsed.Host, nil
+}
+
+// StartOAuthAuthorization prepares a PKCE authorization request for the service.
+// Authorization server metadata is discovered from the MCP server, and the client is
+// registered dynamically unless a client ID is configured.
+func (m *MCPManager) StartOAuthAuthorization(
+	ctx context.Context,
+	service *types.MCPService,
+	redirectURI string,
+) (*types.MCPOAuthAuthorization, error) {
+	if !service.UsesOAuth() {
+		return nil, ErrOAuthNotEnabled
+	}
+	if m.oauthRepo == nil {
+		return nil, fmt.Errorf("OAuth credential storage is not configured")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, oauthRequestTimeout)
+	defer cancel()
+
+	credential, err := m.oauthRepo.GetByServiceID(ctx, service.TenantID, service.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load OAuth credential: %w", err)
+	}
+	if credential == nil {
+		credential = &types.MCPOAuthCredential{
+			ServiceID: service.ID,
+			TenantID:  service.TenantID,
+			CreatedAt: time.Now(),
+		}
+	}
+
+	// A configured client takes precedence; a registered client is reused unless the redirect URI changed
+	oauth := service.AuthConfig.OAuth
+	needsRegistration := false
+	if oauth.ClientID != "" {
+		credential.ClientID = oauth.ClientID
+		credential.ClientSecret = types.EncryptedString(oauth.ClientSecret)
+	} else if credential.ClientID == "" || credential.RedirectURI != redirectURI {
+		credential.ClientID = ""
+		credential.ClientSecret = ""
+		needsRegistration = true
+	}
+	credential.RedirectURI = redirectURI
+
+	handler, err := m.newOAuthHandler(service, credential)
+	if err != nil {
+		return nil, err
+	}
+	if needsRegistration {
+		if err := handler.RegisterClient(ctx, oauthClientName); err != nil {
+			return nil, fmt.Errorf("dynamic client registration failed: %w", err)
+		}
+		credential.ClientID = handler.GetClientID()
+		credential.ClientSecret = types.EncryptedString(handler.GetClientSecret())
+		logger.Infof(ctx, "Registered OAuth client for MCP service %s", service.ID)
+	}
+
+	codeVerifier, err := transport.GenerateCodeVerifier()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate code verifier: %w", err)
+	}
+	state, err := transport.GenerateState()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate state: %w", err)
+	}
+	authURL, err := handler.GetAuthorizationURL(ctx, state, transport.GenerateCodeChallenge(codeVerifier))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build authorization URL: %w", err)
+	}
+
+	// Bind the token to this MCP server (RFC 8707 resource indicator)
+	parsedAuthURL, err := url.Parse(authURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid authorization URL: %w", err)
+	}
+	query := parsedAuthURL.Query()
+	query.Set("resource", *service.URL)
+	parsedAuthURL.RawQuery = query.Encode()
+
+	expiresAt := time.Now().Add(oauthPendingTTL)
+	credential.PendingState = state
+	credential.CodeVerifier = types.EncryptedString(codeVerifier)
+	credential.PendingExpiresAt = &expiresAt
+	credential.UpdatedAt = time.Now()
+	if err := m.oauthRepo.Save(ctx, credential); err != nil {
+		return nil, fmt.Errorf("failed to save OAuth credential: %w", err)
+	}
+
+	return &types.MCPOAuthAuthorization{
+		AuthorizationURL: parsedAuthURL.String(),
+		State:            state,
+		ExpiresAt:        expiresAt,
+	}, nil
+}
+
+// CompleteOAuthAuthorization exchanges the authorization code for tokens and stores them encrypted
+func (m *MCPManager) CompleteOAuthAuthorization(
+	ctx context.Context,
+	service *types.MCPService,
+	credential *types.MCPOAuthCredential,
+	state string,
+	code string,
+) error {
+	if !service.UsesOAuth() {
+		return ErrOAuthNotEnabled
+	}
+	if credential.PendingState == "" || credential.PendingState != state {
+		return ErrOAuthInvalidState
+	}
+	if credential.PendingExpiresAt == nil || time.Now().After(*credential.PendingExpiresAt) {
+		return ErrOAuthStateExpired
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, oauthRequestTimeout)
+	defer cancel()
+
+	handler, err := m.newOAuthHandler(service, credential)
+	if err != nil {
+		return err
+	}
+	handler.SetExpectedState(credential.PendingState)
+	if err := handler.ProcessAuthorizationResponse(ctx, code, state, string(credential.CodeVerifier)); err != nil {
+		return fmt.Errorf("token exchange failed: %w", err)
+	}
+
+	// Clear the pending request; tokens were written by the token store
+	saved, err := m.oauthRepo.GetByServiceID(ctx, service.TenantID, service.ID)
+	if err != nil || saved == nil {
+		return fmt.Errorf("failed to reload OAuth credential: %v", err)
+	}
+	saved.PendingState = ""
+	saved.CodeVerifier = ""
+	saved.PendingExpiresAt = nil
+	saved.UpdatedAt = time.Now()
+	if err := m.oauthRepo.Save(ctx, saved); err != nil {
+		return fmt.Errorf("failed to save OAuth credential: %w", err)
+	}
+
+	// Reconnect with the new token on next use
+	return m.CloseClient(service.ID)
+}
+
+// ensureOAuthToken makes sure the service has a usable access token, refreshing it when
+// it is about to expire, and returns the OAuth configuration for the transport.
+func (m *MCPManager) ensureOAuthToken(service *types.MCPService) (*transport.OAuthConfig, error) {
+	if m.oauthRepo == nil {
+		return nil, fmt.Errorf("OAuth credential storage is not configured")
+	}
+
+	ctx, cancel := context.WithTimeout(m.ctx, oauthRequestTimeout)
+	defer cancel()
+
+	credential, err := m.oauthRepo.GetByServiceID(ctx, service.TenantID, service.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load OAuth credential: %w", err)
+	}
+	if credential == nil || credential.AccessToken == "" {
+		return nil, ErrOAuthAuthorizationRequired
+	}
+
+	config := m.newOAuthConfig(service, credential)
+	if credential.ExpiresAt == nil || time.Until(*credential.ExpiresAt) > oauthRefreshSkew {
+		return &config, nil
+	}
+
+	expired := time.Now().After(*credential.ExpiresAt)
+	if credential.RefreshToken == "" {
+		if expired {
+			return nil, ErrOAuthAuthorizationRequired
+		}
+		return &config, nil
+	}
+
+	handler, err := m.newOAuthHandler(service, credential)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := handler.RefreshToken(ctx, string(credential.RefreshToken)); err != nil {
+		if expired {
+			return nil, fmt.Errorf("%w: token refresh failed: %v", ErrOAuthAuthorizationRequired, err)
+		}
+		logger.GetLogger(ctx).Warnf("Failed to refresh OAuth token for MCP service %s: %v", service.ID, err)
+		return &config, nil
+	}
+	logger.GetLogger(ctx).Infof("Refreshed OAuth token for MCP service %s", service.ID)
+	return &config, nil
+}
+
+// ClientConfigFor returns the client configuration of a service, including OAuth when enabled
+func (m *MCPManager) ClientConfigFor(service *types.MCPService) (*ClientConfig, error) {
+	config := &ClientConfig{Service: service}
+	if service.UsesOAuth() {
+		oauthConfig, err := m.ensureOAuthToken(service)
+		if err != nil {
+			return nil, err
+		}
+		config.OAuth = oauthConfig
+	}
+	return config, nil
+}
+
+// IsOAuthAuthorizationRequired reports whether err means the user must (re-)authorize the service
+func IsOAuthAuthorizationRequired(err error) bool {
+	var required *transport.OAuthAuthorizationRequiredError
+	return errors.Is(err, ErrOAuthAuthorizationRequired) || errors.As(err, &required)
+}
@@ -0,0 +1,165 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+const (
+	// resourceCacheTTL is how long a resource read stays cached before it is fetched again
+	resourceCacheTTL = 5 * time.Minute
+	// maxCachedResources bounds the number of cached resource entries
+	maxCachedResources = 256
+	// MaxResourceTextBytes is the hard upper bound on resource text kept in memory
+	MaxResourceTextBytes = 512 * 1024
+)
+
+// ResourceText is the flattened textual content of an MCP resource
+type ResourceText struct {
+	URI       string `json:"uri"`
+	MimeType  string `json:"mime_type,omitempty"`
+	Text      string `json:"text"`
+	Truncated bool   `json:"truncated"`
+	Cached    bool   `json:"cached"`
+}
+
+type cachedResource struct {
+	resource  ResourceText
+	expiresAt time.Time
+}
+
+// resourceCache is a small TTL cache for resource reads, keyed by service and URI
+type resourceCache struct {
+	mu      sync.Mutex
+	entries map[string]*cachedResource
+}
+
+func newResourceCache() *resourceCache {
+	return &resourceCache{entries: make(map[string]*cachedResource)}
+}
+
+func resourceCacheKey(serviceID, uri string) string {
+	return serviceID + "|" + uri
+}
+
+func (c *resourceCache) get(serviceID, uri string) (ResourceText, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[resourceCacheKey(serviceID, uri)]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return ResourceText{}, false
+	}
+	return entry.resource, true
+}
+
+func (c *resourceCache) put(serviceID string, resource ResourceText) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if len(c.entries) >= maxCachedResources {
+		// Drop expired entries first, then an arbitrary one if still full
+		for key, entry := range c.entries {
+			if now.After(entry.expiresAt) {
+				delete(c.entries, key)
+			}
+		}
+		for key := range c.entries {
+			if len(c.entries) < maxCachedResources {
+				break
+			}
+			delete(c.entries, key)
+		}
+	}
+	c.entries[resourceCacheKey(serviceID, resource.URI)] = &cachedResource{
+		resource:  resource,
+		expiresAt: now.Add(resourceCacheTTL),
+	}
+}
+
+// invalidate drops all cached resources of a service
+func (c *resourceCache) invalidate(serviceID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	prefix := serviceID + "|"
+	for key := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// ReadResourceText reads a resource from an MCP service and returns its text content,
+// truncated to MaxResourceTextBytes. Results are cached per service and URI for a short time.
+func (m *MCPManager) ReadResourceText(
+	ctx context.Context,
+	service *types.MCPService,
+	uri string,
+) (ResourceText, error) {
+	if cached, ok := m.resources.get(service.ID, uri); ok {
+		cached.Cached = true
+		return cached, nil
+	}
+
+	client, err := m.GetOrCreateClient(service)
+	if err != nil {
+		return ResourceText{}, fmt.Errorf("failed to get MCP client: %w", err)
+	}
+
+	result, err := client.ReadResource(ctx, uri)
+	if err != nil {
+		return ResourceText{}, err
+	}
+
+	resource := ResourceText{URI: uri}
+	var builder strings.Builder
+	for _, content := range result.Contents {
+		if resource.MimeType == "" {
+			resource.MimeType = content.MimeType
+		}
+		if content.Text == "" {
+			if content.Blob != "" {
+				if builder.Len() > 0 {
+					builder.WriteString("\n")
+				}
+				fmt.Fprintf(&builder, "[Binary content: %s]", content.MimeType)
+			}
+			continue
+		}
+		if builder.Len() > 0 {
+			builder.WriteString("\n")
+		}
+		builder.WriteString(content.Text)
+		if builder.Len() > MaxResourceTextBytes {
+			break
+		}
+	}
+
+	resource.Text = builder.String()
+	if len(resource.Text) > MaxResourceTextBytes {
+		resource.Text = truncateUTF8(resource.Text, MaxResourceTextBytes)
+		resource.Truncated = true
+	}
+
+	m.resources.put(service.ID, resource)
+	return resource, nil
+}
+
+// truncateUTF8 cuts s to at most maxBytes without splitting a multi-byte rune
+func truncateUTF8(s string, maxBytes int) string {
+	if len(s) <= maxBytes {
+		return s
+	}
+	cut := maxBytes
+	for cut > 0 && (s[cut]&0xC0) == 0x80 {
+		cut--
+	}
+	return s[:cut]
+}
@@ -0,0 +1,511 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/event"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	// KnowledgeServerBasePath is where the MCP server is mounted; SSE uses <base>/sse and <base>/message
+	KnowledgeServerBasePath = "/mcp"
+
+	// knowledgeResourceURIPrefix prefixes the URI of every knowledge document resource
+	knowledgeResourceURIPrefix   = "weknora://knowledge/"
+	knowledgeResourceURITemplate = knowledgeResourceURIPrefix + "{knowledge_id}"
+
+	// maxListedResources caps the number of documents returned by resources/list
+	maxListedResources = 500
+	// defaultDocumentMaxChars caps document text returned by get_document and resources/read
+	defaultDocumentMaxChars = 100000
+	// askAgentTimeout bounds a single ask_agent run
+	askAgentTimeout = 5 * time.Minute
+)
+
+// KnowledgeServer exposes WeKnora knowledge bases and agents to external MCP clients.
+// Tenant scope comes from the request context populated by the auth middleware.
+type KnowledgeServer struct {
+	mcpServer            *server.MCPServer
+	streamableServer     *server.StreamableHTTPServer
+	sseServer            *server.SSEServer
+	knowledgeBaseService interfaces.KnowledgeBaseService
+	knowledgeService     interfaces.KnowledgeService
+	chunkService         interfaces.ChunkService
+	sessionService       interfaces.SessionService
+	messageService       interfaces.MessageService
+	customAgentService   interfaces.CustomAgentService
+}
+
+// NewKnowledgeServer creates the MCP server with its tools and resources registered
+func NewKnowledgeServer(
+	knowledgeBaseService interfaces.KnowledgeBaseService,
+	knowledgeService interfaces.KnowledgeService,
+	chunkService interfaces.ChunkService,
+	sessionService interfaces.SessionService,
+	messageService interfaces.MessageService,
+	customAgentService interfaces.CustomAgentService,
+) *KnowledgeServer {
+	s := &KnowledgeServer{
+		knowledgeBaseService: knowledgeBaseService,
+		knowledgeService:     knowledgeService,
+		chunkService:         chunkService,
+		sessionService:       sessionService,
+		messageService:       messageService,
+		customAgentService:   customAgentService,
+	}
+
+	hooks := &server.Hooks{}
+	hooks.AddAfterListResources(s.appendKnowledgeResources)
+
+	s.mcpServer = server.NewMCPServer(
+		"WeKnora",
+		"1.0.0",
+		server.WithToolCapabilities(false),
+		server.WithResourceCapabilities(false, false),
+		server.WithHooks(hooks),
+		server.WithRecovery(),
+		server.WithResourceRecovery(),
+		server.WithInstructions("Search and read the knowledge bases of your WeKnora tenant, or ask one of its agents."),
+	)
+	s.registerTools()
+	s.mcpServer.AddResourceTemplate(
+		mcp.NewResourceTemplate(knowledgeResourceURITemplate, "Knowledge document",
+			mcp.WithTemplateDescription("Parsed text of a knowledge document"),
+			mcp.WithTemplateMIMEType("text/markdown"),
+		),
+		s.readKnowledgeResource,
+	)
+
+	s.streamableServer = server.NewStreamableHTTPServer(s.mcpServer,
+		server.WithEndpointPath(KnowledgeServerBasePath),
+	)
+	s.sseServer = server.NewSSEServer(s.mcpServer,
+		server.WithStaticBasePath(KnowledgeServerBasePath),
+		server.WithUseFullURLForMessageEndpoint(false),
+	)
+	return s
+}
+
+// StreamableHTTPHandler serves the streamable HTTP transport
+func (s *KnowledgeServer) StreamableHTTPHandler() http.Handler {
+	return s.streamableServer
+}
+
+// SSEHandler serves the event stream of the legacy SSE transport
+func (s *KnowledgeServer) SSEHandler() http.Handler {
+	return s.sseServer.SSEHandler()
+}
+
+// MessageHandler serves the message endpoint of the legacy SSE transport
+func (s *KnowledgeServer) MessageHandler() http.Handler {
+	return s.sseServer.MessageHandler()
+}
+
+// registerTools registers the tools exposed to MCP clients
+func (s *KnowledgeServer) registerTools() {
+	s.mcpServer.AddTool(mcp.NewTool("hybrid_search",
+		mcp.WithDescription("Hybrid (vector + keyword) search over knowledge bases. "+
+			"Searches all knowledge bases of the tenant when none are given."),
+		mcp.WithString("query", mcp.Required(), mcp.Description("Search query")),
+		mcp.WithArray("knowledge_base_ids", mcp.WithStringItems(), mcp.Description("Knowledge bases to search")),
+		mcp.WithArray("knowledge_ids", mcp.WithStringItems(), mcp.Description("Restrict search to these documents")),
+		mcp.WithNumber("top_k", mcp.Description("Maximum number of results (default 10)")),
+		mcp.WithReadOnlyHintAnnotation(true),
+	), s.handleHybridSearch)
+
+	s.mcpServer.AddTool(mcp.NewTool("list_knowledge_bases",
+		mcp.WithDescription("List the knowledge bases of the tenant"),
+		mcp.WithReadOnlyHintAnnotation(true),
+	), s.handleListKnowledgeBases)
+
+	s.mcpServer.AddTool(mcp.NewTool("get_document",
+		mcp.WithDescription("Get metadata and parsed text of a knowledge document"),
+		mcp.WithString("knowledge_id", mcp.Required(), mcp.Description("Knowledge (document) ID")),
+		mcp.WithNumber("max_chars", mcp.Description("Maximum characters of text to return (default 100000)")),
+		mcp.WithReadOnlyHintAnnotation(true),
+	), s.handleGetDocument)
+
+	s.mcpServer.AddTool(mcp.NewTool("ask_agent",
+		mcp.WithDescription("Ask a WeKnora agent a question and get its answer with cited references"),
+		mcp.WithString("query", mcp.Required(), mcp.Description("Question to ask")),
+		mcp.WithString("agent_id", mcp.Description("Custom agent ID (default: built-in quick answer agent)")),
+		mcp.WithArray("knowledge_base_ids", mcp.WithStringItems(), mcp.Description("Knowledge bases to answer from")),
+		mcp.WithDestructiveHintAnnotation(false),
+	), s.handleAskAgent)
+}
+
+func (s *KnowledgeServer) handleHybridSearch(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	query, err := request.RequireString("query")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	kbIDs := request.GetStringSlice("knowledge_base_ids", nil)
+	knowledgeIDs := request.GetStringSlice("knowledge_ids", nil)
+	topK := request.GetInt("top_k", 10)
+
+	if len(kbIDs) == 0 && len(knowledgeIDs) == 0 {
+		kbs, err := s.knowledgeBaseService.ListKnowledgeBases(ctx)
+		if err != nil {
+			return mcp.NewToolResultErrorFromErr("failed to list knowledge bases", err), nil
+		}
+		for _, kb := range kbs {
+			kbIDs = append(kbIDs, kb.ID)
+		}
+	}
+
+	results, err := s.sessionService.SearchKnowledge(ctx, kbIDs, knowledgeIDs, query)
+	if err != nil {
+		logger.Errorf(ctx, "[MCPServer] hybrid_search failed: %v", err)
+		return mcp.NewToolResultErrorFromErr("search failed", err), nil
+	}
+	if topK > 0 && len(results) > topK {
+		results = results[:topK]
+	}
+
+	items := make([]map[string]interface{}, 0, len(results))
+	for _, r := range results {
+		items = append(items, map[string]interface{}{
+			"chunk_id":        r.ID,
+			"knowledge_id":    r.KnowledgeID,
+			"knowledge_title": r.KnowledgeTitle,
+			"resource_uri":    knowledgeResourceURIPrefix + r.KnowledgeID,
+			"score":           r.Score,
+			"content":         r.Content,
+		})
+	}
+	return jsonToolResult(map[string]interface{}{"results": items})
+}
+
+func (s *KnowledgeServer) handleListKnowledgeBases(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	kbs, err := s.knowledgeBaseService.ListKnowledgeBases(ctx)
+	if err != nil {
+		return mcp.NewToolResultErrorFromErr("failed to list knowledge bases", err), nil
+	}
+	items := make([]map[string]interface{}, 0, len(kbs))
+	for _, kb := range kbs {
+		items = append(items, map[string]interface{}{
+			"id":              kb.ID,
+			"name":            kb.Name,
+			"type":            kb.Type,
+			"description":     kb.Description,
+			"knowledge_count": kb.KnowledgeCount,
+		})
+	}
+	return jsonToolResult(map[string]interface{}{"knowledge_bases": items})
+}
+
+func (s *KnowledgeServer) handleGetDocument(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	knowledgeID, err := request.RequireString("knowledge_id")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	knowledge, text, err := s.loadDocument(ctx, knowledgeID, request.GetInt("max_chars", defaultDocumentMaxChars))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return jsonToolResult(map[string]interface{}{
+		"id":                knowledge.ID,
+		"knowledge_base_id": knowledge.KnowledgeBaseID,
+		"title":             knowledge.Title,
+		"file_name":         knowledge.FileName,
+		"file_type":         knowledge.FileType,
+		"source":            knowledge.Source,
+		"parse_status":      knowledge.ParseStatus,
+		"resource_uri":      knowledgeResourceURIPrefix + knowledge.ID,
+		"content":           text,
+	})
+}
+
+func (s *KnowledgeServer) handleAskAgent(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	query, err := request.RequireString("query")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	agentID := request.GetString("agent_id", types.BuiltinQuickAnswerID)
+	agent, err := s.customAgentService.GetLiveAgent(ctx, agentID)
+	if err != nil || agent == nil {
+		return mcp.NewToolResultErrorf("agent not found: %s", agentID), nil
+	}
+
+	answer, refs, err := s.askAgent(ctx, agent, query, request.GetStringSlice("knowledge_base_ids", nil))
+	if err != nil {
+		logger.Errorf(ctx, "[MCPServer] ask_agent failed: %v", err)
+		return mcp.NewToolResultErrorFromErr("agent run failed", err), nil
+	}
+
+	references := make([]map[string]interface{}, 0, len(refs))
+	for _, r := range refs {
+		references = append(references, map[string]interface{}{
+			"chunk_id":        r.ID,
+			"knowledge_id":    r.KnowledgeID,
+			"knowledge_title": r.KnowledgeTitle,
+			"resource_uri":    knowledgeResourceURIPrefix + r.KnowledgeID,
+			"content":         r.Content,
+		})
+	}
+	return jsonToolResult(map[string]interface{}{
+		"answer":     answer,
+		"references": references,
+	})
+}
+
+// askAgent runs the agent in a fresh session and waits for its final answer
+func (s *KnowledgeServer) askAgent(
+	ctx context.Context,
+	agent *types.CustomAgent,
+	query string,
+	kbIDs []string,
+) (string, []*types.SearchResult, error) {
+	tenantID, _ := ctx.Value(types.TenantIDContextKey).(uint64)
+	session, err := s.sessionService.CreateSession(ctx, &types.Session{
+		TenantID:    tenantID,
+		Title:       truncateRunes(query, 50),
+		Description: "mcp",
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("create session: %w", err)
+	}
+	if _, err := s.messageService.CreateMessage(ctx, &types.Message{
+		SessionID:   session.ID,
+		Role:        "user",
+		Content:     query,
+		CreatedAt:   time.Now(),
+		IsCompleted: true,
+	}); err != nil {
+		return "", nil, fmt.Errorf("create user message: %w", err)
+	}
+	assistantMessage, err := s.messageService.CreateMessage(ctx, &types.Message{
+		SessionID: session.ID,
+		Role:      "assistant",
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("create assistant message: %w", err)
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, askAgentTimeout)
+	defer cancel()
+
+	var (
+		mu     sync.Mutex
+		answer strings.Builder
+		refs   []*types.SearchResult
+		runErr error
+		once   sync.Once
+	)
+	done := make(chan struct{})
+	finish := func() { once.Do(func() { close(done) }) }
+
+	agentMode := agent.RunsAgentQA()
+	eventBus := event.NewEventBus()
+	eventBus.On(event.EventAgentFinalAnswer, func(ctx context.Context, evt event.Event) error {
+		data, ok := evt.Data.(event.AgentFinalAnswerData)
+		if !ok {
+			return nil
+		}
+		mu.Lock()
+		answer.WriteString(data.Content)
+		mu.Unlock()
+		if data.Done && !agentMode {
+			finish()
+		}
+		return nil
+	})
+	eventBus.On(event.EventAgentReferences, func(ctx context.Context, evt event.Event) error {
+		if data, ok := evt.Data.(event.AgentReferencesData); ok {
+			if results, ok := data.References.([]*types.SearchResult); ok {
+				mu.Lock()
+				refs = append(refs, results...)
+				mu.Unlock()
+			}
+		}
+		return nil
+	})
+	eventBus.On(event.EventAgentComplete, func(ctx context.Context, evt event.Event) error {
+		if data, ok := evt.Data.(event.AgentCompleteData); ok && data.FinalAnswer != "" {
+			mu.Lock()
+			answer.Reset()
+			answer.WriteString(data.FinalAnswer)
+			mu.Unlock()
+		}
+		finish()
+		return nil
+	})
+	eventBus.On(event.EventError, func(ctx context.Context, evt event.Event) error {
+		if data, ok := evt.Data.(event.ErrorData); ok {
+			mu.Lock()
+			runErr = errors.New(data.Error)
+			mu.Unlock()
+		}
+		finish()
+		return nil
+	})
+
+	go func() {
+		var err error
+		if agentMode {
+			err = s.sessionService.AgentQA(runCtx, session, query, assistantMessage.ID, "", eventBus, agent, kbIDs, nil, nil)
+		} else {
+			err = s.sessionService.KnowledgeQA(runCtx, session, query, kbIDs, nil, assistantMessage.ID, "", false, eventBus, agent, nil)
+		}
+		if err != nil {
+			eventBus.Emit(runCtx, event.Event{
+				Type:      event.EventError,
+				SessionID: session.ID,
+				Data:      event.ErrorData{Error: err.Error(), Stage: "mcp_ask_agent", SessionID: session.ID},
+			})
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-runCtx.Done():
+	}
+
+	mu.Lock()
+	if runErr == nil {
+		runErr = runCtx.Err()
+	}
+	assistantMessage.Content = answer.String()
+	assistantMessage.KnowledgeReferences = refs
+	mu.Unlock()
+	assistantMessage.IsCompleted = true
+	assistantMessage.UpdatedAt = time.Now()
+	if err := s.messageService.UpdateMessage(context.WithoutCancel(ctx), assistantMessage); err != nil {
+		logger.Warnf(ctx, "[MCPServer] Failed to save assistant message: %v", err)
+	}
+	if runErr != nil {
+		return "", nil, runErr
+	}
+	return assistantMessage.Content, refs, nil
+}
+
+// readKnowledgeResource serves resources/read for weknora://knowledge/{knowledge_id}
+func (s *KnowledgeServer) readKnowledgeResource(
+	ctx context.Context,
+	request mcp.ReadResourceRequest,
+) ([]mcp.ResourceContents, error) {
+	knowledgeID := strings.TrimPrefix(request.Params.URI, knowledgeResourceURIPrefix)
+	_, text, err := s.loadDocument(ctx, knowledgeID, defaultDocumentMaxChars)
+	if err != nil {
+		return nil, err
+	}
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{
+			URI:      request.Params.URI,
+			MIMEType: "text/markdown",
+			Text:     text,
+		},
+	}, nil
+}
+
+// appendKnowledgeResources adds the tenant's documents to resources/list results.
+// Documents are tenant scoped, so they cannot be registered as static server resources.
+func (s *KnowledgeServer) appendKnowledgeResources(
+	ctx context.Context,
+	id any,
+	message *mcp.ListResourcesRequest,
+	result *mcp.ListResourcesResult,
+) {
+	if result == nil {
+		return
+	}
+	kbs, err := s.knowledgeBaseService.ListKnowledgeBases(ctx)
+	if err != nil {
+		logger.Warnf(ctx, "[MCPServer] Failed to list knowledge bases for resources: %v", err)
+		return
+	}
+	for _, kb := range kbs {
+		remaining := maxListedResources - len(result.Resources)
+		if remaining <= 0 {
+			return
+		}
+		page, err := s.knowledgeService.ListPagedKnowledgeByKnowledgeBaseID(ctx, kb.ID,
+			&types.Pagination{Page: 1, PageSize: min(remaining, 100)}, "", "", "")
+		if err != nil {
+			logger.Warnf(ctx, "[MCPServer] Failed to list knowledge of %s: %v", kb.ID, err)
+			continue
+		}
+		knowledges, _ := page.Data.([]*types.Knowledge)
+		for _, k := range knowledges {
+			name := k.Title
+			if name == "" {
+				name = k.FileName
+			}
+			result.Resources = append(result.Resources, mcp.NewResource(
+				knowledgeResourceURIPrefix+k.ID, name,
+				mcp.WithResourceDescription(fmt.Sprintf("%s (knowledge base: %s)", k.Description, kb.Name)),
+				mcp.WithMIMEType("text/markdown"),
+			))
+		}
+	}
+}
+
+// loadDocument loads a knowledge document of the current tenant and joins its text chunks
+func (s *KnowledgeServer) loadDocument(ctx context.Context, knowledgeID string, maxChars int) (*types.Knowledge, string, error) {
+	if knowledgeID == "" {
+		return nil, "", fmt.Errorf("knowledge_id is required")
+	}
+	knowledge, err := s.knowledgeService.GetKnowledgeByID(ctx, knowledgeID)
+	if err != nil || knowledge == nil {
+		return nil, "", fmt.Errorf("knowledge not found: %s", knowledgeID)
+	}
+	chunks, err := s.chunkService.GetRepository().ListChunksByKnowledgeID(ctx, knowledge.TenantID, knowledge.ID)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to load document content: %w", err)
+	}
+	textChunks := make([]*types.Chunk, 0, len(chunks))
+	for _, c := range chunks {
+		if c.IsEnabled && (c.ChunkType == types.ChunkTypeText || c.ChunkType == types.ChunkTypeFAQ) {
+			textChunks = append(textChunks, c)
+		}
+	}
+	sort.Slice(textChunks, func(i, j int) bool { return textChunks[i].ChunkIndex < textChunks[j].ChunkIndex })
+
+	var sb strings.Builder
+	for _, c := range textChunks {
+		if sb.Len() > 0 {
+			sb.WriteString("\n\n")
+		}
+		sb.WriteString(c.Content)
+	}
+	text := sb.String()
+	if maxChars <= 0 {
+		maxChars = defaultDocumentMaxChars
+	}
+	if truncated := truncateRunes(text, maxChars); len(truncated) < len(text) {
+		text = truncated + "\n\n[truncated]"
+	}
+	return knowledge, text, nil
+}
+
+// jsonToolResult renders a value as both structured content and JSON text for older clients
+func jsonToolResult(v interface{}) (*mcp.CallToolResult, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultErrorFromErr("failed to encode result", err), nil
+	}
+	return mcp.NewToolResultStructured(v, string(data)), nil
+}
+
+// truncateRunes shortens s to at most n runes
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
@@ -0,0 +1,85 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// TaskMiddleware is an asynq middleware that records the duration and failures of every task
+func TaskMiddleware(next asynq.Handler) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+		start := time.Now()
+		err := next.ProcessTask(ctx, t)
+		ObserveTask(t.Type(), err, time.Since(start))
+		return err
+	})
+}
+
+// queueCollector reads the size of each asynq queue from redis at scrape time
+type queueCollector struct {
+	inspector *asynq.Inspector
+	size      *prometheus.Desc
+	processed *prometheus.Desc
+	failed    *prometheus.Desc
+}
+
+// NewQueueCollector creates a collector exposing the task count per queue and state,
+// and the processed and failed totals reported by asynq
+func NewQueueCollector(inspector *asynq.Inspector) prometheus.Collector {
+	return &queueCollector{
+		inspector: inspector,
+		size: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "task", "queue_size"),
+			"Number of tasks in an asynq queue by state.",
+			[]string{"queue", "state"}, nil,
+		),
+		processed: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "task", "queue_processed_total"),
+			"Tasks processed by an asynq queue since the queue was created.",
+			[]string{"queue"}, nil,
+		),
+		failed: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "task", "queue_failed_total"),
+			"Failed task attempts of an asynq queue since the queue was created.",
+			[]string{"queue"}, nil,
+		),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *queueCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.size
+	ch <- c.processed
+	ch <- c.failed
+}
+
+// Collect implements prometheus.Collector
+func (c *queueCollector) Collect(ch chan<- prometheus.Metric) {
+	queues, err := c.inspector.Queues()
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.size, err)
+		return
+	}
+	for _, queue := range queues {
+		info, err := c.inspector.GetQueueInfo(queue)
+		if err != nil {
+			ch <- prometheus.NewInvalidMetric(c.size, err)
+			continue
+		}
+		states := map[string]int{
+			"pending":   info.Pending,
+			"active":    info.Active,
+			"scheduled": info.Scheduled,
+			"retry":     info.Retry,
+			"archived":  info.Archived,
+		}
+		for state, n := range states {
+			ch <- prometheus.MustNewConstMetric(c.size, prometheus.GaugeValue, float64(n), queue, state)
+		}
+		ch <- prometheus.MustNewConstMetric(c.processed, prometheus.CounterValue, float64(info.ProcessedTotal), queue)
+		ch <- prometheus.MustNewConstMetric(c.failed, prometheus.CounterValue, float64(info.FailedTotal), queue)
+	}
+}
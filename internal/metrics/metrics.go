@@ -0,0 +1,256 @@
+package metrics
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "weknora"
+
+// Result label values
+const (
+	ResultSuccess  = "success"
+	ResultError    = "error"
+	ResultRejected = "rejected"
+)
+
+// Model type label values
+const (
+	ModelTypeChat      = "chat"
+	ModelTypeEmbedding = "embedding"
+	ModelTypeRerank    = "rerank"
+)
+
+// Tool kind label values; only builtin tools keep their name in the tool label,
+// so that per-tenant MCP, HTTP and sub-agent tools do not create unbounded series
+const (
+	ToolKindBuiltin  = "builtin"
+	ToolKindMCP      = "mcp"
+	ToolKindHTTP     = "http"
+	ToolKindSubAgent = "sub_agent"
+	ToolKindUnknown  = "unknown"
+)
+
+// Latency buckets from 5ms to about 2 minutes
+var latencyBuckets = prometheus.ExponentialBuckets(0.005, 2, 15)
+
+var registry = prometheus.NewRegistry()
+
+var (
+	pipelineStageDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "pipeline",
+		Name:      "stage_duration_seconds",
+		Help:      "Duration of chat pipeline stages by event type and result.",
+		Buckets:   latencyBuckets,
+	}, []string{"event", "result"})
+
+	agentRoundDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "agent",
+		Name:      "round_duration_seconds",
+		Help:      "Duration of a single agent round (think and act).",
+		Buckets:   latencyBuckets,
+	})
+
+	agentRunRounds = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "agent",
+		Name:      "run_rounds",
+		Help:      "Number of rounds an agent run took before answering.",
+		Buckets:   prometheus.LinearBuckets(1, 1, 20),
+	})
+
+	toolCallDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "agent",
+		Name:      "tool_call_duration_seconds",
+		Help:      "Duration of agent tool calls by tool kind, builtin tool name and result.",
+		Buckets:   latencyBuckets,
+	}, []string{"kind", "tool", "result"})
+
+	modelCallDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "model",
+		Name:      "call_duration_seconds",
+		Help:      "Duration of model calls by provider, model type, mode and result.",
+		Buckets:   latencyBuckets,
+	}, []string{"provider", "model_type", "mode", "result"})
+
+	modelFirstTokenDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "model",
+		Name:      "first_token_duration_seconds",
+		Help:      "Time until the first streamed chunk of a chat model arrives.",
+		Buckets:   latencyBuckets,
+	}, []string{"provider"})
+
+	modelTokens = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "model",
+		Name:      "tokens_total",
+		Help:      "Tokens reported by non-streaming chat model calls by provider and kind (prompt, completion).",
+	}, []string{"provider", "kind"})
+
+	embeddingBatchSize = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "model",
+		Name:      "embedding_batch_size",
+		Help:      "Number of texts sent in a single embedding request.",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 10),
+	}, []string{"provider"})
+
+	retrieveDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "retriever",
+		Name:      "retrieve_duration_seconds",
+		Help:      "Duration of retrieval by engine, retriever type and result.",
+		Buckets:   latencyBuckets,
+	}, []string{"engine", "retriever_type", "result"})
+
+	taskDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "task",
+		Name:      "duration_seconds",
+		Help:      "Duration of asynchronous task processing by task type and result.",
+		Buckets:   latencyBuckets,
+	}, []string{"task_type", "result"})
+
+	taskFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "task",
+		Name:      "failures_total",
+		Help:      "Failed asynchronous task attempts by task type.",
+	}, []string{"task_type"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		pipelineStageDuration,
+		agentRoundDuration,
+		agentRunRounds,
+		toolCallDuration,
+		modelCallDuration,
+		modelFirstTokenDuration,
+		modelTokens,
+		embeddingBatchSize,
+		retrieveDuration,
+		taskDuration,
+		taskFailures,
+	)
+}
+
+// Handler returns the HTTP handler that exposes all metrics in the Prometheus text format.
+// When METRICS_TOKEN is set, requests must carry it as "Authorization: Bearer <token>".
+func Handler() http.Handler {
+	handler := promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+	token := os.Getenv("METRICS_TOKEN")
+	if token == "" {
+		return handler
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		provided, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// Enabled reports whether the metrics endpoint is exposed (METRICS_ENABLED, off by default)
+func Enabled() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv("METRICS_ENABLED"))
+	return enabled
+}
+
+// Register adds extra collectors to the registry served by Handler
+func Register(cs ...prometheus.Collector) error {
+	for _, c := range cs {
+		if err := registry.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Result maps an error to the result label value
+func Result(err error) string {
+	if err != nil {
+		return ResultError
+	}
+	return ResultSuccess
+}
+
+// ObservePipelineStage records the duration of a chat pipeline event
+func ObservePipelineStage(event string, result string, d time.Duration) {
+	pipelineStageDuration.WithLabelValues(event, result).Observe(d.Seconds())
+}
+
+// ObserveAgentRound records the duration of one agent round
+func ObserveAgentRound(d time.Duration) {
+	agentRoundDuration.Observe(d.Seconds())
+}
+
+// ObserveAgentRun records the number of rounds of a finished agent run
+func ObserveAgentRun(rounds int) {
+	agentRunRounds.Observe(float64(rounds))
+}
+
+// ObserveToolCall records the duration and result of an agent tool call.
+// The tool name is only kept for builtin tools.
+func ObserveToolCall(kind, tool, result string, d time.Duration) {
+	if kind != ToolKindBuiltin {
+		tool = ""
+	}
+	toolCallDuration.WithLabelValues(kind, tool, result).Observe(d.Seconds())
+}
+
+// ObserveModelCall records the duration of a model call; mode is the method name such as chat or stream
+func ObserveModelCall(provider, modelType, mode string, err error, d time.Duration) {
+	modelCallDuration.WithLabelValues(provider, modelType, mode, Result(err)).Observe(d.Seconds())
+}
+
+// ObserveFirstToken records the time to the first streamed chunk of a chat model
+func ObserveFirstToken(provider string, d time.Duration) {
+	modelFirstTokenDuration.WithLabelValues(provider).Observe(d.Seconds())
+}
+
+// AddModelTokens adds the prompt and completion tokens of a chat model call
+func AddModelTokens(provider string, promptTokens, completionTokens int) {
+	if promptTokens > 0 {
+		modelTokens.WithLabelValues(provider, "prompt").Add(float64(promptTokens))
+	}
+	if completionTokens > 0 {
+		modelTokens.WithLabelValues(provider, "completion").Add(float64(completionTokens))
+	}
+}
+
+// ObserveEmbeddingBatch records the number of texts in an embedding request
+func ObserveEmbeddingBatch(provider string, size int) {
+	embeddingBatchSize.WithLabelValues(provider).Observe(float64(size))
+}
+
+// ObserveRetrieve records the duration of a retrieval against one engine
+func ObserveRetrieve(engine, retrieverType string, err error, d time.Duration) {
+	retrieveDuration.WithLabelValues(engine, retrieverType, Result(err)).Observe(d.Seconds())
+}
+
+// ObserveTask records the duration of an asynchronous task attempt and counts failures
+func ObserveTask(taskType string, err error, d time.Duration) {
+	taskDuration.WithLabelValues(taskType, Result(err)).Observe(d.Seconds())
+	if err != nil {
+		taskFailures.WithLabelValues(taskType).Inc()
+	}
+}
@@ -0,0 +1,410 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// maxAuditBodySize 超过该大小的请求体/响应体不解析摘要字段
+	maxAuditBodySize = 1024 * 64
+	// maxAuditValueLength 摘要中单个字符串字段的最大长度
+	maxAuditValueLength = 256
+)
+
+// auditRule 描述一个需要审计的路由
+type auditRule struct {
+	Action       string
+	ResourceType string
+	// IDParam 资源ID所在的路径参数，IDField 资源ID所在的请求体字段；都为空时取响应 data.id
+	IDParam string
+	IDField string
+	// Params 需要记录到变更摘要中的路径参数，参数名 -> 摘要字段名
+	Params map[string]string
+}
+
+// auditRoutes 需要审计的路由，键为 "方法 完整路由"
+var auditRoutes = map[string]auditRule{
+	// 知识库
+	"POST /api/v1/knowledge-bases":       {Action: types.AuditActionCreate, ResourceType: types.AuditResourceKnowledgeBase},
+	"PUT /api/v1/knowledge-bases/:id":    {Action: types.AuditActionUpdate, ResourceType: types.AuditResourceKnowledgeBase, IDParam: "id"},
+	"DELETE /api/v1/knowledge-bases/:id": {Action: types.AuditActionDelete, ResourceType: types.AuditResourceKnowledgeBase, IDParam: "id"},
+	"POST /api/v1/knowledge-bases/copy":  {Action: types.AuditActionCopy, ResourceType: types.AuditResourceKnowledgeBase, IDField: "source_id"},
+	"GET /api/v1/knowledge-bases/:id/export": {
+		Action: types.AuditActionExport, ResourceType: types.AuditResourceKnowledgeBase, IDParam: "id",
+	},
+
+	// 知识
+	"POST /api/v1/knowledge-bases/:id/knowledge/file": {
+		Action: types.AuditActionCreate, ResourceType: types.AuditResourceKnowledge,
+		Params: map[string]string{"id": "knowledge_base_id"},
+	},
+	"POST /api/v1/knowledge-bases/:id/knowledge/url": {
+		Action: types.AuditActionCreate, ResourceType: types.AuditResourceKnowledge,
+		Params: map[string]string{"id": "knowledge_base_id"},
+	},
+	"POST /api/v1/knowledge-bases/:id/knowledge/manual": {
+		Action: types.AuditActionCreate, ResourceType: types.AuditResourceKnowledge,
+		Params: map[string]string{"id": "knowledge_base_id"},
+	},
+	"PUT /api/v1/knowledge/:id":          {Action: types.AuditActionUpdate, ResourceType: types.AuditResourceKnowledge, IDParam: "id"},
+	"PUT /api/v1/knowledge/manual/:id":   {Action: types.AuditActionUpdate, ResourceType: types.AuditResourceKnowledge, IDParam: "id"},
+	"DELETE /api/v1/knowledge/:id":       {Action: types.AuditActionDelete, ResourceType: types.AuditResourceKnowledge, IDParam: "id"},
+	"POST /api/v1/knowledge/:id/reparse": {Action: types.AuditActionReparse, ResourceType: types.AuditResourceKnowledge, IDParam: "id"},
+	"PUT /api/v1/knowledge/tags":         {Action: types.AuditActionUpdate, ResourceType: types.AuditResourceKnowledge},
+
+	// 知识库共享
+	"POST /api/v1/knowledge-bases/:id/shares": {
+		Action: types.AuditActionShare, ResourceType: types.AuditResourceKBShare,
+		Params: map[string]string{"id": "knowledge_base_id"},
+	},
+	"PUT /api/v1/knowledge-bases/:id/shares/:share_id": {
+		Action: types.AuditActionUpdatePermission, ResourceType: types.AuditResourceKBShare, IDParam: "share_id",
+		Params: map[string]string{"id": "knowledge_base_id"},
+	},
+	"DELETE /api/v1/knowledge-bases/:id/shares/:share_id": {
+		Action: types.AuditActionUnshare, ResourceType: types.AuditResourceKBShare, IDParam: "share_id",
+		Params: map[string]string{"id": "knowledge_base_id"},
+	},
+
+	// 智能体共享
+	"POST /api/v1/agents/:id/shares": {
+		Action: types.AuditActionShare, ResourceType: types.AuditResourceAgentShare,
+		Params: map[string]string{"id": "agent_id"},
+	},
+	"PUT /api/v1/agents/:id/shares/:share_id": {
+		Action: types.AuditActionPinVersion, ResourceType: types.AuditResourceAgentShare, IDParam: "share_id",
+		Params: map[string]string{"id": "agent_id"},
+	},
+	"DELETE /api/v1/agents/:id/shares/:share_id": {
+		Action: types.AuditActionUnshare, ResourceType: types.AuditResourceAgentShare, IDParam: "share_id",
+		Params: map[string]string{"id": "agent_id"},
+	},
+
+	// 组织
+	"POST /api/v1/organizations":              {Action: types.AuditActionCreate, ResourceType: types.AuditResourceOrganization},
+	"PUT /api/v1/organizations/:id":           {Action: types.AuditActionUpdate, ResourceType: types.AuditResourceOrganization, IDParam: "id"},
+	"DELETE /api/v1/organizations/:id":        {Action: types.AuditActionDelete, ResourceType: types.AuditResourceOrganization, IDParam: "id"},
+	"POST /api/v1/organizations/join":         {Action: types.AuditActionJoin, ResourceType: types.AuditResourceOrganization},
+	"POST /api/v1/organizations/join-by-id":   {Action: types.AuditActionJoin, ResourceType: types.AuditResourceOrganization, IDField: "organization_id"},
+	"POST /api/v1/organizations/join-request": {Action: types.AuditActionRequestJoin, ResourceType: types.AuditResourceOrganization, IDField: "organization_id"},
+	"POST /api/v1/organizations/:id/leave":    {Action: types.AuditActionLeave, ResourceType: types.AuditResourceOrganization, IDParam: "id"},
+	"POST /api/v1/organizations/:id/request-upgrade": {
+		Action: types.AuditActionRequestUpgrade, ResourceType: types.AuditResourceOrganization, IDParam: "id",
+	},
+	"POST /api/v1/organizations/:id/invite-code": {
+		Action: types.AuditActionGenerateInvite, ResourceType: types.AuditResourceOrganization, IDParam: "id",
+	},
+	"POST /api/v1/organizations/:id/invite": {
+		Action: types.AuditActionInviteMember, ResourceType: types.AuditResourceOrganization, IDParam: "id",
+	},
+	"PUT /api/v1/organizations/:id/members/:user_id": {
+		Action: types.AuditActionUpdateMemberRole, ResourceType: types.AuditResourceOrganization, IDParam: "id",
+		Params: map[string]string{"user_id": "user_id"},
+	},
+	"DELETE /api/v1/organizations/:id/members/:user_id": {
+		Action: types.AuditActionRemoveMember, ResourceType: types.AuditResourceOrganization, IDParam: "id",
+		Params: map[string]string{"user_id": "user_id"},
+	},
+	"PUT /api/v1/organizations/:id/join-requests/:request_id/review": {
+		Action: types.AuditActionReviewJoin, ResourceType: types.AuditResourceOrganization, IDParam: "id",
+		Params: map[string]string{"request_id": "request_id"},
+	},
+
+	// 模型
+	"POST /api/v1/models":       {Action: types.AuditActionCreate, ResourceType: types.AuditResourceModel},
+	"PUT /api/v1/models/:id":    {Action: types.AuditActionUpdate, ResourceType: types.AuditResourceModel, IDParam: "id"},
+	"DELETE /api/v1/models/:id": {Action: types.AuditActionDelete, ResourceType: types.AuditResourceModel, IDParam: "id"},
+
+	// 智能体
+	"POST /api/v1/agents":          {Action: types.AuditActionCreate, ResourceType: types.AuditResourceAgent},
+	"PUT /api/v1/agents/:id":       {Action: types.AuditActionUpdate, ResourceType: types.AuditResourceAgent, IDParam: "id"},
+	"DELETE /api/v1/agents/:id":    {Action: types.AuditActionDelete, ResourceType: types.AuditResourceAgent, IDParam: "id"},
+	"POST /api/v1/agents/:id/copy": {Action: types.AuditActionCopy, ResourceType: types.AuditResourceAgent, IDParam: "id"},
+	"POST /api/v1/agents/:id/versions": {
+		Action: types.AuditActionPublish, ResourceType: types.AuditResourceAgent, IDParam: "id",
+	},
+	"POST /api/v1/agents/:id/versions/:version/rollback": {
+		Action: types.AuditActionRollback, ResourceType: types.AuditResourceAgent, IDParam: "id",
+		Params: map[string]string{"version": "version"},
+	},
+	"GET /api/v1/agents/:id/export": {
+		Action: types.AuditActionExport, ResourceType: types.AuditResourceAgent, IDParam: "id",
+	},
+
+	// 导入导出
+	"POST /api/v1/bundles/import": {Action: types.AuditActionImport, ResourceType: types.AuditResourceBundle},
+
+	// 租户
+	"POST /api/v1/tenants":        {Action: types.AuditActionCreate, ResourceType: types.AuditResourceTenant},
+	"PUT /api/v1/tenants/:id":     {Action: types.AuditActionUpdate, ResourceType: types.AuditResourceTenant, IDParam: "id"},
+	"DELETE /api/v1/tenants/:id":  {Action: types.AuditActionDelete, ResourceType: types.AuditResourceTenant, IDParam: "id"},
+	"PUT /api/v1/tenants/kv/:key": {Action: types.AuditActionUpdate, ResourceType: types.AuditResourceTenantKV, IDParam: "key"},
+
+	// MCP 服务
+	"POST /api/v1/mcp-services":       {Action: types.AuditActionCreate, ResourceType: types.AuditResourceMCPService},
+	"PUT /api/v1/mcp-services/:id":    {Action: types.AuditActionUpdate, ResourceType: types.AuditResourceMCPService, IDParam: "id"},
+	"DELETE /api/v1/mcp-services/:id": {Action: types.AuditActionDelete, ResourceType: types.AuditResourceMCPService, IDParam: "id"},
+	"POST /api/v1/mcp-services/:id/oauth/authorize": {
+		Action: types.AuditActionAuthorize, ResourceType: types.AuditResourceMCPService, IDParam: "id",
+	},
+	"DELETE /api/v1/mcp-services/:id/oauth": {
+		Action: types.AuditActionRevoke, ResourceType: types.AuditResourceMCPService, IDParam: "id",
+	},
+
+	// HTTP 工具
+	"POST /api/v1/http-tools":                {Action: types.AuditActionCreate, ResourceType: types.AuditResourceHTTPTool},
+	"POST /api/v1/http-tools/import-openapi": {Action: types.AuditActionImport, ResourceType: types.AuditResourceHTTPTool},
+	"PUT /api/v1/http-tools/:id":             {Action: types.AuditActionUpdate, ResourceType: types.AuditResourceHTTPTool, IDParam: "id"},
+	"DELETE /api/v1/http-tools/:id":          {Action: types.AuditActionDelete, ResourceType: types.AuditResourceHTTPTool, IDParam: "id"},
+}
+
+// auditSummaryFields 可以记录到变更摘要中的字段，其余字段（配置、密钥、URL、内容等）一律不记录
+var auditSummaryFields = map[string]bool{
+	"name":              true,
+	"title":             true,
+	"description":       true,
+	"file_name":         true,
+	"type":              true,
+	"source":            true,
+	"status":            true,
+	"enabled":           true,
+	"is_default":        true,
+	"role":              true,
+	"requested_role":    true,
+	"permission":        true,
+	"approved":          true,
+	"user_id":           true,
+	"organization_id":   true,
+	"knowledge_base_id": true,
+	"agent_id":          true,
+	"source_id":         true,
+	"target_id":         true,
+	"tag_id":            true,
+	"transport_type":    true,
+	"version":           true,
+	"pinned_version":    true,
+}
+
+// auditResponseWriter 捕获响应体，用于读取新建资源的ID
+type auditResponseWriter struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+// Write 同时写入buffer和原始writer，超出大小限制后不再缓存
+func (w auditResponseWriter) Write(b []byte) (int, error) {
+	if w.body.Len() <= maxAuditBodySize {
+		w.body.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Audit 为 auditRoutes 中的写操作记录审计日志（操作人、租户、动作、资源、变更摘要、IP、请求ID）。
+// 需要注册在认证中间件之后；处理器可以通过 types.AuditBeforeContextKey 提供变更前的资源状态
+func Audit(auditService interfaces.AuditLogService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rule, ok := auditRoutes[c.Request.Method+" "+c.FullPath()]
+		if !ok {
+			c.Next()
+			return
+		}
+
+		requestFields := readAuditRequestFields(c)
+		writer := &auditResponseWriter{ResponseWriter: c.Writer, body: &bytes.Buffer{}}
+		c.Writer = writer
+
+		c.Next()
+
+		tenantID := c.GetUint64(types.TenantIDContextKey.String())
+		if tenantID == 0 {
+			return
+		}
+
+		log := &types.AuditLog{
+			TenantID:     tenantID,
+			Action:       rule.Action,
+			ResourceType: rule.ResourceType,
+			Method:       c.Request.Method,
+			Path:         c.Request.URL.Path,
+			IP:           c.ClientIP(),
+			UserAgent:    truncateAuditValue(c.Request.UserAgent()),
+			RequestID:    c.GetString(types.RequestIDContextKey.String()),
+		}
+		setAuditActor(c, log)
+
+		after := types.AuditChange{}
+		for k, v := range requestFields {
+			after[k] = v
+		}
+		for param, field := range rule.Params {
+			after[field] = c.Param(param)
+		}
+
+		switch {
+		case rule.IDParam != "":
+			log.ResourceID = c.Param(rule.IDParam)
+		case rule.IDField != "":
+			if id, ok := requestFields[rule.IDField].(string); ok {
+				log.ResourceID = id
+			}
+		}
+
+		log.StatusCode = auditStatusCode(c)
+		if log.StatusCode < http.StatusBadRequest {
+			log.Result = types.AuditResultSuccess
+			responseFields := readAuditResponseData(writer.body.Bytes())
+			if log.ResourceID == "" {
+				if id, ok := responseFields["id"]; ok {
+					log.ResourceID = truncateAuditValue(toAuditString(id))
+				}
+			}
+			for k, v := range responseFields {
+				if _, exists := after[k]; !exists && auditSummaryFields[k] {
+					after[k] = v
+				}
+			}
+		} else {
+			log.Result = types.AuditResultFailed
+		}
+
+		if len(after) > 0 {
+			log.After = after
+		}
+		if before, ok := c.Get(types.AuditBeforeContextKey.String()); ok {
+			if change, ok := before.(types.AuditChange); ok {
+				log.Before = change
+			}
+		}
+
+		auditService.Record(c.Request.Context(), log)
+	}
+}
+
+// setAuditActor 记录操作人：登录用户或租户 API Key（只保留首尾几位）
+func setAuditActor(c *gin.Context, log *types.AuditLog) {
+	if v, ok := c.Get(types.UserContextKey.String()); ok {
+		if user, ok := v.(*types.User); ok && user != nil {
+			log.ActorType = types.AuditActorUser
+			log.ActorID = user.ID
+			log.ActorName = user.Username
+			return
+		}
+	}
+
+	log.ActorType = types.AuditActorAPIKey
+	apiKey := c.GetHeader("X-API-Key")
+	if apiKey == "" {
+		apiKey = strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	}
+	log.ActorID = maskAPIKey(apiKey)
+	if v, ok := c.Get(types.TenantInfoContextKey.String()); ok {
+		if tenant, ok := v.(*types.Tenant); ok && tenant != nil {
+			log.ActorName = tenant.Name
+		}
+	}
+}
+
+// maskAPIKey 只保留 API Key 的前 6 位和后 4 位
+func maskAPIKey(apiKey string) string {
+	if len(apiKey) <= 10 {
+		return "***"
+	}
+	return apiKey[:6] + "***" + apiKey[len(apiKey)-4:]
+}
+
+// auditStatusCode 返回请求的状态码。错误由 ErrorHandler 在审计中间件之后写入，因此优先从 c.Errors 推断
+func auditStatusCode(c *gin.Context) int {
+	if len(c.Errors) > 0 {
+		if appErr, ok := errors.IsAppError(c.Errors.Last().Err); ok {
+			return appErr.HTTPCode
+		}
+		return http.StatusInternalServerError
+	}
+	return c.Writer.Status()
+}
+
+// readAuditRequestFields 读取 JSON 请求体中允许记录的字段，并重置请求体
+func readAuditRequestFields(c *gin.Context) map[string]interface{} {
+	if c.Request.Body == nil || !strings.Contains(c.GetHeader("Content-Type"), "application/json") {
+		return nil
+	}
+	bodyBytes, err := io.ReadAll(c.Request.Body)
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
+	if err != nil || len(bodyBytes) > maxAuditBodySize {
+		return nil
+	}
+	return pickAuditFields(bodyBytes)
+}
+
+// readAuditResponseData 读取响应 data 中允许记录的字段和资源ID
+func readAuditResponseData(body []byte) map[string]interface{} {
+	if len(body) == 0 || len(body) > maxAuditBodySize {
+		return nil
+	}
+	var resp struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil || len(resp.Data) == 0 {
+		return nil
+	}
+	fields := pickAuditFields(resp.Data)
+	var data map[string]interface{}
+	if err := json.Unmarshal(resp.Data, &data); err == nil {
+		if id, ok := data["id"]; ok {
+			if fields == nil {
+				fields = map[string]interface{}{}
+			}
+			fields["id"] = id
+		}
+	}
+	return fields
+}
+
+// pickAuditFields 从 JSON 对象中挑出允许记录的标量字段
+func pickAuditFields(body []byte) map[string]interface{} {
+	var data map[string]interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil
+	}
+	fields := map[string]interface{}{}
+	for k, v := range data {
+		if !auditSummaryFields[k] {
+			continue
+		}
+		switch value := v.(type) {
+		case string:
+			fields[k] = truncateAuditValue(value)
+		case bool, float64:
+			fields[k] = value
+		}
+	}
+	return fields
+}
+
+// toAuditString 将 JSON 值转换为字符串
+func toAuditString(v interface{}) string {
+	switch value := v.(type) {
+	case string:
+		return value
+	default:
+		b, _ := json.Marshal(value)
+		return string(b)
+	}
+}
+
+// truncateAuditValue 截断过长的字符串
+func truncateAuditValue(s string) string {
+	if len(s) > maxAuditValueLength {
+		return s[:maxAuditValueLength]
+	}
+	return s
+}
@@ -0,0 +1,219 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"github.com/gin-gonic/gin"
+)
+
+// fakeAuditLogService 记录收到的审计日志
+type fakeAuditLogService struct {
+	interfaces.AuditLogService
+	logs []*types.AuditLog
+}
+
+func (f *fakeAuditLogService) Record(ctx context.Context, log *types.AuditLog) {
+	f.logs = append(f.logs, log)
+}
+
+// newAuditTestRouter 按路由注册顺序组装 ErrorHandler、认证（设置租户）和审计中间件
+func newAuditTestRouter(service *fakeAuditLogService, tenantID uint64) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(ErrorHandler())
+	r.Use(func(c *gin.Context) {
+		if tenantID != 0 {
+			c.Set(types.TenantIDContextKey.String(), tenantID)
+		}
+		c.Next()
+	})
+	r.Use(Audit(service))
+	return r
+}
+
+// serveAudit 发送一个带 API Key 的 JSON 请求
+func serveAudit(r *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-Key", "sk-An7_t_izCKFIJ4iht9Xjcjnj")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuditRecordsOnlySummaryFields(t *testing.T) {
+	service := &fakeAuditLogService{}
+	r := newAuditTestRouter(service, 1)
+	var received map[string]interface{}
+	r.POST("/api/v1/models", func(c *gin.Context) {
+		if err := c.ShouldBindJSON(&received); err != nil {
+			c.Error(errors.NewBadRequestError(err.Error()))
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"success": true, "data": gin.H{
+			"id":       "model-1",
+			"name":     "gpt",
+			"status":   "active",
+			"api_key":  "sk-model-secret",
+			"base_url": "https://llm.example.com",
+		}})
+	})
+
+	body := `{
+		"name": "gpt",
+		"type": "KnowledgeQA",
+		"description": "` + strings.Repeat("x", 300) + `",
+		"api_key": "sk-model-secret",
+		"base_url": "https://llm.example.com",
+		"parameters": {"api_key": "sk-model-secret"},
+		"is_default": true
+	}`
+	w := serveAudit(r, http.MethodPost, "/api/v1/models", body)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+	}
+	if received["api_key"] != "sk-model-secret" || received["base_url"] != "https://llm.example.com" {
+		t.Errorf("handler read body %v, want the full request", received)
+	}
+
+	if len(service.logs) != 1 {
+		t.Fatalf("recorded %d logs, want 1", len(service.logs))
+	}
+	log := service.logs[0]
+	want := types.AuditChange{
+		"name":        "gpt",
+		"type":        "KnowledgeQA",
+		"description": strings.Repeat("x", maxAuditValueLength),
+		"is_default":  true,
+		"status":      "active",
+	}
+	if !reflect.DeepEqual(log.After, want) {
+		t.Errorf("After = %v, want %v", log.After, want)
+	}
+	if log.Action != types.AuditActionCreate || log.ResourceType != types.AuditResourceModel ||
+		log.ResourceID != "model-1" || log.Result != types.AuditResultSuccess || log.StatusCode != http.StatusCreated {
+		t.Errorf("log = %+v, want a successful model creation of model-1", log)
+	}
+	if log.ActorType != types.AuditActorAPIKey || log.ActorID != "sk-An7***cjnj" {
+		t.Errorf("actor = %s %q, want the masked API key", log.ActorType, log.ActorID)
+	}
+}
+
+func TestAuditRecordsFailedRequest(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{name: "application error", err: errors.NewBadRequestError("invalid model"), status: http.StatusBadRequest},
+		{name: "other error", err: context.DeadlineExceeded, status: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &fakeAuditLogService{}
+			r := newAuditTestRouter(service, 1)
+			r.PUT("/api/v1/models/:id", func(c *gin.Context) {
+				c.Error(tt.err)
+			})
+
+			w := serveAudit(r, http.MethodPut, "/api/v1/models/model-1", `{"name": "gpt", "api_key": "sk-model-secret"}`)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+			if len(service.logs) != 1 {
+				t.Fatalf("recorded %d logs, want 1", len(service.logs))
+			}
+			log := service.logs[0]
+			if log.Result != types.AuditResultFailed || log.StatusCode != tt.status || log.ResourceID != "model-1" {
+				t.Errorf("log = %+v, want a failed update of model-1 with status %d", log, tt.status)
+			}
+			if !reflect.DeepEqual(log.After, types.AuditChange{"name": "gpt"}) {
+				t.Errorf("After = %v, want only the name", log.After)
+			}
+		})
+	}
+}
+
+func TestAuditSkipsUnauditedRequests(t *testing.T) {
+	service := &fakeAuditLogService{}
+	r := newAuditTestRouter(service, 1)
+	r.GET("/api/v1/models/:id", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"success": true})
+	})
+	serveAudit(r, http.MethodGet, "/api/v1/models/model-1", "")
+
+	unauthenticated := newAuditTestRouter(service, 0)
+	unauthenticated.DELETE("/api/v1/models/:id", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"success": true})
+	})
+	serveAudit(unauthenticated, http.MethodDelete, "/api/v1/models/model-1", "")
+
+	if len(service.logs) != 0 {
+		t.Errorf("recorded %d logs, want none", len(service.logs))
+	}
+}
+
+func TestMaskAPIKey(t *testing.T) {
+	tests := []struct {
+		apiKey string
+		want   string
+	}{
+		{apiKey: "sk-An7_t_izCKFIJ4iht9Xjcjnj", want: "sk-An7***cjnj"},
+		{apiKey: "sk-abcde12", want: "***"},
+		{apiKey: "sk-abcde123", want: "sk-abc***e123"},
+		{apiKey: "", want: "***"},
+	}
+	for _, tt := range tests {
+		if got := maskAPIKey(tt.apiKey); got != tt.want {
+			t.Errorf("maskAPIKey(%q) = %q, want %q", tt.apiKey, got, tt.want)
+		}
+	}
+}
+
+func TestPickAuditFields(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want map[string]interface{}
+	}{
+		{
+			name: "allowlisted scalars",
+			body: `{"name": "kb", "enabled": false, "version": 3}`,
+			want: map[string]interface{}{"name": "kb", "enabled": false, "version": float64(3)},
+		},
+		{
+			name: "keys, URLs, config and content are dropped",
+			body: `{"api_key": "sk-1", "url": "https://example.com", "config": {"name": "x"}, "content": "text"}`,
+			want: map[string]interface{}{},
+		},
+		{
+			name: "allowlisted fields that are not scalars are dropped",
+			body: `{"name": {"secret": "x"}, "user_id": ["u1"], "description": null}`,
+			want: map[string]interface{}{},
+		},
+		{
+			name: "long strings are truncated",
+			body: `{"title": "` + strings.Repeat("t", maxAuditValueLength+10) + `"}`,
+			want: map[string]interface{}{"title": strings.Repeat("t", maxAuditValueLength)},
+		},
+		{
+			name: "not an object",
+			body: `["name"]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pickAuditFields([]byte(tt.body)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("pickAuditFields() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
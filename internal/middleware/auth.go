@@ -21,6 +21,21 @@ var noAuthAPI = map[string][]string{
 	"/api/v1/auth/register": {"POST"},
 	"/api/v1/auth/login":    {"POST"},
 	"/api/v1/auth/refresh":  {"POST"},
+	// MCP OAuth 回调由外部授权服务器重定向，依赖 state 参数校验
+	"/api/v1/mcp-oauth/callback": {"GET"},
+}
+
+// 允许通过 Authorization: Bearer 传递租户 API Key 的路径前缀（OpenAI 兼容接口、MCP Server）
+var bearerAPIKeyPathPrefixes = []string{"/v1/", "/mcp"}
+
+// 检查请求路径是否允许以 Bearer 方式传递 API Key
+func allowBearerAPIKey(path string) bool {
+	for _, prefix := range bearerAPIKeyPathPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
 }
 
 // 检查请求是否在无需认证的API列表中
@@ -151,6 +166,13 @@ func Auth(
 
 		// 尝试X-API-Key认证（兼容模式）
 		apiKey := c.GetHeader("X-API-Key")
+		// OpenAI SDK 与 MCP 客户端通常只会通过 Bearer 传递密钥
+		if apiKey == "" && allowBearerAPIKey(c.Request.URL.Path) {
+			apiKey = strings.TrimPrefix(authHeader, "Bearer ")
+			if apiKey == authHeader {
+				apiKey = ""
+			}
+		}
 		if apiKey != "" {
 			// Get tenant information
 			tenantID, err := tenantService.ExtractTenantIDFromAPIKey(apiKey)
@@ -88,14 +88,20 @@ type ChatConfig struct {
 
 // NewChat 创建聊天实例
 func NewChat(config *ChatConfig, ollamaService *ollama.OllamaService) (Chat, error) {
+	var chat Chat
+	var err error
 	switch strings.ToLower(string(config.Source)) {
 	case string(types.ModelSourceLocal):
-		return NewOllamaChat(config, ollamaService)
+		chat, err = NewOllamaChat(config, ollamaService)
 	case string(types.ModelSourceRemote):
-		return NewRemoteChat(config)
+		chat, err = NewRemoteChat(config)
 	default:
 		return nil, fmt.Errorf("unsupported chat model source: %s", config.Source)
 	}
+	if err != nil {
+		return nil, err
+	}
+	return withMetrics(chat, config), nil
 }
 
 // NewRemoteChat 根据 provider 创建远程聊天实例
@@ -0,0 +1,92 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/metrics"
+	"github.com/Tencent/WeKnora/internal/models/provider"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// errStreamFailed marks a stream that delivered an error response
+var errStreamFailed = errors.New("chat stream returned an error")
+
+// instrumentedChat records latency and token usage of the wrapped chat model
+type instrumentedChat struct {
+	model    Chat
+	provider string
+}
+
+// withMetrics wraps a chat model so that its calls are reported to /metrics
+func withMetrics(chat Chat, config *ChatConfig) Chat {
+	return &instrumentedChat{model: chat, provider: providerLabel(config)}
+}
+
+// providerLabel returns the provider used as metric label
+func providerLabel(config *ChatConfig) string {
+	if strings.EqualFold(string(config.Source), string(types.ModelSourceLocal)) {
+		return "ollama"
+	}
+	if config.Provider != "" {
+		return config.Provider
+	}
+	return string(provider.DetectProvider(config.BaseURL))
+}
+
+// Chat implements Chat
+func (c *instrumentedChat) Chat(ctx context.Context, messages []Message, opts *ChatOptions) (*types.ChatResponse, error) {
+	start := time.Now()
+	resp, err := c.model.Chat(ctx, messages, opts)
+	metrics.ObserveModelCall(c.provider, metrics.ModelTypeChat, "chat", err, time.Since(start))
+	if resp != nil {
+		metrics.AddModelTokens(c.provider, resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
+	}
+	return resp, err
+}
+
+// ChatStream implements Chat. The call is observed when the stream is closed.
+func (c *instrumentedChat) ChatStream(
+	ctx context.Context, messages []Message, opts *ChatOptions,
+) (<-chan types.StreamResponse, error) {
+	start := time.Now()
+	stream, err := c.model.ChatStream(ctx, messages, opts)
+	if err != nil {
+		metrics.ObserveModelCall(c.provider, metrics.ModelTypeChat, "stream", err, time.Since(start))
+		return nil, err
+	}
+
+	out := make(chan types.StreamResponse)
+	go func() {
+		defer close(out)
+		var streamErr error
+		first := true
+		for resp := range stream {
+			if first {
+				metrics.ObserveFirstToken(c.provider, time.Since(start))
+				first = false
+			}
+			if resp.ResponseType == types.ResponseTypeError && streamErr == nil {
+				streamErr = errStreamFailed
+			}
+			if resp.Usage != nil {
+				metrics.AddModelTokens(c.provider, resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
+			}
+			out <- resp
+		}
+		metrics.ObserveModelCall(c.provider, metrics.ModelTypeChat, "stream", streamErr, time.Since(start))
+	}()
+	return out, nil
+}
+
+// GetModelName implements Chat
+func (c *instrumentedChat) GetModelName() string {
+	return c.model.GetModelName()
+}
+
+// GetModelID implements Chat
+func (c *instrumentedChat) GetModelID() string {
+	return c.model.GetModelID()
+}
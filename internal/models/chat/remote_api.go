@@ -381,6 +381,7 @@ func (c *RemoteAPIChat) processStream(ctx context.Context, stream *openai.ChatCo
 					Content:      "",
 					Done:         true,
 					ToolCalls:    state.buildOrderedToolCalls(),
+					Usage:        state.usage,
 				}
 			} else {
 				streamChan <- types.StreamResponse{
@@ -392,6 +393,7 @@ func (c *RemoteAPIChat) processStream(ctx context.Context, stream *openai.ChatCo
 			return
 		}
 
+		state.recordUsage(response.Usage)
 		if len(response.Choices) > 0 {
 			c.processStreamDelta(ctx, &response.Choices[0], state, streamChan)
 		}
@@ -430,6 +432,7 @@ func (c *RemoteAPIChat) processRawHTTPStream(ctx context.Context, resp *http.Res
 				Content:      "",
 				Done:         true,
 				ToolCalls:    state.buildOrderedToolCalls(),
+				Usage:        state.usage,
 			}
 			return
 		}
@@ -444,6 +447,7 @@ func (c *RemoteAPIChat) processRawHTTPStream(ctx context.Context, resp *http.Res
 			continue
 		}
 
+		state.recordUsage(streamResp.Usage)
 		if len(streamResp.Choices) > 0 {
 			c.processStreamDelta(ctx, &streamResp.Choices[0], state, streamChan)
 		}
@@ -456,6 +460,7 @@ type streamState struct {
 	lastFunctionName map[int]string
 	nameNotified     map[int]bool
 	hasThinking      bool
+	usage            *types.TokenUsage
 }
 
 func newStreamState() *streamState {
@@ -467,6 +472,18 @@ func newStreamState() *streamState {
 	}
 }
 
+// recordUsage keeps the token usage reported by a chunk, usually the last one of the stream
+func (s *streamState) recordUsage(usage *openai.Usage) {
+	if usage == nil {
+		return
+	}
+	s.usage = &types.TokenUsage{
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		TotalTokens:      usage.TotalTokens,
+	}
+}
+
 func (s *streamState) buildOrderedToolCalls() []types.LLMToolCall {
 	if len(s.toolCallMap) == 0 {
 		return nil
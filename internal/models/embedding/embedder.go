@@ -51,6 +51,25 @@ type Config struct {
 
 // NewEmbedder creates an embedder based on the configuration
 func NewEmbedder(config Config, pooler EmbedderPooler, ollamaService *ollama.OllamaService) (Embedder, error) {
+	embedder, err := newEmbedder(config, pooler, ollamaService)
+	if err != nil {
+		return nil, err
+	}
+	return withMetrics(embedder, providerLabel(config)), nil
+}
+
+// providerLabel returns the provider used as metric label
+func providerLabel(config Config) string {
+	if strings.EqualFold(string(config.Source), string(types.ModelSourceLocal)) {
+		return "ollama"
+	}
+	if config.Provider != "" {
+		return config.Provider
+	}
+	return string(provider.DetectProvider(config.BaseURL))
+}
+
+func newEmbedder(config Config, pooler EmbedderPooler, ollamaService *ollama.OllamaService) (Embedder, error) {
 	var embedder Embedder
 	var err error
 	switch strings.ToLower(string(config.Source)) {
@@ -0,0 +1,58 @@
+package embedding
+
+import (
+	"context"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/metrics"
+)
+
+// instrumentedEmbedder records latency and batch sizes of the wrapped embedder
+type instrumentedEmbedder struct {
+	model    Embedder
+	provider string
+}
+
+// withMetrics wraps an embedder so that its calls are reported to /metrics
+func withMetrics(model Embedder, provider string) Embedder {
+	return &instrumentedEmbedder{model: model, provider: provider}
+}
+
+// Embed implements Embedder
+func (e *instrumentedEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	start := time.Now()
+	vector, err := e.model.Embed(ctx, text)
+	metrics.ObserveModelCall(e.provider, metrics.ModelTypeEmbedding, "embed", err, time.Since(start))
+	metrics.ObserveEmbeddingBatch(e.provider, 1)
+	return vector, err
+}
+
+// BatchEmbed implements Embedder
+func (e *instrumentedEmbedder) BatchEmbed(ctx context.Context, texts []string) ([][]float32, error) {
+	start := time.Now()
+	vectors, err := e.model.BatchEmbed(ctx, texts)
+	metrics.ObserveModelCall(e.provider, metrics.ModelTypeEmbedding, "batch_embed", err, time.Since(start))
+	metrics.ObserveEmbeddingBatch(e.provider, len(texts))
+	return vectors, err
+}
+
+// BatchEmbedWithPool implements EmbedderPooler. The pool calls back into the
+// instrumented embedder, so each sub-batch is observed separately.
+func (e *instrumentedEmbedder) BatchEmbedWithPool(ctx context.Context, model Embedder, texts []string) ([][]float32, error) {
+	return e.model.BatchEmbedWithPool(ctx, model, texts)
+}
+
+// GetModelName implements Embedder
+func (e *instrumentedEmbedder) GetModelName() string {
+	return e.model.GetModelName()
+}
+
+// GetDimensions implements Embedder
+func (e *instrumentedEmbedder) GetDimensions() int {
+	return e.model.GetDimensions()
+}
+
+// GetModelID implements Embedder
+func (e *instrumentedEmbedder) GetModelID() string {
+	return e.model.GetModelID()
+}
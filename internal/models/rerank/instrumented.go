@@ -0,0 +1,37 @@
+package rerank
+
+import (
+	"context"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/metrics"
+)
+
+// instrumentedReranker records latency of the wrapped reranker
+type instrumentedReranker struct {
+	model    Reranker
+	provider string
+}
+
+// withMetrics wraps a reranker so that its calls are reported to /metrics
+func withMetrics(model Reranker, provider string) Reranker {
+	return &instrumentedReranker{model: model, provider: provider}
+}
+
+// Rerank implements Reranker
+func (r *instrumentedReranker) Rerank(ctx context.Context, query string, documents []string) ([]RankResult, error) {
+	start := time.Now()
+	results, err := r.model.Rerank(ctx, query, documents)
+	metrics.ObserveModelCall(r.provider, metrics.ModelTypeRerank, "rerank", err, time.Since(start))
+	return results, err
+}
+
+// GetModelName implements Reranker
+func (r *instrumentedReranker) GetModelName() string {
+	return r.model.GetModelName()
+}
+
+// GetModelID implements Reranker
+func (r *instrumentedReranker) GetModelID() string {
+	return r.model.GetModelID()
+}
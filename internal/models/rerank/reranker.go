@@ -94,14 +94,20 @@ func NewReranker(config *RerankerConfig) (Reranker, error) {
 		providerName = provider.DetectProvider(config.BaseURL)
 	}
 
+	var reranker Reranker
+	var err error
 	switch providerName {
 	case provider.ProviderAliyun:
-		return NewAliyunReranker(config)
+		reranker, err = NewAliyunReranker(config)
 	case provider.ProviderZhipu:
-		return NewZhipuReranker(config)
+		reranker, err = NewZhipuReranker(config)
 	case provider.ProviderJina:
-		return NewJinaReranker(config)
+		reranker, err = NewJinaReranker(config)
 	default:
-		return NewOpenAIReranker(config)
+		reranker, err = NewOpenAIReranker(config)
 	}
+	if err != nil {
+		return nil, err
+	}
+	return withMetrics(reranker, string(providerName)), nil
 }
@@ -12,6 +12,8 @@ import (
 	"github.com/Tencent/WeKnora/internal/config"
 	"github.com/Tencent/WeKnora/internal/handler"
 	"github.com/Tencent/WeKnora/internal/handler/session"
+	"github.com/Tencent/WeKnora/internal/mcp"
+	"github.com/Tencent/WeKnora/internal/metrics"
 	"github.com/Tencent/WeKnora/internal/middleware"
 	"github.com/Tencent/WeKnora/internal/types/interfaces"
 
@@ -35,6 +37,7 @@ type RouterParams struct {
 	KnowledgeHandler      *handler.KnowledgeHandler
 	TenantHandler         *handler.TenantHandler
 	TenantService         interfaces.TenantService
+	AuditLogService       interfaces.AuditLogService
 	ChunkHandler          *handler.ChunkHandler
 	SessionHandler        *session.Handler
 	MessageHandler        *handler.MessageHandler
@@ -43,13 +46,26 @@ type RouterParams struct {
 	AuthHandler           *handler.AuthHandler
 	InitializationHandler *handler.InitializationHandler
 	SystemHandler         *handler.SystemHandler
+	HealthHandler         *handler.HealthHandler
 	MCPServiceHandler     *handler.MCPServiceHandler
+	HTTPToolHandler       *handler.HTTPToolHandler
 	WebSearchHandler      *handler.WebSearchHandler
 	FAQHandler            *handler.FAQHandler
 	TagHandler            *handler.TagHandler
 	CustomAgentHandler    *handler.CustomAgentHandler
 	SkillHandler          *handler.SkillHandler
 	OrganizationHandler   *handler.OrganizationHandler
+	IndexMigrationHandler *handler.IndexMigrationHandler
+	IndexCheckHandler     *handler.IndexConsistencyHandler
+	WebhookHandler        *handler.WebhookHandler
+	AgentTaskHandler      *handler.AgentTaskHandler
+	AgentTraceHandler     *handler.AgentTraceHandler
+	AgentTestHandler      *handler.AgentTestHandler
+	AgentVersionHandler   *handler.AgentVersionHandler
+	BundleHandler         *handler.BundleHandler
+	AuditLogHandler       *handler.AuditLogHandler
+	MemoryHandler         *handler.MemoryHandler
+	MCPKnowledgeServer    *mcp.KnowledgeServer
 }
 
 // NewRouter 创建新的路由
@@ -66,6 +82,14 @@ func NewRouter(params RouterParams) *gin.Engine {
 		MaxAge:           12 * time.Hour,
 	}))
 
+	// Prometheus 指标与 Kubernetes 探针，在日志中间件之前注册，避免每次采集都记录请求日志
+	// 指标接口需通过 METRICS_ENABLED 开启，配置 METRICS_TOKEN 后要求 Bearer 认证
+	if metrics.Enabled() {
+		r.GET("/metrics", gin.WrapH(metrics.Handler()))
+	}
+	r.GET("/health/live", params.HealthHandler.Live)
+	r.GET("/health/ready", params.HealthHandler.Ready)
+
 	// 基础中间件（不需要认证）
 	r.Use(middleware.RequestID())
 	r.Use(middleware.Logger())
@@ -94,12 +118,17 @@ func NewRouter(params RouterParams) *gin.Engine {
 	// 添加OpenTelemetry追踪中间件
 	r.Use(middleware.TracingMiddleware())
 
+	// 审计日志中间件，记录需要审计的写操作（依赖认证中间件写入的租户和用户信息）
+	r.Use(middleware.Audit(params.AuditLogService))
+
 	// 需要认证的API路由
 	v1 := r.Group("/api/v1")
 	{
 		RegisterAuthRoutes(v1, params.AuthHandler)
 		RegisterTenantRoutes(v1, params.TenantHandler)
+		RegisterIndexMigrationRoutes(v1, params.IndexMigrationHandler)
 		RegisterKnowledgeBaseRoutes(v1, params.KBHandler)
+		RegisterIndexConsistencyRoutes(v1, params.IndexCheckHandler)
 		RegisterKnowledgeTagRoutes(v1, params.TagHandler)
 		RegisterKnowledgeRoutes(v1, params.KnowledgeHandler)
 		RegisterFAQRoutes(v1, params.FAQHandler)
@@ -107,17 +136,32 @@ func NewRouter(params RouterParams) *gin.Engine {
 		RegisterSessionRoutes(v1, params.SessionHandler)
 		RegisterChatRoutes(v1, params.SessionHandler)
 		RegisterMessageRoutes(v1, params.MessageHandler)
+		RegisterAgentTraceRoutes(v1, params.AgentTraceHandler)
 		RegisterModelRoutes(v1, params.ModelHandler)
 		RegisterEvaluationRoutes(v1, params.EvaluationHandler)
 		RegisterInitializationRoutes(v1, params.InitializationHandler)
 		RegisterSystemRoutes(v1, params.SystemHandler)
 		RegisterMCPServiceRoutes(v1, params.MCPServiceHandler)
+		RegisterHTTPToolRoutes(v1, params.HTTPToolHandler)
 		RegisterWebSearchRoutes(v1, params.WebSearchHandler)
 		RegisterCustomAgentRoutes(v1, params.CustomAgentHandler)
 		RegisterSkillRoutes(v1, params.SkillHandler)
+		RegisterWebhookRoutes(v1, params.WebhookHandler)
+		RegisterAgentTaskRoutes(v1, params.AgentTaskHandler)
+		RegisterAgentTestRoutes(v1, params.AgentTestHandler)
+		RegisterAgentVersionRoutes(v1, params.AgentVersionHandler)
+		RegisterBundleRoutes(v1, params.BundleHandler)
+		RegisterAuditLogRoutes(v1, params.AuditLogHandler)
+		RegisterMemoryRoutes(v1, params.MemoryHandler)
 		RegisterOrganizationRoutes(v1, params.OrganizationHandler)
 	}
 
+	// OpenAI 兼容接口（使用租户 API Key 认证）
+	RegisterOpenAICompatibleRoutes(r, params.SessionHandler)
+
+	// MCP Server（使用租户 API Key 认证）
+	RegisterMCPServerRoutes(r, params.MCPKnowledgeServer)
+
 	return r
 }
 
@@ -235,6 +279,130 @@ func RegisterKnowledgeBaseRoutes(r *gin.RouterGroup, handler *handler.KnowledgeB
 	}
 }
 
+// RegisterIndexConsistencyRoutes 注册知识库索引一致性检查相关的路由
+func RegisterIndexConsistencyRoutes(r *gin.RouterGroup, handler *handler.IndexConsistencyHandler) {
+	indexCheck := r.Group("/knowledge-bases/:id/index-check")
+	{
+		// 发起检查（可选自动修复）
+		indexCheck.POST("", handler.StartIndexCheck)
+		// 获取检查报告列表
+		indexCheck.GET("/reports", handler.ListIndexCheckReports)
+		// 获取检查报告详情
+		indexCheck.GET("/reports/:report_id", handler.GetIndexCheckReport)
+	}
+}
+
+// RegisterHTTPToolRoutes 注册 HTTP 工具相关的路由
+func RegisterHTTPToolRoutes(r *gin.RouterGroup, handler *handler.HTTPToolHandler) {
+	httpTools := r.Group("/http-tools")
+	{
+		// 创建 HTTP 工具
+		httpTools.POST("", handler.CreateHTTPTool)
+		// 从 OpenAPI 文档导入
+		httpTools.POST("/import-openapi", handler.ImportOpenAPITools)
+		// 获取 HTTP 工具列表
+		httpTools.GET("", handler.ListHTTPTools)
+		// 获取 HTTP 工具详情
+		httpTools.GET("/:id", handler.GetHTTPTool)
+		// 更新 HTTP 工具
+		httpTools.PUT("/:id", handler.UpdateHTTPTool)
+		// 删除 HTTP 工具
+		httpTools.DELETE("/:id", handler.DeleteHTTPTool)
+		// 测试调用
+		httpTools.POST("/:id/test", handler.TestHTTPTool)
+	}
+}
+
+// RegisterWebhookRoutes 注册 Webhook 相关的路由
+func RegisterWebhookRoutes(r *gin.RouterGroup, handler *handler.WebhookHandler) {
+	webhooks := r.Group("/webhooks")
+	{
+		// 创建 Webhook
+		webhooks.POST("", handler.CreateWebhook)
+		// 获取 Webhook 列表
+		webhooks.GET("", handler.ListWebhooks)
+		// 获取 Webhook 详情
+		webhooks.GET("/:id", handler.GetWebhook)
+		// 更新 Webhook
+		webhooks.PUT("/:id", handler.UpdateWebhook)
+		// 删除 Webhook
+		webhooks.DELETE("/:id", handler.DeleteWebhook)
+		// 发送测试事件
+		webhooks.POST("/:id/test", handler.TestWebhook)
+		// 获取投递记录
+		webhooks.GET("/:id/deliveries", handler.ListWebhookDeliveries)
+	}
+}
+
+// RegisterAgentTaskRoutes 注册智能体定时/事件任务相关的路由
+func RegisterAgentTaskRoutes(r *gin.RouterGroup, handler *handler.AgentTaskHandler) {
+	tasks := r.Group("/agent-tasks")
+	{
+		// 创建任务
+		tasks.POST("", handler.CreateAgentTask)
+		// 获取任务列表
+		tasks.GET("", handler.ListAgentTasks)
+		// 获取任务详情
+		tasks.GET("/:id", handler.GetAgentTask)
+		// 更新任务
+		tasks.PUT("/:id", handler.UpdateAgentTask)
+		// 删除任务
+		tasks.DELETE("/:id", handler.DeleteAgentTask)
+		// 立即运行一次
+		tasks.POST("/:id/run", handler.RunAgentTask)
+		// 获取运行历史
+		tasks.GET("/:id/runs", handler.ListAgentTaskRuns)
+		// 获取运行详情
+		tasks.GET("/:id/runs/:run_id", handler.GetAgentTaskRun)
+	}
+}
+
+// RegisterAgentTestRoutes 注册智能体回归测试相关的路由
+func RegisterAgentTestRoutes(r *gin.RouterGroup, handler *handler.AgentTestHandler) {
+	testCases := r.Group("/agents/:id/test-cases")
+	{
+		// 创建测试用例
+		testCases.POST("", handler.CreateAgentTestCase)
+		// 获取测试用例列表
+		testCases.GET("", handler.ListAgentTestCases)
+		// 更新测试用例
+		testCases.PUT("/:case_id", handler.UpdateAgentTestCase)
+		// 删除测试用例
+		testCases.DELETE("/:case_id", handler.DeleteAgentTestCase)
+	}
+
+	testRuns := r.Group("/agents/:id/test-runs")
+	{
+		// 运行测试
+		testRuns.POST("", handler.StartAgentTestRun)
+		// 获取测试运行列表
+		testRuns.GET("", handler.ListAgentTestRuns)
+		// 对比两次测试运行
+		testRuns.GET("/compare", handler.CompareAgentTestRuns)
+		// 获取测试运行报告
+		testRuns.GET("/:run_id", handler.GetAgentTestRun)
+	}
+}
+
+// RegisterAuditLogRoutes 注册审计日志相关的路由
+func RegisterAuditLogRoutes(r *gin.RouterGroup, handler *handler.AuditLogHandler) {
+	// 查询审计日志
+	r.GET("/audit-logs", handler.ListAuditLogs)
+}
+
+// RegisterMemoryRoutes 注册用户长期记忆相关的路由
+func RegisterMemoryRoutes(r *gin.RouterGroup, handler *handler.MemoryHandler) {
+	memories := r.Group("/memories")
+	{
+		// 获取我的长期记忆
+		memories.GET("", handler.ListMemories)
+		// 清空我的长期记忆
+		memories.DELETE("", handler.ClearMemories)
+		// 删除一条长期记忆
+		memories.DELETE("/:id", handler.DeleteMemory)
+	}
+}
+
 // RegisterKnowledgeTagRoutes 注册知识库标签相关路由
 func RegisterKnowledgeTagRoutes(r *gin.RouterGroup, tagHandler *handler.TagHandler) {
 	if tagHandler == nil {
@@ -254,13 +422,57 @@ func RegisterMessageRoutes(r *gin.RouterGroup, handler *handler.MessageHandler)
 	// 消息路由组
 	messages := r.Group("/messages")
 	{
+		// 搜索历史对话
+		messages.GET("/search", handler.SearchMessages)
 		// 加载更早的消息，用于向上滚动加载
 		messages.GET("/:session_id/load", handler.LoadMessages)
+		// 获取会话的对话分支
+		messages.GET("/:session_id/branches", handler.ListBranches)
+		// 切换当前分支
+		messages.POST("/:session_id/branches/switch", handler.SwitchBranch)
 		// 删除消息
 		messages.DELETE("/:session_id/:id", handler.DeleteMessage)
 	}
 }
 
+// RegisterAgentVersionRoutes 注册智能体版本发布与回滚相关的路由
+func RegisterAgentVersionRoutes(r *gin.RouterGroup, handler *handler.AgentVersionHandler) {
+	versions := r.Group("/agents/:id/versions")
+	{
+		// 将草稿发布为新版本
+		versions.POST("", handler.PublishAgentVersion)
+		// 获取版本列表
+		versions.GET("", handler.ListAgentVersions)
+		// 对比两个版本（必须在 /:version 之前注册）
+		versions.GET("/diff", handler.DiffAgentVersions)
+		// 获取版本详情
+		versions.GET("/:version", handler.GetAgentVersion)
+		// 回滚到历史版本
+		versions.POST("/:version/rollback", handler.RollbackAgentVersion)
+	}
+}
+
+// RegisterBundleRoutes 注册智能体和知识库导入导出相关的路由
+func RegisterBundleRoutes(r *gin.RouterGroup, handler *handler.BundleHandler) {
+	// 导出智能体及其引用的对象
+	r.GET("/agents/:id/export", handler.ExportAgentBundle)
+	// 导出知识库及其内容
+	r.GET("/knowledge-bases/:id/export", handler.ExportKnowledgeBaseBundle)
+	// 导入导出的包
+	r.POST("/bundles/import", handler.ImportBundle)
+}
+
+// RegisterAgentTraceRoutes 注册智能体运行轨迹相关的路由
+func RegisterAgentTraceRoutes(r *gin.RouterGroup, handler *handler.AgentTraceHandler) {
+	messages := r.Group("/messages")
+	{
+		// 导出助手消息的智能体运行轨迹
+		messages.GET("/:session_id/:id/trace", handler.GetAgentTrace)
+		// 使用记录的工具结果重放智能体运行
+		messages.POST("/:session_id/:id/trace/replay", handler.ReplayAgentTrace)
+	}
+}
+
 // RegisterSessionRoutes 注册路由
 func RegisterSessionRoutes(r *gin.RouterGroup, handler *session.Handler) {
 	sessions := r.Group("/sessions")
@@ -272,6 +484,9 @@ func RegisterSessionRoutes(r *gin.RouterGroup, handler *session.Handler) {
 		sessions.DELETE("/:id", handler.DeleteSession)
 		sessions.POST("/:session_id/generate_title", handler.GenerateTitle)
 		sessions.POST("/:session_id/stop", handler.StopSession)
+		// 敏感工具调用审批
+		sessions.GET("/:id/tool-approvals", handler.ListToolApprovals)
+		sessions.POST("/:session_id/tool-approvals/:approval_id", handler.DecideToolApproval)
 		// 继续接收活跃流
 		sessions.GET("/continue-stream/:session_id", handler.ContinueStream)
 	}
@@ -297,6 +512,29 @@ func RegisterChatRoutes(r *gin.RouterGroup, handler *session.Handler) {
 	}
 }
 
+// RegisterOpenAICompatibleRoutes 注册 OpenAI 兼容路由
+func RegisterOpenAICompatibleRoutes(r *gin.Engine, handler *session.Handler) {
+	openai := r.Group("/v1")
+	{
+		// 对话补全，model 为智能体ID或 kb:<知识库ID>
+		openai.POST("/chat/completions", handler.ChatCompletions)
+		// 可用模型（智能体与知识库）列表
+		openai.GET("/models", handler.ListModels)
+	}
+}
+
+// RegisterMCPServerRoutes 注册 MCP Server 路由
+func RegisterMCPServerRoutes(r *gin.Engine, server *mcp.KnowledgeServer) {
+	// Streamable HTTP 传输
+	streamable := gin.WrapH(server.StreamableHTTPHandler())
+	r.GET(mcp.KnowledgeServerBasePath, streamable)
+	r.POST(mcp.KnowledgeServerBasePath, streamable)
+	r.DELETE(mcp.KnowledgeServerBasePath, streamable)
+	// SSE 传输（兼容旧版客户端）
+	r.GET(mcp.KnowledgeServerBasePath+"/sse", gin.WrapH(server.SSEHandler()))
+	r.POST(mcp.KnowledgeServerBasePath+"/message", gin.WrapH(server.MessageHandler()))
+}
+
 // RegisterTenantRoutes 注册租户相关的路由
 func RegisterTenantRoutes(r *gin.RouterGroup, handler *handler.TenantHandler) {
 	// 添加获取所有租户的路由（需要跨租户权限）
@@ -319,6 +557,22 @@ func RegisterTenantRoutes(r *gin.RouterGroup, handler *handler.TenantHandler) {
 	}
 }
 
+// RegisterIndexMigrationRoutes 注册索引迁移相关的路由
+func RegisterIndexMigrationRoutes(r *gin.RouterGroup, handler *handler.IndexMigrationHandler) {
+	// 索引迁移路由组（租户ID从认证上下文获取）
+	migrations := r.Group("/tenants/index-migrations")
+	{
+		// 发起迁移
+		migrations.POST("", handler.StartIndexMigration)
+		// 获取迁移列表
+		migrations.GET("", handler.ListIndexMigrations)
+		// 获取迁移进度
+		migrations.GET("/:id", handler.GetIndexMigration)
+		// 恢复失败的迁移
+		migrations.POST("/:id/resume", handler.ResumeIndexMigration)
+	}
+}
+
 // RegisterModelRoutes 注册模型相关的路由
 func RegisterModelRoutes(r *gin.RouterGroup, handler *handler.ModelHandler) {
 	// 模型路由组
@@ -412,7 +666,20 @@ func RegisterMCPServiceRoutes(r *gin.RouterGroup, handler *handler.MCPServiceHan
 		mcpServices.GET("/:id/tools", handler.GetMCPServiceTools)
 		// Get MCP service resources
 		mcpServices.GET("/:id/resources", handler.GetMCPServiceResources)
+		// Get MCP service prompts
+		mcpServices.GET("/:id/prompts", handler.GetMCPServicePrompts)
+		// Render MCP service prompt
+		mcpServices.POST("/:id/prompts/:name", handler.GetMCPServicePrompt)
+		// Start MCP service OAuth authorization
+		mcpServices.POST("/:id/oauth/authorize", handler.StartMCPServiceOAuth)
+		// Get MCP service OAuth status
+		mcpServices.GET("/:id/oauth", handler.GetMCPServiceOAuthStatus)
+		// Revoke MCP service OAuth authorization
+		mcpServices.DELETE("/:id/oauth", handler.RevokeMCPServiceOAuth)
 	}
+
+	// MCP OAuth callback (public, validated by the state parameter)
+	r.GET("/mcp-oauth/callback", handler.MCPOAuthCallback)
 }
 
 // RegisterWebSearchRoutes registers web search routes
@@ -528,6 +795,8 @@ func RegisterOrganizationRoutes(r *gin.RouterGroup, orgHandler *handler.Organiza
 	{
 		agentShares.POST("", orgHandler.ShareAgent)
 		agentShares.GET("", orgHandler.ListAgentShares)
+		// Pin the agent version used by the organization
+		agentShares.PUT("/:share_id", orgHandler.UpdateAgentShare)
 		agentShares.DELETE("/:share_id", orgHandler.RemoveAgentShare)
 	}
 
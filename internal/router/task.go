@@ -6,6 +6,8 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/Tencent/WeKnora/internal/database"
+	"github.com/Tencent/WeKnora/internal/metrics"
 	"github.com/Tencent/WeKnora/internal/types"
 	"github.com/Tencent/WeKnora/internal/types/interfaces"
 	"github.com/hibiken/asynq"
@@ -15,14 +17,31 @@ import (
 type AsynqTaskParams struct {
 	dig.In
 
-	Server               *asynq.Server
-	KnowledgeService     interfaces.KnowledgeService
-	KnowledgeBaseService interfaces.KnowledgeBaseService
-	TagService           interfaces.KnowledgeTagService
-	ChunkExtractor       interfaces.TaskHandler `name:"chunkExtractor"`
-	DataTableSummary     interfaces.TaskHandler `name:"dataTableSummary"`
+	Server                *asynq.Server
+	KnowledgeService      interfaces.KnowledgeService
+	KnowledgeBaseService  interfaces.KnowledgeBaseService
+	TagService            interfaces.KnowledgeTagService
+	IndexMigrationService interfaces.IndexMigrationService
+	IndexCheckService     interfaces.IndexConsistencyService
+	WebhookService        interfaces.WebhookService
+	AgentTaskService      interfaces.AgentTaskService
+	AuditLogService       interfaces.AuditLogService
+	AgentTraceService     interfaces.AgentTraceService
+	ChunkExtractor        interfaces.TaskHandler `name:"chunkExtractor"`
+	DataTableSummary      interfaces.TaskHandler `name:"dataTableSummary"`
+	AgentTaskRunner       interfaces.TaskHandler `name:"agentTaskRunner"`
+	AgentTestRunner       interfaces.TaskHandler `name:"agentTestRunner"`
 }
 
+// auditLogCleanupSchedule is the cron spec of the daily audit log retention task
+const auditLogCleanupSchedule = "30 4 * * *"
+
+// agentTraceCleanupSchedule is the cron spec of the daily agent trace retention task
+const agentTraceCleanupSchedule = "45 4 * * *"
+
+// agentTaskSchedule is the cron spec of the scan for due agent schedule tasks
+const agentTaskSchedule = "* * * * *"
+
 func getAsynqRedisClientOpt() *asynq.RedisClientOpt {
 	db := 0
 	if dbStr := os.Getenv("REDIS_DB"); dbStr != "" {
@@ -30,8 +49,13 @@ func getAsynqRedisClientOpt() *asynq.RedisClientOpt {
 			db = parsed
 		}
 	}
+	// Falls back to the embedded redis in lite mode when REDIS_ADDR is not set
+	addr, err := database.RedisAddr()
+	if err != nil {
+		log.Fatalf("failed to resolve redis address: %v", err)
+	}
 	opt := &asynq.RedisClientOpt{
-		Addr:         os.Getenv("REDIS_ADDR"),
+		Addr:         addr,
 		Username:     os.Getenv("REDIS_USERNAME"),
 		Password:     os.Getenv("REDIS_PASSWORD"),
 		ReadTimeout:  100 * time.Millisecond,
@@ -69,6 +93,12 @@ func NewAsynqServer() *asynq.Server {
 func RunAsynqServer(params AsynqTaskParams) *asynq.ServeMux {
 	// Create a new mux and register all handlers
 	mux := asynq.NewServeMux()
+	mux.Use(metrics.TaskMiddleware)
+
+	// Expose queue sizes on /metrics
+	if err := metrics.Register(metrics.NewQueueCollector(asynq.NewInspector(getAsynqRedisClientOpt()))); err != nil {
+		log.Printf("failed to register asynq queue metrics: %v", err)
+	}
 
 	// Register extract handlers - router will dispatch to appropriate handler
 	mux.HandleFunc(types.TypeChunkExtract, params.ChunkExtractor.Handle)
@@ -98,6 +128,29 @@ func RunAsynqServer(params AsynqTaskParams) *asynq.ServeMux {
 	// Register KB delete handler
 	mux.HandleFunc(types.TypeKBDelete, params.KnowledgeBaseService.ProcessKBDelete)
 
+	// Register index migration handler
+	mux.HandleFunc(types.TypeIndexMigration, params.IndexMigrationService.ProcessIndexMigration)
+
+	// Register index consistency check handlers
+	mux.HandleFunc(types.TypeIndexCheck, params.IndexCheckService.ProcessIndexCheck)
+	mux.HandleFunc(types.TypeIndexCheckScan, params.IndexCheckService.ProcessIndexCheckScan)
+
+	// Register webhook delivery handler
+	mux.HandleFunc(types.TypeWebhookDelivery, params.WebhookService.ProcessWebhookDelivery)
+
+	// Register audit log retention handler
+	mux.HandleFunc(types.TypeAuditLogCleanup, params.AuditLogService.ProcessAuditLogCleanup)
+
+	// Register agent trace retention handler
+	mux.HandleFunc(types.TypeAgentTraceCleanup, params.AgentTraceService.ProcessAgentTraceCleanup)
+
+	// Register agent task handlers
+	mux.HandleFunc(types.TypeAgentTaskSchedule, params.AgentTaskService.ProcessAgentTaskSchedule)
+	mux.HandleFunc(types.TypeAgentTaskRun, params.AgentTaskRunner.Handle)
+
+	// Register agent test run handler
+	mux.HandleFunc(types.TypeAgentTestRun, params.AgentTestRunner.Handle)
+
 	go func() {
 		// Start the server
 		if err := params.Server.Run(mux); err != nil {
@@ -106,3 +159,68 @@ func RunAsynqServer(params AsynqTaskParams) *asynq.ServeMux {
 	}()
 	return mux
 }
+
+// RunAsynqScheduler registers periodic tasks and starts the scheduler.
+// The index consistency scan runs on the cron spec in INDEX_CHECK_SCHEDULE (e.g. "0 3 * * *"),
+// and is disabled when it is empty. Expired audit logs and agent traces are purged daily unless
+// AUDIT_LOG_RETENTION_DAYS or AGENT_TRACE_RETENTION_DAYS is 0. Due agent schedule tasks are enqueued every minute.
+// Every instance may run the scheduler; asynq.Unique prevents the same task from being
+// enqueued twice.
+func RunAsynqScheduler(cleaner interfaces.ResourceCleaner) error {
+	scheduler := asynq.NewScheduler(getAsynqRedisClientOpt(), nil)
+	registered := 0
+
+	if _, err := scheduler.Register(agentTaskSchedule,
+		asynq.NewTask(types.TypeAgentTaskSchedule, nil),
+		asynq.Queue("default"), asynq.Unique(50*time.Second),
+	); err != nil {
+		return err
+	}
+	registered++
+
+	if spec := os.Getenv("INDEX_CHECK_SCHEDULE"); spec != "" {
+		if _, err := scheduler.Register(spec,
+			asynq.NewTask(types.TypeIndexCheckScan, nil),
+			asynq.Queue("low"), asynq.Unique(time.Hour),
+		); err != nil {
+			return err
+		}
+		registered++
+		log.Printf("index consistency scan scheduled: %s", spec)
+	}
+
+	if types.AuditLogRetentionDays() > 0 {
+		if _, err := scheduler.Register(auditLogCleanupSchedule,
+			asynq.NewTask(types.TypeAuditLogCleanup, nil),
+			asynq.Queue("low"), asynq.Unique(time.Hour),
+		); err != nil {
+			return err
+		}
+		registered++
+		log.Printf("audit log cleanup scheduled: %s, retention %d days", auditLogCleanupSchedule, types.AuditLogRetentionDays())
+	}
+
+	if types.AgentTraceRetentionDays() > 0 {
+		if _, err := scheduler.Register(agentTraceCleanupSchedule,
+			asynq.NewTask(types.TypeAgentTraceCleanup, nil),
+			asynq.Queue("low"), asynq.Unique(time.Hour),
+		); err != nil {
+			return err
+		}
+		registered++
+		log.Printf("agent trace cleanup scheduled: %s, retention %d days",
+			agentTraceCleanupSchedule, types.AgentTraceRetentionDays())
+	}
+
+	if registered == 0 {
+		return nil
+	}
+	if err := scheduler.Start(); err != nil {
+		return err
+	}
+	cleaner.RegisterWithName("AsynqScheduler", func() error {
+		scheduler.Shutdown()
+		return nil
+	})
+	return nil
+}
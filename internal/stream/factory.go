@@ -5,6 +5,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/Tencent/WeKnora/internal/database"
 	"github.com/Tencent/WeKnora/internal/types/interfaces"
 )
 
@@ -22,9 +23,14 @@ func NewStreamManager() (interfaces.StreamManager, error) {
 		if err != nil {
 			db = 0
 		}
+		// 轻量模式下未配置 REDIS_ADDR 时使用内嵌 Redis
+		addr, err := database.RedisAddr()
+		if err != nil {
+			return nil, err
+		}
 		ttl := time.Hour // 默认1小时
 		return NewRedisStreamManager(
-			os.Getenv("REDIS_ADDR"),
+			addr,
 			os.Getenv("REDIS_USERNAME"),
 			os.Getenv("REDIS_PASSWORD"),
 			db,
@@ -29,10 +29,34 @@ type AgentConfig struct {
 	// MCP service selection
 	MCPSelectionMode string   `json:"mcp_selection_mode"` // MCP selection mode: "all", "selected", "none"
 	MCPServices      []string `json:"mcp_services"`       // Selected MCP service IDs (when mode is "selected")
+	// MCP resources/prompts pinned as context sources
+	MCPContextSources []MCPContextSource `json:"mcp_context_sources,omitempty"`
+	// HTTP tools selected by the agent
+	HTTPTools []string `json:"http_tools,omitempty"`
+	// Agents the agent can delegate to, built by the session service (runtime only)
+	SubAgentTools []Tool `json:"-"`
+	// Workflow run instead of the ReAct loop in workflow mode, with defaults applied (runtime only)
+	Workflow *WorkflowDefinition `json:"-"`
+	// JSON Schema the final answer is converted to, from the request or the agent (runtime only)
+	OutputSchema OutputSchema `json:"-"`
+	// Human-in-the-loop approval for sensitive tool calls
+	ApprovalRequiredTools       []string `json:"approval_required_tools,omitempty"`        // Tool names requiring approval
+	ApprovalRequiredMCPServices []string `json:"approval_required_mcp_services,omitempty"` // MCP service IDs whose tools require approval
+	ToolApprovalTimeoutSeconds  int      `json:"tool_approval_timeout_seconds,omitempty"`  // Seconds to wait for a decision
+	ToolApprovalTimeoutAction   string   `json:"tool_approval_timeout_action,omitempty"`   // "deny" or "approve" on timeout
 	// Whether to enable thinking mode (for models that support extended thinking)
 	Thinking *bool `json:"thinking"`
+	// Whether the model also checks the cited sentences of the final answer against their references
+	CitationModelCheck bool `json:"citation_model_check"`
+	// Whether the full trace of the run is recorded for export and replay
+	TraceEnabled bool `json:"trace_enabled"`
 	// Whether to retrieve knowledge base only when explicitly mentioned with @ (default: false)
 	RetrieveKBOnlyWhenMentioned bool `json:"retrieve_kb_only_when_mentioned"`
+	// Long-term user memory across sessions
+	MemoryEnabled          bool   `json:"memory_enabled"`            // Whether the memory tools are enabled
+	MemoryEmbeddingModelID string `json:"memory_embedding_model_id"` // Embedding model for semantic recall (empty = keyword recall)
+	MemoryAgentID          string `json:"-"`                         // Agent the memories are saved from (runtime only)
+	MemoryContext          string `json:"-"`                         // Recalled memories injected into the system prompt (runtime only)
 
 	// Skills configuration (Progressive Disclosure pattern)
 	SkillsEnabled  bool     `json:"skills_enabled"`   // Whether skills are enabled (default: false)
@@ -0,0 +1,181 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Agent task triggers. Event triggers reuse the names of the webhook events that fire them.
+const (
+	AgentTaskTriggerSchedule        = "schedule"                          // Runs on a cron schedule
+	AgentTaskTriggerKnowledgeParsed = WebhookEventKnowledgeParseCompleted // Runs when a knowledge finishes parsing
+	AgentTaskTriggerFAQImported     = WebhookEventFAQImportFinished       // Runs when an FAQ import completes
+)
+
+// AgentTaskTriggers lists the supported agent task triggers
+var AgentTaskTriggers = []string{
+	AgentTaskTriggerSchedule,
+	AgentTaskTriggerKnowledgeParsed,
+	AgentTaskTriggerFAQImported,
+}
+
+// Agent task run statuses
+const (
+	AgentTaskRunStatusPending   = "pending"   // Enqueued, waiting for a worker
+	AgentTaskRunStatusRunning   = "running"   // The agent is running
+	AgentTaskRunStatusSucceeded = "succeeded" // The agent answered
+	AgentTaskRunStatusFailed    = "failed"    // The run failed, see Error
+)
+
+// Agent task template variables available to every run, besides the fields of the trigger event
+const (
+	AgentTaskVariableNow       = "now"         // Start time of the run, RFC 3339
+	AgentTaskVariableDate      = "date"        // Date of the run, YYYY-MM-DD
+	AgentTaskVariableLastRunAt = "last_run_at" // Start time of the previous run of the task, empty for the first run
+	AgentTaskVariableTaskName  = "task_name"   // Name of the task
+)
+
+const (
+	// MaxAgentTaskPromptLength caps the prompt template length in characters
+	MaxAgentTaskPromptLength = 8000
+	// MaxAgentTaskOutputLength caps the answer kept in the run history, the full answer is in the session
+	MaxAgentTaskOutputLength = 16000
+)
+
+// AgentTask runs a custom agent headlessly on a schedule or when an event occurs.
+// Each run answers the rendered prompt in a new session, and its result is recorded as an AgentTaskRun.
+type AgentTask struct {
+	ID          string `json:"id"          gorm:"type:varchar(36);primaryKey"`
+	TenantID    uint64 `json:"tenant_id"   gorm:"index"`
+	Name        string `json:"name"        gorm:"type:varchar(255)"`
+	Description string `json:"description" gorm:"type:text"`
+	// Agent that answers the prompt, it must belong to the tenant
+	AgentID string `json:"agent_id" gorm:"type:varchar(36)"`
+	// One of AgentTaskTriggers
+	Trigger string `json:"trigger" gorm:"type:varchar(64)"`
+	// Standard 5-field cron spec of schedule tasks, e.g. "0 9 * * 1"
+	Schedule string `json:"schedule" gorm:"type:varchar(128)"`
+	// IANA time zone the schedule is evaluated in, the server's local time zone when empty
+	Timezone string `json:"timezone" gorm:"type:varchar(64)"`
+	// Restricts event triggers to events of these knowledge bases, all when empty
+	KnowledgeBaseIDs StringArray `json:"knowledge_base_ids" gorm:"type:json"`
+	// Prompt template, {{name}} is replaced by the event field or variable name
+	Prompt string `json:"prompt" gorm:"type:text"`
+	// Webhook that receives the result of every run, even if it does not subscribe to the run events
+	WebhookID string `json:"webhook_id" gorm:"type:varchar(36)"`
+	Enabled   bool   `json:"enabled"`
+	// Next run of schedule tasks
+	NextRunAt     *time.Time `json:"next_run_at"`
+	LastRunAt     *time.Time `json:"last_run_at"`
+	LastRunStatus string     `json:"last_run_status" gorm:"type:varchar(20)"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// TableName returns the table name for AgentTask
+func (AgentTask) TableName() string {
+	return "agent_tasks"
+}
+
+// Location returns the time zone the schedule is evaluated in
+func (t *AgentTask) Location() (*time.Location, error) {
+	if t.Timezone == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(t.Timezone)
+}
+
+// Matches reports whether an event with data fires the task
+func (t *AgentTask) Matches(trigger string, data map[string]interface{}) bool {
+	if !t.Enabled || t.Trigger != trigger {
+		return false
+	}
+	if len(t.KnowledgeBaseIDs) == 0 {
+		return true
+	}
+	kbID, _ := data["knowledge_base_id"].(string)
+	for _, id := range t.KnowledgeBaseIDs {
+		if id == kbID {
+			return true
+		}
+	}
+	return false
+}
+
+// RenderPrompt replaces the {{name}} references of the prompt template with the fields of the
+// event data. Nested fields are referenced as {{name.field}}, unknown names render empty.
+func (t *AgentTask) RenderPrompt(data map[string]interface{}) string {
+	return RenderWorkflowTemplate(t.Prompt, func(name string, path []string) string {
+		var value interface{} = data[name]
+		for _, key := range path {
+			m, ok := value.(map[string]interface{})
+			if !ok {
+				return ""
+			}
+			value = m[key]
+		}
+		switch v := value.(type) {
+		case nil:
+			return ""
+		case string:
+			return v
+		case map[string]interface{}, []interface{}:
+			encoded, _ := json.Marshal(v)
+			return string(encoded)
+		default:
+			return fmt.Sprint(v)
+		}
+	})
+}
+
+// AgentTaskRun records one run of an agent task
+type AgentTaskRun struct {
+	ID       string `json:"id"        gorm:"type:varchar(36);primaryKey"`
+	TenantID uint64 `json:"tenant_id" gorm:"index"`
+	TaskID   string `json:"task_id"   gorm:"type:varchar(36);index"`
+	AgentID  string `json:"agent_id"  gorm:"type:varchar(36)"`
+	// Trigger of the run, "manual" when started through the API
+	Trigger string `json:"trigger" gorm:"type:varchar(64)"`
+	Status  string `json:"status"  gorm:"type:varchar(20);default:'pending'"`
+	// Event that fired the run, the template variables of the prompt
+	EventData JSON `json:"event_data" gorm:"type:json"`
+	// Rendered prompt sent to the agent
+	Prompt string `json:"prompt" gorm:"type:text"`
+	// Session and assistant message holding the conversation of the run
+	SessionID string `json:"session_id" gorm:"type:varchar(36)"`
+	MessageID string `json:"message_id" gorm:"type:varchar(36)"`
+	// Answer of the agent, truncated to MaxAgentTaskOutputLength
+	Output string `json:"output" gorm:"type:text"`
+	// Answer of agents with an output schema
+	StructuredOutput JSON       `json:"structured_output" gorm:"type:json"`
+	Error            string     `json:"error"             gorm:"type:text"`
+	StartedAt        *time.Time `json:"started_at"`
+	FinishedAt       *time.Time `json:"finished_at"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+// TableName returns the table name for AgentTaskRun
+func (AgentTaskRun) TableName() string {
+	return "agent_task_runs"
+}
+
+// AgentTaskRunTriggerManual is the trigger of runs started through the API
+const AgentTaskRunTriggerManual = "manual"
+
+// AgentTaskRunPayload represents the agent task run task payload
+type AgentTaskRunPayload struct {
+	TenantID uint64 `json:"tenant_id"`
+	RunID    string `json:"run_id"`
+}
+
+// TruncateAgentTaskOutput truncates an answer to MaxAgentTaskOutputLength characters
+func TruncateAgentTaskOutput(output string) string {
+	runes := []rune(output)
+	if len(runes) <= MaxAgentTaskOutputLength {
+		return output
+	}
+	return strings.TrimSpace(string(runes[:MaxAgentTaskOutputLength])) + "…"
+}
@@ -0,0 +1,277 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+)
+
+// Agent test assertion types
+const (
+	AgentTestAssertToolCalled     = "tool_called"     // The agent calls the tool named Value
+	AgentTestAssertKnowledgeCited = "knowledge_cited" // The answer references the knowledge with ID Value
+	AgentTestAssertAnswerContains = "answer_contains" // The answer contains Value, ignoring case
+	AgentTestAssertAnswerMatches  = "answer_matches"  // The answer matches the regular expression Value
+	AgentTestAssertLLMJudge       = "llm_judge"       // A model judges that the answer meets the criteria Value
+	// AgentTestAssertMaxRounds is reported for test cases with MaxRounds, it is not set as an assertion
+	AgentTestAssertMaxRounds = "max_rounds"
+)
+
+// AgentTestAssertionTypes lists the assertion types of test cases
+var AgentTestAssertionTypes = []string{
+	AgentTestAssertToolCalled,
+	AgentTestAssertKnowledgeCited,
+	AgentTestAssertAnswerContains,
+	AgentTestAssertAnswerMatches,
+	AgentTestAssertLLMJudge,
+}
+
+// Agent test run statuses
+const (
+	AgentTestRunStatusPending   = "pending"   // Enqueued, waiting for a worker
+	AgentTestRunStatusRunning   = "running"   // Test cases are running
+	AgentTestRunStatusCompleted = "completed" // All test cases ran, see the results
+	AgentTestRunStatusFailed    = "failed"    // The run stopped before all test cases ran, see Error
+)
+
+// Changes of a test case between two test runs
+const (
+	AgentTestChangeFixed     = "fixed"     // Failed in the base run, passes in the target run
+	AgentTestChangeRegressed = "regressed" // Passed in the base run, fails in the target run
+	AgentTestChangePassing   = "passing"   // Passes in both runs
+	AgentTestChangeFailing   = "failing"   // Fails in both runs
+	AgentTestChangeAdded     = "added"     // Only in the target run
+	AgentTestChangeRemoved   = "removed"   // Only in the base run
+)
+
+const (
+	// MaxAgentTestCases caps the test cases of an agent, which all run in one test run
+	MaxAgentTestCases = 100
+	// MaxAgentTestAssertions caps the assertions of a test case
+	MaxAgentTestAssertions = 20
+)
+
+// AgentTestAssertion is a check of a test case on the run of the agent
+type AgentTestAssertion struct {
+	// One of AgentTestAssertionTypes
+	Type string `json:"type"`
+	// Tool name, knowledge ID, text, regular expression or judging criteria, depending on Type
+	Value string `json:"value"`
+}
+
+// AgentTestAssertions is the JSON column of the assertions of a test case
+type AgentTestAssertions []AgentTestAssertion
+
+// Value implements the driver.Valuer interface
+func (a AgentTestAssertions) Value() (driver.Value, error) {
+	if a == nil {
+		return json.Marshal([]AgentTestAssertion{})
+	}
+	return json.Marshal(a)
+}
+
+// Scan implements the sql.Scanner interface
+func (a *AgentTestAssertions) Scan(value interface{}) error {
+	b, ok := value.([]byte)
+	if !ok || len(b) == 0 {
+		*a = make(AgentTestAssertions, 0)
+		return nil
+	}
+	return json.Unmarshal(b, a)
+}
+
+// AgentTestCase is a saved test conversation of a custom agent: a query, the knowledge it mentions,
+// and the assertions the run of the agent must satisfy
+type AgentTestCase struct {
+	ID       string `json:"id"        gorm:"type:varchar(36);primaryKey"`
+	TenantID uint64 `json:"tenant_id" gorm:"index"`
+	AgentID  string `json:"agent_id"  gorm:"type:varchar(36)"`
+	Name     string `json:"name"      gorm:"type:varchar(255)"`
+	Query    string `json:"query"     gorm:"type:text"`
+	// Knowledge bases and knowledge mentioned with the query, as @ mentions in a conversation
+	KnowledgeBaseIDs StringArray         `json:"knowledge_base_ids" gorm:"type:json"`
+	KnowledgeIDs     StringArray         `json:"knowledge_ids"      gorm:"type:json"`
+	Assertions       AgentTestAssertions `json:"assertions"         gorm:"type:json"`
+	// The agent must answer within this many rounds, unchecked when 0
+	MaxRounds int       `json:"max_rounds"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName returns the table name for AgentTestCase
+func (AgentTestCase) TableName() string {
+	return "agent_test_cases"
+}
+
+// AgentTestAssertionResult is the outcome of an assertion of a test case
+type AgentTestAssertionResult struct {
+	Type   string `json:"type"`
+	Value  string `json:"value"`
+	Passed bool   `json:"passed"`
+	// Why the assertion failed, or the reasoning of the judge
+	Detail string `json:"detail,omitempty"`
+}
+
+// AgentTestResult is the outcome of a test case in a test run
+type AgentTestResult struct {
+	CaseID   string `json:"case_id"`
+	CaseName string `json:"case_name"`
+	Query    string `json:"query"`
+	// A test case passes when the agent answers and all of its assertions pass
+	Passed bool   `json:"passed"`
+	Answer string `json:"answer"`
+	// Tools the agent called, in call order
+	ToolCalls []string `json:"tool_calls"`
+	// Knowledge the answer references
+	KnowledgeIDs []string                   `json:"knowledge_ids"`
+	Rounds       int                        `json:"rounds"`
+	Assertions   []AgentTestAssertionResult `json:"assertions"`
+	// Assistant message of the run in the session of the test run, its trace shows the full run
+	MessageID  string `json:"message_id"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// AgentTestResults is the JSON column of the results of a test run
+type AgentTestResults []AgentTestResult
+
+// Value implements the driver.Valuer interface
+func (r AgentTestResults) Value() (driver.Value, error) {
+	if r == nil {
+		return json.Marshal([]AgentTestResult{})
+	}
+	return json.Marshal(r)
+}
+
+// Scan implements the sql.Scanner interface
+func (r *AgentTestResults) Scan(value interface{}) error {
+	b, ok := value.([]byte)
+	if !ok || len(b) == 0 {
+		*r = make(AgentTestResults, 0)
+		return nil
+	}
+	return json.Unmarshal(b, r)
+}
+
+// AgentTestRun runs the test cases of a custom agent against its current config.
+// The config is recorded with the results, so that runs of different versions of the agent can be compared.
+type AgentTestRun struct {
+	ID       string `json:"id"        gorm:"type:varchar(36);primaryKey"`
+	TenantID uint64 `json:"tenant_id" gorm:"index"`
+	AgentID  string `json:"agent_id"  gorm:"type:varchar(36)"`
+	// Free-form label of the run, e.g. the change being tested
+	Label  string `json:"label"  gorm:"type:varchar(255)"`
+	Status string `json:"status" gorm:"type:varchar(20);default:'pending'"`
+	// Config of the agent when the run started
+	AgentConfig JSON `json:"agent_config" gorm:"type:json"`
+	// Hash of AgentConfig, runs of the same version of the agent have the same hash
+	ConfigHash string `json:"config_hash" gorm:"type:varchar(64)"`
+	// Model judging the llm_judge assertions, the model of the agent when empty
+	JudgeModelID string `json:"judge_model_id" gorm:"type:varchar(64)"`
+	// Test cases to run, all the test cases of the agent when empty
+	CaseIDs StringArray `json:"case_ids" gorm:"type:json"`
+	// Session holding the conversations of the run
+	SessionID  string           `json:"session_id" gorm:"type:varchar(36)"`
+	Total      int              `json:"total"`
+	Passed     int              `json:"passed"`
+	Failed     int              `json:"failed"`
+	Results    AgentTestResults `json:"results"    gorm:"type:json"`
+	Error      string           `json:"error"      gorm:"type:text"`
+	StartedAt  *time.Time       `json:"started_at"`
+	FinishedAt *time.Time       `json:"finished_at"`
+	CreatedAt  time.Time        `json:"created_at"`
+	UpdatedAt  time.Time        `json:"updated_at"`
+}
+
+// TableName returns the table name for AgentTestRun
+func (AgentTestRun) TableName() string {
+	return "agent_test_runs"
+}
+
+// AgentTestRunRequest starts a test run
+type AgentTestRunRequest struct {
+	// Test cases to run, all the test cases of the agent when empty
+	CaseIDs      []string `json:"case_ids"`
+	Label        string   `json:"label"`
+	JudgeModelID string   `json:"judge_model_id"`
+}
+
+// AgentTestRunPayload represents the agent test run task payload
+type AgentTestRunPayload struct {
+	TenantID uint64 `json:"tenant_id"`
+	RunID    string `json:"run_id"`
+}
+
+// AgentTestCaseComparison compares the results of a test case in two test runs
+type AgentTestCaseComparison struct {
+	CaseID   string `json:"case_id"`
+	CaseName string `json:"case_name"`
+	// One of the AgentTestChange constants
+	Change string `json:"change"`
+	// Results of the test case, nil when it did not run
+	Base   *AgentTestResult `json:"base"`
+	Target *AgentTestResult `json:"target"`
+}
+
+// AgentTestRunComparison compares two test runs of an agent, case by case
+type AgentTestRunComparison struct {
+	BaseRunID   string `json:"base_run_id"`
+	TargetRunID string `json:"target_run_id"`
+	// Whether the agent config changed between the runs
+	ConfigChanged bool                      `json:"config_changed"`
+	Fixed         int                       `json:"fixed"`
+	Regressed     int                       `json:"regressed"`
+	Cases         []AgentTestCaseComparison `json:"cases"`
+}
+
+// CompareAgentTestRuns compares the results of target against base, in the order of the test cases of target
+// followed by the test cases that only ran in base
+func CompareAgentTestRuns(base, target *AgentTestRun) *AgentTestRunComparison {
+	comparison := &AgentTestRunComparison{
+		BaseRunID:     base.ID,
+		TargetRunID:   target.ID,
+		ConfigChanged: base.ConfigHash != target.ConfigHash,
+		Cases:         make([]AgentTestCaseComparison, 0, len(target.Results)),
+	}
+	baseResults := make(map[string]*AgentTestResult, len(base.Results))
+	for i := range base.Results {
+		baseResults[base.Results[i].CaseID] = &base.Results[i]
+	}
+
+	compared := make(map[string]bool, len(target.Results))
+	for i := range target.Results {
+		result := &target.Results[i]
+		compared[result.CaseID] = true
+		item := AgentTestCaseComparison{CaseID: result.CaseID, CaseName: result.CaseName, Target: result}
+		before, ok := baseResults[result.CaseID]
+		switch {
+		case !ok:
+			item.Change = AgentTestChangeAdded
+		case !before.Passed && result.Passed:
+			item.Change = AgentTestChangeFixed
+			comparison.Fixed++
+		case before.Passed && !result.Passed:
+			item.Change = AgentTestChangeRegressed
+			comparison.Regressed++
+		case result.Passed:
+			item.Change = AgentTestChangePassing
+		default:
+			item.Change = AgentTestChangeFailing
+		}
+		item.Base = before
+		comparison.Cases = append(comparison.Cases, item)
+	}
+	for i := range base.Results {
+		result := &base.Results[i]
+		if compared[result.CaseID] {
+			continue
+		}
+		comparison.Cases = append(comparison.Cases, AgentTestCaseComparison{
+			CaseID:   result.CaseID,
+			CaseName: result.CaseName,
+			Change:   AgentTestChangeRemoved,
+			Base:     result,
+		})
+	}
+	return comparison
+}
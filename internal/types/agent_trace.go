@@ -0,0 +1,208 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"os"
+	"strconv"
+	"time"
+)
+
+// defaultAgentTraceRetentionDays is how long agent traces are kept when AGENT_TRACE_RETENTION_DAYS is not set
+const defaultAgentTraceRetentionDays = 30
+
+// Agent trace round stages
+const (
+	AgentTraceStageThink       = "think"        // ReAct round: the model thinks and calls tools
+	AgentTraceStageReflection  = "reflection"   // Reflection on a tool result
+	AgentTraceStageFinalAnswer = "final_answer" // Final answer synthesized after the max iterations
+)
+
+// TokenUsage is the token usage of a model call as reported by the provider
+type TokenUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// Add adds the usage of another call
+func (u *TokenUsage) Add(other *TokenUsage) {
+	if other == nil {
+		return
+	}
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.TotalTokens += other.TotalTokens
+}
+
+// Value implements the driver.Valuer interface
+func (u TokenUsage) Value() (driver.Value, error) {
+	return json.Marshal(u)
+}
+
+// Scan implements the sql.Scanner interface
+func (u *TokenUsage) Scan(value interface{}) error {
+	b, ok := value.([]byte)
+	if !ok || len(b) == 0 {
+		return nil
+	}
+	return json.Unmarshal(b, u)
+}
+
+// AgentTrace is the full record of an agent run: the exact prompts, model requests and responses,
+// and tool inputs and outputs of every round. It is stored per assistant message, and can be
+// replayed against another model with the recorded tool results.
+type AgentTrace struct {
+	ID        string `json:"id"         gorm:"type:varchar(36);primaryKey"`
+	TenantID  uint64 `json:"tenant_id"  gorm:"index"`
+	SessionID string `json:"session_id" gorm:"type:varchar(36)"`
+	MessageID string `json:"message_id" gorm:"type:varchar(36);index"`
+	// Tool call of the parent run that delegated to this run, empty for the top-level run
+	ParentToolCallID string `json:"parent_tool_call_id,omitempty" gorm:"type:varchar(255)"`
+	// ID and name of the chat model of the run
+	ModelID   string `json:"model_id"   gorm:"type:varchar(64)"`
+	ModelName string `json:"model_name" gorm:"type:varchar(255)"`
+	Query     string `json:"query"      gorm:"type:text"`
+	// System prompt of the run, including pinned context and skills metadata
+	SystemPrompt string `json:"system_prompt" gorm:"type:text"`
+	// Tools offered to the model
+	Tools AgentTraceTools `json:"tools" gorm:"type:jsonb"`
+	// Max ReAct rounds of the run
+	MaxIterations int `json:"max_iterations"`
+	// Model calls of the run in order, with the tool calls they requested
+	Rounds      AgentTraceRounds `json:"rounds"       gorm:"type:jsonb"`
+	FinalAnswer string           `json:"final_answer" gorm:"type:text"`
+	// Error that ended the run, empty when the run completed
+	Error string `json:"error" gorm:"type:text"`
+	// Token usage summed over the model calls that reported it
+	Usage      TokenUsage `json:"usage"       gorm:"type:jsonb"`
+	StartedAt  time.Time  `json:"started_at"`
+	DurationMs int64      `json:"duration_ms"`
+	CreatedAt  time.Time  `json:"created_at"`
+	// Runs of sub-agents delegated to by tool calls of this run, only set on export
+	SubTraces []*AgentTrace `json:"sub_traces,omitempty" gorm:"-"`
+	// ID of the trace this run replayed, only set on replay results
+	ReplayOf string `json:"replay_of,omitempty" gorm:"-"`
+}
+
+// AgentTraceTool is the definition of a tool offered to the model
+type AgentTraceTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Parameters  json.RawMessage `json:"parameters"`
+}
+
+// AgentTraceMessage is a message sent to the model
+type AgentTraceMessage struct {
+	Role       string        `json:"role"`
+	Content    string        `json:"content"`
+	Name       string        `json:"name,omitempty"`
+	ToolCallID string        `json:"tool_call_id,omitempty"`
+	ToolCalls  []LLMToolCall `json:"tool_calls,omitempty"`
+}
+
+// AgentTraceOptions are the request options of a model call
+type AgentTraceOptions struct {
+	Temperature float64 `json:"temperature"`
+	Thinking    *bool   `json:"thinking,omitempty"`
+	ToolCount   int     `json:"tool_count"`
+}
+
+// AgentTraceToolCall is a tool call requested by the model and its result
+type AgentTraceToolCall struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+	// Whether the call was executed, false when it was denied by the user or its arguments were invalid
+	Executed   bool                   `json:"executed"`
+	Success    bool                   `json:"success"`
+	Output     string                 `json:"output"`
+	Error      string                 `json:"error,omitempty"`
+	Data       map[string]interface{} `json:"data,omitempty"`
+	DurationMs int64                  `json:"duration_ms"`
+}
+
+// AgentTraceRound is a model call of an agent run
+type AgentTraceRound struct {
+	Iteration int    `json:"iteration"`
+	Stage     string `json:"stage"`
+	// Messages sent to the model
+	Messages []AgentTraceMessage `json:"messages"`
+	Options  AgentTraceOptions   `json:"options"`
+	// Content and tool calls of the model response
+	Response      string        `json:"response"`
+	ResponseCalls []LLMToolCall `json:"response_tool_calls,omitempty"`
+	Error         string        `json:"error,omitempty"`
+	// Token usage, nil when the provider did not report it
+	Usage     *TokenUsage          `json:"usage,omitempty"`
+	ToolCalls []AgentTraceToolCall `json:"tool_calls,omitempty"`
+	StartedAt time.Time            `json:"started_at"`
+	// Time spent in the model call, and in the whole round including tools
+	ModelDurationMs int64 `json:"model_duration_ms"`
+	DurationMs      int64 `json:"duration_ms"`
+}
+
+// AgentTraceTools is the JSON column of the tools of a trace
+type AgentTraceTools []AgentTraceTool
+
+// Value implements the driver.Valuer interface
+func (t AgentTraceTools) Value() (driver.Value, error) {
+	if t == nil {
+		return json.Marshal([]AgentTraceTool{})
+	}
+	return json.Marshal(t)
+}
+
+// Scan implements the sql.Scanner interface
+func (t *AgentTraceTools) Scan(value interface{}) error {
+	b, ok := value.([]byte)
+	if !ok || len(b) == 0 {
+		*t = make(AgentTraceTools, 0)
+		return nil
+	}
+	return json.Unmarshal(b, t)
+}
+
+// AgentTraceRounds is the JSON column of the rounds of a trace
+type AgentTraceRounds []AgentTraceRound
+
+// Value implements the driver.Valuer interface
+func (r AgentTraceRounds) Value() (driver.Value, error) {
+	if r == nil {
+		return json.Marshal([]AgentTraceRound{})
+	}
+	return json.Marshal(r)
+}
+
+// Scan implements the sql.Scanner interface
+func (r *AgentTraceRounds) Scan(value interface{}) error {
+	b, ok := value.([]byte)
+	if !ok || len(b) == 0 {
+		*r = make(AgentTraceRounds, 0)
+		return nil
+	}
+	return json.Unmarshal(b, r)
+}
+
+// AgentTraceReplayRequest configures the replay of a trace. Tool calls are answered with the
+// recorded results instead of running the tools, so only the model and prompt change.
+type AgentTraceReplayRequest struct {
+	// Chat model to replay against, the model of the trace when empty
+	ModelID string `json:"model_id"`
+	// System prompt replacing the recorded one, to try a prompt change
+	SystemPrompt *string `json:"system_prompt"`
+	// Temperature replacing the recorded one
+	Temperature *float64 `json:"temperature"`
+	// Max rounds of the replay, the number of recorded think rounds when 0
+	MaxIterations int `json:"max_iterations"`
+}
+
+// AgentTraceRetentionDays returns how many days agent traces are kept, from AGENT_TRACE_RETENTION_DAYS.
+// 0 keeps agent traces forever.
+func AgentTraceRetentionDays() int {
+	days, err := strconv.Atoi(os.Getenv("AGENT_TRACE_RETENTION_DAYS"))
+	if err != nil || days < 0 {
+		return defaultAgentTraceRetentionDays
+	}
+	return days
+}
@@ -0,0 +1,121 @@
+package types
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// AgentVersion is an immutable published version of a custom agent.
+// The agent record itself is the draft: edits change the draft, and conversations use the
+// published version of the agent until the draft is published as a new version.
+type AgentVersion struct {
+	ID       string `json:"id"        gorm:"type:varchar(36);primaryKey"`
+	TenantID uint64 `json:"tenant_id" gorm:"index"`
+	AgentID  string `json:"agent_id"  gorm:"type:varchar(36);index"`
+	// Version number, starting at 1 for each agent
+	Version     int               `json:"version"`
+	Name        string            `json:"name"        gorm:"type:varchar(255)"`
+	Description string            `json:"description" gorm:"type:text"`
+	Avatar      string            `json:"avatar"      gorm:"type:varchar(64)"`
+	Config      CustomAgentConfig `json:"config"      gorm:"type:json"`
+	// SHA-256 of the encoded config, the same as the config hash of agent test runs
+	ConfigHash string `json:"config_hash" gorm:"type:varchar(64)"`
+	// Release note given when publishing
+	Note string `json:"note" gorm:"type:text"`
+	// ID of the user who published the version
+	PublishedBy string    `json:"published_by" gorm:"type:varchar(36)"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// TableName returns the table name for AgentVersion
+func (AgentVersion) TableName() string {
+	return "agent_versions"
+}
+
+// Apply returns a copy of the agent answering with this version
+func (v *AgentVersion) Apply(agent *CustomAgent) *CustomAgent {
+	versioned := *agent
+	versioned.Name = v.Name
+	versioned.Description = v.Description
+	versioned.Avatar = v.Avatar
+	versioned.Config = v.Config
+	versioned.Version = v.Version
+	versioned.EnsureDefaults()
+	return &versioned
+}
+
+// PublishAgentRequest is the request body of publishing the draft of an agent
+type PublishAgentRequest struct {
+	Note string `json:"note"`
+}
+
+// AgentConfigChange is a field that differs between two versions of an agent.
+// Path is the JSON path of the field, e.g. "name" or "config.model_id".
+type AgentConfigChange struct {
+	Path string      `json:"path"`
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// AgentVersionDiff lists the changes from one version of an agent to another, version 0 is the draft
+type AgentVersionDiff struct {
+	AgentID     string              `json:"agent_id"`
+	FromVersion int                 `json:"from_version"`
+	ToVersion   int                 `json:"to_version"`
+	Changes     []AgentConfigChange `json:"changes"`
+}
+
+// DiffAgentVersions compares the basic info and config of two versions of an agent field by field.
+// Objects are compared recursively, lists are compared as a whole.
+func DiffAgentVersions(from, to *AgentVersion) []AgentConfigChange {
+	fromFields := flattenAgentVersion(from)
+	toFields := flattenAgentVersion(to)
+
+	paths := make([]string, 0, len(fromFields)+len(toFields))
+	for path := range fromFields {
+		paths = append(paths, path)
+	}
+	for path := range toFields {
+		if _, ok := fromFields[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	changes := make([]AgentConfigChange, 0)
+	for _, path := range paths {
+		if !reflect.DeepEqual(fromFields[path], toFields[path]) {
+			changes = append(changes, AgentConfigChange{Path: path, From: fromFields[path], To: toFields[path]})
+		}
+	}
+	return changes
+}
+
+// flattenAgentVersion returns the fields of a version by JSON path
+func flattenAgentVersion(v *AgentVersion) map[string]interface{} {
+	fields := map[string]interface{}{
+		"name":        v.Name,
+		"description": v.Description,
+		"avatar":      v.Avatar,
+	}
+	var config interface{}
+	if encoded, err := json.Marshal(v.Config); err == nil {
+		_ = json.Unmarshal(encoded, &config)
+	}
+	flattenJSON("config", config, fields)
+	return fields
+}
+
+// flattenJSON adds the leaves of a decoded JSON value to fields
+func flattenJSON(path string, value interface{}, fields map[string]interface{}) {
+	object, ok := value.(map[string]interface{})
+	if !ok || len(object) == 0 {
+		fields[path] = value
+		return
+	}
+	for key, child := range object {
+		flattenJSON(path+"."+key, child, fields)
+	}
+}
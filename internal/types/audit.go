@@ -0,0 +1,147 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"os"
+	"strconv"
+	"time"
+)
+
+// defaultAuditLogRetentionDays is how long audit logs are kept when AUDIT_LOG_RETENTION_DAYS is not set
+const defaultAuditLogRetentionDays = 180
+
+// Audit actor types
+const (
+	AuditActorUser   = "user"    // Authenticated with a user JWT
+	AuditActorAPIKey = "api_key" // Authenticated with the tenant API key
+)
+
+// Audit actions
+const (
+	AuditActionCreate           = "create"
+	AuditActionUpdate           = "update"
+	AuditActionDelete           = "delete"
+	AuditActionCopy             = "copy"
+	AuditActionReparse          = "reparse"
+	AuditActionShare            = "share"
+	AuditActionUnshare          = "unshare"
+	AuditActionUpdatePermission = "update_permission"
+	AuditActionInviteMember     = "invite_member"
+	AuditActionUpdateMemberRole = "update_member_role"
+	AuditActionRemoveMember     = "remove_member"
+	AuditActionJoin             = "join"
+	AuditActionRequestJoin      = "request_join"
+	AuditActionRequestUpgrade   = "request_role_upgrade"
+	AuditActionLeave            = "leave"
+	AuditActionReviewJoin       = "review_join_request"
+	AuditActionGenerateInvite   = "generate_invite_code"
+	AuditActionAuthorize        = "authorize"
+	AuditActionRevoke           = "revoke"
+	AuditActionImport           = "import"
+	AuditActionPublish          = "publish"
+	AuditActionRollback         = "rollback"
+	AuditActionPinVersion       = "pin_version"
+	AuditActionExport           = "export"
+)
+
+// Audit resource types
+const (
+	AuditResourceKnowledgeBase = "knowledge_base"
+	AuditResourceKnowledge     = "knowledge"
+	AuditResourceKBShare       = "kb_share"
+	AuditResourceAgentShare    = "agent_share"
+	AuditResourceOrganization  = "organization"
+	AuditResourceModel         = "model"
+	AuditResourceAgent         = "agent"
+	AuditResourceTenant        = "tenant"
+	AuditResourceTenantKV      = "tenant_kv"
+	AuditResourceMCPService    = "mcp_service"
+	AuditResourceHTTPTool      = "http_tool"
+	AuditResourceBundle        = "bundle"
+)
+
+// Audit results
+const (
+	AuditResultSuccess = "success"
+	AuditResultFailed  = "failed"
+)
+
+// AuditChange is a summary of a resource state recorded in an audit log.
+// It only holds non-sensitive fields such as names, roles and permissions.
+type AuditChange map[string]interface{}
+
+// Value implements the driver.Valuer interface, used to convert AuditChange to database value
+func (c AuditChange) Value() (driver.Value, error) {
+	if c == nil {
+		return nil, nil
+	}
+	return json.Marshal(c)
+}
+
+// Scan implements the sql.Scanner interface, used to convert database value to AuditChange
+func (c *AuditChange) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	var b []byte
+	switch v := value.(type) {
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return nil
+	}
+	return json.Unmarshal(b, c)
+}
+
+// AuditLog is an append-only record of a mutation performed through the API
+type AuditLog struct {
+	ID       string `json:"id"        gorm:"type:varchar(36);primaryKey"`
+	TenantID uint64 `json:"tenant_id" gorm:"index"`
+	// user or api_key
+	ActorType string `json:"actor_type" gorm:"type:varchar(20)"`
+	// User ID, or the masked API key
+	ActorID      string      `json:"actor_id"      gorm:"type:varchar(64);index"`
+	ActorName    string      `json:"actor_name"    gorm:"type:varchar(255)"`
+	Action       string      `json:"action"        gorm:"type:varchar(64)"`
+	ResourceType string      `json:"resource_type" gorm:"type:varchar(64)"`
+	ResourceID   string      `json:"resource_id"   gorm:"type:varchar(255)"`
+	Before       AuditChange `json:"before"        gorm:"type:json"`
+	After        AuditChange `json:"after"         gorm:"type:json"`
+	Result       string      `json:"result"        gorm:"type:varchar(20)"`
+	StatusCode   int         `json:"status_code"`
+	Method       string      `json:"method"        gorm:"type:varchar(10)"`
+	Path         string      `json:"path"          gorm:"type:varchar(512)"`
+	IP           string      `json:"ip"            gorm:"type:varchar(64)"`
+	UserAgent    string      `json:"user_agent"    gorm:"type:varchar(512)"`
+	RequestID    string      `json:"request_id"    gorm:"type:varchar(64)"`
+	CreatedAt    time.Time   `json:"created_at"`
+}
+
+// TableName returns the table name for AuditLog
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
+
+// AuditLogFilter filters the audit logs of a tenant
+type AuditLogFilter struct {
+	ActorID      string     `form:"actor_id"`
+	Action       string     `form:"action"`
+	ResourceType string     `form:"resource_type"`
+	ResourceID   string     `form:"resource_id"`
+	Result       string     `form:"result"`
+	StartTime    *time.Time `form:"start_time" time_format:"2006-01-02T15:04:05Z07:00"`
+	EndTime      *time.Time `form:"end_time"   time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+// AuditLogRetentionDays returns how many days audit logs are kept, from AUDIT_LOG_RETENTION_DAYS.
+// 0 keeps audit logs forever.
+func AuditLogRetentionDays() int {
+	days, err := strconv.Atoi(os.Getenv("AUDIT_LOG_RETENTION_DAYS"))
+	if err != nil || days < 0 {
+		return defaultAuditLogRetentionDays
+	}
+	return days
+}
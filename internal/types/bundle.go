@@ -0,0 +1,361 @@
+package types
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// Bundle archive format. A bundle is a zip archive holding the files below,
+// the content of each included knowledge base lives under knowledge_bases/<id>/
+// and original documents under files/<knowledge id>.
+const (
+	BundleFormat        = "weknora-bundle"
+	BundleFormatVersion = 1
+
+	BundleManifestFile       = "manifest.json"
+	BundleAgentsFile         = "agents.json"
+	BundleModelsFile         = "models.json"
+	BundleSkillsFile         = "skills.json"
+	BundleMCPServicesFile    = "mcp_services.json"
+	BundleHTTPToolsFile      = "http_tools.json"
+	BundleKnowledgeBasesFile = "knowledge_bases.json"
+	BundleKnowledgeFile      = "knowledge.jsonl"
+	BundleChunksFile         = "chunks.jsonl"
+	BundleVectorsFile        = "vectors.jsonl"
+)
+
+// BundleKnowledgeBasePath returns the path of a file of an included knowledge base in a bundle
+func BundleKnowledgeBasePath(kbID string, name string) string {
+	return "knowledge_bases/" + kbID + "/" + name
+}
+
+// BundleDocumentPath returns the path of the original document of a knowledge in a bundle
+func BundleDocumentPath(knowledgeID string) string {
+	return "files/" + knowledgeID
+}
+
+// BundleManifest describes the content of a bundle
+type BundleManifest struct {
+	Format     string    `json:"format"`
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exported_at"`
+	// Agents the bundle was exported for, the agents they delegate to are included as well
+	AgentIDs []string `json:"agent_ids,omitempty"`
+	// Knowledge bases whose documents and chunks are included
+	KnowledgeBaseIDs []string `json:"knowledge_base_ids,omitempty"`
+	IncludeKnowledge bool     `json:"include_knowledge"`
+	IncludeVectors   bool     `json:"include_vectors"`
+	// References that could not be exported
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// BundleModel is a model referenced by a bundle, resolved by name and type on import
+type BundleModel struct {
+	ID     string      `json:"id"`
+	Name   string      `json:"name"`
+	Type   ModelType   `json:"type"`
+	Source ModelSource `json:"source"`
+}
+
+// BundleSkill is a preloaded skill referenced by a bundle, resolved by name on import
+type BundleSkill struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// BundleMCPService is an MCP service definition in a bundle, exported without its secrets
+type BundleMCPService struct {
+	Service *MCPService `json:"service"`
+	// Fields whose secret values were removed on export and need to be filled in after import
+	RemovedSecrets []string `json:"removed_secrets,omitempty"`
+}
+
+// BundleHTTPTool is an HTTP tool definition in a bundle, exported without its auth secret
+type BundleHTTPTool struct {
+	Tool           *HTTPTool `json:"tool"`
+	RemovedSecrets []string  `json:"removed_secrets,omitempty"`
+}
+
+// BundleKnowledgeBase is a knowledge base referenced by a bundle.
+// When Included is false only its name is used on import, to find a knowledge base of the same name.
+type BundleKnowledgeBase struct {
+	KnowledgeBase  *KnowledgeBase  `json:"knowledge_base"`
+	Included       bool            `json:"included"`
+	Tags           []*KnowledgeTag `json:"tags,omitempty"`
+	KnowledgeCount int64           `json:"knowledge_count"`
+	ChunkCount     int64           `json:"chunk_count"`
+	VectorCount    int64           `json:"vector_count"`
+	// Dimension of the exported vectors, 0 when vectors are not included
+	VectorDimension int      `json:"vector_dimension"`
+	RemovedSecrets  []string `json:"removed_secrets,omitempty"`
+}
+
+// BundleVector is an index entry of a chunk with its embedding
+type BundleVector struct {
+	SourceID      string    `json:"source_id"`
+	ChunkID       string    `json:"chunk_id"`
+	Content       string    `json:"content"`
+	KnowledgeType string    `json:"knowledge_type,omitempty"`
+	IsEnabled     bool      `json:"is_enabled"`
+	IsRecommended bool      `json:"is_recommended,omitempty"`
+	Embedding     []float32 `json:"embedding"`
+}
+
+// BundleExportOptions are the options of exporting a bundle
+type BundleExportOptions struct {
+	// Include the documents and chunks of the knowledge bases, otherwise they are only referenced by name
+	IncludeKnowledge bool `form:"include_knowledge"`
+	// Include the embeddings of the chunks so that import does not need to embed them again
+	IncludeVectors bool `form:"include_vectors"`
+}
+
+// How an import handles objects whose name is already taken in the importing tenant
+const (
+	BundleConflictFail   = "fail"   // Report the conflicts and import nothing
+	BundleConflictRename = "rename" // Import under a new name
+	BundleConflictReuse  = "reuse"  // Use the existing object instead
+)
+
+// BundleImportOptions are the options of importing a bundle
+type BundleImportOptions struct {
+	// Only report what would be imported
+	DryRun bool `form:"dry_run"`
+	// See the BundleConflict* constants, defaults to fail
+	OnConflict string `form:"on_conflict"`
+}
+
+// Kinds of objects in a bundle
+const (
+	BundleKindAgent         = "agent"
+	BundleKindKnowledgeBase = "knowledge_base"
+	BundleKindMCPService    = "mcp_service"
+	BundleKindHTTPTool      = "http_tool"
+	BundleKindModel         = "model"
+	BundleKindSkill         = "skill"
+)
+
+// What an import does with an object of a bundle
+const (
+	BundleActionCreate = "create" // Created under its own name
+	BundleActionRename = "rename" // Created under a new name because its name is taken
+	BundleActionReuse  = "reuse"  // An existing object of the same name is used
+	BundleActionSkip   = "skip"   // Not imported, references to it are dropped
+)
+
+// BundleImportItem reports what an import does with an object of a bundle
+type BundleImportItem struct {
+	Kind       string `json:"kind"`
+	SourceID   string `json:"source_id,omitempty"`
+	Name       string `json:"name"`
+	Action     string `json:"action"`
+	TargetID   string `json:"target_id,omitempty"`
+	TargetName string `json:"target_name,omitempty"`
+}
+
+// BundleConflict is a problem that prevents a bundle from being imported
+type BundleConflict struct {
+	Kind     string `json:"kind"`
+	SourceID string `json:"source_id,omitempty"`
+	Name     string `json:"name"`
+	Reason   string `json:"reason"`
+}
+
+// BundleImportReport is the result of importing a bundle
+type BundleImportReport struct {
+	DryRun     bool   `json:"dry_run"`
+	Imported   bool   `json:"imported"`
+	OnConflict string `json:"on_conflict"`
+	// Target IDs are empty in a dry run for objects that would be created
+	Items     []BundleImportItem `json:"items"`
+	Conflicts []BundleConflict   `json:"conflicts"`
+	Warnings  []string           `json:"warnings"`
+	// Imported knowledge, chunks and vectors, and chunks embedded again because no usable vectors were included
+	KnowledgeCount      int64 `json:"knowledge_count"`
+	ChunkCount          int64 `json:"chunk_count"`
+	VectorCount         int64 `json:"vector_count"`
+	ReindexedChunkCount int64 `json:"reindexed_chunk_count"`
+}
+
+// BundleIDMap maps the IDs referenced by a bundle to the IDs in the importing tenant.
+// References missing from the maps are dropped.
+type BundleIDMap struct {
+	Models         map[string]string
+	KnowledgeBases map[string]string
+	MCPServices    map[string]string
+	HTTPTools      map[string]string
+	// Old to new names of the HTTP tools, tool names are derived from them
+	HTTPToolNames map[string]string
+	Agents        map[string]string
+}
+
+// ReferencedModelIDs returns the IDs of the models an agent config references
+func (c *CustomAgentConfig) ReferencedModelIDs() []string {
+	ids := []string{c.ModelID, c.RerankModelID, c.MemoryEmbeddingModelID}
+	if c.Workflow != nil {
+		for _, node := range c.Workflow.Nodes {
+			ids = append(ids, node.ModelID)
+		}
+	}
+	return compactIDs(ids)
+}
+
+// ReferencedKnowledgeBaseIDs returns the IDs of the knowledge bases an agent config references
+func (c *CustomAgentConfig) ReferencedKnowledgeBaseIDs() []string {
+	ids := slices.Clone(c.KnowledgeBases)
+	if c.Workflow != nil {
+		for _, node := range c.Workflow.Nodes {
+			ids = append(ids, node.KnowledgeBases...)
+		}
+	}
+	return compactIDs(ids)
+}
+
+// RemapReferences rewrites the models, knowledge bases, MCP services, HTTP tools and sub-agents
+// an agent config references, including the tool names derived from them.
+// It returns a description of each dropped reference.
+func (c *CustomAgentConfig) RemapReferences(m *BundleIDMap) []string {
+	var dropped []string
+	remap := func(kind string, ids map[string]string, id string) string {
+		if id == "" {
+			return ""
+		}
+		if newID, ok := ids[id]; ok && newID != "" {
+			return newID
+		}
+		dropped = append(dropped, kind+" "+id)
+		return ""
+	}
+	remapList := func(kind string, ids map[string]string, list []string) []string {
+		if list == nil {
+			return nil
+		}
+		result := make([]string, 0, len(list))
+		for _, id := range list {
+			if newID := remap(kind, ids, id); newID != "" {
+				result = append(result, newID)
+			}
+		}
+		return result
+	}
+
+	c.ModelID = remap(BundleKindModel, m.Models, c.ModelID)
+	c.RerankModelID = remap(BundleKindModel, m.Models, c.RerankModelID)
+	c.MemoryEmbeddingModelID = remap(BundleKindModel, m.Models, c.MemoryEmbeddingModelID)
+	c.KnowledgeBases = remapList(BundleKindKnowledgeBase, m.KnowledgeBases, c.KnowledgeBases)
+	c.MCPServices = remapList(BundleKindMCPService, m.MCPServices, c.MCPServices)
+	c.ApprovalRequiredMCPServices = remapList(BundleKindMCPService, m.MCPServices, c.ApprovalRequiredMCPServices)
+	c.HTTPTools = remapList(BundleKindHTTPTool, m.HTTPTools, c.HTTPTools)
+
+	subAgents := make([]string, 0, len(c.SubAgents))
+	for _, id := range c.SubAgents {
+		if IsBuiltinAgentID(id) {
+			subAgents = append(subAgents, id)
+		} else if newID := remap(BundleKindAgent, m.Agents, id); newID != "" {
+			subAgents = append(subAgents, newID)
+		}
+	}
+	if c.SubAgents != nil {
+		c.SubAgents = subAgents
+	}
+
+	sources := c.MCPContextSources[:0]
+	for _, source := range c.MCPContextSources {
+		if source.ServiceID = remap(BundleKindMCPService, m.MCPServices, source.ServiceID); source.ServiceID != "" {
+			sources = append(sources, source)
+		}
+	}
+	if c.MCPContextSources != nil {
+		c.MCPContextSources = sources
+	}
+
+	rewrite := bundleToolNameRewriter(m)
+	for i, name := range c.AllowedTools {
+		c.AllowedTools[i] = rewrite(name)
+	}
+	for i, name := range c.ApprovalRequiredTools {
+		c.ApprovalRequiredTools[i] = rewrite(name)
+	}
+	if c.Workflow != nil {
+		for i := range c.Workflow.Nodes {
+			node := &c.Workflow.Nodes[i]
+			node.ModelID = remap(BundleKindModel, m.Models, node.ModelID)
+			node.KnowledgeBases = remapList(BundleKindKnowledgeBase, m.KnowledgeBases, node.KnowledgeBases)
+			node.Tool = rewrite(node.Tool)
+		}
+	}
+	return dropped
+}
+
+// ReferencedModelIDs returns the IDs of the models a knowledge base references
+func (kb *KnowledgeBase) ReferencedModelIDs() []string {
+	return compactIDs([]string{
+		kb.EmbeddingModelID, kb.SummaryModelID, kb.ImageProcessingConfig.ModelID, kb.VLMConfig.ModelID,
+	})
+}
+
+// RemapModels rewrites the models a knowledge base references, unknown models are dropped
+func (kb *KnowledgeBase) RemapModels(models map[string]string) {
+	kb.EmbeddingModelID = models[kb.EmbeddingModelID]
+	kb.SummaryModelID = models[kb.SummaryModelID]
+	kb.ImageProcessingConfig.ModelID = models[kb.ImageProcessingConfig.ModelID]
+	kb.VLMConfig.ModelID = models[kb.VLMConfig.ModelID]
+}
+
+// RemoveSecrets clears the storage credentials and legacy VLM API key of a knowledge base,
+// returning the names of the cleared fields
+func (kb *KnowledgeBase) RemoveSecrets() []string {
+	var removed []string
+	if kb.StorageConfig != (StorageConfig{}) {
+		kb.StorageConfig = StorageConfig{}
+		removed = append(removed, "cos_config")
+	}
+	if kb.VLMConfig.APIKey != "" {
+		kb.VLMConfig.APIKey = ""
+		removed = append(removed, "vlm_config.api_key")
+	}
+	return removed
+}
+
+// bundleToolNameRewriter returns a function renaming the tools of remapped MCP services,
+// HTTP tools and sub-agents, whose names embed the service ID, tool name or agent ID
+func bundleToolNameRewriter(m *BundleIDMap) func(string) string {
+	exact := make(map[string]string)
+	for oldName, newName := range m.HTTPToolNames {
+		exact["http_"+oldName] = "http_" + newName
+	}
+	for oldID, newID := range m.Agents {
+		exact["agent_"+bundleToolIDPart(oldID)] = "agent_" + bundleToolIDPart(newID)
+	}
+	prefixes := make(map[string]string)
+	for oldID, newID := range m.MCPServices {
+		prefixes["mcp_"+bundleToolIDPart(oldID)+"_"] = "mcp_" + bundleToolIDPart(newID) + "_"
+	}
+	return func(name string) string {
+		if newName, ok := exact[name]; ok {
+			return newName
+		}
+		for oldPrefix, newPrefix := range prefixes {
+			if strings.HasPrefix(name, oldPrefix) {
+				return newPrefix + strings.TrimPrefix(name, oldPrefix)
+			}
+		}
+		return name
+	}
+}
+
+// bundleToolIDPart returns an ID as it appears in tool names
+func bundleToolIDPart(id string) string {
+	return strings.ReplaceAll(strings.ToLower(id), "-", "_")
+}
+
+// compactIDs returns the distinct non-empty IDs in order
+func compactIDs(ids []string) []string {
+	result := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if id != "" && !slices.Contains(result, id) {
+			result = append(result, id)
+		}
+	}
+	return result
+}
@@ -60,6 +60,14 @@ const (
 	ResponseTypeAgentQuery ResponseType = "agent_query"
 	// Complete response type (agent complete)
 	ResponseTypeComplete ResponseType = "complete"
+	// Tool approval response type (agent tool call waiting for / resolved by user approval)
+	ResponseTypeToolApproval ResponseType = "tool_approval"
+	// Sub-agent answer response type (final answer of an agent delegated to by a tool call)
+	ResponseTypeSubAgentAnswer ResponseType = "sub_agent_answer"
+	// Workflow node start or outcome response type
+	ResponseTypeWorkflowNode ResponseType = "workflow_node"
+	// Structured output response type (final answer parsed against the output schema)
+	ResponseTypeStructuredOutput ResponseType = "structured_output"
 )
 
 // StreamResponse stream response
@@ -82,6 +90,8 @@ type StreamResponse struct {
 	ToolCalls []LLMToolCall `json:"tool_calls,omitempty"`
 	// Additional metadata for enhanced display
 	Data map[string]interface{} `json:"data,omitempty"`
+	// Token usage of the call, set on the final chunk when the provider reports it
+	Usage *TokenUsage `json:"usage,omitempty"`
 }
 
 // References references
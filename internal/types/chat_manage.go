@@ -30,6 +30,11 @@ type ChatManage struct {
 	FallbackResponse string           `json:"fallback_response"` // Default response when fallback occurs
 	FallbackPrompt   string           `json:"fallback_prompt"`   // Prompt for model-based fallback response
 
+	// JSON Schema the answer must conform to, empty for a free-form answer
+	OutputSchema OutputSchema `json:"output_schema,omitempty"`
+	// Whether the model also checks that the cited references support each cited sentence
+	CitationModelCheck bool `json:"citation_model_check"`
+
 	EnableRewrite        bool   `json:"enable_rewrite"`         // Whether to enable rewrite
 	EnableQueryExpansion bool   `json:"enable_query_expansion"` // Whether to enable query expansion with LLM
 	RewritePromptSystem  string `json:"rewrite_prompt_system"`  // Custom system prompt for rewrite stage
@@ -45,6 +50,10 @@ type ChatManage struct {
 	GraphResult     *GraphData        `json:"-"` // Graph data from search phase
 	UserContent     string            `json:"-"` // Processed user content
 	ChatResponse    *ChatResponse     `json:"-"` // Final response from chat model
+	// Answer parsed against OutputSchema
+	StructuredOutput interface{} `json:"-"`
+	// References numbered in the context, marker [n] of the answer cites CitationSources[n-1]
+	CitationSources []*SearchResult `json:"-"`
 
 	// Event system for streaming responses
 	EventBus  EventBusInterface `json:"-"` // EventBus for emitting streaming events
@@ -118,6 +127,8 @@ func (c *ChatManage) Clone() *ChatManage {
 		FallbackStrategy:     c.FallbackStrategy,
 		FallbackResponse:     c.FallbackResponse,
 		FallbackPrompt:       c.FallbackPrompt,
+		OutputSchema:         c.OutputSchema,
+		CitationModelCheck:   c.CitationModelCheck,
 		RewritePromptSystem:  c.RewritePromptSystem,
 		RewritePromptUser:    c.RewritePromptUser,
 		EnableRewrite:        c.EnableRewrite,
@@ -0,0 +1,94 @@
+package types
+
+import (
+	"context"
+	"sync"
+)
+
+// Reasons a cited sentence is not supported by its references
+const (
+	// CitationReasonUnknownReference means the sentence cites a number no reference has
+	CitationReasonUnknownReference = "unknown_reference"
+	// CitationReasonLowOverlap means too few terms of the sentence appear in the cited references
+	CitationReasonLowOverlap = "low_overlap"
+	// CitationReasonModelRejected means the model judged the references do not support the sentence
+	CitationReasonModelRejected = "model_rejected"
+)
+
+// Ways the cited sentences of an answer were verified
+const (
+	CitationCheckLexical = "lexical" // Lexical overlap with the cited references
+	CitationCheckModel   = "model"   // Lexical overlap, then the model's verdict
+)
+
+// CitationSentence is a sentence of an answer that carries citation markers
+type CitationSentence struct {
+	// Position of the sentence among all sentences of the answer, from 0
+	Index int `json:"index"`
+	// Sentence with the citation markers removed
+	Text string `json:"text"`
+	// Cited numbers, [n] refers to the n-th reference of the answer
+	Citations []int `json:"citations"`
+	// IDs of the cited references (SearchResult.ID)
+	ReferenceIDs []string `json:"reference_ids"`
+	// Share of the sentence's terms found in the cited references, from 0 to 1
+	Overlap float64 `json:"overlap"`
+	// Whether the cited references support the sentence
+	Supported bool `json:"supported"`
+	// Why the sentence is not supported, one of the CitationReason constants
+	Reason string `json:"reason,omitempty"`
+}
+
+// CitationReport maps the citation markers of an answer to its references
+// and lists the cited sentences the references do not support
+type CitationReport struct {
+	Sentences         []CitationSentence `json:"sentences"`
+	UnsupportedClaims []CitationSentence `json:"unsupported_claims"`
+	// How the sentences were verified, CitationCheckLexical or CitationCheckModel
+	Check string `json:"check"`
+}
+
+// CitationIndex numbers the knowledge references an answer can cite: marker [n] refers to
+// the n-th reference added. It is safe for concurrent use by the tools of an agent run.
+type CitationIndex struct {
+	mu      sync.Mutex
+	refs    []*SearchResult
+	numbers map[string]int
+}
+
+// NewCitationIndex creates an empty citation index
+func NewCitationIndex() *CitationIndex {
+	return &CitationIndex{numbers: make(map[string]int)}
+}
+
+// Add numbers a reference and returns its citation number, references with the same ID share a number.
+// It returns 0 for a nil index, so that tools can call it whether or not citations are collected.
+func (c *CitationIndex) Add(ref *SearchResult) int {
+	if c == nil || ref == nil || ref.ID == "" {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if n, ok := c.numbers[ref.ID]; ok {
+		return n
+	}
+	c.refs = append(c.refs, ref)
+	c.numbers[ref.ID] = len(c.refs)
+	return len(c.refs)
+}
+
+// References returns the numbered references, reference n at position n-1
+func (c *CitationIndex) References() []*SearchResult {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*SearchResult(nil), c.refs...)
+}
+
+// CitationIndexFromContext returns the citation index of the agent run, nil when none is set
+func CitationIndexFromContext(ctx context.Context) *CitationIndex {
+	index, _ := ctx.Value(CitationIndexContextKey).(*CitationIndex)
+	return index
+}
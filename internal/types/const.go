@@ -19,6 +19,14 @@ const (
 	// SessionTenantIDContextKey is the context key for session owner's tenant ID.
 	// When set (e.g. in pipeline with shared agent), session/message lookups use this instead of TenantIDContextKey.
 	SessionTenantIDContextKey ContextKey = "SessionTenantID"
+	// AuditBeforeContextKey is the gin context key for the resource state captured before a mutation,
+	// recorded by the audit middleware
+	AuditBeforeContextKey ContextKey = "AuditBefore"
+	// ToolCallIDContextKey is the context key for the ID of the agent tool call being executed
+	ToolCallIDContextKey ContextKey = "ToolCallID"
+	// CitationIndexContextKey is the context key for the citation index of an agent run,
+	// which numbers the knowledge references returned by tools
+	CitationIndexContextKey ContextKey = "CitationIndex"
 )
 
 // String returns the string representation of the context key
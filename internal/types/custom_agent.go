@@ -3,6 +3,7 @@ package types
 import (
 	"database/sql/driver"
 	"encoding/json"
+	"fmt"
 	"time"
 
 	"gorm.io/gorm"
@@ -30,6 +31,8 @@ const (
 	AgentModeQuickAnswer = "quick-answer"
 	// AgentModeSmartReasoning is the ReAct mode for multi-step reasoning
 	AgentModeSmartReasoning = "smart-reasoning"
+	// AgentModeWorkflow runs a declarative DAG of retrieval, LLM and tool nodes
+	AgentModeWorkflow = "workflow"
 )
 
 // CustomAgent represents a configurable AI agent (similar to GPTs)
@@ -54,6 +57,11 @@ type CustomAgent struct {
 	// Agent configuration
 	Config CustomAgentConfig `yaml:"config" json:"config" gorm:"type:json"`
 
+	// Version used in conversations, 0 when the agent has never been published and the draft is used
+	PublishedVersion int `yaml:"published_version" json:"published_version" gorm:"default:0"`
+	// Version the name, description, avatar and config above come from, 0 for the draft
+	Version int `yaml:"-" json:"version" gorm:"-"`
+
 	// Timestamps
 	CreatedAt time.Time      `yaml:"created_at" json:"created_at"`
 	UpdatedAt time.Time      `yaml:"updated_at" json:"updated_at"`
@@ -63,8 +71,10 @@ type CustomAgent struct {
 // CustomAgentConfig represents the configuration of a custom agent
 type CustomAgentConfig struct {
 	// ===== Basic Settings =====
-	// Agent mode: "quick-answer" for RAG mode, "smart-reasoning" for ReAct agent mode
+	// Agent mode: "quick-answer" for RAG mode, "smart-reasoning" for ReAct agent mode, "workflow" for workflow mode
 	AgentMode string `yaml:"agent_mode" json:"agent_mode"`
+	// Workflow run by the agent (only for workflow mode)
+	Workflow *WorkflowDefinition `yaml:"workflow" json:"workflow,omitempty"`
 	// System prompt for the agent (unified prompt, uses {{web_search_status}} placeholder for dynamic behavior)
 	SystemPrompt string `yaml:"system_prompt" json:"system_prompt"`
 	// Context template for normal mode (how to format retrieved chunks)
@@ -81,6 +91,11 @@ type CustomAgentConfig struct {
 	MaxCompletionTokens int `yaml:"max_completion_tokens" json:"max_completion_tokens"`
 	// Whether to enable thinking mode (for models that support extended thinking)
 	Thinking *bool `yaml:"thinking" json:"thinking"`
+	// JSON Schema the final answer must conform to, the answer is then returned as a parsed object
+	OutputSchema OutputSchema `yaml:"output_schema" json:"output_schema,omitempty"`
+	// Whether the model also checks that the references cited by each sentence of the answer support it,
+	// in addition to the lexical overlap check
+	CitationModelCheck bool `yaml:"citation_model_check" json:"citation_model_check"`
 
 	// ===== Agent Mode Settings =====
 	// Maximum iterations for ReAct loop (only for agent type)
@@ -93,6 +108,28 @@ type CustomAgentConfig struct {
 	MCPSelectionMode string `yaml:"mcp_selection_mode" json:"mcp_selection_mode"`
 	// Selected MCP service IDs (only used when MCPSelectionMode is "selected")
 	MCPServices []string `yaml:"mcp_services" json:"mcp_services"`
+	// MCP resources/prompts pinned as context sources, injected into the system prompt on every turn
+	MCPContextSources []MCPContextSource `yaml:"mcp_context_sources" json:"mcp_context_sources"`
+	// IDs of the HTTP tools the agent can call (only for agent type)
+	HTTPTools []string `yaml:"http_tools" json:"http_tools"`
+	// IDs of the agents this agent can delegate sub-questions to, each becomes a tool (only for agent type)
+	SubAgents []string `yaml:"sub_agents" json:"sub_agents"`
+	// Maximum ReAct iterations of each delegated run (default 5, capped by the sub-agent's own max_iterations)
+	SubAgentMaxIterations int `yaml:"sub_agent_max_iterations" json:"sub_agent_max_iterations"`
+	// Tool names that require user approval before execution (only for agent type)
+	ApprovalRequiredTools []string `yaml:"approval_required_tools" json:"approval_required_tools"`
+	// MCP service IDs whose tools all require user approval before execution (only for agent type)
+	ApprovalRequiredMCPServices []string `yaml:"approval_required_mcp_services" json:"approval_required_mcp_services"`
+	// Seconds to wait for an approval decision (default 300)
+	ToolApprovalTimeoutSeconds int `yaml:"tool_approval_timeout_seconds" json:"tool_approval_timeout_seconds"`
+	// Action when the approval times out: "deny" (default) or "approve"
+	ToolApprovalTimeoutAction string `yaml:"tool_approval_timeout_action" json:"tool_approval_timeout_action"`
+	// Whether the agent remembers facts about the user across sessions (only for agent type)
+	MemoryEnabled bool `yaml:"memory_enabled" json:"memory_enabled"`
+	// Embedding model used to recall memories semantically (empty = keyword recall)
+	MemoryEmbeddingModelID string `yaml:"memory_embedding_model_id" json:"memory_embedding_model_id"`
+	// Whether the full trace of each run is recorded for export and replay (only for agent type)
+	TraceEnabled bool `yaml:"trace_enabled" json:"trace_enabled"`
 
 	// ===== Skills Settings (only for smart-reasoning mode) =====
 	// Skills selection mode: "all" = all preloaded skills, "selected" = specific skills, "none" = no skills
@@ -164,6 +201,62 @@ type CustomAgentConfig struct {
 	FallbackPrompt string `yaml:"fallback_prompt" json:"fallback_prompt"`
 }
 
+// MCP context source types
+const (
+	MCPContextSourceResource = "resource" // Read an MCP resource by URI
+	MCPContextSourcePrompt   = "prompt"   // Render an MCP prompt with fixed arguments
+)
+
+// MaxMCPContextSources is the maximum number of MCP context sources an agent can pin
+const MaxMCPContextSources = 10
+
+// MCPContextSource is an MCP resource or prompt pinned to an agent as context
+type MCPContextSource struct {
+	// MCP service ID providing the resource or prompt
+	ServiceID string `yaml:"service_id" json:"service_id"`
+	// Source type: "resource" or "prompt"
+	Type string `yaml:"type" json:"type"`
+	// Resource URI (only used when Type is "resource")
+	URI string `yaml:"uri,omitempty" json:"uri,omitempty"`
+	// Prompt name (only used when Type is "prompt")
+	PromptName string `yaml:"prompt_name,omitempty" json:"prompt_name,omitempty"`
+	// Prompt arguments (only used when Type is "prompt")
+	Arguments map[string]string `yaml:"arguments,omitempty" json:"arguments,omitempty"`
+}
+
+// Validate checks that the context source is well-formed
+func (s MCPContextSource) Validate() error {
+	if s.ServiceID == "" {
+		return fmt.Errorf("mcp context source: service_id is required")
+	}
+	switch s.Type {
+	case MCPContextSourceResource:
+		if s.URI == "" {
+			return fmt.Errorf("mcp context source: uri is required for resource sources")
+		}
+	case MCPContextSourcePrompt:
+		if s.PromptName == "" {
+			return fmt.Errorf("mcp context source: prompt_name is required for prompt sources")
+		}
+	default:
+		return fmt.Errorf("mcp context source: unsupported type %q", s.Type)
+	}
+	return nil
+}
+
+// ValidateMCPContextSources validates the pinned MCP context sources of the config
+func (c *CustomAgentConfig) ValidateMCPContextSources() error {
+	if len(c.MCPContextSources) > MaxMCPContextSources {
+		return fmt.Errorf("at most %d mcp context sources are allowed", MaxMCPContextSources)
+	}
+	for _, source := range c.MCPContextSources {
+		if err := source.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Value implements driver.Valuer interface for CustomAgentConfig
 func (c CustomAgentConfig) Value() (driver.Value, error) {
 	return json.Marshal(c)
@@ -237,6 +330,17 @@ func (a *CustomAgent) IsAgentMode() bool {
 	return a.Config.AgentMode == AgentModeSmartReasoning
 }
 
+// IsWorkflowMode returns true if this agent runs a declarative workflow
+func (a *CustomAgent) IsWorkflowMode() bool {
+	return a.Config.AgentMode == AgentModeWorkflow
+}
+
+// RunsAgentQA returns true if this agent answers through AgentQA, which emits agent.complete
+// itself: ReAct agent mode and workflow mode
+func (a *CustomAgent) RunsAgentQA() bool {
+	return a.IsAgentMode() || a.IsWorkflowMode()
+}
+
 // GetBuiltinQuickAnswerAgent returns the built-in quick answer (RAG) mode agent
 func GetBuiltinQuickAnswerAgent(tenantID uint64) *CustomAgent {
 	return &CustomAgent{
@@ -39,3 +39,10 @@ type IndexInfo struct {
 	IsEnabled       bool       // Whether the chunk is enabled for retrieval
 	IsRecommended   bool       // Whether the chunk is recommended
 }
+
+// IndexEntry is a stored index entry together with its embedding,
+// used to move indices between retriever engines without re-embedding
+type IndexEntry struct {
+	IndexInfo
+	Embedding []float32 // Stored embedding, empty if the engine keeps no vector for the entry
+}
@@ -11,6 +11,15 @@ const (
 	TypeKBDelete            = "kb:delete"             // 知识库删除任务
 	TypeKnowledgeListDelete = "knowledge:list_delete" // 批量删除知识任务
 	TypeDataTableSummary    = "datatable:summary"     // 表格摘要任务
+	TypeIndexMigration      = "index:migrate"         // 索引跨检索引擎迁移任务
+	TypeIndexCheck          = "index:check"           // 知识库索引一致性检查任务
+	TypeIndexCheckScan      = "index:check_scan"      // 定时索引一致性巡检任务
+	TypeWebhookDelivery     = "webhook:deliver"       // Webhook 事件投递任务
+	TypeAuditLogCleanup     = "audit:cleanup"         // 审计日志过期清理任务
+	TypeAgentTaskRun        = "agent_task:run"        // 智能体定时/事件任务的单次运行
+	TypeAgentTaskSchedule   = "agent_task:schedule"   // 扫描到期的智能体定时任务
+	TypeAgentTestRun        = "agent_test:run"        // 智能体回归测试运行
+	TypeAgentTraceCleanup   = "agent_trace:cleanup"   // 智能体运行轨迹过期清理任务
 )
 
 // ExtractChunkPayload represents the extract chunk task payload
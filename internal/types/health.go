@@ -0,0 +1,30 @@
+package types
+
+import "time"
+
+// Health statuses of a dependency and of the whole report
+const (
+	HealthStatusUp       = "up"       // The dependency answered the probe
+	HealthStatusDown     = "down"     // The probe failed or timed out
+	HealthStatusDegraded = "degraded" // Only optional dependencies are down
+)
+
+// DependencyHealth is the probe result of a single dependency
+type DependencyHealth struct {
+	// Name of the dependency, e.g. database, redis, retriever:postgres
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	// Required dependencies make the instance not ready when they are down
+	Required  bool   `json:"required"`
+	LatencyMs int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// HealthReport is the readiness report of the instance
+type HealthReport struct {
+	Status    string             `json:"status"`
+	Checks    []DependencyHealth `json:"checks"`
+	CheckedAt time.Time          `json:"checked_at"`
+	// Whether the report was served from the cache
+	Cached bool `json:"cached"`
+}
@@ -0,0 +1,139 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+)
+
+// HTTP tool argument locations
+const (
+	HTTPToolArgPath   = "path"   // Replaces the {name} placeholder of the URL template
+	HTTPToolArgQuery  = "query"  // Added to the query string
+	HTTPToolArgHeader = "header" // Sent as a request header
+	HTTPToolArgBody   = "body"   // Set as a field of the JSON request body
+	// The argument is the whole JSON request body, used for non-object request bodies
+	HTTPToolArgBodyRaw = "body_raw"
+)
+
+// HTTP tool sources
+const (
+	HTTPToolSourceManual  = "manual"  // Written by hand
+	HTTPToolSourceOpenAPI = "openapi" // Imported from an OpenAPI 3 document
+)
+
+// HTTPTool is an HTTP endpoint of a tenant exposed to agents as a tool.
+// The URL template can reference arguments as {name}; arguments not in the URL are sent
+// in the query string, headers or JSON body according to ArgLocations.
+// The auth secret is encrypted at rest and never returned.
+type HTTPTool struct {
+	ID          string `json:"id"          gorm:"type:varchar(36);primaryKey"`
+	TenantID    uint64 `json:"tenant_id"   gorm:"index"`
+	Name        string `json:"name"        gorm:"type:varchar(64)"`
+	Description string `json:"description" gorm:"type:text"`
+	Method      string `json:"method"      gorm:"type:varchar(10)"`
+	URL         string `json:"url"         gorm:"type:varchar(2048)"`
+	// JSON schema of the arguments, an object schema
+	Parameters HTTPToolSchema `json:"parameters" gorm:"type:json"`
+	// Location of each argument, see HTTPToolArg*. Arguments without a location are sent in the path
+	// when the URL references them, in the query string for GET/DELETE and in the JSON body otherwise.
+	ArgLocations HTTPToolStrings `json:"arg_locations" gorm:"type:json"`
+	// Static request headers
+	Headers HTTPToolStrings `json:"headers" gorm:"type:json"`
+	// Header carrying the auth secret, e.g. Authorization
+	AuthHeader    string          `json:"auth_header"     gorm:"type:varchar(255)"`
+	AuthSecret    EncryptedString `json:"-"               gorm:"type:text"`
+	HasAuthSecret bool            `json:"has_auth_secret" gorm:"-"`
+	// JSONPath selecting the part of a JSON response returned to the agent, e.g. $.data.items[*].name
+	ResponsePath   string    `json:"response_path"   gorm:"type:varchar(512)"`
+	TimeoutSeconds int       `json:"timeout_seconds"`
+	Source         string    `json:"source"          gorm:"type:varchar(20);default:'manual'"`
+	Enabled        bool      `json:"enabled"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// TableName returns the table name for HTTPTool
+func (HTTPTool) TableName() string {
+	return "http_tools"
+}
+
+// HTTPToolStrings is a string map stored as JSON
+type HTTPToolStrings map[string]string
+
+// Value implements driver.Valuer interface for HTTPToolStrings
+func (s HTTPToolStrings) Value() (driver.Value, error) {
+	if s == nil {
+		return nil, nil
+	}
+	return json.Marshal(s)
+}
+
+// Scan implements sql.Scanner interface for HTTPToolStrings
+func (s *HTTPToolStrings) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, s)
+	case string:
+		return json.Unmarshal([]byte(v), s)
+	default:
+		*s = nil
+		return nil
+	}
+}
+
+// HTTPToolSchema is the JSON schema of the arguments of an HTTP tool
+type HTTPToolSchema json.RawMessage
+
+// Value implements driver.Valuer interface for HTTPToolSchema
+func (s HTTPToolSchema) Value() (driver.Value, error) {
+	if len(s) == 0 {
+		return nil, nil
+	}
+	return []byte(s), nil
+}
+
+// Scan implements sql.Scanner interface for HTTPToolSchema
+func (s *HTTPToolSchema) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		*s = append(HTTPToolSchema(nil), v...)
+	case string:
+		*s = HTTPToolSchema(v)
+	default:
+		*s = nil
+	}
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler interface for HTTPToolSchema
+func (s HTTPToolSchema) MarshalJSON() ([]byte, error) {
+	if len(s) == 0 {
+		return []byte("null"), nil
+	}
+	return s, nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface for HTTPToolSchema
+func (s *HTTPToolSchema) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*s = nil
+		return nil
+	}
+	*s = append(HTTPToolSchema(nil), data...)
+	return nil
+}
+
+// HTTPToolImportRequest imports the operations of an OpenAPI 3 document as HTTP tools
+type HTTPToolImportRequest struct {
+	// OpenAPI 3 document, JSON or YAML
+	Spec string `json:"spec" binding:"required"`
+	// Overrides the first server URL of the document
+	BaseURL string `json:"base_url"`
+	// Operation IDs to import, all operations when empty
+	Operations []string `json:"operations"`
+	// Auth header and secret applied to the imported tools
+	AuthHeader string `json:"auth_header"`
+	AuthSecret string `json:"auth_secret"`
+	Enabled    *bool  `json:"enabled"`
+}
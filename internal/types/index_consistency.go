@@ -0,0 +1,112 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+)
+
+// Index consistency check statuses
+const (
+	IndexConsistencyStatusPending   = "pending"   // Waiting for the task to start
+	IndexConsistencyStatusRunning   = "running"   // Comparing chunks with the engines
+	IndexConsistencyStatusCompleted = "completed" // Report is ready
+	IndexConsistencyStatusFailed    = "failed"    // Stopped with an error
+)
+
+// Index consistency check triggers
+const (
+	IndexConsistencyTriggerManual    = "manual"    // Requested through the API
+	IndexConsistencyTriggerScheduled = "scheduled" // Started by the periodic scan
+)
+
+// IndexConsistencySampleSize is the maximum number of IDs kept per drift kind in a report
+const IndexConsistencySampleSize = 50
+
+// IndexConsistencyReport is the result of comparing the chunks of a knowledge base
+// with the index entries stored in each retriever engine of the tenant
+type IndexConsistencyReport struct {
+	ID              string `json:"id"                gorm:"type:varchar(36);primaryKey"`
+	TenantID        uint64 `json:"tenant_id"         gorm:"index"`
+	KnowledgeBaseID string `json:"knowledge_base_id" gorm:"type:varchar(36);index"`
+	Trigger         string `json:"trigger"           gorm:"type:varchar(20)"`
+	// Whether drift is repaired after it is detected
+	Repair bool   `json:"repair"`
+	Status string `json:"status" gorm:"type:varchar(20);default:'pending'"`
+	// Number of chunks expected to have index entries
+	ChunkCount int64 `json:"chunk_count"`
+	// Total drift over all engines
+	DriftCount int64                         `json:"drift_count"`
+	Engines    IndexConsistencyEngineReports `json:"engines"     gorm:"type:json"`
+	Error      string                        `json:"error"       gorm:"type:text"`
+	StartedAt  *time.Time                    `json:"started_at"`
+	FinishedAt *time.Time                    `json:"finished_at"`
+	CreatedAt  time.Time                     `json:"created_at"`
+	UpdatedAt  time.Time                     `json:"updated_at"`
+}
+
+// TableName returns the table name for IndexConsistencyReport
+func (IndexConsistencyReport) TableName() string {
+	return "index_consistency_reports"
+}
+
+// IsActive reports whether the check has not finished yet
+func (r *IndexConsistencyReport) IsActive() bool {
+	return r.Status == IndexConsistencyStatusPending || r.Status == IndexConsistencyStatusRunning
+}
+
+// IndexConsistencyEngineReport is the drift found in one retriever engine.
+// Counts are exact, ID lists are samples of at most IndexConsistencySampleSize entries.
+type IndexConsistencyEngineReport struct {
+	Engine     RetrieverEngineType `json:"engine"`
+	EntryCount int64               `json:"entry_count"`
+	// Chunks without any index entry
+	MissingCount    int64    `json:"missing_count"`
+	MissingChunkIDs []string `json:"missing_chunk_ids"`
+	// Index entries whose chunk no longer exists
+	OrphanCount     int64    `json:"orphan_count"`
+	OrphanSourceIDs []string `json:"orphan_source_ids"`
+	// Chunks whose index entries have a different enabled status
+	EnabledMismatchCount    int64    `json:"enabled_mismatch_count"`
+	EnabledMismatchChunkIDs []string `json:"enabled_mismatch_chunk_ids"`
+	// Chunks whose index entries have a different tag
+	TagMismatchCount    int64    `json:"tag_mismatch_count"`
+	TagMismatchChunkIDs []string `json:"tag_mismatch_chunk_ids"`
+	// Whether the drift of this engine was repaired
+	Repaired    bool   `json:"repaired"`
+	RepairError string `json:"repair_error,omitempty"`
+}
+
+// DriftCount returns the total drift of the engine
+func (r *IndexConsistencyEngineReport) DriftCount() int64 {
+	return r.MissingCount + r.OrphanCount + r.EnabledMismatchCount + r.TagMismatchCount
+}
+
+// IndexConsistencyEngineReports is the per engine report list stored as JSON
+type IndexConsistencyEngineReports []IndexConsistencyEngineReport
+
+// Value implements the driver.Valuer interface, used to convert IndexConsistencyEngineReports to database value
+func (c IndexConsistencyEngineReports) Value() (driver.Value, error) {
+	if c == nil {
+		return json.Marshal([]IndexConsistencyEngineReport{})
+	}
+	return json.Marshal(c)
+}
+
+// Scan implements the sql.Scanner interface, used to convert database value to IndexConsistencyEngineReports
+func (c *IndexConsistencyEngineReports) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(b, c)
+}
+
+// IndexConsistencyCheckPayload represents the index consistency check task payload
+type IndexConsistencyCheckPayload struct {
+	TenantID uint64 `json:"tenant_id"`
+	ReportID string `json:"report_id"`
+}
@@ -0,0 +1,98 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+)
+
+// Index migration statuses
+const (
+	IndexMigrationStatusPending   = "pending"   // Waiting for the task to start
+	IndexMigrationStatusRunning   = "running"   // Copying or verifying indices
+	IndexMigrationStatusCompleted = "completed" // All knowledge bases verified and the tenant switched to the target engine
+	IndexMigrationStatusFailed    = "failed"    // Stopped with an error, can be resumed
+)
+
+// Index migration phases of a knowledge base
+const (
+	IndexMigrationPhaseCopy   = "copy"   // Streaming entries from the source engine, resumable from Cursor
+	IndexMigrationPhaseVerify = "verify" // Reconciling changes made during the copy and comparing counts
+	IndexMigrationPhaseDone   = "done"   // Source and target hold the same entries
+)
+
+// IndexMigration moves the indices of all knowledge bases of a tenant from one retriever engine
+// to another without re-embedding, then switches the tenant's engine mapping to the target engine
+type IndexMigration struct {
+	ID           string              `json:"id"            gorm:"type:varchar(36);primaryKey"`
+	TenantID     uint64              `json:"tenant_id"     gorm:"index"`
+	SourceEngine RetrieverEngineType `json:"source_engine" gorm:"type:varchar(50)"`
+	TargetEngine RetrieverEngineType `json:"target_engine" gorm:"type:varchar(50)"`
+	Status       string              `json:"status"        gorm:"type:varchar(20);default:'pending'"`
+	// Progress of each knowledge base, in migration order
+	KnowledgeBases IndexMigrationKnowledgeBases `json:"knowledge_bases" gorm:"type:json"`
+	// Number of entries copied so far, over all knowledge bases
+	Copied int64 `json:"copied"`
+	// Whether the tenant's engine mapping was switched to the target engine
+	Switched   bool       `json:"switched"`
+	Error      string     `json:"error"       gorm:"type:text"`
+	StartedAt  *time.Time `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// TableName returns the table name for IndexMigration
+func (IndexMigration) TableName() string {
+	return "index_migrations"
+}
+
+// IsActive reports whether the migration has not finished yet
+func (m *IndexMigration) IsActive() bool {
+	return m.Status == IndexMigrationStatusPending || m.Status == IndexMigrationStatusRunning
+}
+
+// IndexMigrationKnowledgeBase is the migration progress of a knowledge base
+type IndexMigrationKnowledgeBase struct {
+	KnowledgeBaseID string `json:"knowledge_base_id"`
+	Name            string `json:"name"`
+	// Knowledge base type, passed to the engines as the knowledge type
+	Type string `json:"type"`
+	// Embedding dimension, selects the collection for engines that store each dimension separately
+	Dimension int    `json:"dimension"`
+	Phase     string `json:"phase"`
+	// Cursor of the next page to copy from the source engine
+	Cursor      string `json:"cursor"`
+	Copied      int64  `json:"copied"`
+	SourceCount int64  `json:"source_count"`
+	TargetCount int64  `json:"target_count"`
+}
+
+// IndexMigrationKnowledgeBases is the progress list stored as JSON
+type IndexMigrationKnowledgeBases []IndexMigrationKnowledgeBase
+
+// Value implements the driver.Valuer interface, used to convert IndexMigrationKnowledgeBases to database value
+func (c IndexMigrationKnowledgeBases) Value() (driver.Value, error) {
+	if c == nil {
+		return json.Marshal([]IndexMigrationKnowledgeBase{})
+	}
+	return json.Marshal(c)
+}
+
+// Scan implements the sql.Scanner interface, used to convert database value to IndexMigrationKnowledgeBases
+func (c *IndexMigrationKnowledgeBases) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(b, c)
+}
+
+// IndexMigrationPayload represents the index migration task payload
+type IndexMigrationPayload struct {
+	TenantID    uint64 `json:"tenant_id"`
+	MigrationID string `json:"migration_id"`
+}
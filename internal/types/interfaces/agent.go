@@ -41,6 +41,20 @@ type AgentService interface {
 		sessionID string,
 	) (AgentEngine, error)
 
+	// CreateWorkflowEngine creates an engine running config.Workflow, with the tools of the agent config
+	CreateWorkflowEngine(
+		ctx context.Context,
+		config *types.AgentConfig,
+		chatModel chat.Chat,
+		rerankModel rerank.Reranker,
+		eventBus *event.EventBus,
+		sessionID string,
+	) (AgentEngine, error)
+
 	// ValidateConfig validates an agent configuration
 	ValidateConfig(config *types.AgentConfig) error
+
+	// BuildMCPContext reads the pinned MCP resources/prompts and renders them as a system prompt section.
+	// Sources that fail to load are skipped; an empty string is returned when nothing could be loaded.
+	BuildMCPContext(ctx context.Context, sources []types.MCPContextSource) string
 }
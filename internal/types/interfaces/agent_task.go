@@ -0,0 +1,89 @@
+package interfaces
+
+import (
+	"context"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/hibiken/asynq"
+)
+
+// AgentTaskRepository defines the interface for agent task and run data access
+type AgentTaskRepository interface {
+	// Create creates a new agent task
+	Create(ctx context.Context, task *types.AgentTask) error
+
+	// GetByID retrieves an agent task of a tenant by ID
+	GetByID(ctx context.Context, tenantID uint64, id string) (*types.AgentTask, error)
+
+	// List retrieves all agent tasks of a tenant
+	List(ctx context.Context, tenantID uint64) ([]*types.AgentTask, error)
+
+	// ListEnabledByTrigger retrieves the enabled agent tasks of a tenant with a trigger
+	ListEnabledByTrigger(ctx context.Context, tenantID uint64, trigger string) ([]*types.AgentTask, error)
+
+	// ListDue retrieves the enabled schedule tasks of all tenants due at now, at most limit
+	ListDue(ctx context.Context, now time.Time, limit int) ([]*types.AgentTask, error)
+
+	// ClaimNextRun moves the next run of a schedule task that is still due at now to next,
+	// a nil next disables the task. It returns false when another scheduler claimed the run first.
+	ClaimNextRun(ctx context.Context, task *types.AgentTask, now time.Time, next *time.Time) (bool, error)
+
+	// Update saves an agent task
+	Update(ctx context.Context, task *types.AgentTask) error
+
+	// UpdateLastRun saves the start time and status of the last run of a task
+	UpdateLastRun(ctx context.Context, tenantID uint64, id string, at time.Time, status string) error
+
+	// Delete deletes an agent task and its runs
+	Delete(ctx context.Context, tenantID uint64, id string) error
+
+	// CreateRun creates a new run record
+	CreateRun(ctx context.Context, run *types.AgentTaskRun) error
+
+	// GetRun retrieves a run of a tenant by ID
+	GetRun(ctx context.Context, tenantID uint64, id string) (*types.AgentTaskRun, error)
+
+	// ListRuns retrieves the runs of a task, newest first
+	ListRuns(ctx context.Context,
+		tenantID uint64, taskID string, page *types.Pagination,
+	) ([]*types.AgentTaskRun, int64, error)
+
+	// UpdateRun saves the progress of a run
+	UpdateRun(ctx context.Context, run *types.AgentTaskRun) error
+}
+
+// AgentTaskService defines the interface for managing agent tasks and firing their runs.
+// Runs are executed by the "agentTaskRunner" task handler.
+type AgentTaskService interface {
+	// CreateTask creates an agent task for the current tenant
+	CreateTask(ctx context.Context, task *types.AgentTask) (*types.AgentTask, error)
+
+	// GetTask returns an agent task of the current tenant
+	GetTask(ctx context.Context, id string) (*types.AgentTask, error)
+
+	// ListTasks lists the agent tasks of the current tenant
+	ListTasks(ctx context.Context) ([]*types.AgentTask, error)
+
+	// UpdateTask updates an agent task of the current tenant
+	UpdateTask(ctx context.Context, task *types.AgentTask) (*types.AgentTask, error)
+
+	// DeleteTask deletes an agent task of the current tenant and its run history
+	DeleteTask(ctx context.Context, id string) error
+
+	// RunTask enqueues a run of a task of the current tenant now, with data as the event fields
+	RunTask(ctx context.Context, id string, data map[string]interface{}) (*types.AgentTaskRun, error)
+
+	// ListRuns lists the run history of a task of the current tenant
+	ListRuns(ctx context.Context, taskID string, page *types.Pagination) (*types.PageResult, error)
+
+	// GetRun returns a run of a task of the current tenant
+	GetRun(ctx context.Context, taskID string, runID string) (*types.AgentTaskRun, error)
+
+	// Fire enqueues a run of each enabled task of the tenant the event triggers.
+	// Errors are logged and never returned, so callers are not affected by task failures.
+	Fire(ctx context.Context, tenantID uint64, trigger string, data map[string]interface{})
+
+	// ProcessAgentTaskSchedule handles the periodic asynq task that enqueues the due schedule tasks
+	ProcessAgentTaskSchedule(ctx context.Context, t *asynq.Task) error
+}
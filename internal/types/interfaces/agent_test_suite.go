@@ -0,0 +1,70 @@
+package interfaces
+
+import (
+	"context"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// AgentTestRepository defines the interface for agent test case and test run data access
+type AgentTestRepository interface {
+	// CreateCase creates a new test case
+	CreateCase(ctx context.Context, testCase *types.AgentTestCase) error
+
+	// GetCase retrieves a test case of a tenant by ID
+	GetCase(ctx context.Context, tenantID uint64, id string) (*types.AgentTestCase, error)
+
+	// ListCases retrieves the test cases of an agent, oldest first
+	ListCases(ctx context.Context, tenantID uint64, agentID string) ([]*types.AgentTestCase, error)
+
+	// CountCases counts the test cases of an agent
+	CountCases(ctx context.Context, tenantID uint64, agentID string) (int64, error)
+
+	// UpdateCase saves a test case
+	UpdateCase(ctx context.Context, testCase *types.AgentTestCase) error
+
+	// DeleteCase deletes a test case
+	DeleteCase(ctx context.Context, tenantID uint64, id string) error
+
+	// CreateRun creates a new test run
+	CreateRun(ctx context.Context, run *types.AgentTestRun) error
+
+	// GetRun retrieves a test run of a tenant by ID
+	GetRun(ctx context.Context, tenantID uint64, id string) (*types.AgentTestRun, error)
+
+	// ListRuns retrieves the test runs of an agent, newest first
+	ListRuns(ctx context.Context,
+		tenantID uint64, agentID string, page *types.Pagination,
+	) ([]*types.AgentTestRun, int64, error)
+
+	// UpdateRun saves the progress of a test run
+	UpdateRun(ctx context.Context, run *types.AgentTestRun) error
+}
+
+// AgentTestService defines the interface for managing the regression test cases of custom agents
+// and starting test runs. Test runs are executed by the "agentTestRunner" task handler.
+type AgentTestService interface {
+	// CreateCase creates a test case of an agent of the current tenant
+	CreateCase(ctx context.Context, testCase *types.AgentTestCase) (*types.AgentTestCase, error)
+
+	// ListCases lists the test cases of an agent of the current tenant
+	ListCases(ctx context.Context, agentID string) ([]*types.AgentTestCase, error)
+
+	// UpdateCase updates a test case of an agent of the current tenant
+	UpdateCase(ctx context.Context, testCase *types.AgentTestCase) (*types.AgentTestCase, error)
+
+	// DeleteCase deletes a test case of an agent of the current tenant
+	DeleteCase(ctx context.Context, agentID string, id string) error
+
+	// StartRun enqueues a test run of an agent of the current tenant
+	StartRun(ctx context.Context, agentID string, req *types.AgentTestRunRequest) (*types.AgentTestRun, error)
+
+	// ListRuns lists the test runs of an agent of the current tenant
+	ListRuns(ctx context.Context, agentID string, page *types.Pagination) (*types.PageResult, error)
+
+	// GetRun returns a test run of an agent of the current tenant
+	GetRun(ctx context.Context, agentID string, runID string) (*types.AgentTestRun, error)
+
+	// CompareRuns compares the results of the target test run of an agent against the base one
+	CompareRuns(ctx context.Context, agentID string, baseRunID string, targetRunID string) (*types.AgentTestRunComparison, error)
+}
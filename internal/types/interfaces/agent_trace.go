@@ -0,0 +1,43 @@
+package interfaces
+
+import (
+	"context"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/hibiken/asynq"
+)
+
+// AgentTraceRepository defines the interface for agent run trace data access
+type AgentTraceRepository interface {
+	// Create saves the trace of a run
+	Create(ctx context.Context, trace *types.AgentTrace) error
+
+	// ListByMessage retrieves the traces of the runs of an assistant message, oldest first
+	ListByMessage(ctx context.Context, tenantID uint64, sessionID, messageID string) ([]*types.AgentTrace, error)
+
+	// DeleteBySession deletes the traces of all runs of a session
+	DeleteBySession(ctx context.Context, tenantID uint64, sessionID string) error
+
+	// DeleteByMessage deletes the traces of the runs of an assistant message
+	DeleteByMessage(ctx context.Context, tenantID uint64, sessionID, messageID string) error
+
+	// DeleteBefore deletes the traces of all tenants created before the given time
+	DeleteBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+// AgentTraceService defines the interface for exporting and replaying agent run traces
+type AgentTraceService interface {
+	// GetTrace returns the trace of the agent run of an assistant message, with the traces of
+	// the sub-agent runs it delegated to
+	GetTrace(ctx context.Context, sessionID, messageID string) (*types.AgentTrace, error)
+
+	// ReplayTrace re-executes the agent run of an assistant message with its recorded tool results
+	// and returns the trace of the replay
+	ReplayTrace(
+		ctx context.Context, sessionID, messageID string, req *types.AgentTraceReplayRequest,
+	) (*types.AgentTrace, error)
+
+	// ProcessAgentTraceCleanup handles the asynq agent trace retention task
+	ProcessAgentTraceCleanup(ctx context.Context, t *asynq.Task) error
+}
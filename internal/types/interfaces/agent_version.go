@@ -0,0 +1,40 @@
+package interfaces
+
+import (
+	"context"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// AgentVersionRepository defines the interface for published agent version data access
+type AgentVersionRepository interface {
+	// Publish saves a new version and makes it the published version of its agent
+	Publish(ctx context.Context, version *types.AgentVersion) error
+
+	// GetVersion retrieves a version of an agent, nil when it does not exist
+	GetVersion(ctx context.Context, tenantID uint64, agentID string, version int) (*types.AgentVersion, error)
+
+	// ListVersions retrieves the versions of an agent, newest first
+	ListVersions(ctx context.Context, tenantID uint64, agentID string) ([]*types.AgentVersion, error)
+
+	// LatestVersion returns the highest version number of an agent, 0 when it has none
+	LatestVersion(ctx context.Context, tenantID uint64, agentID string) (int, error)
+}
+
+// AgentVersionService defines the interface for publishing, comparing and rolling back custom agent versions
+type AgentVersionService interface {
+	// Publish publishes the draft of an agent of the current tenant as a new version
+	Publish(ctx context.Context, agentID string, req *types.PublishAgentRequest) (*types.AgentVersion, error)
+
+	// ListVersions lists the versions of an agent of the current tenant, newest first
+	ListVersions(ctx context.Context, agentID string) ([]*types.AgentVersion, error)
+
+	// GetVersion returns a version of an agent of the current tenant, version 0 is the draft
+	GetVersion(ctx context.Context, agentID string, version int) (*types.AgentVersion, error)
+
+	// DiffVersions compares two versions of an agent of the current tenant, version 0 is the draft
+	DiffVersions(ctx context.Context, agentID string, from int, to int) (*types.AgentVersionDiff, error)
+
+	// Rollback publishes an earlier version of an agent of the current tenant again as a new version
+	Rollback(ctx context.Context, agentID string, version int) (*types.CustomAgent, error)
+}
@@ -0,0 +1,39 @@
+package interfaces
+
+import (
+	"context"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/hibiken/asynq"
+)
+
+// AuditLogRepository defines the interface for audit log data access.
+// Audit logs are append-only: they are never updated and only deleted by the retention task.
+type AuditLogRepository interface {
+	// Create appends an audit log
+	Create(ctx context.Context, log *types.AuditLog) error
+
+	// List retrieves the audit logs of a tenant matching the filter, newest first
+	List(ctx context.Context,
+		tenantID uint64, filter *types.AuditLogFilter, page *types.Pagination,
+	) ([]*types.AuditLog, int64, error)
+
+	// DeleteBefore deletes the audit logs of all tenants created before the given time
+	DeleteBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+// AuditLogService defines the interface for recording and querying audit logs
+type AuditLogService interface {
+	// Record appends an audit log. Errors are logged and never returned,
+	// so the audited request is not affected by audit failures.
+	Record(ctx context.Context, log *types.AuditLog)
+
+	// ListAuditLogs lists the audit logs of the current tenant
+	ListAuditLogs(ctx context.Context,
+		filter *types.AuditLogFilter, page *types.Pagination,
+	) (*types.PageResult, error)
+
+	// ProcessAuditLogCleanup handles the asynq audit log retention task
+	ProcessAuditLogCleanup(ctx context.Context, t *asynq.Task) error
+}
@@ -0,0 +1,21 @@
+package interfaces
+
+import (
+	"context"
+	"io"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// BundleService defines the interface for exporting agents and knowledge bases as bundles
+// and importing bundles into another tenant or environment
+type BundleService interface {
+	// ExportAgent writes a bundle of an agent of the current tenant, the agents it delegates to,
+	// the MCP services, HTTP tools, skills and models they reference, and optionally their knowledge bases
+	ExportAgent(ctx context.Context, agentID string, opts *types.BundleExportOptions, w io.Writer) error
+	// ExportKnowledgeBase writes a bundle of a knowledge base of the current tenant and its content
+	ExportKnowledgeBase(ctx context.Context, kbID string, opts *types.BundleExportOptions, w io.Writer) error
+	// Import imports a bundle into the current tenant. When the bundle has conflicts nothing is imported
+	// and the report is returned together with ErrBundleConflict.
+	Import(ctx context.Context, r io.ReaderAt, size int64, opts *types.BundleImportOptions) (*types.BundleImportReport, error)
+}
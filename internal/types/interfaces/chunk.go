@@ -71,6 +71,12 @@ type ChunkRepository interface {
 	ListAllFAQChunksWithMetadataByKnowledgeBaseID(ctx context.Context, tenantID uint64, kbID string) ([]*types.Chunk, error)
 	// ListAllFAQChunksForExport lists all FAQ chunks for export with full metadata, tag_id, is_enabled, and flags
 	ListAllFAQChunksForExport(ctx context.Context, tenantID uint64, knowledgeID string) ([]*types.Chunk, error)
+	// ListAllChunksForIndexCheck lists all chunks of a knowledge base for index consistency checks
+	// only ID, KnowledgeID, ChunkType, Status, TagID and IsEnabled fields for efficiency
+	ListAllChunksForIndexCheck(ctx context.Context, tenantID uint64, kbID string) ([]*types.Chunk, error)
+	// ListChunksForExport lists a page of the chunks of a knowledge base with all fields, ordered by ID,
+	// starting after afterID; graph and web search chunks are not included
+	ListChunksForExport(ctx context.Context, tenantID uint64, kbID string, afterID string, limit int) ([]*types.Chunk, error)
 	// UpdateChunkFlagsBatch updates flags for multiple chunks in batch using a single SQL statement.
 	// setFlags: map of chunk ID to flags to set (OR operation)
 	// clearFlags: map of chunk ID to flags to clear (AND NOT operation)
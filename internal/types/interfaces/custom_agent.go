@@ -28,6 +28,10 @@ type CustomAgentService interface {
 	//   - Possible errors such as not existing, insufficient permissions, etc.
 	GetAgentByID(ctx context.Context, id string) (*types.CustomAgent, error)
 
+	// GetLiveAgent retrieves an agent by ID as it answers in conversations: its published version,
+	// or its draft when it has never been published
+	GetLiveAgent(ctx context.Context, id string) (*types.CustomAgent, error)
+
 	// GetAgentByIDAndTenant retrieves agent by ID and tenant (for shared agents; skips built-in resolution)
 	GetAgentByIDAndTenant(ctx context.Context, id string, tenantID uint64) (*types.CustomAgent, error)
 
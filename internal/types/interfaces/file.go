@@ -20,4 +20,6 @@ type FileService interface {
 	GetFileURL(ctx context.Context, filePath string) (string, error)
 	// DeleteFile deletes a file.
 	DeleteFile(ctx context.Context, filePath string) error
+	// Ping checks that the storage backend is reachable.
+	Ping(ctx context.Context) error
 }
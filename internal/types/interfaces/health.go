@@ -0,0 +1,14 @@
+package interfaces
+
+import (
+	"context"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// HealthService probes the dependencies of the service
+type HealthService interface {
+	// Ready probes all dependencies and returns the readiness report.
+	// Results are cached for a short time so frequent probes do not hit the dependencies.
+	Ready(ctx context.Context) *types.HealthReport
+}
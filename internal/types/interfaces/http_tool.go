@@ -0,0 +1,60 @@
+package interfaces
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// HTTPToolRepository defines the interface for HTTP tool data access
+type HTTPToolRepository interface {
+	// Create creates a new HTTP tool
+	Create(ctx context.Context, tool *types.HTTPTool) error
+
+	// GetByID retrieves an HTTP tool of a tenant by ID
+	GetByID(ctx context.Context, tenantID uint64, id string) (*types.HTTPTool, error)
+
+	// GetByName retrieves an HTTP tool of a tenant by name
+	GetByName(ctx context.Context, tenantID uint64, name string) (*types.HTTPTool, error)
+
+	// List retrieves all HTTP tools of a tenant
+	List(ctx context.Context, tenantID uint64) ([]*types.HTTPTool, error)
+
+	// ListByIDs retrieves the HTTP tools of a tenant with the given IDs
+	ListByIDs(ctx context.Context, tenantID uint64, ids []string) ([]*types.HTTPTool, error)
+
+	// Update saves an HTTP tool
+	Update(ctx context.Context, tool *types.HTTPTool) error
+
+	// Delete deletes an HTTP tool
+	Delete(ctx context.Context, tenantID uint64, id string) error
+}
+
+// HTTPToolService defines the interface for managing the HTTP tools of custom agents
+type HTTPToolService interface {
+	// CreateTool creates an HTTP tool for the current tenant
+	CreateTool(ctx context.Context, tool *types.HTTPTool) (*types.HTTPTool, error)
+
+	// GetTool returns an HTTP tool of the current tenant
+	GetTool(ctx context.Context, id string) (*types.HTTPTool, error)
+
+	// ListTools lists the HTTP tools of the current tenant
+	ListTools(ctx context.Context) ([]*types.HTTPTool, error)
+
+	// UpdateTool updates an HTTP tool of the current tenant
+	UpdateTool(ctx context.Context, tool *types.HTTPTool) (*types.HTTPTool, error)
+
+	// DeleteTool deletes an HTTP tool of the current tenant
+	DeleteTool(ctx context.Context, id string) error
+
+	// ImportOpenAPI creates an HTTP tool for each operation of an OpenAPI 3 document.
+	// Tools with the same name are updated, so a document can be imported again after it changes.
+	ImportOpenAPI(ctx context.Context, req *types.HTTPToolImportRequest) ([]*types.HTTPTool, error)
+
+	// TestTool calls an HTTP tool of the current tenant with the given arguments
+	TestTool(ctx context.Context, id string, args json.RawMessage) (*types.ToolResult, error)
+
+	// GetAgentTools returns the enabled HTTP tools among ids as agent tools
+	GetAgentTools(ctx context.Context, tenantID uint64, ids []string) ([]types.Tool, error)
+}
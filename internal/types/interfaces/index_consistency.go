@@ -0,0 +1,47 @@
+package interfaces
+
+import (
+	"context"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/hibiken/asynq"
+)
+
+// IndexConsistencyRepository defines the interface for index consistency report data access
+type IndexConsistencyRepository interface {
+	// Create creates a new report
+	Create(ctx context.Context, report *types.IndexConsistencyReport) error
+
+	// GetByID retrieves a report of a tenant by ID
+	GetByID(ctx context.Context, tenantID uint64, id string) (*types.IndexConsistencyReport, error)
+
+	// ListByKnowledgeBase retrieves the latest reports of a knowledge base, newest first
+	ListByKnowledgeBase(ctx context.Context,
+		tenantID uint64, kbID string, limit int,
+	) ([]*types.IndexConsistencyReport, error)
+
+	// GetActive retrieves the pending or running check of a knowledge base, if any
+	GetActive(ctx context.Context, tenantID uint64, kbID string) (*types.IndexConsistencyReport, error)
+
+	// Update saves the status and result of a report
+	Update(ctx context.Context, report *types.IndexConsistencyReport) error
+}
+
+// IndexConsistencyService defines the interface for checking and repairing drift
+// between the chunks table and the retriever engines
+type IndexConsistencyService interface {
+	// StartCheck enqueues a consistency check of a knowledge base of the current tenant
+	StartCheck(ctx context.Context, kbID string, repair bool) (*types.IndexConsistencyReport, error)
+
+	// GetReport returns a report of a knowledge base of the current tenant
+	GetReport(ctx context.Context, kbID string, id string) (*types.IndexConsistencyReport, error)
+
+	// ListReports lists the latest reports of a knowledge base of the current tenant
+	ListReports(ctx context.Context, kbID string) ([]*types.IndexConsistencyReport, error)
+
+	// ProcessIndexCheck handles the asynq index consistency check task
+	ProcessIndexCheck(ctx context.Context, t *asynq.Task) error
+
+	// ProcessIndexCheckScan handles the periodic task that checks all knowledge bases
+	ProcessIndexCheckScan(ctx context.Context, t *asynq.Task) error
+}
@@ -0,0 +1,46 @@
+package interfaces
+
+import (
+	"context"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/hibiken/asynq"
+)
+
+// IndexMigrationRepository defines the interface for index migration data access
+type IndexMigrationRepository interface {
+	// Create creates a new migration
+	Create(ctx context.Context, migration *types.IndexMigration) error
+
+	// GetByID retrieves a migration of a tenant by ID
+	GetByID(ctx context.Context, tenantID uint64, id string) (*types.IndexMigration, error)
+
+	// List retrieves the migrations of a tenant, newest first
+	List(ctx context.Context, tenantID uint64) ([]*types.IndexMigration, error)
+
+	// GetActive retrieves the pending or running migration of a tenant, if any
+	GetActive(ctx context.Context, tenantID uint64) (*types.IndexMigration, error)
+
+	// Update saves the status and progress of a migration
+	Update(ctx context.Context, migration *types.IndexMigration) error
+}
+
+// IndexMigrationService defines the interface for moving indices between retriever engines
+type IndexMigrationService interface {
+	// StartMigration validates the engines, snapshots the tenant's knowledge bases and enqueues the migration task
+	StartMigration(ctx context.Context,
+		sourceEngine types.RetrieverEngineType, targetEngine types.RetrieverEngineType,
+	) (*types.IndexMigration, error)
+
+	// GetMigration returns the progress of a migration of the current tenant
+	GetMigration(ctx context.Context, id string) (*types.IndexMigration, error)
+
+	// ListMigrations lists the migrations of the current tenant
+	ListMigrations(ctx context.Context) ([]*types.IndexMigration, error)
+
+	// ResumeMigration re-enqueues a failed migration, which continues from its saved cursors
+	ResumeMigration(ctx context.Context, id string) (*types.IndexMigration, error)
+
+	// ProcessIndexMigration handles the asynq index migration task
+	ProcessIndexMigration(ctx context.Context, t *asynq.Task) error
+}
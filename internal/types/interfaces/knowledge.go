@@ -123,6 +123,9 @@ type KnowledgeService interface {
 	// UpdateFAQEntryTagBatch updates tag for FAQ entries in batch.
 	// Key: entry seq_id, Value: tag seq_id (nil to remove tag)
 	UpdateFAQEntryTagBatch(ctx context.Context, kbID string, updates map[int64]*int64) error
+	// ReindexChunks rebuilds the index entries of chunks of a knowledge base in all engines of the tenant,
+	// including FAQ questions and generated questions, keeping their enabled status and tag.
+	ReindexChunks(ctx context.Context, kb *types.KnowledgeBase, chunks []*types.Chunk) error
 	// GetRepository gets the knowledge repository
 	GetRepository() KnowledgeRepository
 	// ProcessDocument handles Asynq document processing tasks
@@ -30,6 +30,21 @@ type MCPServiceRepository interface {
 	Delete(ctx context.Context, tenantID uint64, id string) error
 }
 
+// MCPOAuthCredentialRepository defines the interface for MCP OAuth credential data access
+type MCPOAuthCredentialRepository interface {
+	// GetByServiceID retrieves the OAuth credential of an MCP service
+	GetByServiceID(ctx context.Context, tenantID uint64, serviceID string) (*types.MCPOAuthCredential, error)
+
+	// GetByPendingState retrieves the credential with an in-flight authorization request
+	GetByPendingState(ctx context.Context, state string) (*types.MCPOAuthCredential, error)
+
+	// Save creates or updates an OAuth credential
+	Save(ctx context.Context, credential *types.MCPOAuthCredential) error
+
+	// Delete deletes the OAuth credential of an MCP service
+	Delete(ctx context.Context, tenantID uint64, serviceID string) error
+}
+
 // MCPServiceService defines the interface for MCP service business logic
 type MCPServiceService interface {
 	// CreateMCPService creates a new MCP service
@@ -58,4 +73,33 @@ type MCPServiceService interface {
 
 	// GetMCPServiceResources retrieves the list of resources from an MCP service
 	GetMCPServiceResources(ctx context.Context, tenantID uint64, id string) ([]*types.MCPResource, error)
+
+	// GetMCPServicePrompts retrieves the list of prompts from an MCP service
+	GetMCPServicePrompts(ctx context.Context, tenantID uint64, id string) ([]*types.MCPPrompt, error)
+
+	// GetMCPServicePrompt renders a prompt of an MCP service with the given arguments
+	GetMCPServicePrompt(
+		ctx context.Context,
+		tenantID uint64,
+		id string,
+		name string,
+		args map[string]string,
+	) (*types.MCPPromptResult, error)
+
+	// StartMCPServiceOAuth starts the OAuth authorization flow and returns the authorization URL
+	StartMCPServiceOAuth(
+		ctx context.Context,
+		tenantID uint64,
+		id string,
+		redirectURI string,
+	) (*types.MCPOAuthAuthorization, error)
+
+	// CompleteMCPServiceOAuth exchanges the authorization code of a callback for tokens
+	CompleteMCPServiceOAuth(ctx context.Context, state string, code string) (*types.MCPService, error)
+
+	// GetMCPServiceOAuthStatus returns the OAuth authorization status of an MCP service
+	GetMCPServiceOAuthStatus(ctx context.Context, tenantID uint64, id string) (*types.MCPOAuthStatus, error)
+
+	// RevokeMCPServiceOAuth removes the stored OAuth tokens of an MCP service
+	RevokeMCPServiceOAuth(ctx context.Context, tenantID uint64, id string) error
 }
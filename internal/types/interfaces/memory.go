@@ -0,0 +1,67 @@
+package interfaces
+
+import (
+	"context"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// UserMemoryRepository defines the interface for user memory data access
+type UserMemoryRepository interface {
+	// Create creates a new memory
+	Create(ctx context.Context, memory *types.UserMemory) error
+
+	// GetByID retrieves a memory of a user by ID
+	GetByID(ctx context.Context, tenantID uint64, userID string, id string) (*types.UserMemory, error)
+
+	// GetByContent retrieves a memory of a user with exactly the given content
+	GetByContent(ctx context.Context, tenantID uint64, userID string, content string) (*types.UserMemory, error)
+
+	// List retrieves the memories of a user, newest first
+	List(ctx context.Context,
+		tenantID uint64, userID string, page *types.Pagination,
+	) ([]*types.UserMemory, int64, error)
+
+	// ListAll retrieves all memories of a user, newest first, for recall
+	ListAll(ctx context.Context, tenantID uint64, userID string) ([]*types.UserMemory, error)
+
+	// Count counts the memories of a user
+	Count(ctx context.Context, tenantID uint64, userID string) (int64, error)
+
+	// Touch refreshes the update time of a memory
+	Touch(ctx context.Context, memory *types.UserMemory) error
+
+	// Delete deletes a memory of a user
+	Delete(ctx context.Context, tenantID uint64, userID string, id string) error
+
+	// DeleteAll deletes all memories of a user
+	DeleteAll(ctx context.Context, tenantID uint64, userID string) (int64, error)
+}
+
+// UserMemoryService defines the interface for the cross-session long-term memory of users.
+// All methods operate on the user in the context and fail with ErrMemoryNoUser without one.
+type UserMemoryService interface {
+	// SaveMemory saves a memory of the current user.
+	// The content is embedded with embeddingModelID when set, saving the same content again refreshes it.
+	SaveMemory(ctx context.Context,
+		content string, agentID string, sessionID string, embeddingModelID string,
+	) (*types.UserMemory, error)
+
+	// RecallMemories returns the memories of the current user most relevant to the query
+	RecallMemories(ctx context.Context,
+		query string, embeddingModelID string, limit int,
+	) ([]*types.UserMemoryRecall, error)
+
+	// ListMemories lists the memories of the current user, newest first
+	ListMemories(ctx context.Context, page *types.Pagination) (*types.PageResult, error)
+
+	// DeleteMemory deletes a memory of the current user
+	DeleteMemory(ctx context.Context, id string) error
+
+	// ClearMemories deletes all memories of the current user
+	ClearMemories(ctx context.Context) (int64, error)
+
+	// BuildMemoryContext renders the memories relevant to the query, filled up with the most recent ones,
+	// as a system prompt section. It returns an empty string when the user has no memories
+	BuildMemoryContext(ctx context.Context, query string, embeddingModelID string) string
+}
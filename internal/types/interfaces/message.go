@@ -31,11 +31,63 @@ type MessageService interface {
 
 	// DeleteMessage deletes a message
 	DeleteMessage(ctx context.Context, sessionID string, id string) error
+
+	// CreateBranchMessage creates a message as a child of parentID (empty for a new first message)
+	// and makes it the active branch of the session, used to edit a user message or regenerate an answer
+	CreateBranchMessage(ctx context.Context, message *types.Message, parentID string) (*types.Message, error)
+
+	// ListBranches lists the branches of a session
+	ListBranches(ctx context.Context, sessionID string) ([]*types.MessageBranch, error)
+
+	// SwitchBranch makes the most recent branch containing the message active and returns its messages
+	SwitchBranch(ctx context.Context, sessionID string, messageID string) ([]*types.Message, error)
+
+	// SearchMessages searches the conversation history of the current tenant
+	SearchMessages(
+		ctx context.Context, filter *types.MessageSearchFilter, page *types.Pagination,
+	) (*types.MessageSearchResult, error)
 }
 
 // MessageRepository defines the message repository interface
 type MessageRepository interface {
-	MessageService
+	// CreateMessage creates a message
+	CreateMessage(ctx context.Context, message *types.Message) (*types.Message, error)
+
+	// GetMessage gets a message
+	GetMessage(ctx context.Context, sessionID string, id string) (*types.Message, error)
+
+	// GetMessagesBySession gets all messages of a session
+	GetMessagesBySession(ctx context.Context, sessionID string, page int, pageSize int) ([]*types.Message, error)
+
+	// GetRecentMessagesBySession gets recent messages of a session
+	GetRecentMessagesBySession(ctx context.Context, sessionID string, limit int) ([]*types.Message, error)
+
+	// GetMessagesBySessionBeforeTime gets messages before a specific time of a session
+	GetMessagesBySessionBeforeTime(
+		ctx context.Context, sessionID string, beforeTime time.Time, limit int,
+	) ([]*types.Message, error)
+
+	// UpdateMessage updates a message
+	UpdateMessage(ctx context.Context, message *types.Message) error
+
+	// DeleteMessage deletes a message
+	DeleteMessage(ctx context.Context, sessionID string, id string) error
+
 	// GetFirstMessageOfUser gets the first message of a user
 	GetFirstMessageOfUser(ctx context.Context, sessionID string) (*types.Message, error)
+
+	// GetAllMessagesBySession gets all messages of a session across branches, in creation order
+	GetAllMessagesBySession(ctx context.Context, sessionID string) ([]*types.Message, error)
+
+	// ReparentMessages moves the children of a message to another parent
+	ReparentMessages(ctx context.Context, sessionID string, fromParentID string, toParentID string) error
+
+	// SearchMessages finds the user and assistant messages of a tenant containing all terms, newest first
+	SearchMessages(
+		ctx context.Context,
+		tenantID uint64,
+		terms []string,
+		filter *types.MessageSearchFilter,
+		page *types.Pagination,
+	) ([]*types.MessageSearchHit, int64, error)
 }
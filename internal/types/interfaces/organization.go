@@ -159,6 +159,9 @@ type AgentShareService interface {
 	GetShareByAgentIDForUser(ctx context.Context, userID, agentID string, excludeTenantID uint64) (*types.AgentShare, error)
 	// CountByOrganizations returns share counts per organization (for sidebar); excludes deleted agents
 	CountByOrganizations(ctx context.Context, orgIDs []string) (map[string]int64, error)
+	// PinShareVersion pins the version of the agent used by members of the share's organization, 0 follows the published version.
+	// Only the agent's tenant and admins of the organization can pin.
+	PinShareVersion(ctx context.Context, agentID string, shareID string, userID string, tenantID uint64, version int) (*types.AgentShare, error)
 }
 
 // AgentShareRepository defines the agent sharing repository interface
@@ -167,6 +170,7 @@ type AgentShareRepository interface {
 	GetByID(ctx context.Context, id string) (*types.AgentShare, error)
 	GetByAgentAndOrg(ctx context.Context, agentID string, orgID string) (*types.AgentShare, error)
 	Update(ctx context.Context, share *types.AgentShare) error
+	UpdatePinnedVersion(ctx context.Context, id string, version int) error
 	Delete(ctx context.Context, id string) error
 	DeleteByAgentIDAndSourceTenant(ctx context.Context, agentID string, sourceTenantID uint64) error
 	DeleteByOrganizationID(ctx context.Context, orgID string) error
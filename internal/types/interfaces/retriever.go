@@ -60,6 +60,16 @@ type RetrieveEngineRepository interface {
 	// chunkTagMap: map of chunk ID to tag ID (empty string means no tag)
 	BatchUpdateChunkTagID(ctx context.Context, chunkTagMap map[string]string) error
 
+	// ListIndices lists the index entries of a knowledge base with their embeddings, page by page.
+	// cursor is empty for the first page; the returned cursor is empty after the last page.
+	// dimension selects the collection for engines that store each dimension separately.
+	ListIndices(ctx context.Context,
+		knowledgeBaseID string, dimension int, knowledgeType string, cursor string, limit int,
+	) ([]*types.IndexEntry, string, error)
+
+	// Ping checks that the engine backend is reachable
+	Ping(ctx context.Context) error
+
 	// RetrieveEngine retrieves the engine
 	RetrieveEngine
 }
@@ -127,6 +137,19 @@ type RetrieveEngineService interface {
 	// chunkTagMap: map of chunk ID to tag ID (empty string means no tag)
 	BatchUpdateChunkTagID(ctx context.Context, chunkTagMap map[string]string) error
 
+	// ListIndices lists the index entries of a knowledge base with their embeddings, page by page.
+	// cursor is empty for the first page; the returned cursor is empty after the last page.
+	// dimension selects the collection for engines that store each dimension separately.
+	ListIndices(ctx context.Context,
+		knowledgeBaseID string, dimension int, knowledgeType string, cursor string, limit int,
+	) ([]*types.IndexEntry, string, error)
+
+	// SaveIndices saves index entries that already carry their embeddings, without calling an embedder
+	SaveIndices(ctx context.Context, entries []*types.IndexEntry) error
+
+	// Ping checks that the engine backend is reachable
+	Ping(ctx context.Context) error
+
 	// RetrieveEngine retrieves the engine
 	RetrieveEngine
 }
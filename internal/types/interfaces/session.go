@@ -34,11 +34,12 @@ type SessionService interface {
 	// summaryModelID: optional summary model ID override (if empty, uses session/KB default)
 	// webSearchEnabled: whether to enable web search to supplement knowledge base results
 	// customAgent: optional custom agent for config override (multiTurnEnabled, historyTurns)
-	// Events are emitted through eventBus (references, answer chunks, completion)
+	// outputSchema: optional JSON Schema the answer must conform to (if empty, uses the custom agent's)
+	// Events are emitted through eventBus (references, answer chunks, structured output, completion)
 	KnowledgeQA(ctx context.Context,
 		session *types.Session, query string, knowledgeBaseIDs []string, knowledgeIDs []string,
 		assistantMessageID string, summaryModelID string, webSearchEnabled bool, eventBus *event.EventBus,
-		customAgent *types.CustomAgent,
+		customAgent *types.CustomAgent, outputSchema types.OutputSchema,
 	) error
 	// KnowledgeQAByEvent performs knowledge-based question answering by event
 	KnowledgeQAByEvent(ctx context.Context, chatManage *types.ChatManage, eventList []types.EventType) error
@@ -50,6 +51,7 @@ type SessionService interface {
 	// eventBus is optional - if nil, uses service's default EventBus
 	// customAgent is optional - if provided, uses custom agent configuration instead of tenant defaults
 	// summaryModelID is optional - if provided, overrides the model from customAgent config
+	// outputSchema is optional - if provided, overrides the output schema from customAgent config
 	AgentQA(
 		ctx context.Context,
 		session *types.Session,
@@ -60,7 +62,20 @@ type SessionService interface {
 		customAgent *types.CustomAgent,
 		knowledgeBaseIDs []string,
 		knowledgeIDs []string,
+		outputSchema types.OutputSchema,
 	) error
+	// RunAgent runs a smart-reasoning custom agent for query without conversation history and returns
+	// the final state of the run. Events are emitted through eventBus, the answer is not saved.
+	RunAgent(
+		ctx context.Context,
+		session *types.Session,
+		query string,
+		assistantMessageID string,
+		eventBus *event.EventBus,
+		customAgent *types.CustomAgent,
+		knowledgeBaseIDs []string,
+		knowledgeIDs []string,
+	) (*types.AgentState, error)
 	// ClearContext clears the LLM context for a session
 	ClearContext(ctx context.Context, sessionID string) error
 }
@@ -77,6 +92,12 @@ type SessionRepository interface {
 	GetPagedByTenantID(ctx context.Context, tenantID uint64, page *types.Pagination) ([]*types.Session, int64, error)
 	// Update updates a session
 	Update(ctx context.Context, session *types.Session) error
+	// UpdateActiveMessage sets the last message of the active conversation branch
+	UpdateActiveMessage(ctx context.Context, tenantID uint64, id string, messageID string) error
+	// SearchByTitle finds the sessions of a tenant whose title contains all terms
+	SearchByTitle(
+		ctx context.Context, tenantID uint64, terms []string, filter *types.MessageSearchFilter, limit int,
+	) ([]*types.Session, error)
 	// Delete deletes a session
 	Delete(ctx context.Context, tenantID uint64, id string) error
 }
@@ -0,0 +1,38 @@
+package interfaces
+
+import (
+	"context"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// ToolApprovalRepository defines the interface for tool approval data access
+type ToolApprovalRepository interface {
+	// Create creates a new approval request
+	Create(ctx context.Context, approval *types.ToolApproval) error
+
+	// GetByID retrieves an approval request of a session by ID
+	GetByID(ctx context.Context, sessionID string, id string) (*types.ToolApproval, error)
+
+	// ListBySession retrieves the approval requests of a session, optionally filtered by status
+	ListBySession(ctx context.Context, sessionID string, status string) ([]*types.ToolApproval, error)
+
+	// Resolve moves a pending approval request to a final status.
+	// Returns false if the request was already resolved.
+	Resolve(ctx context.Context, approval *types.ToolApproval) (bool, error)
+}
+
+// ToolApprovalService defines the interface for human-in-the-loop tool approval
+type ToolApprovalService interface {
+	// RequestApproval persists a pending approval request for a tool call
+	RequestApproval(ctx context.Context, approval *types.ToolApproval) error
+
+	// WaitForDecision blocks until the request is approved, denied or expired (or ctx is done)
+	WaitForDecision(ctx context.Context, approval *types.ToolApproval) (*types.ToolApproval, error)
+
+	// Decide approves or denies a pending approval request of a session
+	Decide(ctx context.Context, sessionID string, id string, approved bool, reason string) (*types.ToolApproval, error)
+
+	// ListPending lists the pending approval requests of a session
+	ListPending(ctx context.Context, sessionID string) ([]*types.ToolApproval, error)
+}
@@ -0,0 +1,81 @@
+package interfaces
+
+import (
+	"context"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/hibiken/asynq"
+)
+
+// WebhookRepository defines the interface for webhook and delivery data access
+type WebhookRepository interface {
+	// Create creates a new webhook
+	Create(ctx context.Context, webhook *types.Webhook) error
+
+	// GetByID retrieves a webhook of a tenant by ID
+	GetByID(ctx context.Context, tenantID uint64, id string) (*types.Webhook, error)
+
+	// List retrieves all webhooks of a tenant
+	List(ctx context.Context, tenantID uint64) ([]*types.Webhook, error)
+
+	// ListEnabled retrieves the enabled webhooks of a tenant
+	ListEnabled(ctx context.Context, tenantID uint64) ([]*types.Webhook, error)
+
+	// Update saves a webhook
+	Update(ctx context.Context, webhook *types.Webhook) error
+
+	// Delete deletes a webhook and its deliveries
+	Delete(ctx context.Context, tenantID uint64, id string) error
+
+	// CreateDelivery creates a new delivery record
+	CreateDelivery(ctx context.Context, delivery *types.WebhookDelivery) error
+
+	// GetDelivery retrieves a delivery of a tenant by ID
+	GetDelivery(ctx context.Context, tenantID uint64, id string) (*types.WebhookDelivery, error)
+
+	// ListDeliveries retrieves the deliveries of a webhook, newest first
+	ListDeliveries(ctx context.Context,
+		tenantID uint64, webhookID string, page *types.Pagination,
+	) ([]*types.WebhookDelivery, int64, error)
+
+	// UpdateDelivery saves the result of a delivery attempt
+	UpdateDelivery(ctx context.Context, delivery *types.WebhookDelivery) error
+}
+
+// WebhookService defines the interface for managing webhooks and delivering events
+type WebhookService interface {
+	// CreateWebhook creates a webhook for the current tenant.
+	// A signing secret is generated when none is given; it is only returned here.
+	CreateWebhook(ctx context.Context, webhook *types.Webhook) (*types.Webhook, string, error)
+
+	// GetWebhook returns a webhook of the current tenant
+	GetWebhook(ctx context.Context, id string) (*types.Webhook, error)
+
+	// ListWebhooks lists the webhooks of the current tenant
+	ListWebhooks(ctx context.Context) ([]*types.Webhook, error)
+
+	// UpdateWebhook updates a webhook of the current tenant. The secret is rotated when set.
+	UpdateWebhook(ctx context.Context, webhook *types.Webhook) (*types.Webhook, error)
+
+	// DeleteWebhook deletes a webhook of the current tenant
+	DeleteWebhook(ctx context.Context, id string) error
+
+	// TestWebhook sends a test event synchronously and returns the recorded delivery
+	TestWebhook(ctx context.Context, id string) (*types.WebhookDelivery, error)
+
+	// ListDeliveries lists the delivery log of a webhook of the current tenant
+	ListDeliveries(ctx context.Context,
+		webhookID string, page *types.Pagination,
+	) (*types.PageResult, error)
+
+	// Publish delivers an event to the enabled webhooks of a tenant subscribed to it.
+	// Errors are logged and never returned, so callers are not affected by webhook failures.
+	Publish(ctx context.Context, tenantID uint64, event string, data interface{})
+
+	// PublishTo publishes the event like Publish, and also delivers it to the webhook webhookID
+	// of the tenant even if that webhook does not subscribe to the event
+	PublishTo(ctx context.Context, tenantID uint64, webhookID string, event string, data interface{})
+
+	// ProcessWebhookDelivery handles the asynq webhook delivery task
+	ProcessWebhookDelivery(ctx context.Context, t *asynq.Task) error
+}
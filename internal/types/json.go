@@ -44,7 +44,8 @@ func (j *JSON) UnmarshalJSON(data []byte) error {
 	if j == nil {
 		return errors.New("JSON: UnmarshalJSON on nil pointer")
 	}
-	*j = JSON(data)
+	// data may be reused by the decoder (e.g. a json.Decoder reading a stream), keep a copy
+	*j = append((*j)[0:0], data...)
 	return nil
 }
 
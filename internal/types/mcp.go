@@ -3,6 +3,7 @@ package types
 import (
 	"database/sql/driver"
 	"encoding/json"
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
@@ -45,6 +46,23 @@ type MCPAuthConfig struct {
 	APIKey        string            `json:"api_key,omitempty"`
 	Token         string            `json:"token,omitempty"`
 	CustomHeaders map[string]string `json:"custom_headers,omitempty"`
+	OAuth         *MCPOAuthConfig   `json:"oauth,omitempty"` // OAuth 2.0 authorization (MCP authorization spec)
+}
+
+// MCPOAuthConfig represents OAuth 2.0 configuration for a remote MCP service.
+// Client credentials are optional: when ClientID is empty the client is registered dynamically.
+// Tokens obtained through the authorization flow are stored encrypted in MCPOAuthCredential.
+type MCPOAuthConfig struct {
+	Enabled               bool     `json:"enabled"`
+	ClientID              string   `json:"client_id,omitempty"`
+	ClientSecret          string   `json:"client_secret,omitempty"`
+	Scopes                []string `json:"scopes,omitempty"`
+	AuthServerMetadataURL string   `json:"auth_server_metadata_url,omitempty"` // Optional, discovered when empty
+}
+
+// UsesOAuth returns true if the service authenticates with OAuth 2.0
+func (m *MCPService) UsesOAuth() bool {
+	return m.AuthConfig != nil && m.AuthConfig.OAuth != nil && m.AuthConfig.OAuth.Enabled
 }
 
 // MCPAdvancedConfig represents advanced configuration for MCP service
@@ -78,12 +96,39 @@ type MCPResource struct {
 	MimeType    string `json:"mimeType,omitempty"`
 }
 
+// MCPPrompt represents a prompt template exposed by an MCP service
+type MCPPrompt struct {
+	Name        string              `json:"name"`
+	Description string              `json:"description,omitempty"`
+	Arguments   []MCPPromptArgument `json:"arguments,omitempty"`
+}
+
+// MCPPromptArgument describes an argument accepted by an MCP prompt
+type MCPPromptArgument struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+// MCPPromptResult represents a prompt rendered by an MCP service
+type MCPPromptResult struct {
+	Description string             `json:"description,omitempty"`
+	Messages    []MCPPromptMessage `json:"messages"`
+}
+
+// MCPPromptMessage represents a single rendered prompt message
+type MCPPromptMessage struct {
+	Role    string `json:"role"` // "user" or "assistant"
+	Content string `json:"content"`
+}
+
 // MCPTestResult represents the result of testing an MCP service connection
 type MCPTestResult struct {
 	Success   bool           `json:"success"`
 	Message   string         `json:"message,omitempty"`
 	Tools     []*MCPTool     `json:"tools,omitempty"`
 	Resources []*MCPResource `json:"resources,omitempty"`
+	Prompts   []*MCPPrompt   `json:"prompts,omitempty"`
 }
 
 // BeforeCreate is a GORM hook that runs before creating a new MCP service
@@ -214,7 +259,48 @@ func (m *MCPService) MaskSensitiveData() {
 		if m.AuthConfig.Token != "" {
 			m.AuthConfig.Token = maskString(m.AuthConfig.Token)
 		}
+		if m.AuthConfig.OAuth != nil && m.AuthConfig.OAuth.ClientSecret != "" {
+			oauth := *m.AuthConfig.OAuth
+			oauth.ClientSecret = maskString(oauth.ClientSecret)
+			m.AuthConfig.OAuth = &oauth
+		}
+	}
+}
+
+// RemoveSecrets clears the credentials, header values and environment variable values of the service,
+// keeping their names, and returns the names of the cleared fields
+func (m *MCPService) RemoveSecrets() []string {
+	var removed []string
+	if m.AuthConfig != nil {
+		if m.AuthConfig.APIKey != "" {
+			m.AuthConfig.APIKey = ""
+			removed = append(removed, "auth_config.api_key")
+		}
+		if m.AuthConfig.Token != "" {
+			m.AuthConfig.Token = ""
+			removed = append(removed, "auth_config.token")
+		}
+		for name := range m.AuthConfig.CustomHeaders {
+			m.AuthConfig.CustomHeaders[name] = ""
+			removed = append(removed, "auth_config.custom_headers."+name)
+		}
+		if m.AuthConfig.OAuth != nil && m.AuthConfig.OAuth.ClientSecret != "" {
+			oauth := *m.AuthConfig.OAuth
+			oauth.ClientSecret = ""
+			m.AuthConfig.OAuth = &oauth
+			removed = append(removed, "auth_config.oauth.client_secret")
+		}
+	}
+	for name := range m.Headers {
+		m.Headers[name] = ""
+		removed = append(removed, "headers."+name)
+	}
+	for name := range m.EnvVars {
+		m.EnvVars[name] = ""
+		removed = append(removed, "env_vars."+name)
 	}
+	slices.Sort(removed)
+	return removed
 }
 
 // maskString masks a string, showing only first 4 and last 4 characters
@@ -0,0 +1,86 @@
+package types
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/utils"
+)
+
+// MCPOAuthCredential stores the OAuth client registration and tokens of an MCP service.
+// Secrets are encrypted at rest with EncryptedString.
+type MCPOAuthCredential struct {
+	ServiceID    string          `json:"service_id"    gorm:"type:varchar(36);primaryKey"`
+	TenantID     uint64          `json:"tenant_id"     gorm:"index"`
+	ClientID     string          `json:"client_id"     gorm:"type:varchar(512)"`
+	ClientSecret EncryptedString `json:"-"             gorm:"type:text"`
+	RedirectURI  string          `json:"redirect_uri"  gorm:"type:varchar(1024)"`
+	AccessToken  EncryptedString `json:"-"             gorm:"type:text"`
+	RefreshToken EncryptedString `json:"-"             gorm:"type:text"`
+	TokenType    string          `json:"token_type"    gorm:"type:varchar(50)"`
+	Scope        string          `json:"scope"         gorm:"type:text"`
+	ExpiresAt    *time.Time      `json:"expires_at"`
+	// Pending authorization request (PKCE), cleared once the callback completes
+	PendingState     string          `json:"-" gorm:"type:varchar(128);index"`
+	CodeVerifier     EncryptedString `json:"-" gorm:"type:text"`
+	PendingExpiresAt *time.Time      `json:"-"`
+	CreatedAt        time.Time       `json:"created_at"`
+	UpdatedAt        time.Time       `json:"updated_at"`
+}
+
+// TableName returns the table name for MCPOAuthCredential
+func (MCPOAuthCredential) TableName() string {
+	return "mcp_oauth_credentials"
+}
+
+// MCPOAuthStatus describes the OAuth authorization state of an MCP service
+type MCPOAuthStatus struct {
+	Enabled     bool       `json:"enabled"`
+	Authorized  bool       `json:"authorized"`
+	ClientID    string     `json:"client_id,omitempty"`
+	Scope       string     `json:"scope,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	Refreshable bool       `json:"refreshable"`
+}
+
+// MCPOAuthAuthorization is returned when an OAuth authorization flow is started
+type MCPOAuthAuthorization struct {
+	AuthorizationURL string    `json:"authorization_url"`
+	State            string    `json:"state"`
+	ExpiresAt        time.Time `json:"expires_at"`
+}
+
+// EncryptedString is a string column encrypted with AES-GCM keyed by TENANT_AES_KEY
+type EncryptedString string
+
+// Value implements driver.Valuer interface for EncryptedString
+func (s EncryptedString) Value() (driver.Value, error) {
+	if s == "" {
+		return "", nil
+	}
+	return utils.EncryptSecret(string(s))
+}
+
+// Scan implements sql.Scanner interface for EncryptedString
+func (s *EncryptedString) Scan(value interface{}) error {
+	if value == nil {
+		*s = ""
+		return nil
+	}
+	var encoded string
+	switch v := value.(type) {
+	case string:
+		encoded = v
+	case []byte:
+		encoded = string(v)
+	default:
+		return fmt.Errorf("unsupported type for EncryptedString: %T", value)
+	}
+	plaintext, err := utils.DecryptSecret(encoded)
+	if err != nil {
+		return err
+	}
+	*s = EncryptedString(plaintext)
+	return nil
+}
@@ -0,0 +1,64 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+)
+
+// MemoryEmbedding is the embedding vector of a memory, stored as a JSON array
+type MemoryEmbedding []float32
+
+// Value implements the driver.Valuer interface, used to convert MemoryEmbedding to database value
+func (e MemoryEmbedding) Value() (driver.Value, error) {
+	if e == nil {
+		return nil, nil
+	}
+	return json.Marshal(e)
+}
+
+// Scan implements the sql.Scanner interface, used to convert database value to MemoryEmbedding
+func (e *MemoryEmbedding) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	var b []byte
+	switch v := value.(type) {
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return nil
+	}
+	return json.Unmarshal(b, e)
+}
+
+// UserMemory is a fact or preference about a user that agents remember across sessions.
+// Memories belong to a user and are shared by all agents that have memory enabled.
+type UserMemory struct {
+	ID       string `json:"id"        gorm:"type:varchar(36);primaryKey"`
+	TenantID uint64 `json:"tenant_id" gorm:"index"`
+	UserID   string `json:"user_id"   gorm:"type:varchar(36);index"`
+	// Agent that saved the memory
+	AgentID string `json:"agent_id" gorm:"type:varchar(36)"`
+	// Session in which the memory was saved
+	SessionID string `json:"session_id" gorm:"type:varchar(36)"`
+	Content   string `json:"content"    gorm:"type:text"`
+	// Embedding for semantic recall, empty when the agent has no memory embedding model
+	Embedding        MemoryEmbedding `json:"-"                  gorm:"type:json"`
+	EmbeddingModelID string          `json:"embedding_model_id" gorm:"type:varchar(64)"`
+	CreatedAt        time.Time       `json:"created_at"`
+	UpdatedAt        time.Time       `json:"updated_at"`
+}
+
+// TableName returns the table name for UserMemory
+func (UserMemory) TableName() string {
+	return "user_memories"
+}
+
+// UserMemoryRecall is a memory recalled for a query with its relevance score
+type UserMemoryRecall struct {
+	Memory *UserMemory `json:"memory"`
+	Score  float64     `json:"score"`
+}
@@ -61,12 +61,19 @@ type Message struct {
 	ID string `json:"id"                    gorm:"type:varchar(36);primaryKey"`
 	// ID of the session this message belongs to
 	SessionID string `json:"session_id"`
+	// ID of the previous message in the conversation tree, empty for the first message.
+	// Editing a user message or regenerating an answer adds a sibling under the same parent.
+	ParentID string `json:"parent_id"             gorm:"type:varchar(36)"`
 	// Request identifier for tracking API requests
 	RequestID string `json:"request_id"`
 	// Message text content
 	Content string `json:"content"`
 	// Message role: "user", "assistant", "system"
 	Role string `json:"role"`
+	// ID of the custom agent the question was asked to, empty when no agent was selected
+	AgentID string `json:"agent_id,omitempty"    gorm:"type:varchar(36)"`
+	// Published version of the agent that answered, 0 when its draft answered
+	AgentVersion int `json:"agent_version,omitempty" gorm:"default:0"`
 	// References to knowledge chunks used in the response
 	KnowledgeReferences References `json:"knowledge_references"  gorm:"type:json,column:knowledge_references"`
 	// Agent execution steps (only for assistant messages generated by agent)
@@ -84,6 +91,74 @@ type Message struct {
 	UpdatedAt time.Time `json:"updated_at"`
 	// Soft delete timestamp
 	DeletedAt gorm.DeletedAt `json:"deleted_at"            gorm:"index"`
+	// IDs of all versions of this message (siblings under the same parent, including itself) in creation order,
+	// only set when the message has been edited or regenerated
+	SiblingIDs []string `json:"sibling_ids,omitempty" gorm:"-"`
+}
+
+// MessageBranch is a branch of a conversation, identified by its last message
+type MessageBranch struct {
+	// ID of the last message of the branch
+	LeafID string `json:"leaf_id"`
+	// ID of the first message that differs from the other branches, empty for a conversation without forks
+	ForkMessageID string `json:"fork_message_id"`
+	// Content of the last user message of the branch
+	LastQuery string `json:"last_query"`
+	// Number of messages on the branch
+	MessageCount int `json:"message_count"`
+	// Whether this is the active branch of the session
+	IsActive  bool      `json:"is_active"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// MessageSearchFilter is a search over the conversation history of a tenant
+type MessageSearchFilter struct {
+	// Search text, whitespace separated terms must all appear
+	Query string `form:"q"                 binding:"required"`
+	// Only messages asked to this custom agent
+	AgentID string `form:"agent_id"`
+	// Only messages citing a document of this knowledge base or @mentioning it
+	KnowledgeBaseID string     `form:"knowledge_base_id"`
+	StartTime       *time.Time `form:"start_time"        time_format:"2006-01-02T15:04:05Z07:00"`
+	EndTime         *time.Time `form:"end_time"          time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+// TextSpan is a highlighted range of a snippet, in characters (Unicode code points)
+type TextSpan struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// MessageSearchHit is a message matching a history search
+type MessageSearchHit struct {
+	SessionID    string `json:"session_id"`
+	SessionTitle string `json:"session_title"`
+	MessageID    string `json:"message_id"`
+	RequestID    string `json:"request_id"`
+	Role         string `json:"role"`
+	AgentID      string `json:"agent_id,omitempty"`
+	// Full message content, only used to build the snippet
+	Content string `json:"-"`
+	// Part of the message around the first match
+	Snippet    string     `json:"snippet"`
+	Highlights []TextSpan `json:"highlights"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// SessionSearchHit is a session whose title matches a history search
+type SessionSearchHit struct {
+	SessionID  string     `json:"session_id"`
+	Title      string     `json:"title"`
+	Highlights []TextSpan `json:"highlights"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// MessageSearchResult is the result of a history search
+type MessageSearchResult struct {
+	// Sessions whose title matches, only returned with the first page of messages
+	Sessions []*SessionSearchHit `json:"sessions"`
+	// Matching messages, newest first
+	Messages *PageResult `json:"messages"`
 }
 
 // AgentSteps represents a collection of agent execution steps
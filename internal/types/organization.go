@@ -218,10 +218,12 @@ type AgentShare struct {
 	SharedByUserID string         `json:"shared_by_user_id" gorm:"type:varchar(36);not null"`
 	SourceTenantID uint64         `json:"source_tenant_id" gorm:"not null;index"`
 	Permission     OrgMemberRole  `json:"permission" gorm:"type:varchar(32);not null;default:'viewer'"`
-	CreatedAt      time.Time      `json:"created_at"`
-	UpdatedAt      time.Time      `json:"updated_at"`
-	DeletedAt      gorm.DeletedAt `json:"deleted_at" gorm:"index"`
-	Agent          *CustomAgent   `json:"agent,omitempty" gorm:"foreignKey:AgentID,SourceTenantID;references:ID,TenantID"`
+	// Version of the agent members of the organization use, 0 to follow the published version
+	PinnedVersion int            `json:"pinned_version" gorm:"default:0"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+	Agent         *CustomAgent   `json:"agent,omitempty" gorm:"foreignKey:AgentID,SourceTenantID;references:ID,TenantID"`
 	Organization   *Organization  `json:"organization,omitempty" gorm:"foreignKey:OrganizationID"`
 }
 
@@ -423,6 +425,7 @@ type AgentShareResponse struct {
 	SharedByUsername string    `json:"shared_by_username"`
 	SourceTenantID   uint64    `json:"source_tenant_id"`
 	Permission       string    `json:"permission"`
+	PinnedVersion    int       `json:"pinned_version"`
 	MyRoleInOrg      string    `json:"my_role_in_org,omitempty"`
 	MyPermission     string    `json:"my_permission,omitempty"`
 	CreatedAt        time.Time `json:"created_at"`
@@ -10,6 +10,7 @@ const (
 	InfinityRetrieverEngineType      RetrieverEngineType = "infinity"
 	ElasticFaissRetrieverEngineType  RetrieverEngineType = "elasticfaiss"
 	QdrantRetrieverEngineType        RetrieverEngineType = "qdrant"
+	LocalRetrieverEngineType         RetrieverEngineType = "local"
 )
 
 // RetrieverType represents the type of retriever
@@ -56,6 +57,8 @@ type RetrieverEngineParams struct {
 	RetrieverEngineType RetrieverEngineType `yaml:"retriever_engine_type" json:"retriever_engine_type"`
 	// Retriever type
 	RetrieverType RetrieverType `yaml:"retriever_type"        json:"retriever_type"`
+	// Whether the engine only receives writes without serving retrieval, set for mirror engines
+	WriteOnly bool `yaml:"write_only,omitempty" json:"write_only,omitempty"`
 }
 
 // IndexWithScore represents the index with score
@@ -81,6 +81,9 @@ type Session struct {
 	Description string `json:"description"`
 	// Tenant ID
 	TenantID uint64 `json:"tenant_id"   gorm:"index"`
+	// Last message of the active conversation branch, empty until the first message.
+	// Read-only for GORM so that saving a stale session never switches the branch back.
+	ActiveMessageID string `json:"active_message_id" gorm:"type:varchar(36);->"`
 
 	// // Strategy configuration
 	// KnowledgeBaseID   string              `json:"knowledge_base_id"`                    // 关联的知识库ID
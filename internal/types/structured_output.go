@@ -0,0 +1,71 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	jsonschema "github.com/google/jsonschema-go/jsonschema"
+)
+
+// MaxOutputSchemaBytes caps the encoded size of an output schema, it is sent to the model with every answer
+const MaxOutputSchemaBytes = 16 * 1024
+
+// OutputSchema is a JSON Schema the final answer must conform to.
+// The answer is requested as a JSON object, so the schema root must be of type "object".
+type OutputSchema map[string]interface{}
+
+// JSON returns the encoded schema, nil when no schema is set
+func (s OutputSchema) JSON() json.RawMessage {
+	if len(s) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// Compile checks the schema and resolves it for validating answers
+func (s OutputSchema) Compile() (*jsonschema.Resolved, error) {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid output schema: %w", err)
+	}
+	if len(data) > MaxOutputSchemaBytes {
+		return nil, fmt.Errorf("output schema must not exceed %d bytes", MaxOutputSchemaBytes)
+	}
+	var schema jsonschema.Schema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("invalid output schema: %w", err)
+	}
+	if schema.Type != "object" {
+		return nil, errors.New(`output schema must be of type "object"`)
+	}
+	resolved, err := schema.Resolve(nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid output schema: %w", err)
+	}
+	return resolved, nil
+}
+
+// EffectiveOutputSchema returns the schema of the request, falling back to the one of the agent
+func EffectiveOutputSchema(schema OutputSchema, agent *CustomAgent) OutputSchema {
+	if len(schema) > 0 {
+		return schema
+	}
+	if agent != nil && len(agent.Config.OutputSchema) > 0 {
+		return agent.Config.OutputSchema
+	}
+	return nil
+}
+
+// ValidateOutputSchema validates the output schema of the config
+func (c *CustomAgentConfig) ValidateOutputSchema() error {
+	if len(c.OutputSchema) == 0 {
+		return nil
+	}
+	_, err := c.OutputSchema.Compile()
+	return err
+}
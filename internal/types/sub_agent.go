@@ -0,0 +1,36 @@
+package types
+
+import "fmt"
+
+const (
+	// MaxSubAgents is the maximum number of agents an agent can delegate to
+	MaxSubAgents = 10
+	// MaxSubAgentDepth is the maximum nesting depth of delegated runs, a sub-agent of a sub-agent is depth 2
+	MaxSubAgentDepth = 2
+	// DefaultSubAgentMaxIterations is the default ReAct iteration budget of a delegated run
+	DefaultSubAgentMaxIterations = 5
+)
+
+// ValidateSubAgents validates the sub-agent settings of the config of agent agentID
+func (c *CustomAgentConfig) ValidateSubAgents(agentID string) error {
+	if len(c.SubAgents) > MaxSubAgents {
+		return fmt.Errorf("at most %d sub agents are allowed", MaxSubAgents)
+	}
+	seen := make(map[string]bool, len(c.SubAgents))
+	for _, id := range c.SubAgents {
+		if id == "" {
+			return fmt.Errorf("sub agent id cannot be empty")
+		}
+		if agentID != "" && id == agentID {
+			return fmt.Errorf("an agent cannot delegate to itself")
+		}
+		if seen[id] {
+			return fmt.Errorf("duplicate sub agent %s", id)
+		}
+		seen[id] = true
+	}
+	if c.SubAgentMaxIterations < 0 || c.SubAgentMaxIterations > 100 {
+		return fmt.Errorf("sub_agent_max_iterations must be between 0 and 100")
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"os"
+	"slices"
 	"strings"
 	"time"
 
@@ -27,6 +28,10 @@ var retrieverEngineMapping = map[string][]RetrieverEngineParams{
 		{RetrieverType: KeywordsRetrieverType, RetrieverEngineType: QdrantRetrieverEngineType},
 		{RetrieverType: VectorRetrieverType, RetrieverEngineType: QdrantRetrieverEngineType},
 	},
+	"local": {
+		{RetrieverType: KeywordsRetrieverType, RetrieverEngineType: LocalRetrieverEngineType},
+		{RetrieverType: VectorRetrieverType, RetrieverEngineType: LocalRetrieverEngineType},
+	},
 }
 
 // GetRetrieverEngineMapping returns the retriever engine mapping
@@ -96,10 +101,29 @@ type Tenant struct {
 // RetrieverEngines represents the retriever engines for a tenant
 type RetrieverEngines struct {
 	Engines []RetrieverEngineParams `yaml:"engines" json:"engines" gorm:"type:json"`
+	// Engines that receive every write without serving retrieval, set while an index migration copies
+	// the indices to its target engine so that changes made during the copy are not lost
+	Mirrors []RetrieverEngineParams `yaml:"mirrors,omitempty" json:"mirrors,omitempty"`
 }
 
-// GetEffectiveEngines returns the tenant's engines if configured, otherwise returns system defaults
+// GetEffectiveEngines returns the engines that index and delete the tenant's data: the serving engines
+// followed by the mirror engines, which are marked write-only
 func (t *Tenant) GetEffectiveEngines() []RetrieverEngineParams {
+	engines := t.GetServingEngines()
+	if len(t.RetrieverEngines.Mirrors) == 0 {
+		return engines
+	}
+	engines = slices.Clone(engines)
+	for _, mirror := range t.RetrieverEngines.Mirrors {
+		mirror.WriteOnly = true
+		engines = append(engines, mirror)
+	}
+	return engines
+}
+
+// GetServingEngines returns the engines that serve retrieval: the tenant's engines if configured,
+// otherwise the system defaults
+func (t *Tenant) GetServingEngines() []RetrieverEngineParams {
 	if len(t.RetrieverEngines.Engines) > 0 {
 		return t.RetrieverEngines.Engines
 	}
@@ -0,0 +1,109 @@
+package types
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTenantEngines(t *testing.T) {
+	t.Setenv("RETRIEVE_DRIVER", "postgres")
+	postgres := []RetrieverEngineParams{
+		{RetrieverType: KeywordsRetrieverType, RetrieverEngineType: PostgresRetrieverEngineType},
+		{RetrieverType: VectorRetrieverType, RetrieverEngineType: PostgresRetrieverEngineType},
+	}
+	qdrantMirrors := []RetrieverEngineParams{
+		{RetrieverType: KeywordsRetrieverType, RetrieverEngineType: QdrantRetrieverEngineType},
+		{RetrieverType: VectorRetrieverType, RetrieverEngineType: QdrantRetrieverEngineType},
+	}
+	writeOnly := func(engines []RetrieverEngineParams) []RetrieverEngineParams {
+		engines = slices.Clone(engines)
+		for i := range engines {
+			engines[i].WriteOnly = true
+		}
+		return engines
+	}
+
+	tests := []struct {
+		name      string
+		engines   RetrieverEngines
+		serving   []RetrieverEngineParams
+		effective []RetrieverEngineParams
+	}{
+		{
+			name:      "system defaults",
+			serving:   postgres,
+			effective: postgres,
+		},
+		{
+			name: "tenant engines",
+			engines: RetrieverEngines{Engines: []RetrieverEngineParams{
+				{RetrieverType: VectorRetrieverType, RetrieverEngineType: LocalRetrieverEngineType},
+			}},
+			serving: []RetrieverEngineParams{
+				{RetrieverType: VectorRetrieverType, RetrieverEngineType: LocalRetrieverEngineType},
+			},
+			effective: []RetrieverEngineParams{
+				{RetrieverType: VectorRetrieverType, RetrieverEngineType: LocalRetrieverEngineType},
+			},
+		},
+		{
+			name:      "mirrors of the system defaults are write-only",
+			engines:   RetrieverEngines{Mirrors: qdrantMirrors},
+			serving:   postgres,
+			effective: append(slices.Clone(postgres), writeOnly(qdrantMirrors)...),
+		},
+		{
+			name:      "mirrors of the tenant engines are write-only",
+			engines:   RetrieverEngines{Engines: postgres, Mirrors: qdrantMirrors[1:]},
+			serving:   postgres,
+			effective: append(slices.Clone(postgres), writeOnly(qdrantMirrors[1:])...),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tenant := &Tenant{RetrieverEngines: tt.engines}
+			serving := tenant.GetServingEngines()
+			if !slices.Equal(serving, tt.serving) {
+				t.Errorf("GetServingEngines() = %+v, want %+v", serving, tt.serving)
+			}
+			for _, engine := range serving {
+				if engine.WriteOnly {
+					t.Errorf("serving engine %+v is write-only", engine)
+				}
+			}
+			if effective := tenant.GetEffectiveEngines(); !slices.Equal(effective, tt.effective) {
+				t.Errorf("GetEffectiveEngines() = %+v, want %+v", effective, tt.effective)
+			}
+
+			// Marking the mirrors write-only does not modify the stored configuration
+			if !slices.Equal(tenant.RetrieverEngines.Mirrors, tt.engines.Mirrors) {
+				t.Errorf("Mirrors changed to %+v", tenant.RetrieverEngines.Mirrors)
+			}
+			for _, mirror := range tenant.RetrieverEngines.Mirrors {
+				if mirror.WriteOnly {
+					t.Errorf("stored mirror %+v was marked write-only", mirror)
+				}
+			}
+		})
+	}
+}
+
+func TestTenantEffectiveEnginesDoNotShareTheServingSlice(t *testing.T) {
+	// Spare capacity would let an append write the mirrors into the tenant's engines
+	engines := make([]RetrieverEngineParams, 1, 4)
+	engines[0] = RetrieverEngineParams{RetrieverType: VectorRetrieverType, RetrieverEngineType: PostgresRetrieverEngineType}
+	tenant := &Tenant{RetrieverEngines: RetrieverEngines{
+		Engines: engines,
+		Mirrors: []RetrieverEngineParams{{RetrieverType: VectorRetrieverType, RetrieverEngineType: QdrantRetrieverEngineType}},
+	}}
+
+	if effective := tenant.GetEffectiveEngines(); len(effective) != 2 || !effective[1].WriteOnly {
+		t.Fatalf("GetEffectiveEngines() = %+v", effective)
+	}
+	if spare := engines[:2][1]; spare != (RetrieverEngineParams{}) {
+		t.Errorf("the mirror was written into the serving engines: %+v", spare)
+	}
+	if serving := tenant.GetServingEngines(); len(serving) != 1 {
+		t.Errorf("GetServingEngines() = %+v, want the postgres engine only", serving)
+	}
+}
@@ -0,0 +1,76 @@
+package types
+
+import (
+	"fmt"
+	"time"
+)
+
+// Tool approval statuses
+const (
+	ToolApprovalStatusPending  = "pending"  // Waiting for the user's decision
+	ToolApprovalStatusApproved = "approved" // Approved by the user, the tool call is executed
+	ToolApprovalStatusDenied   = "denied"   // Denied by the user, the tool call is skipped
+	ToolApprovalStatusExpired  = "expired"  // No decision before the deadline, the timeout action applies
+)
+
+// Tool approval timeout actions
+const (
+	ToolApprovalTimeoutDeny    = "deny"    // Skip the tool call when the approval times out (default)
+	ToolApprovalTimeoutApprove = "approve" // Execute the tool call when the approval times out
+)
+
+const (
+	// DefaultToolApprovalTimeoutSeconds is the default time to wait for a decision
+	DefaultToolApprovalTimeoutSeconds = 300
+	// MaxToolApprovalTimeoutSeconds caps the time an agent run can be paused for approval
+	MaxToolApprovalTimeoutSeconds = 3600
+)
+
+// ToolApproval is a persisted approval request for a sensitive agent tool call
+type ToolApproval struct {
+	ID         string `json:"id"           gorm:"type:varchar(36);primaryKey"`
+	TenantID   uint64 `json:"tenant_id"    gorm:"index"`
+	SessionID  string `json:"session_id"   gorm:"type:varchar(36);index"`
+	MessageID  string `json:"message_id"   gorm:"type:varchar(36)"`
+	ToolCallID string `json:"tool_call_id" gorm:"type:varchar(255)"`
+	ToolName   string `json:"tool_name"    gorm:"type:varchar(255)"`
+	Arguments  JSON   `json:"arguments"    gorm:"type:jsonb"`
+	Status     string `json:"status"       gorm:"type:varchar(20);default:'pending'"`
+	// Action applied when no decision is made before ExpiresAt
+	TimeoutAction string     `json:"timeout_action" gorm:"type:varchar(20)"`
+	Reason        string     `json:"reason"         gorm:"type:text"`
+	ExpiresAt     time.Time  `json:"expires_at"`
+	DecidedAt     *time.Time `json:"decided_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// TableName returns the table name for ToolApproval
+func (ToolApproval) TableName() string {
+	return "agent_tool_approvals"
+}
+
+// IsApproved reports whether the tool call may be executed
+func (a *ToolApproval) IsApproved() bool {
+	switch a.Status {
+	case ToolApprovalStatusApproved:
+		return true
+	case ToolApprovalStatusExpired:
+		return a.TimeoutAction == ToolApprovalTimeoutApprove
+	default:
+		return false
+	}
+}
+
+// ValidateToolApproval validates the tool approval settings of the config
+func (c *CustomAgentConfig) ValidateToolApproval() error {
+	switch c.ToolApprovalTimeoutAction {
+	case "", ToolApprovalTimeoutDeny, ToolApprovalTimeoutApprove:
+	default:
+		return fmt.Errorf("unsupported tool approval timeout action %q", c.ToolApprovalTimeoutAction)
+	}
+	if c.ToolApprovalTimeoutSeconds < 0 || c.ToolApprovalTimeoutSeconds > MaxToolApprovalTimeoutSeconds {
+		return fmt.Errorf("tool approval timeout must be between 0 and %d seconds", MaxToolApprovalTimeoutSeconds)
+	}
+	return nil
+}
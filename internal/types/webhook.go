@@ -0,0 +1,141 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+)
+
+// Webhook event types
+const (
+	WebhookEventKnowledgeParseCompleted = "knowledge.parse_completed"     // Document parsed and indexed
+	WebhookEventKnowledgeParseFailed    = "knowledge.parse_failed"        // Document parsing failed
+	WebhookEventSummaryGenerated        = "knowledge.summary_generated"   // Document summary generated
+	WebhookEventFAQImportFinished       = "faq.import_finished"           // FAQ import completed or failed
+	WebhookEventKBCloneFinished         = "knowledge_base.clone_finished" // Knowledge base clone completed or failed
+	WebhookEventMessageCompleted        = "message.completed"             // Assistant answer completed
+	WebhookEventAgentTaskSucceeded      = "agent_task.run_succeeded"      // Agent task run answered
+	WebhookEventAgentTaskFailed         = "agent_task.run_failed"         // Agent task run failed
+	WebhookEventTest                    = "webhook.test"                  // Sent by the test endpoint
+)
+
+// WebhookEvents lists the events a webhook can subscribe to
+var WebhookEvents = []string{
+	WebhookEventKnowledgeParseCompleted,
+	WebhookEventKnowledgeParseFailed,
+	WebhookEventSummaryGenerated,
+	WebhookEventFAQImportFinished,
+	WebhookEventKBCloneFinished,
+	WebhookEventMessageCompleted,
+	WebhookEventAgentTaskSucceeded,
+	WebhookEventAgentTaskFailed,
+}
+
+// Webhook delivery statuses
+const (
+	WebhookDeliveryStatusPending  = "pending"  // Waiting for the first attempt
+	WebhookDeliveryStatusRetrying = "retrying" // An attempt failed, another one is scheduled
+	WebhookDeliveryStatusSuccess  = "success"  // The endpoint returned 2xx
+	WebhookDeliveryStatusFailed   = "failed"   // All attempts failed
+)
+
+// Webhook signature headers sent with every delivery
+const (
+	WebhookHeaderEvent     = "X-WeKnora-Event"
+	WebhookHeaderDelivery  = "X-WeKnora-Delivery"
+	WebhookHeaderTimestamp = "X-WeKnora-Timestamp"
+	// sha256=hex(HMAC-SHA256(secret, timestamp + "." + body))
+	WebhookHeaderSignature = "X-WeKnora-Signature"
+)
+
+// Webhook is an HTTP endpoint of a tenant that receives subscribed events.
+// The signing secret is encrypted at rest and only returned when the webhook is created.
+type Webhook struct {
+	ID          string          `json:"id"          gorm:"type:varchar(36);primaryKey"`
+	TenantID    uint64          `json:"tenant_id"   gorm:"index"`
+	Name        string          `json:"name"        gorm:"type:varchar(255)"`
+	Description string          `json:"description" gorm:"type:text"`
+	URL         string          `json:"url"         gorm:"type:varchar(2048)"`
+	Secret      EncryptedString `json:"-"           gorm:"type:text"`
+	Events      StringArray     `json:"events"      gorm:"type:json"`
+	Enabled     bool            `json:"enabled"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// TableName returns the table name for Webhook
+func (Webhook) TableName() string {
+	return "webhooks"
+}
+
+// Subscribes reports whether the webhook receives the event
+func (w *Webhook) Subscribes(event string) bool {
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookEvent is the JSON body posted to webhook endpoints
+type WebhookEvent struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	TenantID  uint64      `json:"tenant_id"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// Value implements the driver.Valuer interface, used to convert WebhookEvent to database value
+func (e WebhookEvent) Value() (driver.Value, error) {
+	return json.Marshal(e)
+}
+
+// Scan implements the sql.Scanner interface, used to convert database value to WebhookEvent
+func (e *WebhookEvent) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	var b []byte
+	switch v := value.(type) {
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return nil
+	}
+	return json.Unmarshal(b, e)
+}
+
+// WebhookDelivery records the delivery of one event to one webhook.
+// Retries of the same delivery reuse the record and the event ID, so receivers can deduplicate.
+type WebhookDelivery struct {
+	ID        string       `json:"id"         gorm:"type:varchar(36);primaryKey"`
+	TenantID  uint64       `json:"tenant_id"  gorm:"index"`
+	WebhookID string       `json:"webhook_id" gorm:"type:varchar(36);index"`
+	Event     string       `json:"event"      gorm:"type:varchar(64)"`
+	Payload   WebhookEvent `json:"payload"    gorm:"type:json"`
+	Status    string       `json:"status"     gorm:"type:varchar(20);default:'pending'"`
+	Attempts  int          `json:"attempts"`
+	// HTTP status and (truncated) body returned by the last attempt
+	ResponseStatus int        `json:"response_status"`
+	ResponseBody   string     `json:"response_body"   gorm:"type:text"`
+	Error          string     `json:"error"           gorm:"type:text"`
+	DurationMs     int64      `json:"duration_ms"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// TableName returns the table name for WebhookDelivery
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+// WebhookDeliveryPayload represents the webhook delivery task payload
+type WebhookDeliveryPayload struct {
+	TenantID   uint64 `json:"tenant_id"`
+	DeliveryID string `json:"delivery_id"`
+}
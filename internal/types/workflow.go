@@ -0,0 +1,400 @@
+package types
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Workflow node types
+const (
+	WorkflowNodeRetrieve  = "retrieve"  // Hybrid search in the agent's knowledge bases
+	WorkflowNodeRerank    = "rerank"    // Rerank the search results of the dependencies
+	WorkflowNodeLLM       = "llm"       // Call the chat model with a templated prompt
+	WorkflowNodeTool      = "tool"      // Call an agent tool (built-in, MCP, HTTP or sub-agent)
+	WorkflowNodeCondition = "condition" // Choose a branch by matching a templated input
+	WorkflowNodeMerge     = "merge"     // Join the outputs of the dependencies that ran
+)
+
+// Workflow condition operators
+const (
+	WorkflowOperatorContains = "contains"  // Input contains the value, case-insensitive
+	WorkflowOperatorEquals   = "equals"    // Trimmed input equals the value, case-insensitive
+	WorkflowOperatorRegex    = "regex"     // Input matches the regular expression
+	WorkflowOperatorNotEmpty = "not_empty" // Trimmed input is not empty
+)
+
+const (
+	// MaxWorkflowNodes is the maximum number of nodes of a workflow
+	MaxWorkflowNodes = 50
+	// WorkflowDefaultBranch is the branch chosen by a condition node when no case matches
+	WorkflowDefaultBranch = "default"
+	// WorkflowQueryVariable is the template variable holding the user query
+	WorkflowQueryVariable = "query"
+)
+
+var (
+	workflowNodeIDPattern   = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]{0,63}$`)
+	workflowTemplatePattern = regexp.MustCompile(`\{\{\s*([A-Za-z][A-Za-z0-9_]*)((?:\.[A-Za-z0-9_]+)*)\s*\}\}`)
+)
+
+// WorkflowDefinition is a DAG of nodes run in dependency order by a workflow agent.
+// Templates in node settings reference {{query}}, the output of a node as {{node_id}},
+// or a field of a node's JSON output as {{node_id.field.subfield}}.
+type WorkflowDefinition struct {
+	// Nodes of the workflow, each depending on the nodes listed in its depends_on
+	Nodes []WorkflowNode `yaml:"nodes" json:"nodes"`
+	// Template of the answer, defaults to the output of the last node that ran
+	Output string `yaml:"output" json:"output"`
+}
+
+// WorkflowNode is a step of a workflow
+type WorkflowNode struct {
+	// Unique ID of the node, referenced by depends_on and templates
+	ID string `yaml:"id" json:"id"`
+	// Node type, see the WorkflowNode* constants
+	Type string `yaml:"type" json:"type"`
+	// Display name of the node
+	Name string `yaml:"name" json:"name,omitempty"`
+	// IDs of the nodes that must run before this node
+	DependsOn []string `yaml:"depends_on" json:"depends_on,omitempty"`
+	// Branch of the condition node this node depends on that the node runs for
+	When string `yaml:"when" json:"when,omitempty"`
+	// Whether the workflow continues with an empty output when the node fails
+	ContinueOnError bool `yaml:"continue_on_error" json:"continue_on_error,omitempty"`
+
+	// ===== retrieve / rerank =====
+	// Query template (default {{query}})
+	Query string `yaml:"query" json:"query,omitempty"`
+	// Knowledge bases to search, a subset of the agent's (default all of them, retrieve only)
+	KnowledgeBases []string `yaml:"knowledge_bases" json:"knowledge_bases,omitempty"`
+	// Number of results to keep (default the agent's embedding_top_k / rerank_top_k)
+	TopK int `yaml:"top_k" json:"top_k,omitempty"`
+	// Retrieval thresholds (default the agent's, retrieve only)
+	VectorThreshold  float64 `yaml:"vector_threshold" json:"vector_threshold,omitempty"`
+	KeywordThreshold float64 `yaml:"keyword_threshold" json:"keyword_threshold,omitempty"`
+	// Minimum rerank score (default the agent's rerank_threshold, rerank only)
+	Threshold float64 `yaml:"threshold" json:"threshold,omitempty"`
+
+	// ===== llm =====
+	// User prompt template
+	Prompt string `yaml:"prompt" json:"prompt,omitempty"`
+	// System prompt template
+	SystemPrompt string `yaml:"system_prompt" json:"system_prompt,omitempty"`
+	// Chat model ID (default the agent's model)
+	ModelID string `yaml:"model_id" json:"model_id,omitempty"`
+	// Temperature (default the agent's)
+	Temperature float64 `yaml:"temperature" json:"temperature,omitempty"`
+	// Maximum completion tokens (default the agent's max_completion_tokens)
+	MaxTokens int `yaml:"max_tokens" json:"max_tokens,omitempty"`
+
+	// ===== tool =====
+	// Name of the tool as registered for the agent, e.g. knowledge_search or http_weather
+	Tool string `yaml:"tool" json:"tool,omitempty"`
+	// Tool arguments, string values anywhere in the arguments are templates
+	Arguments map[string]interface{} `yaml:"arguments" json:"arguments,omitempty"`
+
+	// ===== condition =====
+	// Template of the value matched against the cases (default the output of the first dependency)
+	Input string `yaml:"input" json:"input,omitempty"`
+	// Cases tried in order, the first match chooses the branch, otherwise the branch is "default"
+	Cases []WorkflowCondition `yaml:"cases" json:"cases,omitempty"`
+
+	// ===== merge =====
+	// Separator between the outputs of the dependencies (default a blank line)
+	Separator string `yaml:"separator" json:"separator,omitempty"`
+}
+
+// WorkflowCondition is a case of a condition node
+type WorkflowCondition struct {
+	// Branch chosen when the case matches
+	Branch string `yaml:"branch" json:"branch"`
+	// Operator, see the WorkflowOperator* constants (default contains)
+	Operator string `yaml:"operator" json:"operator,omitempty"`
+	// Value compared with the input
+	Value string `yaml:"value" json:"value,omitempty"`
+}
+
+// DisplayName returns the name of the node, or its ID when it has none
+func (n *WorkflowNode) DisplayName() string {
+	if n.Name != "" {
+		return n.Name
+	}
+	return n.ID
+}
+
+// WorkflowTemplateRefs returns the node IDs referenced by a template, {{query}} excluded
+func WorkflowTemplateRefs(template string) []string {
+	var refs []string
+	for _, match := range workflowTemplatePattern.FindAllStringSubmatch(template, -1) {
+		if match[1] != WorkflowQueryVariable {
+			refs = append(refs, match[1])
+		}
+	}
+	return refs
+}
+
+// RenderWorkflowTemplate replaces the references of a template using resolve, which receives
+// the referenced name and the field path (empty for the whole output)
+func RenderWorkflowTemplate(template string, resolve func(name string, path []string) string) string {
+	return workflowTemplatePattern.ReplaceAllStringFunc(template, func(placeholder string) string {
+		match := workflowTemplatePattern.FindStringSubmatch(placeholder)
+		var path []string
+		if match[2] != "" {
+			path = strings.Split(strings.TrimPrefix(match[2], "."), ".")
+		}
+		return resolve(match[1], path)
+	})
+}
+
+// RuntimeWorkflow returns a copy of the workflow with the unset retrieval and generation
+// settings of its nodes taken from the agent config
+func (c *CustomAgentConfig) RuntimeWorkflow() *WorkflowDefinition {
+	if c.Workflow == nil {
+		return nil
+	}
+	w := &WorkflowDefinition{Output: c.Workflow.Output, Nodes: make([]WorkflowNode, len(c.Workflow.Nodes))}
+	copy(w.Nodes, c.Workflow.Nodes)
+	for i := range w.Nodes {
+		node := &w.Nodes[i]
+		switch node.Type {
+		case WorkflowNodeRetrieve:
+			if node.TopK == 0 {
+				node.TopK = c.EmbeddingTopK
+			}
+			if node.VectorThreshold == 0 {
+				node.VectorThreshold = c.VectorThreshold
+			}
+			if node.KeywordThreshold == 0 {
+				node.KeywordThreshold = c.KeywordThreshold
+			}
+		case WorkflowNodeRerank:
+			if node.TopK == 0 {
+				node.TopK = c.RerankTopK
+			}
+			if node.Threshold == 0 {
+				node.Threshold = c.RerankThreshold
+			}
+		case WorkflowNodeLLM:
+			if node.MaxTokens == 0 {
+				node.MaxTokens = c.MaxCompletionTokens
+			}
+		}
+	}
+	return w
+}
+
+// ValidateWorkflow validates the workflow of a workflow-mode agent config
+func (c *CustomAgentConfig) ValidateWorkflow() error {
+	if c.AgentMode != AgentModeWorkflow {
+		return nil
+	}
+	w := c.Workflow
+	if w == nil || len(w.Nodes) == 0 {
+		return fmt.Errorf("workflow mode requires a workflow with at least one node")
+	}
+	if len(w.Nodes) > MaxWorkflowNodes {
+		return fmt.Errorf("a workflow can have at most %d nodes", MaxWorkflowNodes)
+	}
+
+	nodes := make(map[string]*WorkflowNode, len(w.Nodes))
+	for i := range w.Nodes {
+		node := &w.Nodes[i]
+		if !workflowNodeIDPattern.MatchString(node.ID) {
+			return fmt.Errorf("invalid node id %q: use 1-64 letters, digits or underscores starting with a letter", node.ID)
+		}
+		if node.ID == WorkflowQueryVariable {
+			return fmt.Errorf("node id %q is reserved", node.ID)
+		}
+		if nodes[node.ID] != nil {
+			return fmt.Errorf("duplicate node id %s", node.ID)
+		}
+		nodes[node.ID] = node
+	}
+
+	order, err := w.TopologicalOrder()
+	if err != nil {
+		return err
+	}
+	// Ancestors of each node, the only nodes its templates may reference
+	ancestors := make(map[string]map[string]bool, len(order))
+	for _, node := range order {
+		set := make(map[string]bool)
+		for _, dep := range node.DependsOn {
+			set[dep] = true
+			for ancestor := range ancestors[dep] {
+				set[ancestor] = true
+			}
+		}
+		ancestors[node.ID] = set
+	}
+
+	approvalRequired := make(map[string]bool, len(c.ApprovalRequiredTools))
+	for _, name := range c.ApprovalRequiredTools {
+		approvalRequired[name] = true
+	}
+
+	for _, node := range order {
+		if err := node.validate(nodes, approvalRequired); err != nil {
+			return fmt.Errorf("node %s: %w", node.ID, err)
+		}
+		for _, template := range node.templates() {
+			for _, ref := range WorkflowTemplateRefs(template) {
+				if !ancestors[node.ID][ref] {
+					return fmt.Errorf("node %s: template references %s, which is not one of its dependencies", node.ID, ref)
+				}
+			}
+		}
+	}
+	for _, ref := range WorkflowTemplateRefs(w.Output) {
+		if nodes[ref] == nil {
+			return fmt.Errorf("output references unknown node %s", ref)
+		}
+	}
+	return nil
+}
+
+// validate checks the settings of a node, nodes holds all nodes of the workflow by ID
+func (n *WorkflowNode) validate(nodes map[string]*WorkflowNode, approvalRequired map[string]bool) error {
+	conditionDeps := 0
+	for _, dep := range n.DependsOn {
+		if nodes[dep].Type == WorkflowNodeCondition {
+			conditionDeps++
+		}
+	}
+	if n.When != "" && conditionDeps != 1 {
+		return fmt.Errorf("when requires depending on exactly one condition node")
+	}
+	if n.TopK < 0 || n.TopK > 100 {
+		return fmt.Errorf("top_k must be between 0 and 100")
+	}
+
+	switch n.Type {
+	case WorkflowNodeRetrieve:
+	case WorkflowNodeRerank:
+		hasResults := false
+		for _, dep := range n.DependsOn {
+			switch nodes[dep].Type {
+			case WorkflowNodeRetrieve, WorkflowNodeRerank, WorkflowNodeMerge:
+				hasResults = true
+			}
+		}
+		if !hasResults {
+			return fmt.Errorf("rerank requires depending on a retrieve, rerank or merge node")
+		}
+	case WorkflowNodeLLM:
+		if strings.TrimSpace(n.Prompt) == "" {
+			return fmt.Errorf("prompt is required")
+		}
+		if n.Temperature < 0 || n.Temperature > 2 {
+			return fmt.Errorf("temperature must be between 0 and 2")
+		}
+	case WorkflowNodeTool:
+		if n.Tool == "" {
+			return fmt.Errorf("tool is required")
+		}
+		if approvalRequired[n.Tool] {
+			return fmt.Errorf("tool %s requires approval and cannot be called by a workflow", n.Tool)
+		}
+	case WorkflowNodeCondition:
+		if len(n.Cases) == 0 {
+			return fmt.Errorf("at least one case is required")
+		}
+		if n.Input == "" && len(n.DependsOn) == 0 {
+			return fmt.Errorf("input is required when the node has no dependencies")
+		}
+		for _, cond := range n.Cases {
+			if cond.Branch == "" || cond.Branch == WorkflowDefaultBranch {
+				return fmt.Errorf("case branch must be set and not %q", WorkflowDefaultBranch)
+			}
+			switch cond.Operator {
+			case "", WorkflowOperatorContains, WorkflowOperatorEquals, WorkflowOperatorNotEmpty:
+			case WorkflowOperatorRegex:
+				if _, err := regexp.Compile(cond.Value); err != nil {
+					return fmt.Errorf("invalid regex %q: %v", cond.Value, err)
+				}
+			default:
+				return fmt.Errorf("unknown operator %q", cond.Operator)
+			}
+		}
+	case WorkflowNodeMerge:
+		if len(n.DependsOn) < 2 {
+			return fmt.Errorf("merge requires at least two dependencies")
+		}
+	default:
+		return fmt.Errorf("unknown node type %q", n.Type)
+	}
+	return nil
+}
+
+// templates returns the template settings of a node
+func (n *WorkflowNode) templates() []string {
+	templates := []string{n.Query, n.Prompt, n.SystemPrompt, n.Input}
+	var collect func(value interface{})
+	collect = func(value interface{}) {
+		switch v := value.(type) {
+		case string:
+			templates = append(templates, v)
+		case map[string]interface{}:
+			for _, item := range v {
+				collect(item)
+			}
+		case []interface{}:
+			for _, item := range v {
+				collect(item)
+			}
+		}
+	}
+	collect(n.Arguments)
+	return templates
+}
+
+// TopologicalOrder returns the nodes in dependency order, nodes without ordering constraints
+// keep their definition order. It fails on unknown dependencies and cycles.
+func (w *WorkflowDefinition) TopologicalOrder() ([]*WorkflowNode, error) {
+	index := make(map[string]int, len(w.Nodes))
+	for i := range w.Nodes {
+		index[w.Nodes[i].ID] = i
+	}
+	pending := make([]int, len(w.Nodes))
+	dependents := make([][]int, len(w.Nodes))
+	for i := range w.Nodes {
+		seen := make(map[string]bool, len(w.Nodes[i].DependsOn))
+		for _, dep := range w.Nodes[i].DependsOn {
+			j, ok := index[dep]
+			if !ok {
+				return nil, fmt.Errorf("node %s depends on unknown node %s", w.Nodes[i].ID, dep)
+			}
+			if j == i {
+				return nil, fmt.Errorf("node %s depends on itself", dep)
+			}
+			if seen[dep] {
+				return nil, fmt.Errorf("node %s lists dependency %s twice", w.Nodes[i].ID, dep)
+			}
+			seen[dep] = true
+			pending[i]++
+			dependents[j] = append(dependents[j], i)
+		}
+	}
+
+	order := make([]*WorkflowNode, 0, len(w.Nodes))
+	done := make([]bool, len(w.Nodes))
+	for len(order) < len(w.Nodes) {
+		next := -1
+		for i := range w.Nodes {
+			if !done[i] && pending[i] == 0 {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			return nil, fmt.Errorf("workflow contains a dependency cycle")
+		}
+		done[next] = true
+		order = append(order, &w.Nodes[next])
+		for _, dependent := range dependents[next] {
+			pending[dependent]--
+		}
+	}
+	return order, nil
+}
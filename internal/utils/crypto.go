@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ErrInvalidCiphertext is returned when a value cannot be decrypted with TENANT_AES_KEY
+var ErrInvalidCiphertext = errors.New("invalid ciphertext")
+
+// newTenantGCM creates the AES-GCM cipher keyed by TENANT_AES_KEY (16, 24 or 32 bytes).
+// It is shared by tenant API keys and the secrets stored in the database.
+func newTenantGCM() (cipher.AEAD, error) {
+	key := os.Getenv("TENANT_AES_KEY")
+	if key == "" {
+		return nil, errors.New("TENANT_AES_KEY is not set")
+	}
+	block, err := aes.NewCipher([]byte(key))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AES cipher: %w", err)
+	}
+	aesgcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM cipher: %w", err)
+	}
+	return aesgcm, nil
+}
+
+// EncryptAESGCM encrypts data with TENANT_AES_KEY and returns the random nonce followed by the ciphertext
+func EncryptAESGCM(plaintext []byte) ([]byte, error) {
+	aesgcm, err := newTenantGCM()
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aesgcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return aesgcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// DecryptAESGCM decrypts a value produced by EncryptAESGCM
+func DecryptAESGCM(data []byte) ([]byte, error) {
+	aesgcm, err := newTenantGCM()
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aesgcm.NonceSize() {
+		return nil, ErrInvalidCiphertext
+	}
+	nonce, ciphertext := data[:aesgcm.NonceSize()], data[aesgcm.NonceSize():]
+	plaintext, err := aesgcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCiphertext, err)
+	}
+	return plaintext, nil
+}
+
+// EncryptSecret encrypts a secret with EncryptAESGCM and returns it base64 encoded.
+// An empty input returns an empty string.
+func EncryptSecret(plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+	ciphertext, err := EncryptAESGCM([]byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// DecryptSecret decrypts a value produced by EncryptSecret.
+// An empty input returns an empty string.
+func DecryptSecret(encoded string) (string, error) {
+	if encoded == "" {
+		return "", nil
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode secret: %w", err)
+	}
+	plaintext, err := DecryptAESGCM(data)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
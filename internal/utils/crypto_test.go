@@ -0,0 +1,32 @@
+package utils
+
+import "testing"
+
+func TestEncryptDecryptSecret(t *testing.T) {
+	t.Setenv("TENANT_AES_KEY", "0123456789abcdef0123456789abcdef")
+
+	encrypted, err := EncryptSecret("refresh-token-value")
+	if err != nil {
+		t.Fatalf("EncryptSecret() error = %v", err)
+	}
+	if encrypted == "refresh-token-value" {
+		t.Fatal("EncryptSecret() returned plaintext")
+	}
+
+	decrypted, err := DecryptSecret(encrypted)
+	if err != nil {
+		t.Fatalf("DecryptSecret() error = %v", err)
+	}
+	if decrypted != "refresh-token-value" {
+		t.Errorf("DecryptSecret() = %q, want %q", decrypted, "refresh-token-value")
+	}
+
+	if empty, err := EncryptSecret(""); err != nil || empty != "" {
+		t.Errorf("EncryptSecret(\"\") = %q, %v, want empty", empty, err)
+	}
+
+	t.Setenv("TENANT_AES_KEY", "fedcba9876543210fedcba9876543210")
+	if _, err := DecryptSecret(encrypted); err == nil {
+		t.Error("DecryptSecret() with a different key should fail")
+	}
+}
@@ -25,3 +25,14 @@ func GetMaxFileSizeMB() int64 {
 	}
 	return 50 // default 50MB
 }
+
+// GetMaxBundleSizeMB returns the maximum size of an imported bundle in MB.
+// Default is 1024MB, can be configured via BUNDLE_MAX_SIZE_MB environment variable.
+func GetMaxBundleSizeMB() int64 {
+	if sizeStr := os.Getenv("BUNDLE_MAX_SIZE_MB"); sizeStr != "" {
+		if size, err := strconv.ParseInt(sizeStr, 10, 64); err == nil && size > 0 {
+			return size
+		}
+	}
+	return 1024 // default 1GB
+}
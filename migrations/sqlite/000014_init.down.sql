@@ -0,0 +1,21 @@
+-- Migration: 000014_init (SQLite, down)
+DROP TABLE IF EXISTS agent_tool_approvals;
+DROP TABLE IF EXISTS mcp_oauth_credentials;
+DROP TABLE IF EXISTS tenant_disabled_shared_agents;
+DROP TABLE IF EXISTS agent_shares;
+DROP TABLE IF EXISTS organization_join_requests;
+DROP TABLE IF EXISTS kb_shares;
+DROP TABLE IF EXISTS organization_members;
+DROP TABLE IF EXISTS organizations;
+DROP TABLE IF EXISTS custom_agents;
+DROP TABLE IF EXISTS mcp_services;
+DROP TABLE IF EXISTS knowledge_tags;
+DROP TABLE IF EXISTS auth_tokens;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS chunks;
+DROP TABLE IF EXISTS messages;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS knowledges;
+DROP TABLE IF EXISTS knowledge_bases;
+DROP TABLE IF EXISTS models;
+DROP TABLE IF EXISTS tenants;
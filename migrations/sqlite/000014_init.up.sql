@@ -0,0 +1,425 @@
+-- Migration: 000014_init (SQLite)
+-- Description: Consolidated schema for the single-binary lite mode (DB_DRIVER=sqlite)
+-- Mirrors the PostgreSQL schema after versioned migration 000014, so that later
+-- migrations keep the same version numbers in both directories.
+-- Notes:
+--   * JSON columns are BLOB: the JSON types scan []byte, and SQLite returns TEXT as string
+--   * Time columns are DATETIME so that the driver parses them into time.Time
+--   * seq_id values are assigned by the application (see internal/database/sqlite.go)
+--   * The embeddings table is not created: vectors live in the local retrieve engine
+
+-- Tenants (ids start at 10000, as in PostgreSQL)
+CREATE TABLE IF NOT EXISTS tenants (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    api_key VARCHAR(64) NOT NULL,
+    retriever_engines BLOB NOT NULL DEFAULT X'5B5D',
+    status VARCHAR(50) DEFAULT 'active',
+    business VARCHAR(255) NOT NULL,
+    storage_quota BIGINT NOT NULL DEFAULT 10737418240,
+    storage_used BIGINT NOT NULL DEFAULT 0,
+    agent_config BLOB DEFAULT NULL,
+    context_config BLOB,
+    conversation_config BLOB,
+    web_search_config BLOB DEFAULT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    deleted_at DATETIME
+);
+INSERT INTO sqlite_sequence (name, seq)
+SELECT 'tenants', 9999
+WHERE NOT EXISTS (SELECT 1 FROM sqlite_sequence WHERE name = 'tenants');
+CREATE INDEX IF NOT EXISTS idx_tenants_api_key ON tenants(api_key);
+CREATE INDEX IF NOT EXISTS idx_tenants_status ON tenants(status);
+
+-- Models
+CREATE TABLE IF NOT EXISTS models (
+    id VARCHAR(64) PRIMARY KEY,
+    tenant_id INTEGER NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    type VARCHAR(50) NOT NULL,
+    source VARCHAR(50) NOT NULL,
+    description TEXT,
+    parameters BLOB NOT NULL,
+    is_default BOOLEAN NOT NULL DEFAULT false,
+    is_builtin BOOLEAN NOT NULL DEFAULT false,
+    status VARCHAR(50) NOT NULL DEFAULT 'active',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    deleted_at DATETIME
+);
+CREATE INDEX IF NOT EXISTS idx_models_type ON models(type);
+CREATE INDEX IF NOT EXISTS idx_models_source ON models(source);
+CREATE INDEX IF NOT EXISTS idx_models_is_builtin ON models(is_builtin);
+
+-- Knowledge bases
+CREATE TABLE IF NOT EXISTS knowledge_bases (
+    id VARCHAR(36) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    tenant_id INTEGER NOT NULL,
+    type VARCHAR(32) NOT NULL DEFAULT 'document',
+    is_temporary BOOLEAN NOT NULL DEFAULT false,
+    chunking_config BLOB NOT NULL DEFAULT X'7B7D',
+    image_processing_config BLOB NOT NULL DEFAULT X'7B7D',
+    embedding_model_id VARCHAR(64) NOT NULL,
+    summary_model_id VARCHAR(64) NOT NULL,
+    cos_config BLOB NOT NULL DEFAULT X'7B7D',
+    vlm_config BLOB NOT NULL DEFAULT X'7B7D',
+    extract_config BLOB NULL DEFAULT NULL,
+    faq_config BLOB,
+    question_generation_config BLOB NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    deleted_at DATETIME
+);
+CREATE INDEX IF NOT EXISTS idx_knowledge_bases_tenant_id ON knowledge_bases(tenant_id);
+
+-- Knowledges
+CREATE TABLE IF NOT EXISTS knowledges (
+    id VARCHAR(36) PRIMARY KEY,
+    tenant_id INTEGER NOT NULL,
+    knowledge_base_id VARCHAR(36) NOT NULL,
+    tag_id VARCHAR(36),
+    type VARCHAR(50) NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    description TEXT,
+    source VARCHAR(128) NOT NULL,
+    parse_status VARCHAR(50) NOT NULL DEFAULT 'unprocessed',
+    summary_status VARCHAR(32) DEFAULT 'none',
+    enable_status VARCHAR(50) NOT NULL DEFAULT 'enabled',
+    embedding_model_id VARCHAR(64),
+    file_name VARCHAR(255),
+    file_type VARCHAR(50),
+    file_size BIGINT,
+    file_path TEXT,
+    file_hash VARCHAR(64),
+    storage_size BIGINT NOT NULL DEFAULT 0,
+    metadata BLOB,
+    last_faq_import_result BLOB DEFAULT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    processed_at DATETIME,
+    error_message TEXT,
+    deleted_at DATETIME
+);
+CREATE INDEX IF NOT EXISTS idx_knowledges_tenant_id ON knowledges(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_knowledges_base_id ON knowledges(knowledge_base_id);
+CREATE INDEX IF NOT EXISTS idx_knowledges_parse_status ON knowledges(parse_status);
+CREATE INDEX IF NOT EXISTS idx_knowledges_enable_status ON knowledges(enable_status);
+CREATE INDEX IF NOT EXISTS idx_knowledges_tag ON knowledges(tag_id);
+CREATE INDEX IF NOT EXISTS idx_knowledges_summary_status ON knowledges(summary_status);
+
+-- Sessions
+CREATE TABLE IF NOT EXISTS sessions (
+    id VARCHAR(36) PRIMARY KEY,
+    tenant_id INTEGER NOT NULL,
+    title VARCHAR(255),
+    description TEXT,
+    knowledge_base_id VARCHAR(36),
+    max_rounds INTEGER NOT NULL DEFAULT 5,
+    enable_rewrite BOOLEAN NOT NULL DEFAULT true,
+    fallback_strategy VARCHAR(255) NOT NULL DEFAULT 'fixed',
+    fallback_response TEXT NOT NULL DEFAULT '很抱歉，我暂时无法回答这个问题。',
+    keyword_threshold FLOAT NOT NULL DEFAULT 0.5,
+    vector_threshold FLOAT NOT NULL DEFAULT 0.5,
+    rerank_model_id VARCHAR(64),
+    embedding_top_k INTEGER NOT NULL DEFAULT 10,
+    rerank_top_k INTEGER NOT NULL DEFAULT 10,
+    rerank_threshold FLOAT NOT NULL DEFAULT 0.65,
+    summary_model_id VARCHAR(64),
+    summary_parameters BLOB NOT NULL DEFAULT X'7B7D',
+    agent_config BLOB DEFAULT NULL,
+    context_config BLOB DEFAULT NULL,
+    agent_id VARCHAR(36),
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    deleted_at DATETIME
+);
+CREATE INDEX IF NOT EXISTS idx_sessions_tenant_id ON sessions(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_sessions_agent_id ON sessions(agent_id);
+
+-- Messages
+CREATE TABLE IF NOT EXISTS messages (
+    id VARCHAR(36) PRIMARY KEY,
+    request_id VARCHAR(36) NOT NULL,
+    session_id VARCHAR(36) NOT NULL,
+    role VARCHAR(50) NOT NULL,
+    content TEXT NOT NULL,
+    knowledge_references BLOB NOT NULL DEFAULT X'5B5D',
+    agent_steps BLOB DEFAULT NULL,
+    mentioned_items BLOB DEFAULT X'5B5D',
+    is_completed BOOLEAN NOT NULL DEFAULT false,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    deleted_at DATETIME
+);
+CREATE INDEX IF NOT EXISTS idx_messages_session_id ON messages(session_id);
+
+-- Chunks
+CREATE TABLE IF NOT EXISTS chunks (
+    id VARCHAR(36) PRIMARY KEY,
+    seq_id BIGINT NOT NULL,
+    tenant_id INTEGER NOT NULL,
+    knowledge_base_id VARCHAR(36) NOT NULL,
+    knowledge_id VARCHAR(36) NOT NULL,
+    tag_id VARCHAR(36),
+    content TEXT NOT NULL,
+    chunk_index INTEGER NOT NULL,
+    is_enabled BOOLEAN NOT NULL DEFAULT true,
+    flags INTEGER NOT NULL DEFAULT 1,
+    status INTEGER NOT NULL DEFAULT 0,
+    start_at INTEGER NOT NULL,
+    end_at INTEGER NOT NULL,
+    pre_chunk_id VARCHAR(36),
+    next_chunk_id VARCHAR(36),
+    chunk_type VARCHAR(20) NOT NULL DEFAULT 'text',
+    parent_chunk_id VARCHAR(36),
+    image_info TEXT,
+    relation_chunks BLOB,
+    indirect_relation_chunks BLOB,
+    metadata BLOB,
+    content_hash VARCHAR(64),
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    deleted_at DATETIME
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_chunks_seq_id ON chunks(seq_id);
+CREATE INDEX IF NOT EXISTS idx_chunks_tenant_kg ON chunks(tenant_id, knowledge_id);
+CREATE INDEX IF NOT EXISTS idx_chunks_parent_id ON chunks(parent_chunk_id);
+CREATE INDEX IF NOT EXISTS idx_chunks_chunk_type ON chunks(chunk_type);
+CREATE INDEX IF NOT EXISTS idx_chunks_tag ON chunks(tag_id);
+CREATE INDEX IF NOT EXISTS idx_chunks_content_hash ON chunks(content_hash);
+
+-- Users and auth tokens
+CREATE TABLE IF NOT EXISTS users (
+    id VARCHAR(36) PRIMARY KEY,
+    username VARCHAR(100) NOT NULL UNIQUE,
+    email VARCHAR(255) NOT NULL UNIQUE,
+    password_hash VARCHAR(255) NOT NULL,
+    avatar VARCHAR(500),
+    tenant_id INTEGER REFERENCES tenants(id) ON DELETE SET NULL,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    can_access_all_tenants BOOLEAN NOT NULL DEFAULT false,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    deleted_at DATETIME
+);
+CREATE INDEX IF NOT EXISTS idx_users_tenant_id ON users(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users(deleted_at);
+
+CREATE TABLE IF NOT EXISTS auth_tokens (
+    id VARCHAR(36) PRIMARY KEY,
+    user_id VARCHAR(36) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token TEXT NOT NULL,
+    token_type VARCHAR(50) NOT NULL,
+    expires_at DATETIME NOT NULL,
+    is_revoked BOOLEAN NOT NULL DEFAULT false,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_auth_tokens_user_id ON auth_tokens(user_id);
+CREATE INDEX IF NOT EXISTS idx_auth_tokens_token ON auth_tokens(token);
+CREATE INDEX IF NOT EXISTS idx_auth_tokens_token_type ON auth_tokens(token_type);
+CREATE INDEX IF NOT EXISTS idx_auth_tokens_expires_at ON auth_tokens(expires_at);
+
+-- Knowledge tags
+CREATE TABLE IF NOT EXISTS knowledge_tags (
+    id VARCHAR(36) PRIMARY KEY,
+    seq_id BIGINT NOT NULL,
+    tenant_id INTEGER NOT NULL,
+    knowledge_base_id VARCHAR(36) NOT NULL,
+    name VARCHAR(128) NOT NULL,
+    color VARCHAR(32),
+    sort_order INTEGER NOT NULL DEFAULT 0,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    deleted_at DATETIME
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_knowledge_tags_seq_id ON knowledge_tags(seq_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_knowledge_tags_kb_name ON knowledge_tags(tenant_id, knowledge_base_id, name);
+CREATE INDEX IF NOT EXISTS idx_knowledge_tags_kb ON knowledge_tags(tenant_id, knowledge_base_id);
+
+-- MCP services
+CREATE TABLE IF NOT EXISTS mcp_services (
+    id VARCHAR(36) PRIMARY KEY,
+    tenant_id INTEGER NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    enabled BOOLEAN DEFAULT true,
+    transport_type VARCHAR(50) NOT NULL,
+    url VARCHAR(512),
+    headers BLOB,
+    auth_config BLOB,
+    advanced_config BLOB,
+    stdio_config BLOB,
+    env_vars BLOB,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    deleted_at DATETIME
+);
+CREATE INDEX IF NOT EXISTS idx_mcp_services_tenant_id ON mcp_services(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_mcp_services_enabled ON mcp_services(enabled);
+CREATE INDEX IF NOT EXISTS idx_mcp_services_deleted_at ON mcp_services(deleted_at);
+
+-- Custom agents (composite primary key, built-in agents share ids across tenants)
+CREATE TABLE IF NOT EXISTS custom_agents (
+    id VARCHAR(36) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    avatar VARCHAR(64),
+    is_builtin BOOLEAN NOT NULL DEFAULT false,
+    tenant_id INTEGER NOT NULL,
+    created_by VARCHAR(36),
+    config BLOB NOT NULL DEFAULT X'7B7D',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    deleted_at DATETIME,
+    PRIMARY KEY (id, tenant_id)
+);
+CREATE INDEX IF NOT EXISTS idx_custom_agents_tenant_id ON custom_agents(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_custom_agents_is_builtin ON custom_agents(is_builtin);
+CREATE INDEX IF NOT EXISTS idx_custom_agents_deleted_at ON custom_agents(deleted_at);
+
+-- Organizations and sharing
+CREATE TABLE IF NOT EXISTS organizations (
+    id VARCHAR(36) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    owner_id VARCHAR(36) NOT NULL,
+    invite_code VARCHAR(32),
+    require_approval BOOLEAN DEFAULT false,
+    invite_code_expires_at DATETIME,
+    invite_code_validity_days SMALLINT NOT NULL DEFAULT 7,
+    avatar VARCHAR(512) DEFAULT '',
+    searchable BOOLEAN NOT NULL DEFAULT false,
+    member_limit INTEGER NOT NULL DEFAULT 50,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    deleted_at DATETIME
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_organizations_invite_code ON organizations(invite_code) WHERE invite_code IS NOT NULL AND deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_organizations_owner_id ON organizations(owner_id);
+CREATE INDEX IF NOT EXISTS idx_organizations_deleted_at ON organizations(deleted_at);
+
+CREATE TABLE IF NOT EXISTS organization_members (
+    id VARCHAR(36) PRIMARY KEY,
+    organization_id VARCHAR(36) NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id VARCHAR(36) NOT NULL,
+    tenant_id INTEGER NOT NULL,
+    role VARCHAR(32) NOT NULL DEFAULT 'viewer',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_org_members_org_user ON organization_members(organization_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_org_members_user_id ON organization_members(user_id);
+CREATE INDEX IF NOT EXISTS idx_org_members_tenant_id ON organization_members(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_org_members_role ON organization_members(role);
+
+CREATE TABLE IF NOT EXISTS kb_shares (
+    id VARCHAR(36) PRIMARY KEY,
+    knowledge_base_id VARCHAR(36) NOT NULL REFERENCES knowledge_bases(id) ON DELETE CASCADE,
+    organization_id VARCHAR(36) NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    shared_by_user_id VARCHAR(36) NOT NULL,
+    source_tenant_id INTEGER NOT NULL,
+    permission VARCHAR(32) NOT NULL DEFAULT 'viewer',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    deleted_at DATETIME
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_kb_shares_kb_org ON kb_shares(knowledge_base_id, organization_id) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_kb_shares_kb_id ON kb_shares(knowledge_base_id);
+CREATE INDEX IF NOT EXISTS idx_kb_shares_org_id ON kb_shares(organization_id);
+CREATE INDEX IF NOT EXISTS idx_kb_shares_source_tenant ON kb_shares(source_tenant_id);
+CREATE INDEX IF NOT EXISTS idx_kb_shares_deleted_at ON kb_shares(deleted_at);
+
+CREATE TABLE IF NOT EXISTS organization_join_requests (
+    id VARCHAR(36) PRIMARY KEY,
+    organization_id VARCHAR(36) NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id VARCHAR(36) NOT NULL,
+    tenant_id INTEGER NOT NULL,
+    status VARCHAR(32) NOT NULL DEFAULT 'pending',
+    requested_role VARCHAR(32) NOT NULL DEFAULT 'viewer',
+    request_type VARCHAR(32) NOT NULL DEFAULT 'join',
+    prev_role VARCHAR(32),
+    message TEXT,
+    reviewed_by VARCHAR(36),
+    reviewed_at DATETIME,
+    review_message TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_org_join_requests_org_user_pending ON organization_join_requests(organization_id, user_id) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_org_join_requests_org_id ON organization_join_requests(organization_id);
+CREATE INDEX IF NOT EXISTS idx_org_join_requests_user_id ON organization_join_requests(user_id);
+CREATE INDEX IF NOT EXISTS idx_org_join_requests_status ON organization_join_requests(status);
+CREATE INDEX IF NOT EXISTS idx_org_join_requests_type ON organization_join_requests(request_type);
+
+CREATE TABLE IF NOT EXISTS agent_shares (
+    id VARCHAR(36) PRIMARY KEY,
+    agent_id VARCHAR(36) NOT NULL,
+    organization_id VARCHAR(36) NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    shared_by_user_id VARCHAR(36) NOT NULL,
+    source_tenant_id INTEGER NOT NULL,
+    permission VARCHAR(32) NOT NULL DEFAULT 'viewer',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    deleted_at DATETIME,
+    FOREIGN KEY (agent_id, source_tenant_id) REFERENCES custom_agents(id, tenant_id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_agent_shares_agent_org ON agent_shares(agent_id, source_tenant_id, organization_id) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_agent_shares_agent_id ON agent_shares(agent_id);
+CREATE INDEX IF NOT EXISTS idx_agent_shares_org_id ON agent_shares(organization_id);
+CREATE INDEX IF NOT EXISTS idx_agent_shares_source_tenant ON agent_shares(source_tenant_id);
+CREATE INDEX IF NOT EXISTS idx_agent_shares_deleted_at ON agent_shares(deleted_at);
+
+CREATE TABLE IF NOT EXISTS tenant_disabled_shared_agents (
+    tenant_id BIGINT NOT NULL,
+    agent_id VARCHAR(36) NOT NULL,
+    source_tenant_id BIGINT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (tenant_id, agent_id, source_tenant_id)
+);
+CREATE INDEX IF NOT EXISTS idx_tenant_disabled_shared_agents_tenant_id ON tenant_disabled_shared_agents(tenant_id);
+
+-- MCP OAuth credentials
+CREATE TABLE IF NOT EXISTS mcp_oauth_credentials (
+    service_id VARCHAR(36) PRIMARY KEY,
+    tenant_id BIGINT NOT NULL,
+    client_id VARCHAR(512),
+    client_secret TEXT,
+    redirect_uri VARCHAR(1024),
+    access_token TEXT,
+    refresh_token TEXT,
+    token_type VARCHAR(50),
+    scope TEXT,
+    expires_at DATETIME,
+    pending_state VARCHAR(128),
+    code_verifier TEXT,
+    pending_expires_at DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_mcp_oauth_credentials_tenant_id ON mcp_oauth_credentials(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_mcp_oauth_credentials_pending_state ON mcp_oauth_credentials(pending_state);
+
+-- Agent tool approvals
+CREATE TABLE IF NOT EXISTS agent_tool_approvals (
+    id VARCHAR(36) PRIMARY KEY,
+    tenant_id BIGINT NOT NULL,
+    session_id VARCHAR(36) NOT NULL,
+    message_id VARCHAR(36),
+    tool_call_id VARCHAR(255),
+    tool_name VARCHAR(255) NOT NULL,
+    arguments BLOB,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    timeout_action VARCHAR(20) NOT NULL DEFAULT 'deny',
+    reason TEXT,
+    expires_at DATETIME NOT NULL,
+    decided_at DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_agent_tool_approvals_tenant_id ON agent_tool_approvals(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_agent_tool_approvals_session_status ON agent_tool_approvals(session_id, status);
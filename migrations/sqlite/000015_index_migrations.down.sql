@@ -0,0 +1,3 @@
+-- Migration: 000015_index_migrations (SQLite, down)
+DROP INDEX IF EXISTS idx_index_migrations_tenant_status;
+DROP TABLE IF EXISTS index_migrations;
@@ -0,0 +1,18 @@
+-- Migration: 000015_index_migrations (SQLite)
+-- Description: Jobs that move a tenant's indices from one retriever engine to another
+CREATE TABLE IF NOT EXISTS index_migrations (
+    id VARCHAR(36) PRIMARY KEY,
+    tenant_id BIGINT NOT NULL,
+    source_engine VARCHAR(50) NOT NULL,
+    target_engine VARCHAR(50) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    knowledge_bases BLOB,
+    copied BIGINT NOT NULL DEFAULT 0,
+    switched BOOLEAN NOT NULL DEFAULT FALSE,
+    error TEXT,
+    started_at DATETIME,
+    finished_at DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_index_migrations_tenant_status ON index_migrations(tenant_id, status);
@@ -0,0 +1,3 @@
+-- Migration: 000016_index_consistency_reports (SQLite, down)
+DROP INDEX IF EXISTS idx_index_consistency_reports_kb;
+DROP TABLE IF EXISTS index_consistency_reports;
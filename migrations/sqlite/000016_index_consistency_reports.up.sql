@@ -0,0 +1,19 @@
+-- Migration: 000016_index_consistency_reports (SQLite)
+-- Description: Drift reports between the chunks table and the retriever engines
+CREATE TABLE IF NOT EXISTS index_consistency_reports (
+    id VARCHAR(36) PRIMARY KEY,
+    tenant_id BIGINT NOT NULL,
+    knowledge_base_id VARCHAR(36) NOT NULL,
+    "trigger" VARCHAR(20) NOT NULL DEFAULT 'manual',
+    repair BOOLEAN NOT NULL DEFAULT FALSE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    chunk_count BIGINT NOT NULL DEFAULT 0,
+    drift_count BIGINT NOT NULL DEFAULT 0,
+    engines BLOB,
+    error TEXT,
+    started_at DATETIME,
+    finished_at DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_index_consistency_reports_kb ON index_consistency_reports(tenant_id, knowledge_base_id, created_at);
@@ -0,0 +1,5 @@
+-- Migration: 000017_webhooks (SQLite, down)
+DROP INDEX IF EXISTS idx_webhook_deliveries_webhook;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP INDEX IF EXISTS idx_webhooks_tenant;
+DROP TABLE IF EXISTS webhooks;
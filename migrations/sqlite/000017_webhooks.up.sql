@@ -0,0 +1,33 @@
+-- Migration: 000017_webhooks (SQLite)
+-- Description: Tenant webhooks and their delivery log
+CREATE TABLE IF NOT EXISTS webhooks (
+    id VARCHAR(36) PRIMARY KEY,
+    tenant_id BIGINT NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    url VARCHAR(2048) NOT NULL,
+    secret TEXT,
+    events BLOB,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_webhooks_tenant ON webhooks(tenant_id);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id VARCHAR(36) PRIMARY KEY,
+    tenant_id BIGINT NOT NULL,
+    webhook_id VARCHAR(36) NOT NULL,
+    event VARCHAR(64) NOT NULL,
+    payload BLOB,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    response_status INT NOT NULL DEFAULT 0,
+    response_body TEXT,
+    error TEXT,
+    duration_ms BIGINT NOT NULL DEFAULT 0,
+    delivered_at DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook ON webhook_deliveries(tenant_id, webhook_id, created_at);
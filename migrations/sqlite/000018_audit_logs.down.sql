@@ -0,0 +1,5 @@
+-- Migration: 000018_audit_logs (SQLite, down)
+DROP INDEX IF EXISTS idx_audit_logs_tenant_actor;
+DROP INDEX IF EXISTS idx_audit_logs_tenant_resource;
+DROP INDEX IF EXISTS idx_audit_logs_tenant_created;
+DROP TABLE IF EXISTS audit_logs;
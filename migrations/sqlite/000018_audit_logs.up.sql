@@ -0,0 +1,25 @@
+-- Migration: 000018_audit_logs (SQLite)
+-- Description: Append-only audit trail of administrative and data-access actions
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id VARCHAR(36) PRIMARY KEY,
+    tenant_id BIGINT NOT NULL,
+    actor_type VARCHAR(20) NOT NULL,
+    actor_id VARCHAR(64) NOT NULL DEFAULT '',
+    actor_name VARCHAR(255) NOT NULL DEFAULT '',
+    action VARCHAR(64) NOT NULL,
+    resource_type VARCHAR(64) NOT NULL,
+    resource_id VARCHAR(255) NOT NULL DEFAULT '',
+    before BLOB,
+    after BLOB,
+    result VARCHAR(20) NOT NULL,
+    status_code INT NOT NULL DEFAULT 0,
+    method VARCHAR(10) NOT NULL DEFAULT '',
+    path VARCHAR(512) NOT NULL DEFAULT '',
+    ip VARCHAR(64) NOT NULL DEFAULT '',
+    user_agent VARCHAR(512) NOT NULL DEFAULT '',
+    request_id VARCHAR(64) NOT NULL DEFAULT '',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_tenant_created ON audit_logs(tenant_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_tenant_resource ON audit_logs(tenant_id, resource_type, resource_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_tenant_actor ON audit_logs(tenant_id, actor_id);
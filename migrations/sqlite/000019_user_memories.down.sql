@@ -0,0 +1,3 @@
+-- Migration: 000019_user_memories (SQLite, down)
+DROP INDEX IF EXISTS idx_user_memories_tenant_user;
+DROP TABLE IF EXISTS user_memories;
@@ -0,0 +1,15 @@
+-- Migration: 000019_user_memories (SQLite)
+-- Description: Cross-session long-term memory of users, saved and recalled by agents
+CREATE TABLE IF NOT EXISTS user_memories (
+    id VARCHAR(36) PRIMARY KEY,
+    tenant_id BIGINT NOT NULL,
+    user_id VARCHAR(36) NOT NULL,
+    agent_id VARCHAR(36) NOT NULL DEFAULT '',
+    session_id VARCHAR(36) NOT NULL DEFAULT '',
+    content TEXT NOT NULL,
+    embedding BLOB,
+    embedding_model_id VARCHAR(64) NOT NULL DEFAULT '',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_user_memories_tenant_user ON user_memories(tenant_id, user_id, updated_at);
@@ -0,0 +1,4 @@
+-- Migration: 000020_message_branches (SQLite, down)
+DROP INDEX IF EXISTS idx_messages_session_parent;
+ALTER TABLE sessions DROP COLUMN active_message_id;
+ALTER TABLE messages DROP COLUMN parent_id;
@@ -0,0 +1,17 @@
+-- Migration: 000020_message_branches (SQLite)
+-- Description: Conversation branches, created by editing a past question or regenerating an answer
+ALTER TABLE messages ADD COLUMN parent_id VARCHAR(36) NOT NULL DEFAULT '';
+ALTER TABLE sessions ADD COLUMN active_message_id VARCHAR(36) NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS idx_messages_session_parent ON messages(session_id, parent_id);
+
+-- Existing conversations are linear, each message follows the previous one
+UPDATE messages SET parent_id = linked.prev_id
+FROM (
+    SELECT id, LAG(id) OVER (
+        PARTITION BY session_id
+        ORDER BY created_at, CASE role WHEN 'user' THEN 0 ELSE 1 END
+    ) AS prev_id
+    FROM messages
+    WHERE deleted_at IS NULL
+) AS linked
+WHERE messages.id = linked.id AND linked.prev_id IS NOT NULL AND messages.parent_id = '';
@@ -0,0 +1,3 @@
+-- Migration: 000021_message_search (SQLite, down)
+DROP INDEX IF EXISTS idx_messages_agent_id;
+ALTER TABLE messages DROP COLUMN agent_id;
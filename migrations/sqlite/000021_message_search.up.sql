@@ -0,0 +1,4 @@
+-- Migration: 000021_message_search (SQLite)
+-- Description: Search across the conversation history, filtered by agent
+ALTER TABLE messages ADD COLUMN agent_id VARCHAR(36) NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS idx_messages_agent_id ON messages(agent_id);
@@ -0,0 +1,3 @@
+-- Migration: 000022_http_tools (SQLite, down)
+DROP INDEX IF EXISTS idx_http_tools_tenant_name;
+DROP TABLE IF EXISTS http_tools;
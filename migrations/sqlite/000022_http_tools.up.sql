@@ -0,0 +1,22 @@
+-- Migration: 000022_http_tools (SQLite)
+-- Description: Declarative HTTP tools of custom agents, written by hand or imported from OpenAPI documents
+CREATE TABLE IF NOT EXISTS http_tools (
+    id VARCHAR(36) PRIMARY KEY,
+    tenant_id BIGINT NOT NULL,
+    name VARCHAR(64) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    method VARCHAR(10) NOT NULL DEFAULT 'GET',
+    url VARCHAR(2048) NOT NULL,
+    parameters BLOB,
+    arg_locations BLOB,
+    headers BLOB,
+    auth_header VARCHAR(255) NOT NULL DEFAULT '',
+    auth_secret TEXT,
+    response_path VARCHAR(512) NOT NULL DEFAULT '',
+    timeout_seconds INT NOT NULL DEFAULT 0,
+    source VARCHAR(20) NOT NULL DEFAULT 'manual',
+    enabled BOOLEAN NOT NULL DEFAULT 1,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_http_tools_tenant_name ON http_tools(tenant_id, name);
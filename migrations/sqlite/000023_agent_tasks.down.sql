@@ -0,0 +1,6 @@
+-- Migration: 000023_agent_tasks (SQLite, down)
+DROP INDEX IF EXISTS idx_agent_task_runs_task;
+DROP TABLE IF EXISTS agent_task_runs;
+DROP INDEX IF EXISTS idx_agent_tasks_next_run;
+DROP INDEX IF EXISTS idx_agent_tasks_tenant_trigger;
+DROP TABLE IF EXISTS agent_tasks;
@@ -0,0 +1,44 @@
+-- Migration: 000023_agent_tasks (SQLite)
+-- Description: Scheduled and event-triggered agent runs and their history
+CREATE TABLE IF NOT EXISTS agent_tasks (
+    id VARCHAR(36) PRIMARY KEY,
+    tenant_id BIGINT NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    agent_id VARCHAR(36) NOT NULL,
+    trigger VARCHAR(64) NOT NULL,
+    schedule VARCHAR(128) NOT NULL DEFAULT '',
+    timezone VARCHAR(64) NOT NULL DEFAULT '',
+    knowledge_base_ids BLOB,
+    prompt TEXT NOT NULL,
+    webhook_id VARCHAR(36) NOT NULL DEFAULT '',
+    enabled BOOLEAN NOT NULL DEFAULT 1,
+    next_run_at DATETIME,
+    last_run_at DATETIME,
+    last_run_status VARCHAR(20) NOT NULL DEFAULT '',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_agent_tasks_tenant_trigger ON agent_tasks(tenant_id, trigger);
+CREATE INDEX IF NOT EXISTS idx_agent_tasks_next_run ON agent_tasks(next_run_at);
+
+CREATE TABLE IF NOT EXISTS agent_task_runs (
+    id VARCHAR(36) PRIMARY KEY,
+    tenant_id BIGINT NOT NULL,
+    task_id VARCHAR(36) NOT NULL,
+    agent_id VARCHAR(36) NOT NULL,
+    trigger VARCHAR(64) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    event_data BLOB,
+    prompt TEXT,
+    session_id VARCHAR(36) NOT NULL DEFAULT '',
+    message_id VARCHAR(36) NOT NULL DEFAULT '',
+    output TEXT,
+    structured_output BLOB,
+    error TEXT,
+    started_at DATETIME,
+    finished_at DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_agent_task_runs_task ON agent_task_runs(tenant_id, task_id, created_at);
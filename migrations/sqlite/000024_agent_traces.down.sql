@@ -0,0 +1,3 @@
+-- Migration: 000024_agent_traces (SQLite, down)
+DROP INDEX IF EXISTS idx_agent_traces_message;
+DROP TABLE IF EXISTS agent_traces;
@@ -0,0 +1,23 @@
+-- Migration: 000024_agent_traces (SQLite)
+-- Description: Full traces of agent runs for export and replay
+CREATE TABLE IF NOT EXISTS agent_traces (
+    id VARCHAR(36) PRIMARY KEY,
+    tenant_id BIGINT NOT NULL,
+    session_id VARCHAR(36) NOT NULL,
+    message_id VARCHAR(36) NOT NULL,
+    parent_tool_call_id VARCHAR(255) NOT NULL DEFAULT '',
+    model_id VARCHAR(64) NOT NULL DEFAULT '',
+    model_name VARCHAR(255) NOT NULL DEFAULT '',
+    query TEXT,
+    system_prompt TEXT,
+    tools BLOB,
+    max_iterations INT NOT NULL DEFAULT 0,
+    rounds BLOB,
+    final_answer TEXT,
+    error TEXT,
+    usage BLOB,
+    started_at DATETIME,
+    duration_ms BIGINT NOT NULL DEFAULT 0,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_agent_traces_message ON agent_traces(tenant_id, session_id, message_id);
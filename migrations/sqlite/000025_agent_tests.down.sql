@@ -0,0 +1,5 @@
+-- Migration: 000025_agent_tests (SQLite, down)
+DROP INDEX IF EXISTS idx_agent_test_runs_agent;
+DROP TABLE IF EXISTS agent_test_runs;
+DROP INDEX IF EXISTS idx_agent_test_cases_agent;
+DROP TABLE IF EXISTS agent_test_cases;
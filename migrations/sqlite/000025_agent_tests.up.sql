@@ -0,0 +1,39 @@
+-- Migration: 000025_agent_tests (SQLite)
+-- Description: Regression test cases of custom agents and their test runs
+CREATE TABLE IF NOT EXISTS agent_test_cases (
+    id VARCHAR(36) PRIMARY KEY,
+    tenant_id BIGINT NOT NULL,
+    agent_id VARCHAR(36) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    query TEXT NOT NULL,
+    knowledge_base_ids BLOB,
+    knowledge_ids BLOB,
+    assertions BLOB,
+    max_rounds INTEGER NOT NULL DEFAULT 0,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_agent_test_cases_agent ON agent_test_cases(tenant_id, agent_id);
+
+CREATE TABLE IF NOT EXISTS agent_test_runs (
+    id VARCHAR(36) PRIMARY KEY,
+    tenant_id BIGINT NOT NULL,
+    agent_id VARCHAR(36) NOT NULL,
+    label VARCHAR(255) NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    agent_config BLOB,
+    config_hash VARCHAR(64) NOT NULL DEFAULT '',
+    judge_model_id VARCHAR(64) NOT NULL DEFAULT '',
+    case_ids BLOB,
+    session_id VARCHAR(36) NOT NULL DEFAULT '',
+    total INTEGER NOT NULL DEFAULT 0,
+    passed INTEGER NOT NULL DEFAULT 0,
+    failed INTEGER NOT NULL DEFAULT 0,
+    results BLOB,
+    error TEXT,
+    started_at DATETIME,
+    finished_at DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_agent_test_runs_agent ON agent_test_runs(tenant_id, agent_id, created_at);
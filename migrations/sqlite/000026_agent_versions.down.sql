@@ -0,0 +1,6 @@
+-- Migration: 000026_agent_versions (SQLite, down)
+ALTER TABLE messages DROP COLUMN agent_version;
+ALTER TABLE agent_shares DROP COLUMN pinned_version;
+ALTER TABLE custom_agents DROP COLUMN published_version;
+DROP INDEX IF EXISTS idx_agent_versions_agent_version;
+DROP TABLE IF EXISTS agent_versions;
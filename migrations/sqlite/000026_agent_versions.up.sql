@@ -0,0 +1,21 @@
+-- Migration: 000026_agent_versions (SQLite)
+-- Description: Published versions of custom agents, version pinning of agent shares
+CREATE TABLE IF NOT EXISTS agent_versions (
+    id VARCHAR(36) PRIMARY KEY,
+    tenant_id BIGINT NOT NULL,
+    agent_id VARCHAR(36) NOT NULL,
+    version INTEGER NOT NULL,
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    description TEXT,
+    avatar VARCHAR(64) NOT NULL DEFAULT '',
+    config BLOB,
+    config_hash VARCHAR(64) NOT NULL DEFAULT '',
+    note TEXT,
+    published_by VARCHAR(36) NOT NULL DEFAULT '',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_agent_versions_agent_version ON agent_versions(tenant_id, agent_id, version);
+
+ALTER TABLE custom_agents ADD COLUMN published_version INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE agent_shares ADD COLUMN pinned_version INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE messages ADD COLUMN agent_version INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+-- Migration: 000027_agent_trace_retention (SQLite, down)
+DROP INDEX IF EXISTS idx_agent_traces_created_at;
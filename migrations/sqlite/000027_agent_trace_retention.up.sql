@@ -0,0 +1,3 @@
+-- Migration: 000027_agent_trace_retention (SQLite)
+-- Description: Index for deleting agent traces past the retention period
+CREATE INDEX IF NOT EXISTS idx_agent_traces_created_at ON agent_traces(created_at);
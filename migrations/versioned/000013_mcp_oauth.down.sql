@@ -0,0 +1,8 @@
+-- Migration: 000013_mcp_oauth (down)
+DO $$ BEGIN RAISE NOTICE '[Migration 000013] Rolling back mcp_oauth_credentials...'; END $$;
+
+DROP INDEX IF EXISTS idx_mcp_oauth_credentials_pending_state;
+DROP INDEX IF EXISTS idx_mcp_oauth_credentials_tenant_id;
+DROP TABLE IF EXISTS mcp_oauth_credentials;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000013] Rollback completed successfully!'; END $$;
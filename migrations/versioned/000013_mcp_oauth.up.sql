@@ -0,0 +1,33 @@
+-- Migration: 000013_mcp_oauth
+-- Description: OAuth 2.0 client registrations and encrypted tokens for remote MCP services
+DO $$ BEGIN RAISE NOTICE '[Migration 000013] Creating table: mcp_oauth_credentials'; END $$;
+
+CREATE TABLE IF NOT EXISTS mcp_oauth_credentials (
+    service_id VARCHAR(36) PRIMARY KEY,
+    tenant_id BIGINT NOT NULL,
+    client_id VARCHAR(512),
+    client_secret TEXT,
+    redirect_uri VARCHAR(1024),
+    access_token TEXT,
+    refresh_token TEXT,
+    token_type VARCHAR(50),
+    scope TEXT,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    pending_state VARCHAR(128),
+    code_verifier TEXT,
+    pending_expires_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_mcp_oauth_credentials_tenant_id ON mcp_oauth_credentials(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_mcp_oauth_credentials_pending_state ON mcp_oauth_credentials(pending_state);
+
+COMMENT ON TABLE mcp_oauth_credentials IS 'OAuth 2.0 client registrations and tokens for MCP services';
+COMMENT ON COLUMN mcp_oauth_credentials.client_secret IS 'AES-GCM encrypted client secret';
+COMMENT ON COLUMN mcp_oauth_credentials.access_token IS 'AES-GCM encrypted access token';
+COMMENT ON COLUMN mcp_oauth_credentials.refresh_token IS 'AES-GCM encrypted refresh token';
+COMMENT ON COLUMN mcp_oauth_credentials.pending_state IS 'State of the in-flight authorization request';
+COMMENT ON COLUMN mcp_oauth_credentials.code_verifier IS 'AES-GCM encrypted PKCE code verifier of the in-flight authorization request';
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000013] mcp_oauth_credentials setup completed successfully!'; END $$;
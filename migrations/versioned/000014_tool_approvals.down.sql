@@ -0,0 +1,8 @@
+-- Migration: 000014_tool_approvals (down)
+DO $$ BEGIN RAISE NOTICE '[Migration 000014] Rolling back agent_tool_approvals...'; END $$;
+
+DROP INDEX IF EXISTS idx_agent_tool_approvals_session_status;
+DROP INDEX IF EXISTS idx_agent_tool_approvals_tenant_id;
+DROP TABLE IF EXISTS agent_tool_approvals;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000014] Rollback completed successfully!'; END $$;
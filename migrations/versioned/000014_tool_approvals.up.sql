@@ -0,0 +1,29 @@
+-- Migration: 000014_tool_approvals
+-- Description: Human-in-the-loop approval requests for sensitive agent tool calls
+DO $$ BEGIN RAISE NOTICE '[Migration 000014] Creating table: agent_tool_approvals'; END $$;
+
+CREATE TABLE IF NOT EXISTS agent_tool_approvals (
+    id VARCHAR(36) PRIMARY KEY,
+    tenant_id BIGINT NOT NULL,
+    session_id VARCHAR(36) NOT NULL,
+    message_id VARCHAR(36),
+    tool_call_id VARCHAR(255),
+    tool_name VARCHAR(255) NOT NULL,
+    arguments JSONB,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    timeout_action VARCHAR(20) NOT NULL DEFAULT 'deny',
+    reason TEXT,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    decided_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_agent_tool_approvals_tenant_id ON agent_tool_approvals(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_agent_tool_approvals_session_status ON agent_tool_approvals(session_id, status);
+
+COMMENT ON TABLE agent_tool_approvals IS 'Approval requests for agent tool calls that require user confirmation';
+COMMENT ON COLUMN agent_tool_approvals.status IS 'pending, approved, denied or expired';
+COMMENT ON COLUMN agent_tool_approvals.timeout_action IS 'Action applied when no decision is made before expires_at: deny or approve';
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000014] agent_tool_approvals setup completed successfully!'; END $$;
@@ -0,0 +1,7 @@
+-- Migration: 000015_index_migrations (down)
+DO $$ BEGIN RAISE NOTICE '[Migration 000015] Rolling back index_migrations...'; END $$;
+
+DROP INDEX IF EXISTS idx_index_migrations_tenant_status;
+DROP TABLE IF EXISTS index_migrations;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000015] Rollback completed successfully!'; END $$;
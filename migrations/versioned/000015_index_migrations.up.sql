@@ -0,0 +1,28 @@
+-- Migration: 000015_index_migrations
+-- Description: Jobs that move a tenant's indices from one retriever engine to another
+DO $$ BEGIN RAISE NOTICE '[Migration 000015] Creating table: index_migrations'; END $$;
+
+CREATE TABLE IF NOT EXISTS index_migrations (
+    id VARCHAR(36) PRIMARY KEY,
+    tenant_id BIGINT NOT NULL,
+    source_engine VARCHAR(50) NOT NULL,
+    target_engine VARCHAR(50) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    knowledge_bases JSONB,
+    copied BIGINT NOT NULL DEFAULT 0,
+    switched BOOLEAN NOT NULL DEFAULT FALSE,
+    error TEXT,
+    started_at TIMESTAMP WITH TIME ZONE,
+    finished_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_index_migrations_tenant_status ON index_migrations(tenant_id, status);
+
+COMMENT ON TABLE index_migrations IS 'Index migrations between retriever engines, with per knowledge base progress';
+COMMENT ON COLUMN index_migrations.status IS 'pending, running, completed or failed';
+COMMENT ON COLUMN index_migrations.knowledge_bases IS 'Phase, cursor and counts of each knowledge base';
+COMMENT ON COLUMN index_migrations.switched IS 'Whether the tenant engine mapping was switched to the target engine';
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000015] index_migrations setup completed successfully!'; END $$;
@@ -0,0 +1,7 @@
+-- Migration: 000016_index_consistency_reports (down)
+DO $$ BEGIN RAISE NOTICE '[Migration 000016] Rolling back index_consistency_reports...'; END $$;
+
+DROP INDEX IF EXISTS idx_index_consistency_reports_kb;
+DROP TABLE IF EXISTS index_consistency_reports;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000016] Rollback completed successfully!'; END $$;
@@ -0,0 +1,29 @@
+-- Migration: 000016_index_consistency_reports
+-- Description: Drift reports between the chunks table and the retriever engines
+DO $$ BEGIN RAISE NOTICE '[Migration 000016] Creating table: index_consistency_reports'; END $$;
+
+CREATE TABLE IF NOT EXISTS index_consistency_reports (
+    id VARCHAR(36) PRIMARY KEY,
+    tenant_id BIGINT NOT NULL,
+    knowledge_base_id VARCHAR(36) NOT NULL,
+    trigger VARCHAR(20) NOT NULL DEFAULT 'manual',
+    repair BOOLEAN NOT NULL DEFAULT FALSE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    chunk_count BIGINT NOT NULL DEFAULT 0,
+    drift_count BIGINT NOT NULL DEFAULT 0,
+    engines JSONB,
+    error TEXT,
+    started_at TIMESTAMP WITH TIME ZONE,
+    finished_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_index_consistency_reports_kb ON index_consistency_reports(tenant_id, knowledge_base_id, created_at);
+
+COMMENT ON TABLE index_consistency_reports IS 'Index consistency checks of knowledge bases';
+COMMENT ON COLUMN index_consistency_reports.trigger IS 'manual or scheduled';
+COMMENT ON COLUMN index_consistency_reports.status IS 'pending, running, completed or failed';
+COMMENT ON COLUMN index_consistency_reports.engines IS 'Drift counts and sample IDs of each retriever engine';
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000016] index_consistency_reports setup completed successfully!'; END $$;
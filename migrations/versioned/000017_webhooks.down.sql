@@ -0,0 +1,9 @@
+-- Migration: 000017_webhooks (down)
+DO $$ BEGIN RAISE NOTICE '[Migration 000017] Rolling back webhooks...'; END $$;
+
+DROP INDEX IF EXISTS idx_webhook_deliveries_webhook;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP INDEX IF EXISTS idx_webhooks_tenant;
+DROP TABLE IF EXISTS webhooks;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000017] Rollback completed successfully!'; END $$;
@@ -0,0 +1,47 @@
+-- Migration: 000017_webhooks
+-- Description: Tenant webhooks and their delivery log
+DO $$ BEGIN RAISE NOTICE '[Migration 000017] Creating tables: webhooks, webhook_deliveries'; END $$;
+
+CREATE TABLE IF NOT EXISTS webhooks (
+    id VARCHAR(36) PRIMARY KEY,
+    tenant_id BIGINT NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    url VARCHAR(2048) NOT NULL,
+    secret TEXT,
+    events JSONB,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhooks_tenant ON webhooks(tenant_id);
+
+COMMENT ON TABLE webhooks IS 'HTTP endpoints of tenants that receive subscribed events';
+COMMENT ON COLUMN webhooks.secret IS 'HMAC signing secret, encrypted with TENANT_AES_KEY';
+COMMENT ON COLUMN webhooks.events IS 'Subscribed event types';
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id VARCHAR(36) PRIMARY KEY,
+    tenant_id BIGINT NOT NULL,
+    webhook_id VARCHAR(36) NOT NULL,
+    event VARCHAR(64) NOT NULL,
+    payload JSONB,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    response_status INT NOT NULL DEFAULT 0,
+    response_body TEXT,
+    error TEXT,
+    duration_ms BIGINT NOT NULL DEFAULT 0,
+    delivered_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook ON webhook_deliveries(tenant_id, webhook_id, created_at);
+
+COMMENT ON TABLE webhook_deliveries IS 'Delivery log of webhook events';
+COMMENT ON COLUMN webhook_deliveries.payload IS 'Event body posted to the webhook';
+COMMENT ON COLUMN webhook_deliveries.status IS 'pending, retrying, success or failed';
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000017] webhooks setup completed successfully!'; END $$;
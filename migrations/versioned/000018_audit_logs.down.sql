@@ -0,0 +1,9 @@
+-- Migration: 000018_audit_logs (down)
+DO $$ BEGIN RAISE NOTICE '[Migration 000018] Rolling back audit_logs...'; END $$;
+
+DROP INDEX IF EXISTS idx_audit_logs_tenant_actor;
+DROP INDEX IF EXISTS idx_audit_logs_tenant_resource;
+DROP INDEX IF EXISTS idx_audit_logs_tenant_created;
+DROP TABLE IF EXISTS audit_logs;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000018] Rollback completed successfully!'; END $$;
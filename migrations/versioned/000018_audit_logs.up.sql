@@ -0,0 +1,35 @@
+-- Migration: 000018_audit_logs
+-- Description: Append-only audit trail of administrative and data-access actions
+DO $$ BEGIN RAISE NOTICE '[Migration 000018] Creating table: audit_logs'; END $$;
+
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id VARCHAR(36) PRIMARY KEY,
+    tenant_id BIGINT NOT NULL,
+    actor_type VARCHAR(20) NOT NULL,
+    actor_id VARCHAR(64) NOT NULL DEFAULT '',
+    actor_name VARCHAR(255) NOT NULL DEFAULT '',
+    action VARCHAR(64) NOT NULL,
+    resource_type VARCHAR(64) NOT NULL,
+    resource_id VARCHAR(255) NOT NULL DEFAULT '',
+    before JSONB,
+    after JSONB,
+    result VARCHAR(20) NOT NULL,
+    status_code INT NOT NULL DEFAULT 0,
+    method VARCHAR(10) NOT NULL DEFAULT '',
+    path VARCHAR(512) NOT NULL DEFAULT '',
+    ip VARCHAR(64) NOT NULL DEFAULT '',
+    user_agent VARCHAR(512) NOT NULL DEFAULT '',
+    request_id VARCHAR(64) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_logs_tenant_created ON audit_logs(tenant_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_tenant_resource ON audit_logs(tenant_id, resource_type, resource_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_tenant_actor ON audit_logs(tenant_id, actor_id);
+
+COMMENT ON TABLE audit_logs IS 'Append-only audit trail of mutations performed through the API';
+COMMENT ON COLUMN audit_logs.actor_id IS 'User ID, or the masked tenant API key';
+COMMENT ON COLUMN audit_logs.before IS 'Summary of the resource before the change';
+COMMENT ON COLUMN audit_logs.after IS 'Summary of the change, without secrets or content';
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000018] audit_logs setup completed successfully!'; END $$;
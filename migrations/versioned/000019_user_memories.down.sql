@@ -0,0 +1,7 @@
+-- Migration: 000019_user_memories (down)
+DO $$ BEGIN RAISE NOTICE '[Migration 000019] Rolling back user_memories...'; END $$;
+
+DROP INDEX IF EXISTS idx_user_memories_tenant_user;
+DROP TABLE IF EXISTS user_memories;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000019] Rollback completed successfully!'; END $$;
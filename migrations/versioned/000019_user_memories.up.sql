@@ -0,0 +1,25 @@
+-- Migration: 000019_user_memories
+-- Description: Cross-session long-term memory of users, saved and recalled by agents
+DO $$ BEGIN RAISE NOTICE '[Migration 000019] Creating table: user_memories'; END $$;
+
+CREATE TABLE IF NOT EXISTS user_memories (
+    id VARCHAR(36) PRIMARY KEY,
+    tenant_id BIGINT NOT NULL,
+    user_id VARCHAR(36) NOT NULL,
+    agent_id VARCHAR(36) NOT NULL DEFAULT '',
+    session_id VARCHAR(36) NOT NULL DEFAULT '',
+    content TEXT NOT NULL,
+    embedding JSONB,
+    embedding_model_id VARCHAR(64) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_memories_tenant_user ON user_memories(tenant_id, user_id, updated_at);
+
+COMMENT ON TABLE user_memories IS 'Facts and preferences about users that agents remember across sessions';
+COMMENT ON COLUMN user_memories.agent_id IS 'Agent that saved the memory';
+COMMENT ON COLUMN user_memories.embedding IS 'Embedding for semantic recall, NULL when saved without an embedding model';
+COMMENT ON COLUMN user_memories.embedding_model_id IS 'Model the embedding was computed with, recall only compares embeddings of the same model';
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000019] user_memories setup completed successfully!'; END $$;
@@ -0,0 +1,8 @@
+-- Migration: 000020_message_branches (down)
+DO $$ BEGIN RAISE NOTICE '[Migration 000020] Rolling back message branches...'; END $$;
+
+DROP INDEX IF EXISTS idx_messages_session_parent;
+ALTER TABLE sessions DROP COLUMN IF EXISTS active_message_id;
+ALTER TABLE messages DROP COLUMN IF EXISTS parent_id;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000020] Rollback completed successfully!'; END $$;
@@ -0,0 +1,26 @@
+-- Migration: 000020_message_branches
+-- Description: Conversation branches, created by editing a past question or regenerating an answer
+DO $$ BEGIN RAISE NOTICE '[Migration 000020] Adding message branch columns'; END $$;
+
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS parent_id VARCHAR(36) NOT NULL DEFAULT '';
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS active_message_id VARCHAR(36) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_messages_session_parent ON messages(session_id, parent_id);
+
+COMMENT ON COLUMN messages.parent_id IS 'Previous message on the same branch, empty for the first message of a session';
+COMMENT ON COLUMN sessions.active_message_id IS 'Last message of the branch shown and continued by the session';
+
+-- Existing conversations are linear, each message follows the previous one
+DO $$ BEGIN RAISE NOTICE '[Migration 000020] Linking existing messages'; END $$;
+UPDATE messages SET parent_id = linked.prev_id
+FROM (
+    SELECT id, LAG(id) OVER (
+        PARTITION BY session_id
+        ORDER BY created_at, CASE role WHEN 'user' THEN 0 ELSE 1 END
+    ) AS prev_id
+    FROM messages
+    WHERE deleted_at IS NULL
+) AS linked
+WHERE messages.id = linked.id AND linked.prev_id IS NOT NULL AND messages.parent_id = '';
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000020] message branches setup completed successfully!'; END $$;
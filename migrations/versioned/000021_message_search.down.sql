@@ -0,0 +1,9 @@
+-- Migration: 000021_message_search (down)
+DO $$ BEGIN RAISE NOTICE '[Migration 000021] Rolling back message search...'; END $$;
+
+DROP INDEX IF EXISTS idx_sessions_title_trgm;
+DROP INDEX IF EXISTS idx_messages_content_trgm;
+DROP INDEX IF EXISTS idx_messages_agent_id;
+ALTER TABLE messages DROP COLUMN IF EXISTS agent_id;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000021] Rollback completed successfully!'; END $$;
@@ -0,0 +1,22 @@
+-- Migration: 000021_message_search
+-- Description: Search across the conversation history, filtered by agent
+DO $$ BEGIN RAISE NOTICE '[Migration 000021] Adding message search columns and indexes'; END $$;
+
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS agent_id VARCHAR(36) NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS idx_messages_agent_id ON messages(agent_id);
+
+COMMENT ON COLUMN messages.agent_id IS 'Custom agent the question was asked to, empty when no agent was selected';
+
+-- Substring search on message content and session titles, only when pg_trgm is installed
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'pg_trgm') THEN
+        RAISE NOTICE '[Migration 000021] Creating trigram indexes for message search';
+        CREATE INDEX IF NOT EXISTS idx_messages_content_trgm ON messages USING gin (content gin_trgm_ops);
+        CREATE INDEX IF NOT EXISTS idx_sessions_title_trgm ON sessions USING gin (title gin_trgm_ops);
+    ELSE
+        RAISE NOTICE '[Migration 000021] pg_trgm not installed, skipping trigram indexes';
+    END IF;
+END $$;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000021] message search setup completed successfully!'; END $$;
@@ -0,0 +1,7 @@
+-- Migration: 000022_http_tools (down)
+DO $$ BEGIN RAISE NOTICE '[Migration 000022] Rolling back http_tools...'; END $$;
+
+DROP INDEX IF EXISTS idx_http_tools_tenant_name;
+DROP TABLE IF EXISTS http_tools;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000022] Rollback completed successfully!'; END $$;
@@ -0,0 +1,34 @@
+-- Migration: 000022_http_tools
+-- Description: Declarative HTTP tools of custom agents, written by hand or imported from OpenAPI documents
+DO $$ BEGIN RAISE NOTICE '[Migration 000022] Creating table: http_tools'; END $$;
+
+CREATE TABLE IF NOT EXISTS http_tools (
+    id VARCHAR(36) PRIMARY KEY,
+    tenant_id BIGINT NOT NULL,
+    name VARCHAR(64) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    method VARCHAR(10) NOT NULL DEFAULT 'GET',
+    url VARCHAR(2048) NOT NULL,
+    parameters JSONB,
+    arg_locations JSONB,
+    headers JSONB,
+    auth_header VARCHAR(255) NOT NULL DEFAULT '',
+    auth_secret TEXT,
+    response_path VARCHAR(512) NOT NULL DEFAULT '',
+    timeout_seconds INT NOT NULL DEFAULT 0,
+    source VARCHAR(20) NOT NULL DEFAULT 'manual',
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_http_tools_tenant_name ON http_tools(tenant_id, name);
+
+COMMENT ON TABLE http_tools IS 'HTTP endpoints of tenants exposed to custom agents as tools';
+COMMENT ON COLUMN http_tools.url IS 'URL template, arguments are referenced as {name}';
+COMMENT ON COLUMN http_tools.parameters IS 'JSON schema of the tool arguments';
+COMMENT ON COLUMN http_tools.arg_locations IS 'Location of each argument: path, query, header, body or body_raw';
+COMMENT ON COLUMN http_tools.auth_secret IS 'Auth header value, encrypted with TENANT_AES_KEY';
+COMMENT ON COLUMN http_tools.response_path IS 'JSONPath selecting the part of the response returned to the agent';
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000022] http_tools setup completed successfully!'; END $$;
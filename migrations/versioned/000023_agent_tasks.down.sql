@@ -0,0 +1,10 @@
+-- Migration: 000023_agent_tasks (down)
+DO $$ BEGIN RAISE NOTICE '[Migration 000023] Rolling back agent_tasks...'; END $$;
+
+DROP INDEX IF EXISTS idx_agent_task_runs_task;
+DROP TABLE IF EXISTS agent_task_runs;
+DROP INDEX IF EXISTS idx_agent_tasks_next_run;
+DROP INDEX IF EXISTS idx_agent_tasks_tenant_trigger;
+DROP TABLE IF EXISTS agent_tasks;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000023] Rollback completed successfully!'; END $$;
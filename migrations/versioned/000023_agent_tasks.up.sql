@@ -0,0 +1,60 @@
+-- Migration: 000023_agent_tasks
+-- Description: Scheduled and event-triggered agent runs and their history
+DO $$ BEGIN RAISE NOTICE '[Migration 000023] Creating tables: agent_tasks, agent_task_runs'; END $$;
+
+CREATE TABLE IF NOT EXISTS agent_tasks (
+    id VARCHAR(36) PRIMARY KEY,
+    tenant_id BIGINT NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    agent_id VARCHAR(36) NOT NULL,
+    trigger VARCHAR(64) NOT NULL,
+    schedule VARCHAR(128) NOT NULL DEFAULT '',
+    timezone VARCHAR(64) NOT NULL DEFAULT '',
+    knowledge_base_ids JSONB,
+    prompt TEXT NOT NULL,
+    webhook_id VARCHAR(36) NOT NULL DEFAULT '',
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    next_run_at TIMESTAMP WITH TIME ZONE,
+    last_run_at TIMESTAMP WITH TIME ZONE,
+    last_run_status VARCHAR(20) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_agent_tasks_tenant_trigger ON agent_tasks(tenant_id, trigger);
+CREATE INDEX IF NOT EXISTS idx_agent_tasks_next_run ON agent_tasks(next_run_at) WHERE enabled AND trigger = 'schedule';
+
+COMMENT ON TABLE agent_tasks IS 'Custom agents run headlessly on a schedule or when an event occurs';
+COMMENT ON COLUMN agent_tasks.trigger IS 'schedule, knowledge.parse_completed or faq.import_finished';
+COMMENT ON COLUMN agent_tasks.schedule IS 'Cron spec of schedule tasks';
+COMMENT ON COLUMN agent_tasks.prompt IS 'Prompt template, {{name}} is replaced by event fields';
+COMMENT ON COLUMN agent_tasks.webhook_id IS 'Webhook receiving the result of every run';
+
+CREATE TABLE IF NOT EXISTS agent_task_runs (
+    id VARCHAR(36) PRIMARY KEY,
+    tenant_id BIGINT NOT NULL,
+    task_id VARCHAR(36) NOT NULL,
+    agent_id VARCHAR(36) NOT NULL,
+    trigger VARCHAR(64) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    event_data JSONB,
+    prompt TEXT,
+    session_id VARCHAR(36) NOT NULL DEFAULT '',
+    message_id VARCHAR(36) NOT NULL DEFAULT '',
+    output TEXT,
+    structured_output JSONB,
+    error TEXT,
+    started_at TIMESTAMP WITH TIME ZONE,
+    finished_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_agent_task_runs_task ON agent_task_runs(tenant_id, task_id, created_at);
+
+COMMENT ON TABLE agent_task_runs IS 'Run history of agent tasks';
+COMMENT ON COLUMN agent_task_runs.status IS 'pending, running, succeeded or failed';
+COMMENT ON COLUMN agent_task_runs.event_data IS 'Event that fired the run';
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000023] agent_tasks setup completed successfully!'; END $$;
@@ -0,0 +1,7 @@
+-- Migration: 000024_agent_traces (down)
+DO $$ BEGIN RAISE NOTICE '[Migration 000024] Rolling back agent_traces...'; END $$;
+
+DROP INDEX IF EXISTS idx_agent_traces_message;
+DROP TABLE IF EXISTS agent_traces;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000024] Rollback completed successfully!'; END $$;
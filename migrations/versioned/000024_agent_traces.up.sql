@@ -0,0 +1,32 @@
+-- Migration: 000024_agent_traces
+-- Description: Full traces of agent runs for export and replay
+DO $$ BEGIN RAISE NOTICE '[Migration 000024] Creating table: agent_traces'; END $$;
+
+CREATE TABLE IF NOT EXISTS agent_traces (
+    id VARCHAR(36) PRIMARY KEY,
+    tenant_id BIGINT NOT NULL,
+    session_id VARCHAR(36) NOT NULL,
+    message_id VARCHAR(36) NOT NULL,
+    parent_tool_call_id VARCHAR(255) NOT NULL DEFAULT '',
+    model_id VARCHAR(64) NOT NULL DEFAULT '',
+    model_name VARCHAR(255) NOT NULL DEFAULT '',
+    query TEXT,
+    system_prompt TEXT,
+    tools JSONB,
+    max_iterations INT NOT NULL DEFAULT 0,
+    rounds JSONB,
+    final_answer TEXT,
+    error TEXT,
+    usage JSONB,
+    started_at TIMESTAMP WITH TIME ZONE,
+    duration_ms BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_agent_traces_message ON agent_traces(tenant_id, session_id, message_id);
+
+COMMENT ON TABLE agent_traces IS 'Prompts, model calls and tool calls of agent runs, one row per run';
+COMMENT ON COLUMN agent_traces.parent_tool_call_id IS 'Tool call that delegated to this sub-agent run, empty for the top-level run';
+COMMENT ON COLUMN agent_traces.rounds IS 'Model calls with their request messages, options, response, usage and tool calls';
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000024] agent_traces setup completed successfully!'; END $$;
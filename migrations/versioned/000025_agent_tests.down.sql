@@ -0,0 +1,9 @@
+-- Migration: 000025_agent_tests (down)
+DO $$ BEGIN RAISE NOTICE '[Migration 000025] Rolling back agent_tests...'; END $$;
+
+DROP INDEX IF EXISTS idx_agent_test_runs_agent;
+DROP TABLE IF EXISTS agent_test_runs;
+DROP INDEX IF EXISTS idx_agent_test_cases_agent;
+DROP TABLE IF EXISTS agent_test_cases;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000025] Rollback completed successfully!'; END $$;
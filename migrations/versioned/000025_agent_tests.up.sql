@@ -0,0 +1,54 @@
+-- Migration: 000025_agent_tests
+-- Description: Regression test cases of custom agents and their test runs
+DO $$ BEGIN RAISE NOTICE '[Migration 000025] Creating tables: agent_test_cases, agent_test_runs'; END $$;
+
+CREATE TABLE IF NOT EXISTS agent_test_cases (
+    id VARCHAR(36) PRIMARY KEY,
+    tenant_id BIGINT NOT NULL,
+    agent_id VARCHAR(36) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    query TEXT NOT NULL,
+    knowledge_base_ids JSONB,
+    knowledge_ids JSONB,
+    assertions JSONB,
+    max_rounds INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_agent_test_cases_agent ON agent_test_cases(tenant_id, agent_id);
+
+COMMENT ON TABLE agent_test_cases IS 'Saved test conversations of custom agents';
+COMMENT ON COLUMN agent_test_cases.assertions IS 'Checks on the run: tool_called, knowledge_cited, answer_contains, answer_matches or llm_judge';
+COMMENT ON COLUMN agent_test_cases.max_rounds IS 'The agent must answer within this many rounds, unchecked when 0';
+
+CREATE TABLE IF NOT EXISTS agent_test_runs (
+    id VARCHAR(36) PRIMARY KEY,
+    tenant_id BIGINT NOT NULL,
+    agent_id VARCHAR(36) NOT NULL,
+    label VARCHAR(255) NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    agent_config JSONB,
+    config_hash VARCHAR(64) NOT NULL DEFAULT '',
+    judge_model_id VARCHAR(64) NOT NULL DEFAULT '',
+    case_ids JSONB,
+    session_id VARCHAR(36) NOT NULL DEFAULT '',
+    total INTEGER NOT NULL DEFAULT 0,
+    passed INTEGER NOT NULL DEFAULT 0,
+    failed INTEGER NOT NULL DEFAULT 0,
+    results JSONB,
+    error TEXT,
+    started_at TIMESTAMP WITH TIME ZONE,
+    finished_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_agent_test_runs_agent ON agent_test_runs(tenant_id, agent_id, created_at);
+
+COMMENT ON TABLE agent_test_runs IS 'Runs of the test cases of custom agents and their results';
+COMMENT ON COLUMN agent_test_runs.status IS 'pending, running, completed or failed';
+COMMENT ON COLUMN agent_test_runs.agent_config IS 'Config of the agent the run tested';
+COMMENT ON COLUMN agent_test_runs.config_hash IS 'SHA-256 of agent_config, equal for runs of the same agent version';
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000025] agent_tests setup completed successfully!'; END $$;
@@ -0,0 +1,10 @@
+-- Migration: 000026_agent_versions (down)
+DO $$ BEGIN RAISE NOTICE '[Migration 000026] Rolling back agent versions...'; END $$;
+
+ALTER TABLE messages DROP COLUMN IF EXISTS agent_version;
+ALTER TABLE agent_shares DROP COLUMN IF EXISTS pinned_version;
+ALTER TABLE custom_agents DROP COLUMN IF EXISTS published_version;
+DROP INDEX IF EXISTS idx_agent_versions_agent_version;
+DROP TABLE IF EXISTS agent_versions;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000026] Rollback completed successfully!'; END $$;
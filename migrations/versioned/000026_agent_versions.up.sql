@@ -0,0 +1,35 @@
+-- Migration: 000026_agent_versions
+-- Description: Published versions of custom agents, version pinning of agent shares
+DO $$ BEGIN RAISE NOTICE '[Migration 000026] Creating table: agent_versions'; END $$;
+
+CREATE TABLE IF NOT EXISTS agent_versions (
+    id VARCHAR(36) PRIMARY KEY,
+    tenant_id BIGINT NOT NULL,
+    agent_id VARCHAR(36) NOT NULL,
+    version INTEGER NOT NULL,
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    description TEXT,
+    avatar VARCHAR(64) NOT NULL DEFAULT '',
+    config JSONB,
+    config_hash VARCHAR(64) NOT NULL DEFAULT '',
+    note TEXT,
+    published_by VARCHAR(36) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_agent_versions_agent_version ON agent_versions(tenant_id, agent_id, version);
+
+COMMENT ON TABLE agent_versions IS 'Immutable published versions of custom agents';
+COMMENT ON COLUMN agent_versions.config_hash IS 'SHA-256 of the config, the same as the config hash of agent test runs';
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000026] Adding agent version columns'; END $$;
+
+ALTER TABLE custom_agents ADD COLUMN IF NOT EXISTS published_version INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE agent_shares ADD COLUMN IF NOT EXISTS pinned_version INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS agent_version INTEGER NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN custom_agents.published_version IS 'Version used in conversations, 0 when the agent has never been published and its draft is used';
+COMMENT ON COLUMN agent_shares.pinned_version IS 'Version used by members of the organization, 0 to follow the published version';
+COMMENT ON COLUMN messages.agent_version IS 'Published version of the agent that answered, 0 when its draft answered';
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000026] agent versions setup completed successfully!'; END $$;
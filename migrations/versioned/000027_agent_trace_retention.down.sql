@@ -0,0 +1,4 @@
+-- Migration: 000027_agent_trace_retention (down)
+DO $$ BEGIN RAISE NOTICE '[Migration 000027] Rolling back agent trace retention...'; END $$;
+
+DROP INDEX IF EXISTS idx_agent_traces_created_at;
@@ -0,0 +1,7 @@
+-- Migration: 000027_agent_trace_retention
+-- Description: Index for deleting agent traces past the retention period
+DO $$ BEGIN RAISE NOTICE '[Migration 000027] Creating index: idx_agent_traces_created_at'; END $$;
+
+CREATE INDEX IF NOT EXISTS idx_agent_traces_created_at ON agent_traces(created_at);
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000027] agent trace retention setup completed successfully!'; END $$;